@@ -0,0 +1,102 @@
+// Command notifly-all runs the HTTP server, the asynq worker, and the stale
+// task reaper in a single process. It is meant for small deployments and local
+// development; the roles share one store and one queue client.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/badrkarrachai/notifly/internal/app"
+	"github.com/badrkarrachai/notifly/internal/config"
+)
+
+func main() {
+	// Initialize structured logger (level is adjustable at runtime)
+	logLevel := new(slog.LevelVar)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: logLevel,
+	}))
+	slog.SetDefault(logger)
+
+	// Load and validate configuration (exits on any problem)
+	cfg := app.MustLoadConfig(config.RoleAll, logLevel)
+
+	slog.Info("configuration loaded", "port", cfg.Server.Port, "mode", cfg.Server.Mode, "roles", "server,worker")
+
+	// Wait for Redis and the store (exits if they stay unreachable)
+	app.MustWaitForDependencies(cfg)
+
+	// ==========================================
+	// Dependency Injection (Manual Wiring)
+	// ==========================================
+
+	deps, err := app.NewDeps(cfg)
+	if err != nil {
+		slog.Error("failed to initialize dependencies", "error", err)
+		os.Exit(1)
+	}
+	defer deps.Close()
+
+	// Build the worker first so broken templates stop the process before it accepts traffic
+	worker, err := app.NewWorker(deps)
+	if err != nil {
+		slog.Error("failed to initialize worker", "error", err)
+		os.Exit(1)
+	}
+
+	server, err := app.NewServer(deps)
+	if err != nil {
+		slog.Error("failed to initialize server", "error", err)
+		os.Exit(1)
+	}
+
+	if err := worker.Start(); err != nil {
+		slog.Error("worker failed to start", "error", err)
+		os.Exit(1)
+	}
+	serverErr := server.Start()
+
+	// Hot reload: config file changes, SIGHUP, and runtime settings
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	app.WatchConfig(watchCtx, deps, config.RoleAll, logLevel, server, worker)
+
+	// Drain on SIGTSTP: no new tasks, in-flight ones finish, then idle until stopped
+	worker.DrainOnSignal(watchCtx)
+
+	// ==========================================
+	// Graceful Shutdown
+	// ==========================================
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	exitCode := 0
+	select {
+	case <-quit:
+	case err := <-serverErr:
+		slog.Error("server failed to start", "error", err)
+		exitCode = 1
+	}
+
+	slog.Info("shutting down...")
+
+	// Stop accepting requests first, then drain in-flight tasks
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("server forced to shutdown", "error", err)
+		exitCode = 1
+	}
+	worker.Shutdown()
+
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+	slog.Info("exited gracefully")
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+)
+
+const loadgenUsage = "Usage: notifly loadgen -api-key key [-url http://localhost:8081] [-n 1000] [-rate 100] [-c 20] [-type magic_link] [-data json] [-to pattern] [-wait 2m]\n"
+
+// loadgenOptions configures one load-generation run.
+type loadgenOptions struct {
+	url         string
+	apiKey      string
+	total       int
+	rate        float64
+	concurrency int
+	notifType   string
+	data        map[string]any
+	to          string
+	wait        time.Duration
+	timeout     time.Duration
+}
+
+// enqueued is a send the server accepted.
+type enqueued struct {
+	id       string
+	accepted time.Time
+}
+
+// runLoadgen enqueues synthetic sends against a running server and reports
+// enqueue and processing latencies. Point it at a deployment whose worker
+// uses email.provider=dryrun so nothing is delivered.
+func runLoadgen(args []string) int {
+	fs := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+	opts := loadgenOptions{}
+	fs.StringVar(&opts.url, "url", "http://localhost:8081", "server base URL")
+	fs.StringVar(&opts.apiKey, "api-key", "", "API key sent as X-API-Key (one of auth.api_keys)")
+	fs.IntVar(&opts.total, "n", 1000, "number of sends to enqueue")
+	fs.Float64Var(&opts.rate, "rate", 100, "sends started per second (0 = as fast as -c allows)")
+	fs.IntVar(&opts.concurrency, "c", 20, "concurrent requests")
+	fs.StringVar(&opts.notifType, "type", string(notification.TypeMagicLink), "notification type")
+	data := fs.String("data", `{"MagicLinkURL":"https://example.com/magic?token=loadgen"}`, "template data as JSON")
+	fs.StringVar(&opts.to, "to", "loadgen+{n}@example.com", "recipient pattern; {n} becomes a unique per-send value")
+	fs.DurationVar(&opts.wait, "wait", 2*time.Minute, "how long to wait for the worker to finish the sends (0 = skip)")
+	fs.DurationVar(&opts.timeout, "timeout", 10*time.Second, "timeout per HTTP request")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 || opts.apiKey == "" || opts.total < 1 || opts.concurrency < 1 || opts.rate < 0 {
+		fmt.Fprint(os.Stderr, loadgenUsage)
+		return 2
+	}
+	if !strings.Contains(opts.to, "{n}") {
+		slog.Error("-to must contain {n}, or every send goes to one recipient and hits its rate limit", "to", opts.to)
+		return 2
+	}
+	if err := json.Unmarshal([]byte(*data), &opts.data); err != nil {
+		slog.Error("-data is not a JSON object", "error", err)
+		return 2
+	}
+	opts.url = strings.TrimRight(opts.url, "/")
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	client := &http.Client{Timeout: opts.timeout}
+	sends, ok := enqueueLoad(ctx, client, opts)
+	if !ok {
+		return 1
+	}
+	if opts.wait <= 0 {
+		return 0
+	}
+	if !awaitProcessing(ctx, client, opts, sends) {
+		return 1
+	}
+	return 0
+}
+
+// enqueueLoad posts opts.total sends at opts.rate with opts.concurrency
+// workers, logs the enqueue report, and returns the accepted sends. It
+// returns false when nothing was accepted.
+func enqueueLoad(ctx context.Context, client *http.Client, opts loadgenOptions) ([]enqueued, bool) {
+	run := randomHex(4)
+	slog.Info("enqueueing", "run", run, "sends", opts.total, "rate", opts.rate, "concurrency", opts.concurrency, "url", opts.url)
+
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		var tick <-chan time.Time
+		if opts.rate > 0 {
+			ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.rate))
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for i := 0; i < opts.total; i++ {
+			if tick != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-tick:
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- i:
+			}
+		}
+	}()
+
+	var (
+		mu        sync.Mutex
+		sends     []enqueued
+		latencies []time.Duration
+		failures  = make(map[string]int) // by HTTP status or "error"
+	)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range opts.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				n := run + "-" + strconv.Itoa(i)
+				sent := time.Now()
+				id, outcome := enqueueOne(ctx, client, opts, n)
+				elapsed := time.Since(sent)
+
+				mu.Lock()
+				if id != "" {
+					sends = append(sends, enqueued{id: id, accepted: time.Now()})
+					latencies = append(latencies, elapsed)
+				} else {
+					failures[outcome]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	attempted := len(sends)
+	for _, count := range failures {
+		attempted += count
+	}
+	slog.Info("enqueue finished",
+		"attempted", attempted,
+		"accepted", len(sends),
+		"rejected", attempted-len(sends),
+		"elapsed", elapsed.Round(time.Millisecond).String(),
+		"accepted_per_sec", perSecond(len(sends), elapsed),
+	)
+	for outcome, count := range failures {
+		slog.Warn("enqueue failures", "outcome", outcome, "count", count)
+	}
+	if len(latencies) > 0 {
+		logPercentiles("enqueue latency", latencies)
+	}
+	return sends, len(sends) > 0
+}
+
+// enqueueOne posts one send and returns its log ID, or the failure outcome:
+// the response status, or "error" when no response arrived.
+func enqueueOne(ctx context.Context, client *http.Client, opts loadgenOptions, n string) (string, string) {
+	body, _ := json.Marshal(notification.SendRequest{
+		Channel:        notification.ChannelEmail,
+		Type:           notification.NotificationType(opts.notifType),
+		To:             notification.Recipients{strings.ReplaceAll(opts.to, "{n}", n)},
+		Data:           opts.data,
+		IdempotencyKey: "loadgen-" + n,
+	})
+
+	var resp struct {
+		Data notification.SendResponse `json:"data"`
+	}
+	status, err := callAPI(ctx, client, opts, "/api/v1/send", body, &resp)
+	if err != nil {
+		if status == 0 {
+			slog.Debug("enqueue failed", "error", err)
+			return "", "error"
+		}
+		return "", strconv.Itoa(status) + " " + http.StatusText(status)
+	}
+	return resp.Data.ID, ""
+}
+
+// awaitProcessing polls the statuses of sends until every one is final or
+// opts.wait passes, then logs the processing report. It returns false when
+// some sends did not finish.
+func awaitProcessing(ctx context.Context, client *http.Client, opts loadgenOptions, sends []enqueued) bool {
+	slog.Info("waiting for the worker", "sends", len(sends), "max_wait", opts.wait.String())
+
+	pending := make(map[string]time.Time, len(sends))
+	first := sends[0].accepted
+	for _, send := range sends {
+		pending[send.id] = send.accepted
+		if send.accepted.Before(first) {
+			first = send.accepted
+		}
+	}
+
+	var latencies []time.Duration
+	var last time.Time
+	byStatus := make(map[notification.NotificationStatus]int)
+	deadline := time.Now().Add(opts.wait)
+	for len(pending) > 0 && time.Now().Before(deadline) {
+		ids := make([]string, 0, len(pending))
+		for id := range pending {
+			ids = append(ids, id)
+		}
+		for start := 0; start < len(ids); start += notification.MaxStatusQuery {
+			chunk := ids[start:min(start+notification.MaxStatusQuery, len(ids))]
+			body, _ := json.Marshal(notification.StatusQuery{IDs: chunk})
+			var resp struct {
+				Data notification.StatusQueryResponse `json:"data"`
+			}
+			if _, err := callAPI(ctx, client, opts, "/api/v1/notifications/status", body, &resp); err != nil {
+				slog.Warn("status query failed", "error", err)
+				continue
+			}
+			for _, result := range resp.Data.Notifications {
+				if result.Status == notification.StatusQueued || result.Status == notification.StatusProcessing {
+					continue
+				}
+				// updated_at is when the worker finished; the server's and
+				// this host's clocks must agree for the latency to be exact
+				latencies = append(latencies, result.UpdatedAt.Sub(pending[result.ID]))
+				byStatus[result.Status]++
+				if result.UpdatedAt.After(last) {
+					last = result.UpdatedAt
+				}
+				delete(pending, result.ID)
+			}
+		}
+
+		if len(pending) == 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			slog.Warn("interrupted", "unfinished", len(pending))
+			return false
+		case <-time.After(time.Second):
+		}
+	}
+
+	done := len(sends) - len(pending)
+	slog.Info("processing finished",
+		"finished", done,
+		"unfinished", len(pending),
+		"processed_per_sec", perSecond(done, last.Sub(first)),
+	)
+	for status, count := range byStatus {
+		slog.Info("final status", "status", status, "count", count)
+	}
+	if len(latencies) > 0 {
+		logPercentiles("processing latency", latencies)
+	}
+	if len(pending) > 0 {
+		slog.Error("sends still unfinished after the wait — the worker is behind or stopped", "unfinished", len(pending))
+		return false
+	}
+	return true
+}
+
+// callAPI posts body to path and decodes a successful response into out. The
+// returned status is 0 when no response arrived.
+func callAPI(ctx context.Context, client *http.Client, opts loadgenOptions, path string, body []byte, out any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, opts.url+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", opts.apiKey)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	return resp.StatusCode, json.Unmarshal(data, out)
+}
+
+// logPercentiles logs the p50, p95, p99, and max of durations.
+func logPercentiles(msg string, durations []time.Duration) {
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	slog.Info(msg,
+		"count", len(durations),
+		"p50", percentile(durations, 50).String(),
+		"p95", percentile(durations, 95).String(),
+		"p99", percentile(durations, 99).String(),
+		"max", durations[len(durations)-1].Round(time.Millisecond).String(),
+	)
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1].Round(time.Millisecond)
+}
+
+// perSecond formats count over elapsed as a rate.
+func perSecond(count int, elapsed time.Duration) string {
+	if elapsed <= 0 {
+		return "n/a"
+	}
+	return strconv.FormatFloat(float64(count)/elapsed.Seconds(), 'f', 1, 64)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
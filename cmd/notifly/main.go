@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+const usage = `notifly — operational commands for the Notifly service
+
+Usage:
+  notifly <command> [subcommand] [flags]
+
+Commands:
+  templates validate   Render every registered template with sample data and report problems
+  migrate up           Apply pending database migrations
+  migrate status       List database migrations and when each was applied
+  migrate print        Print the migrations as one SQL script for the Supabase SQL editor
+  loadgen              Enqueue synthetic sends against a server and report latency percentiles
+  seed                 Fill a development database with sample keys, devices, and notification logs
+`
+
+func main() {
+	// Human-readable output: this is an interactive tool, not a service
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	}))
+	slog.SetDefault(logger)
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "templates":
+		os.Exit(runTemplates(os.Args[2:]))
+	case "migrate":
+		os.Exit(runMigrate(os.Args[2:]))
+	case "loadgen":
+		os.Exit(runLoadgen(os.Args[2:]))
+	case "seed":
+		os.Exit(runSeed(os.Args[2:]))
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, usage)
+	default:
+		slog.Error("unknown command", "command", os.Args[1])
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
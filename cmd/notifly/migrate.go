@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/badrkarrachai/notifly/internal/config"
+	"github.com/badrkarrachai/notifly/internal/infra/migrate"
+	"github.com/badrkarrachai/notifly/migrations"
+)
+
+const migrateUsage = `Usage:
+  notifly migrate up                Apply pending migrations
+  notifly migrate status            List migrations and when each was applied
+  notifly migrate print [-from N]   Print migrations from version N as one SQL script
+`
+
+// runMigrate dispatches "notifly migrate <subcommand>" and returns the exit code.
+func runMigrate(args []string) int {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	all, err := migrate.Load(migrations.FS)
+	if err != nil {
+		slog.Error("failed to load migrations", "error", err)
+		return 1
+	}
+
+	switch args[0] {
+	case "print":
+		fs := flag.NewFlagSet("migrate print", flag.ContinueOnError)
+		from := fs.Int("from", 1, "first migration version to print")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		fmt.Fprint(os.Stdout, migrate.Script(all, *from))
+		return 0
+	case "up", "status":
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	migrator, ok := newMigrator(all)
+	if !ok {
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if args[0] == "status" {
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			slog.Error("failed to read migration status", "error", err)
+			return 1
+		}
+		pending := 0
+		for _, status := range statuses {
+			if status.AppliedAt == nil {
+				pending++
+				slog.Info("pending", "migration", status.File())
+				continue
+			}
+			slog.Info("applied", "migration", status.File(), "at", status.AppliedAt.Format("2006-01-02 15:04:05 UTC"))
+		}
+		slog.Info("migration status", "total", len(statuses), "pending", pending)
+		return 0
+	}
+
+	applied, err := migrator.Up(ctx)
+	for _, migration := range applied {
+		slog.Info("applied", "migration", migration.File())
+	}
+	if err != nil {
+		slog.Error("migration failed — fix it and run notifly migrate up again", "error", err)
+		return 1
+	}
+	slog.Info("database is up to date", "applied", len(applied))
+	return 0
+}
+
+// newMigrator builds a migrator on the Supabase Management API from
+// configuration, logging what is missing when it cannot.
+func newMigrator(all []migrate.Migration) (*migrate.Migrator, bool) {
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("failed to load configuration", "error", err)
+		return nil, false
+	}
+
+	ref := cfg.Supabase.ProjectRef
+	if ref == "" {
+		ref = migrate.ProjectRef(cfg.Supabase.URL)
+	}
+	if ref == "" {
+		slog.Error("supabase.project_ref is required when supabase.url is not a hosted https://<ref>.supabase.co URL (NOTIFLY_SUPABASE_PROJECT_REF); " +
+			"for a self-hosted database, run the output of notifly migrate print in its SQL editor instead")
+		return nil, false
+	}
+	if cfg.Supabase.AccessToken == "" {
+		slog.Error("supabase.access_token is required to run migrations — create a personal access token in the Supabase dashboard (NOTIFLY_SUPABASE_ACCESS_TOKEN)")
+		return nil, false
+	}
+
+	db := migrate.NewManagementAPI(migrate.DefaultManagementURL, ref, cfg.Supabase.AccessToken)
+	return migrate.NewMigrator(db, all), true
+}
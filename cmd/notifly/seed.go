@@ -0,0 +1,423 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/badrkarrachai/notifly/internal/config"
+	"github.com/badrkarrachai/notifly/internal/infra/store"
+	"github.com/badrkarrachai/notifly/internal/middleware"
+	"github.com/badrkarrachai/notifly/pkg/notification"
+	"github.com/badrkarrachai/notifly/pkg/template"
+)
+
+const seedUsage = "Usage: notifly seed [-n 300] [-users 20] [-keys 2] [-c 8] [-templates-dir dir] [-force]\n"
+
+// seedStatuses weighs the final statuses of seeded sends: mostly delivered
+// and engaged, with enough failures and bounces to fill the failure views.
+var seedStatuses = []struct {
+	status notification.NotificationStatus
+	weight int
+}{
+	{notification.StatusDelivered, 34},
+	{notification.StatusOpened, 14},
+	{notification.StatusClicked, 9},
+	{notification.StatusSent, 10},
+	{notification.StatusFailed, 15},
+	{notification.StatusBounced, 8},
+	{notification.StatusComplained, 2},
+	{notification.StatusAbandoned, 3},
+	// archived is a delivered send archived afterwards
+	{"archived", 5},
+}
+
+// seedFailures are the failures a seeded failed send gets, with the provider
+// response that caused each.
+var seedFailures = []struct {
+	code      notification.FailureCode
+	message   string
+	retryable bool
+	status    int
+}{
+	{notification.FailureProvider5xx, "provider returned 503: service unavailable", true, 503},
+	{notification.FailureProvider4xx, "provider returned 422: invalid `to` field", false, 422},
+	{notification.FailureInvalidRecipient, "recipient address rejected", false, 422},
+	{notification.FailureTimeout, "context deadline exceeded", true, 0},
+	{notification.FailureSuppressed, "recipient is suppressed after a hard bounce", false, 0},
+	{notification.FailureRenderError, `template: magic_link: map has no entry for key "MagicLinkURL"`, false, 0},
+}
+
+// seedOptions configures one seed run.
+type seedOptions struct {
+	total        int
+	users        int
+	keys         int
+	concurrency  int
+	templatesDir string
+	force        bool
+}
+
+// seeder writes one run's fixtures. Every log it creates carries the tag
+// seed=<run>, so the data can be told apart from real sends.
+type seeder struct {
+	logs    *store.SupabaseStore
+	devices *store.DeviceStore
+	run     string
+	keyIDs  []string
+	types   []notification.NotificationType
+	tokens  map[string][]string // user ID -> device tokens
+
+	mu     sync.Mutex
+	counts map[notification.NotificationStatus]int
+	failed int
+}
+
+// runSeed fills the configured Supabase project with sample data for local
+// development: API keys to configure, devices for a few users, and a few
+// hundred notification logs in every status.
+func runSeed(args []string) int {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	opts := seedOptions{}
+	fs.IntVar(&opts.total, "n", 300, "number of sends to create logs for")
+	fs.IntVar(&opts.users, "users", 20, "number of users to register push devices for (0 = no push logs)")
+	fs.IntVar(&opts.keys, "keys", 2, "number of API keys to generate when auth.api_keys is empty")
+	fs.IntVar(&opts.concurrency, "c", 8, "concurrent sends written")
+	fs.StringVar(&opts.templatesDir, "templates-dir", "", "also copy the embedded templates into this directory, to edit and mount at /app/templates")
+	fs.BoolVar(&opts.force, "force", false, "seed even when server.mode is release")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 || opts.total < 0 || opts.users < 0 || opts.keys < 1 || opts.concurrency < 1 {
+		fmt.Fprint(os.Stderr, seedUsage)
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("failed to load configuration", "error", err)
+		return 1
+	}
+	if cfg.Server.Mode == "release" && !opts.force {
+		slog.Error("refusing to seed: server.mode is release, so this looks like a production project — pass -force if it is not")
+		return 1
+	}
+	if cfg.Supabase.URL == "" || cfg.Supabase.ServiceKey == "" {
+		slog.Error("supabase.url and supabase.service_key are required to seed (NOTIFLY_SUPABASE_URL, NOTIFLY_SUPABASE_SERVICE_KEY)")
+		return 1
+	}
+
+	if opts.templatesDir != "" {
+		copied, err := exportTemplates(opts.templatesDir)
+		if err != nil {
+			slog.Error("failed to copy templates", "dir", opts.templatesDir, "error", err)
+			return 1
+		}
+		slog.Info("templates copied", "dir", opts.templatesDir, "files", copied)
+	}
+
+	logs, err := store.NewSupabaseStore(cfg.Supabase.URL, cfg.Supabase.ServiceKey, store.CallConfig{
+		Timeout:    time.Duration(cfg.Supabase.TimeoutSec) * time.Second,
+		MaxRetries: cfg.Supabase.MaxRetries,
+		Backoff:    time.Duration(cfg.Supabase.RetryBackoffMs) * time.Millisecond,
+	})
+	if err != nil {
+		slog.Error("failed to initialize supabase store", "error", err)
+		return 1
+	}
+	logs.SetDataCompression(cfg.Supabase.CompressDataBytes)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	s := &seeder{
+		logs:    logs,
+		devices: store.NewDeviceStore(logs),
+		run:     randomHex(4),
+		types:   seedTypes(),
+		tokens:  make(map[string][]string),
+		counts:  make(map[notification.NotificationStatus]int),
+	}
+
+	// Sends are attributed to the configured keys, or to new ones to add to
+	// the configuration so usage reports show them
+	keys := cfg.Auth.APIKeys
+	if len(keys) == 0 {
+		for range opts.keys {
+			keys = append(keys, "dev_"+randomHex(16))
+		}
+		slog.Info("generated API keys — add this line to .env so the server accepts them and reports their usage",
+			"line", "NOTIFLY_AUTH_API_KEYS="+strings.Join(keys, ","))
+	}
+	for _, key := range keys {
+		s.keyIDs = append(s.keyIDs, middleware.APIKeyID(key))
+	}
+
+	slog.Info("seeding", "run", s.run, "sends", opts.total, "users", opts.users)
+	if err := s.seedDevices(ctx, opts.users); err != nil {
+		slog.Error("failed to register devices", "error", err)
+		return 1
+	}
+	s.seedLogs(ctx, opts.total, opts.concurrency)
+
+	for _, weighted := range seedStatuses {
+		slog.Info("seeded", "status", weighted.status, "logs", s.counts[weighted.status])
+	}
+	if s.failed > 0 {
+		slog.Error("some sends could not be seeded", "run", s.run, "failed", s.failed)
+		return 1
+	}
+	slog.Info("seed complete — every seeded log is tagged seed="+s.run, "run", s.run, "users", len(s.tokens))
+	return 0
+}
+
+// seedTypes returns the types sends are seeded for: every templated type but
+// the daily summary, which only goes to admins.
+func seedTypes() []notification.NotificationType {
+	var types []notification.NotificationType
+	for _, notifType := range template.Types() {
+		if notifType != notification.TypeDailySummary {
+			types = append(types, notifType)
+		}
+	}
+	return types
+}
+
+// seedDevices registers one or two push devices for each of n users.
+func (s *seeder) seedDevices(ctx context.Context, users int) error {
+	for i := range users {
+		userID := fmt.Sprintf("seed_%s_user_%d", s.run, i+1)
+		platform := notification.PlatformFCM
+		if i%3 == 0 {
+			platform = notification.PlatformAPNs
+		}
+		for range 1 + i%2 {
+			device := &notification.Device{
+				UserID:     userID,
+				Token:      "seed-" + randomHex(32),
+				Platform:   platform,
+				LastSeenAt: time.Now().Add(-time.Duration(rand.IntN(30*24)) * time.Hour),
+			}
+			if err := s.devices.UpsertDevice(ctx, device); err != nil {
+				return err
+			}
+			s.tokens[userID] = append(s.tokens[userID], device.Token)
+		}
+	}
+	return nil
+}
+
+// seedLogs creates logs for total sends over concurrency workers: about 60%
+// email, 25% SMS, and 15% push to the seeded users' devices.
+func (s *seeder) seedLogs(ctx context.Context, total, concurrency int) {
+	users := make([]string, 0, len(s.tokens))
+	for userID := range s.tokens {
+		users = append(users, userID)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range jobs {
+				status := pickStatus()
+				var err error
+				switch roll := rand.IntN(100); {
+				case roll < 15 && len(users) > 0:
+					err = s.seedPush(ctx, n, users[rand.IntN(len(users))], status)
+				case roll < 40:
+					err = s.seedSend(ctx, s.newLog(n, notification.ChannelSMS, fmt.Sprintf("+1555%07d", n)), status)
+				default:
+					err = s.seedSend(ctx, s.newLog(n, notification.ChannelEmail, fmt.Sprintf("seed+%s-%d@example.com", s.run, n)), status)
+				}
+
+				s.mu.Lock()
+				if err != nil {
+					s.failed++
+					slog.Warn("seeding send failed", "n", n, "error", err)
+				} else {
+					s.counts[status]++
+				}
+				s.mu.Unlock()
+			}
+		}()
+	}
+
+	for n := range total {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- n
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// pickStatus draws a final status by the weights of seedStatuses.
+func pickStatus() notification.NotificationStatus {
+	total := 0
+	for _, weighted := range seedStatuses {
+		total += weighted.weight
+	}
+	roll := rand.IntN(total)
+	for _, weighted := range seedStatuses {
+		if roll < weighted.weight {
+			return weighted.status
+		}
+		roll -= weighted.weight
+	}
+	return notification.StatusDelivered
+}
+
+// newLog returns the queued log of seeded send n on channel to recipient.
+func (s *seeder) newLog(n int, channel notification.Channel, recipient string) *notification.NotificationLog {
+	notifType := s.types[rand.IntN(len(s.types))]
+	return &notification.NotificationLog{
+		Channel:      string(channel),
+		Type:         string(notifType),
+		Recipient:    recipient,
+		APIKeyID:     s.keyIDs[n%len(s.keyIDs)],
+		Tags:         map[string]string{"seed": s.run},
+		TemplateData: template.SampleData(notifType),
+		Status:       notification.StatusQueued,
+	}
+}
+
+// seedPush creates a push to a user's devices: a parent log, and a child per
+// device that goes through status. The parent takes the children's status,
+// as the worker sets it.
+func (s *seeder) seedPush(ctx context.Context, n int, userID string, status notification.NotificationStatus) error {
+	parent := s.newLog(n, notification.ChannelPush, userID)
+	parent.UserID = userID
+	if err := s.logs.Create(ctx, parent); err != nil {
+		return err
+	}
+
+	for _, token := range s.tokens[userID] {
+		child := *parent
+		child.ID = ""
+		child.ParentID = parent.ID
+		child.Recipient = token
+		if err := s.seedSend(ctx, &child, status); err != nil {
+			return err
+		}
+	}
+
+	if status == "archived" {
+		if err := s.logs.UpdateStatus(ctx, parent.ID, notification.StatusDelivered, "", ""); err != nil {
+			return err
+		}
+		return s.logs.ArchiveLogs(ctx, []string{parent.ID}, time.Now())
+	}
+	return s.logs.UpdateStatus(ctx, parent.ID, status, "", "")
+}
+
+// seedSend creates notifLog and moves it to status the way the worker and the
+// provider webhooks would.
+func (s *seeder) seedSend(ctx context.Context, notifLog *notification.NotificationLog, status notification.NotificationStatus) error {
+	if err := s.logs.Create(ctx, notifLog); err != nil {
+		return err
+	}
+
+	switch status {
+	case notification.StatusFailed:
+		failure := seedFailures[rand.IntN(len(seedFailures))]
+		var metadata *notification.ProviderMetadata
+		if failure.status != 0 {
+			metadata = &notification.ProviderMetadata{StatusCode: failure.status, Attempts: 1}
+		}
+		return s.logs.RecordFailure(ctx, notifLog.ID, failure.message, failure.code, failure.retryable, metadata)
+	case notification.StatusAbandoned:
+		return s.logs.UpdateStatus(ctx, notifLog.ID, notification.StatusAbandoned, "", "abandoned after 3 recovery attempts")
+	}
+
+	providerID := fmt.Sprintf("seed_%s_%s", s.run, randomHex(8))
+	cost := 0.0004
+	switch notifLog.Channel {
+	case string(notification.ChannelSMS):
+		cost = 0.0079
+	case string(notification.ChannelPush):
+		cost = 0
+	}
+	if err := s.logs.RecordSent(ctx, notifLog.ID, providerID, &cost, &notification.ProviderMetadata{StatusCode: 200, Attempts: 1}); err != nil {
+		return err
+	}
+
+	// The webhook events that lead to status, in the order providers send them
+	var events []notification.NotificationStatus
+	var bounceType notification.BounceType
+	switch status {
+	case notification.StatusDelivered, "archived":
+		events = []notification.NotificationStatus{notification.StatusDelivered}
+	case notification.StatusOpened:
+		events = []notification.NotificationStatus{notification.StatusDelivered, notification.StatusOpened}
+	case notification.StatusClicked:
+		events = []notification.NotificationStatus{notification.StatusDelivered, notification.StatusOpened, notification.StatusClicked}
+	case notification.StatusComplained:
+		events = []notification.NotificationStatus{notification.StatusDelivered, notification.StatusComplained}
+	case notification.StatusBounced:
+		events = []notification.NotificationStatus{notification.StatusBounced}
+		bounceType = notification.BounceHard
+		if rand.IntN(3) == 0 {
+			bounceType = notification.BounceSoft
+		}
+	}
+	for _, event := range events {
+		if _, err := s.logs.UpdateWebhookStatus(ctx, providerID, event, bounceType); err != nil {
+			return err
+		}
+	}
+
+	if status == "archived" && notifLog.ParentID == "" {
+		return s.logs.ArchiveLogs(ctx, []string{notifLog.ID}, time.Now())
+	}
+	return nil
+}
+
+// exportTemplates copies the embedded templates into dir, creating it, and
+// returns how many files it wrote. It overwrites nothing.
+func exportTemplates(dir string) (int, error) {
+	embedded := template.Embedded()
+	copied := 0
+	err := fs.WalkDir(embedded, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+
+		content, err := fs.ReadFile(embedded, name)
+		if err != nil {
+			return err
+		}
+		file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("%s already exists — copy into an empty directory", target)
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(content); err != nil {
+			file.Close()
+			return err
+		}
+		copied++
+		return file.Close()
+	})
+	return copied, err
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/badrkarrachai/notifly/internal/app"
+	"github.com/badrkarrachai/notifly/pkg/template"
+)
+
+// runTemplates dispatches "notifly templates <subcommand>" and returns the exit code.
+func runTemplates(args []string) int {
+	if len(args) < 1 || args[0] != "validate" {
+		fmt.Fprint(os.Stderr, "Usage: notifly templates validate [-dir path]\n")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("templates validate", flag.ContinueOnError)
+	dir := fs.String("dir", "", "templates directory (default: /app/templates if present, else the embedded templates)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		slog.Error("unexpected arguments — pass the directory with -dir", "args", fs.Args())
+		fmt.Fprint(os.Stderr, "Usage: notifly templates validate [-dir path]\n")
+		return 2
+	}
+
+	templatesFS, source := app.ResolveTemplates()
+	if *dir != "" {
+		templatesFS, source = os.DirFS(*dir), *dir
+	}
+
+	issues, err := template.ValidateFS(templatesFS)
+	if err != nil {
+		slog.Error("failed to load templates", "source", source, "error", err)
+		return 1
+	}
+
+	if len(issues) == 0 {
+		slog.Info("all templates valid", "source", source)
+		return 0
+	}
+
+	for _, issue := range issues {
+		slog.Error("template issue",
+			"template", issue.Template,
+			"type", issue.Type,
+			"message", issue.Message,
+		)
+	}
+	slog.Error("template validation failed", "source", source, "issues", len(issues))
+	return 1
+}
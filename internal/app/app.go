@@ -0,0 +1,645 @@
+// Package app wires Notifly's components into runnable server and worker roles.
+//
+// Entry points in cmd/ load configuration, build Deps once, and start whichever
+// roles they run. Wiring stays manual and explicit — every dependency is
+// constructed here and injected — but lives in one place so the standalone
+// binaries and the combined single-binary mode cannot drift apart.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/badrkarrachai/notifly/internal/config"
+	"github.com/badrkarrachai/notifly/internal/infra/alert"
+	"github.com/badrkarrachai/notifly/internal/infra/fault"
+	"github.com/badrkarrachai/notifly/internal/infra/lock"
+	"github.com/badrkarrachai/notifly/internal/infra/metrics"
+	"github.com/badrkarrachai/notifly/internal/infra/queue"
+	"github.com/badrkarrachai/notifly/internal/infra/store"
+	"github.com/badrkarrachai/notifly/internal/infra/tracking"
+	"github.com/badrkarrachai/notifly/internal/middleware"
+	"github.com/badrkarrachai/notifly/pkg/notification"
+	"github.com/badrkarrachai/notifly/pkg/settings"
+	"github.com/badrkarrachai/notifly/pkg/template"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	_ notification.Enqueuer            = (*queueEnqueuer)(nil)
+	_ notification.BatchEnqueuer       = (*queueEnqueuer)(nil)
+	_ notification.CriticalEnqueuer    = (*queueEnqueuer)(nil)
+	_ notification.DigestEnqueuer      = (*queueEnqueuer)(nil)
+	_ notification.ErasureEnqueuer     = (*queueEnqueuer)(nil)
+	_ notification.CampaignEnqueuer    = (*queueEnqueuer)(nil)
+	_ notification.FallbackEnqueuer    = (*queueEnqueuer)(nil)
+	_ notification.EscalationEnqueuer  = (*queueEnqueuer)(nil)
+	_ notification.FollowUpEnqueuer    = (*queueEnqueuer)(nil)
+	_ notification.BounceRetryEnqueuer = (*queueEnqueuer)(nil)
+)
+
+// queueEnqueuer adapts the asynq client to the notification.Enqueuer,
+// notification.BatchEnqueuer, notification.CriticalEnqueuer,
+// notification.DigestEnqueuer, notification.ErasureEnqueuer,
+// notification.CampaignEnqueuer, notification.FallbackEnqueuer,
+// notification.EscalationEnqueuer, notification.FollowUpEnqueuer, and
+// notification.BounceRetryEnqueuer interfaces.
+type queueEnqueuer struct {
+	client    *asynq.Client
+	inspector *asynq.Inspector
+	maxRetry  int
+	timeout   time.Duration
+}
+
+func (q *queueEnqueuer) EnqueueSendNotification(logID string) error {
+	return queue.EnqueueSendNotification(q.client, q.inspector, queue.NotificationsQueue, logID, q.maxRetry, q.timeout)
+}
+
+func (q *queueEnqueuer) EnqueueSendBatch(logIDs []string) error {
+	return queue.EnqueueSendBatch(q.client, queue.NotificationsQueue, logIDs, q.maxRetry, q.timeout)
+}
+
+func (q *queueEnqueuer) EnqueueCriticalSend(logID string) error {
+	return queue.EnqueueSendNotification(q.client, q.inspector, queue.CriticalQueue, logID, q.maxRetry, q.timeout)
+}
+
+func (q *queueEnqueuer) EnqueueCriticalBatch(logIDs []string) error {
+	return queue.EnqueueSendBatch(q.client, queue.CriticalQueue, logIDs, q.maxRetry, q.timeout)
+}
+
+func (q *queueEnqueuer) EnqueueDigestEvent(group, logID string) error {
+	return queue.EnqueueDigestEvent(q.client, group, logID)
+}
+
+func (q *queueEnqueuer) EnqueueCampaignSend(logID string) error {
+	return queue.EnqueueSendNotification(q.client, q.inspector, queue.CampaignsQueue, logID, q.maxRetry, q.timeout)
+}
+
+func (q *queueEnqueuer) EnqueueErasure(jobID, recipient string) error {
+	return queue.EnqueueErasure(q.client, jobID, recipient, q.maxRetry, q.timeout)
+}
+
+func (q *queueEnqueuer) EnqueueCampaignDispatch(campaignID string, cursor int, delay time.Duration) error {
+	return queue.EnqueueCampaignDispatch(q.client, campaignID, cursor, delay, q.maxRetry, q.timeout)
+}
+
+func (q *queueEnqueuer) EnqueueFallback(logID string, delay time.Duration) error {
+	return queue.EnqueueFallback(q.client, logID, delay, q.maxRetry, q.timeout)
+}
+
+func (q *queueEnqueuer) EnqueueEscalationStep(logID string, step int, delay time.Duration) error {
+	return queue.EnqueueEscalationStep(q.client, logID, step, delay, q.maxRetry, q.timeout)
+}
+
+func (q *queueEnqueuer) EnqueueFollowUp(logID, sequenceID string, step int, delay time.Duration) error {
+	return queue.EnqueueFollowUp(q.client, logID, sequenceID, step, delay, q.maxRetry, q.timeout)
+}
+
+func (q *queueEnqueuer) EnqueueBounceRetry(logID string, retry int, delay time.Duration) error {
+	return queue.EnqueueBounceRetry(q.client, logID, retry, delay, q.maxRetry, q.timeout)
+}
+
+// Deps holds the infrastructure shared by the server and worker roles.
+// In combined mode both roles use the same store and queue client.
+type Deps struct {
+	Config   *config.Config
+	Store    *store.SupabaseStore
+	Queue    *asynq.Client
+	Enqueuer notification.Enqueuer
+	Tracker  notification.LinkTracker
+	Settings *settings.Service
+
+	// Templates renders notifications: in the worker, or in the server when
+	// templates.render_at_enqueue is on.
+	Templates *template.Engine
+
+	// Variants holds the A/B tests of templates: the server and campaigns
+	// pick a log's variant, the worker renders it, and stats report it.
+	Variants *notification.Variants
+
+	// Senders holds the email sender identities: the server records a log's
+	// sender, the worker sends from it.
+	Senders *notification.Senders
+
+	// QueueControl pauses and resumes the notifications queue for every worker.
+	QueueControl *queue.Controller
+
+	// Eraser handles recipient data erasure: the server creates jobs, the worker runs them.
+	Eraser *notification.Eraser
+
+	// Campaigner manages campaigns: the server creates and controls them, the
+	// worker fans them out.
+	Campaigner *notification.Campaigner
+
+	// Devices is the push device token registry: the server registers tokens,
+	// the worker removes the ones push services report as invalid.
+	Devices *notification.Devices
+
+	// Escalations holds the escalation policies: the server manages them and
+	// starts escalations, the worker runs their steps.
+	Escalations *notification.Escalations
+
+	// FollowUps holds the follow-up sequences: the server manages them and
+	// starts them, the worker checks their steps.
+	FollowUps *notification.FollowUps
+
+	// Latency holds the delivery latency histograms in Redis: the worker
+	// observes sends, the server webhook events, and both roles report them.
+	Latency *metrics.RedisLatency
+
+	// Outcomes counts sends and bounces per minute in Redis for alerting: the
+	// worker counts sends, the server bounces, and the worker's alerter reads them.
+	Outcomes *metrics.RedisOutcomes
+
+	// Costs totals estimated send costs per day in Redis: the worker records
+	// costed sends, the server reports them in stats.
+	Costs *metrics.RedisCosts
+
+	// Reaper runs on a timer in the worker role; the server role uses it for
+	// manual sweeps and to report sweep stats.
+	Reaper      *notification.Reaper
+	reaperLock  *lock.RedisLock
+	reaperStats *metrics.RedisReaperStats
+
+	logCache  *store.RedisLogCache // nil unless cache.backend is redis
+	inspector *asynq.Inspector     // resolves the Enqueuer's task ID conflicts
+}
+
+// NewDeps constructs the shared infrastructure from configuration.
+func NewDeps(cfg *config.Config) (*Deps, error) {
+	// Template Engine (embedded templates unless overridden on disk)
+	tmplEngine, err := newTemplateEngine()
+	if err != nil {
+		return nil, err
+	}
+	tmplEngine.SetSMSLimits(cfg.Templates.SMSMaxSegments, cfg.Templates.SMSTruncate)
+	tmplEngine.SetSanitizedTypes(sanitizedTypes(cfg))
+	tmplEngine.SetSubjects(subjectOverrides(cfg))
+	tmplEngine.SetDefaultLocale(cfg.Templates.DefaultLocale)
+
+	if cfg.Faults.Enabled {
+		slog.Warn("fault injection enabled — for staging only",
+			"provider_error_rate", cfg.Faults.ProviderErrorRate,
+			"store_latency_ms", cfg.Faults.StoreLatencyMs,
+			"store_latency_rate", cfg.Faults.StoreLatencyRate,
+			"redis_error_rate", cfg.Faults.RedisErrorRate,
+		)
+	}
+
+	// Supabase Store
+	notifStore, err := store.NewSupabaseStore(cfg.Supabase.URL, cfg.Supabase.ServiceKey, storeCallConfig(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("initializing supabase store: %w", err)
+	}
+	notifStore.SetDataCompression(cfg.Supabase.CompressDataBytes)
+	if cfg.Supabase.ReadReplicaURL != "" {
+		if err := notifStore.SetReadReplica(cfg.Supabase.ReadReplicaURL, cfg.Supabase.ServiceKey); err != nil {
+			return nil, err
+		}
+		slog.Info("supabase read replica enabled for listings and stats")
+	}
+	slog.Info("supabase store initialized", "timeout_sec", cfg.Supabase.TimeoutSec, "max_retries", cfg.Supabase.MaxRetries)
+
+	// Log read cache (optional) — shared by the stores created from notifStore
+	var logCache *store.RedisLogCache
+	switch cfg.Cache.Backend {
+	case config.CacheBackendMemory:
+		notifStore.SetCache(store.NewMemoryLogCache(cfg.Cache.MaxEntries), time.Duration(cfg.Cache.TTLSec)*time.Second)
+		slog.Info("log cache enabled", "backend", cfg.Cache.Backend, "ttl_sec", cfg.Cache.TTLSec)
+	case config.CacheBackendRedis:
+		logCache = store.NewRedisLogCache(cfg.Redis.Address, cfg.Redis.Password, cfg.Redis.DB)
+		notifStore.SetCache(logCache, time.Duration(cfg.Cache.TTLSec)*time.Second)
+		slog.Info("log cache enabled", "backend", cfg.Cache.Backend, "ttl_sec", cfg.Cache.TTLSec)
+	}
+
+	// Asynq Client (for enqueuing tasks)
+	asynqClient := queue.NewClient(cfg.Redis.Address, cfg.Redis.Password, cfg.Redis.DB, redisFaultHooks(cfg)...)
+	slog.Info("asynq client initialized", "redis", cfg.Redis.Address)
+
+	// Click tracker (optional) — the worker rewrites links, the server resolves them
+	var linkTracker notification.LinkTracker
+	if cfg.Tracking.ClickEnabled {
+		linkTracker = tracking.NewClickTracker(cfg.Tracking.BaseURL, cfg.Tracking.Secret)
+		slog.Info("click tracking enabled", "base_url", cfg.Tracking.BaseURL)
+	}
+
+	// The inspector resolves task ID conflicts of sends enqueued twice
+	inspector := queue.NewInspector(cfg.Redis.Address, cfg.Redis.Password, cfg.Redis.DB)
+	enqueuer := &queueEnqueuer{
+		client:    asynqClient,
+		inspector: inspector,
+		maxRetry:  cfg.Queue.MaxRetry,
+		timeout:   taskTimeout(cfg),
+	}
+
+	// Stale Task Reaper — the Redis lock keeps replicas from sweeping concurrently,
+	// and sweep totals are kept in Redis so the API can report them
+	reaperLock := lock.NewRedisLock(cfg.Redis.Address, cfg.Redis.Password, cfg.Redis.DB, reaperLockKey)
+	reaperStats := metrics.NewRedisReaperStats(cfg.Redis.Address, cfg.Redis.Password, cfg.Redis.DB)
+	queueControl := queue.NewController(cfg.Redis.Address, cfg.Redis.Password, cfg.Redis.DB)
+	reaper := notification.NewReaper(notifStore, enqueuer, reaperLock, reaperStats, queueControl, reaperConfig(cfg))
+
+	// A/B tests of templates
+	variants := notification.NewVariants(templateVariants(cfg))
+	if len(cfg.Templates.Variants) > 0 {
+		slog.Info("template a/b tests enabled", "types", len(cfg.Templates.Variants))
+	}
+	campaigner := notification.NewCampaigner(store.NewCampaignStore(notifStore), notifStore, enqueuer, tmplEngine, campaignConfig(cfg))
+	campaigner.SetVariants(variants)
+
+	return &Deps{
+		Config:   cfg,
+		Store:    notifStore,
+		Queue:    asynqClient,
+		Enqueuer: enqueuer,
+		Tracker:  linkTracker,
+		Settings: settings.NewService(store.NewSettingsStore(notifStore)),
+
+		Templates: tmplEngine,
+		Variants:  variants,
+		Senders:   newSenders(cfg),
+
+		QueueControl: queueControl,
+		Eraser:       notification.NewEraser(store.NewErasureStore(notifStore), enqueuer),
+		Campaigner:   campaigner,
+		Devices:      notification.NewDevices(store.NewDeviceStore(notifStore)),
+		Escalations:  notification.NewEscalations(store.NewEscalationPolicyStore(notifStore), notifStore, enqueuer),
+		FollowUps:    notification.NewFollowUps(store.NewFollowUpStore(notifStore), notifStore, enqueuer),
+		Latency:      metrics.NewRedisLatency(cfg.Redis.Address, cfg.Redis.Password, cfg.Redis.DB),
+		Outcomes:     metrics.NewRedisOutcomes(cfg.Redis.Address, cfg.Redis.Password, cfg.Redis.DB),
+		Costs:        metrics.NewRedisCosts(cfg.Redis.Address, cfg.Redis.Password, cfg.Redis.DB),
+
+		Reaper:      reaper,
+		reaperLock:  reaperLock,
+		reaperStats: reaperStats,
+
+		logCache:  logCache,
+		inspector: inspector,
+	}, nil
+}
+
+// Close releases shared connections.
+func (d *Deps) Close() {
+	if err := d.Queue.Close(); err != nil {
+		slog.Error("failed to close asynq client", "error", err)
+	}
+	if err := d.QueueControl.Close(); err != nil {
+		slog.Error("failed to close queue controller", "error", err)
+	}
+	if err := d.inspector.Close(); err != nil {
+		slog.Error("failed to close queue inspector", "error", err)
+	}
+	if err := d.reaperLock.Close(); err != nil {
+		slog.Error("failed to close reaper lock", "error", err)
+	}
+	if err := d.reaperStats.Close(); err != nil {
+		slog.Error("failed to close reaper stats", "error", err)
+	}
+	if err := d.Latency.Close(); err != nil {
+		slog.Error("failed to close latency metrics", "error", err)
+	}
+	if err := d.Outcomes.Close(); err != nil {
+		slog.Error("failed to close outcome metrics", "error", err)
+	}
+	if err := d.Costs.Close(); err != nil {
+		slog.Error("failed to close cost metrics", "error", err)
+	}
+	if d.logCache != nil {
+		if err := d.logCache.Close(); err != nil {
+			slog.Error("failed to close log cache", "error", err)
+		}
+	}
+}
+
+// reaperLockKey is the Redis key the reaper replicas compete for.
+const reaperLockKey = "notifly:lock:reaper"
+
+func storeCallConfig(cfg *config.Config) store.CallConfig {
+	callConfig := store.CallConfig{
+		Timeout:    time.Duration(cfg.Supabase.TimeoutSec) * time.Second,
+		MaxRetries: cfg.Supabase.MaxRetries,
+		Backoff:    time.Duration(cfg.Supabase.RetryBackoffMs) * time.Millisecond,
+	}
+	if cfg.Faults.Enabled && cfg.Faults.StoreLatencyRate > 0 {
+		callConfig.Fault = fault.StoreLatency(time.Duration(cfg.Faults.StoreLatencyMs)*time.Millisecond, cfg.Faults.StoreLatencyRate)
+	}
+	return callConfig
+}
+
+// redisFaultHooks returns the hooks that inject Redis errors, or none when
+// fault injection is off.
+func redisFaultHooks(cfg *config.Config) []redis.Hook {
+	if !cfg.Faults.Enabled || cfg.Faults.RedisErrorRate <= 0 {
+		return nil
+	}
+	return []redis.Hook{fault.NewRedisHook(cfg.Faults.RedisErrorRate)}
+}
+
+func reaperConfig(cfg *config.Config) notification.ReaperConfig {
+	return notification.ReaperConfig{
+		Interval:       time.Duration(cfg.Reaper.IntervalSec) * time.Second,
+		StaleThreshold: time.Duration(cfg.Reaper.StaleThresholdSec) * time.Second,
+		BatchSize:      cfg.Reaper.BatchSize,
+
+		MaxRecoveryAttempts: cfg.Reaper.MaxRecoveryAttempts,
+	}
+}
+
+func campaignConfig(cfg *config.Config) notification.CampaignConfig {
+	return notification.CampaignConfig{
+		BatchSize:       cfg.Campaigns.BatchSize,
+		BatchInterval:   time.Duration(cfg.Campaigns.BatchIntervalSec) * time.Second,
+		MaxAudience:     cfg.Campaigns.MaxAudience,
+		MaxDataSize:     cfg.Validation.MaxDataBytes,
+		SuppressBounced: cfg.Suppression.Bounced,
+		RenderAtEnqueue: cfg.Templates.RenderAtEnqueue,
+
+		ProgressInterval: time.Duration(cfg.Campaigns.ProgressIntervalSec) * time.Second,
+	}
+}
+
+// prices converts the configured price table.
+func prices(cfg *config.Config) notification.Prices {
+	out := make(notification.Prices, len(cfg.Costs.Prices))
+	for channel, byProvider := range cfg.Costs.Prices {
+		out[notification.Channel(channel)] = byProvider
+	}
+	return out
+}
+
+// queues converts the configured per-queue concurrency, weights, and retry
+// backoffs.
+func queues(cfg *config.Config) map[string]queue.QueueConfig {
+	out := make(map[string]queue.QueueConfig, len(cfg.Queue.Queues))
+	for name, q := range cfg.Queue.Queues {
+		qc := queue.QueueConfig{Concurrency: q.Concurrency, Weight: q.Weight}
+		if q.Retry != nil {
+			retry := retryBackoff(*q.Retry)
+			qc.Retry = &retry
+		}
+		out[name] = qc
+	}
+	return out
+}
+
+// retryBackoff converts a configured retry backoff.
+func retryBackoff(b config.RetryBackoff) queue.Backoff {
+	backoff := queue.Backoff{
+		Delay:      time.Duration(b.DelaySec) * time.Second,
+		Multiplier: b.Multiplier,
+		MaxDelay:   time.Duration(b.MaxDelaySec) * time.Second,
+		Jitter:     b.Jitter,
+	}
+	for _, sec := range b.ScheduleSec {
+		backoff.Schedule = append(backoff.Schedule, time.Duration(sec)*time.Second)
+	}
+	return backoff
+}
+
+// digestConfig converts the configured digest grouping limits.
+func digestConfig(cfg *config.Config) queue.DigestConfig {
+	return queue.DigestConfig{
+		GracePeriod: time.Duration(cfg.Digests.GracePeriodSec) * time.Second,
+		MaxDelay:    time.Duration(cfg.Digests.MaxDelaySec) * time.Second,
+		MaxSize:     cfg.Digests.MaxSize,
+		MaxRetry:    cfg.Queue.MaxRetry,
+		Timeout:     taskTimeout(cfg),
+	}
+}
+
+// digestTypes converts the digested notification types.
+func digestTypes(cfg *config.Config) []notification.NotificationType {
+	types := make([]notification.NotificationType, len(cfg.Digests.Types))
+	for i, t := range cfg.Digests.Types {
+		types[i] = notification.NotificationType(t)
+	}
+	return types
+}
+
+// usage converts the configured usage report settings, reporting on the
+// configured API keys.
+func usage(cfg *config.Config) notification.UsageConfig {
+	ids := make([]string, len(cfg.Auth.APIKeys))
+	for i, key := range cfg.Auth.APIKeys {
+		ids[i] = middleware.APIKeyID(key)
+	}
+	return notification.UsageConfig{
+		APIKeyIDs:  ids,
+		BillingDay: cfg.Usage.BillingDay,
+		Quotas:     cfg.Usage.Quotas,
+	}
+}
+
+// softBounceRetry converts the configured soft bounce retry policy.
+func softBounceRetry(cfg *config.Config) notification.SoftBounceRetry {
+	return notification.SoftBounceRetry{
+		MaxRetries: cfg.Suppression.SoftBounceRetries,
+		Delay:      time.Duration(cfg.Suppression.SoftBounceDelaySec) * time.Second,
+	}
+}
+
+// fallbackRules converts the configured fallback rules.
+func fallbackRules(cfg *config.Config) notification.FallbackRules {
+	rules := make(notification.FallbackRules, len(cfg.Fallbacks))
+	for key, rule := range cfg.Fallbacks {
+		rules[key] = notification.FallbackRule{
+			Channel: notification.Channel(rule.Channel),
+			After:   time.Duration(rule.AfterSec) * time.Second,
+			Until:   notification.NotificationStatus(rule.Until),
+		}
+	}
+	return rules
+}
+
+// dailyReportConfig converts the daily summary report settings.
+func dailyReportConfig(cfg *config.Config) notification.DailyReportConfig {
+	report := notification.DailyReportConfig{
+		Recipients: cfg.Reports.DailySummary.Recipients,
+		Hour:       cfg.Reports.DailySummary.HourUTC,
+		Quotas:     make(map[notification.Channel]int64, len(cfg.Reports.DailySummary.Quotas)),
+	}
+	for channel, limit := range cfg.Reports.DailySummary.Quotas {
+		report.Quotas[notification.Channel(channel)] = limit
+	}
+	return report
+}
+
+// alertConfig converts the alerting timings and rules.
+func alertConfig(cfg *config.Config) notification.AlertConfig {
+	alerts := notification.AlertConfig{
+		Interval: time.Duration(cfg.Alerts.IntervalSec) * time.Second,
+		Window:   time.Duration(cfg.Alerts.WindowSec) * time.Second,
+		Cooldown: time.Duration(cfg.Alerts.CooldownSec) * time.Second,
+	}
+	for key, rule := range cfg.Alerts.Rules {
+		alerts.Rules = append(alerts.Rules, notification.AlertRule{
+			Key:         key,
+			FailureRate: rule.FailureRate,
+			BounceRate:  rule.BounceRate,
+			MinVolume:   rule.MinVolume,
+		})
+	}
+	return alerts
+}
+
+// alertActions builds the configured alert actions.
+func alertActions(cfg *config.Config) []notification.AlertAction {
+	var actions []notification.AlertAction
+	if cfg.Alerts.Slack.WebhookURL != "" {
+		actions = append(actions, alert.NewSlack(cfg.Alerts.Slack.WebhookURL))
+	}
+	if cfg.Alerts.PagerDuty.RoutingKey != "" {
+		actions = append(actions, alert.NewPagerDuty(cfg.Alerts.PagerDuty.RoutingKey, cfg.Alerts.PagerDuty.Severity))
+	}
+	if cfg.Alerts.Webhook.URL != "" {
+		actions = append(actions, alert.NewWebhook(cfg.Alerts.Webhook.URL, cfg.Alerts.Webhook.Headers))
+	}
+	return actions
+}
+
+// sanitizedTypes converts the types whose template data is sanitized.
+func sanitizedTypes(cfg *config.Config) []notification.NotificationType {
+	types := make([]notification.NotificationType, len(cfg.Templates.SanitizeTypes))
+	for i, t := range cfg.Templates.SanitizeTypes {
+		types[i] = notification.NotificationType(t)
+	}
+	return types
+}
+
+// criticalTypes converts the types sent through the critical queue.
+func criticalTypes(cfg *config.Config) []notification.NotificationType {
+	types := make([]notification.NotificationType, len(cfg.Queue.CriticalTypes))
+	for i, t := range cfg.Queue.CriticalTypes {
+		types[i] = notification.NotificationType(t)
+	}
+	return types
+}
+
+// templateVariants converts the A/B tests of templates.
+func templateVariants(cfg *config.Config) map[notification.NotificationType][]notification.TemplateVariant {
+	tests := make(map[notification.NotificationType][]notification.TemplateVariant, len(cfg.Templates.Variants))
+	for t, variants := range cfg.Templates.Variants {
+		test := make([]notification.TemplateVariant, len(variants))
+		for i, variant := range variants {
+			test[i] = notification.TemplateVariant{Name: variant.Name, Percent: variant.Percent, Subject: variant.Subject}
+		}
+		tests[notification.NotificationType(t)] = test
+	}
+	return tests
+}
+
+// subjectOverrides converts the subjects configured to replace the built-in ones.
+func subjectOverrides(cfg *config.Config) map[notification.NotificationType]string {
+	subjects := make(map[notification.NotificationType]string, len(cfg.Templates.Overrides))
+	for t, override := range cfg.Templates.Overrides {
+		if override.Subject != "" {
+			subjects[notification.NotificationType(t)] = override.Subject
+		}
+	}
+	return subjects
+}
+
+// fromNames converts the sender display names configured per type.
+func fromNames(cfg *config.Config) map[notification.NotificationType]string {
+	names := make(map[notification.NotificationType]string, len(cfg.Templates.Overrides))
+	for t, override := range cfg.Templates.Overrides {
+		if override.FromName != "" {
+			names[notification.NotificationType(t)] = override.FromName
+		}
+	}
+	return names
+}
+
+// newSenders creates the sender identities, type defaults, and per-type
+// display names.
+func newSenders(cfg *config.Config) *notification.Senders {
+	s := notification.NewSenders(senders(cfg))
+	s.SetFromNames(cfg.Email.FromAddress, fromNames(cfg))
+	return s
+}
+
+// senders converts the sender identities and their type defaults.
+func senders(cfg *config.Config) (map[string]notification.Sender, map[notification.NotificationType]string) {
+	identities := make(map[string]notification.Sender, len(cfg.Email.Senders))
+	for name, sender := range cfg.Email.Senders {
+		identities[name] = notification.Sender{Address: sender.Address, Name: sender.Name}
+	}
+	types := make(map[notification.NotificationType]string, len(cfg.Email.SenderTypes))
+	for t, name := range cfg.Email.SenderTypes {
+		types[notification.NotificationType(t)] = name
+	}
+	return identities, types
+}
+
+// featureFlags converts the flags config to rollouts.
+func featureFlags(cfg *config.Config) map[notification.FeatureFlag]notification.Rollout {
+	rollouts := make(map[notification.FeatureFlag]notification.Rollout, len(cfg.Flags))
+	for name, flag := range cfg.Flags {
+		rollout := notification.Rollout{Percent: flag.Percent}
+		if len(flag.Types) > 0 {
+			rollout.Types = make(map[notification.NotificationType]int, len(flag.Types))
+			for t, percent := range flag.Types {
+				rollout.Types[notification.NotificationType(t)] = percent
+			}
+		}
+		rollouts[notification.FeatureFlag(name)] = rollout
+	}
+	return rollouts
+}
+
+// taskTimeout bounds one task attempt: the worker enforces it and the enqueuer
+// passes it to asynq.
+func taskTimeout(cfg *config.Config) time.Duration {
+	return time.Duration(cfg.Queue.TaskTimeoutSec) * time.Second
+}
+
+// MustLoadConfig loads configuration, applies its log level, and validates it
+// for role. On any problem it logs one line per problem and exits, so entry
+// points fail fast on missing or nonsensical settings instead of at the first send.
+func MustLoadConfig(role config.Role, logLevel *slog.LevelVar) *config.Config {
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+	logLevel.Set(cfg.Log.SlogLevel())
+
+	if err := cfg.Validate(role); err != nil {
+		var verr *config.ValidationError
+		if errors.As(err, &verr) {
+			for _, problem := range verr.Problems {
+				slog.Error("config problem", "problem", problem)
+			}
+		}
+		slog.Error("invalid configuration — fix the problems above and restart", "error", err)
+		os.Exit(1)
+	}
+	return cfg
+}
+
+// Reloader is a role that can apply hot-reloadable settings (Server, Worker).
+type Reloader interface {
+	Reload(cfg *config.Config)
+}
+
+// WatchConfig keeps the roles' hot-reloadable settings current. It reloads
+// configuration on config.yaml changes and SIGHUP, polls the runtime settings
+// table every settings.poll_interval_sec, and hands the merged result (database
+// overrides win) to every role along with the new log level. The first poll
+// runs before it returns, so stored overrides apply from startup.
+//
+// Cancelling ctx stops reloads; the underlying file watcher lasts for the whole
+// process (see config.Watch), so call this once from main.
+func WatchConfig(ctx context.Context, deps *Deps, role config.Role, logLevel *slog.LevelVar, roles ...Reloader) {
+	live := &liveConfig{role: role, logLevel: logLevel, roles: roles, base: deps.Config}
+	live.refresh(ctx, deps.Settings)
+
+	config.Watch(ctx, role, live.setBase)
+	go live.poll(ctx, deps.Settings, time.Duration(deps.Config.Settings.PollIntervalSec)*time.Second)
+}
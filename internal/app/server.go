@@ -0,0 +1,297 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/badrkarrachai/notifly/internal/config"
+	"github.com/badrkarrachai/notifly/internal/infra/lock"
+	"github.com/badrkarrachai/notifly/internal/infra/metrics"
+	"github.com/badrkarrachai/notifly/internal/infra/ratelimit"
+	"github.com/badrkarrachai/notifly/internal/infra/store"
+	"github.com/badrkarrachai/notifly/internal/infra/validation"
+	"github.com/badrkarrachai/notifly/internal/middleware"
+	"github.com/badrkarrachai/notifly/internal/router"
+	"github.com/badrkarrachai/notifly/pkg/domain"
+	"github.com/badrkarrachai/notifly/pkg/email"
+	"github.com/badrkarrachai/notifly/pkg/notification"
+	"github.com/badrkarrachai/notifly/pkg/settings"
+	"github.com/badrkarrachai/notifly/pkg/template"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Server is the HTTP API role.
+type Server struct {
+	http             *http.Server
+	redis            *redis.Client
+	ipLimiter        middleware.IPLimiter
+	recipientLimiter *ratelimit.RedisRecipientLimiter
+	service          *notification.Service
+	reaper           *notification.Reaper
+	campaigner       *notification.Campaigner
+	templates        *template.Engine
+	variants         *notification.Variants
+	senders          *notification.Senders
+
+	// scheduler sends recurring notifications through service while the server runs
+	scheduler     *notification.Scheduler
+	schedulerLock *lock.RedisLock
+	stopScheduler context.CancelFunc // also stops reporter and backlog
+
+	// reporter emails the daily summary; nil unless reports.daily_summary.enabled
+	reporter *notification.DailyReporter
+
+	// backlog pushes the queue backlog; nil unless metrics.pushgateway is set
+	backlog *notification.BacklogPusher
+}
+
+// NewServer wires the notification service, handler, and router on top of deps.
+func NewServer(deps *Deps) (*Server, error) {
+	cfg := deps.Config
+
+	// Recipient Rate Limiter — shares its Redis connection with the IP limiter
+	redisClient := ratelimit.NewClient(cfg.Redis.Address, cfg.Redis.Password, cfg.Redis.DB, redisFaultHooks(cfg)...)
+	recipientLimiter := ratelimit.NewRedisRecipientLimiter(redisClient, recipientLimits(cfg))
+	slog.Info("recipient rate limiter initialized",
+		"max_per_hour", cfg.RecipientRateLimit.MaxPerHour,
+		"limits", cfg.RecipientRateLimit.Limits,
+	)
+
+	// MX checker (optional) — rejects email domains that cannot receive mail
+	var mxChecker notification.MXChecker
+	if cfg.Validation.CheckMX {
+		mxChecker = validation.NewMXChecker(time.Duration(cfg.Validation.MXCacheTTLSec) * time.Second)
+		slog.Info("mx validation enabled", "cache_ttl_sec", cfg.Validation.MXCacheTTLSec)
+	}
+
+	// Service
+	notificationService := notification.NewService(deps.Store, deps.Enqueuer, recipientLimiter, deps.Tracker, mxChecker, deps.Templates, notification.ServiceConfig{
+		MaxRecipients:   cfg.Recipients.MaxPerRequest,
+		MaxDataSize:     cfg.Validation.MaxDataBytes,
+		FanOut:          cfg.Recipients.FanOut,
+		BatchSize:       cfg.Recipients.BatchSize,
+		SuppressBounced: cfg.Suppression.Bounced,
+
+		RateLimitFailClosed: cfg.RecipientRateLimit.FailClosed,
+		RenderAtEnqueue:     cfg.Templates.RenderAtEnqueue,
+		Fallbacks:           fallbackRules(cfg),
+		SoftBounces:         softBounceRetry(cfg),
+		CriticalTypes:       criticalTypes(cfg),
+		DigestTypes:         digestTypes(cfg),
+	})
+	notificationService.SetDevices(deps.Devices)
+	notificationService.SetEscalations(deps.Escalations)
+	notificationService.SetFollowUps(deps.FollowUps)
+	notificationService.SetVariants(deps.Variants)
+	notificationService.SetSenders(deps.Senders)
+	notificationService.SetLatency(deps.Latency)
+	notificationService.SetOutcomes(deps.Outcomes)
+	notificationService.SetCosts(deps.Costs)
+	notificationService.SetUsage(usage(cfg))
+
+	// Direct sends (optional) — critical types go out from the server while
+	// Redis is unreachable, through a worker of its own
+	if cfg.Queue.DirectSend {
+		_, providers := newEmailProviders(cfg)
+		direct, err := newNotificationWorker(deps, providers, notification.NewFlags(featureFlags(cfg)))
+		if err != nil {
+			return nil, err
+		}
+		notificationService.SetDirectSender(direct, taskTimeout(cfg))
+		slog.Info("direct sends enabled for critical types", "types", cfg.Queue.CriticalTypes)
+	}
+
+	// Provider webhooks, each served at /api/v1/webhooks/<provider> — Resend
+	// behind the API key unless it has a signing secret; SES (SNS) and
+	// Twilio, which cannot send one, are authenticated by signature
+	webhooks := notification.NewWebhookRegistry()
+	if cfg.Webhooks.Resend.Enabled {
+		resend, err := notification.NewResendWebhookAdapter(cfg.Webhooks.Resend.SigningSecret)
+		if err != nil {
+			return nil, err
+		}
+		if resend.Signed() {
+			webhooks.RegisterSigned(notification.WebhookProviderResend, resend)
+		} else {
+			webhooks.Register(notification.WebhookProviderResend, resend)
+		}
+	}
+	if cfg.Webhooks.SES.Enabled {
+		webhooks.RegisterSigned(notification.WebhookProviderSES, notification.NewSESWebhookAdapter(cfg.Webhooks.SES.TopicARNs))
+	}
+	if cfg.Webhooks.Twilio.Enabled {
+		webhooks.RegisterSigned(notification.WebhookProviderTwilio, notification.NewTwilioWebhookAdapter(cfg.Webhooks.Twilio.AuthToken, cfg.Webhooks.Twilio.BaseURL))
+	}
+	slog.Info("webhook providers registered", "providers", webhooks.Providers())
+
+	// Recurring notification scheduler — sends through the service like POST
+	// /send; the Redis lock keeps server replicas from running the same tick
+	schedulerLock := lock.NewRedisLock(cfg.Redis.Address, cfg.Redis.Password, cfg.Redis.DB, schedulerLockKey)
+	scheduler := notification.NewScheduler(store.NewScheduleStore(deps.Store), notificationService, schedulerLock, notification.SchedulerConfig{
+		Interval:  time.Duration(cfg.Scheduler.IntervalSec) * time.Second,
+		BatchSize: cfg.Scheduler.BatchSize,
+	})
+
+	// Daily summary report (optional) — sent through the service as a daily_summary email
+	var reporter *notification.DailyReporter
+	if cfg.Reports.DailySummary.Enabled {
+		reporter = notification.NewDailyReporter(deps.Store, deps.Outcomes, deps.Reaper, notificationService, dailyReportConfig(cfg))
+	}
+
+	// Queue backlog pusher (optional) — feeds autoscalers through a Pushgateway
+	var backlog *notification.BacklogPusher
+	if cfg.Metrics.Pushgateway != "" {
+		sink := metrics.NewPushgateway(cfg.Metrics.Pushgateway, cfg.Metrics.PushJob)
+		backlog = notification.NewBacklogPusher(deps.QueueControl, sink, time.Duration(cfg.Metrics.PushIntervalSec)*time.Second)
+	}
+
+	// Handler
+	notificationHandler := notification.NewHandler(notificationService, deps.Reaper, deps.QueueControl, deps.Eraser, deps.Campaigner, scheduler, deps.Devices, deps.Escalations, deps.FollowUps, webhooks)
+
+	// Per-IP Rate Limiter — in memory per replica, or in Redis to share limits cluster-wide
+	var ipLimiter middleware.IPLimiter
+	if cfg.RateLimit.Backend == config.RateLimitBackendRedis {
+		ipLimiter = ratelimit.NewRedisIPLimiter(redisClient, cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.Burst)
+	} else {
+		ipLimiter = middleware.NewRateLimiter(
+			cfg.RateLimit.RequestsPerSecond,
+			cfg.RateLimit.Burst,
+			cfg.RateLimit.MaxEntries,
+		)
+	}
+	slog.Info("ip rate limiter initialized", "backend", cfg.RateLimit.Backend)
+
+	// Runtime settings admin API
+	settingsHandler := settings.NewHandler(deps.Settings)
+
+	// Sending-domain admin API (optional)
+	var domainHandler *domain.Handler
+	if cfg.Domains.Provider != "" {
+		domainHandler = domain.NewHandler(domain.NewService(domainProvider(cfg), cfg.Domains.Region))
+		slog.Info("sending domain management enabled", "provider", cfg.Domains.Provider)
+	}
+
+	// Router
+	r := router.New(cfg, ipLimiter, notificationHandler, settingsHandler, domainHandler)
+
+	return &Server{
+		http: &http.Server{
+			Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
+			Handler:      r,
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: config.WriteTimeoutSec * time.Second,
+			IdleTimeout:  60 * time.Second,
+		},
+		redis:            redisClient,
+		ipLimiter:        ipLimiter,
+		recipientLimiter: recipientLimiter,
+		service:          notificationService,
+		reaper:           deps.Reaper,
+		campaigner:       deps.Campaigner,
+		templates:        deps.Templates,
+		variants:         deps.Variants,
+		senders:          deps.Senders,
+		scheduler:        scheduler,
+		schedulerLock:    schedulerLock,
+		reporter:         reporter,
+		backlog:          backlog,
+	}, nil
+}
+
+// Reload applies the hot-reloadable server settings from cfg: per-IP and
+// per-recipient rate limits and their failure mode, bounce suppression and
+// soft bounce retries, rendering at enqueue, SMS segment limits, sanitized
+// template types, template A/B tests, the default locale, subject and sender
+// name overrides, sender identities, fallback rules, and the reaper settings
+// used by manual sweeps.
+func (s *Server) Reload(cfg *config.Config) {
+	s.ipLimiter.SetLimit(cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.Burst)
+	if memLimiter, ok := s.ipLimiter.(*middleware.RateLimiter); ok {
+		memLimiter.SetMaxEntries(cfg.RateLimit.MaxEntries)
+	}
+	s.recipientLimiter.SetLimits(recipientLimits(cfg))
+	s.service.SetSuppressBounced(cfg.Suppression.Bounced)
+	s.service.SetRateLimitFailClosed(cfg.RecipientRateLimit.FailClosed)
+	s.service.SetRenderAtEnqueue(cfg.Templates.RenderAtEnqueue)
+	s.campaigner.SetRenderAtEnqueue(cfg.Templates.RenderAtEnqueue)
+	s.templates.SetSMSLimits(cfg.Templates.SMSMaxSegments, cfg.Templates.SMSTruncate)
+	s.templates.SetSanitizedTypes(sanitizedTypes(cfg))
+	s.variants.SetTests(templateVariants(cfg))
+	s.templates.SetSubjects(subjectOverrides(cfg))
+	s.templates.SetDefaultLocale(cfg.Templates.DefaultLocale)
+	s.senders.Set(senders(cfg))
+	s.senders.SetFromNames(cfg.Email.FromAddress, fromNames(cfg))
+	s.service.SetFallbacks(fallbackRules(cfg))
+	s.service.SetSoftBounceRetry(softBounceRetry(cfg))
+	s.service.SetUsage(usage(cfg))
+	s.reaper.UpdateConfig(reaperConfig(cfg))
+}
+
+func recipientLimits(cfg *config.Config) ratelimit.Limits {
+	return ratelimit.Limits{
+		Default: cfg.RecipientRateLimit.MaxPerHour,
+		Rules:   cfg.RecipientRateLimit.Limits,
+	}
+}
+
+// domainProvider creates the sending-domain provider named by domains.provider.
+func domainProvider(cfg *config.Config) domain.Provider {
+	if cfg.Domains.Provider == "ses" {
+		ses := cfg.Domains.SES
+		return email.NewSESDomains(cfg.Domains.Region, ses.AccessKeyID, ses.SecretAccessKey, ses.SessionToken)
+	}
+	apiKey := cfg.Domains.APIKey
+	if apiKey == "" {
+		apiKey = cfg.Email.APIKey
+	}
+	return email.NewResendDomains(apiKey)
+}
+
+// schedulerLockKey is the Redis key the scheduler replicas compete for.
+const schedulerLockKey = "notifly:lock:scheduler"
+
+// Start serves HTTP and runs the scheduler and, when enabled, the daily
+// reporter and the backlog pusher in background goroutines. A listen failure is delivered on the
+// returned channel; a clean shutdown closes it without a value.
+func (s *Server) Start() <-chan error {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopScheduler = cancel
+	go s.scheduler.Run(ctx)
+	if s.reporter != nil {
+		go s.reporter.Run(ctx)
+	}
+	if s.backlog != nil {
+		go s.backlog.Run(ctx)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(errCh)
+		slog.Info("server starting", "address", s.http.Addr)
+		if err := s.http.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+	}()
+	return errCh
+}
+
+// Shutdown stops accepting requests, waits for in-flight ones until ctx
+// expires, and releases server-only resources.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.http.Shutdown(ctx)
+	if s.stopScheduler != nil {
+		s.stopScheduler()
+	}
+	if closeErr := s.schedulerLock.Close(); closeErr != nil {
+		slog.Error("failed to close scheduler lock", "error", closeErr)
+	}
+	if closeErr := s.redis.Close(); closeErr != nil {
+		slog.Error("failed to close rate limiter redis client", "error", closeErr)
+	}
+	return err
+}
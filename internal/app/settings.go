@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/badrkarrachai/notifly/internal/config"
+	"github.com/badrkarrachai/notifly/pkg/notification"
+	"github.com/badrkarrachai/notifly/pkg/settings"
+)
+
+// liveConfig merges the file/env configuration with the runtime settings
+// stored in the database and pushes the effective config to the roles.
+// Either source can change independently; both go through the same apply path.
+type liveConfig struct {
+	role     config.Role
+	logLevel *slog.LevelVar
+	roles    []Reloader
+
+	mu        sync.Mutex
+	base      *config.Config
+	overrides settings.Values
+}
+
+// setBase records a reloaded file/env config and applies it with the current overrides.
+func (l *liveConfig) setBase(cfg *config.Config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.base = cfg
+	l.applyLocked()
+}
+
+// setOverrides records freshly polled runtime settings. Unchanged settings are a no-op.
+func (l *liveConfig) setOverrides(values settings.Values) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if reflect.DeepEqual(values, l.overrides) {
+		return
+	}
+	l.overrides = values
+	l.applyLocked()
+	slog.Info("runtime settings applied", "overrides", len(values))
+}
+
+func (l *liveConfig) applyLocked() {
+	cfg := applySettings(l.base, l.overrides)
+	if err := cfg.Validate(l.role); err != nil {
+		slog.Error("runtime settings produce an invalid config — keeping current values", "error", err)
+		return
+	}
+	l.logLevel.Set(cfg.Log.SlogLevel())
+	for _, r := range l.roles {
+		r.Reload(cfg)
+	}
+}
+
+// poll refreshes the overrides every interval until ctx is cancelled. A failed
+// load is logged and the previous overrides stay in effect.
+func (l *liveConfig) poll(ctx context.Context, svc *settings.Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.refresh(ctx, svc)
+		}
+	}
+}
+
+func (l *liveConfig) refresh(ctx context.Context, svc *settings.Service) {
+	values, err := svc.Values(ctx)
+	if err != nil {
+		slog.Error("loading runtime settings failed — keeping current values", "error", err)
+		return
+	}
+	l.setOverrides(values)
+}
+
+// applySettings returns a copy of cfg with the stored overrides applied.
+func applySettings(cfg *config.Config, v settings.Values) *config.Config {
+	out := *cfg
+	if n, ok := v.Int(settings.KeyRecipientMaxPerHour); ok {
+		out.RecipientRateLimit.MaxPerHour = n
+	}
+	if n, ok := v.Int(settings.KeyReaperIntervalSec); ok {
+		out.Reaper.IntervalSec = n
+	}
+	if n, ok := v.Int(settings.KeyReaperStaleThresholdSec); ok {
+		out.Reaper.StaleThresholdSec = n
+	}
+	if n, ok := v.Int(settings.KeyReaperBatchSize); ok {
+		out.Reaper.BatchSize = n
+	}
+	if b, ok := v.Bool(settings.KeySuppressionBounced); ok {
+		out.Suppression.Bounced = b
+	}
+	if s, ok := v.String(settings.KeyEmailProvider); ok {
+		out.Email.Provider = s
+	}
+
+	// Flags is a map: copy it before overriding, so cfg is left untouched
+	flagKeys := map[settings.Key]notification.FeatureFlag{
+		settings.KeyFlagClickTracking:  notification.FlagClickTracking,
+		settings.KeyFlagProviderCanary: notification.FlagProviderCanary,
+	}
+	out.Flags = make(map[string]config.FlagConfig, len(cfg.Flags))
+	for name, flag := range cfg.Flags {
+		out.Flags[name] = flag
+	}
+	for key, flag := range flagKeys {
+		if r, ok := v.Rollout(key); ok {
+			out.Flags[string(flag)] = config.FlagConfig{Percent: r.Percent, Types: r.Types}
+		}
+	}
+	return &out
+}
@@ -0,0 +1,116 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/badrkarrachai/notifly/internal/config"
+	"github.com/badrkarrachai/notifly/internal/infra/store"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Backoff between dependency checks at startup: it starts at
+// startupBackoffMin and doubles up to startupBackoffMax.
+const (
+	startupBackoffMin = 500 * time.Millisecond
+	startupBackoffMax = 10 * time.Second
+)
+
+// startupCheckTimeout bounds one dependency check, so an address that drops
+// packets fails the attempt instead of hanging it.
+const startupCheckTimeout = 5 * time.Second
+
+// dependency is a service a process needs before it can start.
+type dependency struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// MustWaitForDependencies blocks until Redis and the store answer, retrying
+// each with backoff for up to startup.wait_max_sec. Every failed attempt is
+// logged with the time until the next one. When the wait runs out, or
+// SIGINT/SIGTERM arrives first, it logs which dependency is missing and exits,
+// so orchestrators that start notifly before Redis or the database settle
+// instead of crash-looping it.
+func MustWaitForDependencies(cfg *config.Config) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := WaitForDependencies(ctx, cfg); err != nil {
+		slog.Error("dependencies unavailable — check that they are running and reachable, then restart", "error", err)
+		os.Exit(1)
+	}
+}
+
+// WaitForDependencies checks Redis and then the store, retrying each until it
+// answers, the wait set by startup.wait_max_sec runs out, or ctx is cancelled.
+func WaitForDependencies(ctx context.Context, cfg *config.Config) error {
+	// The client's own retries are off: waitFor retries with backoff instead
+	rdb := redis.NewClient(&redis.Options{
+		Addr:          cfg.Redis.Address,
+		Password:      cfg.Redis.Password,
+		DB:            cfg.Redis.DB,
+		MaxRetries:    -1,
+		DialerRetries: 1,
+	})
+	defer rdb.Close()
+
+	// No store retries either; each check is bounded by startupCheckTimeout
+	notifStore, err := store.NewSupabaseStore(cfg.Supabase.URL, cfg.Supabase.ServiceKey, store.CallConfig{})
+	if err != nil {
+		return fmt.Errorf("initializing supabase store: %w", err)
+	}
+
+	deadline := time.Now().Add(time.Duration(cfg.Startup.WaitMaxSec) * time.Second)
+	for _, dep := range []dependency{
+		{name: "redis", check: func(ctx context.Context) error { return rdb.Ping(ctx).Err() }},
+		{name: "store", check: notifStore.Ping},
+	} {
+		if err := waitFor(ctx, dep, deadline); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitFor retries dep's check with backoff until it succeeds or deadline passes.
+func waitFor(ctx context.Context, dep dependency, deadline time.Time) error {
+	start := time.Now()
+	backoff := startupBackoffMin
+	for attempt := 1; ; attempt++ {
+		checkCtx, cancel := context.WithTimeout(ctx, startupCheckTimeout)
+		err := dep.check(checkCtx)
+		cancel()
+		if err == nil {
+			if attempt > 1 {
+				slog.Info("dependency available", "dependency", dep.name, "attempts", attempt, "waited", time.Since(start).Round(time.Millisecond).String())
+			}
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%s not available after %d attempt(s) over %s: %w", dep.name, attempt, time.Since(start).Round(time.Second), err)
+		}
+		wait := min(backoff, remaining)
+		slog.Warn("waiting for dependency",
+			"dependency", dep.name,
+			"attempt", attempt,
+			"retry_in", wait.Round(time.Millisecond).String(),
+			"error", err,
+		)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for %s: %w", dep.name, ctx.Err())
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, startupBackoffMax)
+	}
+}
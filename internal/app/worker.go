@@ -0,0 +1,436 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/badrkarrachai/notifly/internal/config"
+	"github.com/badrkarrachai/notifly/internal/infra/alert"
+	"github.com/badrkarrachai/notifly/internal/infra/fault"
+	"github.com/badrkarrachai/notifly/internal/infra/queue"
+	"github.com/badrkarrachai/notifly/internal/infra/ratelimit"
+	"github.com/badrkarrachai/notifly/pkg/common"
+	"github.com/badrkarrachai/notifly/pkg/email"
+	"github.com/badrkarrachai/notifly/pkg/notification"
+	"github.com/badrkarrachai/notifly/pkg/template"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+// Worker is the queue-processing role: the asynq server plus the stale task reaper.
+type Worker struct {
+	cfg      *config.Config
+	server   *queue.Server
+	mux      *asynq.ServeMux
+	provider *email.ResendProvider
+	worker   *notification.Worker
+	reaper   *notification.Reaper
+
+	campaigner *notification.Campaigner
+	templates  *template.Engine
+	variants   *notification.Variants
+	senders    *notification.Senders
+
+	// followUps sends reminders through the same bounce suppression and
+	// per-recipient limits as the server; recipientLimiter holds the limits
+	followUps        *notification.FollowUps
+	recipientLimiter *ratelimit.RedisRecipientLimiter
+	redis            *redis.Client
+
+	// taskTimeout bounds each task attempt (time.Duration); read by the Timeout middleware.
+	taskTimeout atomic.Int64
+
+	// emailProviders are the email providers selectable with email.provider,
+	// by name; emailProvider is the one currently installed, and
+	// emailCanary the one installed as canary ("" for none).
+	emailProviders map[string]notification.Provider
+	emailProvider  string
+	emailCanary    string
+
+	// flags gate click tracking and the canary provider; reloadable.
+	flags *notification.Flags
+
+	cancelReaper context.CancelFunc
+
+	// alerter is nil unless alerts.enabled; cancelAlerter stops it.
+	alerter       *notification.Alerter
+	alertCooldown *alert.RedisCooldown
+	cancelAlerter context.CancelFunc
+}
+
+// newEmailProviders returns the Resend provider and the email providers
+// selectable via email.provider, by name; dryrun and mock send nothing and are
+// not runtime settings, so they cannot be switched on by accident.
+func newEmailProviders(cfg *config.Config) (*email.ResendProvider, map[string]notification.Provider) {
+	resend := email.NewResendProvider(
+		cfg.Email.APIKey,
+		cfg.Email.FromAddress,
+		cfg.Email.FromName,
+	)
+	providers := map[string]notification.Provider{
+		"resend": resend,
+		"dryrun": email.NewDryRunProvider(time.Duration(cfg.Email.DryRunLatencyMs) * time.Millisecond),
+		"mock": email.NewMockProvider(email.MockBehavior{
+			Latency:       time.Duration(cfg.Email.MockLatencyMs) * time.Millisecond,
+			FailureRate:   cfg.Email.MockFailureRate,
+			FailureStatus: cfg.Email.MockFailureStatus,
+		}),
+	}
+	if cfg.Faults.Enabled && cfg.Faults.ProviderErrorRate > 0 {
+		for name, p := range providers {
+			providers[name] = fault.Provider(p, cfg.Faults.ProviderErrorRate)
+		}
+	}
+	return resend, providers
+}
+
+// newNotificationWorker creates the notification worker sending through the
+// email.provider and email.canary_provider of providers, gated by flags.
+func newNotificationWorker(deps *Deps, providers map[string]notification.Provider, flags *notification.Flags) (*notification.Worker, error) {
+	cfg := deps.Config
+	selected, ok := providers[cfg.Email.Provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown email provider: %s", cfg.Email.Provider)
+	}
+
+	notifWorker := notification.NewWorker(deps.Store, deps.Templates, deps.Tracker, selected)
+	notifWorker.SetDevices(deps.Devices)
+	notifWorker.SetLatency(deps.Latency)
+	notifWorker.SetOutcomes(deps.Outcomes)
+	notifWorker.SetCosts(deps.Costs)
+	notifWorker.SetPrices(prices(cfg))
+	notifWorker.SetFlags(flags)
+	notifWorker.SetVariants(deps.Variants)
+	notifWorker.SetSenders(deps.Senders)
+	if cfg.Email.CanaryProvider != "" {
+		notifWorker.SetCanary(notification.ChannelEmail, providers[cfg.Email.CanaryProvider])
+	}
+	return notifWorker, nil
+}
+
+// NewWorker wires the providers, notification worker, and reaper on top of deps.
+func NewWorker(deps *Deps) (*Worker, error) {
+	cfg := deps.Config
+
+	// Notification Worker
+	emailProvider, emailProviders := newEmailProviders(cfg)
+	flags := notification.NewFlags(featureFlags(cfg))
+	notifWorker, err := newNotificationWorker(deps, emailProviders, flags)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Email.CanaryProvider != "" {
+		slog.Info("email canary provider installed", "provider", cfg.Email.CanaryProvider, "rollout", cfg.Flags[string(notification.FlagProviderCanary)].Percent)
+	}
+
+	// Recipient Rate Limiter — follow-up reminders count against the same
+	// windows as the server's sends
+	redisClient := ratelimit.NewClient(cfg.Redis.Address, cfg.Redis.Password, cfg.Redis.DB, redisFaultHooks(cfg)...)
+	recipientLimiter := ratelimit.NewRedisRecipientLimiter(redisClient, recipientLimits(cfg))
+	deps.FollowUps.SetRateLimiter(recipientLimiter)
+	deps.FollowUps.SetSuppressBounced(cfg.Suppression.Bounced)
+	deps.FollowUps.SetRateLimitFailClosed(cfg.RecipientRateLimit.FailClosed)
+
+	// Asynq Server (task processing)
+	asynqServer := queue.NewServer(
+		cfg.Redis.Address,
+		cfg.Redis.Password,
+		cfg.Redis.DB,
+		cfg.Queue.Concurrency,
+		queues(cfg),
+		retryBackoff(cfg.Queue.Retry),
+		digestConfig(cfg),
+	)
+
+	w := &Worker{
+		cfg:      cfg,
+		server:   asynqServer,
+		provider: emailProvider,
+		worker:   notifWorker,
+		reaper:   deps.Reaper,
+
+		campaigner: deps.Campaigner,
+		templates:  deps.Templates,
+		variants:   deps.Variants,
+		senders:    deps.Senders,
+
+		followUps:        deps.FollowUps,
+		recipientLimiter: recipientLimiter,
+		redis:            redisClient,
+
+		emailProviders: emailProviders,
+		emailProvider:  cfg.Email.Provider,
+		emailCanary:    cfg.Email.CanaryProvider,
+		flags:          flags,
+	}
+	w.taskTimeout.Store(int64(taskTimeout(cfg)))
+
+	// Failure-rate alerter (optional) — cooldowns in Redis so replicas fire each alert once
+	if cfg.Alerts.Enabled {
+		w.alertCooldown = alert.NewRedisCooldown(cfg.Redis.Address, cfg.Redis.Password, cfg.Redis.DB)
+		w.alerter = notification.NewAlerter(deps.Outcomes, w.alertCooldown, alertActions(cfg), alertConfig(cfg))
+	}
+
+	// Task middleware (order matters)
+	mux := asynq.NewServeMux()
+	mux.Use(
+		queue.Recovery(),
+		queue.Logging(),
+		queue.Timeout(func() time.Duration { return time.Duration(w.taskTimeout.Load()) }),
+	)
+
+	// Register task handlers
+	mux.HandleFunc(notification.TaskTypeSendNotification, func(ctx context.Context, task *asynq.Task) error {
+		payload, err := notification.ParseSendNotificationPayload(task.Payload())
+		if err != nil {
+			return notification.TaskError(common.NewPermanentError(err))
+		}
+		return notification.TaskError(notifWorker.ProcessTask(ctx, payload.LogID))
+	})
+	mux.HandleFunc(notification.TaskTypeSendBatch, func(ctx context.Context, task *asynq.Task) error {
+		payload, err := notification.ParseSendBatchPayload(task.Payload())
+		if err != nil {
+			return notification.TaskError(common.NewPermanentError(err))
+		}
+		return notification.TaskError(notifWorker.ProcessBatch(ctx, payload.LogIDs))
+	})
+	mux.HandleFunc(notification.TaskTypeSendDigest, func(ctx context.Context, task *asynq.Task) error {
+		payload, err := notification.ParseSendDigestPayload(task.Payload())
+		if err != nil {
+			return notification.TaskError(common.NewPermanentError(err))
+		}
+		return notification.TaskError(notifWorker.ProcessDigest(ctx, payload.LogIDs))
+	})
+	// Digest events are combined before they run; one that runs anyway (a
+	// worker without the aggregator) is sent on its own
+	mux.HandleFunc(notification.TaskTypeDigestEvent, func(ctx context.Context, task *asynq.Task) error {
+		payload, err := notification.ParseSendNotificationPayload(task.Payload())
+		if err != nil {
+			return notification.TaskError(common.NewPermanentError(err))
+		}
+		return notification.TaskError(notifWorker.ProcessTask(ctx, payload.LogID))
+	})
+	mux.HandleFunc(notification.TaskTypeEraseRecipient, func(ctx context.Context, task *asynq.Task) error {
+		payload, err := notification.ParseEraseRecipientPayload(task.Payload())
+		if err != nil {
+			return notification.TaskError(common.NewPermanentError(err))
+		}
+		return notification.TaskError(deps.Eraser.Process(ctx, payload.JobID, payload.Recipient))
+	})
+	mux.HandleFunc(notification.TaskTypeDispatchCampaign, func(ctx context.Context, task *asynq.Task) error {
+		payload, err := notification.ParseDispatchCampaignPayload(task.Payload())
+		if err != nil {
+			return notification.TaskError(common.NewPermanentError(err))
+		}
+		return notification.TaskError(deps.Campaigner.Dispatch(ctx, payload.CampaignID))
+	})
+	fallbacker := notification.NewFallbacker(deps.Store, deps.Enqueuer)
+	mux.HandleFunc(notification.TaskTypeCheckFallback, func(ctx context.Context, task *asynq.Task) error {
+		payload, err := notification.ParseCheckFallbackPayload(task.Payload())
+		if err != nil {
+			return notification.TaskError(common.NewPermanentError(err))
+		}
+		return notification.TaskError(fallbacker.Process(ctx, payload.LogID))
+	})
+	mux.HandleFunc(notification.TaskTypeEscalate, func(ctx context.Context, task *asynq.Task) error {
+		payload, err := notification.ParseEscalatePayload(task.Payload())
+		if err != nil {
+			return notification.TaskError(common.NewPermanentError(err))
+		}
+		return notification.TaskError(deps.Escalations.Step(ctx, payload.LogID, payload.Step))
+	})
+	mux.HandleFunc(notification.TaskTypeFollowUp, func(ctx context.Context, task *asynq.Task) error {
+		payload, err := notification.ParseFollowUpPayload(task.Payload())
+		if err != nil {
+			return notification.TaskError(common.NewPermanentError(err))
+		}
+		return notification.TaskError(deps.FollowUps.Check(ctx, payload.LogID, payload.SequenceID, payload.Step))
+	})
+	bounceRetrier := notification.NewBounceRetrier(deps.Store, deps.Enqueuer)
+	mux.HandleFunc(notification.TaskTypeRetryBounce, func(ctx context.Context, task *asynq.Task) error {
+		payload, err := notification.ParseRetryBouncePayload(task.Payload())
+		if err != nil {
+			return notification.TaskError(common.NewPermanentError(err))
+		}
+		return notification.TaskError(bounceRetrier.Process(ctx, payload.LogID, payload.Retry))
+	})
+
+	w.mux = mux
+
+	return w, nil
+}
+
+// Reload applies the hot-reloadable worker settings from cfg: reaper timings,
+// alert timings and rules, the task timeout, the email provider API key, the
+// email provider and canary selection, feature flag rollouts, bounce
+// suppression for campaigns and follow-ups, the per-recipient rate limits of
+// follow-ups and their failure mode, SMS segment limits, the sanitized
+// template types, template A/B tests, the default locale, subject and sender
+// name overrides, sender identities, and prices. Reload calls are serialized
+// by the caller.
+func (w *Worker) Reload(cfg *config.Config) {
+	w.reaper.UpdateConfig(reaperConfig(cfg))
+	if w.alerter != nil {
+		w.alerter.UpdateConfig(alertConfig(cfg))
+	}
+	w.campaigner.SetSuppressBounced(cfg.Suppression.Bounced)
+	w.followUps.SetSuppressBounced(cfg.Suppression.Bounced)
+	w.followUps.SetRateLimitFailClosed(cfg.RecipientRateLimit.FailClosed)
+	w.recipientLimiter.SetLimits(recipientLimits(cfg))
+	w.templates.SetSMSLimits(cfg.Templates.SMSMaxSegments, cfg.Templates.SMSTruncate)
+	w.templates.SetSanitizedTypes(sanitizedTypes(cfg))
+	w.variants.SetTests(templateVariants(cfg))
+	w.templates.SetSubjects(subjectOverrides(cfg))
+	w.templates.SetDefaultLocale(cfg.Templates.DefaultLocale)
+	w.senders.Set(senders(cfg))
+	w.senders.SetFromNames(cfg.Email.FromAddress, fromNames(cfg))
+	w.taskTimeout.Store(int64(taskTimeout(cfg)))
+	w.provider.SetAPIKey(cfg.Email.APIKey)
+
+	if cfg.Email.Provider != w.emailProvider {
+		if p, ok := w.emailProviders[cfg.Email.Provider]; ok {
+			w.worker.SetProvider(p)
+			w.emailProvider = cfg.Email.Provider
+			slog.Info("email provider switched", "provider", cfg.Email.Provider)
+		}
+	}
+	if cfg.Email.CanaryProvider != w.emailCanary {
+		// An empty name removes the canary: the lookup yields nil
+		w.worker.SetCanary(notification.ChannelEmail, w.emailProviders[cfg.Email.CanaryProvider])
+		w.emailCanary = cfg.Email.CanaryProvider
+		slog.Info("email canary provider switched", "provider", cfg.Email.CanaryProvider)
+	}
+	w.flags.SetRollouts(featureFlags(cfg))
+	w.worker.SetPrices(prices(cfg))
+}
+
+// Start begins processing tasks and launches the reaper and, when enabled,
+// the alerter. It does not block.
+func (w *Worker) Start() error {
+	slog.Info("worker starting",
+		"concurrency", w.cfg.Queue.Concurrency,
+		"redis", w.cfg.Redis.Address,
+	)
+	if err := w.server.Start(w.mux); err != nil {
+		return fmt.Errorf("starting asynq server: %w", err)
+	}
+
+	reaperCtx, cancel := context.WithCancel(context.Background())
+	w.cancelReaper = cancel
+	go w.reaper.Run(reaperCtx)
+
+	if w.alerter != nil {
+		alerterCtx, cancel := context.WithCancel(context.Background())
+		w.cancelAlerter = cancel
+		go w.alerter.Run(alerterCtx)
+	}
+
+	return nil
+}
+
+// Drain stops the worker from picking up new tasks, and stops the reaper and
+// alerter, then waits for the tasks in flight to finish or ctx to be done,
+// logging when the worker is drained. The process stays up, idle, until
+// Shutdown; there is no undoing a drain short of a restart.
+func (w *Worker) Drain(ctx context.Context) error {
+	start := time.Now()
+	slog.Info("worker draining", "in_flight", w.server.Active())
+	w.stopLoops()
+	if err := w.server.Drain(ctx); err != nil {
+		return fmt.Errorf("draining worker: %w", err)
+	}
+	slog.Info("worker drained", "duration", time.Since(start))
+	return nil
+}
+
+// DrainOnSignal drains the worker (see Drain) when the process receives
+// SIGTSTP, the signal asynq itself stops processing on, until ctx is
+// cancelled. Deploys and maintenance windows send it ahead of stopping the
+// process, then wait for the "worker drained" log.
+func (w *Worker) DrainOnSignal(ctx context.Context) {
+	tstp := make(chan os.Signal, 1)
+	signal.Notify(tstp, syscall.SIGTSTP)
+	go func() {
+		defer signal.Stop(tstp)
+		select {
+		case <-ctx.Done():
+		case <-tstp:
+			slog.Info("drain requested", "signal", "SIGTSTP")
+			if err := w.Drain(ctx); err != nil {
+				slog.Error("worker drain failed", "error", err)
+			}
+		}
+	}()
+}
+
+// stopLoops stops the reaper and alerter.
+func (w *Worker) stopLoops() {
+	if w.cancelReaper != nil {
+		w.cancelReaper()
+	}
+	if w.cancelAlerter != nil {
+		w.cancelAlerter()
+	}
+}
+
+// Shutdown stops the reaper and alerter first, then waits for in-flight tasks
+// to finish.
+func (w *Worker) Shutdown() {
+	w.stopLoops()
+	w.server.Shutdown()
+
+	if w.alertCooldown != nil {
+		if err := w.alertCooldown.Close(); err != nil {
+			slog.Error("failed to close alert cooldowns", "error", err)
+		}
+	}
+	if err := w.redis.Close(); err != nil {
+		slog.Error("failed to close rate limiter redis client", "error", err)
+	}
+}
+
+// templatesOverrideDir is where the Docker image copies the templates. When it
+// exists it replaces the embedded set, so templates can change without a rebuild.
+const templatesOverrideDir = "/app/templates"
+
+// ResolveTemplates returns the templates to render and a description of their
+// source for logs: the override directory when present, otherwise the templates
+// embedded in the binary.
+func ResolveTemplates() (fs.FS, string) {
+	if _, err := os.Stat(templatesOverrideDir); err == nil {
+		return os.DirFS(templatesOverrideDir), templatesOverrideDir
+	}
+	return template.Embedded(), "embedded"
+}
+
+// newTemplateEngine loads and validates the resolved templates.
+func newTemplateEngine() (*template.Engine, error) {
+	templatesFS, templatesSource := ResolveTemplates()
+
+	tmplEngine, err := template.NewEngineFS(templatesFS)
+	if err != nil {
+		return nil, fmt.Errorf("initializing template engine from %s: %w", templatesSource, err)
+	}
+	slog.Info("template engine initialized", "source", templatesSource)
+
+	// Fail fast on broken templates instead of failing every task that uses them
+	templateIssues, err := template.ValidateFS(templatesFS)
+	if err != nil {
+		return nil, fmt.Errorf("validating templates in %s: %w", templatesSource, err)
+	}
+	if len(templateIssues) > 0 {
+		for _, issue := range templateIssues {
+			slog.Error("template issue", "template", issue.Template, "type", issue.Type, "message", issue.Message)
+		}
+		return nil, fmt.Errorf("template validation failed with %d issue(s) — run `notifly templates validate` for details", len(templateIssues))
+	}
+	return tmplEngine, nil
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
 	"strings"
 
 	"github.com/joho/godotenv"
@@ -11,6 +12,7 @@ import (
 // Config holds all application configuration.
 type Config struct {
 	Server             ServerConfig             `mapstructure:"server"`
+	Log                LogConfig                `mapstructure:"log"`
 	Auth               AuthConfig               `mapstructure:"auth"`
 	Email              EmailConfig              `mapstructure:"email"`
 	CORS               CORSConfig               `mapstructure:"cors"`
@@ -19,13 +21,60 @@ type Config struct {
 	Supabase           SupabaseConfig           `mapstructure:"supabase"`
 	Queue              QueueConfig              `mapstructure:"queue"`
 	RecipientRateLimit RecipientRateLimitConfig `mapstructure:"recipient_rate_limit"`
+	Recipients         RecipientsConfig         `mapstructure:"recipients"`
 	Reaper             ReaperConfigYAML         `mapstructure:"reaper"`
+	Scheduler          SchedulerConfigYAML      `mapstructure:"scheduler"`
+	Campaigns          CampaignsConfig          `mapstructure:"campaigns"`
+	Templates          TemplatesConfig          `mapstructure:"templates"`
+	Fallbacks          FallbacksConfig          `mapstructure:"fallbacks"`
+	Tracking           TrackingConfig           `mapstructure:"tracking"`
+	Validation         ValidationConfig         `mapstructure:"validation"`
+	Suppression        SuppressionConfig        `mapstructure:"suppression"`
+	Settings           SettingsConfig           `mapstructure:"settings"`
+	Webhooks           WebhooksConfig           `mapstructure:"webhooks"`
+	Domains            DomainsConfig            `mapstructure:"domains"`
+	Metrics            MetricsConfig            `mapstructure:"metrics"`
+	Alerts             AlertsConfig             `mapstructure:"alerts"`
+	Reports            ReportsConfig            `mapstructure:"reports"`
+	Startup            StartupConfig            `mapstructure:"startup"`
+	Cache              CacheConfig              `mapstructure:"cache"`
+	Faults             FaultsConfig             `mapstructure:"faults"`
+	Costs              CostsConfig              `mapstructure:"costs"`
+	Usage              UsageConfig              `mapstructure:"usage"`
+	Digests            DigestsConfig            `mapstructure:"digests"`
+	Flags              map[string]FlagConfig    `mapstructure:"flags"`
 }
 
 // ServerConfig holds HTTP server settings.
 type ServerConfig struct {
-	Port int    `mapstructure:"port"`
-	Mode string `mapstructure:"mode"`
+	Port         int    `mapstructure:"port"`
+	Mode         string `mapstructure:"mode"`
+	MaxBodyBytes int64  `mapstructure:"max_body_bytes"` // after gzip decompression
+
+	// RequestTimeoutSec bounds each /api/v1 request; past it the client gets 503.
+	RequestTimeoutSec int `mapstructure:"request_timeout_sec"`
+}
+
+// WriteTimeoutSec is the HTTP server's write timeout. Request timeouts must be
+// shorter, or the connection is cut before the 503 can be written.
+const WriteTimeoutSec = 15
+
+// LogConfig holds logging settings.
+type LogConfig struct {
+	Level string `mapstructure:"level"`
+
+	// AccessSample maps a route to the fraction (0–1) of its successful
+	// requests written to the access log, e.g. {"/health": 0.01}.
+	AccessSample map[string]float64 `mapstructure:"access_sample"`
+}
+
+// SlogLevel parses Level (debug, info, warn, error). Unknown values fall back to info.
+func (c LogConfig) SlogLevel() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
 }
 
 // AuthConfig holds API key authentication settings.
@@ -39,6 +88,34 @@ type EmailConfig struct {
 	APIKey      string `mapstructure:"api_key"`
 	FromAddress string `mapstructure:"from_address"`
 	FromName    string `mapstructure:"from_name"`
+
+	// Senders are named sender identities email can be sent from instead of
+	// FromAddress: by type with SenderTypes, or per request with "sender".
+	Senders     map[string]SenderConfig `mapstructure:"senders"`
+	SenderTypes map[string]string       `mapstructure:"sender_types"`
+
+	// CanaryProvider, when set, receives the email traffic the
+	// provider_canary feature flag is rolled out to, instead of Provider.
+	CanaryProvider string `mapstructure:"canary_provider"`
+
+	// DryRunLatencyMs is the simulated API latency of the dryrun provider,
+	// which sends nothing; it is for load tests.
+	DryRunLatencyMs int `mapstructure:"dryrun_latency_ms"`
+
+	// The mock provider sends nothing either and answers as scripted per
+	// send by mock_* tags; otherwise it waits MockLatencyMs and fails a
+	// fraction MockFailureRate of sends with MockFailureStatus. It is for
+	// integration tests.
+	MockLatencyMs     int     `mapstructure:"mock_latency_ms"`
+	MockFailureRate   float64 `mapstructure:"mock_failure_rate"`
+	MockFailureStatus int     `mapstructure:"mock_failure_status"`
+}
+
+// SenderConfig is one sender identity: the address and display name email
+// is sent from. The address's domain must be verified with the provider.
+type SenderConfig struct {
+	Address string `mapstructure:"address"`
+	Name    string `mapstructure:"name"`
 }
 
 // CORSConfig holds CORS policy settings.
@@ -48,10 +125,18 @@ type CORSConfig struct {
 	AllowedHeaders []string `mapstructure:"allowed_headers"`
 }
 
+// Per-IP rate limiter backends.
+const (
+	RateLimitBackendMemory = "memory" // per replica
+	RateLimitBackendRedis  = "redis"  // shared by all replicas
+)
+
 // RateLimitConfig holds rate limiting settings.
 type RateLimitConfig struct {
+	Backend           string  `mapstructure:"backend"`
 	RequestsPerSecond float64 `mapstructure:"requests_per_second"`
 	Burst             int     `mapstructure:"burst"`
+	MaxEntries        int     `mapstructure:"max_entries"` // memory backend: IPs tracked before the least recent is evicted
 }
 
 // RedisConfig holds Redis connection settings.
@@ -65,31 +150,444 @@ type RedisConfig struct {
 type SupabaseConfig struct {
 	URL        string `mapstructure:"url"`
 	ServiceKey string `mapstructure:"service_key"`
+
+	// TimeoutSec bounds each store request attempt. Transient failures
+	// (network errors, 5xx from PostgREST) are retried up to MaxRetries
+	// times, waiting RetryBackoffMs before the first retry and doubling after.
+	TimeoutSec     int `mapstructure:"timeout_sec"`
+	MaxRetries     int `mapstructure:"max_retries"`
+	RetryBackoffMs int `mapstructure:"retry_backoff_ms"`
+
+	// ReadReplicaURL is the API URL of a read replica (same service key) that
+	// serves notification listings and stats. Empty reads from URL.
+	ReadReplicaURL string `mapstructure:"read_replica_url"`
+
+	// CompressDataBytes stores a log's template data gzipped once its JSON
+	// reaches this size. 0 stores it as is.
+	CompressDataBytes int `mapstructure:"compress_data_bytes"`
+
+	// AccessToken is a personal access token for the Management API, used only
+	// by "notifly migrate". ProjectRef defaults to the one in URL.
+	AccessToken string `mapstructure:"access_token"`
+	ProjectRef  string `mapstructure:"project_ref"`
 }
 
 // QueueConfig holds async queue settings.
 type QueueConfig struct {
-	Concurrency   int `mapstructure:"concurrency"`
-	MaxRetry      int `mapstructure:"max_retry"`
-	RetryDelaySec int `mapstructure:"retry_delay_sec"`
+	Concurrency    int `mapstructure:"concurrency"`
+	MaxRetry       int `mapstructure:"max_retry"`
+	TaskTimeoutSec int `mapstructure:"task_timeout_sec"`
+
+	// Retry is the backoff between attempts of failed tasks, in queues
+	// without their own.
+	Retry RetryBackoff `mapstructure:"retry"`
+
+	// Queues configures each queue (critical, notifications, campaigns, low)
+	// by name; see QueueOptions.
+	Queues map[string]QueueOptions `mapstructure:"queues"`
+
+	// CriticalTypes are the notification types sent through the critical
+	// queue instead of the notifications queue.
+	CriticalTypes []string `mapstructure:"critical_types"`
+
+	// DirectSend makes the server send CriticalTypes itself, in the request,
+	// when their task cannot be enqueued because Redis is unreachable.
+	DirectSend bool `mapstructure:"direct_send"`
+}
+
+// QueueOptions is how workers process one queue. Without a Concurrency the
+// queue shares the Concurrency-sized main pool, picked by Weight relative to
+// the other shared queues; with one it gets a pool of that many workers to
+// itself, so it can neither starve nor be starved by the others.
+// A queue with its own Retry backoff uses it instead of queue.retry.
+type QueueOptions struct {
+	Concurrency int           `mapstructure:"concurrency"`
+	Weight      int           `mapstructure:"weight"`
+	Retry       *RetryBackoff `mapstructure:"retry"`
+}
+
+// RetryBackoff is the wait before each retry of a failed task. With a
+// ScheduleSec, the n-th retry waits its n-th entry, and later retries its
+// last. Otherwise the first retry waits DelaySec and each later one
+// Multiplier times the one before (0 or 1: a fixed delay), up to MaxDelaySec
+// (0: no cap). Jitter (0–1) moves each wait by up to that fraction either way.
+type RetryBackoff struct {
+	DelaySec    int     `mapstructure:"delay_sec"`
+	Multiplier  float64 `mapstructure:"multiplier"`
+	MaxDelaySec int     `mapstructure:"max_delay_sec"`
+	Jitter      float64 `mapstructure:"jitter"`
+	ScheduleSec []int   `mapstructure:"schedule_sec"`
 }
 
 // RecipientRateLimitConfig holds per-recipient rate limiting settings.
 type RecipientRateLimitConfig struct {
-	MaxPerHour int `mapstructure:"max_per_hour"`
+	MaxPerHour int  `mapstructure:"max_per_hour"`
+	FailClosed bool `mapstructure:"fail_closed"`
+
+	// Limits override MaxPerHour per channel ("sms"), notification type
+	// ("password_changed"), or both ("email:magic_link"). 0 exempts.
+	Limits map[string]int `mapstructure:"limits"`
+}
+
+// RecipientsConfig holds multi-recipient request settings.
+type RecipientsConfig struct {
+	MaxPerRequest int  `mapstructure:"max_per_request"`
+	FanOut        bool `mapstructure:"fan_out"`
+	BatchSize     int  `mapstructure:"batch_size"`
 }
 
 // ReaperConfigYAML holds stale task reaper settings (durations as seconds for YAML/env compat).
 type ReaperConfigYAML struct {
-	IntervalSec       int `mapstructure:"interval_sec"`
-	StaleThresholdSec int `mapstructure:"stale_threshold_sec"`
-	BatchSize         int `mapstructure:"batch_size"`
+	IntervalSec         int `mapstructure:"interval_sec"`
+	StaleThresholdSec   int `mapstructure:"stale_threshold_sec"`
+	BatchSize           int `mapstructure:"batch_size"`
+	MaxRecoveryAttempts int `mapstructure:"max_recovery_attempts"`
+}
+
+// SchedulerConfigYAML holds recurring notification scheduler settings.
+type SchedulerConfigYAML struct {
+	IntervalSec int `mapstructure:"interval_sec"`
+	BatchSize   int `mapstructure:"batch_size"`
+}
+
+// CampaignsConfig holds campaign fan-out settings.
+type CampaignsConfig struct {
+	BatchSize        int `mapstructure:"batch_size"`
+	BatchIntervalSec int `mapstructure:"batch_interval_sec"`
+	MaxAudience      int `mapstructure:"max_audience"`
+
+	// ProgressIntervalSec is how often a campaign's progress stream checks
+	// for changes.
+	ProgressIntervalSec int `mapstructure:"progress_interval_sec"`
+}
+
+// TemplatesConfig holds template rendering settings.
+type TemplatesConfig struct {
+	// RenderAtEnqueue renders templates when a request is accepted and stores
+	// the content on the log, so later template edits cannot change it.
+	RenderAtEnqueue bool `mapstructure:"render_at_enqueue"`
+
+	// SMSMaxSegments is how many segments an SMS body may take before it is
+	// logged, or truncated with SMSTruncate. 0 disables the check.
+	SMSMaxSegments int  `mapstructure:"sms_max_segments"`
+	SMSTruncate    bool `mapstructure:"sms_truncate"`
+
+	// SanitizeTypes lists the notification types whose template data has
+	// HTML stripped from its string values before rendering, for types that
+	// carry user-generated content.
+	SanitizeTypes []string `mapstructure:"sanitize_types"`
+
+	// Variants lists the A/B tests of templates, by notification type. Each
+	// variant takes Percent of the type's recipients; the rest get the
+	// type's own templates.
+	Variants map[string][]VariantConfig `mapstructure:"variants"`
+
+	// DefaultLocale is the locale subjects are localized in when a request's
+	// data sets no Locale, or one without a translation.
+	DefaultLocale string `mapstructure:"default_locale"`
+
+	// Overrides replace the built-in subject, and the sender's display name,
+	// of notification types, so they change without a release.
+	Overrides map[string]TemplateOverrideConfig `mapstructure:"overrides"`
+}
+
+// TemplateOverrideConfig overrides a notification type's subject and the
+// display name its email is sent under; empty fields keep the defaults.
+type TemplateOverrideConfig struct {
+	Subject  string `mapstructure:"subject"`
+	FromName string `mapstructure:"from_name"`
+}
+
+// VariantConfig is one variant of an A/B test: it renders the type's
+// <template>.<name> templates where they exist, with Subject (when set)
+// replacing the type's subject.
+type VariantConfig struct {
+	Name    string `mapstructure:"name"`
+	Percent int    `mapstructure:"percent"`
+	Subject string `mapstructure:"subject"`
+}
+
+// FallbacksConfig holds the cross-channel fallback rules, keyed by the
+// channel ("push"), notification type ("password_changed"), or both
+// ("push:password_changed") of the notifications they apply to.
+type FallbacksConfig map[string]FallbackConfig
+
+// FallbackConfig is one fallback rule: a notification that has not reached
+// Until ("sent", "delivered", or "opened") AfterSec after it was accepted is
+// sent again on Channel, to the request's fallback_to address.
+type FallbackConfig struct {
+	Channel  string `mapstructure:"channel"`
+	AfterSec int    `mapstructure:"after_sec"`
+	Until    string `mapstructure:"until"` // default "delivered"
+}
+
+// TrackingConfig holds click tracking settings.
+type TrackingConfig struct {
+	ClickEnabled bool   `mapstructure:"click_enabled"`
+	BaseURL      string `mapstructure:"base_url"`
+	Secret       string `mapstructure:"secret"`
+}
+
+// ValidationConfig holds recipient validation settings.
+type ValidationConfig struct {
+	CheckMX       bool `mapstructure:"check_mx"`
+	MXCacheTTLSec int  `mapstructure:"mx_cache_ttl_sec"`
+
+	// MaxDataBytes caps the JSON size of a send's or campaign's template data.
+	MaxDataBytes int `mapstructure:"max_data_bytes"`
+}
+
+// SuppressionConfig holds recipient suppression settings.
+type SuppressionConfig struct {
+	Bounced bool `mapstructure:"bounced"`
+
+	// SoftBounceRetries is how many times a soft-bounced notification is sent
+	// again, the first after SoftBounceDelaySec and each next one after twice
+	// the previous wait. Only hard bounces suppress the recipient.
+	SoftBounceRetries  int `mapstructure:"soft_bounce_retries"`
+	SoftBounceDelaySec int `mapstructure:"soft_bounce_delay_sec"`
+}
+
+// SettingsConfig holds runtime settings (database overrides) polling.
+type SettingsConfig struct {
+	PollIntervalSec int `mapstructure:"poll_interval_sec"`
+}
+
+// WebhooksConfig holds inbound provider webhook settings.
+type WebhooksConfig struct {
+	Resend ResendWebhookConfig `mapstructure:"resend"`
+	SES    SESWebhookConfig    `mapstructure:"ses"`
+	Twilio TwilioWebhookConfig `mapstructure:"twilio"`
+}
+
+// ResendWebhookConfig holds the Resend webhook settings.
+type ResendWebhookConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// SigningSecret (whsec_…) verifies the svix-signature header; the route
+	// then no longer needs the API key. Empty keeps it behind the API key.
+	SigningSecret string `mapstructure:"signing_secret"`
+}
+
+// SESWebhookConfig holds the SES-over-SNS webhook settings.
+type SESWebhookConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// TopicARNs restricts accepted SNS messages to these topics. Empty accepts any topic.
+	TopicARNs []string `mapstructure:"topic_arns"`
+}
+
+// TwilioWebhookConfig holds the Twilio status callback settings.
+type TwilioWebhookConfig struct {
+	Enabled   bool   `mapstructure:"enabled"`
+	AuthToken string `mapstructure:"auth_token"` // signs X-Twilio-Signature
+
+	// BaseURL is the public scheme and host Twilio calls. Signatures cover the
+	// full URL, so set it when a proxy rewrites the host; empty uses the request's.
+	BaseURL string `mapstructure:"base_url"`
+}
+
+// DomainsConfig holds the sending-domain admin API, which registers domains
+// with the email provider and reports their DNS verification. An empty
+// Provider leaves the routes out.
+type DomainsConfig struct {
+	Provider string `mapstructure:"provider"` // resend or ses
+
+	// APIKey is the Resend key for the domains API, which needs full access
+	// where sending needs only a sending key. Empty uses email.api_key.
+	APIKey string `mapstructure:"api_key"`
+
+	// Region is where new domains are registered when a request names none.
+	// With ses it is the AWS region, and the only one domains can be in.
+	Region string `mapstructure:"region"`
+
+	SES SESDomainsConfig `mapstructure:"ses"`
+}
+
+// SESDomainsConfig holds the AWS credentials for managing SES domains.
+type SESDomainsConfig struct {
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+	SessionToken    string `mapstructure:"session_token"` // temporary credentials only
+}
+
+// MetricsConfig holds metrics export settings.
+type MetricsConfig struct {
+	// Prometheus serves the delivery latency histograms and the queue backlog
+	// gauges at GET /metrics. The endpoint is public like /health, so restrict
+	// it at the network level.
+	Prometheus bool `mapstructure:"prometheus"`
+
+	// Pushgateway, when set, is the URL of a Prometheus Pushgateway the
+	// servers push the queue backlog gauges to every PushIntervalSec under
+	// PushJob, for autoscalers that cannot scrape the servers.
+	Pushgateway     string `mapstructure:"pushgateway"`
+	PushIntervalSec int    `mapstructure:"push_interval_sec"`
+	PushJob         string `mapstructure:"push_job"`
+}
+
+// AlertsConfig holds failure-rate alerting settings. The worker checks the
+// rates every IntervalSec over the last WindowSec and fires each configured
+// action; an alert then stays quiet for CooldownSec.
+type AlertsConfig struct {
+	Enabled     bool `mapstructure:"enabled"`
+	IntervalSec int  `mapstructure:"interval_sec"`
+	WindowSec   int  `mapstructure:"window_sec"`
+	CooldownSec int  `mapstructure:"cooldown_sec"`
+
+	// Rules are keyed by the channel ("email"), notification type
+	// ("magic_link"), or both ("email:magic_link") of the sends they watch.
+	Rules map[string]AlertRuleConfig `mapstructure:"rules"`
+
+	Slack     SlackAlertConfig     `mapstructure:"slack"`
+	PagerDuty PagerDutyAlertConfig `mapstructure:"pagerduty"`
+	Webhook   WebhookAlertConfig   `mapstructure:"webhook"`
+}
+
+// AlertRuleConfig is one alert rule. Rates are fractions (0.1 is 10%); 0
+// leaves that rate unwatched.
+type AlertRuleConfig struct {
+	FailureRate float64 `mapstructure:"failure_rate"`
+	BounceRate  float64 `mapstructure:"bounce_rate"`
+	MinVolume   int64   `mapstructure:"min_volume"` // default 20
+}
+
+// SlackAlertConfig holds the Slack alert action settings; empty disables it.
+type SlackAlertConfig struct {
+	WebhookURL string `mapstructure:"webhook_url"`
+}
+
+// PagerDutyAlertConfig holds the PagerDuty alert action settings; an empty
+// routing key disables it.
+type PagerDutyAlertConfig struct {
+	RoutingKey string `mapstructure:"routing_key"`
+	Severity   string `mapstructure:"severity"`
+}
+
+// WebhookAlertConfig holds the generic webhook alert action settings; an
+// empty URL disables it.
+type WebhookAlertConfig struct {
+	URL     string            `mapstructure:"url"`
+	Headers map[string]string `mapstructure:"headers"`
+}
+
+// Log cache backends.
+const (
+	CacheBackendNone   = "none"   // every read goes to Supabase
+	CacheBackendMemory = "memory" // per process; other processes' writes show after the TTL
+	CacheBackendRedis  = "redis"  // shared, so any process's write invalidates it
+)
+
+// CacheConfig holds the read cache in front of GetByID and idempotency key
+// lookups.
+type CacheConfig struct {
+	Backend    string `mapstructure:"backend"`
+	TTLSec     int    `mapstructure:"ttl_sec"`
+	MaxEntries int    `mapstructure:"max_entries"` // memory backend
+}
+
+// StartupConfig holds startup settings.
+type StartupConfig struct {
+	// WaitMaxSec is how long a starting process retries Redis and the store,
+	// with backoff, before giving up. 0 checks each once.
+	WaitMaxSec int `mapstructure:"wait_max_sec"`
+}
+
+// FlagConfig is a feature flag's rollout: the percent of notifications it is
+// on for, and per-type percents that take precedence.
+type FlagConfig struct {
+	Percent int            `mapstructure:"percent"`
+	Types   map[string]int `mapstructure:"types"`
+}
+
+// FaultsConfig holds fault injection for chaos testing in staging. Rates are
+// fractions of calls, from 0 to 1; nothing is injected unless Enabled.
+type FaultsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// ProviderErrorRate fails sends with a retryable 503 instead of calling
+	// the provider.
+	ProviderErrorRate float64 `mapstructure:"provider_error_rate"`
+
+	// StoreLatencyMs delays StoreLatencyRate of store calls, counting
+	// against supabase.timeout_sec.
+	StoreLatencyMs   int     `mapstructure:"store_latency_ms"`
+	StoreLatencyRate float64 `mapstructure:"store_latency_rate"`
+
+	// RedisErrorRate fails commands on the queue client that enqueues tasks
+	// and on the server's rate limiter connection.
+	RedisErrorRate float64 `mapstructure:"redis_error_rate"`
+}
+
+// CostsConfig holds the price table sends are costed at.
+type CostsConfig struct {
+	// Prices is the estimated cost of one message by channel, then by
+	// provider name ("default" for the channel's other providers), in one
+	// currency of the operator's choosing. Sends without a price are not
+	// costed.
+	Prices map[string]map[string]float64 `mapstructure:"prices"`
+}
+
+// UsageConfig holds usage report settings.
+type UsageConfig struct {
+	// BillingDay is the day of the month (1–28) billing periods start on, at
+	// 00:00 UTC.
+	BillingDay int `mapstructure:"billing_day"`
+
+	// Quotas caps each API key's sends per billing period, by key ID
+	// ("key_" and the first 8 hex digits of the key's SHA-256), with
+	// "default" for the other keys. Usage reports show how much of it is used.
+	Quotas map[string]int `mapstructure:"quotas"`
+}
+
+// DigestsConfig holds notification digest settings.
+type DigestsConfig struct {
+	// Types are the notification types whose sends to one recipient are
+	// combined into one message during bursts.
+	Types []string `mapstructure:"types"`
+
+	// A recipient's pending events are sent once none has arrived for
+	// GracePeriodSec, once the oldest has waited MaxDelaySec, or once there
+	// are MaxSize of them (0: no limit).
+	GracePeriodSec int `mapstructure:"grace_period_sec"`
+	MaxDelaySec    int `mapstructure:"max_delay_sec"`
+	MaxSize        int `mapstructure:"max_size"`
+}
+
+// ReportsConfig holds scheduled report settings.
+type ReportsConfig struct {
+	DailySummary DailySummaryConfig `mapstructure:"daily_summary"`
+}
+
+// DailySummaryConfig holds the daily summary report settings. The server
+// emails the report for the previous 24 hours to Recipients at HourUTC.
+type DailySummaryConfig struct {
+	Enabled    bool     `mapstructure:"enabled"`
+	Recipients []string `mapstructure:"recipients"`
+	HourUTC    int      `mapstructure:"hour_utc"`
+
+	// Quotas are daily send quotas by channel, reported against the day's sends.
+	Quotas map[string]int64 `mapstructure:"quotas"`
 }
 
 // Load reads configuration from config.yaml and environment variables.
 // Environment variables use the NOTIFLY_ prefix and underscore separators.
 // Example: NOTIFLY_SERVER_PORT overrides server.port in config.yaml.
 func Load() (*Config, error) {
+	v := newViper()
+
+	// Read config file (optional — env vars can provide everything)
+	if err := v.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return nil, fmt.Errorf("reading config file: %w", err)
+		}
+	}
+
+	return unmarshal(v)
+}
+
+// newViper returns a viper instance with config paths, env bindings, and defaults set.
+func newViper() *viper.Viper {
 	v := viper.New()
 
 	// Config file settings
@@ -109,27 +607,118 @@ func Load() (*Config, error) {
 	// Defaults
 	v.SetDefault("server.port", 8081)
 	v.SetDefault("server.mode", "debug")
+	v.SetDefault("server.max_body_bytes", 1<<20) // 1 MiB
+	v.SetDefault("server.request_timeout_sec", 10)
+	v.SetDefault("log.level", "info")
+	v.SetDefault("log.access_sample", map[string]float64{"/health": 0.01})
 	v.SetDefault("email.provider", "resend")
+	v.SetDefault("email.dryrun_latency_ms", 0)
+	v.SetDefault("email.mock_latency_ms", 0)
+	v.SetDefault("email.mock_failure_rate", 0.0)
+	v.SetDefault("email.mock_failure_status", 503)
+	v.SetDefault("email.senders", map[string]any{})
+	v.SetDefault("email.sender_types", map[string]string{})
+	v.SetDefault("rate_limit.backend", RateLimitBackendMemory)
 	v.SetDefault("rate_limit.requests_per_second", 10)
 	v.SetDefault("rate_limit.burst", 20)
+	v.SetDefault("rate_limit.max_entries", 10000)
 	v.SetDefault("redis.address", "localhost:6379")
 	v.SetDefault("redis.password", "")
 	v.SetDefault("redis.db", 0)
+	v.SetDefault("supabase.timeout_sec", 10)
+	v.SetDefault("supabase.max_retries", 2)
+	v.SetDefault("supabase.retry_backoff_ms", 200)
+	v.SetDefault("supabase.compress_data_bytes", 0)
+	v.SetDefault("supabase.read_replica_url", "")
 	v.SetDefault("queue.concurrency", 10)
 	v.SetDefault("queue.max_retry", 5)
-	v.SetDefault("queue.retry_delay_sec", 30)
+	v.SetDefault("queue.retry.delay_sec", 30)
+	v.SetDefault("queue.retry.multiplier", 2)
+	v.SetDefault("queue.retry.max_delay_sec", 3600)
+	v.SetDefault("queue.retry.jitter", 0)
+	v.SetDefault("queue.retry.schedule_sec", []int{})
+	v.SetDefault("queue.task_timeout_sec", 30)
+	v.SetDefault("queue.queues.critical.concurrency", 0)
+	v.SetDefault("queue.queues.critical.weight", 20)
+	v.SetDefault("queue.queues.notifications.concurrency", 0)
+	v.SetDefault("queue.queues.notifications.weight", 10)
+	v.SetDefault("queue.queues.campaigns.concurrency", 0)
+	v.SetDefault("queue.queues.campaigns.weight", 3)
+	v.SetDefault("queue.queues.low.concurrency", 0)
+	v.SetDefault("queue.queues.low.weight", 1)
+	v.SetDefault("queue.critical_types", []string{})
+	v.SetDefault("queue.direct_send", false)
 	v.SetDefault("recipient_rate_limit.max_per_hour", 3)
+	v.SetDefault("recipient_rate_limit.fail_closed", false)
+	v.SetDefault("recipients.max_per_request", 50)
+	v.SetDefault("recipients.fan_out", true)
+	v.SetDefault("recipients.batch_size", 100)
 	v.SetDefault("reaper.interval_sec", 300)         // 5 minutes
 	v.SetDefault("reaper.stale_threshold_sec", 600)   // 10 minutes
 	v.SetDefault("reaper.batch_size", 50)
+	v.SetDefault("reaper.max_recovery_attempts", 3)
+	v.SetDefault("scheduler.interval_sec", 30)
+	v.SetDefault("scheduler.batch_size", 100)
+	v.SetDefault("campaigns.batch_size", 100)
+	v.SetDefault("campaigns.batch_interval_sec", 10)
+	v.SetDefault("campaigns.max_audience", 10000)
+	v.SetDefault("campaigns.progress_interval_sec", 2)
+	v.SetDefault("templates.render_at_enqueue", false)
+	v.SetDefault("templates.sms_max_segments", 3)
+	v.SetDefault("templates.sms_truncate", false)
+	v.SetDefault("templates.sanitize_types", []string{})
+	v.SetDefault("templates.variants", map[string]any{})
+	v.SetDefault("templates.default_locale", "en")
+	v.SetDefault("templates.overrides", map[string]any{})
+	v.SetDefault("tracking.click_enabled", false)
+	v.SetDefault("validation.check_mx", false)
+	v.SetDefault("validation.mx_cache_ttl_sec", 3600)
+	v.SetDefault("validation.max_data_bytes", 65536)
+	v.SetDefault("suppression.bounced", false)
+	v.SetDefault("suppression.soft_bounce_retries", 3)
+	v.SetDefault("suppression.soft_bounce_delay_sec", 1800)
+	v.SetDefault("settings.poll_interval_sec", 30)
+	v.SetDefault("webhooks.resend.enabled", true)
+	v.SetDefault("webhooks.resend.signing_secret", "")
+	v.SetDefault("webhooks.ses.enabled", false)
+	v.SetDefault("webhooks.twilio.enabled", false)
+	v.SetDefault("domains.provider", "")
+	v.SetDefault("domains.api_key", "")
+	v.SetDefault("domains.region", "")
+	v.SetDefault("domains.ses.access_key_id", "")
+	v.SetDefault("domains.ses.secret_access_key", "")
+	v.SetDefault("domains.ses.session_token", "")
+	v.SetDefault("metrics.prometheus", false)
+	v.SetDefault("metrics.pushgateway", "")
+	v.SetDefault("metrics.push_interval_sec", 15)
+	v.SetDefault("metrics.push_job", "notifly")
+	v.SetDefault("alerts.enabled", false)
+	v.SetDefault("alerts.interval_sec", 60)
+	v.SetDefault("alerts.window_sec", 900)
+	v.SetDefault("alerts.cooldown_sec", 1800)
+	v.SetDefault("alerts.pagerduty.severity", "error")
+	v.SetDefault("reports.daily_summary.enabled", false)
+	v.SetDefault("reports.daily_summary.hour_utc", 8)
+	v.SetDefault("startup.wait_max_sec", 60)
+	v.SetDefault("cache.backend", CacheBackendNone)
+	v.SetDefault("cache.ttl_sec", 5)
+	v.SetDefault("cache.max_entries", 10000)
+	v.SetDefault("faults.enabled", false)
+	v.SetDefault("costs.prices", map[string]any{})
+	v.SetDefault("usage.billing_day", 1)
+	v.SetDefault("usage.quotas", map[string]any{})
+	v.SetDefault("digests.types", []string{})
+	v.SetDefault("digests.grace_period_sec", 60)
+	v.SetDefault("digests.max_delay_sec", 300)
+	v.SetDefault("digests.max_size", 50)
+	v.SetDefault("flags.click_tracking.percent", 100)
+	v.SetDefault("flags.provider_canary.percent", 0)
 
-	// Read config file (optional — env vars can provide everything)
-	if err := v.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return nil, fmt.Errorf("reading config file: %w", err)
-		}
-	}
+	return v
+}
 
+// unmarshal decodes the viper state into a Config.
+func unmarshal(v *viper.Viper) (*Config, error) {
 	var cfg Config
 	if err := v.Unmarshal(&cfg); err != nil {
 		return nil, fmt.Errorf("unmarshaling config: %w", err)
@@ -144,5 +733,23 @@ func Load() (*Config, error) {
 		cfg.Auth.APIKeys = keys
 	}
 
+	// Same for SNS topic ARNs
+	if arnsStr := v.GetString("webhooks.ses.topic_arns"); arnsStr != "" && len(cfg.Webhooks.SES.TopicARNs) == 0 {
+		arns := strings.Split(arnsStr, ",")
+		for i := range arns {
+			arns[i] = strings.TrimSpace(arns[i])
+		}
+		cfg.Webhooks.SES.TopicARNs = arns
+	}
+
+	// And daily summary recipients
+	if toStr := v.GetString("reports.daily_summary.recipients"); toStr != "" && len(cfg.Reports.DailySummary.Recipients) == 0 {
+		to := strings.Split(toStr, ",")
+		for i := range to {
+			to[i] = strings.TrimSpace(to[i])
+		}
+		cfg.Reports.DailySummary.Recipients = to
+	}
+
 	return &cfg, nil
 }
@@ -0,0 +1,640 @@
+package config
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+	"github.com/badrkarrachai/notifly/pkg/template"
+)
+
+// Role selects which process components a Config is validated for.
+// Roles combine with |, e.g. RoleServer|RoleWorker for the single-binary mode.
+type Role int
+
+const (
+	RoleServer Role = 1 << iota
+	RoleWorker
+
+	RoleAll = RoleServer | RoleWorker
+)
+
+// minStaleThresholdSec comfortably exceeds the provider HTTP timeout (10s).
+const minStaleThresholdSec = 60
+
+// ValidationError lists every problem found in a Config.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration (%d problem(s)): %s", len(e.Problems), strings.Join(e.Problems, "; "))
+}
+
+// Validate checks that the settings required by the given role are present and
+// sane. It returns a *ValidationError listing every problem, or nil.
+// Each problem names the env var to set so the fix is obvious from the log.
+func (c *Config) Validate(role Role) error {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	// Shared by every role
+	switch c.Server.Mode {
+	case "debug", "release", "test":
+	default:
+		add("server.mode must be debug, release, or test, got %q (NOTIFLY_SERVER_MODE)", c.Server.Mode)
+	}
+	if c.Log.Level != "" && !validLogLevel(c.Log.Level) {
+		add("log.level must be debug, info, warn, or error, got %q (NOTIFLY_LOG_LEVEL)", c.Log.Level)
+	}
+	for route, rate := range c.Log.AccessSample {
+		if rate < 0 || rate > 1 {
+			add("log.access_sample.%s must be between 0 and 1, got %g", route, rate)
+		}
+	}
+	if c.Redis.Address == "" {
+		add("redis.address is required (NOTIFLY_REDIS_ADDRESS)")
+	}
+	if c.Redis.DB < 0 {
+		add("redis.db must not be negative, got %d (NOTIFLY_REDIS_DB)", c.Redis.DB)
+	}
+	if c.Supabase.URL == "" {
+		add("supabase.url is required (NOTIFLY_SUPABASE_URL)")
+	} else if !isHTTPURL(c.Supabase.URL) {
+		add("supabase.url must be an http(s) URL, got %q (NOTIFLY_SUPABASE_URL)", c.Supabase.URL)
+	}
+	if c.Supabase.ServiceKey == "" {
+		add("supabase.service_key is required (NOTIFLY_SUPABASE_SERVICE_KEY)")
+	}
+	if c.Supabase.TimeoutSec < 1 {
+		add("supabase.timeout_sec must be at least 1, got %d (NOTIFLY_SUPABASE_TIMEOUT_SEC)", c.Supabase.TimeoutSec)
+	}
+	if c.Supabase.MaxRetries < 0 {
+		add("supabase.max_retries must not be negative, got %d (NOTIFLY_SUPABASE_MAX_RETRIES)", c.Supabase.MaxRetries)
+	}
+	if c.Supabase.RetryBackoffMs < 0 {
+		add("supabase.retry_backoff_ms must not be negative, got %d (NOTIFLY_SUPABASE_RETRY_BACKOFF_MS)", c.Supabase.RetryBackoffMs)
+	}
+	if c.Supabase.ReadReplicaURL != "" && !isHTTPURL(c.Supabase.ReadReplicaURL) {
+		add("supabase.read_replica_url must be an http(s) URL, got %q (NOTIFLY_SUPABASE_READ_REPLICA_URL)", c.Supabase.ReadReplicaURL)
+	}
+	if c.Supabase.CompressDataBytes < 0 {
+		add("supabase.compress_data_bytes must not be negative, got %d (NOTIFLY_SUPABASE_COMPRESS_DATA_BYTES)", c.Supabase.CompressDataBytes)
+	}
+	if c.Queue.MaxRetry < 0 {
+		add("queue.max_retry must not be negative, got %d (NOTIFLY_QUEUE_MAX_RETRY)", c.Queue.MaxRetry)
+	}
+	if c.Queue.TaskTimeoutSec < 1 {
+		add("queue.task_timeout_sec must be at least 1, got %d (NOTIFLY_QUEUE_TASK_TIMEOUT_SEC)", c.Queue.TaskTimeoutSec)
+	}
+	switch c.Cache.Backend {
+	case CacheBackendNone:
+	case CacheBackendMemory, CacheBackendRedis:
+		if c.Cache.TTLSec < 1 {
+			add("cache.ttl_sec must be at least 1, got %d (NOTIFLY_CACHE_TTL_SEC)", c.Cache.TTLSec)
+		}
+		if c.Cache.Backend == CacheBackendMemory && c.Cache.MaxEntries < 1 {
+			add("cache.max_entries must be at least 1, got %d (NOTIFLY_CACHE_MAX_ENTRIES)", c.Cache.MaxEntries)
+		}
+	default:
+		add("cache.backend must be %q, %q, or %q, got %q (NOTIFLY_CACHE_BACKEND)", CacheBackendNone, CacheBackendMemory, CacheBackendRedis, c.Cache.Backend)
+	}
+	if c.Startup.WaitMaxSec < 0 {
+		add("startup.wait_max_sec must not be negative, got %d (NOTIFLY_STARTUP_WAIT_MAX_SEC)", c.Startup.WaitMaxSec)
+	}
+	if c.Faults.Enabled {
+		for _, rate := range []struct {
+			key, env string
+			value    float64
+		}{
+			{"faults.provider_error_rate", "NOTIFLY_FAULTS_PROVIDER_ERROR_RATE", c.Faults.ProviderErrorRate},
+			{"faults.store_latency_rate", "NOTIFLY_FAULTS_STORE_LATENCY_RATE", c.Faults.StoreLatencyRate},
+			{"faults.redis_error_rate", "NOTIFLY_FAULTS_REDIS_ERROR_RATE", c.Faults.RedisErrorRate},
+		} {
+			if rate.value < 0 || rate.value > 1 {
+				add("%s must be between 0 and 1, got %g (%s)", rate.key, rate.value, rate.env)
+			}
+		}
+		if c.Faults.StoreLatencyMs < 0 {
+			add("faults.store_latency_ms must not be negative, got %d (NOTIFLY_FAULTS_STORE_LATENCY_MS)", c.Faults.StoreLatencyMs)
+		}
+	}
+	if c.Settings.PollIntervalSec < 1 {
+		add("settings.poll_interval_sec must be at least 1, got %d (NOTIFLY_SETTINGS_POLL_INTERVAL_SEC)", c.Settings.PollIntervalSec)
+	}
+	if c.Templates.SMSMaxSegments < 0 {
+		add("templates.sms_max_segments must not be negative, got %d (NOTIFLY_TEMPLATES_SMS_MAX_SEGMENTS)", c.Templates.SMSMaxSegments)
+	}
+	for _, t := range c.Templates.SanitizeTypes {
+		if !notification.IsValidType(notification.NotificationType(t)) {
+			add("templates.sanitize_types has unknown notification type %q", t)
+		}
+	}
+	for _, t := range c.Digests.Types {
+		if !notification.IsValidType(notification.NotificationType(t)) {
+			add("digests.types has unknown notification type %q", t)
+		}
+	}
+	for _, t := range c.Queue.CriticalTypes {
+		if !notification.IsValidType(notification.NotificationType(t)) {
+			add("queue.critical_types has unknown notification type %q", t)
+		}
+	}
+	for t, variants := range c.Templates.Variants {
+		if !notification.IsValidType(notification.NotificationType(t)) {
+			add("templates.variants has unknown notification type %q", t)
+			continue
+		}
+		total := 0
+		names := make(map[string]bool, len(variants))
+		for _, variant := range variants {
+			switch {
+			case !notification.IsValidVariantName(variant.Name):
+				add("templates.variants.%s has invalid variant name %q: use lowercase letters, digits, and underscores", t, variant.Name)
+			case names[variant.Name]:
+				add("templates.variants.%s has variant %q twice", t, variant.Name)
+			}
+			names[variant.Name] = true
+			if variant.Percent < 0 || variant.Percent > 100 {
+				add("templates.variants.%s.%s percent must be between 0 and 100, got %d", t, variant.Name, variant.Percent)
+			}
+			total += variant.Percent
+		}
+		if total > 100 {
+			add("templates.variants.%s percents must add up to at most 100, got %d", t, total)
+		}
+	}
+	if !template.IsValidLocale(c.Templates.DefaultLocale) {
+		add("templates.default_locale must be a language tag such as en or pt-BR, got %q (NOTIFLY_TEMPLATES_DEFAULT_LOCALE)", c.Templates.DefaultLocale)
+	}
+	for t := range c.Templates.Overrides {
+		if !notification.IsValidType(notification.NotificationType(t)) {
+			add("templates.overrides has unknown notification type %q", t)
+		}
+	}
+	for name, sender := range c.Email.Senders {
+		if _, err := mail.ParseAddress(sender.Address); err != nil {
+			add("email.senders.%s.address is not a valid address, got %q", name, sender.Address)
+		}
+	}
+	for t, name := range c.Email.SenderTypes {
+		if !notification.IsValidType(notification.NotificationType(t)) {
+			add("email.sender_types has unknown notification type %q", t)
+		}
+		if _, ok := c.Email.Senders[name]; !ok {
+			add("email.sender_types.%s names unknown sender %q", t, name)
+		}
+	}
+	for name, flag := range c.Flags {
+		if !notification.IsValidFlag(notification.FeatureFlag(name)) {
+			add("flags has unknown feature flag %q", name)
+			continue
+		}
+		if flag.Percent < 0 || flag.Percent > 100 {
+			add("flags.%s.percent must be between 0 and 100, got %d", name, flag.Percent)
+		}
+		for t, percent := range flag.Types {
+			if !notification.IsValidType(notification.NotificationType(t)) {
+				add("flags.%s.types has unknown notification type %q", name, t)
+			} else if percent < 0 || percent > 100 {
+				add("flags.%s.types.%s must be between 0 and 100, got %d", name, t, percent)
+			}
+		}
+	}
+	if c.Tracking.ClickEnabled {
+		if !isHTTPURL(c.Tracking.BaseURL) {
+			add("tracking.base_url must be an http(s) URL when click tracking is enabled, got %q (NOTIFLY_TRACKING_BASE_URL)", c.Tracking.BaseURL)
+		}
+		if c.Tracking.Secret == "" {
+			add("tracking.secret is required when click tracking is enabled (NOTIFLY_TRACKING_SECRET)")
+		}
+	}
+
+	if role&RoleServer != 0 {
+		if c.Server.Port < 1 || c.Server.Port > 65535 {
+			add("server.port must be between 1 and 65535, got %d (NOTIFLY_SERVER_PORT)", c.Server.Port)
+		}
+		if c.Server.MaxBodyBytes < 1 {
+			add("server.max_body_bytes must be at least 1, got %d (NOTIFLY_SERVER_MAX_BODY_BYTES)", c.Server.MaxBodyBytes)
+		}
+		if c.Server.RequestTimeoutSec < 1 || c.Server.RequestTimeoutSec >= WriteTimeoutSec {
+			add("server.request_timeout_sec must be between 1 and %d (below the HTTP write timeout), got %d (NOTIFLY_SERVER_REQUEST_TIMEOUT_SEC)", WriteTimeoutSec-1, c.Server.RequestTimeoutSec)
+		}
+		if len(c.Auth.APIKeys) == 0 {
+			add("auth.api_keys needs at least one key — every API request would be rejected (NOTIFLY_AUTH_API_KEYS)")
+		}
+		for i, key := range c.Auth.APIKeys {
+			if strings.TrimSpace(key) == "" {
+				add("auth.api_keys[%d] is empty (NOTIFLY_AUTH_API_KEYS)", i)
+			}
+		}
+		if c.RateLimit.Backend != RateLimitBackendMemory && c.RateLimit.Backend != RateLimitBackendRedis {
+			add("rate_limit.backend must be %q or %q, got %q (NOTIFLY_RATE_LIMIT_BACKEND)", RateLimitBackendMemory, RateLimitBackendRedis, c.RateLimit.Backend)
+		}
+		if c.RateLimit.RequestsPerSecond <= 0 {
+			add("rate_limit.requests_per_second must be positive, got %g (NOTIFLY_RATE_LIMIT_REQUESTS_PER_SECOND)", c.RateLimit.RequestsPerSecond)
+		}
+		if c.RateLimit.Burst < 1 {
+			add("rate_limit.burst must be at least 1, got %d (NOTIFLY_RATE_LIMIT_BURST)", c.RateLimit.Burst)
+		}
+		if c.RateLimit.MaxEntries < 1 {
+			add("rate_limit.max_entries must be at least 1, got %d (NOTIFLY_RATE_LIMIT_MAX_ENTRIES)", c.RateLimit.MaxEntries)
+		}
+		if c.RecipientRateLimit.MaxPerHour < 1 {
+			add("recipient_rate_limit.max_per_hour must be at least 1, got %d (NOTIFLY_RECIPIENT_RATE_LIMIT_MAX_PER_HOUR)", c.RecipientRateLimit.MaxPerHour)
+		}
+		for key, limit := range c.RecipientRateLimit.Limits {
+			if !isRateLimitRule(key) {
+				add("recipient_rate_limit.limits key %q must be a channel, a notification type, or channel:type", key)
+			}
+			if limit < 0 {
+				add("recipient_rate_limit.limits.%s must not be negative (0 exempts), got %d", key, limit)
+			}
+		}
+		for key, rule := range c.Fallbacks {
+			if !isRateLimitRule(key) {
+				add("fallbacks key %q must be a channel, a notification type, or channel:type", key)
+			}
+			switch notification.Channel(rule.Channel) {
+			case notification.ChannelEmail, notification.ChannelSMS, notification.ChannelPush:
+				if from, _, _ := strings.Cut(key, ":"); from == rule.Channel {
+					add("fallbacks.%s.channel must differ from the channel it falls back from, got %q", key, rule.Channel)
+				}
+			default:
+				add("fallbacks.%s.channel must be email, sms, or push, got %q", key, rule.Channel)
+			}
+			if rule.AfterSec < 1 {
+				add("fallbacks.%s.after_sec must be at least 1, got %d", key, rule.AfterSec)
+			}
+			switch notification.NotificationStatus(rule.Until) {
+			case "", notification.StatusSent, notification.StatusDelivered, notification.StatusOpened:
+			default:
+				add("fallbacks.%s.until must be sent, delivered, or opened, got %q", key, rule.Until)
+			}
+		}
+		if c.Suppression.SoftBounceRetries < 0 {
+			add("suppression.soft_bounce_retries must not be negative (0 disables retries), got %d (NOTIFLY_SUPPRESSION_SOFT_BOUNCE_RETRIES)", c.Suppression.SoftBounceRetries)
+		}
+		if c.Suppression.SoftBounceDelaySec < 60 {
+			add("suppression.soft_bounce_delay_sec must be at least 60, got %d (NOTIFLY_SUPPRESSION_SOFT_BOUNCE_DELAY_SEC)", c.Suppression.SoftBounceDelaySec)
+		}
+		if c.Usage.BillingDay < 1 || c.Usage.BillingDay > 28 {
+			add("usage.billing_day must be between 1 and 28, got %d (NOTIFLY_USAGE_BILLING_DAY)", c.Usage.BillingDay)
+		}
+		for key, quota := range c.Usage.Quotas {
+			if quota < 0 {
+				add("usage.quotas.%s must not be negative, got %d", key, quota)
+			}
+		}
+		if c.Recipients.MaxPerRequest < 1 {
+			add("recipients.max_per_request must be at least 1, got %d (NOTIFLY_RECIPIENTS_MAX_PER_REQUEST)", c.Recipients.MaxPerRequest)
+		}
+		// 100 is the most emails Resend's batch endpoint accepts per call
+		if c.Recipients.BatchSize < 0 || c.Recipients.BatchSize > 100 {
+			add("recipients.batch_size must be between 0 and 100, got %d (NOTIFLY_RECIPIENTS_BATCH_SIZE)", c.Recipients.BatchSize)
+		}
+		if c.Webhooks.Resend.Enabled {
+			if _, err := notification.NewResendWebhookAdapter(c.Webhooks.Resend.SigningSecret); err != nil {
+				add("webhooks.resend.signing_secret must be whsec_ followed by base64 (NOTIFLY_WEBHOOKS_RESEND_SIGNING_SECRET)")
+			}
+		}
+		for i, arn := range c.Webhooks.SES.TopicARNs {
+			if !strings.HasPrefix(arn, "arn:aws") {
+				add("webhooks.ses.topic_arns[%d] must be an SNS topic ARN, got %q (NOTIFLY_WEBHOOKS_SES_TOPIC_ARNS)", i, arn)
+			}
+		}
+		if c.Webhooks.Twilio.Enabled {
+			if c.Webhooks.Twilio.AuthToken == "" {
+				add("webhooks.twilio.auth_token is required when the Twilio webhook is enabled (NOTIFLY_WEBHOOKS_TWILIO_AUTH_TOKEN)")
+			}
+			if c.Webhooks.Twilio.BaseURL != "" && !isHTTPURL(c.Webhooks.Twilio.BaseURL) {
+				add("webhooks.twilio.base_url must be an http(s) URL, got %q (NOTIFLY_WEBHOOKS_TWILIO_BASE_URL)", c.Webhooks.Twilio.BaseURL)
+			}
+		}
+		switch c.Domains.Provider {
+		case "":
+		case "resend":
+			if c.Domains.APIKey == "" && c.Email.APIKey == "" {
+				add("domains.api_key or email.api_key is required for resend domains (NOTIFLY_DOMAINS_API_KEY)")
+			}
+			switch c.Domains.Region {
+			case "", "us-east-1", "eu-west-1", "sa-east-1", "ap-northeast-1":
+			default:
+				add("domains.region must be a Resend region (us-east-1, eu-west-1, sa-east-1, ap-northeast-1), got %q (NOTIFLY_DOMAINS_REGION)", c.Domains.Region)
+			}
+		case "ses":
+			if c.Domains.Region == "" {
+				add("domains.region is required for ses domains (NOTIFLY_DOMAINS_REGION)")
+			}
+			if c.Domains.SES.AccessKeyID == "" || c.Domains.SES.SecretAccessKey == "" {
+				add("domains.ses.access_key_id and domains.ses.secret_access_key are required for ses domains (NOTIFLY_DOMAINS_SES_ACCESS_KEY_ID, NOTIFLY_DOMAINS_SES_SECRET_ACCESS_KEY)")
+			}
+		default:
+			add("domains.provider must be empty, resend, or ses, got %q (NOTIFLY_DOMAINS_PROVIDER)", c.Domains.Provider)
+		}
+		if c.Metrics.Pushgateway != "" {
+			if !isHTTPURL(c.Metrics.Pushgateway) {
+				add("metrics.pushgateway must be an http(s) URL, got %q (NOTIFLY_METRICS_PUSHGATEWAY)", c.Metrics.Pushgateway)
+			}
+			if c.Metrics.PushIntervalSec < 1 {
+				add("metrics.push_interval_sec must be at least 1, got %d (NOTIFLY_METRICS_PUSH_INTERVAL_SEC)", c.Metrics.PushIntervalSec)
+			}
+			if c.Metrics.PushJob == "" {
+				add("metrics.push_job is required when pushing metrics (NOTIFLY_METRICS_PUSH_JOB)")
+			}
+		}
+		if c.Scheduler.IntervalSec < 1 {
+			add("scheduler.interval_sec must be at least 1, got %d (NOTIFLY_SCHEDULER_INTERVAL_SEC)", c.Scheduler.IntervalSec)
+		}
+		if c.Scheduler.BatchSize < 1 {
+			add("scheduler.batch_size must be at least 1, got %d (NOTIFLY_SCHEDULER_BATCH_SIZE)", c.Scheduler.BatchSize)
+		}
+		if c.Campaigns.MaxAudience < 1 {
+			add("campaigns.max_audience must be at least 1, got %d (NOTIFLY_CAMPAIGNS_MAX_AUDIENCE)", c.Campaigns.MaxAudience)
+		}
+		if c.Campaigns.ProgressIntervalSec < 1 {
+			add("campaigns.progress_interval_sec must be at least 1, got %d (NOTIFLY_CAMPAIGNS_PROGRESS_INTERVAL_SEC)", c.Campaigns.ProgressIntervalSec)
+		}
+		if c.Reports.DailySummary.Enabled {
+			report := c.Reports.DailySummary
+			if len(report.Recipients) == 0 {
+				add("reports.daily_summary.recipients needs at least one address when the daily summary is enabled (NOTIFLY_REPORTS_DAILY_SUMMARY_RECIPIENTS)")
+			}
+			for i, to := range report.Recipients {
+				if err := notification.ValidateRecipient(notification.ChannelEmail, to); err != nil {
+					add("reports.daily_summary.recipients[%d] is not a valid address, got %q (NOTIFLY_REPORTS_DAILY_SUMMARY_RECIPIENTS)", i, to)
+				}
+			}
+			if report.HourUTC < 0 || report.HourUTC > 23 {
+				add("reports.daily_summary.hour_utc must be between 0 and 23, got %d (NOTIFLY_REPORTS_DAILY_SUMMARY_HOUR_UTC)", report.HourUTC)
+			}
+			for channel, limit := range report.Quotas {
+				switch notification.Channel(channel) {
+				case notification.ChannelEmail, notification.ChannelSMS, notification.ChannelPush:
+				default:
+					add("reports.daily_summary.quotas key %q must be email, sms, or push", channel)
+				}
+				if limit < 1 {
+					add("reports.daily_summary.quotas.%s must be at least 1, got %d", channel, limit)
+				}
+			}
+		}
+		if c.Queue.DirectSend {
+			if len(c.Queue.CriticalTypes) == 0 {
+				add("queue.critical_types needs at least one type when queue.direct_send is on (NOTIFLY_QUEUE_CRITICAL_TYPES)")
+			}
+			// The server sends critical types itself while the queue is down
+			if role&RoleWorker == 0 {
+				c.validateEmail(add)
+			}
+		}
+		if c.Validation.MaxDataBytes < notification.MaxDataValueSize {
+			add("validation.max_data_bytes must be at least %d, the cap on one data value, got %d (NOTIFLY_VALIDATION_MAX_DATA_BYTES)", notification.MaxDataValueSize, c.Validation.MaxDataBytes)
+		}
+		if c.Validation.CheckMX && c.Validation.MXCacheTTLSec < 0 {
+			add("validation.mx_cache_ttl_sec must not be negative, got %d (NOTIFLY_VALIDATION_MX_CACHE_TTL_SEC)", c.Validation.MXCacheTTLSec)
+		}
+	}
+
+	if role&RoleWorker != 0 {
+		c.validateEmail(add)
+		if c.Queue.Concurrency < 1 {
+			add("queue.concurrency must be at least 1, got %d (NOTIFLY_QUEUE_CONCURRENCY)", c.Queue.Concurrency)
+		}
+		if c.Digests.GracePeriodSec < 1 {
+			add("digests.grace_period_sec must be at least 1, got %d (NOTIFLY_DIGESTS_GRACE_PERIOD_SEC)", c.Digests.GracePeriodSec)
+		}
+		// Events wait queued until their digest is sent; past the stale
+		// threshold the reaper would send them one by one as well
+		if c.Digests.MaxDelaySec < 1 || c.Digests.MaxDelaySec >= c.Reaper.StaleThresholdSec {
+			add("digests.max_delay_sec must be at least 1 and less than reaper.stale_threshold_sec (%d), got %d (NOTIFLY_DIGESTS_MAX_DELAY_SEC)", c.Reaper.StaleThresholdSec, c.Digests.MaxDelaySec)
+		}
+		if c.Digests.GracePeriodSec > c.Digests.MaxDelaySec {
+			add("digests.grace_period_sec must not exceed digests.max_delay_sec (%d), got %d (NOTIFLY_DIGESTS_GRACE_PERIOD_SEC)", c.Digests.MaxDelaySec, c.Digests.GracePeriodSec)
+		}
+		if c.Digests.MaxSize < 0 {
+			add("digests.max_size must not be negative (0 means no limit), got %d (NOTIFLY_DIGESTS_MAX_SIZE)", c.Digests.MaxSize)
+		}
+		for name, q := range c.Queue.Queues {
+			switch name {
+			case "critical", "notifications", "campaigns", "low":
+			default:
+				add("queue.queues key %q must be critical, notifications, campaigns, or low", name)
+			}
+			if q.Concurrency < 0 {
+				add("queue.queues.%s.concurrency must not be negative (0 shares the main pool), got %d", name, q.Concurrency)
+			}
+			if q.Weight < 1 {
+				add("queue.queues.%s.weight must be at least 1, got %d", name, q.Weight)
+			}
+			if q.Retry != nil {
+				validateRetryBackoff("queue.queues."+name+".retry", "", *q.Retry, add)
+			}
+		}
+		validateRetryBackoff("queue.retry", "NOTIFLY_QUEUE_RETRY_", c.Queue.Retry, add)
+		if c.Reaper.IntervalSec < 1 {
+			add("reaper.interval_sec must be at least 1, got %d (NOTIFLY_REAPER_INTERVAL_SEC)", c.Reaper.IntervalSec)
+		}
+		// Anything shorter risks re-enqueuing sends that are still in flight
+		if c.Reaper.StaleThresholdSec < minStaleThresholdSec {
+			add("reaper.stale_threshold_sec must be at least %d so in-flight sends are not re-enqueued, got %d (NOTIFLY_REAPER_STALE_THRESHOLD_SEC)", minStaleThresholdSec, c.Reaper.StaleThresholdSec)
+		}
+		// A task still running when the reaper sweeps would be sent twice
+		if c.Queue.TaskTimeoutSec >= c.Reaper.StaleThresholdSec {
+			add("queue.task_timeout_sec must be less than reaper.stale_threshold_sec (%d), got %d (NOTIFLY_QUEUE_TASK_TIMEOUT_SEC)", c.Reaper.StaleThresholdSec, c.Queue.TaskTimeoutSec)
+		}
+		if c.Reaper.BatchSize < 1 {
+			add("reaper.batch_size must be at least 1, got %d (NOTIFLY_REAPER_BATCH_SIZE)", c.Reaper.BatchSize)
+		}
+		if c.Reaper.MaxRecoveryAttempts < 1 {
+			add("reaper.max_recovery_attempts must be at least 1, got %d (NOTIFLY_REAPER_MAX_RECOVERY_ATTEMPTS)", c.Reaper.MaxRecoveryAttempts)
+		}
+		if c.Campaigns.BatchSize < 1 {
+			add("campaigns.batch_size must be at least 1, got %d (NOTIFLY_CAMPAIGNS_BATCH_SIZE)", c.Campaigns.BatchSize)
+		}
+		if c.Campaigns.BatchIntervalSec < 0 {
+			add("campaigns.batch_interval_sec must not be negative, got %d (NOTIFLY_CAMPAIGNS_BATCH_INTERVAL_SEC)", c.Campaigns.BatchIntervalSec)
+		}
+		for channel, prices := range c.Costs.Prices {
+			switch notification.Channel(channel) {
+			case notification.ChannelEmail, notification.ChannelSMS, notification.ChannelPush:
+			default:
+				add("costs.prices key %q must be email, sms, or push", channel)
+			}
+			for provider, price := range prices {
+				if price < 0 {
+					add("costs.prices.%s.%s must not be negative, got %g", channel, provider, price)
+				}
+			}
+		}
+		if c.Alerts.Enabled {
+			c.validateAlerts(add)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// validateEmail checks the email provider settings, needed wherever sends
+// are made.
+func (c *Config) validateEmail(add func(format string, args ...any)) {
+	switch c.Email.Provider {
+	case "resend":
+		if c.Email.APIKey == "" {
+			add("email.api_key is required (NOTIFLY_EMAIL_API_KEY)")
+		}
+	case "dryrun":
+		if c.Email.DryRunLatencyMs < 0 {
+			add("email.dryrun_latency_ms must not be negative, got %d (NOTIFLY_EMAIL_DRYRUN_LATENCY_MS)", c.Email.DryRunLatencyMs)
+		}
+	case "mock":
+	default:
+		add("email.provider must be resend, dryrun, or mock, got %q (NOTIFLY_EMAIL_PROVIDER)", c.Email.Provider)
+	}
+	if c.Email.Provider == "mock" || c.Email.CanaryProvider == "mock" {
+		if c.Email.MockLatencyMs < 0 {
+			add("email.mock_latency_ms must not be negative, got %d (NOTIFLY_EMAIL_MOCK_LATENCY_MS)", c.Email.MockLatencyMs)
+		}
+		if c.Email.MockFailureRate < 0 || c.Email.MockFailureRate > 1 {
+			add("email.mock_failure_rate must be between 0 and 1, got %v (NOTIFLY_EMAIL_MOCK_FAILURE_RATE)", c.Email.MockFailureRate)
+		}
+		if c.Email.MockFailureStatus < 400 || c.Email.MockFailureStatus > 599 {
+			add("email.mock_failure_status must be an HTTP error status (400–599), got %d (NOTIFLY_EMAIL_MOCK_FAILURE_STATUS)", c.Email.MockFailureStatus)
+		}
+	}
+	switch c.Email.CanaryProvider {
+	case "":
+	case c.Email.Provider:
+		add("email.canary_provider must differ from email.provider, got %q (NOTIFLY_EMAIL_CANARY_PROVIDER)", c.Email.CanaryProvider)
+	case "resend":
+		if c.Email.APIKey == "" {
+			add("email.api_key is required for the resend canary (NOTIFLY_EMAIL_API_KEY)")
+		}
+	case "dryrun", "mock":
+	default:
+		add("email.canary_provider must be resend, dryrun, or mock, got %q (NOTIFLY_EMAIL_CANARY_PROVIDER)", c.Email.CanaryProvider)
+	}
+	if c.Email.FromAddress == "" {
+		add("email.from_address is required (NOTIFLY_EMAIL_FROM_ADDRESS)")
+	} else if _, err := mail.ParseAddress(c.Email.FromAddress); err != nil {
+		add("email.from_address is not a valid address, got %q (NOTIFLY_EMAIL_FROM_ADDRESS)", c.Email.FromAddress)
+	}
+}
+
+// validateAlerts checks the alerting settings, which only matter when alerting is on.
+func (c *Config) validateAlerts(add func(format string, args ...any)) {
+	a := c.Alerts
+	if a.IntervalSec < 1 {
+		add("alerts.interval_sec must be at least 1, got %d (NOTIFLY_ALERTS_INTERVAL_SEC)", a.IntervalSec)
+	}
+	// Outcome counts are kept for a day
+	if a.WindowSec < 60 || a.WindowSec > 86400 {
+		add("alerts.window_sec must be between 60 and 86400, got %d (NOTIFLY_ALERTS_WINDOW_SEC)", a.WindowSec)
+	}
+	if a.CooldownSec < 1 {
+		add("alerts.cooldown_sec must be at least 1, got %d (NOTIFLY_ALERTS_COOLDOWN_SEC)", a.CooldownSec)
+	}
+	if len(a.Rules) == 0 {
+		add("alerts.rules needs at least one rule when alerting is enabled")
+	}
+	for key, rule := range a.Rules {
+		if !isRateLimitRule(key) {
+			add("alerts.rules key %q must be a channel, a notification type, or channel:type", key)
+		}
+		if rule.FailureRate < 0 || rule.FailureRate > 1 {
+			add("alerts.rules.%s.failure_rate must be between 0 and 1, got %g", key, rule.FailureRate)
+		}
+		if rule.BounceRate < 0 || rule.BounceRate > 1 {
+			add("alerts.rules.%s.bounce_rate must be between 0 and 1, got %g", key, rule.BounceRate)
+		}
+		if rule.FailureRate == 0 && rule.BounceRate == 0 {
+			add("alerts.rules.%s needs a failure_rate or bounce_rate", key)
+		}
+		if rule.MinVolume < 0 {
+			add("alerts.rules.%s.min_volume must not be negative, got %d", key, rule.MinVolume)
+		}
+	}
+	if a.Slack.WebhookURL == "" && a.PagerDuty.RoutingKey == "" && a.Webhook.URL == "" {
+		add("alerts needs a slack.webhook_url, pagerduty.routing_key, or webhook.url when alerting is enabled")
+	}
+	if a.Slack.WebhookURL != "" && !isHTTPURL(a.Slack.WebhookURL) {
+		add("alerts.slack.webhook_url must be an http(s) URL (NOTIFLY_ALERTS_SLACK_WEBHOOK_URL)")
+	}
+	switch a.PagerDuty.Severity {
+	case "critical", "error", "warning", "info":
+	default:
+		add("alerts.pagerduty.severity must be critical, error, warning, or info, got %q (NOTIFLY_ALERTS_PAGERDUTY_SEVERITY)", a.PagerDuty.Severity)
+	}
+	if a.Webhook.URL != "" && !isHTTPURL(a.Webhook.URL) {
+		add("alerts.webhook.url must be an http(s) URL, got %q (NOTIFLY_ALERTS_WEBHOOK_URL)", a.Webhook.URL)
+	}
+}
+
+func validLogLevel(level string) bool {
+	switch strings.ToLower(level) {
+	case "debug", "info", "warn", "error":
+		return true
+	}
+	return false
+}
+
+// validateRetryBackoff checks the backoff b configured at key; envPrefix, if
+// any, prefixes the env vars named in problems.
+func validateRetryBackoff(key, envPrefix string, b RetryBackoff, add func(format string, args ...any)) {
+	env := func(name string) string {
+		if envPrefix == "" {
+			return ""
+		}
+		return " (" + envPrefix + name + ")"
+	}
+	for i, sec := range b.ScheduleSec {
+		if sec < 1 {
+			add("%s.schedule_sec[%d] must be at least 1, got %d%s", key, i, sec, env("SCHEDULE_SEC"))
+		}
+	}
+	if len(b.ScheduleSec) > 0 {
+		return // the schedule replaces the exponential settings
+	}
+	if b.DelaySec < 1 {
+		add("%s.delay_sec must be at least 1, got %d%s", key, b.DelaySec, env("DELAY_SEC"))
+	}
+	if b.Multiplier != 0 && b.Multiplier < 1 {
+		add("%s.multiplier must be 0 or at least 1, got %g%s", key, b.Multiplier, env("MULTIPLIER"))
+	}
+	if b.MaxDelaySec != 0 && b.MaxDelaySec < b.DelaySec {
+		add("%s.max_delay_sec must be 0 (no cap) or at least delay_sec (%d), got %d%s", key, b.DelaySec, b.MaxDelaySec, env("MAX_DELAY_SEC"))
+	}
+	if b.Jitter < 0 || b.Jitter > 1 {
+		add("%s.jitter must be between 0 and 1, got %g%s", key, b.Jitter, env("JITTER"))
+	}
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// isRateLimitRule reports whether key names a channel, a notification type, or
+// a channel:type pair.
+func isRateLimitRule(key string) bool {
+	isChannel := func(s string) bool {
+		switch notification.Channel(s) {
+		case notification.ChannelEmail, notification.ChannelSMS, notification.ChannelPush:
+			return true
+		}
+		return false
+	}
+	isType := func(s string) bool { return notification.IsValidType(notification.NotificationType(s)) }
+
+	if channel, notifType, ok := strings.Cut(key, ":"); ok {
+		return isChannel(channel) && isType(notifType)
+	}
+	return isChannel(key) || isType(key)
+}
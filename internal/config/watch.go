@@ -0,0 +1,72 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch reloads configuration when config.yaml changes or the process receives
+// SIGHUP, and passes the freshly loaded Config to onReload. Reloads are
+// serialized. A reload that fails to load or to validate for role is logged and
+// skipped, leaving the running values untouched.
+//
+// Callers decide which values to apply: only settings with thread-safe setters
+// (rate limits, reaper timings, log level, provider API keys) take effect
+// without a restart. Ports, Redis, Supabase, and queue concurrency do not.
+//
+// Cancelling ctx stops the SIGHUP listener and makes later file events no-ops,
+// but viper offers no way to stop its file watcher: the watcher goroutine lives
+// for the rest of the process, so call Watch once per process.
+func Watch(ctx context.Context, role Role, onReload func(*Config)) {
+	var mu sync.Mutex
+	reload := func(trigger string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		cfg, err := Load()
+		if err != nil {
+			slog.Error("config reload failed — keeping current values", "trigger", trigger, "error", err)
+			return
+		}
+		if err := cfg.Validate(role); err != nil {
+			slog.Error("reloaded config is invalid — keeping current values", "trigger", trigger, "error", err)
+			return
+		}
+		onReload(cfg)
+		slog.Info("configuration reloaded", "trigger", trigger)
+	}
+
+	// File watching needs a config file; env-only deployments rely on SIGHUP
+	v := newViper()
+	if err := v.ReadInConfig(); err == nil {
+		v.OnConfigChange(func(e fsnotify.Event) {
+			reload("file")
+		})
+		v.WatchConfig()
+		slog.Info("watching config file for changes", "file", v.ConfigFileUsed())
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				reload("sighup")
+			}
+		}
+	}()
+}
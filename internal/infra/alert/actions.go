@@ -0,0 +1,131 @@
+// Package alert delivers failure-rate alerts to Slack, PagerDuty, and generic
+// webhooks, and keeps alert cooldowns in Redis so replicas fire each alert once.
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+)
+
+var (
+	_ notification.AlertAction = (*Slack)(nil)
+	_ notification.AlertAction = (*PagerDuty)(nil)
+	_ notification.AlertAction = (*Webhook)(nil)
+)
+
+// Slack posts alerts to a Slack incoming webhook.
+type Slack struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewSlack creates a Slack action posting to webhookURL.
+func NewSlack(webhookURL string) *Slack {
+	return &Slack{webhookURL: webhookURL, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Name implements notification.AlertAction.
+func (s *Slack) Name() string { return "slack" }
+
+// Fire posts the alert summary as a message.
+func (s *Slack) Fire(ctx context.Context, alert *notification.Alert) error {
+	return postJSON(ctx, s.client, s.webhookURL, map[string]string{"text": alert.Summary()}, nil)
+}
+
+// pagerDutyEventsURL is the PagerDuty Events API v2 endpoint.
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty triggers PagerDuty incidents through the Events API v2.
+type PagerDuty struct {
+	routingKey string
+	severity   string
+	client     *http.Client
+}
+
+// NewPagerDuty creates a PagerDuty action for the service integration with
+// routingKey. severity is critical, error, warning, or info.
+func NewPagerDuty(routingKey, severity string) *PagerDuty {
+	return &PagerDuty{routingKey: routingKey, severity: severity, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Name implements notification.AlertAction.
+func (p *PagerDuty) Name() string { return "pagerduty" }
+
+// Fire triggers an event deduplicated by the alert key, so an alert that
+// fires again after its cooldown updates the open incident.
+func (p *PagerDuty) Fire(ctx context.Context, alert *notification.Alert) error {
+	event := map[string]any{
+		"routing_key":  p.routingKey,
+		"event_action": "trigger",
+		"dedup_key":    "notifly:" + alert.Key(),
+		"payload": map[string]any{
+			"summary":        alert.Summary(),
+			"source":         "notifly",
+			"severity":       p.severity,
+			"timestamp":      alert.FiredAt.Format(time.RFC3339),
+			"custom_details": alert,
+		},
+	}
+	return postJSON(ctx, p.client, pagerDutyEventsURL, event, nil)
+}
+
+// Webhook posts alerts as JSON to any URL.
+type Webhook struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewWebhook creates a webhook action posting to url with the given extra
+// headers (e.g. Authorization).
+func NewWebhook(url string, headers map[string]string) *Webhook {
+	return &Webhook{url: url, headers: headers, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Name implements notification.AlertAction.
+func (w *Webhook) Name() string { return "webhook" }
+
+// Fire posts the alert with its summary.
+func (w *Webhook) Fire(ctx context.Context, alert *notification.Alert) error {
+	body := struct {
+		*notification.Alert
+		Summary string `json:"summary"`
+	}{alert, alert.Summary()}
+	return postJSON(ctx, w.client, w.url, body, w.headers)
+}
+
+// postJSON posts body as JSON and fails on any non-2xx response.
+func postJSON(ctx context.Context, client *http.Client, url string, body any, headers map[string]string) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshaling alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("creating alert request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending alert: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert endpoint returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ notification.AlertCooldown = (*RedisCooldown)(nil)
+
+// cooldownKeyPrefix prefixes the Redis keys of running alert cooldowns.
+const cooldownKeyPrefix = "notifly:alert:cooldown:"
+
+// RedisCooldown keeps alert cooldowns as expiring Redis keys.
+type RedisCooldown struct {
+	client *redis.Client
+}
+
+// NewRedisCooldown creates a Redis-backed cooldown store.
+func NewRedisCooldown(redisAddr, password string, db int) *RedisCooldown {
+	return &RedisCooldown{
+		client: redis.NewClient(&redis.Options{
+			Addr:     redisAddr,
+			Password: password,
+			DB:       db,
+		}),
+	}
+}
+
+// TryStart sets the cooldown key with SET NX, so of several replicas checking
+// the same alert exactly one starts its cooldown.
+func (c *RedisCooldown) TryStart(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	ok, err := c.client.SetNX(ctx, cooldownKeyPrefix+key, time.Now().UTC().Format(time.RFC3339), ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("starting alert cooldown %s: %w", key, err)
+	}
+	return ok, nil
+}
+
+// Close closes the Redis connection.
+func (c *RedisCooldown) Close() error {
+	return c.client.Close()
+}
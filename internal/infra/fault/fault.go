@@ -0,0 +1,162 @@
+// Package fault injects failures for chaos testing in staging: provider send
+// errors, store latency, and Redis command errors, each at a configured rate.
+// Nothing here runs unless faults.enabled is set.
+package fault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+	"github.com/badrkarrachai/notifly/pkg/notification"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrInjected marks every injected failure, so it can be told apart in logs.
+var ErrInjected = errors.New("injected fault")
+
+// hit reports whether an event with probability rate (0–1) happens.
+func hit(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
+
+var (
+	_ notification.MetadataProvider      = (*provider)(nil)
+	_ notification.MetadataBatchProvider = (*batchProvider)(nil)
+	_ notification.NamedProvider         = (*provider)(nil)
+	_ redis.Hook                         = (*RedisHook)(nil)
+)
+
+// Provider wraps p so that a fraction rate of its sends fail with a 503, as
+// a provider outage would, without calling p. The result is a BatchProvider
+// exactly when p is one.
+func Provider(p notification.Provider, rate float64) notification.Provider {
+	wrapped := &provider{inner: p, rate: rate}
+	if batcher, ok := p.(notification.BatchProvider); ok {
+		return &batchProvider{provider: wrapped, batcher: batcher}
+	}
+	return wrapped
+}
+
+type provider struct {
+	inner notification.Provider
+	rate  float64
+}
+
+// providerError is the injected send failure: retryable, classified provider_5xx.
+func providerError() error {
+	return common.NewHTTPStatusError(http.StatusServiceUnavailable, fmt.Errorf("%w: provider unavailable", ErrInjected))
+}
+
+func (p *provider) Channel() notification.Channel {
+	return p.inner.Channel()
+}
+
+func (p *provider) Name() string {
+	return notification.ProviderName(p.inner)
+}
+
+func (p *provider) Send(ctx context.Context, msg *notification.Message) (string, error) {
+	id, _, err := p.SendWithMetadata(ctx, msg)
+	return id, err
+}
+
+func (p *provider) SendWithMetadata(ctx context.Context, msg *notification.Message) (string, *notification.ProviderMetadata, error) {
+	if hit(p.rate) {
+		return "", nil, providerError()
+	}
+	if m, ok := p.inner.(notification.MetadataProvider); ok {
+		return m.SendWithMetadata(ctx, msg)
+	}
+	id, err := p.inner.Send(ctx, msg)
+	return id, nil, err
+}
+
+type batchProvider struct {
+	*provider
+	batcher notification.BatchProvider
+}
+
+func (p *batchProvider) SendBatch(ctx context.Context, msgs []*notification.Message) ([]string, error) {
+	ids, _, err := p.SendBatchWithMetadata(ctx, msgs)
+	return ids, err
+}
+
+func (p *batchProvider) SendBatchWithMetadata(ctx context.Context, msgs []*notification.Message) ([]string, *notification.ProviderMetadata, error) {
+	if hit(p.rate) {
+		return nil, nil, providerError()
+	}
+	if m, ok := p.batcher.(notification.MetadataBatchProvider); ok {
+		return m.SendBatchWithMetadata(ctx, msgs)
+	}
+	ids, err := p.batcher.SendBatch(ctx, msgs)
+	return ids, nil, err
+}
+
+// StoreLatency returns a hook for store.CallConfig.Fault that delays a
+// fraction rate of store calls by latency, or until ctx is done.
+func StoreLatency(latency time.Duration, rate float64) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if latency <= 0 || !hit(rate) {
+			return nil
+		}
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		}
+	}
+}
+
+// RedisHook is a go-redis hook that fails a fraction of commands and
+// pipelines before they reach Redis.
+type RedisHook struct {
+	rate float64
+}
+
+// NewRedisHook creates a hook failing a fraction rate of Redis calls.
+func NewRedisHook(rate float64) *RedisHook {
+	return &RedisHook{rate: rate}
+}
+
+// DialHook leaves connecting untouched.
+func (h *RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+// ProcessHook fails the command at the hook's rate.
+func (h *RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if hit(h.rate) {
+			err := fmt.Errorf("%w: redis %s", ErrInjected, cmd.Name())
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+// ProcessPipelineHook fails the whole pipeline at the hook's rate.
+func (h *RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if hit(h.rate) {
+			err := fmt.Errorf("%w: redis pipeline", ErrInjected)
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			return err
+		}
+		return next(ctx, cmds)
+	}
+}
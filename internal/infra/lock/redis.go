@@ -0,0 +1,79 @@
+// Package lock provides a Redis-based distributed lock used to elect a single
+// reaper among worker replicas.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ notification.SweepLock = (*RedisLock)(nil)
+
+// releaseScript deletes the lock only if it still holds our token, so a holder
+// whose TTL expired cannot release a lock another replica has since acquired.
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// RedisLock is a single-key lock built on SET NX PX.
+type RedisLock struct {
+	client *redis.Client
+	key    string
+}
+
+// NewRedisLock creates a lock stored under key.
+func NewRedisLock(redisAddr, password string, db int, key string) *RedisLock {
+	return &RedisLock{
+		client: redis.NewClient(&redis.Options{
+			Addr:     redisAddr,
+			Password: password,
+			DB:       db,
+		}),
+		key: key,
+	}
+}
+
+// TryAcquire takes the lock for ttl if nobody holds it. On success the
+// returned release func gives it back early; it is safe to call after the TTL
+// has expired.
+func (l *RedisLock) TryAcquire(ctx context.Context, ttl time.Duration) (func(), bool, error) {
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, false, fmt.Errorf("generating lock token: %w", err)
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	ok, err := l.client.SetNX(ctx, l.key, token, ttl).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("acquiring lock %s: %w", l.key, err)
+	}
+	if !ok {
+		return nil, false, nil
+	}
+
+	release := func() {
+		// Use a fresh context: the caller's may already be cancelled on shutdown
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := releaseScript.Run(ctx, l.client, []string{l.key}, token).Err(); err != nil {
+			slog.Error("releasing lock failed", "key", l.key, "error", err)
+		}
+	}
+	return release, true, nil
+}
+
+// Close closes the Redis connection.
+func (l *RedisLock) Close() error {
+	return l.client.Close()
+}
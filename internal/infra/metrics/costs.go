@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ notification.CostStore = (*RedisCosts)(nil)
+
+// costsKeyPrefix prefixes the per-day Redis hashes of send costs. Each hash
+// holds "api_key|channel|type|sends" and "api_key|channel|type|cost" fields
+// for one UTC day.
+const costsKeyPrefix = "notifly:metrics:costs:"
+
+// CostsRetention is how long each day's totals are kept: a little over a
+// year, so a month can be compared with the same month a year before.
+const CostsRetention = 400 * 24 * time.Hour
+
+// RedisCosts totals estimated send costs in one Redis hash per day.
+type RedisCosts struct {
+	client *redis.Client
+}
+
+// NewRedisCosts creates a Redis-backed cost store.
+func NewRedisCosts(redisAddr, password string, db int) *RedisCosts {
+	return &RedisCosts{
+		client: redis.NewClient(&redis.Options{
+			Addr:     redisAddr,
+			Password: password,
+			DB:       db,
+		}),
+	}
+}
+
+// RecordCost adds a send and its cost to today's totals and keeps the hash
+// for the retention period.
+func (s *RedisCosts) RecordCost(ctx context.Context, apiKeyID string, channel notification.Channel, notifType notification.NotificationType, cost float64) error {
+	key := costsKey(time.Now())
+	field := apiKeyID + "|" + string(channel) + "|" + string(notifType) + "|"
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(ctx, key, field+"sends", 1)
+		pipe.HIncrByFloat(ctx, key, field+"cost", cost)
+		pipe.Expire(ctx, key, CostsRetention+24*time.Hour)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("recording cost: %w", err)
+	}
+	return nil
+}
+
+// CostTotals reads the days from since's through today, oldest first and
+// then by API key, channel, and type. Malformed fields are skipped.
+func (s *RedisCosts) CostTotals(ctx context.Context, since time.Time) ([]*notification.CostTotal, error) {
+	now := time.Now().UTC()
+	if oldest := now.Add(-CostsRetention); since.Before(oldest) {
+		since = oldest
+	}
+
+	pipe := s.client.Pipeline()
+	var days []string
+	var cmds []*redis.MapStringStringCmd
+	for day := since.UTC().Truncate(24 * time.Hour); !day.After(now); day = day.AddDate(0, 0, 1) {
+		days = append(days, day.Format(time.DateOnly))
+		cmds = append(cmds, pipe.HGetAll(ctx, costsKey(day)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("reading cost totals: %w", err)
+	}
+
+	var totals []*notification.CostTotal
+	for i, cmd := range cmds {
+		byName := make(map[string]*notification.CostTotal)
+		var dayTotals []*notification.CostTotal
+		for field, value := range cmd.Val() {
+			parts := strings.Split(field, "|")
+			if len(parts) != 4 {
+				continue
+			}
+			name := parts[0] + "|" + parts[1] + "|" + parts[2]
+			total, ok := byName[name]
+			if !ok {
+				total = &notification.CostTotal{
+					Day:      days[i],
+					APIKeyID: parts[0],
+					Channel:  notification.Channel(parts[1]),
+					Type:     notification.NotificationType(parts[2]),
+				}
+				byName[name] = total
+				dayTotals = append(dayTotals, total)
+			}
+
+			switch parts[3] {
+			case "sends":
+				total.Sends += parseCount(value)
+			case "cost":
+				if cost, err := strconv.ParseFloat(value, 64); err == nil {
+					total.Cost += cost
+				}
+			}
+		}
+		sort.Slice(dayTotals, func(a, b int) bool {
+			x, y := dayTotals[a], dayTotals[b]
+			if x.APIKeyID != y.APIKeyID {
+				return x.APIKeyID < y.APIKeyID
+			}
+			if x.Channel != y.Channel {
+				return x.Channel < y.Channel
+			}
+			return x.Type < y.Type
+		})
+		totals = append(totals, dayTotals...)
+	}
+	return totals, nil
+}
+
+// Close closes the Redis connection.
+func (s *RedisCosts) Close() error {
+	return s.client.Close()
+}
+
+// costsKey names the hash for t's UTC day.
+func costsKey(t time.Time) string {
+	return costsKeyPrefix + t.UTC().Format(time.DateOnly)
+}
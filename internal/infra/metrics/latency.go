@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ notification.LatencyRecorder = (*RedisLatency)(nil)
+
+// latencyKey is the Redis hash holding the latency histograms. Each histogram
+// is stored as "stage|channel|type|<field>" fields, where field is a bucket
+// index, "count", or "sum".
+const latencyKey = "notifly:metrics:latency"
+
+// RedisLatency stores delivery latency histograms in a Redis hash.
+type RedisLatency struct {
+	client *redis.Client
+}
+
+// NewRedisLatency creates a Redis-backed latency recorder.
+func NewRedisLatency(redisAddr, password string, db int) *RedisLatency {
+	return &RedisLatency{
+		client: redis.NewClient(&redis.Options{
+			Addr:     redisAddr,
+			Password: password,
+			DB:       db,
+		}),
+	}
+}
+
+// ObserveLatency increments d's bucket, the count, and the sum in one transaction.
+func (s *RedisLatency) ObserveLatency(ctx context.Context, stage notification.LatencyStage, channel notification.Channel, notifType notification.NotificationType, d time.Duration) error {
+	prefix := string(stage) + "|" + string(channel) + "|" + string(notifType) + "|"
+	seconds := d.Seconds()
+	bucket := sort.SearchFloat64s(notification.LatencyBuckets, seconds)
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if bucket < len(notification.LatencyBuckets) {
+			pipe.HIncrBy(ctx, latencyKey, prefix+strconv.Itoa(bucket), 1)
+		}
+		pipe.HIncrBy(ctx, latencyKey, prefix+"count", 1)
+		pipe.HIncrByFloat(ctx, latencyKey, prefix+"sum", seconds)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("recording latency: %w", err)
+	}
+	return nil
+}
+
+// LatencyHistograms reads every histogram. Malformed fields are skipped.
+func (s *RedisLatency) LatencyHistograms(ctx context.Context) ([]*notification.LatencyHistogram, error) {
+	fields, err := s.client.HGetAll(ctx, latencyKey).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("reading latency histograms: %w", err)
+	}
+
+	byName := make(map[string]*notification.LatencyHistogram)
+	for field, value := range fields {
+		parts := strings.Split(field, "|")
+		if len(parts) != 4 {
+			continue
+		}
+		name := strings.Join(parts[:3], "|")
+		h, ok := byName[name]
+		if !ok {
+			h = &notification.LatencyHistogram{
+				Stage:   notification.LatencyStage(parts[0]),
+				Channel: notification.Channel(parts[1]),
+				Type:    notification.NotificationType(parts[2]),
+				Buckets: make([]int64, len(notification.LatencyBuckets)),
+			}
+			byName[name] = h
+		}
+
+		switch parts[3] {
+		case "count":
+			h.Count = parseCount(value)
+		case "sum":
+			h.Sum, _ = strconv.ParseFloat(value, 64)
+		default:
+			if i, err := strconv.Atoi(parts[3]); err == nil && i >= 0 && i < len(h.Buckets) {
+				h.Buckets[i] = parseCount(value)
+			}
+		}
+	}
+
+	histograms := make([]*notification.LatencyHistogram, 0, len(byName))
+	for _, h := range byName {
+		histograms = append(histograms, h)
+	}
+	return histograms, nil
+}
+
+// Close closes the Redis connection.
+func (s *RedisLatency) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ notification.OutcomeStore = (*RedisOutcomes)(nil)
+
+// outcomesKeyPrefix prefixes the per-minute Redis hashes of send outcomes.
+// Each hash holds "channel|type|outcome" counters for one minute.
+const outcomesKeyPrefix = "notifly:metrics:outcomes:"
+
+// OutcomesRetention is how long each minute's counts are kept, and so the
+// longest window they can be read over.
+const OutcomesRetention = 24 * time.Hour
+
+// RedisOutcomes counts send outcomes in one Redis hash per minute.
+type RedisOutcomes struct {
+	client *redis.Client
+}
+
+// NewRedisOutcomes creates a Redis-backed outcome store.
+func NewRedisOutcomes(redisAddr, password string, db int) *RedisOutcomes {
+	return &RedisOutcomes{
+		client: redis.NewClient(&redis.Options{
+			Addr:     redisAddr,
+			Password: password,
+			DB:       db,
+		}),
+	}
+}
+
+// RecordOutcome increments the current minute's counter and keeps the hash
+// for the retention period.
+func (s *RedisOutcomes) RecordOutcome(ctx context.Context, channel notification.Channel, notifType notification.NotificationType, outcome notification.Outcome) error {
+	key := outcomesKey(time.Now())
+	field := string(channel) + "|" + string(notifType) + "|" + string(outcome)
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(ctx, key, field, 1)
+		pipe.Expire(ctx, key, OutcomesRetention+time.Minute)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("recording outcome: %w", err)
+	}
+	return nil
+}
+
+// OutcomeCounts sums the minutes from since's through the current one.
+// Malformed fields are skipped.
+func (s *RedisOutcomes) OutcomeCounts(ctx context.Context, since time.Time) ([]*notification.OutcomeCount, error) {
+	now := time.Now()
+	if oldest := now.Add(-OutcomesRetention); since.Before(oldest) {
+		since = oldest
+	}
+
+	pipe := s.client.Pipeline()
+	var cmds []*redis.MapStringStringCmd
+	for minute := since.Truncate(time.Minute); !minute.After(now); minute = minute.Add(time.Minute) {
+		cmds = append(cmds, pipe.HGetAll(ctx, outcomesKey(minute)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("reading outcome counts: %w", err)
+	}
+
+	byName := make(map[string]*notification.OutcomeCount)
+	var counts []*notification.OutcomeCount
+	for _, cmd := range cmds {
+		for field, value := range cmd.Val() {
+			parts := strings.Split(field, "|")
+			if len(parts) != 3 {
+				continue
+			}
+			name := parts[0] + "|" + parts[1]
+			count, ok := byName[name]
+			if !ok {
+				count = &notification.OutcomeCount{
+					Channel: notification.Channel(parts[0]),
+					Type:    notification.NotificationType(parts[1]),
+				}
+				byName[name] = count
+				counts = append(counts, count)
+			}
+
+			n := parseCount(value)
+			switch notification.Outcome(parts[2]) {
+			case notification.OutcomeSent:
+				count.Sent += n
+			case notification.OutcomeFailed:
+				count.Failed += n
+			case notification.OutcomeBounced:
+				count.Bounced += n
+			}
+		}
+	}
+	return counts, nil
+}
+
+// Close closes the Redis connection.
+func (s *RedisOutcomes) Close() error {
+	return s.client.Close()
+}
+
+// outcomesKey names the hash for t's minute.
+func outcomesKey(t time.Time) string {
+	return outcomesKeyPrefix + strconv.FormatInt(t.Unix()/60, 10)
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+)
+
+var _ notification.BacklogSink = (*Pushgateway)(nil)
+
+// Pushgateway pushes the queue backlog gauges to a Prometheus Pushgateway
+// (or any endpoint accepting its push API), replacing the job's metrics on
+// every push.
+type Pushgateway struct {
+	url    string
+	client *http.Client
+}
+
+// NewPushgateway creates a sink pushing to the Pushgateway at baseURL under
+// job.
+func NewPushgateway(baseURL, job string) *Pushgateway {
+	return &Pushgateway{
+		url:    strings.TrimRight(baseURL, "/") + "/metrics/job/" + url.PathEscape(job),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// PushBacklog writes state's gauges and PUTs them to the job's group.
+func (p *Pushgateway) PushBacklog(ctx context.Context, state *notification.QueueState) error {
+	var buf bytes.Buffer
+	if err := notification.WritePrometheusBacklog(&buf, state); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, p.url, &buf)
+	if err != nil {
+		return fmt.Errorf("creating push request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("pushing backlog: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("pushgateway returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
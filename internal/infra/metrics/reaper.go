@@ -0,0 +1,94 @@
+// Package metrics keeps operational counters in Redis so every replica and the
+// API see the same numbers.
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ notification.ReaperStatsStore = (*RedisReaperStats)(nil)
+
+// reaperStatsKey is the Redis hash holding the reaper counters and last sweep.
+const reaperStatsKey = "notifly:metrics:reaper"
+
+// RedisReaperStats stores reaper sweep totals in a Redis hash.
+type RedisReaperStats struct {
+	client *redis.Client
+}
+
+// NewRedisReaperStats creates a Redis-backed reaper stats store.
+func NewRedisReaperStats(redisAddr, password string, db int) *RedisReaperStats {
+	return &RedisReaperStats{
+		client: redis.NewClient(&redis.Options{
+			Addr:     redisAddr,
+			Password: password,
+			DB:       db,
+		}),
+	}
+}
+
+// RecordSweep increments the counters and replaces the last sweep in one transaction.
+func (s *RedisReaperStats) RecordSweep(ctx context.Context, result *notification.SweepResult) error {
+	last, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("encoding sweep result: %w", err)
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(ctx, reaperStatsKey, "sweeps", 1)
+		pipe.HIncrBy(ctx, reaperStatsKey, "stale_found", int64(result.StaleFound))
+		pipe.HIncrBy(ctx, reaperStatsKey, "recovered", int64(result.Recovered))
+		pipe.HIncrBy(ctx, reaperStatsKey, "abandoned", int64(result.Abandoned))
+		pipe.HIncrBy(ctx, reaperStatsKey, "failures", int64(result.Failures))
+		pipe.HSet(ctx, reaperStatsKey, "last_sweep", last)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("recording reaper sweep: %w", err)
+	}
+	return nil
+}
+
+// ReaperStats reads the counters and the last sweep. Before the first sweep
+// every counter is zero and LastSweep is nil.
+func (s *RedisReaperStats) ReaperStats(ctx context.Context) (*notification.ReaperStats, error) {
+	fields, err := s.client.HGetAll(ctx, reaperStatsKey).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("reading reaper stats: %w", err)
+	}
+
+	stats := &notification.ReaperStats{
+		Sweeps:     parseCount(fields["sweeps"]),
+		StaleFound: parseCount(fields["stale_found"]),
+		Recovered:  parseCount(fields["recovered"]),
+		Abandoned:  parseCount(fields["abandoned"]),
+		Failures:   parseCount(fields["failures"]),
+	}
+	if raw, ok := fields["last_sweep"]; ok {
+		var last notification.SweepResult
+		if err := json.Unmarshal([]byte(raw), &last); err != nil {
+			return nil, fmt.Errorf("decoding last reaper sweep: %w", err)
+		}
+		stats.LastSweep = &last
+	}
+	return stats, nil
+}
+
+// Close closes the Redis connection.
+func (s *RedisReaperStats) Close() error {
+	return s.client.Close()
+}
+
+// parseCount reads a counter field; missing or malformed fields count as zero.
+func parseCount(v string) int64 {
+	n, _ := strconv.ParseInt(v, 10, 64)
+	return n
+}
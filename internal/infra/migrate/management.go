@@ -0,0 +1,98 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var _ DB = (*ManagementAPI)(nil)
+
+// DefaultManagementURL is the Supabase Management API.
+const DefaultManagementURL = "https://api.supabase.com"
+
+// ManagementAPI runs SQL through the Supabase Management API's query
+// endpoint, so migrations need only an access token, not a database
+// connection string or driver.
+type ManagementAPI struct {
+	baseURL    string
+	projectRef string
+	token      string
+	client     *http.Client
+}
+
+// NewManagementAPI creates a client for projectRef authenticated with a
+// personal access token.
+func NewManagementAPI(baseURL, projectRef, token string) *ManagementAPI {
+	return &ManagementAPI{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		projectRef: projectRef,
+		token:      token,
+		client:     &http.Client{Timeout: 2 * time.Minute}, // index builds can be slow
+	}
+}
+
+// ProjectRef extracts the project reference from a hosted Supabase URL
+// (https://<ref>.supabase.co). It returns "" for other URLs, such as a
+// self-hosted instance.
+func ProjectRef(supabaseURL string) string {
+	u, err := url.Parse(supabaseURL)
+	if err != nil {
+		return ""
+	}
+	ref, ok := strings.CutSuffix(u.Hostname(), ".supabase.co")
+	if !ok || strings.Contains(ref, ".") {
+		return ""
+	}
+	return ref
+}
+
+// Query implements DB.
+func (a *ManagementAPI) Query(ctx context.Context, sql string, rows any) error {
+	payload, err := json.Marshal(map[string]string{"query": sql})
+	if err != nil {
+		return fmt.Errorf("marshaling query: %w", err)
+	}
+
+	endpoint := a.baseURL + "/v1/projects/" + url.PathEscape(a.projectRef) + "/database/query"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("creating query request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+a.token)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending query: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return fmt.Errorf("reading query response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("management API returned %s: %s", resp.Status, apiErr.Message)
+		}
+		return fmt.Errorf("management API returned %s", resp.Status)
+	}
+
+	if rows == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, rows); err != nil {
+		return fmt.Errorf("parsing query result: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+// Package migrate applies the versioned SQL migrations in migrations/ and
+// records them in the schema_migrations table.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Migration is one versioned schema change.
+type Migration struct {
+	Version int
+	Name    string // file name without the version and extension, e.g. "init"
+	SQL     string
+}
+
+// File returns the migration's file name.
+func (m Migration) File() string {
+	return fmt.Sprintf("%03d_%s.sql", m.Version, m.Name)
+}
+
+// Status reports whether a migration has been applied, and when.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// DB runs SQL against the database being migrated.
+type DB interface {
+	// Query runs sql, which may hold several statements, and decodes the rows
+	// the last one returns into rows (a pointer to a slice of structs).
+	Query(ctx context.Context, sql string, rows any) error
+}
+
+// fileName matches migration files: a version, an underscore, and a name of
+// lowercase letters, digits, and underscores.
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.sql$`)
+
+// Load reads the migrations in fsys's root, ordered by version. Files that
+// are not .sql are ignored; a misnamed .sql file or a repeated version is an
+// error.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("reading migrations: %w", err)
+	}
+
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		m := fileName.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migration %s: name must be NNN_name.sql", entry.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, entry.Name(), version)
+		}
+		seen[version] = entry.Name()
+
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("reading migration %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: m[2], SQL: string(data)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// createTable creates the table recording applied migrations.
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    INTEGER PRIMARY KEY,
+    name       TEXT        NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);`
+
+// Migrator applies migrations to a database.
+type Migrator struct {
+	db         DB
+	migrations []Migration
+}
+
+// NewMigrator creates a migrator for migrations, as returned by Load.
+func NewMigrator(db DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// Status lists every migration with when it was applied, creating the
+// schema_migrations table if needed.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	// Epoch seconds, since drivers and APIs disagree on timestamp formats
+	var rows []struct {
+		Version     int   `json:"version"`
+		AppliedUnix int64 `json:"applied_unix"`
+	}
+	query := createTable + "\nSELECT version, floor(extract(epoch FROM applied_at))::bigint AS applied_unix FROM schema_migrations;"
+	if err := m.db.Query(ctx, query, &rows); err != nil {
+		return nil, fmt.Errorf("reading applied migrations: %w", err)
+	}
+	applied := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = time.Unix(row.AppliedUnix, 0).UTC()
+	}
+
+	statuses := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i] = Status{Migration: migration}
+		if at, ok := applied[migration.Version]; ok {
+			statuses[i].AppliedAt = &at
+		}
+	}
+	return statuses, nil
+}
+
+// Up applies every pending migration in version order, each in its own
+// transaction with its schema_migrations row, and returns those applied. It
+// stops at the first failure, which leaves that migration unapplied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			continue
+		}
+		if err := m.db.Query(ctx, transaction(status.Migration), nil); err != nil {
+			return applied, fmt.Errorf("applying migration %s: %w", status.File(), err)
+		}
+		applied = append(applied, status.Migration)
+	}
+	return applied, nil
+}
+
+// transaction wraps migration in a transaction that also records it. The
+// name is safe to inline: Load only accepts [a-z0-9_].
+func transaction(migration Migration) string {
+	return fmt.Sprintf("BEGIN;\n%s\nINSERT INTO schema_migrations (version, name) VALUES (%d, '%s') ON CONFLICT (version) DO NOTHING;\nCOMMIT;",
+		migration.SQL, migration.Version, migration.Name)
+}
+
+// Script returns the migrations from version from onward as one SQL script
+// that applies and records each, for pasting into an SQL editor when the
+// database cannot be reached directly.
+func Script(migrations []Migration, from int) string {
+	script := createTable + "\n"
+	for _, migration := range migrations {
+		if migration.Version < from {
+			continue
+		}
+		script += fmt.Sprintf("\n-- %s\n%s\n", migration.File(), transaction(migration))
+	}
+	return script
+}
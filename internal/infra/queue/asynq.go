@@ -1,25 +1,140 @@
 package queue
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"notifly/internal/domain/notification"
+	"github.com/badrkarrachai/notifly/pkg/notification"
 
 	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
 )
 
-// NewClient creates a new asynq client connected to Redis.
-func NewClient(redisAddr, password string, db int) *asynq.Client {
-	return asynq.NewClient(asynq.RedisClientOpt{
+// Asynq queues: sends of critical types, other notification sends, campaign
+// fan-out and sends, and low-priority maintenance work.
+const (
+	CriticalQueue      = "critical"
+	NotificationsQueue = "notifications"
+	CampaignsQueue     = "campaigns"
+	LowQueue           = "low"
+)
+
+// DefaultQueue held maintenance work and campaign fan-out before LowQueue and
+// CampaignsQueue. Workers still drain it, so tasks enqueued before an upgrade
+// run.
+const DefaultQueue = "default"
+
+// Queues lists every queue a worker processes.
+var Queues = []string{CriticalQueue, NotificationsQueue, CampaignsQueue, LowQueue}
+
+// SendQueues lists the queues holding sends, the ones paused together.
+var SendQueues = []string{CriticalQueue, NotificationsQueue, CampaignsQueue}
+
+// NewClient creates a new asynq client connected to Redis. Hooks, if any,
+// are added to its Redis connection.
+func NewClient(redisAddr, password string, db int, hooks ...redis.Hook) *asynq.Client {
+	opt := asynq.RedisClientOpt{
+		Addr:     redisAddr,
+		Password: password,
+		DB:       db,
+	}
+	if len(hooks) == 0 {
+		return asynq.NewClient(opt)
+	}
+	return asynq.NewClient(hookedClientOpt{RedisClientOpt: opt, hooks: hooks})
+}
+
+// NewInspector creates an Asynq inspector, used to look up and replace the
+// task already holding a send's task ID.
+func NewInspector(redisAddr, password string, db int) *asynq.Inspector {
+	return asynq.NewInspector(asynq.RedisClientOpt{
 		Addr:     redisAddr,
 		Password: password,
 		DB:       db,
 	})
 }
 
-// NewServer creates a new asynq server connected to Redis.
-func NewServer(redisAddr, password string, db int, concurrency int) *asynq.Server {
+// hookedClientOpt is a RedisClientOpt whose client has hooks added. Going
+// through asynq.NewClient, rather than NewClientFromRedisClient, keeps the
+// connection owned and closed by the asynq client.
+type hookedClientOpt struct {
+	asynq.RedisClientOpt
+	hooks []redis.Hook
+}
+
+func (o hookedClientOpt) MakeRedisClient() interface{} {
+	client := o.RedisClientOpt.MakeRedisClient().(*redis.Client)
+	for _, hook := range o.hooks {
+		client.AddHook(hook)
+	}
+	return client
+}
+
+// QueueConfig is how a worker processes one queue. A queue without its own
+// Concurrency shares the worker's main pool with the others like it, picked
+// by Weight relative to theirs; one with a Concurrency gets a pool of that
+// many workers to itself, so it neither waits behind nor holds up any other.
+// A queue with a Retry backoff retries its failed tasks by it instead of the
+// worker's.
+type QueueConfig struct {
+	Concurrency int
+	Weight      int
+	Retry       *Backoff
+}
+
+// DigestConfig is how digest event tasks are combined. A group's events are
+// combined into one send digest task once none has arrived for GracePeriod,
+// once the oldest has waited MaxDelay, or once there are MaxSize of them;
+// zero MaxDelay and MaxSize mean no limit.
+// The digest task is retried up to MaxRetry times, each attempt bounded by
+// Timeout (zero leaves asynq's default).
+type DigestConfig struct {
+	GracePeriod time.Duration
+	MaxDelay    time.Duration
+	MaxSize     int
+	MaxRetry    int
+	Timeout     time.Duration
+}
+
+// Server processes every queue: one asynq server for the queues sharing the
+// main pool, and one per queue with a pool of its own.
+type Server struct {
+	servers []*asynq.Server
+
+	// active counts the tasks being processed, for Drain
+	active atomic.Int64
+}
+
+// NewServer creates the asynq servers connected to Redis. concurrency sizes
+// the main pool; queues configures each of Queues, a missing or zero Weight
+// counting as 1. Failed tasks are retried by their queue's backoff, or retry;
+// a task run early is retried when due without using up a retry.
+// Every server combines digest events as digest says.
+func NewServer(redisAddr, password string, db int, concurrency int, queues map[string]QueueConfig, retry Backoff, digest DigestConfig) *Server {
+	delay := retryDelay(retry, queues)
+	shared := map[string]int{DefaultQueue: 1}
+	var servers []*asynq.Server
+	for _, name := range Queues {
+		q := queues[name]
+		weight := max(q.Weight, 1)
+		if q.Concurrency > 0 {
+			servers = append(servers, newServer(redisAddr, password, db, q.Concurrency, map[string]int{name: weight}, delay, digest))
+			continue
+		}
+		shared[name] = weight
+	}
+	servers = append(servers, newServer(redisAddr, password, db, concurrency, shared, delay, digest))
+	return &Server{servers: servers}
+}
+
+// newServer creates one asynq server processing queues, by priority weight.
+func newServer(redisAddr, password string, db int, concurrency int, queues map[string]int, delay asynq.RetryDelayFunc, digest DigestConfig) *asynq.Server {
 	return asynq.NewServer(
 		asynq.RedisClientOpt{
 			Addr:     redisAddr,
@@ -27,32 +142,399 @@ func NewServer(redisAddr, password string, db int, concurrency int) *asynq.Serve
 			DB:       db,
 		},
 		asynq.Config{
-			Concurrency: concurrency,
-			Queues: map[string]int{
-				"notifications": 10, // priority weight
-				"default":       1,
-			},
-			RetryDelayFunc: func(n int, e error, t *asynq.Task) time.Duration {
-				// Exponential backoff: 30s, 60s, 120s, 240s, 480s
-				return time.Duration(30*(1<<uint(n-1))) * time.Second
-			},
+			Concurrency:      concurrency,
+			Queues:           queues,
+			RetryDelayFunc:   delay,
+			IsFailure:        isFailure,
+			GroupAggregator:  digestAggregator(digest),
+			GroupGracePeriod: digest.GracePeriod,
+			GroupMaxDelay:    digest.MaxDelay,
+			GroupMaxSize:     digest.MaxSize,
 		},
 	)
 }
 
-// EnqueueSendNotification enqueues a send notification task.
-func EnqueueSendNotification(client *asynq.Client, logID string, maxRetry int) error {
+// digestAggregator combines the digest event tasks of a group into one send
+// digest task, oldest event first. Events with a malformed payload are
+// dropped; a group with none left yields a digest with no logs, which sends
+// nothing.
+func digestAggregator(digest DigestConfig) asynq.GroupAggregator {
+	return asynq.GroupAggregatorFunc(func(group string, tasks []*asynq.Task) *asynq.Task {
+		logIDs := make([]string, 0, len(tasks))
+		for _, task := range tasks {
+			payload, err := notification.ParseSendNotificationPayload(task.Payload())
+			if err != nil {
+				slog.Error("dropping malformed digest event", "group", group, "error", err)
+				continue
+			}
+			logIDs = append(logIDs, payload.LogID)
+		}
+
+		opts := []asynq.Option{asynq.MaxRetry(digest.MaxRetry)}
+		if digest.Timeout > 0 {
+			opts = append(opts, asynq.Timeout(digest.Timeout))
+		}
+		task, err := notification.NewSendDigestTask(logIDs)
+		if err != nil {
+			panic(err) // a list of strings always marshals
+		}
+		return onQueue(task, NotificationsQueue, opts...)
+	})
+}
+
+// Start starts every server with handler. If one fails to start, those
+// already started are shut down.
+func (s *Server) Start(handler asynq.Handler) error {
+	handler = asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		s.active.Add(1)
+		defer s.active.Add(-1)
+		return handler.ProcessTask(ctx, task)
+	})
+	for i, server := range s.servers {
+		if err := server.Start(handler); err != nil {
+			for _, started := range s.servers[:i] {
+				started.Shutdown()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown stops every server from picking up tasks and waits for the tasks
+// in flight to finish, all servers at once.
+func (s *Server) Shutdown() {
+	var wg sync.WaitGroup
+	for _, server := range s.servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			server.Shutdown()
+		}()
+	}
+	wg.Wait()
+}
+
+// drainPoll is how often Drain checks for tasks still in flight.
+const drainPoll = 250 * time.Millisecond
+
+// Drain stops every server from picking up tasks, then waits until the tasks
+// in flight have finished or ctx is done, whichever is first. Processing
+// cannot be resumed: a drained server only awaits Shutdown.
+func (s *Server) Drain(ctx context.Context) error {
+	for _, server := range s.servers {
+		server.Stop()
+	}
+
+	ticker := time.NewTicker(drainPoll)
+	defer ticker.Stop()
+	for s.active.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d task(s) still in flight: %w", s.active.Load(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// Active returns the number of tasks being processed.
+func (s *Server) Active() int {
+	return int(s.active.Load())
+}
+
+// EnqueueSendNotification enqueues a send notification task on queue, one of
+// SendQueues. timeout bounds each processing attempt on the asynq side; zero
+// leaves asynq's default.
+//
+// The task ID is derived from logID, so a log has one send task per queue
+// while it is pending, running, or waiting to retry: enqueuing it again then
+// (e.g. the reaper requeuing a log whose slow task is still running) is a
+// no-op, or, for a task waiting to retry, runs it now. A task that finished
+// (archived after its last retry, or completed and kept for retention) is
+// replaced by the new one. No time window is needed: the ID is free again as
+// soon as the task finishes, so intentional resends go through.
+func EnqueueSendNotification(client *asynq.Client, inspector *asynq.Inspector, queue, logID string, maxRetry int, timeout time.Duration) error {
 	task, err := notification.NewSendNotificationTask(logID)
 	if err != nil {
 		return fmt.Errorf("creating task: %w", err)
 	}
 
-	_, err = client.Enqueue(task,
+	taskID := sendTaskID(logID)
+	opts := []asynq.Option{
 		asynq.MaxRetry(maxRetry),
-		asynq.Queue("notifications"),
-	)
+		asynq.Queue(queue),
+		asynq.TaskID(taskID),
+	}
+	if timeout > 0 {
+		opts = append(opts, asynq.Timeout(timeout))
+	}
+
+	_, err = client.Enqueue(onQueue(task, queue), opts...)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		err = resolveConflict(client, inspector, queue, taskID, onQueue(task, queue), opts)
+	}
 	if err != nil {
-		return fmt.Errorf("enqueuing task: %w", err)
+		return enqueueError(err)
+	}
+
+	return nil
+}
+
+// enqueueError wraps err from enqueuing a send, marking it
+// notification.ErrQueueUnavailable when Redis could not be reached, so
+// critical sends can go out directly instead.
+func enqueueError(err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, redis.ErrPoolTimeout) || errors.Is(err, redis.ErrClosed) {
+		return fmt.Errorf("enqueuing task: %w: %w", notification.ErrQueueUnavailable, err)
+	}
+	return fmt.Errorf("enqueuing task: %w", err)
+}
+
+// sendTaskID returns the task ID of the send notification task for logID.
+func sendTaskID(logID string) string {
+	return "send:" + logID
+}
+
+// resolveConflict handles enqueuing task when taskID is taken on queue: an
+// unfinished task already sends the log, so task is dropped (and a task
+// waiting to retry is run now, as a requeue is an explicit ask to send); a
+// finished one is deleted and task enqueued in its place.
+func resolveConflict(client *asynq.Client, inspector *asynq.Inspector, queue, taskID string, task *asynq.Task, opts []asynq.Option) error {
+	info, err := inspector.GetTaskInfo(queue, taskID)
+	if err != nil && !errors.Is(err, asynq.ErrTaskNotFound) {
+		return fmt.Errorf("inspecting task %s: %w", taskID, err)
+	}
+	if err == nil {
+		switch info.State {
+		case asynq.TaskStateArchived, asynq.TaskStateCompleted:
+			if err := inspector.DeleteTask(queue, taskID); err != nil && !errors.Is(err, asynq.ErrTaskNotFound) {
+				return fmt.Errorf("deleting finished task %s: %w", taskID, err)
+			}
+		case asynq.TaskStateRetry:
+			if err := inspector.RunTask(queue, taskID); err != nil && !errors.Is(err, asynq.ErrTaskNotFound) {
+				return fmt.Errorf("running task %s: %w", taskID, err)
+			}
+			slog.Info("send task already waiting to retry, running it now", "task_id", taskID, "queue", queue)
+			return nil
+		default:
+			slog.Info("send task already enqueued, skipping duplicate", "task_id", taskID, "queue", queue, "state", info.State)
+			return nil
+		}
+	}
+	// The old task is gone (deleted above, or finished and expired meanwhile)
+	if _, err := client.Enqueue(task, opts...); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return err
+	}
+	return nil
+}
+
+// EnqueueSendBatch enqueues a send batch task for logIDs on queue, one of
+// SendQueues.
+func EnqueueSendBatch(client *asynq.Client, queue string, logIDs []string, maxRetry int, timeout time.Duration) error {
+	task, err := notification.NewSendBatchTask(logIDs)
+	if err != nil {
+		return fmt.Errorf("creating batch task: %w", err)
+	}
+
+	opts := []asynq.Option{
+		asynq.MaxRetry(maxRetry),
+		asynq.Queue(queue),
+	}
+	if timeout > 0 {
+		opts = append(opts, asynq.Timeout(timeout))
+	}
+
+	if _, err := client.Enqueue(onQueue(task, queue), opts...); err != nil {
+		return fmt.Errorf("enqueuing batch task: %w", err)
+	}
+
+	return nil
+}
+
+// EnqueueDigestEvent enqueues a digest event for logID on the notifications
+// queue, in group. The worker combines the group's events into a send digest
+// task instead of running them.
+func EnqueueDigestEvent(client *asynq.Client, group, logID string) error {
+	task, err := notification.NewDigestEventTask(logID)
+	if err != nil {
+		return fmt.Errorf("creating digest event task: %w", err)
+	}
+
+	if _, err := client.Enqueue(onQueue(task, NotificationsQueue), asynq.Queue(NotificationsQueue), asynq.Group(group)); err != nil {
+		return fmt.Errorf("enqueuing digest event task: %w", err)
+	}
+
+	return nil
+}
+
+// EnqueueErasure enqueues an erasure job on the low queue, behind
+// notification sends in priority. It is not affected by pausing sends.
+func EnqueueErasure(client *asynq.Client, jobID, recipient string, maxRetry int, timeout time.Duration) error {
+	task, err := notification.NewEraseRecipientTask(jobID, recipient)
+	if err != nil {
+		return fmt.Errorf("creating erasure task: %w", err)
+	}
+
+	opts := []asynq.Option{
+		asynq.MaxRetry(maxRetry),
+		asynq.Queue(LowQueue),
+	}
+	if timeout > 0 {
+		opts = append(opts, asynq.Timeout(timeout))
+	}
+
+	if _, err := client.Enqueue(onQueue(task, LowQueue), opts...); err != nil {
+		return fmt.Errorf("enqueuing erasure task: %w", err)
+	}
+
+	return nil
+}
+
+// EnqueueCampaignDispatch enqueues the campaign batch starting at audience
+// index cursor on the campaigns queue, to run after delay. The task ID is derived
+// from the campaign and cursor, so enqueuing a batch that is already pending
+// or running (e.g. on a quick pause and resume) does nothing.
+func EnqueueCampaignDispatch(client *asynq.Client, campaignID string, cursor int, delay time.Duration, maxRetry int, timeout time.Duration) error {
+	task, err := notification.NewDispatchCampaignTask(campaignID)
+	if err != nil {
+		return fmt.Errorf("creating campaign task: %w", err)
+	}
+
+	opts := []asynq.Option{
+		asynq.MaxRetry(maxRetry),
+		asynq.Queue(CampaignsQueue),
+		asynq.TaskID(fmt.Sprintf("campaign:%s:%d", campaignID, cursor)),
+	}
+	if delay > 0 {
+		opts = append(opts, asynq.ProcessIn(delay))
+	}
+	if timeout > 0 {
+		opts = append(opts, asynq.Timeout(timeout))
+	}
+
+	if _, err := client.Enqueue(onQueue(task, CampaignsQueue), opts...); err != nil {
+		if errors.Is(err, asynq.ErrTaskIDConflict) {
+			return nil
+		}
+		return fmt.Errorf("enqueuing campaign task: %w", err)
+	}
+
+	return nil
+}
+
+// EnqueueFallback schedules the fallback check for logID on the notifications
+// queue, so pausing sends also holds fallbacks. The task ID is derived from
+// the log, so scheduling the same check twice does nothing.
+func EnqueueFallback(client *asynq.Client, logID string, delay time.Duration, maxRetry int, timeout time.Duration) error {
+	task, err := notification.NewCheckFallbackTask(logID)
+	if err != nil {
+		return fmt.Errorf("creating fallback task: %w", err)
+	}
+
+	opts := []asynq.Option{
+		asynq.MaxRetry(maxRetry),
+		asynq.Queue(NotificationsQueue),
+		asynq.TaskID("fallback:" + logID),
+		asynq.ProcessIn(delay),
+	}
+	if timeout > 0 {
+		opts = append(opts, asynq.Timeout(timeout))
+	}
+
+	if _, err := client.Enqueue(onQueue(task, NotificationsQueue), opts...); err != nil {
+		if errors.Is(err, asynq.ErrTaskIDConflict) {
+			return nil
+		}
+		return fmt.Errorf("enqueuing fallback task: %w", err)
+	}
+
+	return nil
+}
+
+// EnqueueEscalationStep schedules step of the escalation of logID on the
+// notifications queue. The task ID is derived from the log and step, so
+// scheduling the same step twice does nothing.
+func EnqueueEscalationStep(client *asynq.Client, logID string, step int, delay time.Duration, maxRetry int, timeout time.Duration) error {
+	task, err := notification.NewEscalateTask(logID, step)
+	if err != nil {
+		return fmt.Errorf("creating escalation task: %w", err)
+	}
+
+	opts := []asynq.Option{
+		asynq.MaxRetry(maxRetry),
+		asynq.Queue(NotificationsQueue),
+		asynq.TaskID(fmt.Sprintf("escalation:%s:%d", logID, step)),
+		asynq.ProcessIn(delay),
+	}
+	if timeout > 0 {
+		opts = append(opts, asynq.Timeout(timeout))
+	}
+
+	if _, err := client.Enqueue(onQueue(task, NotificationsQueue), opts...); err != nil {
+		if errors.Is(err, asynq.ErrTaskIDConflict) {
+			return nil
+		}
+		return fmt.Errorf("enqueuing escalation task: %w", err)
+	}
+
+	return nil
+}
+
+// EnqueueFollowUp schedules the check of step of sequenceID on logID on the
+// notifications queue. The task ID is derived from the log and step, so
+// scheduling the same step twice does nothing.
+func EnqueueFollowUp(client *asynq.Client, logID, sequenceID string, step int, delay time.Duration, maxRetry int, timeout time.Duration) error {
+	task, err := notification.NewFollowUpTask(logID, sequenceID, step)
+	if err != nil {
+		return fmt.Errorf("creating follow-up task: %w", err)
+	}
+
+	opts := []asynq.Option{
+		asynq.MaxRetry(maxRetry),
+		asynq.Queue(NotificationsQueue),
+		asynq.TaskID(fmt.Sprintf("followup:%s:%d", logID, step)),
+		asynq.ProcessIn(delay),
+	}
+	if timeout > 0 {
+		opts = append(opts, asynq.Timeout(timeout))
+	}
+
+	if _, err := client.Enqueue(onQueue(task, NotificationsQueue), opts...); err != nil {
+		if errors.Is(err, asynq.ErrTaskIDConflict) {
+			return nil
+		}
+		return fmt.Errorf("enqueuing follow-up task: %w", err)
+	}
+
+	return nil
+}
+
+// EnqueueBounceRetry schedules the retry-th resend of the soft-bounced logID
+// on the notifications queue. The task ID is derived from the log and retry,
+// so scheduling the same retry twice does nothing.
+func EnqueueBounceRetry(client *asynq.Client, logID string, retry int, delay time.Duration, maxRetry int, timeout time.Duration) error {
+	task, err := notification.NewRetryBounceTask(logID, retry)
+	if err != nil {
+		return fmt.Errorf("creating bounce retry task: %w", err)
+	}
+
+	opts := []asynq.Option{
+		asynq.MaxRetry(maxRetry),
+		asynq.Queue(NotificationsQueue),
+		asynq.TaskID(fmt.Sprintf("bounce-retry:%s:%d", logID, retry)),
+		asynq.ProcessIn(delay),
+	}
+	if timeout > 0 {
+		opts = append(opts, asynq.Timeout(timeout))
+	}
+
+	if _, err := client.Enqueue(onQueue(task, NotificationsQueue), opts...); err != nil {
+		if errors.Is(err, asynq.ErrTaskIDConflict) {
+			return nil
+		}
+		return fmt.Errorf("enqueuing bounce retry task: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,78 @@
+package queue
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+
+	"github.com/hibiken/asynq"
+)
+
+// Backoff is how long a failed task waits before each retry. With a
+// Schedule, the n-th retry waits Schedule[n-1], and retries past its end wait
+// its last entry. Otherwise the first retry waits Delay and each later one
+// Multiplier times the one before (below 1 counts as 1: a fixed delay), up to
+// MaxDelay (zero: no cap). Each wait is then moved by up to Jitter (0–1) of
+// itself either way, so tasks that failed together do not retry together.
+type Backoff struct {
+	Delay      time.Duration
+	Multiplier float64
+	MaxDelay   time.Duration
+	Jitter     float64
+	Schedule   []time.Duration
+}
+
+// Wait returns the wait before the n-th retry, counting from 1.
+func (b Backoff) Wait(n int) time.Duration {
+	n = max(n, 1)
+	var wait float64
+	if len(b.Schedule) > 0 {
+		wait = float64(b.Schedule[min(n, len(b.Schedule))-1])
+	} else {
+		wait = float64(b.Delay) * math.Pow(max(b.Multiplier, 1), float64(n-1))
+		if b.MaxDelay > 0 {
+			wait = min(wait, float64(b.MaxDelay))
+		}
+	}
+	if b.Jitter > 0 {
+		wait *= 1 + b.Jitter*(2*rand.Float64()-1)
+	}
+	if wait >= math.MaxInt64 {
+		return math.MaxInt64 // an uncapped delay grown past what a Duration holds
+	}
+	return time.Duration(wait)
+}
+
+// queueHeader is the task header naming the queue a task was enqueued on, so
+// its retries wait by that queue's backoff.
+const queueHeader = "notifly-queue"
+
+// onQueue returns task with queueHeader set to queue, and opts.
+func onQueue(task *asynq.Task, queue string, opts ...asynq.Option) *asynq.Task {
+	return asynq.NewTaskWithHeaders(task.Type(), task.Payload(), map[string]string{queueHeader: queue}, opts...)
+}
+
+// retryDelay returns the asynq RetryDelayFunc waiting as long as the error
+// asks (common.RetryAfter, e.g. a provider's 429), or else by the backoff of
+// the task's queue, or by retry for queues without their own and tasks
+// enqueued without queueHeader.
+func retryDelay(retry Backoff, queues map[string]QueueConfig) asynq.RetryDelayFunc {
+	return func(n int, err error, task *asynq.Task) time.Duration {
+		if wait, ok := common.RetryAfter(err); ok {
+			return wait
+		}
+		backoff := retry
+		if q, ok := queues[task.Headers()[queueHeader]]; ok && q.Retry != nil {
+			backoff = *q.Retry
+		}
+		return backoff.Wait(n)
+	}
+}
+
+// isFailure is the asynq IsFailure func: every error but an early run
+// (common.EarlyError) counts against the task's retries.
+func isFailure(err error) bool {
+	return !common.IsEarly(err)
+}
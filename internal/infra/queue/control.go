@@ -0,0 +1,108 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+
+	"github.com/hibiken/asynq"
+)
+
+var _ notification.QueueControl = (*Controller)(nil)
+
+// Controller pauses, resumes, and inspects the send queues through the asynq
+// inspector. Pausing is stored in Redis, so it applies to every worker
+// replica and survives worker restarts.
+type Controller struct {
+	inspector *asynq.Inspector
+}
+
+// NewController creates a queue controller connected to Redis.
+func NewController(redisAddr, password string, db int) *Controller {
+	return &Controller{inspector: NewInspector(redisAddr, password, db)}
+}
+
+// PauseQueue stops workers from picking up tasks from every send queue.
+// Queues already paused are left as they are.
+func (q *Controller) PauseQueue(ctx context.Context) error {
+	state, err := q.QueueState(ctx)
+	if err != nil {
+		return err
+	}
+	for _, queue := range state.Queues {
+		if queue.Paused {
+			continue
+		}
+		if err := q.inspector.PauseQueue(queue.Queue); err != nil {
+			return fmt.Errorf("pausing queue %s: %w", queue.Queue, err)
+		}
+	}
+	return nil
+}
+
+// ResumeQueue lets workers pick up tasks from every send queue again. Queues
+// already running are left as they are.
+func (q *Controller) ResumeQueue(ctx context.Context) error {
+	state, err := q.QueueState(ctx)
+	if err != nil {
+		return err
+	}
+	for _, queue := range state.Queues {
+		if !queue.Paused {
+			continue
+		}
+		if err := q.inspector.UnpauseQueue(queue.Queue); err != nil {
+			return fmt.Errorf("resuming queue %s: %w", queue.Queue, err)
+		}
+	}
+	return nil
+}
+
+// QueueState reports each send queue's paused state, task counts, and oldest
+// pending task's age, and their totals; the send queues are paused if any of
+// them is. A queue nothing has been enqueued to yet reports zero counts.
+func (q *Controller) QueueState(_ context.Context) (*notification.QueueState, error) {
+	queues, err := q.inspector.Queues()
+	if err != nil {
+		return nil, fmt.Errorf("listing queues: %w", err)
+	}
+
+	state := &notification.QueueState{Queue: strings.Join(SendQueues, ",")}
+	for _, name := range SendQueues {
+		queue := notification.QueueState{Queue: name}
+		if slices.Contains(queues, name) {
+			info, err := q.inspector.GetQueueInfo(name)
+			if err != nil {
+				return nil, fmt.Errorf("reading queue %s info: %w", name, err)
+			}
+			queue = notification.QueueState{
+				Queue:     info.Queue,
+				Paused:    info.Paused,
+				Pending:   info.Pending,
+				Active:    info.Active,
+				Scheduled: info.Scheduled,
+				Retry:     info.Retry,
+				Archived:  info.Archived,
+
+				OldestPendingSec: info.Latency.Seconds(),
+			}
+		}
+		state.Paused = state.Paused || queue.Paused
+		state.Pending += queue.Pending
+		state.Active += queue.Active
+		state.Scheduled += queue.Scheduled
+		state.Retry += queue.Retry
+		state.Archived += queue.Archived
+		state.OldestPendingSec = max(state.OldestPendingSec, queue.OldestPendingSec)
+		state.Queues = append(state.Queues, queue)
+	}
+	return state, nil
+}
+
+// Close closes the inspector's Redis connection.
+func (q *Controller) Close() error {
+	return q.inspector.Close()
+}
@@ -0,0 +1,87 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+
+	"github.com/hibiken/asynq"
+)
+
+// The worker's middleware stack mirrors the HTTP one: register with
+// ServeMux.Use in order, outermost first — Recovery, Logging, Timeout.
+
+// Recovery turns a panic in any task handler into a permanent (non-retried)
+// error, logging the stack. Handlers that can record the failure themselves,
+// like notification.Worker.ProcessTask, recover first; this is the backstop.
+func Recovery() asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					slog.Error("task handler panicked",
+						"task_id", taskID(ctx),
+						"type", task.Type(),
+						"panic", r,
+						"stack", string(debug.Stack()),
+					)
+					err = fmt.Errorf("panic: %v: %w", r, asynq.SkipRetry)
+				}
+			}()
+			return next.ProcessTask(ctx, task)
+		})
+	}
+}
+
+// Logging logs every task with its ID, type, attempt, duration, and outcome —
+// the worker-side counterpart of the HTTP access log.
+func Logging() asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			start := time.Now()
+			retry, _ := asynq.GetRetryCount(ctx)
+			attrs := []any{
+				"task_id", taskID(ctx),
+				"type", task.Type(),
+				"retry", retry,
+			}
+
+			err := next.ProcessTask(ctx, task)
+
+			attrs = append(attrs, "duration", time.Since(start))
+			if err != nil {
+				attrs = append(attrs, "error", err, "permanent", common.IsPermanent(err))
+				slog.Warn("task failed", attrs...)
+				return err
+			}
+			slog.Debug("task processed", attrs...)
+			return nil
+		})
+	}
+}
+
+// Timeout bounds each attempt to the duration timeout returns when the attempt
+// starts, so a hung provider call cannot hold a concurrency slot. It is read
+// per task so the limit can change at runtime; zero disables it.
+func Timeout(timeout func() time.Duration) asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			if d := timeout(); d > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, d)
+				defer cancel()
+			}
+			return next.ProcessTask(ctx, task)
+		})
+	}
+}
+
+// taskID returns the asynq task ID carried by ctx, or "" outside a handler.
+func taskID(ctx context.Context) string {
+	id, _ := asynq.GetTaskID(ctx)
+	return id
+}
@@ -0,0 +1,18 @@
+package ratelimit
+
+import "github.com/redis/go-redis/v9"
+
+// NewClient creates the Redis client shared by the server's rate limiters,
+// with hooks, if any, added. The caller owns it and closes it after the
+// limiters are no longer used.
+func NewClient(redisAddr, password string, db int, hooks ...redis.Hook) *redis.Client {
+	client := redis.NewClient(&redis.Options{
+		Addr:     redisAddr,
+		Password: password,
+		DB:       db,
+	})
+	for _, hook := range hooks {
+		client.AddHook(hook)
+	}
+	return client
+}
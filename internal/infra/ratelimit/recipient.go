@@ -5,85 +5,218 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync/atomic"
 	"time"
 
-	"notifly/internal/domain/notification"
+	"github.com/badrkarrachai/notifly/pkg/notification"
 
 	"github.com/redis/go-redis/v9"
 )
 
-var _ notification.RecipientRateLimiter = (*RedisRecipientLimiter)(nil)
+var (
+	_ notification.RecipientRateLimiter = (*RedisRecipientLimiter)(nil)
+	_ notification.RateLimitInspector   = (*RedisRecipientLimiter)(nil)
+)
+
+// Limits are the per-recipient hourly caps. Rules override Default for a
+// channel ("sms"), a notification type ("password_changed"), or both
+// ("email:magic_link"); the most specific match wins. A rule of 0 exempts
+// matching sends from limiting.
+type Limits struct {
+	Default int
+	Rules   map[string]int
+}
+
+// resolve returns the rule key that applies to a send (empty for Default) and its limit.
+func (l *Limits) resolve(channel notification.Channel, notifType notification.NotificationType) (string, int) {
+	for _, key := range []string{string(channel) + ":" + string(notifType), string(notifType), string(channel)} {
+		if limit, ok := l.Rules[key]; ok {
+			return key, limit
+		}
+	}
+	return "", l.Default
+}
 
 // RedisRecipientLimiter enforces per-recipient notification rate limits using Redis sorted sets.
 // It uses a sliding window approach: each notification is a member scored by its timestamp.
+// Each rule counts in its own window (key notifly:ratelimit:<rule>:<recipient>);
+// sends under the default limit share notifly:ratelimit:<recipient>.
 type RedisRecipientLimiter struct {
-	client     *redis.Client
-	maxPerHour int
-	window     time.Duration
+	client *redis.Client
+	limits atomic.Pointer[Limits]
+	window time.Duration
 }
 
-// NewRedisRecipientLimiter creates a new Redis-based per-recipient rate limiter.
-func NewRedisRecipientLimiter(redisAddr, password string, db int, maxPerHour int) *RedisRecipientLimiter {
-	client := redis.NewClient(&redis.Options{
-		Addr:     redisAddr,
-		Password: password,
-		DB:       db,
-	})
-
-	return &RedisRecipientLimiter{
-		client:     client,
-		maxPerHour: maxPerHour,
-		window:     time.Hour,
+// NewRedisRecipientLimiter creates a new Redis-based per-recipient rate limiter on client.
+func NewRedisRecipientLimiter(client *redis.Client, limits Limits) *RedisRecipientLimiter {
+	limiter := &RedisRecipientLimiter{
+		client: client,
+		window: time.Hour,
 	}
+	limiter.SetLimits(limits)
+	return limiter
 }
 
-// Allow checks whether a notification can be sent to the given recipient.
-// Uses a Redis sorted set with timestamps as scores for a sliding window counter.
-func (r *RedisRecipientLimiter) Allow(ctx context.Context, recipient string) (bool, error) {
-	key := fmt.Sprintf("notifly:ratelimit:%s", recipient)
-	now := time.Now()
-	windowStart := now.Add(-r.window)
+// SetLimits replaces the per-recipient limits. Safe for concurrent use.
+func (r *RedisRecipientLimiter) SetLimits(limits Limits) {
+	r.limits.Store(&limits)
+}
 
-	pipe := r.client.Pipeline()
+// allowScript trims the sliding window, counts it, and records the new send
+// only when under the limit — all in one atomic step, so concurrent requests
+// cannot both pass a check that only one of them should.
+//
+// KEYS[1] = limiter key; ARGV = window start (ns), now (ns), member, limit, TTL (ms).
+// Returns {allowed (1 or 0), sends in the window, score of the oldest send}.
+var allowScript = redis.NewScript(`
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ARGV[1])
+local allowed = 0
+if redis.call("ZCARD", KEYS[1]) < tonumber(ARGV[4]) then
+	redis.call("ZADD", KEYS[1], ARGV[2], ARGV[3])
+	redis.call("PEXPIRE", KEYS[1], ARGV[5])
+	allowed = 1
+end
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+return {allowed, redis.call("ZCARD", KEYS[1]), oldest[2] or ARGV[2]}
+`)
+
+// Allow checks whether a notification can be sent to the given recipient and,
+// if so, counts it. Uses a Redis sorted set with timestamps as scores for a
+// sliding window, updated atomically by allowScript.
+func (r *RedisRecipientLimiter) Allow(ctx context.Context, recipient string, channel notification.Channel, notifType notification.NotificationType) (notification.RateLimitResult, error) {
+	rule, limit := r.limits.Load().resolve(channel, notifType)
+	if limit == 0 {
+		return notification.RateLimitResult{Allowed: true}, nil // exempt
+	}
 
-	// Remove expired entries (outside the sliding window)
-	pipe.ZRemRangeByScore(ctx, key, "-inf", fmt.Sprintf("%d", windowStart.UnixNano()))
+	key := limiterKey(rule, recipient)
+	now := time.Now()
+	windowStart := now.Add(-r.window)
 
-	// Count remaining entries in the window
-	countCmd := pipe.ZCard(ctx, key)
+	// Generate a unique member to avoid collisions on concurrent requests
+	randBytes := make([]byte, 4)
+	_, _ = rand.Read(randBytes)
+	member := fmt.Sprintf("%d:%s", now.UnixNano(), hex.EncodeToString(randBytes))
+
+	ttl := r.window + time.Minute // slightly longer than the window for cleanup
+	reply, err := allowScript.Run(ctx, r.client, []string{key},
+		windowStart.UnixNano(),
+		now.UnixNano(),
+		member,
+		limit,
+		ttl.Milliseconds(),
+	).Slice()
+	if err != nil {
+		return notification.RateLimitResult{}, fmt.Errorf("checking recipient rate limit: %w", err)
+	}
+	if len(reply) != 3 {
+		return notification.RateLimitResult{}, fmt.Errorf("checking recipient rate limit: unexpected reply %v", reply)
+	}
 
-	_, err := pipe.Exec(ctx)
+	allowed, _ := reply[0].(int64)
+	count, _ := reply[1].(int64)
+	oldestScore, _ := reply[2].(string)
+	oldest, err := strconv.ParseFloat(oldestScore, 64)
 	if err != nil {
-		return false, fmt.Errorf("checking recipient rate limit: %w", err)
+		return notification.RateLimitResult{}, fmt.Errorf("checking recipient rate limit: parsing oldest score %q: %w", oldestScore, err)
 	}
 
-	count := countCmd.Val()
+	return notification.RateLimitResult{
+		Allowed:   allowed == 1,
+		Limit:     limit,
+		Remaining: max(limit-int(count), 0),
+		Reset:     max(time.Unix(0, int64(oldest)).Add(r.window).Sub(now), 0),
+	}, nil
+}
 
-	// If at or over the limit, deny
-	if count >= int64(r.maxPerHour) {
-		return false, nil
+// limiterKey is the sorted set holding a recipient's sends under rule ("" for
+// the default limit).
+func limiterKey(rule, recipient string) string {
+	if rule == "" {
+		return fmt.Sprintf("notifly:ratelimit:%s", recipient)
 	}
+	return fmt.Sprintf("notifly:ratelimit:%s:%s", rule, recipient)
+}
 
-	// Generate a unique member to avoid collisions on concurrent requests
-	randBytes := make([]byte, 4)
-	_, _ = rand.Read(randBytes)
-	member := redis.Z{
-		Score:  float64(now.UnixNano()),
-		Member: fmt.Sprintf("%d:%s", now.UnixNano(), hex.EncodeToString(randBytes)),
+// defaultRule names the max_per_hour window in usage reports.
+const defaultRule = "default"
+
+// windows lists the rules that currently apply, default first, rules sorted.
+// Exempt rules (limit 0) have no window.
+func (l *Limits) windows() []string {
+	rules := []string{""}
+	for rule, limit := range l.Rules {
+		if limit > 0 {
+			rules = append(rules, rule)
+		}
 	}
-	pipe2 := r.client.Pipeline()
-	pipe2.ZAdd(ctx, key, member)
-	pipe2.Expire(ctx, key, r.window+time.Minute) // TTL slightly longer than window for cleanup
+	sort.Strings(rules[1:])
+	return rules
+}
 
-	_, err = pipe2.Exec(ctx)
+// Usage reports the recipient's usage of every window under the current limits.
+func (r *RedisRecipientLimiter) Usage(ctx context.Context, recipient string) ([]notification.RateLimitWindow, error) {
+	limits := r.limits.Load()
+	rules := limits.windows()
+	now := time.Now()
+	windowStart := now.Add(-r.window)
+
+	counts := make([]*redis.IntCmd, len(rules))
+	oldest := make([]*redis.ZSliceCmd, len(rules))
+	from := "(" + strconv.FormatInt(windowStart.UnixNano(), 10) // exclusive, like allowScript's trim
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, rule := range rules {
+			key := limiterKey(rule, recipient)
+			counts[i] = pipe.ZCount(ctx, key, from, "+inf")
+			oldest[i] = pipe.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{Min: from, Max: "+inf", Count: 1})
+		}
+		return nil
+	})
 	if err != nil {
-		return false, fmt.Errorf("recording rate limit entry: %w", err)
+		return nil, fmt.Errorf("reading recipient rate limit usage: %w", err)
 	}
 
-	return true, nil
+	windows := make([]notification.RateLimitWindow, len(rules))
+	for i, rule := range rules {
+		limit := limits.Default
+		if rule != "" {
+			limit = limits.Rules[rule]
+		} else {
+			rule = defaultRule
+		}
+		used := int(counts[i].Val())
+
+		var reset time.Duration
+		if z := oldest[i].Val(); len(z) > 0 {
+			reset = max(time.Unix(0, int64(z[0].Score)).Add(r.window).Sub(now), 0)
+		}
+
+		windows[i] = notification.RateLimitWindow{
+			Rule:       rule,
+			Limit:      limit,
+			Used:       used,
+			Remaining:  max(limit-used, 0),
+			ResetInSec: int(math.Ceil(reset.Seconds())),
+		}
+	}
+	return windows, nil
 }
 
-// Close closes the Redis connection.
-func (r *RedisRecipientLimiter) Close() error {
-	return r.client.Close()
+// Reset deletes the recipient's windows under the current limits, including
+// those of exempt rules, and returns how many existed.
+func (r *RedisRecipientLimiter) Reset(ctx context.Context, recipient string) (int, error) {
+	limits := r.limits.Load()
+	keys := []string{limiterKey("", recipient)}
+	for rule := range limits.Rules {
+		keys = append(keys, limiterKey(rule, recipient))
+	}
+
+	deleted, err := r.client.Del(ctx, keys...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("resetting recipient rate limit: %w", err)
+	}
+	return int(deleted), nil
 }
@@ -0,0 +1,224 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestLimiter returns a limiter on a fresh miniredis, and the server.
+func newTestLimiter(t *testing.T, limits Limits) (*RedisRecipientLimiter, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return NewRedisRecipientLimiter(client, limits), server
+}
+
+// seed adds sends to a recipient's default window, each the given age.
+func seed(t *testing.T, server *miniredis.Miniredis, recipient string, ages ...time.Duration) {
+	t.Helper()
+	now := time.Now()
+	for i, age := range ages {
+		score := float64(now.Add(-age).UnixNano())
+		if _, err := server.ZAdd(limiterKey("", recipient), score, fmt.Sprintf("seed-%d", i)); err != nil {
+			t.Fatalf("seeding window: %v", err)
+		}
+	}
+}
+
+func TestRecipientAllow(t *testing.T) {
+	const recipient = "user@example.com"
+	cases := []struct {
+		name          string
+		limit         int
+		seeded        []time.Duration
+		wantAllowed   bool
+		wantRemaining int
+		wantCount     int // sends left in the window afterwards
+	}{
+		{"empty window", 3, nil, true, 2, 1},
+		{"under the limit", 3, []time.Duration{time.Minute}, true, 1, 2},
+		{"reaches the limit", 3, []time.Duration{time.Minute, 2 * time.Minute}, true, 0, 3},
+		{"at the limit", 3, []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute}, false, 0, 3},
+		{"expired sends trimmed", 3, []time.Duration{2 * time.Hour, 90 * time.Minute, 61 * time.Minute}, true, 2, 1},
+		{"expired and live sends", 2, []time.Duration{2 * time.Hour, time.Minute}, true, 0, 2},
+		{"live sends at the limit after trim", 2, []time.Duration{2 * time.Hour, time.Minute, 2 * time.Minute}, false, 0, 2},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			limiter, server := newTestLimiter(t, Limits{Default: c.limit})
+			seed(t, server, recipient, c.seeded...)
+
+			res, err := limiter.Allow(context.Background(), recipient, notification.ChannelEmail, notification.TypeMagicLink)
+			if err != nil {
+				t.Fatalf("Allow: %v", err)
+			}
+			if res.Allowed != c.wantAllowed {
+				t.Errorf("Allowed = %v, want %v", res.Allowed, c.wantAllowed)
+			}
+			if res.Limit != c.limit || res.Remaining != c.wantRemaining {
+				t.Errorf("limit %d, remaining %d; want %d, %d", res.Limit, res.Remaining, c.limit, c.wantRemaining)
+			}
+			// Scores are float64 nanoseconds, exact only to a few hundred
+			if res.Reset <= 0 || res.Reset > time.Hour+time.Microsecond {
+				t.Errorf("Reset = %v, want within the hour", res.Reset)
+			}
+
+			members, err := server.ZMembers(limiterKey("", recipient))
+			if err != nil {
+				t.Fatalf("reading window: %v", err)
+			}
+			if len(members) != c.wantCount {
+				t.Errorf("window holds %d sends, want %d", len(members), c.wantCount)
+			}
+		})
+	}
+}
+
+func TestRecipientAllowRules(t *testing.T) {
+	limiter, server := newTestLimiter(t, Limits{Default: 1, Rules: map[string]int{
+		"sms":                 2,
+		"password_changed":    0,
+		"email:magic_link":    3,
+		"push:reset_password": 5,
+	}})
+	ctx := context.Background()
+	const recipient = "user@example.com"
+
+	cases := []struct {
+		channel   notification.Channel
+		notifType notification.NotificationType
+		wantKey   string
+		wantLimit int
+	}{
+		{notification.ChannelEmail, notification.TypeMagicLink, limiterKey("email:magic_link", recipient), 3},
+		{notification.ChannelSMS, notification.TypeMagicLink, limiterKey("sms", recipient), 2},
+		{notification.ChannelEmail, notification.TypeResetPassword, limiterKey("", recipient), 1},
+	}
+	for _, c := range cases {
+		res, err := limiter.Allow(ctx, recipient, c.channel, c.notifType)
+		if err != nil {
+			t.Fatalf("Allow(%s, %s): %v", c.channel, c.notifType, err)
+		}
+		if !res.Allowed || res.Limit != c.wantLimit {
+			t.Errorf("Allow(%s, %s) = allowed %v, limit %d; want allowed, %d", c.channel, c.notifType, res.Allowed, res.Limit, c.wantLimit)
+		}
+		if !server.Exists(c.wantKey) {
+			t.Errorf("Allow(%s, %s) did not count in %s", c.channel, c.notifType, c.wantKey)
+		}
+	}
+
+	// An exempt rule neither limits nor counts
+	for range 3 {
+		res, err := limiter.Allow(ctx, recipient, notification.ChannelEmail, notification.TypePasswordChanged)
+		if err != nil || !res.Allowed {
+			t.Fatalf("Allow(exempt) = %+v, %v; want allowed", res, err)
+		}
+	}
+	if server.Exists(limiterKey("password_changed", recipient)) {
+		t.Error("an exempt send was counted")
+	}
+}
+
+// TestRecipientAllowConcurrent checks that a burst of concurrent sends to one
+// recipient never lets more than the limit through.
+func TestRecipientAllowConcurrent(t *testing.T) {
+	const (
+		limit     = 5
+		burst     = 50
+		recipient = "user@example.com"
+	)
+	limiter, server := newTestLimiter(t, Limits{Default: limit})
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+		errs    []error
+	)
+	start := make(chan struct{})
+	for range burst {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			res, err := limiter.Allow(context.Background(), recipient, notification.ChannelEmail, notification.TypeMagicLink)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			if res.Allowed {
+				allowed++
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if len(errs) > 0 {
+		t.Fatalf("Allow failed %d times, first: %v", len(errs), errs[0])
+	}
+	if allowed != limit {
+		t.Errorf("%d of %d concurrent sends allowed, want %d", allowed, burst, limit)
+	}
+	members, err := server.ZMembers(limiterKey("", recipient))
+	if err != nil {
+		t.Fatalf("reading window: %v", err)
+	}
+	if len(members) > limit {
+		t.Errorf("window holds %d sends, more than the limit of %d", len(members), limit)
+	}
+}
+
+func TestRecipientUsageAndReset(t *testing.T) {
+	limiter, server := newTestLimiter(t, Limits{Default: 3, Rules: map[string]int{"sms": 2}})
+	ctx := context.Background()
+	const recipient = "user@example.com"
+	seed(t, server, recipient, 2*time.Hour, time.Minute)
+
+	if _, err := limiter.Allow(ctx, recipient, notification.ChannelSMS, notification.TypeMagicLink); err != nil {
+		t.Fatalf("Allow: %v", err)
+	}
+
+	windows, err := limiter.Usage(ctx, recipient)
+	if err != nil {
+		t.Fatalf("Usage: %v", err)
+	}
+	want := []notification.RateLimitWindow{
+		{Rule: defaultRule, Limit: 3, Used: 1, Remaining: 2},
+		{Rule: "sms", Limit: 2, Used: 1, Remaining: 1},
+	}
+	if len(windows) != len(want) {
+		t.Fatalf("Usage returned %d windows, want %d", len(windows), len(want))
+	}
+	for i, w := range want {
+		got := windows[i]
+		if got.Rule != w.Rule || got.Limit != w.Limit || got.Used != w.Used || got.Remaining != w.Remaining {
+			t.Errorf("window %d = %+v, want %+v", i, got, w)
+		}
+		if got.ResetInSec <= 0 || got.ResetInSec > 3600 {
+			t.Errorf("window %s resets in %ds, want within the hour", got.Rule, got.ResetInSec)
+		}
+	}
+
+	deleted, err := limiter.Reset(ctx, recipient)
+	if err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("Reset deleted %d windows, want 2", deleted)
+	}
+	if server.Exists(limiterKey("", recipient)) || server.Exists(limiterKey("sms", recipient)) {
+		t.Error("windows remain after Reset")
+	}
+}
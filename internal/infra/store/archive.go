@@ -0,0 +1,129 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+
+	"github.com/supabase-community/postgrest-go"
+)
+
+var _ notification.ArchiveStore = (*SupabaseStore)(nil)
+
+// unsettledStatuses lists the statuses of logs still on their way, which
+// cannot be archived, as a PostgREST in-list.
+var unsettledStatuses = "(" + quoteList([]string{
+	string(notification.StatusQueued),
+	string(notification.StatusProcessing),
+}) + ")"
+
+// ListArchivable returns the IDs of up to limit unarchived, settled logs
+// matching filter, oldest first.
+func (s *SupabaseStore) ListArchivable(ctx context.Context, filter notification.ArchiveFilter, limit int) ([]string, error) {
+	query := s.client.From(tableName).
+		Select("id", "", false).
+		Is("archived_at", "null").
+		Lt("created_at", filter.CreatedBefore.UTC().Format(time.RFC3339Nano))
+
+	if filter.Status != "" {
+		query = query.Eq("status", filter.Status)
+	} else {
+		query = query.Not("status", "in", unsettledStatuses)
+	}
+	if filter.Type != "" {
+		query = query.Eq("type", filter.Type)
+	}
+	if filter.Channel != "" {
+		query = query.Eq("channel", filter.Channel)
+	}
+	if filter.Recipient != "" {
+		query = query.Eq("recipient", filter.Recipient)
+	}
+
+	data, _, err := s.calls.execute(ctx, query.
+		Order("created_at", &postgrest.OrderOpts{Ascending: true}).
+		Range(0, limit-1, ""))
+	if err != nil {
+		return nil, fmt.Errorf("listing archivable notifications: %w", err)
+	}
+	return parseIDs(data)
+}
+
+// ArchiveLogs sets archived_at on the given logs not archived yet.
+func (s *SupabaseStore) ArchiveLogs(ctx context.Context, ids []string, at time.Time) error {
+	defer s.cache.invalidate(ctx, ids...)
+
+	update := map[string]any{
+		"archived_at": at.UTC().Format(time.RFC3339Nano),
+		"updated_at":  time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	if _, _, err := s.calls.execute(ctx, s.client.From(tableName).
+		Update(update, "", "").
+		In("id", ids).
+		Is("archived_at", "null")); err != nil {
+		return fmt.Errorf("archiving notifications: %w", err)
+	}
+	return nil
+}
+
+// UnarchiveLog clears archived_at on a log.
+func (s *SupabaseStore) UnarchiveLog(ctx context.Context, id string) error {
+	defer s.cache.invalidate(ctx, id)
+
+	update := map[string]any{
+		"archived_at": nil,
+		"updated_at":  time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	if _, _, err := s.calls.execute(ctx, s.client.From(tableName).Update(update, "", "").Eq("id", id)); err != nil {
+		return fmt.Errorf("unarchiving notification: %w", err)
+	}
+	return nil
+}
+
+// PurgeArchived deletes up to limit logs archived before before, oldest
+// archive first. PostgREST deletes take no limit, so the IDs are selected
+// first and deleted by ID.
+func (s *SupabaseStore) PurgeArchived(ctx context.Context, before time.Time, limit int) (int, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(tableName).
+		Select("id", "", false).
+		Lt("archived_at", before.UTC().Format(time.RFC3339Nano)).
+		Order("archived_at", &postgrest.OrderOpts{Ascending: true}).
+		Range(0, limit-1, ""))
+	if err != nil {
+		return 0, fmt.Errorf("listing purgeable notifications: %w", err)
+	}
+	ids, err := parseIDs(data)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	defer s.cache.invalidate(ctx, ids...)
+
+	data, _, err = s.calls.execute(ctx, s.client.From(tableName).Delete("representation", "").In("id", ids))
+	if err != nil {
+		return 0, fmt.Errorf("deleting notifications: %w", err)
+	}
+	deleted, err := parseIDs(data)
+	if err != nil {
+		return 0, err
+	}
+	return len(deleted), nil
+}
+
+// parseIDs parses a PostgREST response of rows to their IDs.
+func parseIDs(data []byte) ([]string, error) {
+	var rows []struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("parsing notification IDs: %w", err)
+	}
+	ids := make([]string, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	return ids, nil
+}
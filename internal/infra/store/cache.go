@@ -0,0 +1,251 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LogCache holds serialized notification log rows in front of Supabase.
+type LogCache interface {
+	// Get returns the value under key, or nil without error on a miss.
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Set stores value under key for ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Delete removes keys; missing keys are ignored.
+	Delete(ctx context.Context, keys ...string) error
+}
+
+var (
+	_ LogCache = (*MemoryLogCache)(nil)
+	_ LogCache = (*RedisLogCache)(nil)
+)
+
+// logCache caches the rows GetByID and GetByIdempotencyKey read. Rows are
+// cached by ID; idempotency keys map to IDs, which never change, so
+// invalidating a log by ID covers both lookups. Cache errors are logged and
+// treated as misses: the cache must never fail a read the store could serve.
+//
+// It is shared by the notification store and every store created from it, so
+// all of them invalidate the logs they write. Writers invalidate even when the
+// write fails, since a failed call may still have been applied.
+type logCache struct {
+	backend LogCache // nil when caching is off
+	ttl     time.Duration
+}
+
+func (c *logCache) enabled() bool {
+	return c.backend != nil
+}
+
+// row returns the cached row for id, if any.
+func (c *logCache) row(ctx context.Context, id string) (*supabaseRow, bool) {
+	if !c.enabled() {
+		return nil, false
+	}
+	data, err := c.backend.Get(ctx, "log:"+id)
+	if err != nil {
+		slog.Warn("log cache read failed", "id", id, "error", err)
+		return nil, false
+	}
+	if data == nil {
+		return nil, false
+	}
+	var row supabaseRow
+	if err := json.Unmarshal(data, &row); err != nil {
+		return nil, false
+	}
+	return &row, true
+}
+
+// keyID returns the ID cached for an idempotency key, if any.
+func (c *logCache) keyID(ctx context.Context, key string) (string, bool) {
+	if !c.enabled() {
+		return "", false
+	}
+	data, err := c.backend.Get(ctx, "idem:"+key)
+	if err != nil {
+		slog.Warn("log cache read failed", "idempotency_key", key, "error", err)
+		return "", false
+	}
+	return string(data), data != nil
+}
+
+// store caches row, and its idempotency key's ID when it has one.
+func (c *logCache) store(ctx context.Context, row *supabaseRow) {
+	if !c.enabled() || row.ID == "" {
+		return
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		return
+	}
+	if err := c.backend.Set(ctx, "log:"+row.ID, data, c.ttl); err != nil {
+		slog.Warn("log cache write failed", "id", row.ID, "error", err)
+		return
+	}
+	if row.IdempotencyKey != nil {
+		if err := c.backend.Set(ctx, "idem:"+*row.IdempotencyKey, []byte(row.ID), c.ttl); err != nil {
+			slog.Warn("log cache write failed", "id", row.ID, "error", err)
+		}
+	}
+}
+
+// invalidate drops the cached rows of ids. A failure is logged; the stale
+// rows then expire with the TTL.
+func (c *logCache) invalidate(ctx context.Context, ids ...string) {
+	if !c.enabled() || len(ids) == 0 {
+		return
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = "log:" + id
+	}
+	if err := c.backend.Delete(context.WithoutCancel(ctx), keys...); err != nil {
+		slog.Warn("log cache invalidation failed", "ids", len(ids), "error", err)
+	}
+}
+
+// memoryEntry is a cached value.
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// MemoryLogCache is a LogCache in process memory. Writes made by other
+// processes do not invalidate it, so their changes show once the TTL passes.
+type MemoryLogCache struct {
+	maxEntries int
+
+	mu      sync.RWMutex
+	entries map[string]memoryEntry
+}
+
+// NewMemoryLogCache creates an in-memory cache of up to maxEntries values.
+func NewMemoryLogCache(maxEntries int) *MemoryLogCache {
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+	return &MemoryLogCache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]memoryEntry),
+	}
+}
+
+// Get returns the unexpired value under key.
+func (m *MemoryLogCache) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.RLock()
+	entry, ok := m.entries[key]
+	m.mu.RUnlock()
+	if !ok || !time.Now().Before(entry.expires) {
+		return nil, nil
+	}
+	return entry.value, nil
+}
+
+// Set stores value under key, evicting to stay within maxEntries.
+func (m *MemoryLogCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.entries[key]; !exists && len(m.entries) >= m.maxEntries {
+		m.evictLocked()
+	}
+	m.entries[key] = memoryEntry{value: value, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// Delete removes keys.
+func (m *MemoryLogCache) Delete(_ context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+// evictLocked makes room for one entry: it drops every expired entry and, if
+// the cache is still full, the entry closest to expiry (the oldest, since all
+// entries share one TTL). Callers must hold the write lock.
+func (m *MemoryLogCache) evictLocked() {
+	now := time.Now()
+	var oldest string
+	var oldestExpires time.Time
+	for key, entry := range m.entries {
+		if !now.Before(entry.expires) {
+			delete(m.entries, key)
+			continue
+		}
+		if oldest == "" || entry.expires.Before(oldestExpires) {
+			oldest, oldestExpires = key, entry.expires
+		}
+	}
+	if len(m.entries) >= m.maxEntries && oldest != "" {
+		delete(m.entries, oldest)
+	}
+}
+
+// redisLogCachePrefix prefixes the Redis keys of the log cache.
+const redisLogCachePrefix = "notifly:cache:"
+
+// RedisLogCache is a LogCache in Redis, shared by every process, so a write
+// in the worker invalidates the row the server would read.
+type RedisLogCache struct {
+	client *redis.Client
+}
+
+// NewRedisLogCache creates a Redis-backed log cache.
+func NewRedisLogCache(redisAddr, password string, db int) *RedisLogCache {
+	return &RedisLogCache{
+		client: redis.NewClient(&redis.Options{
+			Addr:     redisAddr,
+			Password: password,
+			DB:       db,
+		}),
+	}
+}
+
+// Get returns the value under key.
+func (r *RedisLogCache) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := r.client.Get(ctx, redisLogCachePrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading cache: %w", err)
+	}
+	return data, nil
+}
+
+// Set stores value under key for ttl.
+func (r *RedisLogCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := r.client.Set(ctx, redisLogCachePrefix+key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("writing cache: %w", err)
+	}
+	return nil
+}
+
+// Delete removes keys.
+func (r *RedisLogCache) Delete(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = redisLogCachePrefix + key
+	}
+	if err := r.client.Del(ctx, prefixed...).Err(); err != nil {
+		return fmt.Errorf("deleting cache keys: %w", err)
+	}
+	return nil
+}
+
+// Close closes the Redis connection.
+func (r *RedisLogCache) Close() error {
+	return r.client.Close()
+}
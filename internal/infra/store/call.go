@@ -0,0 +1,163 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// CallConfig bounds and retries every PostgREST request the stores make.
+type CallConfig struct {
+	// Timeout bounds each attempt. 0 waits as long as the caller's context.
+	Timeout time.Duration
+
+	// MaxRetries is how many times a transiently failed request is retried.
+	MaxRetries int
+
+	// Backoff is the wait before the first retry; it doubles on each one.
+	Backoff time.Duration
+
+	// Fault, when set, runs before each attempt, counting against its
+	// timeout; an error it returns fails the attempt. It injects faults for
+	// chaos testing.
+	Fault func(ctx context.Context) error
+}
+
+// errCallTimeout reports an attempt that ran past CallConfig.Timeout.
+var errCallTimeout = errors.New("store call timed out")
+
+// request is a built PostgREST request. Builders can be executed repeatedly.
+type request interface {
+	Execute() ([]byte, int64, error)
+}
+
+// caller runs requests under a CallConfig. It is shared by the notification
+// store and every store created from it.
+type caller struct {
+	config CallConfig
+}
+
+// execute runs req, retrying every transient failure. Use it for reads and
+// for writes that can be applied twice: updates that set values, deletes,
+// and upserts.
+func (c *caller) execute(ctx context.Context, req request) ([]byte, int64, error) {
+	return c.run(ctx, req, true)
+}
+
+// executeOnce runs req, retrying only failures that show it was not applied,
+// such as a refused connection. Use it for writes that must not apply twice:
+// inserts, and updates conditional on the row's current state. A timeout or a
+// dropped response is returned as is, since the write may have gone through.
+func (c *caller) executeOnce(ctx context.Context, req request) ([]byte, int64, error) {
+	return c.run(ctx, req, false)
+}
+
+func (c *caller) run(ctx context.Context, req request, idempotent bool) ([]byte, int64, error) {
+	backoff := c.config.Backoff
+	for attempt := 0; ; attempt++ {
+		data, count, err := c.attempt(ctx, req)
+		if err == nil || ctx.Err() != nil || attempt >= c.config.MaxRetries || !retryable(err, idempotent) {
+			return data, count, err
+		}
+
+		slog.Warn("store call failed, retrying", "attempt", attempt+1, "retry_in", backoff.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, 0, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// attempt executes req once, giving up when ctx is done or the timeout
+// passes. postgrest-go cannot cancel a request, so one given up on finishes in
+// the background and its result is dropped.
+func (c *caller) attempt(ctx context.Context, req request) ([]byte, int64, error) {
+	if ctx.Done() == nil && c.config.Timeout <= 0 && c.config.Fault == nil {
+		return req.Execute()
+	}
+
+	type result struct {
+		data  []byte
+		count int64
+		err   error
+	}
+	// Like a slow database, a delayed request given up on still runs later
+	done := make(chan result, 1)
+	go func() {
+		if c.config.Fault != nil {
+			if err := c.config.Fault(ctx); err != nil {
+				done <- result{err: err}
+				return
+			}
+		}
+		data, count, err := req.Execute()
+		done <- result{data, count, err}
+	}()
+
+	var timeout <-chan time.Time
+	if c.config.Timeout > 0 {
+		timer := time.NewTimer(c.config.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case r := <-done:
+		return r.data, r.count, r.err
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	case <-timeout:
+		return nil, 0, fmt.Errorf("%w after %s", errCallTimeout, c.config.Timeout)
+	}
+}
+
+// postgrestCode extracts the error code postgrest-go puts in front of
+// PostgREST and Postgres error messages, as in "(PGRST003) Timed out ...".
+var postgrestCode = regexp.MustCompile(`^\(([0-9A-Z]+)\) `)
+
+// retryable reports whether err is worth retrying. Failures that show the
+// request was not applied — no connection, or PostgREST or Postgres
+// reporting a connection problem or a rolled-back transaction — always are.
+// Failures that leave it unknown — timeouts, dropped connections, and non-JSON
+// error pages from a proxy in front of PostgREST, typically a 502 or 503 —
+// are only when idempotent.
+func retryable(err error, idempotent bool) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	if m := postgrestCode.FindStringSubmatch(err.Error()); m != nil {
+		code := m[1]
+		switch {
+		case code == "PGRST000", code == "PGRST001", code == "PGRST002", code == "PGRST003":
+			return true // PostgREST could not reach the database
+		case strings.HasPrefix(code, "08"), code == "53300", code == "57P01", code == "57P03":
+			return true // connection lost, refused, or shutting down; the transaction rolled back
+		case code == "40001", code == "40P01":
+			return true // serialization failure or deadlock; the transaction rolled back
+		}
+		return false
+	}
+
+	if !idempotent {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, errCallTimeout) ||
+		errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		strings.HasPrefix(err.Error(), "error parsing error response")
+}
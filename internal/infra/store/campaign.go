@@ -0,0 +1,262 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+
+	"github.com/supabase-community/postgrest-go"
+	supa "github.com/supabase-community/supabase-go"
+)
+
+const campaignsTable = "campaigns"
+
+// campaignListColumns are the columns listed campaigns carry; the audience is
+// left out because it can hold thousands of addresses.
+const campaignListColumns = "id,name,channel,type,data,throttle,send_at_local,timezone,status,total,dispatched,suppressed,error,scheduled_at,started_at,completed_at,created_at,updated_at"
+
+var _ notification.CampaignStore = (*CampaignStore)(nil)
+
+// CampaignStore implements notification.CampaignStore on the same Supabase
+// project as the notification logs.
+type CampaignStore struct {
+	client *supa.Client
+	calls  *caller
+}
+
+// NewCampaignStore creates a campaign store sharing the notification store's client.
+func NewCampaignStore(s *SupabaseStore) *CampaignStore {
+	return &CampaignStore{client: s.client, calls: s.calls}
+}
+
+// campaignRow is the PostgREST representation of a campaigns row.
+type campaignRow struct {
+	ID          string                         `json:"id,omitempty"`
+	Name        string                         `json:"name"`
+	Channel     string                         `json:"channel"`
+	Type        string                         `json:"type"`
+	Data        map[string]any                 `json:"data,omitempty"`
+	Audience    []string                       `json:"audience"`
+	Throttle    *notification.CampaignThrottle `json:"throttle"`
+	SendAtLocal *string                        `json:"send_at_local"`
+	Timezone    *string                        `json:"timezone"`
+	Timezones   map[string]string              `json:"timezones"`
+	Content     *notification.RenderedContent  `json:"content"`
+	Status      string                         `json:"status"`
+	Total       int                            `json:"total"`
+	Dispatched  int                            `json:"dispatched"`
+	Suppressed  int                            `json:"suppressed"`
+	Error       *string                        `json:"error"`
+	ScheduledAt *string                        `json:"scheduled_at"`
+	StartedAt   *string                        `json:"started_at"`
+	CompletedAt *string                        `json:"completed_at"`
+	CreatedAt   string                         `json:"created_at,omitempty"`
+	UpdatedAt   string                         `json:"updated_at,omitempty"`
+}
+
+// CreateCampaign inserts campaign and fills in its ID and timestamps.
+func (s *CampaignStore) CreateCampaign(ctx context.Context, campaign *notification.Campaign) error {
+	row := campaignRow{
+		Name:        campaign.Name,
+		Channel:     string(campaign.Channel),
+		Type:        string(campaign.Type),
+		Data:        campaign.Data,
+		Audience:    campaign.Audience,
+		Throttle:    campaign.Throttle,
+		Timezones:   campaign.Timezones,
+		Content:     campaign.Content,
+		Status:      string(campaign.Status),
+		Total:       campaign.Total,
+		ScheduledAt: formatTime(campaign.ScheduledAt),
+		StartedAt:   formatTime(campaign.StartedAt),
+	}
+	if campaign.SendAtLocal != "" {
+		row.SendAtLocal = &campaign.SendAtLocal
+		row.Timezone = &campaign.Timezone
+	}
+
+	data, _, err := s.calls.executeOnce(ctx, s.client.From(campaignsTable).Insert(row, false, "", "representation", ""))
+	if err != nil {
+		return fmt.Errorf("inserting campaign: %w", err)
+	}
+
+	var rows []campaignRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return fmt.Errorf("parsing insert response: %w", err)
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("inserting campaign: no row returned")
+	}
+	created := rowToCampaign(&rows[0])
+	campaign.ID = created.ID
+	campaign.CreatedAt = created.CreatedAt
+	campaign.UpdatedAt = created.UpdatedAt
+	return nil
+}
+
+// GetCampaign retrieves a campaign with its audience. Returns nil, nil if none exists.
+func (s *CampaignStore) GetCampaign(ctx context.Context, id string) (*notification.Campaign, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(campaignsTable).Select("*", "", false).Eq("id", id))
+	if err != nil {
+		return nil, fmt.Errorf("fetching campaign: %w", err)
+	}
+
+	var rows []campaignRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("parsing campaign: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return rowToCampaign(&rows[0]), nil
+}
+
+// ListCampaigns returns up to limit campaigns without their audience, newest first.
+func (s *CampaignStore) ListCampaigns(ctx context.Context, limit int) ([]*notification.Campaign, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(campaignsTable).
+		Select(campaignListColumns, "", false).
+		Order("created_at", &postgrest.OrderOpts{Ascending: false}).
+		Range(0, limit-1, ""))
+	if err != nil {
+		return nil, fmt.Errorf("listing campaigns: %w", err)
+	}
+
+	var rows []campaignRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("parsing campaigns: %w", err)
+	}
+
+	campaigns := make([]*notification.Campaign, len(rows))
+	for i, row := range rows {
+		campaigns[i] = rowToCampaign(&row)
+	}
+	return campaigns, nil
+}
+
+// TransitionCampaign stores campaign's status, error, and start and completion
+// times if its stored status is one of from, and reports whether it did.
+func (s *CampaignStore) TransitionCampaign(ctx context.Context, campaign *notification.Campaign, from ...notification.CampaignStatus) (bool, error) {
+	now := time.Now().UTC()
+	update := map[string]any{
+		"status":       string(campaign.Status),
+		"error":        nil,
+		"started_at":   formatTime(campaign.StartedAt),
+		"completed_at": formatTime(campaign.CompletedAt),
+		"updated_at":   now.Format(time.RFC3339Nano),
+	}
+	if campaign.Error != "" {
+		update["error"] = campaign.Error
+	}
+	if campaign.CompletedAt != nil {
+		// Nothing more will be sent, so the addresses are no longer needed
+		update["audience"] = []string{}
+		update["timezones"] = nil
+	}
+
+	statuses := make([]string, len(from))
+	for i, status := range from {
+		statuses[i] = string(status)
+	}
+
+	data, _, err := s.calls.executeOnce(ctx, s.client.From(campaignsTable).
+		Update(update, "representation", "").
+		Eq("id", campaign.ID).
+		In("status", statuses))
+	if err != nil {
+		return false, fmt.Errorf("updating campaign status: %w", err)
+	}
+
+	var rows []campaignRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return false, fmt.Errorf("parsing update response: %w", err)
+	}
+	if len(rows) == 0 {
+		return false, nil
+	}
+	campaign.UpdatedAt = now
+	return true, nil
+}
+
+// RecordCampaignBatch stores the fan-out position and suppressed count after a batch.
+func (s *CampaignStore) RecordCampaignBatch(ctx context.Context, id string, dispatched, suppressed int) error {
+	update := map[string]any{
+		"dispatched": dispatched,
+		"suppressed": suppressed,
+		"updated_at": time.Now().UTC().Format(time.RFC3339Nano),
+	}
+
+	if _, _, err := s.calls.execute(ctx, s.client.From(campaignsTable).Update(update, "", "").Eq("id", id)); err != nil {
+		return fmt.Errorf("recording campaign batch: %w", err)
+	}
+	return nil
+}
+
+// CountCampaignLogs returns the number of the campaign's logs in each status,
+// with one head-only count query per status like CountByStatus.
+func (s *CampaignStore) CountCampaignLogs(ctx context.Context, id string) (map[notification.NotificationStatus]int, error) {
+	counts := make(map[notification.NotificationStatus]int)
+	for _, status := range notification.Statuses() {
+		_, count, err := s.calls.execute(ctx, s.client.From(tableName).
+			Select("id", "exact", true).
+			Eq("campaign_id", id).
+			Eq("status", string(status)))
+		if err != nil {
+			return nil, fmt.Errorf("counting %s campaign logs: %w", status, err)
+		}
+		counts[status] = int(count)
+	}
+	return counts, nil
+}
+
+// rowToCampaign converts a campaignRow to a Campaign.
+func rowToCampaign(row *campaignRow) *notification.Campaign {
+	campaign := &notification.Campaign{
+		ID:         row.ID,
+		Name:       row.Name,
+		Channel:    notification.Channel(row.Channel),
+		Type:       notification.NotificationType(row.Type),
+		Data:       row.Data,
+		Audience:   row.Audience,
+		Throttle:   row.Throttle,
+		Timezones:  row.Timezones,
+		Content:    row.Content,
+		Status:     notification.CampaignStatus(row.Status),
+		Total:      row.Total,
+		Dispatched: row.Dispatched,
+		Suppressed: row.Suppressed,
+	}
+	if row.Error != nil {
+		campaign.Error = *row.Error
+	}
+	if row.SendAtLocal != nil {
+		campaign.SendAtLocal = *row.SendAtLocal
+	}
+	if row.Timezone != nil {
+		campaign.Timezone = *row.Timezone
+	}
+	campaign.ScheduledAt = parseTime(row.ScheduledAt)
+	campaign.StartedAt = parseTime(row.StartedAt)
+	campaign.CompletedAt = parseTime(row.CompletedAt)
+	if t, err := time.Parse(time.RFC3339Nano, row.CreatedAt); err == nil {
+		campaign.CreatedAt = t
+	}
+	if t, err := time.Parse(time.RFC3339Nano, row.UpdatedAt); err == nil {
+		campaign.UpdatedAt = t
+	}
+	return campaign
+}
+
+// parseTime parses an optional PostgREST timestamp.
+func parseTime(s *string) *time.Time {
+	if s == nil {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, *s)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
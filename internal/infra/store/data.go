@@ -0,0 +1,80 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// compressedDataKey is the only key of the template_data object a compressed
+// payload is stored under: base64 of the gzipped JSON. Keeping the column a
+// JSON object spares a migration, and rows read back the same with
+// compression on or off.
+const compressedDataKey = "$notifly_gzip"
+
+// encodeTemplateData returns data as stored in template_data: compressed when
+// threshold is positive and its JSON is at least threshold bytes, otherwise
+// as is.
+func encodeTemplateData(data map[string]any, threshold int) (map[string]any, error) {
+	if threshold <= 0 || data == nil {
+		return data, nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("encoding template data: %w", err)
+	}
+	if len(raw) < threshold {
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(raw); err != nil {
+		return nil, fmt.Errorf("compressing template data: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("compressing template data: %w", err)
+	}
+	return map[string]any{compressedDataKey: base64.StdEncoding.EncodeToString(buf.Bytes())}, nil
+}
+
+// decodeTemplateData reverses encodeTemplateData. A payload that fails to
+// decompress is logged and returned as stored.
+func decodeTemplateData(id string, stored map[string]any) map[string]any {
+	encoded, ok := stored[compressedDataKey].(string)
+	if !ok || len(stored) != 1 {
+		return stored
+	}
+	data, err := decompressData(encoded)
+	if err != nil {
+		slog.Error("failed to decompress template data", "log_id", id, "error", err)
+		return stored
+	}
+	return data
+}
+
+// decompressData decodes base64 gzipped JSON to template data.
+func decompressData(encoded string) (map[string]any, error) {
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]any
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+
+	"github.com/supabase-community/postgrest-go"
+	supa "github.com/supabase-community/supabase-go"
+)
+
+const devicesTable = "device_tokens"
+
+var _ notification.DeviceStore = (*DeviceStore)(nil)
+
+// DeviceStore implements notification.DeviceStore on the same Supabase
+// project as the notification logs.
+type DeviceStore struct {
+	client *supa.Client
+	calls  *caller
+}
+
+// NewDeviceStore creates a device store sharing the notification store's client.
+func NewDeviceStore(s *SupabaseStore) *DeviceStore {
+	return &DeviceStore{client: s.client, calls: s.calls}
+}
+
+// deviceRow is the PostgREST representation of a device_tokens row.
+type deviceRow struct {
+	ID         string `json:"id,omitempty"`
+	UserID     string `json:"user_id"`
+	Token      string `json:"token"`
+	Platform   string `json:"platform"`
+	LastSeenAt string `json:"last_seen_at"`
+	CreatedAt  string `json:"created_at,omitempty"`
+}
+
+// UpsertDevice inserts device, or updates the row with the same token, and
+// fills in its ID and CreatedAt.
+func (s *DeviceStore) UpsertDevice(ctx context.Context, device *notification.Device) error {
+	row := deviceRow{
+		UserID:     device.UserID,
+		Token:      device.Token,
+		Platform:   string(device.Platform),
+		LastSeenAt: device.LastSeenAt.UTC().Format(time.RFC3339Nano),
+	}
+
+	data, _, err := s.calls.execute(ctx, s.client.From(devicesTable).Insert(row, true, "token", "representation", ""))
+	if err != nil {
+		return fmt.Errorf("upserting device: %w", err)
+	}
+
+	var rows []deviceRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return fmt.Errorf("parsing upsert response: %w", err)
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("upserting device: no row returned")
+	}
+	stored := rowToDevice(&rows[0])
+	device.ID = stored.ID
+	device.CreatedAt = stored.CreatedAt
+	return nil
+}
+
+// ListDevices returns a user's devices, most recently seen first.
+func (s *DeviceStore) ListDevices(ctx context.Context, userID string) ([]*notification.Device, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(devicesTable).
+		Select("*", "", false).
+		Eq("user_id", userID).
+		Order("last_seen_at", &postgrest.OrderOpts{Ascending: false}))
+	if err != nil {
+		return nil, fmt.Errorf("listing devices: %w", err)
+	}
+
+	var rows []deviceRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("parsing devices: %w", err)
+	}
+	devices := make([]*notification.Device, len(rows))
+	for i, row := range rows {
+		devices[i] = rowToDevice(&row)
+	}
+	return devices, nil
+}
+
+// DeleteDevice removes a token. Returns false if it was not registered.
+func (s *DeviceStore) DeleteDevice(ctx context.Context, token string) (bool, error) {
+	n, err := s.DeleteDeviceTokens(ctx, []string{token})
+	return n > 0, err
+}
+
+// DeleteDeviceTokens removes every given token and returns how many were registered.
+func (s *DeviceStore) DeleteDeviceTokens(ctx context.Context, tokens []string) (int, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(devicesTable).Delete("representation", "").In("token", tokens))
+	if err != nil {
+		return 0, fmt.Errorf("deleting device tokens: %w", err)
+	}
+
+	var rows []deviceRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return 0, fmt.Errorf("parsing delete response: %w", err)
+	}
+	return len(rows), nil
+}
+
+// rowToDevice converts a deviceRow to a Device.
+func rowToDevice(row *deviceRow) *notification.Device {
+	device := &notification.Device{
+		ID:       row.ID,
+		UserID:   row.UserID,
+		Token:    row.Token,
+		Platform: notification.Platform(row.Platform),
+	}
+	if t, err := time.Parse(time.RFC3339Nano, row.LastSeenAt); err == nil {
+		device.LastSeenAt = t
+	}
+	if t, err := time.Parse(time.RFC3339Nano, row.CreatedAt); err == nil {
+		device.CreatedAt = t
+	}
+	return device
+}
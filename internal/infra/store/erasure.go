@@ -0,0 +1,211 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+
+	supa "github.com/supabase-community/supabase-go"
+)
+
+const erasureJobsTable = "erasure_jobs"
+
+var _ notification.ErasureStore = (*ErasureStore)(nil)
+
+// ErasureStore implements notification.ErasureStore on the same Supabase
+// project as the notification logs.
+type ErasureStore struct {
+	client *supa.Client
+	calls  *caller
+	cache  *logCache
+}
+
+// NewErasureStore creates an erasure store sharing the notification store's client.
+func NewErasureStore(s *SupabaseStore) *ErasureStore {
+	return &ErasureStore{client: s.client, calls: s.calls, cache: s.cache}
+}
+
+// erasureJobRow is the PostgREST representation of an erasure_jobs row.
+type erasureJobRow struct {
+	ID            string  `json:"id,omitempty"`
+	RecipientHash string  `json:"recipient_hash"`
+	Status        string  `json:"status"`
+	LogsErased    int     `json:"logs_erased"`
+	Error         *string `json:"error"`
+	CreatedAt     string  `json:"created_at,omitempty"`
+	CompletedAt   *string `json:"completed_at"`
+}
+
+// CreateErasureJob inserts job and fills in its ID and CreatedAt.
+func (s *ErasureStore) CreateErasureJob(ctx context.Context, job *notification.ErasureJob) error {
+	row := erasureJobRow{RecipientHash: job.RecipientHash, Status: string(job.Status)}
+
+	data, _, err := s.calls.executeOnce(ctx, s.client.From(erasureJobsTable).Insert(row, false, "", "representation", ""))
+	if err != nil {
+		return fmt.Errorf("inserting erasure job: %w", err)
+	}
+
+	var rows []erasureJobRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return fmt.Errorf("parsing insert response: %w", err)
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("inserting erasure job: no row returned")
+	}
+	created := rowToErasureJob(&rows[0])
+	job.ID = created.ID
+	job.CreatedAt = created.CreatedAt
+	return nil
+}
+
+// GetErasureJob retrieves a job by ID. Returns nil, nil if none exists.
+func (s *ErasureStore) GetErasureJob(ctx context.Context, id string) (*notification.ErasureJob, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(erasureJobsTable).Select("*", "", false).Eq("id", id))
+	if err != nil {
+		return nil, fmt.Errorf("fetching erasure job: %w", err)
+	}
+
+	var rows []erasureJobRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("parsing erasure job: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return rowToErasureJob(&rows[0]), nil
+}
+
+// UpdateErasureJob stores job's status, count, error, and completion time.
+func (s *ErasureStore) UpdateErasureJob(ctx context.Context, job *notification.ErasureJob) error {
+	update := map[string]any{
+		"status":      string(job.Status),
+		"logs_erased": job.LogsErased,
+		"error":       nil,
+	}
+	if job.Error != "" {
+		update["error"] = job.Error
+	}
+	if job.CompletedAt != nil {
+		update["completed_at"] = job.CompletedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	if _, _, err := s.calls.execute(ctx, s.client.From(erasureJobsTable).Update(update, "", "").Eq("id", job.ID)); err != nil {
+		return fmt.Errorf("updating erasure job: %w", err)
+	}
+	return nil
+}
+
+// EraseRecipientLogs anonymizes up to limit logs addressed to recipient. Every
+// field that can hold personal data is cleared; status, type, channel,
+// timestamps, and the provider ID stay so stats and webhooks keep working.
+func (s *ErasureStore) EraseRecipientLogs(ctx context.Context, recipient string, limit int) (int, error) {
+	quoted := `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(recipient) + `"`
+	matches := fmt.Sprintf("recipient.eq.%[1]s,recipients.cs.{%[1]s},cc.cs.{%[1]s},bcc.cs.{%[1]s},fallback->>to.eq.%[1]s,escalation->to->>email.eq.%[1]s,escalation->to->>sms.eq.%[1]s,escalation->to->>push.eq.%[1]s", quoted)
+
+	data, _, err := s.calls.execute(ctx, s.client.From(tableName).
+		Select("id", "", false).
+		Or(matches, "").
+		Limit(limit, ""))
+	if err != nil {
+		return 0, fmt.Errorf("listing recipient logs: %w", err)
+	}
+
+	var rows []struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return 0, fmt.Errorf("parsing recipient logs: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]string, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+
+	update := map[string]any{
+		"recipient":       notification.ErasedRecipient,
+		"recipients":      nil,
+		"cc":              nil,
+		"bcc":             nil,
+		"reply_to":        nil,
+		"headers":         nil,
+		"tags":            nil,
+		"template_data":   nil,
+		"content":         nil,
+		"fallback":        nil,
+		"escalation":      nil,
+		"error_message":   nil,
+		"idempotency_key": nil, // derived keys embed the recipient
+		"payload_hash":    nil,
+		"updated_at":      time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	defer s.cache.invalidate(ctx, ids...)
+	if _, _, err := s.calls.execute(ctx, s.client.From(tableName).Update(update, "", "").In("id", ids)); err != nil {
+		return 0, fmt.Errorf("anonymizing recipient logs: %w", err)
+	}
+	return len(ids), nil
+}
+
+// webhookRecipientFilters match the payload field holding a webhook event's
+// addressees, per provider: Resend's data.to, SES's mail.destination, and
+// Twilio's To param.
+var webhookRecipientFilters = []struct {
+	column string
+	value  func(recipient string) any
+}{
+	{"payload->data->to", func(r string) any { return []string{r} }},
+	{"payload->mail->destination", func(r string) any { return []string{r} }},
+	{"payload", func(r string) any { return map[string]string{"To": r} }},
+}
+
+// EraseRecipientWebhookEvents deletes stored webhook events whose payload is
+// addressed to recipient.
+func (s *ErasureStore) EraseRecipientWebhookEvents(ctx context.Context, recipient string) (int, error) {
+	deleted := 0
+	for _, filter := range webhookRecipientFilters {
+		data, _, err := s.calls.execute(ctx, s.client.From(webhookEventsTable).
+			Delete("representation", "").
+			ContainsObject(filter.column, filter.value(recipient)))
+		if err != nil {
+			return deleted, fmt.Errorf("deleting recipient webhook events: %w", err)
+		}
+
+		var rows []struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(data, &rows); err != nil {
+			return deleted, fmt.Errorf("parsing deleted webhook events: %w", err)
+		}
+		deleted += len(rows)
+	}
+	return deleted, nil
+}
+
+// rowToErasureJob converts an erasureJobRow to an ErasureJob.
+func rowToErasureJob(row *erasureJobRow) *notification.ErasureJob {
+	job := &notification.ErasureJob{
+		ID:            row.ID,
+		RecipientHash: row.RecipientHash,
+		Status:        notification.ErasureStatus(row.Status),
+		LogsErased:    row.LogsErased,
+	}
+	if row.Error != nil {
+		job.Error = *row.Error
+	}
+	if t, err := time.Parse(time.RFC3339Nano, row.CreatedAt); err == nil {
+		job.CreatedAt = t
+	}
+	if row.CompletedAt != nil {
+		if t, err := time.Parse(time.RFC3339Nano, *row.CompletedAt); err == nil {
+			job.CompletedAt = &t
+		}
+	}
+	return job
+}
@@ -0,0 +1,171 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+
+	"github.com/supabase-community/postgrest-go"
+	supa "github.com/supabase-community/supabase-go"
+)
+
+const escalationPoliciesTable = "escalation_policies"
+
+var _ notification.EscalationPolicyStore = (*EscalationPolicyStore)(nil)
+
+// EscalationPolicyStore implements notification.EscalationPolicyStore on the
+// same Supabase project as the notification logs.
+type EscalationPolicyStore struct {
+	client *supa.Client
+	calls  *caller
+}
+
+// NewEscalationPolicyStore creates an escalation policy store sharing the
+// notification store's client.
+func NewEscalationPolicyStore(s *SupabaseStore) *EscalationPolicyStore {
+	return &EscalationPolicyStore{client: s.client, calls: s.calls}
+}
+
+// escalationPolicyRow is the PostgREST representation of an escalation_policies row.
+type escalationPolicyRow struct {
+	ID        string                        `json:"id,omitempty"`
+	Name      string                        `json:"name"`
+	Type      string                        `json:"type"`
+	Steps     []notification.EscalationStep `json:"steps"`
+	Enabled   bool                          `json:"enabled"`
+	CreatedAt string                        `json:"created_at,omitempty"`
+	UpdatedAt string                        `json:"updated_at,omitempty"`
+}
+
+// CreatePolicy inserts policy and fills in its ID and timestamps.
+func (s *EscalationPolicyStore) CreatePolicy(ctx context.Context, policy *notification.EscalationPolicy) error {
+	row := escalationPolicyRow{
+		Name:    policy.Name,
+		Type:    string(policy.Type),
+		Steps:   policy.Steps,
+		Enabled: policy.Enabled,
+	}
+
+	data, _, err := s.calls.executeOnce(ctx, s.client.From(escalationPoliciesTable).Insert(row, false, "", "representation", ""))
+	if err != nil {
+		return fmt.Errorf("inserting escalation policy: %w", err)
+	}
+
+	var rows []escalationPolicyRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return fmt.Errorf("parsing insert response: %w", err)
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("inserting escalation policy: no row returned")
+	}
+	created := rowToEscalationPolicy(&rows[0])
+	policy.ID = created.ID
+	policy.CreatedAt = created.CreatedAt
+	policy.UpdatedAt = created.UpdatedAt
+	return nil
+}
+
+// GetPolicy retrieves a policy by ID. Returns nil, nil if none exists.
+func (s *EscalationPolicyStore) GetPolicy(ctx context.Context, id string) (*notification.EscalationPolicy, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(escalationPoliciesTable).Select("*", "", false).Eq("id", id))
+	if err != nil {
+		return nil, fmt.Errorf("fetching escalation policy: %w", err)
+	}
+	return firstEscalationPolicy(data)
+}
+
+// GetPolicyByType retrieves the policy for a notification type. Returns nil,
+// nil if none exists.
+func (s *EscalationPolicyStore) GetPolicyByType(ctx context.Context, notifType notification.NotificationType) (*notification.EscalationPolicy, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(escalationPoliciesTable).Select("*", "", false).Eq("type", string(notifType)))
+	if err != nil {
+		return nil, fmt.Errorf("fetching escalation policy for %s: %w", notifType, err)
+	}
+	return firstEscalationPolicy(data)
+}
+
+// ListPolicies returns every policy, oldest first.
+func (s *EscalationPolicyStore) ListPolicies(ctx context.Context) ([]*notification.EscalationPolicy, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(escalationPoliciesTable).
+		Select("*", "", false).
+		Order("created_at", &postgrest.OrderOpts{Ascending: true}))
+	if err != nil {
+		return nil, fmt.Errorf("listing escalation policies: %w", err)
+	}
+
+	var rows []escalationPolicyRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("parsing escalation policies: %w", err)
+	}
+	policies := make([]*notification.EscalationPolicy, len(rows))
+	for i, row := range rows {
+		policies[i] = rowToEscalationPolicy(&row)
+	}
+	return policies, nil
+}
+
+// UpdatePolicy stores policy's definition.
+func (s *EscalationPolicyStore) UpdatePolicy(ctx context.Context, policy *notification.EscalationPolicy) error {
+	now := time.Now().UTC()
+	update := map[string]any{
+		"name":       policy.Name,
+		"type":       string(policy.Type),
+		"steps":      policy.Steps,
+		"enabled":    policy.Enabled,
+		"updated_at": now.Format(time.RFC3339Nano),
+	}
+
+	if _, _, err := s.calls.execute(ctx, s.client.From(escalationPoliciesTable).Update(update, "", "").Eq("id", policy.ID)); err != nil {
+		return fmt.Errorf("updating escalation policy: %w", err)
+	}
+	policy.UpdatedAt = now
+	return nil
+}
+
+// DeletePolicy removes a policy. Returns false if none existed.
+func (s *EscalationPolicyStore) DeletePolicy(ctx context.Context, id string) (bool, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(escalationPoliciesTable).Delete("representation", "").Eq("id", id))
+	if err != nil {
+		return false, fmt.Errorf("deleting escalation policy: %w", err)
+	}
+
+	var rows []escalationPolicyRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return false, fmt.Errorf("parsing delete response: %w", err)
+	}
+	return len(rows) > 0, nil
+}
+
+// firstEscalationPolicy decodes a PostgREST response of at most one
+// escalation_policies row. Returns nil, nil if it has none.
+func firstEscalationPolicy(data []byte) (*notification.EscalationPolicy, error) {
+	var rows []escalationPolicyRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("parsing escalation policy: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return rowToEscalationPolicy(&rows[0]), nil
+}
+
+// rowToEscalationPolicy converts an escalationPolicyRow to an EscalationPolicy.
+func rowToEscalationPolicy(row *escalationPolicyRow) *notification.EscalationPolicy {
+	policy := &notification.EscalationPolicy{
+		ID:      row.ID,
+		Name:    row.Name,
+		Type:    notification.NotificationType(row.Type),
+		Steps:   row.Steps,
+		Enabled: row.Enabled,
+	}
+	if t, err := time.Parse(time.RFC3339Nano, row.CreatedAt); err == nil {
+		policy.CreatedAt = t
+	}
+	if t, err := time.Parse(time.RFC3339Nano, row.UpdatedAt); err == nil {
+		policy.UpdatedAt = t
+	}
+	return policy
+}
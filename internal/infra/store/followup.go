@@ -0,0 +1,171 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+
+	"github.com/supabase-community/postgrest-go"
+	supa "github.com/supabase-community/supabase-go"
+)
+
+const followUpSequencesTable = "follow_up_sequences"
+
+var _ notification.FollowUpStore = (*FollowUpStore)(nil)
+
+// FollowUpStore implements notification.FollowUpStore on the same Supabase
+// project as the notification logs.
+type FollowUpStore struct {
+	client *supa.Client
+	calls  *caller
+}
+
+// NewFollowUpStore creates a follow-up sequence store sharing the notification
+// store's client.
+func NewFollowUpStore(s *SupabaseStore) *FollowUpStore {
+	return &FollowUpStore{client: s.client, calls: s.calls}
+}
+
+// followUpSequenceRow is the PostgREST representation of a follow_up_sequences row.
+type followUpSequenceRow struct {
+	ID        string                      `json:"id,omitempty"`
+	Name      string                      `json:"name"`
+	Type      string                      `json:"type"`
+	Steps     []notification.FollowUpStep `json:"steps"`
+	Enabled   bool                        `json:"enabled"`
+	CreatedAt string                      `json:"created_at,omitempty"`
+	UpdatedAt string                      `json:"updated_at,omitempty"`
+}
+
+// CreateSequence inserts sequence and fills in its ID and timestamps.
+func (s *FollowUpStore) CreateSequence(ctx context.Context, sequence *notification.FollowUpSequence) error {
+	row := followUpSequenceRow{
+		Name:    sequence.Name,
+		Type:    string(sequence.Type),
+		Steps:   sequence.Steps,
+		Enabled: sequence.Enabled,
+	}
+
+	data, _, err := s.calls.executeOnce(ctx, s.client.From(followUpSequencesTable).Insert(row, false, "", "representation", ""))
+	if err != nil {
+		return fmt.Errorf("inserting follow-up sequence: %w", err)
+	}
+
+	var rows []followUpSequenceRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return fmt.Errorf("parsing insert response: %w", err)
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("inserting follow-up sequence: no row returned")
+	}
+	created := rowToFollowUpSequence(&rows[0])
+	sequence.ID = created.ID
+	sequence.CreatedAt = created.CreatedAt
+	sequence.UpdatedAt = created.UpdatedAt
+	return nil
+}
+
+// GetSequence retrieves a sequence by ID. Returns nil, nil if none exists.
+func (s *FollowUpStore) GetSequence(ctx context.Context, id string) (*notification.FollowUpSequence, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(followUpSequencesTable).Select("*", "", false).Eq("id", id))
+	if err != nil {
+		return nil, fmt.Errorf("fetching follow-up sequence: %w", err)
+	}
+	return firstFollowUpSequence(data)
+}
+
+// GetSequenceByType retrieves the sequence for a notification type. Returns
+// nil, nil if none exists.
+func (s *FollowUpStore) GetSequenceByType(ctx context.Context, notifType notification.NotificationType) (*notification.FollowUpSequence, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(followUpSequencesTable).Select("*", "", false).Eq("type", string(notifType)))
+	if err != nil {
+		return nil, fmt.Errorf("fetching follow-up sequence for %s: %w", notifType, err)
+	}
+	return firstFollowUpSequence(data)
+}
+
+// ListSequences returns every sequence, oldest first.
+func (s *FollowUpStore) ListSequences(ctx context.Context) ([]*notification.FollowUpSequence, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(followUpSequencesTable).
+		Select("*", "", false).
+		Order("created_at", &postgrest.OrderOpts{Ascending: true}))
+	if err != nil {
+		return nil, fmt.Errorf("listing follow-up sequences: %w", err)
+	}
+
+	var rows []followUpSequenceRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("parsing follow-up sequences: %w", err)
+	}
+	sequences := make([]*notification.FollowUpSequence, len(rows))
+	for i, row := range rows {
+		sequences[i] = rowToFollowUpSequence(&row)
+	}
+	return sequences, nil
+}
+
+// UpdateSequence stores sequence's definition.
+func (s *FollowUpStore) UpdateSequence(ctx context.Context, sequence *notification.FollowUpSequence) error {
+	now := time.Now().UTC()
+	update := map[string]any{
+		"name":       sequence.Name,
+		"type":       string(sequence.Type),
+		"steps":      sequence.Steps,
+		"enabled":    sequence.Enabled,
+		"updated_at": now.Format(time.RFC3339Nano),
+	}
+
+	if _, _, err := s.calls.execute(ctx, s.client.From(followUpSequencesTable).Update(update, "", "").Eq("id", sequence.ID)); err != nil {
+		return fmt.Errorf("updating follow-up sequence: %w", err)
+	}
+	sequence.UpdatedAt = now
+	return nil
+}
+
+// DeleteSequence removes a sequence. Returns false if none existed.
+func (s *FollowUpStore) DeleteSequence(ctx context.Context, id string) (bool, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(followUpSequencesTable).Delete("representation", "").Eq("id", id))
+	if err != nil {
+		return false, fmt.Errorf("deleting follow-up sequence: %w", err)
+	}
+
+	var rows []followUpSequenceRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return false, fmt.Errorf("parsing delete response: %w", err)
+	}
+	return len(rows) > 0, nil
+}
+
+// firstFollowUpSequence decodes a PostgREST response of at most one
+// follow_up_sequences row. Returns nil, nil if it has none.
+func firstFollowUpSequence(data []byte) (*notification.FollowUpSequence, error) {
+	var rows []followUpSequenceRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("parsing follow-up sequence: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return rowToFollowUpSequence(&rows[0]), nil
+}
+
+// rowToFollowUpSequence converts a followUpSequenceRow to a FollowUpSequence.
+func rowToFollowUpSequence(row *followUpSequenceRow) *notification.FollowUpSequence {
+	sequence := &notification.FollowUpSequence{
+		ID:      row.ID,
+		Name:    row.Name,
+		Type:    notification.NotificationType(row.Type),
+		Steps:   row.Steps,
+		Enabled: row.Enabled,
+	}
+	if t, err := time.Parse(time.RFC3339Nano, row.CreatedAt); err == nil {
+		sequence.CreatedAt = t
+	}
+	if t, err := time.Parse(time.RFC3339Nano, row.UpdatedAt); err == nil {
+		sequence.UpdatedAt = t
+	}
+	return sequence
+}
@@ -0,0 +1,223 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+
+	"github.com/supabase-community/postgrest-go"
+	supa "github.com/supabase-community/supabase-go"
+)
+
+const schedulesTable = "schedules"
+
+var _ notification.ScheduleStore = (*ScheduleStore)(nil)
+
+// ScheduleStore implements notification.ScheduleStore on the same Supabase
+// project as the notification logs.
+type ScheduleStore struct {
+	client *supa.Client
+	calls  *caller
+}
+
+// NewScheduleStore creates a schedule store sharing the notification store's client.
+func NewScheduleStore(s *SupabaseStore) *ScheduleStore {
+	return &ScheduleStore{client: s.client, calls: s.calls}
+}
+
+// scheduleRow is the PostgREST representation of a schedules row.
+type scheduleRow struct {
+	ID        string                   `json:"id,omitempty"`
+	Name      string                   `json:"name"`
+	Cron      string                   `json:"cron"`
+	Timezone  string                   `json:"timezone"`
+	Request   notification.SendRequest `json:"request"`
+	Enabled   bool                     `json:"enabled"`
+	NextRunAt *string                  `json:"next_run_at"`
+	LastRunAt *string                  `json:"last_run_at,omitempty"`
+	LastError *string                  `json:"last_error,omitempty"`
+	CreatedAt string                   `json:"created_at,omitempty"`
+	UpdatedAt string                   `json:"updated_at,omitempty"`
+}
+
+// CreateSchedule inserts schedule and fills in its ID and timestamps.
+func (s *ScheduleStore) CreateSchedule(ctx context.Context, schedule *notification.Schedule) error {
+	row := scheduleRow{
+		Name:      schedule.Name,
+		Cron:      schedule.Cron,
+		Timezone:  schedule.Timezone,
+		Request:   schedule.Request,
+		Enabled:   schedule.Enabled,
+		NextRunAt: formatTime(schedule.NextRunAt),
+	}
+
+	data, _, err := s.calls.executeOnce(ctx, s.client.From(schedulesTable).Insert(row, false, "", "representation", ""))
+	if err != nil {
+		return fmt.Errorf("inserting schedule: %w", err)
+	}
+
+	var rows []scheduleRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return fmt.Errorf("parsing insert response: %w", err)
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("inserting schedule: no row returned")
+	}
+	created := rowToSchedule(&rows[0])
+	schedule.ID = created.ID
+	schedule.CreatedAt = created.CreatedAt
+	schedule.UpdatedAt = created.UpdatedAt
+	return nil
+}
+
+// GetSchedule retrieves a schedule by ID. Returns nil, nil if none exists.
+func (s *ScheduleStore) GetSchedule(ctx context.Context, id string) (*notification.Schedule, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(schedulesTable).Select("*", "", false).Eq("id", id))
+	if err != nil {
+		return nil, fmt.Errorf("fetching schedule: %w", err)
+	}
+
+	var rows []scheduleRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("parsing schedule: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return rowToSchedule(&rows[0]), nil
+}
+
+// ListSchedules returns every schedule, oldest first.
+func (s *ScheduleStore) ListSchedules(ctx context.Context) ([]*notification.Schedule, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(schedulesTable).
+		Select("*", "", false).
+		Order("created_at", &postgrest.OrderOpts{Ascending: true}))
+	if err != nil {
+		return nil, fmt.Errorf("listing schedules: %w", err)
+	}
+	return parseSchedules(data)
+}
+
+// UpdateSchedule stores schedule's definition and next run time.
+func (s *ScheduleStore) UpdateSchedule(ctx context.Context, schedule *notification.Schedule) error {
+	now := time.Now().UTC()
+	update := map[string]any{
+		"name":        schedule.Name,
+		"cron":        schedule.Cron,
+		"timezone":    schedule.Timezone,
+		"request":     schedule.Request,
+		"enabled":     schedule.Enabled,
+		"next_run_at": formatTime(schedule.NextRunAt),
+		"updated_at":  now.Format(time.RFC3339Nano),
+	}
+
+	if _, _, err := s.calls.execute(ctx, s.client.From(schedulesTable).Update(update, "", "").Eq("id", schedule.ID)); err != nil {
+		return fmt.Errorf("updating schedule: %w", err)
+	}
+	schedule.UpdatedAt = now
+	return nil
+}
+
+// DeleteSchedule removes a schedule. Returns false if none existed.
+func (s *ScheduleStore) DeleteSchedule(ctx context.Context, id string) (bool, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(schedulesTable).Delete("representation", "").Eq("id", id))
+	if err != nil {
+		return false, fmt.Errorf("deleting schedule: %w", err)
+	}
+
+	var rows []scheduleRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return false, fmt.Errorf("parsing delete response: %w", err)
+	}
+	return len(rows) > 0, nil
+}
+
+// ListDueSchedules returns up to limit enabled schedules whose next run is at
+// or before now, earliest first.
+func (s *ScheduleStore) ListDueSchedules(ctx context.Context, now time.Time, limit int) ([]*notification.Schedule, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(schedulesTable).
+		Select("*", "", false).
+		Eq("enabled", "true").
+		Lte("next_run_at", now.UTC().Format(time.RFC3339Nano)).
+		Order("next_run_at", &postgrest.OrderOpts{Ascending: true}).
+		Limit(limit, ""))
+	if err != nil {
+		return nil, fmt.Errorf("listing due schedules: %w", err)
+	}
+	return parseSchedules(data)
+}
+
+// RecordScheduleRun stores the outcome of a run and the next run time.
+func (s *ScheduleStore) RecordScheduleRun(ctx context.Context, id string, ranAt, next time.Time, errMsg string) error {
+	update := map[string]any{
+		"last_run_at": ranAt.UTC().Format(time.RFC3339Nano),
+		"next_run_at": next.UTC().Format(time.RFC3339Nano),
+		"last_error":  nil,
+	}
+	if errMsg != "" {
+		update["last_error"] = errMsg
+	}
+
+	if _, _, err := s.calls.execute(ctx, s.client.From(schedulesTable).Update(update, "", "").Eq("id", id)); err != nil {
+		return fmt.Errorf("recording schedule run: %w", err)
+	}
+	return nil
+}
+
+// parseSchedules decodes a PostgREST response of schedules rows.
+func parseSchedules(data []byte) ([]*notification.Schedule, error) {
+	var rows []scheduleRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("parsing schedules: %w", err)
+	}
+
+	schedules := make([]*notification.Schedule, len(rows))
+	for i, row := range rows {
+		schedules[i] = rowToSchedule(&row)
+	}
+	return schedules, nil
+}
+
+// formatTime formats t for PostgREST, or returns nil to store NULL.
+func formatTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.UTC().Format(time.RFC3339Nano)
+	return &s
+}
+
+// rowToSchedule converts a scheduleRow to a Schedule.
+func rowToSchedule(row *scheduleRow) *notification.Schedule {
+	schedule := &notification.Schedule{
+		ID:       row.ID,
+		Name:     row.Name,
+		Cron:     row.Cron,
+		Timezone: row.Timezone,
+		Request:  row.Request,
+		Enabled:  row.Enabled,
+	}
+	if row.NextRunAt != nil {
+		if t, err := time.Parse(time.RFC3339Nano, *row.NextRunAt); err == nil {
+			schedule.NextRunAt = &t
+		}
+	}
+	if row.LastRunAt != nil {
+		if t, err := time.Parse(time.RFC3339Nano, *row.LastRunAt); err == nil {
+			schedule.LastRunAt = &t
+		}
+	}
+	if row.LastError != nil {
+		schedule.LastError = *row.LastError
+	}
+	if t, err := time.Parse(time.RFC3339Nano, row.CreatedAt); err == nil {
+		schedule.CreatedAt = t
+	}
+	if t, err := time.Parse(time.RFC3339Nano, row.UpdatedAt); err == nil {
+		schedule.UpdatedAt = t
+	}
+	return schedule
+}
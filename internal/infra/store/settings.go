@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/settings"
+
+	supa "github.com/supabase-community/supabase-go"
+)
+
+const settingsTable = "settings"
+
+var _ settings.Store = (*SettingsStore)(nil)
+
+// SettingsStore implements settings.Store on the same Supabase project as the notification logs.
+type SettingsStore struct {
+	client *supa.Client
+	calls  *caller
+}
+
+// NewSettingsStore creates a settings store sharing the notification store's client.
+func NewSettingsStore(s *SupabaseStore) *SettingsStore {
+	return &SettingsStore{client: s.client, calls: s.calls}
+}
+
+// settingRow is the PostgREST representation of a settings row.
+type settingRow struct {
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	UpdatedAt string          `json:"updated_at,omitempty"`
+}
+
+// List returns every stored setting.
+func (s *SettingsStore) List(ctx context.Context) ([]settings.Setting, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(settingsTable).Select("*", "", false))
+	if err != nil {
+		return nil, fmt.Errorf("listing settings: %w", err)
+	}
+
+	var rows []settingRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("parsing settings: %w", err)
+	}
+
+	out := make([]settings.Setting, 0, len(rows))
+	for _, row := range rows {
+		var value any
+		if err := json.Unmarshal(row.Value, &value); err != nil {
+			return nil, fmt.Errorf("parsing value of setting %s: %w", row.Key, err)
+		}
+		st := settings.Setting{Key: settings.Key(row.Key), Value: value}
+		if t, err := time.Parse(time.RFC3339Nano, row.UpdatedAt); err == nil {
+			st.UpdatedAt = t
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// Put inserts or replaces the value for key.
+func (s *SettingsStore) Put(ctx context.Context, key settings.Key, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("encoding setting %s: %w", key, err)
+	}
+
+	row := settingRow{
+		Key:       string(key),
+		Value:     raw,
+		UpdatedAt: time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	if _, _, err := s.calls.execute(ctx, s.client.From(settingsTable).Upsert(row, "key", "minimal", "")); err != nil {
+		return fmt.Errorf("upserting setting %s: %w", key, err)
+	}
+	return nil
+}
+
+// Delete removes the override for key.
+func (s *SettingsStore) Delete(ctx context.Context, key settings.Key) error {
+	if _, _, err := s.calls.execute(ctx, s.client.From(settingsTable).Delete("minimal", "").Eq("key", string(key))); err != nil {
+		return fmt.Errorf("deleting setting %s: %w", key, err)
+	}
+	return nil
+}
@@ -4,9 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
-	"notifly/internal/domain/notification"
+	"github.com/badrkarrachai/notifly/pkg/notification"
 
 	"github.com/supabase-community/postgrest-go"
 	supa "github.com/supabase-community/supabase-go"
@@ -19,56 +20,193 @@ var _ notification.NotificationStore = (*SupabaseStore)(nil)
 // SupabaseStore implements NotificationStore using the Supabase Go SDK.
 type SupabaseStore struct {
 	client *supa.Client
+	calls  *caller
+	cache  *logCache
+
+	// replica serves List and the count queries when set, so dashboards
+	// reading them do not load the primary the sends write to
+	replica *supa.Client
+
+	// compressData is the template data size, in JSON bytes, from which
+	// Create stores it compressed; 0 stores it as is
+	compressData int
 }
 
-// NewSupabaseStore creates a new Supabase-backed notification store.
-func NewSupabaseStore(supabaseURL, serviceKey string) (*SupabaseStore, error) {
+// NewSupabaseStore creates a new Supabase-backed notification store. Every
+// request it and the stores created from it make is bounded and retried per
+// calls.
+func NewSupabaseStore(supabaseURL, serviceKey string, calls CallConfig) (*SupabaseStore, error) {
 	client, err := supa.NewClient(supabaseURL, serviceKey, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating supabase client: %w", err)
 	}
-	return &SupabaseStore{client: client}, nil
+	return &SupabaseStore{client: client, calls: &caller{config: calls}, cache: &logCache{}}, nil
+}
+
+// SetCache caches the logs GetByID and GetByIdempotencyKey read in cache for
+// ttl. Every write to a log through this store, or a store created from it,
+// invalidates the log's entry. Call it before the store is used.
+func (s *SupabaseStore) SetCache(cache LogCache, ttl time.Duration) {
+	s.cache.backend = cache
+	s.cache.ttl = ttl
+}
+
+// SetReadReplica sends List (unless its filter asks for the primary) and
+// the count queries behind stats, usage, and reports to the read replica at
+// replicaURL, authenticated with serviceKey. Replicas lag the primary
+// slightly, so those reads may miss the latest writes. Call it before the
+// store is used.
+func (s *SupabaseStore) SetReadReplica(replicaURL, serviceKey string) error {
+	client, err := supa.NewClient(replicaURL, serviceKey, nil)
+	if err != nil {
+		return fmt.Errorf("creating supabase replica client: %w", err)
+	}
+	s.replica = client
+	return nil
+}
+
+// reads returns the client for reads that tolerate replica lag: the replica
+// when there is one, otherwise the primary.
+func (s *SupabaseStore) reads() *supa.Client {
+	if s.replica != nil {
+		return s.replica
+	}
+	return s.client
+}
+
+// SetDataCompression stores the template data of new logs gzipped once its
+// JSON reaches threshold bytes; 0 turns compression off. Logs are read back
+// the same either way. Call it before the store is used.
+func (s *SupabaseStore) SetDataCompression(threshold int) {
+	s.compressData = threshold
+}
+
+// Ping reads one log id, which fails unless Supabase is reachable, the
+// service key is accepted, and the schema is migrated.
+func (s *SupabaseStore) Ping(ctx context.Context) error {
+	_, _, err := s.calls.execute(ctx, s.client.From(tableName).
+		Select("id", "", false).
+		Limit(1, ""))
+	if err != nil {
+		return fmt.Errorf("querying %s: %w", tableName, err)
+	}
+	return nil
 }
 
 // supabaseRow is the internal representation for Supabase PostgREST insert/update.
 type supabaseRow struct {
-	ID             string         `json:"id,omitempty"`
-	IdempotencyKey *string        `json:"idempotency_key,omitempty"`
-	Channel        string         `json:"channel"`
-	Type           string         `json:"type"`
-	Recipient      string         `json:"recipient"`
-	TemplateData   map[string]any `json:"template_data,omitempty"`
-	ProviderID     *string        `json:"provider_id,omitempty"`
-	Status         string         `json:"status"`
-	ErrorMessage   *string        `json:"error_message,omitempty"`
-	CreatedAt      string         `json:"created_at,omitempty"`
-	UpdatedAt      string         `json:"updated_at,omitempty"`
-	SentAt         *string        `json:"sent_at,omitempty"`
-	DeliveredAt    *string        `json:"delivered_at,omitempty"`
-	OpenedAt       *string        `json:"opened_at,omitempty"`
-	BouncedAt      *string        `json:"bounced_at,omitempty"`
+	ID               string            `json:"id,omitempty"`
+	IdempotencyKey   *string           `json:"idempotency_key,omitempty"`
+	PayloadHash      *string           `json:"payload_hash,omitempty"`
+	CampaignID       *string           `json:"campaign_id,omitempty"`
+	UserID           *string           `json:"user_id,omitempty"`
+	ParentID         *string           `json:"parent_id,omitempty"`
+	FallbackOf       *string           `json:"fallback_of,omitempty"`
+	EscalationOf     *string           `json:"escalation_of,omitempty"`
+	FollowUpOf       *string           `json:"follow_up_of,omitempty"`
+	Channel          string            `json:"channel"`
+	Type             string            `json:"type"`
+	Variant          *string           `json:"variant,omitempty"`
+	Recipient        string            `json:"recipient"`
+	Recipients       []string          `json:"recipients,omitempty"`
+	CC               []string          `json:"cc,omitempty"`
+	BCC              []string          `json:"bcc,omitempty"`
+	ReplyTo          *string           `json:"reply_to,omitempty"`
+	Sender           *string           `json:"sender,omitempty"`
+	Headers          map[string]string `json:"headers,omitempty"`
+	Tags             map[string]string `json:"tags,omitempty"`
+	TemplateData     map[string]any    `json:"template_data,omitempty"`
+	ProviderID       *string           `json:"provider_id,omitempty"`
+	Status           string            `json:"status"`
+	ErrorMessage     *string           `json:"error_message,omitempty"`
+	FailureCode      *string           `json:"failure_code,omitempty"`
+	Retryable        *bool             `json:"retryable,omitempty"`
+	RecoveryAttempts int               `json:"recovery_attempts,omitempty"`
+	BounceType       *string           `json:"bounce_type,omitempty"`
+	BounceRetries    int               `json:"bounce_retries,omitempty"`
+	APIKeyID         *string           `json:"api_key_id,omitempty"`
+	Cost             *float64          `json:"cost,omitempty"`
+	DirectSend       bool              `json:"direct_send,omitempty"`
+	CreatedAt        string            `json:"created_at,omitempty"`
+	UpdatedAt        string            `json:"updated_at,omitempty"`
+	SentAt           *string           `json:"sent_at,omitempty"`
+	DeliveredAt      *string           `json:"delivered_at,omitempty"`
+	OpenedAt         *string           `json:"opened_at,omitempty"`
+	ClickedAt        *string           `json:"clicked_at,omitempty"`
+	BouncedAt        *string           `json:"bounced_at,omitempty"`
+	ComplainedAt     *string           `json:"complained_at,omitempty"`
+	AcknowledgedAt   *string           `json:"acknowledged_at,omitempty"`
+	ArchivedAt       *string           `json:"archived_at,omitempty"`
+
+	Content          *notification.RenderedContent  `json:"content,omitempty"`
+	Fallback         *notification.Fallback         `json:"fallback,omitempty"`
+	Escalation       *notification.Escalation       `json:"escalation,omitempty"`
+	ProviderMetadata *notification.ProviderMetadata `json:"provider_metadata,omitempty"`
 }
 
 // Create inserts a new notification log record.
 func (s *SupabaseStore) Create(ctx context.Context, log *notification.NotificationLog) error {
 	row := supabaseRow{
-		Channel:   log.Channel,
-		Type:      log.Type,
-		Recipient: log.Recipient,
-		Status:    string(log.Status),
+		Channel:    log.Channel,
+		Type:       log.Type,
+		Recipient:  log.Recipient,
+		Recipients: log.Recipients,
+		CC:         log.CC,
+		BCC:        log.BCC,
+		Headers:    log.Headers,
+		Tags:       log.Tags,
+		Status:     string(log.Status),
 	}
 
 	if log.IdempotencyKey != "" {
 		row.IdempotencyKey = &log.IdempotencyKey
 	}
+	if log.PayloadHash != "" {
+		row.PayloadHash = &log.PayloadHash
+	}
+	if log.CampaignID != "" {
+		row.CampaignID = &log.CampaignID
+	}
+	if log.UserID != "" {
+		row.UserID = &log.UserID
+	}
+	if log.ParentID != "" {
+		row.ParentID = &log.ParentID
+	}
+	if log.APIKeyID != "" {
+		row.APIKeyID = &log.APIKeyID
+	}
+	if log.FallbackOf != "" {
+		row.FallbackOf = &log.FallbackOf
+	}
+	if log.EscalationOf != "" {
+		row.EscalationOf = &log.EscalationOf
+	}
+	if log.FollowUpOf != "" {
+		row.FollowUpOf = &log.FollowUpOf
+	}
+	if log.ReplyTo != "" {
+		row.ReplyTo = &log.ReplyTo
+	}
+	if log.Variant != "" {
+		row.Variant = &log.Variant
+	}
+	if log.Sender != "" {
+		row.Sender = &log.Sender
+	}
 
-	if log.TemplateData != nil {
-		row.TemplateData = log.TemplateData
+	templateData, err := encodeTemplateData(log.TemplateData, s.compressData)
+	if err != nil {
+		return err
 	}
+	row.TemplateData = templateData
+	row.Content = log.Content
+	row.Fallback = log.Fallback
+	row.Escalation = log.Escalation
 
 	// Insert and get the created row back
 	var results []supabaseRow
-	data, _, err := s.client.From(tableName).Insert(row, false, "", "representation", "").Execute()
+	data, _, err := s.calls.executeOnce(ctx, s.client.From(tableName).Insert(row, false, "", "representation", ""))
 	if err != nil {
 		return fmt.Errorf("inserting notification log: %w", err)
 	}
@@ -78,6 +216,7 @@ func (s *SupabaseStore) Create(ctx context.Context, log *notification.Notificati
 	}
 
 	if len(results) > 0 {
+		s.cache.store(ctx, &results[0])
 		log.ID = results[0].ID
 		if results[0].CreatedAt != "" {
 			if t, err := time.Parse(time.RFC3339Nano, results[0].CreatedAt); err == nil {
@@ -96,7 +235,11 @@ func (s *SupabaseStore) Create(ctx context.Context, log *notification.Notificati
 
 // GetByID retrieves a notification log by its ID.
 func (s *SupabaseStore) GetByID(ctx context.Context, id string) (*notification.NotificationLog, error) {
-	data, _, err := s.client.From(tableName).Select("*", "exact", false).Eq("id", id).Single().Execute()
+	if row, ok := s.cache.row(ctx, id); ok {
+		return rowToLog(row), nil
+	}
+
+	data, _, err := s.calls.execute(ctx, s.client.From(tableName).Select("*", "exact", false).Eq("id", id).Single())
 	if err != nil {
 		return nil, fmt.Errorf("fetching notification log: %w", err)
 	}
@@ -105,6 +248,7 @@ func (s *SupabaseStore) GetByID(ctx context.Context, id string) (*notification.N
 	if err := json.Unmarshal(data, &row); err != nil {
 		return nil, fmt.Errorf("parsing notification log: %w", err)
 	}
+	s.cache.store(ctx, &row)
 
 	return rowToLog(&row), nil
 }
@@ -112,7 +256,15 @@ func (s *SupabaseStore) GetByID(ctx context.Context, id string) (*notification.N
 // GetByIdempotencyKey retrieves a notification log by its idempotency key.
 // Returns nil, nil if no record is found.
 func (s *SupabaseStore) GetByIdempotencyKey(ctx context.Context, key string) (*notification.NotificationLog, error) {
-	data, _, err := s.client.From(tableName).Select("*", "exact", false).Eq("idempotency_key", key).Execute()
+	// The key's log is read by ID, cached or not; erasure clears keys, so the
+	// row must still carry it
+	if id, ok := s.cache.keyID(ctx, key); ok {
+		if row, ok := s.cache.row(ctx, id); ok && row.IdempotencyKey != nil && *row.IdempotencyKey == key {
+			return rowToLog(row), nil
+		}
+	}
+
+	data, _, err := s.calls.execute(ctx, s.client.From(tableName).Select("*", "exact", false).Eq("idempotency_key", key))
 	if err != nil {
 		return nil, fmt.Errorf("fetching by idempotency key: %w", err)
 	}
@@ -125,12 +277,23 @@ func (s *SupabaseStore) GetByIdempotencyKey(ctx context.Context, key string) (*n
 	if len(rows) == 0 {
 		return nil, nil
 	}
+	s.cache.store(ctx, &rows[0])
 
 	return rowToLog(&rows[0]), nil
 }
 
-// UpdateStatus updates the status of a notification log.
+// sendable and unsent are the statuses a log can still move to processing or
+// failed from, and to sent from; a log past them keeps its status.
+var (
+	sendable = []string{string(notification.StatusQueued), string(notification.StatusProcessing), string(notification.StatusFailed)}
+	unsent   = []string{string(notification.StatusQueued), string(notification.StatusProcessing)}
+)
+
+// UpdateStatus updates the status of a notification log. A move to processing
+// applies only to a log in a sendable status.
 func (s *SupabaseStore) UpdateStatus(ctx context.Context, id string, status notification.NotificationStatus, providerID string, errMsg string) error {
+	defer s.cache.invalidate(ctx, id)
+
 	now := time.Now().UTC().Format(time.RFC3339Nano)
 
 	update := map[string]any{
@@ -149,20 +312,95 @@ func (s *SupabaseStore) UpdateStatus(ctx context.Context, id string, status noti
 	switch status {
 	case notification.StatusSent:
 		update["sent_at"] = now
+	case notification.StatusClicked:
+		update["clicked_at"] = now
 	case notification.StatusFailed:
 		// no extra timestamp
 	}
 
-	_, _, err := s.client.From(tableName).Update(update, "", "").Eq("id", id).Execute()
-	if err != nil {
+	query := s.client.From(tableName).Update(update, "", "").Eq("id", id)
+	if status == notification.StatusProcessing {
+		query = query.In("status", sendable)
+	}
+	if _, _, err := s.calls.execute(ctx, query); err != nil {
 		return fmt.Errorf("updating notification status: %w", err)
 	}
 
 	return nil
 }
 
-// UpdateWebhookStatus updates the status of a notification based on provider ID.
-func (s *SupabaseStore) UpdateWebhookStatus(ctx context.Context, providerID string, status notification.NotificationStatus) error {
+// RecordSent marks a log sent and stores the provider's message ID, the
+// estimated cost, and response metadata. Only a queued or processing log is
+// updated.
+func (s *SupabaseStore) RecordSent(ctx context.Context, id string, providerID string, cost *float64, metadata *notification.ProviderMetadata) error {
+	defer s.cache.invalidate(ctx, id)
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	update := map[string]any{
+		"status":     string(notification.StatusSent),
+		"sent_at":    now,
+		"updated_at": now,
+	}
+	if providerID != "" {
+		update["provider_id"] = providerID
+	}
+	if cost != nil {
+		update["cost"] = *cost
+	}
+	if metadata != nil {
+		update["provider_metadata"] = metadata
+	}
+
+	if _, _, err := s.calls.execute(ctx, s.client.From(tableName).Update(update, "", "").Eq("id", id).In("status", unsent)); err != nil {
+		return fmt.Errorf("recording sent notification: %w", err)
+	}
+	return nil
+}
+
+// RecordFailure marks a log failed and records its failure code, whether the
+// failure is retryable, and the provider's response metadata. A log already
+// sent is left as is.
+func (s *SupabaseStore) RecordFailure(ctx context.Context, id string, errMsg string, code notification.FailureCode, retryable bool, metadata *notification.ProviderMetadata) error {
+	defer s.cache.invalidate(ctx, id)
+
+	update := map[string]any{
+		"status":        string(notification.StatusFailed),
+		"error_message": errMsg,
+		"failure_code":  nil,
+		"retryable":     retryable,
+		"updated_at":    time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	if code != "" {
+		update["failure_code"] = string(code)
+	}
+	if metadata != nil {
+		update["provider_metadata"] = metadata
+	}
+
+	if _, _, err := s.calls.execute(ctx, s.client.From(tableName).Update(update, "", "").Eq("id", id).In("status", sendable)); err != nil {
+		return fmt.Errorf("recording failure: %w", err)
+	}
+	return nil
+}
+
+// Acknowledge sets acknowledged_at, unless the log was already acknowledged.
+func (s *SupabaseStore) Acknowledge(ctx context.Context, id string, at time.Time) error {
+	defer s.cache.invalidate(ctx, id)
+
+	update := map[string]any{
+		"acknowledged_at": at.UTC().Format(time.RFC3339Nano),
+		"updated_at":      time.Now().UTC().Format(time.RFC3339Nano),
+	}
+
+	if _, _, err := s.calls.execute(ctx, s.client.From(tableName).Update(update, "", "").Eq("id", id).Is("acknowledged_at", "null")); err != nil {
+		return fmt.Errorf("acknowledging notification: %w", err)
+	}
+	return nil
+}
+
+// UpdateWebhookStatus updates the status of a notification based on provider
+// ID and returns the updated logs. bounceType is stored with a bounce.
+func (s *SupabaseStore) UpdateWebhookStatus(ctx context.Context, providerID string, status notification.NotificationStatus, bounceType notification.BounceType) ([]*notification.NotificationLog, error) {
 	now := time.Now().UTC().Format(time.RFC3339Nano)
 
 	update := map[string]any{
@@ -175,16 +413,34 @@ func (s *SupabaseStore) UpdateWebhookStatus(ctx context.Context, providerID stri
 		update["delivered_at"] = now
 	case notification.StatusBounced:
 		update["bounced_at"] = now
+		if bounceType != "" {
+			update["bounce_type"] = string(bounceType)
+		}
+	case notification.StatusComplained:
+		update["complained_at"] = now
 	case notification.StatusOpened:
 		update["opened_at"] = now
+	case notification.StatusClicked:
+		update["clicked_at"] = now
 	}
 
-	_, _, err := s.client.From(tableName).Update(update, "", "").Eq("provider_id", providerID).Execute()
+	data, _, err := s.calls.execute(ctx, s.client.From(tableName).Update(update, "representation", "").Eq("provider_id", providerID))
 	if err != nil {
-		return fmt.Errorf("updating webhook status: %w", err)
+		return nil, fmt.Errorf("updating webhook status: %w", err)
 	}
 
-	return nil
+	var rows []supabaseRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("parsing update response: %w", err)
+	}
+	logs := make([]*notification.NotificationLog, len(rows))
+	ids := make([]string, len(rows))
+	for i, row := range rows {
+		logs[i] = rowToLog(&row)
+		ids[i] = row.ID
+	}
+	s.cache.invalidate(ctx, ids...)
+	return logs, nil
 }
 
 // List retrieves notification logs with pagination and filtering.
@@ -199,7 +455,18 @@ func (s *SupabaseStore) List(ctx context.Context, filter notification.ListFilter
 
 	offset := (filter.Page - 1) * filter.PageSize
 
-	query := s.client.From(tableName).Select("*", "exact", false)
+	count := filter.Count
+	switch count {
+	case "":
+		count = notification.CountExact
+	case notification.CountNone:
+		count = "" // PostgREST counts nothing without a count preference
+	}
+	client := s.reads()
+	if filter.Primary {
+		client = s.client
+	}
+	query := client.From(tableName).Select("*", count, false)
 
 	// Apply filters
 	if filter.Status != "" {
@@ -211,15 +478,40 @@ func (s *SupabaseStore) List(ctx context.Context, filter notification.ListFilter
 	if filter.Channel != "" {
 		query = query.Eq("channel", filter.Channel)
 	}
+	if filter.CampaignID != "" {
+		query = query.Eq("campaign_id", filter.CampaignID)
+	}
+	if filter.ParentID != "" {
+		query = query.Eq("parent_id", filter.ParentID)
+	}
+	if filter.EscalationOf != "" {
+		query = query.Eq("escalation_of", filter.EscalationOf)
+	}
+	if filter.FollowUpOf != "" {
+		query = query.Eq("follow_up_of", filter.FollowUpOf)
+	}
+	if filter.FailureCode != "" {
+		query = query.Eq("failure_code", filter.FailureCode)
+	}
+	switch filter.Archived {
+	case notification.ArchivedInclude:
+	case notification.ArchivedOnly:
+		query = query.Not("archived_at", "is", "null")
+	default:
+		query = query.Is("archived_at", "null")
+	}
 
 	// Order by created_at desc, paginate
 	query = query.Order("created_at", &postgrest.OrderOpts{Ascending: false})
 	query = query.Range(offset, offset+filter.PageSize-1, "")
 
-	data, count, err := query.Execute()
+	data, total, err := s.calls.execute(ctx, query)
 	if err != nil {
 		return nil, 0, fmt.Errorf("listing notification logs: %w", err)
 	}
+	if count == "" {
+		total = -1
+	}
 
 	var rows []supabaseRow
 	if err := json.Unmarshal(data, &rows); err != nil {
@@ -231,7 +523,287 @@ func (s *SupabaseStore) List(ctx context.Context, filter notification.ListFilter
 		logs[i] = rowToLog(&row)
 	}
 
-	return logs, int(count), nil
+	return logs, int(total), nil
+}
+
+// HasBounced reports whether any notification to recipient has hard-bounced
+// or drawn a spam complaint. A bounce without a type, recorded before bounces
+// were classified, counts as hard.
+func (s *SupabaseStore) HasBounced(ctx context.Context, recipient string) (bool, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(tableName).
+		Select("id", "", false).
+		Eq("recipient", recipient).
+		Or("status.eq.complained,and(status.eq.bounced,or(bounce_type.is.null,bounce_type.neq.soft))", "").
+		Limit(1, ""))
+	if err != nil {
+		return false, fmt.Errorf("checking bounces: %w", err)
+	}
+
+	var rows []struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return false, fmt.Errorf("parsing bounce check: %w", err)
+	}
+	return len(rows) > 0, nil
+}
+
+// RecordRecovery resets a stale log to queued and stores its recovery attempt count.
+func (s *SupabaseStore) RecordRecovery(ctx context.Context, id string, attempts int) error {
+	defer s.cache.invalidate(ctx, id)
+
+	update := map[string]any{
+		"status":            string(notification.StatusQueued),
+		"recovery_attempts": attempts,
+		"updated_at":        time.Now().UTC().Format(time.RFC3339Nano),
+	}
+
+	if _, _, err := s.calls.execute(ctx, s.client.From(tableName).Update(update, "", "").Eq("id", id)); err != nil {
+		return fmt.Errorf("recording recovery: %w", err)
+	}
+	return nil
+}
+
+// RecordDirectSend flags a log as sent directly, bypassing the queue.
+func (s *SupabaseStore) RecordDirectSend(ctx context.Context, id string) error {
+	defer s.cache.invalidate(ctx, id)
+
+	update := map[string]any{
+		"direct_send": true,
+		"updated_at":  time.Now().UTC().Format(time.RFC3339Nano),
+	}
+
+	if _, _, err := s.calls.execute(ctx, s.client.From(tableName).Update(update, "", "").Eq("id", id)); err != nil {
+		return fmt.Errorf("recording direct send: %w", err)
+	}
+	return nil
+}
+
+// RecordBounceRetry resets a soft-bounced log to queued for its retries-th
+// resend, clearing the bounce and the provider ID of the bounced send.
+func (s *SupabaseStore) RecordBounceRetry(ctx context.Context, id string, retries int) error {
+	defer s.cache.invalidate(ctx, id)
+
+	update := map[string]any{
+		"status":         string(notification.StatusQueued),
+		"bounce_retries": retries,
+		"bounce_type":    nil,
+		"bounced_at":     nil,
+		"provider_id":    nil,
+		"updated_at":     time.Now().UTC().Format(time.RFC3339Nano),
+	}
+
+	if _, _, err := s.calls.execute(ctx, s.client.From(tableName).Update(update, "", "").Eq("id", id)); err != nil {
+		return fmt.Errorf("recording bounce retry: %w", err)
+	}
+	return nil
+}
+
+// ListFailed retrieves up to limit unarchived failed logs matching filter,
+// oldest first.
+func (s *SupabaseStore) ListFailed(ctx context.Context, filter notification.FailedFilter, limit int) ([]*notification.NotificationLog, error) {
+	query := s.client.From(tableName).
+		Select("*", "", false).
+		Eq("status", string(notification.StatusFailed)).
+		Is("archived_at", "null")
+
+	if filter.Type != "" {
+		query = query.Eq("type", filter.Type)
+	}
+	if filter.Channel != "" {
+		query = query.Eq("channel", filter.Channel)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Gte("created_at", filter.CreatedAfter.UTC().Format(time.RFC3339Nano))
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Lt("created_at", filter.CreatedBefore.UTC().Format(time.RFC3339Nano))
+	}
+	if filter.ErrorContains != "" {
+		// PostgREST accepts * as the LIKE wildcard
+		query = query.Ilike("error_message", "*"+strings.ReplaceAll(filter.ErrorContains, "*", "")+"*")
+	}
+
+	data, _, err := s.calls.execute(ctx, query.
+		Order("created_at", &postgrest.OrderOpts{Ascending: true}).
+		Range(0, limit-1, ""))
+	if err != nil {
+		return nil, fmt.Errorf("listing failed notifications: %w", err)
+	}
+
+	var rows []supabaseRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("parsing failed notifications: %w", err)
+	}
+
+	logs := make([]*notification.NotificationLog, len(rows))
+	for i, row := range rows {
+		logs[i] = rowToLog(&row)
+	}
+	return logs, nil
+}
+
+// Requeue resets the given logs to queued and clears their error in one update.
+func (s *SupabaseStore) Requeue(ctx context.Context, ids []string) error {
+	defer s.cache.invalidate(ctx, ids...)
+
+	update := map[string]any{
+		"status":        string(notification.StatusQueued),
+		"error_message": nil,
+		"failure_code":  nil,
+		"retryable":     nil,
+		"updated_at":    time.Now().UTC().Format(time.RFC3339Nano),
+	}
+
+	if _, _, err := s.calls.execute(ctx, s.client.From(tableName).Update(update, "", "").In("id", ids)); err != nil {
+		return fmt.Errorf("requeuing notifications: %w", err)
+	}
+	return nil
+}
+
+// statusColumns are the columns GetStatuses fetches.
+const statusColumns = "id,idempotency_key,channel,status,error_message,failure_code,updated_at"
+
+// GetStatuses retrieves the logs with any of the given IDs or idempotency keys
+// in one query, fetching only statusColumns.
+func (s *SupabaseStore) GetStatuses(ctx context.Context, ids, keys []string) ([]*notification.NotificationLog, error) {
+	var matches []string
+	if len(ids) > 0 {
+		matches = append(matches, "id.in.("+quoteList(ids)+")")
+	}
+	if len(keys) > 0 {
+		matches = append(matches, "idempotency_key.in.("+quoteList(keys)+")")
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	data, _, err := s.calls.execute(ctx, s.client.From(tableName).
+		Select(statusColumns, "", false).
+		Or(strings.Join(matches, ","), ""))
+	if err != nil {
+		return nil, fmt.Errorf("fetching notification statuses: %w", err)
+	}
+
+	var rows []supabaseRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("parsing notification statuses: %w", err)
+	}
+	logs := make([]*notification.NotificationLog, len(rows))
+	for i, row := range rows {
+		logs[i] = rowToLog(&row)
+	}
+	return logs, nil
+}
+
+// quoteList double-quotes each value for a PostgREST in.(...) list, so commas
+// and parentheses in values are taken literally.
+func quoteList(values []string) string {
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = `"` + escape.Replace(v) + `"`
+	}
+	return strings.Join(quoted, ",")
+}
+
+// CountByStatus returns the number of logs in each status. It issues one
+// head-only count query per status, which the status index keeps cheap.
+func (s *SupabaseStore) CountByStatus(ctx context.Context) (map[notification.NotificationStatus]int, error) {
+	counts := make(map[notification.NotificationStatus]int)
+	for _, status := range notification.Statuses() {
+		_, count, err := s.calls.execute(ctx, s.reads().From(tableName).
+			Select("id", "exact", true).
+			Eq("status", string(status)))
+		if err != nil {
+			return nil, fmt.Errorf("counting %s notifications: %w", status, err)
+		}
+		counts[status] = int(count)
+	}
+	return counts, nil
+}
+
+// CountByFailureCode returns the number of failed logs with each failure
+// code, one head-only count query per code. A log whose retry succeeded keeps
+// its code but is no longer counted.
+func (s *SupabaseStore) CountByFailureCode(ctx context.Context) (map[notification.FailureCode]int, error) {
+	counts := make(map[notification.FailureCode]int)
+	for _, code := range notification.FailureCodes() {
+		_, count, err := s.calls.execute(ctx, s.reads().From(tableName).
+			Select("id", "exact", true).
+			Eq("status", string(notification.StatusFailed)).
+			Eq("failure_code", string(code)))
+		if err != nil {
+			return nil, fmt.Errorf("counting %s failures: %w", code, err)
+		}
+		counts[code] = int(count)
+	}
+	return counts, nil
+}
+
+// CountVariant counts the sent, opened, and clicked logs of a template
+// variant, one head-only count query each. A clicked log counts as opened.
+func (s *SupabaseStore) CountVariant(ctx context.Context, notifType notification.NotificationType, variant string) (*notification.VariantStats, error) {
+	logs := func() *postgrest.FilterBuilder {
+		return s.reads().From(tableName).
+			Select("id", "exact", true).
+			Eq("type", string(notifType)).
+			Eq("variant", variant)
+	}
+
+	stats := &notification.VariantStats{Type: notifType, Variant: variant}
+	for _, c := range []struct {
+		into  *int
+		query *postgrest.FilterBuilder
+	}{
+		{&stats.Sent, logs().Not("sent_at", "is", "null")},
+		{&stats.Opened, logs().Or("opened_at.not.is.null,clicked_at.not.is.null", "")},
+		{&stats.Clicked, logs().Not("clicked_at", "is", "null")},
+	} {
+		_, count, err := s.calls.execute(ctx, c.query)
+		if err != nil {
+			return nil, fmt.Errorf("counting %s variant %s: %w", notifType, variant, err)
+		}
+		*c.into = int(count)
+	}
+	return stats, nil
+}
+
+// CountCreatedByType returns the number of logs of each type created in
+// [since, until), one head-only count query per type.
+func (s *SupabaseStore) CountCreatedByType(ctx context.Context, since, until time.Time) (map[notification.NotificationType]int, error) {
+	counts := make(map[notification.NotificationType]int)
+	for _, notifType := range notification.Types() {
+		_, count, err := s.calls.execute(ctx, s.reads().From(tableName).
+			Select("id", "exact", true).
+			Eq("type", string(notifType)).
+			Gte("created_at", since.UTC().Format(time.RFC3339Nano)).
+			Lt("created_at", until.UTC().Format(time.RFC3339Nano)))
+		if err != nil {
+			return nil, fmt.Errorf("counting %s notifications: %w", notifType, err)
+		}
+		counts[notifType] = int(count)
+	}
+	return counts, nil
+}
+
+// CountFailuresByCode returns the number of failed logs created in [since,
+// until) with each failure code, one head-only count query per code.
+func (s *SupabaseStore) CountFailuresByCode(ctx context.Context, since, until time.Time) (map[notification.FailureCode]int, error) {
+	counts := make(map[notification.FailureCode]int)
+	for _, code := range notification.FailureCodes() {
+		_, count, err := s.calls.execute(ctx, s.reads().From(tableName).
+			Select("id", "exact", true).
+			Eq("status", string(notification.StatusFailed)).
+			Eq("failure_code", string(code)).
+			Gte("created_at", since.UTC().Format(time.RFC3339Nano)).
+			Lt("created_at", until.UTC().Format(time.RFC3339Nano)))
+		if err != nil {
+			return nil, fmt.Errorf("counting %s failures: %w", code, err)
+		}
+		counts[code] = int(count)
+	}
+	return counts, nil
 }
 
 // ListStale retrieves notification logs stuck in queued/processing for longer than olderThan.
@@ -242,15 +814,17 @@ func (s *SupabaseStore) ListStale(ctx context.Context, olderThan time.Time, limi
 
 	threshold := olderThan.UTC().Format(time.RFC3339Nano)
 
-	// Query for records with status in (queued, processing) AND updated_at < threshold
+	// Query for records with status in (queued, processing) AND updated_at < threshold,
+	// leaving out user fan-out parents: they are never sent, only settled by their children
 	query := s.client.From(tableName).
 		Select("*", "exact", false).
 		In("status", []string{string(notification.StatusQueued), string(notification.StatusProcessing)}).
 		Lt("updated_at", threshold).
+		Or("user_id.is.null,parent_id.not.is.null", "").
 		Order("updated_at", &postgrest.OrderOpts{Ascending: true}).
 		Range(0, limit-1, "")
 
-	data, _, err := query.Execute()
+	data, _, err := s.calls.execute(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("listing stale notifications: %w", err)
 	}
@@ -271,18 +845,71 @@ func (s *SupabaseStore) ListStale(ctx context.Context, olderThan time.Time, limi
 // rowToLog converts a supabaseRow to a NotificationLog.
 func rowToLog(row *supabaseRow) *notification.NotificationLog {
 	log := &notification.NotificationLog{
-		ID:        row.ID,
-		Channel:   row.Channel,
-		Type:      row.Type,
-		Recipient: row.Recipient,
-		Status:    notification.NotificationStatus(row.Status),
+		ID:         row.ID,
+		Channel:    row.Channel,
+		Type:       row.Type,
+		Recipient:  row.Recipient,
+		Recipients: row.Recipients,
+		CC:         row.CC,
+		BCC:        row.BCC,
+		Headers:    row.Headers,
+		Tags:       row.Tags,
+		Status:     notification.NotificationStatus(row.Status),
+		Retryable:  row.Retryable,
+
+		RecoveryAttempts: row.RecoveryAttempts,
+		BounceRetries:    row.BounceRetries,
+		Cost:             row.Cost,
+		DirectSend:       row.DirectSend,
+	}
+
+	if row.APIKeyID != nil {
+		log.APIKeyID = *row.APIKeyID
+	}
+
+	if row.BounceType != nil {
+		log.BounceType = notification.BounceType(*row.BounceType)
 	}
 
 	if row.IdempotencyKey != nil {
 		log.IdempotencyKey = *row.IdempotencyKey
 	}
+	if row.PayloadHash != nil {
+		log.PayloadHash = *row.PayloadHash
+	}
+	if row.CampaignID != nil {
+		log.CampaignID = *row.CampaignID
+	}
+	if row.UserID != nil {
+		log.UserID = *row.UserID
+	}
+	if row.ParentID != nil {
+		log.ParentID = *row.ParentID
+	}
+	if row.FallbackOf != nil {
+		log.FallbackOf = *row.FallbackOf
+	}
+	if row.EscalationOf != nil {
+		log.EscalationOf = *row.EscalationOf
+	}
+	if row.FollowUpOf != nil {
+		log.FollowUpOf = *row.FollowUpOf
+	}
+	if row.ReplyTo != nil {
+		log.ReplyTo = *row.ReplyTo
+	}
+	if row.Variant != nil {
+		log.Variant = *row.Variant
+	}
+	if row.Sender != nil {
+		log.Sender = *row.Sender
+	}
+	log.Content = row.Content
+	log.Fallback = row.Fallback
+	log.Escalation = row.Escalation
+	log.ProviderMetadata = row.ProviderMetadata
 	if row.TemplateData != nil {
-		log.TemplateData = row.TemplateData
+		log.TemplateData = decodeTemplateData(row.ID, row.TemplateData)
 	}
 	if row.ProviderID != nil {
 		log.ProviderID = *row.ProviderID
@@ -290,6 +917,9 @@ func rowToLog(row *supabaseRow) *notification.NotificationLog {
 	if row.ErrorMessage != nil {
 		log.ErrorMessage = *row.ErrorMessage
 	}
+	if row.FailureCode != nil {
+		log.FailureCode = notification.FailureCode(*row.FailureCode)
+	}
 
 	if row.CreatedAt != "" {
 		if t, err := time.Parse(time.RFC3339Nano, row.CreatedAt); err == nil {
@@ -316,11 +946,31 @@ func rowToLog(row *supabaseRow) *notification.NotificationLog {
 			log.OpenedAt = &t
 		}
 	}
+	if row.ClickedAt != nil {
+		if t, err := time.Parse(time.RFC3339Nano, *row.ClickedAt); err == nil {
+			log.ClickedAt = &t
+		}
+	}
 	if row.BouncedAt != nil {
 		if t, err := time.Parse(time.RFC3339Nano, *row.BouncedAt); err == nil {
 			log.BouncedAt = &t
 		}
 	}
+	if row.ComplainedAt != nil {
+		if t, err := time.Parse(time.RFC3339Nano, *row.ComplainedAt); err == nil {
+			log.ComplainedAt = &t
+		}
+	}
+	if row.AcknowledgedAt != nil {
+		if t, err := time.Parse(time.RFC3339Nano, *row.AcknowledgedAt); err == nil {
+			log.AcknowledgedAt = &t
+		}
+	}
+	if row.ArchivedAt != nil {
+		if t, err := time.Parse(time.RFC3339Nano, *row.ArchivedAt); err == nil {
+			log.ArchivedAt = &t
+		}
+	}
 
 	return log
 }
@@ -0,0 +1,28 @@
+package store
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+	"github.com/badrkarrachai/notifly/pkg/notification/storetest"
+)
+
+// TestSupabaseStore runs the store conformance suite against the Supabase
+// project in NOTIFLY_SUPABASE_URL, which must be one used only for tests and
+// have every migration applied. It is skipped when the project is not set.
+func TestSupabaseStore(t *testing.T) {
+	url, key := os.Getenv("NOTIFLY_SUPABASE_URL"), os.Getenv("NOTIFLY_SUPABASE_SERVICE_KEY")
+	if url == "" || key == "" {
+		t.Skip("NOTIFLY_SUPABASE_URL and NOTIFLY_SUPABASE_SERVICE_KEY are not set")
+	}
+
+	storetest.Run(t, func(t *testing.T) notification.NotificationStore {
+		s, err := NewSupabaseStore(url, key, CallConfig{Timeout: 10 * time.Second, MaxRetries: 2, Backoff: 200 * time.Millisecond})
+		if err != nil {
+			t.Fatalf("NewSupabaseStore: %v", err)
+		}
+		return s
+	})
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+
+	"github.com/supabase-community/postgrest-go"
+)
+
+var _ notification.UsageStore = (*SupabaseStore)(nil)
+
+// CountUsage counts the logs apiKeyID sent that were created in [from, to),
+// and of those the sent, delivered, and bounced ones, one head-only count
+// query each on the (api_key_id, created_at) index.
+func (s *SupabaseStore) CountUsage(ctx context.Context, apiKeyID string, from, to time.Time) (*notification.UsageCounts, error) {
+	logs := func() *postgrest.FilterBuilder {
+		return s.reads().From(tableName).
+			Select("id", "exact", true).
+			Eq("api_key_id", apiKeyID).
+			Gte("created_at", from.UTC().Format(time.RFC3339Nano)).
+			Lt("created_at", to.UTC().Format(time.RFC3339Nano))
+	}
+
+	counts := &notification.UsageCounts{}
+	for _, c := range []struct {
+		into  *int
+		query *postgrest.FilterBuilder
+	}{
+		{&counts.Accepted, logs()},
+		{&counts.Sent, logs().Not("sent_at", "is", "null")},
+		{&counts.Delivered, logs().Not("delivered_at", "is", "null")},
+		{&counts.Bounced, logs().Not("bounced_at", "is", "null")},
+	} {
+		_, count, err := s.calls.execute(ctx, c.query)
+		if err != nil {
+			return nil, fmt.Errorf("counting usage of %s: %w", apiKeyID, err)
+		}
+		*c.into = int(count)
+	}
+	return counts, nil
+}
@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+
+	"github.com/supabase-community/postgrest-go"
+)
+
+const webhookEventsTable = "webhook_events"
+
+var _ notification.WebhookEventStore = (*SupabaseStore)(nil)
+
+// webhookEventRow is the PostgREST representation of a webhook_events row.
+type webhookEventRow struct {
+	ID          string          `json:"id,omitempty"`
+	Provider    string          `json:"provider"`
+	EventID     *string         `json:"event_id"`
+	EventType   string          `json:"event_type"`
+	ProviderID  *string         `json:"provider_id"`
+	Status      *string         `json:"status"`
+	BounceType  *string         `json:"bounce_type"`
+	Payload     json.RawMessage `json:"payload"`
+	Result      string          `json:"result"`
+	Error       *string         `json:"error"`
+	ReceivedAt  string          `json:"received_at,omitempty"`
+	ProcessedAt *string         `json:"processed_at"`
+}
+
+// CreateWebhookEvent inserts event and fills in its ID and ReceivedAt.
+func (s *SupabaseStore) CreateWebhookEvent(ctx context.Context, event *notification.WebhookEvent) error {
+	row := webhookEventRow{
+		Provider:  event.Provider,
+		EventType: event.EventType,
+		Payload:   event.Payload,
+		Result:    string(event.Result),
+	}
+	if event.EventID != "" {
+		row.EventID = &event.EventID
+	}
+	if event.ProviderID != "" {
+		row.ProviderID = &event.ProviderID
+	}
+	if event.Status != "" {
+		status := string(event.Status)
+		row.Status = &status
+	}
+	if event.BounceType != "" {
+		bounceType := string(event.BounceType)
+		row.BounceType = &bounceType
+	}
+
+	data, _, err := s.calls.executeOnce(ctx, s.client.From(webhookEventsTable).Insert(row, false, "", "representation", ""))
+	if err != nil {
+		return fmt.Errorf("inserting webhook event: %w", err)
+	}
+
+	var rows []webhookEventRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return fmt.Errorf("parsing insert response: %w", err)
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("inserting webhook event: no row returned")
+	}
+	created := rowToWebhookEvent(&rows[0])
+	event.ID = created.ID
+	event.ReceivedAt = created.ReceivedAt
+	return nil
+}
+
+// UpdateWebhookEvent stores event's result, error, and processing time.
+func (s *SupabaseStore) UpdateWebhookEvent(ctx context.Context, event *notification.WebhookEvent) error {
+	update := map[string]any{
+		"result": string(event.Result),
+		"error":  nil,
+	}
+	if event.Error != "" {
+		update["error"] = event.Error
+	}
+	if event.ProcessedAt != nil {
+		update["processed_at"] = event.ProcessedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	if _, _, err := s.calls.execute(ctx, s.client.From(webhookEventsTable).Update(update, "", "").Eq("id", event.ID)); err != nil {
+		return fmt.Errorf("updating webhook event: %w", err)
+	}
+	return nil
+}
+
+// GetWebhookEvent retrieves an event by ID. Returns nil, nil if none exists.
+func (s *SupabaseStore) GetWebhookEvent(ctx context.Context, id string) (*notification.WebhookEvent, error) {
+	data, _, err := s.calls.execute(ctx, s.client.From(webhookEventsTable).Select("*", "", false).Eq("id", id))
+	if err != nil {
+		return nil, fmt.Errorf("fetching webhook event: %w", err)
+	}
+
+	var rows []webhookEventRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("parsing webhook event: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return rowToWebhookEvent(&rows[0]), nil
+}
+
+// ListWebhookEvents returns matching events, newest first.
+func (s *SupabaseStore) ListWebhookEvents(ctx context.Context, filter notification.WebhookEventFilter) ([]*notification.WebhookEvent, error) {
+	query := s.client.From(webhookEventsTable).Select("*", "", false)
+
+	if filter.Provider != "" {
+		query = query.Eq("provider", filter.Provider)
+	}
+	if filter.EventType != "" {
+		query = query.Eq("event_type", filter.EventType)
+	}
+	if filter.Result != "" {
+		query = query.Eq("result", filter.Result)
+	}
+
+	data, _, err := s.calls.execute(ctx, query.
+		Order("received_at", &postgrest.OrderOpts{Ascending: false}).
+		Range(0, filter.Limit-1, ""))
+	if err != nil {
+		return nil, fmt.Errorf("listing webhook events: %w", err)
+	}
+
+	var rows []webhookEventRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("parsing webhook events: %w", err)
+	}
+
+	events := make([]*notification.WebhookEvent, len(rows))
+	for i, row := range rows {
+		events[i] = rowToWebhookEvent(&row)
+	}
+	return events, nil
+}
+
+// rowToWebhookEvent converts a webhookEventRow to a WebhookEvent.
+func rowToWebhookEvent(row *webhookEventRow) *notification.WebhookEvent {
+	event := &notification.WebhookEvent{
+		ID:        row.ID,
+		Provider:  row.Provider,
+		EventType: row.EventType,
+		Payload:   row.Payload,
+		Result:    notification.WebhookResult(row.Result),
+	}
+	if row.EventID != nil {
+		event.EventID = *row.EventID
+	}
+	if row.ProviderID != nil {
+		event.ProviderID = *row.ProviderID
+	}
+	if row.Status != nil {
+		event.Status = notification.NotificationStatus(*row.Status)
+	}
+	if row.BounceType != nil {
+		event.BounceType = notification.BounceType(*row.BounceType)
+	}
+	if row.Error != nil {
+		event.Error = *row.Error
+	}
+	if t, err := time.Parse(time.RFC3339Nano, row.ReceivedAt); err == nil {
+		event.ReceivedAt = t
+	}
+	if row.ProcessedAt != nil {
+		if t, err := time.Parse(time.RFC3339Nano, *row.ProcessedAt); err == nil {
+			event.ProcessedAt = &t
+		}
+	}
+	return event
+}
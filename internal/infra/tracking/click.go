@@ -0,0 +1,86 @@
+package tracking
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+)
+
+var _ notification.LinkTracker = (*ClickTracker)(nil)
+
+// macSize is the truncated HMAC-SHA256 length in bytes. 16 bytes keeps tracked
+// URLs short while still making token forgery infeasible.
+const macSize = 16
+
+// hrefRe matches absolute http(s) links in href attributes. mailto:, tel:, and
+// fragment links are left untouched.
+var hrefRe = regexp.MustCompile(`href="(https?://[^"]+)"`)
+
+// ClickTracker rewrites links to point at the click-tracking endpoint.
+// Tokens carry the log ID and target URL, signed with HMAC so the endpoint
+// can't be abused as an open redirect.
+type ClickTracker struct {
+	baseURL string
+	secret  []byte
+}
+
+// NewClickTracker creates a click tracker. baseURL is the public URL of the
+// notifly server (e.g. https://notify.example.com).
+func NewClickTracker(baseURL, secret string) *ClickTracker {
+	return &ClickTracker{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		secret:  []byte(secret),
+	}
+}
+
+// Rewrite replaces every absolute link in the HTML with a tracked redirect.
+func (t *ClickTracker) Rewrite(logID, body string) string {
+	return hrefRe.ReplaceAllStringFunc(body, func(match string) string {
+		target := html.UnescapeString(hrefRe.FindStringSubmatch(match)[1])
+		if strings.HasPrefix(target, t.baseURL+"/t/") {
+			return match // already tracked
+		}
+		return `href="` + html.EscapeString(t.baseURL+"/t/click/"+t.sign(logID, target)) + `"`
+	})
+}
+
+// Resolve verifies a token and returns the log ID and original target URL.
+func (t *ClickTracker) Resolve(token string) (logID, target string, err error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", "", errors.New("malformed tracking token")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", errors.New("malformed tracking token")
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, t.mac(payload)) {
+		return "", "", errors.New("invalid tracking token signature")
+	}
+
+	logID, target, ok = strings.Cut(string(payload), "\n")
+	if !ok || logID == "" || target == "" {
+		return "", "", errors.New("malformed tracking token")
+	}
+	return logID, target, nil
+}
+
+// sign builds the "<payload>.<mac>" token for a log ID and target URL.
+func (t *ClickTracker) sign(logID, target string) string {
+	payload := []byte(logID + "\n" + target)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(t.mac(payload))
+}
+
+func (t *ClickTracker) mac(payload []byte) []byte {
+	h := hmac.New(sha256.New, t.secret)
+	h.Write(payload)
+	return h.Sum(nil)[:macSize]
+}
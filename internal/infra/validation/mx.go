@@ -0,0 +1,134 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+)
+
+var _ notification.MXChecker = (*MXChecker)(nil)
+
+// maxCacheEntries bounds the cache so a server accepting arbitrary recipient
+// domains cannot grow it without limit.
+const maxCacheEntries = 10000
+
+// mxEntry is a cached lookup result.
+type mxEntry struct {
+	ok      bool
+	expires time.Time
+}
+
+// MXChecker verifies email domains via DNS, caching answers so repeated
+// sends to the same domain don't hit the resolver every time.
+type MXChecker struct {
+	resolver *net.Resolver
+	ttl      time.Duration
+	timeout  time.Duration
+
+	mu    sync.RWMutex
+	cache map[string]mxEntry
+}
+
+// NewMXChecker creates a DNS-backed MX checker with the given cache TTL.
+func NewMXChecker(ttl time.Duration) *MXChecker {
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	return &MXChecker{
+		resolver: net.DefaultResolver,
+		ttl:      ttl,
+		timeout:  3 * time.Second,
+		cache:    make(map[string]mxEntry),
+	}
+}
+
+// CanReceiveMail reports whether the domain has MX records, falling back to
+// A/AAAA records (RFC 5321 implicit MX). Definitive answers are cached for
+// the TTL; transient resolver errors are returned to the caller uncached.
+func (m *MXChecker) CanReceiveMail(ctx context.Context, domain string) (bool, error) {
+	m.mu.RLock()
+	entry, found := m.cache[domain]
+	m.mu.RUnlock()
+	if found {
+		if time.Now().Before(entry.expires) {
+			return entry.ok, nil
+		}
+		m.mu.Lock()
+		if current, ok := m.cache[domain]; ok && !time.Now().Before(current.expires) {
+			delete(m.cache, domain)
+		}
+		m.mu.Unlock()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	ok, err := m.lookup(ctx, domain)
+	if err != nil {
+		return false, err
+	}
+
+	m.mu.Lock()
+	if _, exists := m.cache[domain]; !exists && len(m.cache) >= maxCacheEntries {
+		m.evictLocked()
+	}
+	m.cache[domain] = mxEntry{ok: ok, expires: time.Now().Add(m.ttl)}
+	m.mu.Unlock()
+
+	return ok, nil
+}
+
+// evictLocked makes room for one entry: it drops every expired entry and, if
+// the cache is still full, the entry closest to expiry (the oldest, since all
+// entries share one TTL). Callers must hold the write lock.
+func (m *MXChecker) evictLocked() {
+	now := time.Now()
+	var oldest string
+	var oldestExpires time.Time
+	for domain, entry := range m.cache {
+		if !now.Before(entry.expires) {
+			delete(m.cache, domain)
+			continue
+		}
+		if oldest == "" || entry.expires.Before(oldestExpires) {
+			oldest, oldestExpires = domain, entry.expires
+		}
+	}
+	if len(m.cache) >= maxCacheEntries && oldest != "" {
+		delete(m.cache, oldest)
+	}
+}
+
+// lookup performs the uncached DNS queries.
+func (m *MXChecker) lookup(ctx context.Context, domain string) (bool, error) {
+	mxs, err := m.resolver.LookupMX(ctx, domain)
+	if err == nil && len(mxs) > 0 {
+		// A single "." MX is a null MX (RFC 7505): the domain accepts no mail
+		if len(mxs) == 1 && mxs[0].Host == "." {
+			return false, nil
+		}
+		return true, nil
+	}
+	if err != nil && !isNotFound(err) {
+		return false, err
+	}
+
+	addrs, err := m.resolver.LookupHost(ctx, domain)
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return len(addrs) > 0, nil
+}
+
+// isNotFound reports whether a resolver error is a definitive NXDOMAIN / no-data answer.
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLog logs one structured line per request through slog, so access logs
+// share the format of the rest of the process's logs. sampleRates maps a route
+// (e.g. "/health") to the fraction of its successful requests to log; routes
+// without an entry are always logged, and error responses always are.
+func AccessLog(sampleRates map[string]float64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		if rate, ok := sampleRates[c.FullPath()]; ok && status < 400 && rand.Float64() >= rate {
+			return
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		slog.Log(c.Request.Context(), level, "http request",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", status,
+			"latency_ms", time.Since(start).Milliseconds(),
+			"size", c.Writer.Size(),
+			"request_id", c.GetString(requestIDKey),
+			"api_key", c.GetString(apiKeyIDKey),
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
@@ -1,16 +1,23 @@
 package middleware
 
 import (
+	"crypto/sha256"
 	"crypto/subtle"
+	"encoding/hex"
 	"net/http"
 
-	"notifly/internal/common"
+	"github.com/badrkarrachai/notifly/pkg/common"
 
 	"github.com/gin-gonic/gin"
 )
 
+// apiKeyIDKey is the gin context key holding the authenticated key's ID.
+const apiKeyIDKey = "apiKeyID"
+
 // Auth returns middleware that validates the X-API-Key header against configured keys.
-// This is service-to-service authentication — not JWT-based.
+// This is service-to-service authentication — not JWT-based. The accepted key's
+// ID (see APIKeyID) is stored on the gin context for access logs and on the
+// request context, where the notifications it sends are attributed to it.
 func Auth(validKeys []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		apiKey := c.GetHeader("X-API-Key")
@@ -25,6 +32,9 @@ func Auth(validKeys []string) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		id := APIKeyID(apiKey)
+		c.Set(apiKeyIDKey, id)
+		c.Request = c.Request.WithContext(common.WithAPIKeyID(c.Request.Context(), id))
 
 		c.Next()
 	}
@@ -39,3 +49,11 @@ func isValidKey(key string, validKeys []string) bool {
 	}
 	return false
 }
+
+// APIKeyID names a key in logs and usage reports without revealing it: the
+// first 8 hex digits of its SHA-256, which tells apart the configured keys of
+// different client apps.
+func APIKeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "key_" + hex.EncodeToString(sum[:4])
+}
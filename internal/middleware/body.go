@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimit caps request bodies at maxBytes and transparently decompresses
+// gzip bodies (Content-Encoding: gzip). The cap applies to the decompressed
+// size, so a small compressed body cannot expand without bound. Handlers see
+// an *http.MaxBytesError from the body once the cap is passed.
+func BodyLimit(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		gzipped := strings.EqualFold(c.GetHeader("Content-Encoding"), "gzip")
+
+		// Reject early when the declared size already exceeds the cap
+		if !gzipped && c.Request.ContentLength > maxBytes {
+			common.Error(c, http.StatusRequestEntityTooLarge, "request body too large")
+			c.Abort()
+			return
+		}
+
+		if gzipped {
+			zr, err := gzip.NewReader(c.Request.Body)
+			if err != nil {
+				common.Error(c, http.StatusBadRequest, "invalid gzip request body")
+				c.Abort()
+				return
+			}
+			c.Request.Body = &gzipBody{Reader: zr, body: c.Request.Body}
+			c.Request.Header.Del("Content-Encoding")
+			c.Request.ContentLength = -1
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
+
+// gzipBody decompresses a request body and closes both the gzip reader and
+// the underlying body.
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b *gzipBody) Close() error {
+	zerr := b.Reader.Close()
+	if err := b.body.Close(); err != nil {
+		return err
+	}
+	return zerr
+}
@@ -5,11 +5,16 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// CORS returns a configured CORS middleware.
+// CORS returns a configured CORS middleware. Rate limit headers are exposed so
+// browser clients can read them on a 429, and ETag so they can poll with
+// If-None-Match.
 func CORS(origins, methods, headers []string) gin.HandlerFunc {
 	return cors.New(cors.Config{
 		AllowOrigins: origins,
 		AllowMethods: methods,
 		AllowHeaders: headers,
+		ExposeHeaders: []string{
+			"Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "ETag",
+		},
 	})
 }
@@ -1,63 +1,152 @@
 package middleware
 
 import (
-	"net/http"
+	"container/list"
+	"context"
+	"errors"
+	"log/slog"
 	"sync"
+	"time"
 
-	"notifly/internal/common"
+	"github.com/badrkarrachai/notifly/pkg/common"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
 )
 
-// RateLimiter is a per-IP token bucket rate limiter.
+// IPLimiter enforces the per-IP request rate. RateLimiter keeps buckets in
+// process memory; ratelimit.RedisIPLimiter shares them across replicas.
+type IPLimiter interface {
+	// Allow consumes one request for ip. A rejected request returns a
+	// *common.RateLimitError; any other error means the limit could not be checked.
+	Allow(ctx context.Context, ip string) error
+	// SetLimit changes the rate and burst. Safe for concurrent use.
+	SetLimit(rps float64, burst int)
+}
+
+var _ IPLimiter = (*RateLimiter)(nil)
+
+// RateLimiter is a per-IP token bucket rate limiter. It keeps at most
+// maxEntries buckets, evicting the least recently seen IP when full, so
+// scanner traffic from many addresses cannot grow memory without bound. An
+// evicted IP starts over with a full bucket on its next request.
 type RateLimiter struct {
-	limiters map[string]*rate.Limiter
-	mu       sync.RWMutex
-	rate     rate.Limit
-	burst    int
+	limiters   map[string]*list.Element // values are *ipLimiter
+	recent     *list.List               // most recently seen IP at the front
+	mu         sync.Mutex
+	rate       rate.Limit
+	burst      int
+	maxEntries int
 }
 
-// NewRateLimiter creates a new RateLimiter.
-func NewRateLimiter(rps float64, burst int) *RateLimiter {
+type ipLimiter struct {
+	ip      string
+	limiter *rate.Limiter
+}
+
+// NewRateLimiter creates a new RateLimiter that tracks at most maxEntries IPs.
+func NewRateLimiter(rps float64, burst, maxEntries int) *RateLimiter {
 	return &RateLimiter{
-		limiters: make(map[string]*rate.Limiter),
-		rate:     rate.Limit(rps),
-		burst:    burst,
+		limiters:   make(map[string]*list.Element),
+		recent:     list.New(),
+		rate:       rate.Limit(rps),
+		burst:      burst,
+		maxEntries: maxEntries,
 	}
 }
 
-// getLimiter retrieves or creates a rate limiter for the given IP.
+// getLimiter retrieves or creates a rate limiter for the given IP and marks
+// the IP as most recently seen.
 func (rl *RateLimiter) getLimiter(ip string) *rate.Limiter {
-	rl.mu.RLock()
-	limiter, exists := rl.limiters[ip]
-	rl.mu.RUnlock()
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if elem, exists := rl.limiters[ip]; exists {
+		rl.recent.MoveToFront(elem)
+		return elem.Value.(*ipLimiter).limiter
+	}
+
+	limiter := rate.NewLimiter(rl.rate, rl.burst)
+	rl.limiters[ip] = rl.recent.PushFront(&ipLimiter{ip: ip, limiter: limiter})
+	rl.evict()
+	return limiter
+}
 
-	if exists {
-		return limiter
+// evict drops the least recently seen IPs until at most maxEntries remain.
+// Callers must hold rl.mu.
+func (rl *RateLimiter) evict() {
+	for rl.recent.Len() > rl.maxEntries {
+		oldest := rl.recent.Back()
+		rl.recent.Remove(oldest)
+		delete(rl.limiters, oldest.Value.(*ipLimiter).ip)
 	}
+}
 
+// SetLimit changes the rate and burst for new and existing per-IP limiters.
+// It is safe to call while the middleware is serving requests.
+func (rl *RateLimiter) SetLimit(rps float64, burst int) {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	// Double-check after acquiring write lock
-	if limiter, exists = rl.limiters[ip]; exists {
-		return limiter
+	rl.rate = rate.Limit(rps)
+	rl.burst = burst
+	for _, elem := range rl.limiters {
+		limiter := elem.Value.(*ipLimiter).limiter
+		limiter.SetLimit(rl.rate)
+		limiter.SetBurst(rl.burst)
 	}
+}
 
-	limiter = rate.NewLimiter(rl.rate, rl.burst)
-	rl.limiters[ip] = limiter
-	return limiter
+// SetMaxEntries changes how many IPs are tracked, evicting at once if the
+// limiter is over the new cap. Safe for concurrent use.
+func (rl *RateLimiter) SetMaxEntries(maxEntries int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.maxEntries = maxEntries
+	rl.evict()
+}
+
+// Allow consumes one request for ip, returning a *common.RateLimitError when
+// its bucket is empty.
+func (rl *RateLimiter) Allow(_ context.Context, ip string) error {
+	limiter := rl.getLimiter(ip)
+	if limiter.Allow() {
+		return nil
+	}
+	return common.NewRateLimitError(
+		common.ReasonIPRateLimited,
+		"rate limit exceeded",
+		limiter.Burst(),
+		max(int(limiter.Tokens()), 0),
+		retryAfter(limiter),
+	)
+}
+
+// retryAfter is how long until limiter has a token again, without consuming it.
+func retryAfter(limiter *rate.Limiter) time.Duration {
+	r := limiter.Reserve()
+	defer r.Cancel()
+	if !r.OK() {
+		return time.Second // burst 0: nothing will ever pass, suggest a short back-off
+	}
+	return r.Delay()
 }
 
-// Middleware returns a Gin middleware that enforces rate limiting.
-func (rl *RateLimiter) Middleware() gin.HandlerFunc {
+// RateLimit returns a Gin middleware that enforces per-IP rate limiting with
+// limiter. When the limiter cannot be checked (Redis down) the request is let
+// through, so an outage of the limiter's store doesn't take the API down.
+func RateLimit(limiter IPLimiter) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		limiter := rl.getLimiter(c.ClientIP())
-		if !limiter.Allow() {
-			common.Error(c, http.StatusTooManyRequests, "rate limit exceeded")
-			c.Abort()
-			return
+		err := limiter.Allow(c.Request.Context(), c.ClientIP())
+		if err != nil {
+			var rateLimited *common.RateLimitError
+			if errors.As(err, &rateLimited) {
+				common.RateLimited(c, rateLimited)
+				c.Abort()
+				return
+			}
+			slog.Error("ip rate limit check failed, proceeding without limit", "ip", c.ClientIP(), "error", err)
 		}
 		c.Next()
 	}
@@ -7,6 +7,9 @@ import (
 
 const requestIDHeader = "X-Request-ID"
 
+// requestIDKey is the gin context key holding the request ID.
+const requestIDKey = "requestID"
+
 // RequestID injects a unique request ID into every request context and response header.
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -14,7 +17,7 @@ func RequestID() gin.HandlerFunc {
 		if id == "" {
 			id = uuid.New().String()
 		}
-		c.Set("requestID", id)
+		c.Set(requestIDKey, id)
 		c.Header(requestIDHeader, id)
 		c.Next()
 	}
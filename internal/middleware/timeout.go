@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout cancels the request context after timeout and answers 503 if the
+// handler has not finished by then. The store's Supabase client ignores
+// contexts, so the handler runs in its own goroutine writing to a buffer: on
+// time it is copied to the client, past the deadline it is discarded and the
+// client gets the 503 at once (with Connection: close) instead of waiting out
+// the server's write timeout. The middleware still waits for the handler to
+// return before handing the gin.Context back, since gin reuses contexts.
+func Timeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		w := c.Writer
+		tw := &timeoutWriter{ResponseWriter: w, header: w.Header().Clone()}
+		c.Writer = tw
+
+		done := make(chan struct{})
+		var panicked any
+		go func() {
+			defer close(done)
+			defer func() { panicked = recover() }()
+			c.Next()
+		}()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				tw.expire()
+				writeTimeout(w)
+			}
+			<-done
+		}
+
+		c.Writer = w
+		if panicked != nil {
+			panic(panicked) // let gin.Recovery handle it on the request goroutine
+		}
+		tw.flush()
+	}
+}
+
+// writeTimeout sends the 503 directly on the real writer.
+func writeTimeout(w gin.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Connection", "close")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	_ = json.NewEncoder(w).Encode(common.APIResponse{
+		Success: false,
+		Error: &common.APIError{
+			Code:    http.StatusServiceUnavailable,
+			Message: "request timed out",
+		},
+	})
+	w.Flush()
+}
+
+// timeoutWriter buffers a handler's response so it can be dropped if the
+// request times out. Writes after the deadline fail with http.ErrHandlerTimeout.
+type timeoutWriter struct {
+	gin.ResponseWriter // the real writer, written only by flush
+
+	mu      sync.Mutex
+	header  http.Header
+	body    bytes.Buffer
+	status  int
+	expired bool
+}
+
+// expire discards the buffered response and fails the handler's later writes.
+func (w *timeoutWriter) expire() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expired = true
+}
+
+// flush copies the buffered response to the real writer unless it expired.
+func (w *timeoutWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.expired || w.status == 0 {
+		return
+	}
+
+	dst := w.ResponseWriter.Header()
+	for key, values := range w.header {
+		dst[key] = values
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	_, _ = w.ResponseWriter.Write(w.body.Bytes())
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.WriteHeader(http.StatusOK)
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.expired {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status == 0 {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status != 0
+}
+
+// Flush is a no-op: the response is only sent once the handler returns.
+func (w *timeoutWriter) Flush() {}
@@ -2,19 +2,27 @@ package router
 
 import (
 	"net/http"
+	"time"
 
-	"notifly/internal/common"
-	"notifly/internal/config"
-	"notifly/internal/domain/notification"
-	"notifly/internal/middleware"
+	"github.com/badrkarrachai/notifly/internal/config"
+	"github.com/badrkarrachai/notifly/internal/middleware"
+	"github.com/badrkarrachai/notifly/pkg/common"
+	"github.com/badrkarrachai/notifly/pkg/domain"
+	"github.com/badrkarrachai/notifly/pkg/notification"
+	"github.com/badrkarrachai/notifly/pkg/settings"
 
 	"github.com/gin-gonic/gin"
 )
 
 // New creates and configures the Gin router with all middleware and routes.
+// The per-IP rate limiter is passed in so its limits can be changed on config reload.
+// domainHandler may be nil to leave out the sending-domain routes.
 func New(
 	cfg *config.Config,
+	rateLimiter middleware.IPLimiter,
 	notificationHandler *notification.Handler,
+	settingsHandler *settings.Handler,
+	domainHandler *domain.Handler,
 ) *gin.Engine {
 	// Set Gin mode
 	gin.SetMode(cfg.Server.Mode)
@@ -31,25 +39,37 @@ func New(
 	))
 
 	// Rate limiter
-	rateLimiter := middleware.NewRateLimiter(
-		cfg.RateLimit.RequestsPerSecond,
-		cfg.RateLimit.Burst,
-	)
-	r.Use(rateLimiter.Middleware())
+	r.Use(middleware.RateLimit(rateLimiter))
 
-	// Custom structured logger middleware
-	r.Use(gin.Logger())
+	// Structured access log (slog), sampled per route
+	r.Use(middleware.AccessLog(cfg.Log.AccessSample))
 
 	// Public routes
 	r.GET("/health", healthCheck)
+	notificationHandler.RegisterPublicRoutes(r)
+	if cfg.Metrics.Prometheus {
+		r.GET("/metrics", notificationHandler.Metrics)
+	}
 
 	// Protected API routes (API key required)
 	protectedAPI := r.Group("/api/v1")
 	protectedAPI.Use(middleware.Auth(cfg.Auth.APIKeys))
+	protectedAPI.Use(middleware.BodyLimit(cfg.Server.MaxBodyBytes))
+	protectedAPI.Use(middleware.Timeout(time.Duration(cfg.Server.RequestTimeoutSec) * time.Second))
 	{
 		notificationHandler.RegisterRoutes(protectedAPI)
+		settingsHandler.RegisterRoutes(protectedAPI)
+		if domainHandler != nil {
+			domainHandler.RegisterRoutes(protectedAPI)
+		}
 	}
 
+	// Protected streaming routes: like the API, but without the request
+	// timeout, which buffers responses until the handler returns
+	streamAPI := r.Group("/api/v1")
+	streamAPI.Use(middleware.Auth(cfg.Auth.APIKeys))
+	notificationHandler.RegisterStreamRoutes(streamAPI)
+
 	return r
 }
 
@@ -0,0 +1,7 @@
+-- Notifly: CC, BCC, and Reply-To addressing
+-- Run this in Supabase SQL Editor after 001_init.sql
+
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS cc       TEXT[],
+    ADD COLUMN IF NOT EXISTS bcc      TEXT[],
+    ADD COLUMN IF NOT EXISTS reply_to VARCHAR(255);
@@ -0,0 +1,6 @@
+-- Notifly: multiple recipients per notification
+-- Used when recipients.fan_out is false: one log covers every address in "to".
+-- The first address is still stored in `recipient` for filtering and indexing.
+
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS recipients TEXT[];
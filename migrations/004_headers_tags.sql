@@ -0,0 +1,9 @@
+-- Notifly: custom email headers and provider tags
+-- Stored on the log so every send can be traced back to its request metadata.
+
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS headers JSONB,
+    ADD COLUMN IF NOT EXISTS tags    JSONB;
+
+-- Lookup by tag (e.g. tags @> '{"campaign":"spring"}')
+CREATE INDEX IF NOT EXISTS idx_notif_logs_tags ON notification_logs USING GIN (tags);
@@ -0,0 +1,6 @@
+-- Notifly: click tracking
+-- Set when a tracked link is followed (GET /t/click/:token) or a provider
+-- reports an email.clicked event.
+
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS clicked_at TIMESTAMPTZ;
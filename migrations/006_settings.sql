@@ -0,0 +1,15 @@
+-- Notifly: runtime settings
+-- Operational knobs changed through /api/v1/admin/settings. A row overrides the
+-- matching config.yaml / env value; deleting it restores the configured value.
+-- Server and worker processes poll this table (settings.poll_interval_sec).
+
+CREATE TABLE IF NOT EXISTS settings (
+    key         TEXT PRIMARY KEY,
+    value       JSONB NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Bounce suppression looks up a recipient's most recent bounce
+CREATE INDEX IF NOT EXISTS idx_notif_logs_bounced_recipient
+    ON notification_logs (recipient)
+    WHERE status = 'bounced';
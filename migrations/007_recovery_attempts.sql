@@ -0,0 +1,6 @@
+-- Notifly: reaper recovery cap
+-- Counts how many times the reaper re-enqueued a stale log. Past
+-- reaper.max_recovery_attempts the log is marked 'abandoned' instead.
+
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS recovery_attempts INTEGER NOT NULL DEFAULT 0;
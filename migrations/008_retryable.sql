@@ -0,0 +1,6 @@
+-- Notifly: failure classification
+-- Records whether a failed send is worth retrying. Permanent failures
+-- (invalid recipient, rejected payload) are not retried by the worker.
+
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS retryable BOOLEAN;
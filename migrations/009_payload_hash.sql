@@ -0,0 +1,7 @@
+-- Notifly: idempotency conflict detection
+-- Fingerprint of the request that created the log. A request that reuses an
+-- idempotency key with a different fingerprint is rejected with 409 Conflict.
+-- Logs created before this migration have no hash and are never in conflict.
+
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS payload_hash TEXT;
@@ -0,0 +1,19 @@
+-- Notifly: recipient data erasure
+-- One row per DELETE /api/v1/recipients/:recipient/data request. The worker
+-- anonymizes the recipient's logs in pages and records progress here.
+-- Only a SHA-256 of the lowercased address is kept, never the address itself.
+
+CREATE TABLE IF NOT EXISTS erasure_jobs (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    recipient_hash  TEXT        NOT NULL,
+    status          VARCHAR(20) NOT NULL DEFAULT 'pending',
+    logs_erased     INT         NOT NULL DEFAULT 0,
+    error           TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at    TIMESTAMPTZ
+);
+
+-- Erasure matches the recipient in the cc/bcc/recipients arrays as well
+CREATE INDEX IF NOT EXISTS idx_notif_logs_recipients ON notification_logs USING GIN (recipients);
+CREATE INDEX IF NOT EXISTS idx_notif_logs_cc ON notification_logs USING GIN (cc);
+CREATE INDEX IF NOT EXISTS idx_notif_logs_bcc ON notification_logs USING GIN (bcc);
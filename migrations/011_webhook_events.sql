@@ -0,0 +1,23 @@
+-- Notifly: raw webhook event storage
+-- Every inbound provider webhook is stored as received, before it is mapped to
+-- a notification status, with the outcome of processing it. Ignored and failed
+-- events can be listed and replayed through /api/v1/admin/webhooks/events.
+
+CREATE TABLE IF NOT EXISTS webhook_events (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    provider        VARCHAR(20)  NOT NULL,
+    event_type      VARCHAR(100) NOT NULL DEFAULT '',
+    provider_id     VARCHAR(255),
+    payload         JSONB        NOT NULL,
+    result          VARCHAR(20)  NOT NULL DEFAULT 'received',
+    error           TEXT,
+    received_at     TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    processed_at    TIMESTAMPTZ
+);
+
+-- Admin listing, newest first, optionally by result
+CREATE INDEX IF NOT EXISTS idx_webhook_events_received ON webhook_events (received_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_events_result ON webhook_events (result, received_at DESC);
+
+-- Recipient erasure matches the addressees in the raw payload
+CREATE INDEX IF NOT EXISTS idx_webhook_events_to ON webhook_events USING GIN ((payload->'data'->'to'));
@@ -0,0 +1,12 @@
+-- Notifly: spam complaints
+-- SES and Resend report complaints when a recipient marks a message as spam.
+-- Complained logs get status 'complained' and are suppressed like bounces.
+
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS complained_at TIMESTAMPTZ;
+
+-- Suppression now looks up bounces and complaints
+DROP INDEX IF EXISTS idx_notif_logs_bounced_recipient;
+CREATE INDEX IF NOT EXISTS idx_notif_logs_suppressed_recipient
+    ON notification_logs (recipient)
+    WHERE status IN ('bounced', 'complained');
@@ -0,0 +1,12 @@
+-- Notifly: webhook adapters
+-- Adapters parse a provider's webhook once, when it arrives: the provider's
+-- event ID and the notification status it maps to are stored with the event,
+-- so a replay applies the same status without parsing the payload again.
+-- Events stored before this migration have no status and replay as ignored.
+
+ALTER TABLE webhook_events
+    ADD COLUMN IF NOT EXISTS event_id TEXT,
+    ADD COLUMN IF NOT EXISTS status   VARCHAR(20);
+
+-- Look up a provider's event by its own ID, e.g. to spot redeliveries
+CREATE INDEX IF NOT EXISTS idx_webhook_events_event_id ON webhook_events (provider, event_id);
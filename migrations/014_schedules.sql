@@ -0,0 +1,21 @@
+-- Notifly: recurring scheduled notifications
+-- One row per schedule created through /api/v1/schedules. The scheduler sends
+-- request (a POST /send body) whenever next_run_at passes, then advances
+-- next_run_at to the cron expression's next occurrence in timezone.
+
+CREATE TABLE IF NOT EXISTS schedules (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name         TEXT         NOT NULL,
+    cron         TEXT         NOT NULL,
+    timezone     VARCHAR(64)  NOT NULL DEFAULT 'UTC',
+    request      JSONB        NOT NULL,
+    enabled      BOOLEAN      NOT NULL DEFAULT TRUE,
+    next_run_at  TIMESTAMPTZ,
+    last_run_at  TIMESTAMPTZ,
+    last_error   TEXT,
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+-- The scheduler polls for enabled schedules that are due
+CREATE INDEX IF NOT EXISTS idx_schedules_due ON schedules (next_run_at) WHERE enabled;
@@ -0,0 +1,33 @@
+-- Notifly: campaigns
+-- One row per POST /api/v1/campaigns request. The worker fans the audience out
+-- in batches, advancing dispatched after each one; every log it creates carries
+-- the campaign's ID so GET /api/v1/campaigns/:id can count them by status.
+-- The audience is emptied once the campaign completes or is cancelled.
+
+CREATE TABLE IF NOT EXISTS campaigns (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name          TEXT         NOT NULL,
+    channel       VARCHAR(20)  NOT NULL,
+    type          VARCHAR(50)  NOT NULL,
+    data          JSONB,
+    audience      TEXT[]       NOT NULL DEFAULT '{}',
+    status        VARCHAR(20)  NOT NULL DEFAULT 'running',
+    total         INT          NOT NULL DEFAULT 0,
+    dispatched    INT          NOT NULL DEFAULT 0,
+    suppressed    INT          NOT NULL DEFAULT 0,
+    error         TEXT,
+    scheduled_at  TIMESTAMPTZ,
+    started_at    TIMESTAMPTZ,
+    completed_at  TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaigns_created_at ON campaigns (created_at DESC);
+
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS campaign_id UUID REFERENCES campaigns (id) ON DELETE SET NULL;
+
+-- Campaign progress counts logs by campaign and status
+CREATE INDEX IF NOT EXISTS idx_notif_logs_campaign ON notification_logs (campaign_id, status)
+    WHERE campaign_id IS NOT NULL;
@@ -0,0 +1,8 @@
+-- Notifly: campaign throttles
+-- A campaign can cap its send rate at max_per_minute, optionally warming up
+-- linearly from warmup_start_per_minute over warmup_minutes after it starts.
+-- The worker sizes each batch and the delay before the next one to hold the
+-- current rate. NULL sends at the configured batch pace.
+
+ALTER TABLE campaigns
+    ADD COLUMN IF NOT EXISTS throttle JSONB;
@@ -0,0 +1,13 @@
+-- Notifly: rendering at enqueue
+-- With templates.render_at_enqueue on, the subject, HTML, and text are rendered
+-- when a request is accepted and stored on the log; the worker sends them as
+-- stored, so editing a template cannot change a queued message, and the log
+-- shows exactly what was sent. Campaigns render once at creation and copy the
+-- content to every recipient's log. NULL means the worker renders at send time.
+-- Erasure clears the content along with the template data.
+
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS content JSONB;
+
+ALTER TABLE campaigns
+    ADD COLUMN IF NOT EXISTS content JSONB;
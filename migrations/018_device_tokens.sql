@@ -0,0 +1,16 @@
+-- Notifly: push device token registry
+-- One row per device token registered through /api/v1/devices. Registering a
+-- token again moves it to the new user and refreshes last_seen_at. Tokens FCM
+-- or APNs report as invalid during a send are deleted by the worker.
+
+CREATE TABLE IF NOT EXISTS device_tokens (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id       TEXT         NOT NULL,
+    token         TEXT         NOT NULL UNIQUE,
+    platform      VARCHAR(10)  NOT NULL CHECK (platform IN ('fcm', 'apns')),
+    last_seen_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+-- Devices are listed per user
+CREATE INDEX IF NOT EXISTS idx_device_tokens_user ON device_tokens (user_id, last_seen_at DESC);
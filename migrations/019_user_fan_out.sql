@@ -0,0 +1,12 @@
+-- Notifly: push to a user's devices
+-- A push sent to a user_id creates a parent log (recipient = the user ID) and
+-- one child log per registered device token, linked by parent_id. The parent
+-- is never sent: the worker sets its status from its children's (sent if any
+-- device succeeded, failed once all of them failed). The reaper skips parents.
+
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS user_id   TEXT,
+    ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES notification_logs (id);
+
+-- Parents look up their children after every device send
+CREATE INDEX IF NOT EXISTS idx_notification_logs_parent_id ON notification_logs (parent_id) WHERE parent_id IS NOT NULL;
@@ -0,0 +1,9 @@
+-- Notifly: cross-channel fallbacks
+-- A log accepted with a fallback_to address under a matching fallback rule
+-- stores the fallback (channel, address, and the status it must reach). A
+-- delayed check sends a new log on the fallback channel if the original has
+-- not reached that status; the new log references it in fallback_of.
+
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS fallback    JSONB,
+    ADD COLUMN IF NOT EXISTS fallback_of UUID REFERENCES notification_logs (id);
@@ -0,0 +1,28 @@
+-- Notifly: escalation policies
+-- One row per policy created through /api/v1/escalation-policies: the chain of
+-- steps (email, sms, push, or webhook, each with a delay) run for every
+-- notification of type until it is delivered or acknowledged.
+
+CREATE TABLE IF NOT EXISTS escalation_policies (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name        TEXT         NOT NULL,
+    type        VARCHAR(50)  NOT NULL,
+    steps       JSONB        NOT NULL,
+    enabled     BOOLEAN      NOT NULL DEFAULT TRUE,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+-- A type has at most one policy, looked up on every send
+CREATE UNIQUE INDEX IF NOT EXISTS idx_escalation_policies_type ON escalation_policies (type);
+
+-- A log accepted under a policy stores its escalation (the policy's steps and
+-- the send's escalate_to addresses); each step's log references it in
+-- escalation_of. acknowledged_at stops the remaining steps.
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS escalation      JSONB,
+    ADD COLUMN IF NOT EXISTS escalation_of   UUID REFERENCES notification_logs (id),
+    ADD COLUMN IF NOT EXISTS acknowledged_at TIMESTAMPTZ;
+
+-- Each step checks whether an earlier step's notification was delivered
+CREATE INDEX IF NOT EXISTS idx_notification_logs_escalation_of ON notification_logs (escalation_of) WHERE escalation_of IS NOT NULL;
@@ -0,0 +1,10 @@
+-- Notifly: failure codes
+-- Classifies why a send failed (render_error, invalid_recipient, provider_4xx,
+-- provider_5xx, timeout, suppressed, expired) next to the free-text
+-- error_message, so failures can be filtered and counted.
+
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS failure_code VARCHAR(32);
+
+-- Stats count failed logs per code
+CREATE INDEX IF NOT EXISTS idx_notification_logs_failure_code ON notification_logs (failure_code) WHERE status = 'failed';
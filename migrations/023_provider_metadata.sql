@@ -0,0 +1,7 @@
+-- Notifly: provider response metadata
+-- What the provider's API answered to the last send attempt (HTTP status,
+-- provider error name, rate-limit headers, region, attempts), so a rejection
+-- can be understood without the provider's dashboard.
+
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS provider_metadata JSONB;
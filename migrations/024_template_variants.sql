@@ -0,0 +1,9 @@
+-- Notifly: A/B tests of templates
+-- The template variant a log was sent with (templates.variants), so open and
+-- click rates can be compared per variant. NULL when its type was not under test.
+
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS variant VARCHAR(64);
+
+-- Stats count each variant's sent, opened, and clicked logs
+CREATE INDEX IF NOT EXISTS idx_notification_logs_variant ON notification_logs (type, variant) WHERE variant IS NOT NULL;
@@ -0,0 +1,7 @@
+-- Notifly: sender identities
+-- The named sender identity (email.senders) an email log is sent from: the
+-- one its request named, or its type's default. NULL for the provider's
+-- default address.
+
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS sender VARCHAR(64);
@@ -0,0 +1,12 @@
+-- Notifly: hard and soft bounces
+-- A bounce is hard (the address or number will never take the message) or
+-- soft (it may on a later attempt). Soft-bounced logs are sent again, up to
+-- suppression.soft_bounce_retries times; bounce_retries counts the resends.
+-- Bounces recorded before this migration have no type and count as hard.
+
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS bounce_type VARCHAR(8),
+    ADD COLUMN IF NOT EXISTS bounce_retries INT NOT NULL DEFAULT 0;
+
+ALTER TABLE webhook_events
+    ADD COLUMN IF NOT EXISTS bounce_type VARCHAR(8);
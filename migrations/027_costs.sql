@@ -0,0 +1,8 @@
+-- Notifly: per-notification cost
+-- api_key_id is the ID of the API key a notification was accepted with (NULL
+-- for campaigns and schedules); cost is its estimated cost at the configured
+-- costs.prices, set when it is sent (NULL when no price applies).
+
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS api_key_id VARCHAR(16),
+    ADD COLUMN IF NOT EXISTS cost NUMERIC(12, 6);
@@ -0,0 +1,7 @@
+-- Notifly: usage reports
+-- GET /api/v1/usage counts each API key's notifications per billing period;
+-- this index keeps those counts cheap.
+
+CREATE INDEX IF NOT EXISTS idx_notif_logs_api_key_created
+    ON notification_logs (api_key_id, created_at)
+    WHERE api_key_id IS NOT NULL;
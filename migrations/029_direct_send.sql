@@ -0,0 +1,6 @@
+-- Notifly: direct sends
+-- direct_send is set on a notification the API sent itself, bypassing the
+-- queue, because its task could not be enqueued while Redis was unreachable.
+
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS direct_send BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,23 @@
+-- Notifly: archived notifications
+-- archived_at is set on a notification archived through the API: it is left
+-- out of default listings and bulk retries but kept for audits until purged.
+
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+
+-- Purges select archived logs by archive time
+CREATE INDEX IF NOT EXISTS idx_notification_logs_archived_at ON notification_logs (archived_at) WHERE archived_at IS NOT NULL;
+
+-- A purged log may still be referenced by a device child, fallback, or
+-- escalation step that was not purged with it: clear the reference rather
+-- than refuse the delete
+ALTER TABLE notification_logs
+    DROP CONSTRAINT IF EXISTS notification_logs_parent_id_fkey,
+    ADD CONSTRAINT notification_logs_parent_id_fkey
+        FOREIGN KEY (parent_id) REFERENCES notification_logs (id) ON DELETE SET NULL,
+    DROP CONSTRAINT IF EXISTS notification_logs_fallback_of_fkey,
+    ADD CONSTRAINT notification_logs_fallback_of_fkey
+        FOREIGN KEY (fallback_of) REFERENCES notification_logs (id) ON DELETE SET NULL,
+    DROP CONSTRAINT IF EXISTS notification_logs_escalation_of_fkey,
+    ADD CONSTRAINT notification_logs_escalation_of_fkey
+        FOREIGN KEY (escalation_of) REFERENCES notification_logs (id) ON DELETE SET NULL;
@@ -0,0 +1,10 @@
+-- Notifly: campaigns sent at a local time
+-- send_at_local is a wall-clock time ("2024-06-01T09:00") each audience member
+-- is sent to in their own timezone: timezones maps members to IANA zones, and
+-- timezone is the zone of the rest. The audience is stored in the order members
+-- are due, and the timezones are emptied with it once the campaign finishes.
+
+ALTER TABLE campaigns
+    ADD COLUMN IF NOT EXISTS send_at_local VARCHAR(19),
+    ADD COLUMN IF NOT EXISTS timezone      VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS timezones     JSONB;
@@ -0,0 +1,25 @@
+-- Notifly: follow-up sequences
+-- One row per sequence created through /api/v1/follow-up-sequences: the
+-- reminders (a template and a delay each) sent after every notification of
+-- type that has not been opened — or delivered, or clicked — in time.
+
+CREATE TABLE IF NOT EXISTS follow_up_sequences (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name        TEXT         NOT NULL,
+    type        VARCHAR(50)  NOT NULL,
+    steps       JSONB        NOT NULL,
+    enabled     BOOLEAN      NOT NULL DEFAULT TRUE,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+-- A type has at most one sequence, looked up on every send
+CREATE UNIQUE INDEX IF NOT EXISTS idx_follow_up_sequences_type ON follow_up_sequences (type);
+
+-- Each reminder's log references the notification it follows up; a purged
+-- original leaves its reminders unlinked
+ALTER TABLE notification_logs
+    ADD COLUMN IF NOT EXISTS follow_up_of UUID REFERENCES notification_logs (id) ON DELETE SET NULL;
+
+-- Each step checks whether an earlier reminder got far enough
+CREATE INDEX IF NOT EXISTS idx_notification_logs_follow_up_of ON notification_logs (follow_up_of) WHERE follow_up_of IS NOT NULL;
@@ -0,0 +1,10 @@
+// Package migrations embeds the versioned database schema. Each file is named
+// NNN_name.sql and is applied once, in version order, by "notifly migrate".
+package migrations
+
+import "embed"
+
+// FS holds every migration file.
+//
+//go:embed *.sql
+var FS embed.FS
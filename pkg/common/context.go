@@ -0,0 +1,19 @@
+package common
+
+import "context"
+
+// apiKeyIDContextKey is the context key for the authenticated API key's ID.
+type apiKeyIDContextKey struct{}
+
+// WithAPIKeyID returns ctx carrying id, the ID of the API key the request was
+// authenticated with. Notifications accepted under ctx are attributed to it.
+func WithAPIKeyID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, apiKeyIDContextKey{}, id)
+}
+
+// APIKeyID returns the API key ID carried by ctx, or "" when there is none,
+// as for work started by the worker or the scheduler.
+func APIKeyID(ctx context.Context) string {
+	id, _ := ctx.Value(apiKeyIDContextKey{}).(string)
+	return id
+}
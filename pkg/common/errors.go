@@ -0,0 +1,262 @@
+// Package common holds the typed errors returned by the notification pipeline,
+// the HTTP response envelope that maps them to status codes, and the request
+// context values the pipeline reads.
+package common
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// NotFoundError indicates a resource was not found.
+type NotFoundError struct {
+	Resource string
+	ID       string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s with id '%s' not found", e.Resource, e.ID)
+}
+
+// NewNotFoundError creates a new NotFoundError.
+func NewNotFoundError(resource, id string) *NotFoundError {
+	return &NotFoundError{Resource: resource, ID: id}
+}
+
+// ValidationError indicates invalid input data. Field, when set, names the
+// request field at fault (e.g. "to" or "data.token"), so clients can point
+// at it without parsing the message.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// NewValidationError creates a new ValidationError.
+func NewValidationError(message string) *ValidationError {
+	return &ValidationError{Message: message}
+}
+
+// NewFieldError creates a ValidationError about one request field.
+func NewFieldError(field, message string) *ValidationError {
+	return &ValidationError{Field: field, Message: message}
+}
+
+// UnauthorizedError indicates missing or invalid authentication.
+type UnauthorizedError struct {
+	Message string
+}
+
+func (e *UnauthorizedError) Error() string {
+	if e.Message == "" {
+		return "unauthorized"
+	}
+	return e.Message
+}
+
+// NewUnauthorizedError creates a new UnauthorizedError.
+func NewUnauthorizedError(message string) *UnauthorizedError {
+	return &UnauthorizedError{Message: message}
+}
+
+// ProviderError indicates an external provider failure.
+type ProviderError struct {
+	Provider string
+	Message  string
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s provider error: %s", e.Provider, e.Message)
+}
+
+// NewProviderError creates a new ProviderError.
+func NewProviderError(provider, message string) *ProviderError {
+	return &ProviderError{Provider: provider, Message: message}
+}
+
+// HTTPStatusError is a provider API failure that carries the HTTP status the
+// provider answered with, so callers can tell client errors from server ones.
+type HTTPStatusError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *HTTPStatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *HTTPStatusError) Unwrap() error {
+	return e.Err
+}
+
+// NewHTTPStatusError wraps err with the HTTP status that caused it.
+func NewHTTPStatusError(statusCode int, err error) *HTTPStatusError {
+	return &HTTPStatusError{StatusCode: statusCode, Err: err}
+}
+
+// ConflictError indicates a request clashes with existing state, e.g. an
+// idempotency key reused for a different payload. ExistingID references the
+// resource the key already belongs to.
+type ConflictError struct {
+	Message    string
+	ExistingID string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+// NewConflictError creates a new ConflictError.
+func NewConflictError(message, existingID string) *ConflictError {
+	return &ConflictError{Message: message, ExistingID: existingID}
+}
+
+// UnavailableError indicates a dependency needed to accept the request is down
+// and the request was refused rather than processed unsafely.
+type UnavailableError struct {
+	Message string
+}
+
+func (e *UnavailableError) Error() string {
+	return e.Message
+}
+
+// NewUnavailableError creates a new UnavailableError.
+func NewUnavailableError(message string) *UnavailableError {
+	return &UnavailableError{Message: message}
+}
+
+// Reasons identify which limiter rejected a request, so clients can tell them
+// apart without parsing messages.
+const (
+	ReasonIPRateLimited        = "ip_rate_limited"
+	ReasonRecipientRateLimited = "recipient_rate_limited"
+)
+
+// RateLimitError indicates a request was rejected by a rate limiter. Limit,
+// Remaining, and Reset describe the limiter's window and are returned to the
+// client as X-RateLimit-* and Retry-After headers.
+type RateLimitError struct {
+	Reason    string
+	Message   string
+	Limit     int
+	Remaining int
+	Reset     time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return e.Message
+}
+
+// NewRateLimitError creates a new RateLimitError.
+func NewRateLimitError(reason, message string, limit, remaining int, reset time.Duration) *RateLimitError {
+	return &RateLimitError{Reason: reason, Message: message, Limit: limit, Remaining: remaining, Reset: reset}
+}
+
+// PermanentError marks a failure that will recur on every retry (a rejected
+// recipient, a malformed payload), so the task should not be retried.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// NewPermanentError wraps err as a permanent failure.
+func NewPermanentError(err error) *PermanentError {
+	return &PermanentError{Err: err}
+}
+
+// RetryAfterError marks a transient failure the provider asked not to retry
+// before After has passed, e.g. a 429 with Retry-After, so the retry waits
+// that long instead of the usual backoff.
+type RetryAfterError struct {
+	Err   error
+	After time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// NewRetryAfterError wraps err with the wait the provider asked for.
+func NewRetryAfterError(err error, after time.Duration) *RetryAfterError {
+	return &RetryAfterError{Err: err, After: after}
+}
+
+// RetryAfter returns the wait err asks for before a retry: the After of the
+// RetryAfterError it is or wraps.
+func RetryAfter(err error) (time.Duration, bool) {
+	var retryAfterErr *RetryAfterError
+	if errors.As(err, &retryAfterErr) && retryAfterErr.After > 0 {
+		return retryAfterErr.After, true
+	}
+	return 0, false
+}
+
+// EarlyError marks a task that ran before it was due, e.g. early by clock
+// skew. It is retried once After has passed, and the early run does not count
+// against the task's retries.
+type EarlyError struct {
+	Err error
+}
+
+func (e *EarlyError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *EarlyError) Unwrap() error {
+	return e.Err
+}
+
+// NewEarlyError wraps err as an early run, due after the given wait.
+func NewEarlyError(err error, after time.Duration) *EarlyError {
+	return &EarlyError{Err: NewRetryAfterError(err, after)}
+}
+
+// IsEarly reports whether err is or wraps an EarlyError.
+func IsEarly(err error) bool {
+	var earlyErr *EarlyError
+	return errors.As(err, &earlyErr)
+}
+
+// InvalidTokenError is returned by push providers when FCM or APNs reports
+// device tokens as unregistered or malformed. Sending to them again can never
+// succeed, so the failure is permanent and the tokens are dropped from the
+// device registry.
+type InvalidTokenError struct {
+	Tokens []string
+	Reason string
+}
+
+func (e *InvalidTokenError) Error() string {
+	return fmt.Sprintf("invalid device token (%s)", e.Reason)
+}
+
+// NewInvalidTokenError creates a new InvalidTokenError.
+func NewInvalidTokenError(reason string, tokens ...string) *InvalidTokenError {
+	return &InvalidTokenError{Tokens: tokens, Reason: reason}
+}
+
+// IsPermanent reports whether retrying the operation that returned err is
+// pointless: err is or wraps a PermanentError, a ValidationError, or an
+// InvalidTokenError.
+func IsPermanent(err error) bool {
+	var permErr *PermanentError
+	var valErr *ValidationError
+	var tokenErr *InvalidTokenError
+	return errors.As(err, &permErr) || errors.As(err, &valErr) || errors.As(err, &tokenErr)
+}
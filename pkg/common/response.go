@@ -0,0 +1,136 @@
+package common
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIResponse is the standardized JSON response envelope.
+type APIResponse struct {
+	Success bool      `json:"success"`
+	Data    any       `json:"data,omitempty"`
+	Error   *APIError `json:"error,omitempty"`
+}
+
+// APIError contains error details in the response. Reason is a stable
+// machine-readable code, set for errors clients are expected to handle
+// programmatically (e.g. "recipient_rate_limited"). Field names the request
+// field a validation error is about.
+type APIError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Reason  string `json:"reason,omitempty"`
+	Field   string `json:"field,omitempty"`
+}
+
+// Success sends a successful JSON response with data.
+func Success(c *gin.Context, statusCode int, data any) {
+	c.JSON(statusCode, APIResponse{
+		Success: true,
+		Data:    data,
+	})
+}
+
+// Error sends an error JSON response.
+func Error(c *gin.Context, statusCode int, message string) {
+	c.JSON(statusCode, APIResponse{
+		Success: false,
+		Error: &APIError{
+			Code:    statusCode,
+			Message: message,
+		},
+	})
+}
+
+// HandleError inspects a domain error and sends the appropriate HTTP response.
+// Uses errors.As to traverse the full error chain, supporting wrapped errors.
+func HandleError(c *gin.Context, err error) {
+	var notFound *NotFoundError
+	var validation *ValidationError
+	var unauthorized *UnauthorizedError
+	var provider *ProviderError
+	var conflict *ConflictError
+	var unavailable *UnavailableError
+	var rateLimited *RateLimitError
+
+	switch {
+	case errors.As(err, &notFound):
+		Error(c, http.StatusNotFound, notFound.Error())
+	case errors.As(err, &rateLimited):
+		RateLimited(c, rateLimited)
+	case errors.As(err, &validation):
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   &APIError{Code: http.StatusBadRequest, Message: validation.Error(), Field: validation.Field},
+		})
+	case errors.As(err, &conflict):
+		c.JSON(http.StatusConflict, APIResponse{
+			Success: false,
+			Data:    gin.H{"id": conflict.ExistingID},
+			Error:   &APIError{Code: http.StatusConflict, Message: conflict.Error()},
+		})
+	case errors.As(err, &unauthorized):
+		Error(c, http.StatusUnauthorized, unauthorized.Error())
+	case errors.As(err, &unavailable):
+		Error(c, http.StatusServiceUnavailable, unavailable.Error())
+	case errors.As(err, &provider):
+		Error(c, http.StatusBadGateway, "notification delivery failed")
+	default:
+		Error(c, http.StatusInternalServerError, "internal server error")
+	}
+}
+
+// NotModified sets etag as the response's ETag and reports whether the
+// request's If-None-Match already names it, in which case it sends a 304 with
+// no body and the caller must not write a response. Responses are marked
+// no-cache, so caches revalidate them on every use.
+func NotModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if !etagMatches(c.GetHeader("If-None-Match"), etag) {
+		return false
+	}
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// etagMatches reports whether an If-None-Match header value names etag,
+// comparing weakly as RFC 9110 requires for If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimited sends a 429 with the limiter's state in X-RateLimit-Limit,
+// X-RateLimit-Remaining, X-RateLimit-Reset (seconds until the window frees up),
+// and Retry-After, so clients can back off without parsing the message.
+func RateLimited(c *gin.Context, err *RateLimitError) {
+	resetSec := int(math.Ceil(err.Reset.Seconds()))
+	c.Header("X-RateLimit-Limit", strconv.Itoa(err.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(err.Remaining))
+	c.Header("X-RateLimit-Reset", strconv.Itoa(resetSec))
+	c.Header("Retry-After", strconv.Itoa(max(resetSec, 1)))
+
+	c.JSON(http.StatusTooManyRequests, APIResponse{
+		Success: false,
+		Error: &APIError{
+			Code:    http.StatusTooManyRequests,
+			Message: err.Error(),
+			Reason:  err.Reason,
+		},
+	})
+}
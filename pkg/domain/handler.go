@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler handles HTTP requests for sending domains.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new domain handler.
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// List handles GET /api/v1/admin/domains
+func (h *Handler) List(c *gin.Context) {
+	domains, err := h.service.List(c.Request.Context())
+	if err != nil {
+		slog.Error("list domains failed", "error", err)
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, gin.H{"domains": domains})
+}
+
+// Create handles POST /api/v1/admin/domains
+// The response lists the DNS records to publish before verifying.
+func (h *Handler) Create(c *gin.Context) {
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Error(c, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	d, err := h.service.Create(c.Request.Context(), &req)
+	if err != nil {
+		slog.Error("create domain failed", "error", err, "name", req.Name)
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusCreated, d)
+}
+
+// Get handles GET /api/v1/admin/domains/:id
+func (h *Handler) Get(c *gin.Context) {
+	d, err := h.service.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, d)
+}
+
+// Verify handles POST /api/v1/admin/domains/:id/verify
+func (h *Handler) Verify(c *gin.Context) {
+	d, err := h.service.Verify(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		slog.Error("verify domain failed", "error", err, "id", c.Param("id"))
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, d)
+}
+
+// Delete handles DELETE /api/v1/admin/domains/:id
+func (h *Handler) Delete(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		slog.Error("delete domain failed", "error", err, "id", id)
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, gin.H{"id": id, "status": "deleted"})
+}
+
+// RegisterRoutes registers domain routes to the given router group.
+func (h *Handler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.GET("/admin/domains", h.List)
+	rg.POST("/admin/domains", h.Create)
+	rg.GET("/admin/domains/:id", h.Get)
+	rg.POST("/admin/domains/:id/verify", h.Verify)
+	rg.DELETE("/admin/domains/:id", h.Delete)
+}
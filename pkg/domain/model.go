@@ -0,0 +1,58 @@
+// Package domain manages the sending domains email goes out from. It
+// registers a domain with the email provider, returns the DNS records (DKIM
+// and SPF) its owner must publish, and reports whether the provider has
+// verified them, so multi-tenant setups can onboard customer domains without
+// the provider's dashboard.
+package domain
+
+import (
+	"regexp"
+	"time"
+)
+
+// Status is the verification state of a domain or of one of its records.
+type Status string
+
+const (
+	StatusNotStarted       Status = "not_started"       // records not checked yet
+	StatusPending          Status = "pending"           // checking; the records may still be propagating
+	StatusVerified         Status = "verified"          // email can be sent from the domain
+	StatusFailed           Status = "failed"            // the records were not found; fix them and verify again
+	StatusTemporaryFailure Status = "temporary_failure" // the check could not complete; the provider retries it
+)
+
+// Record is a DNS record the domain's owner must publish.
+type Record struct {
+	Purpose  string `json:"purpose"` // DKIM or SPF
+	Type     string `json:"type"`    // CNAME, TXT, or MX
+	Name     string `json:"name"`    // fully qualified
+	Value    string `json:"value"`
+	Priority int    `json:"priority,omitempty"` // MX only
+	Status   Status `json:"status,omitempty"`
+}
+
+// Domain is a sending domain registered with the email provider.
+type Domain struct {
+	ID        string     `json:"id"` // the provider's ID; SES uses the name
+	Name      string     `json:"name"`
+	Provider  string     `json:"provider"`
+	Status    Status     `json:"status"`
+	Region    string     `json:"region,omitempty"`
+	Records   []Record   `json:"records,omitempty"` // empty in lists
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// CreateRequest is the body of POST /api/v1/admin/domains.
+type CreateRequest struct {
+	Name   string `json:"name" binding:"required,max=253"`
+	Region string `json:"region"` // empty uses domains.region
+}
+
+// nameRe matches a lowercase DNS name with at least two labels.
+var nameRe = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// IsValidName reports whether name can be registered as a sending domain: a
+// lowercase DNS name like "mail.example.com".
+func IsValidName(name string) bool {
+	return len(name) <= 253 && nameRe.MatchString(name)
+}
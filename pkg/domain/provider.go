@@ -0,0 +1,30 @@
+package domain
+
+import "context"
+
+// Provider registers and verifies sending domains with an email provider.
+// Implementations live in pkg/email/ (e.g., Resend, SES). They return
+// common.NotFoundError for unknown domains, common.ConflictError for names
+// already registered, and common.ValidationError when the provider rejects
+// the request.
+type Provider interface {
+	// Name identifies the provider, e.g. "resend".
+	Name() string
+
+	// Create registers name in region (empty uses the provider's default)
+	// and returns it with the records to publish.
+	Create(ctx context.Context, name, region string) (*Domain, error)
+
+	// Get returns the domain with its records and their current status.
+	Get(ctx context.Context, id string) (*Domain, error)
+
+	// List returns every registered domain, without records.
+	List(ctx context.Context) ([]Domain, error)
+
+	// Verify asks the provider to check the domain's records again and
+	// returns its status. The check may finish later; poll Get for the result.
+	Verify(ctx context.Context, id string) (*Domain, error)
+
+	// Delete removes the domain. Email can no longer be sent from it.
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+)
+
+// Service validates domain requests and passes them to the provider, which
+// keeps the domains: nothing is stored in the database.
+type Service struct {
+	provider Provider
+	region   string
+}
+
+// NewService creates a domain service. region is where new domains are
+// registered when a request names none; empty uses the provider's default.
+func NewService(provider Provider, region string) *Service {
+	return &Service{provider: provider, region: region}
+}
+
+// Create registers a sending domain and returns the DNS records to publish.
+func (s *Service) Create(ctx context.Context, req *CreateRequest) (*Domain, error) {
+	name := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(req.Name)), ".")
+	if !IsValidName(name) {
+		return nil, common.NewFieldError("name", fmt.Sprintf("name must be a domain name like mail.example.com, got %q", req.Name))
+	}
+	region := req.Region
+	if region == "" {
+		region = s.region
+	}
+
+	d, err := s.provider.Create(ctx, name, region)
+	if err != nil {
+		return nil, fmt.Errorf("creating domain %s: %w", name, err)
+	}
+	slog.Info("sending domain created", "provider", s.provider.Name(), "domain", d.Name, "id", d.ID, "region", d.Region)
+	return d, nil
+}
+
+// Get returns a domain with its records and verification status.
+func (s *Service) Get(ctx context.Context, id string) (*Domain, error) {
+	d, err := s.provider.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting domain %s: %w", id, err)
+	}
+	return d, nil
+}
+
+// List returns every domain registered with the provider.
+func (s *Service) List(ctx context.Context) ([]Domain, error) {
+	domains, err := s.provider.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing domains: %w", err)
+	}
+	return domains, nil
+}
+
+// Verify asks the provider to check a domain's records again.
+func (s *Service) Verify(ctx context.Context, id string) (*Domain, error) {
+	d, err := s.provider.Verify(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("verifying domain %s: %w", id, err)
+	}
+	slog.Info("sending domain verification requested", "provider", s.provider.Name(), "domain", d.Name, "id", d.ID, "status", d.Status)
+	return d, nil
+}
+
+// Delete removes a domain from the provider.
+func (s *Service) Delete(ctx context.Context, id string) error {
+	if err := s.provider.Delete(ctx, id); err != nil {
+		return fmt.Errorf("deleting domain %s: %w", id, err)
+	}
+	slog.Info("sending domain deleted", "provider", s.provider.Name(), "id", id)
+	return nil
+}
@@ -0,0 +1,79 @@
+package email
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+)
+
+var (
+	_ notification.BatchProvider = (*DryRunProvider)(nil)
+	_ notification.NamedProvider = (*DryRunProvider)(nil)
+)
+
+// DryRunProvider accepts every email without sending it, after an optional
+// simulated API latency. It lets the server, queue, and store be
+// load-tested without a provider account or real recipients.
+type DryRunProvider struct {
+	latency time.Duration
+}
+
+// NewDryRunProvider creates a provider that waits latency per call and sends nothing.
+func NewDryRunProvider(latency time.Duration) *DryRunProvider {
+	return &DryRunProvider{latency: latency}
+}
+
+// Channel returns the email channel identifier.
+func (p *DryRunProvider) Channel() notification.Channel {
+	return notification.ChannelEmail
+}
+
+// Name returns "dryrun".
+func (p *DryRunProvider) Name() string {
+	return "dryrun"
+}
+
+// Send waits the simulated latency and returns a made-up message ID.
+func (p *DryRunProvider) Send(ctx context.Context, msg *notification.Message) (string, error) {
+	if err := p.wait(ctx); err != nil {
+		return "", err
+	}
+	return dryRunID(), nil
+}
+
+// SendBatch waits the simulated latency once, like one batch API call, and
+// returns a made-up message ID per email.
+func (p *DryRunProvider) SendBatch(ctx context.Context, msgs []*notification.Message) ([]string, error) {
+	if err := p.wait(ctx); err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(msgs))
+	for i := range msgs {
+		ids[i] = dryRunID()
+	}
+	return ids, nil
+}
+
+func (p *DryRunProvider) wait(ctx context.Context) error {
+	if p.latency <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(p.latency)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// dryRunID returns a random message ID that cannot be mistaken for a real one.
+func dryRunID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "dryrun-" + hex.EncodeToString(b)
+}
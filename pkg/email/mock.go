@@ -0,0 +1,235 @@
+package email
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+	"github.com/badrkarrachai/notifly/pkg/notification"
+)
+
+var (
+	_ notification.MetadataProvider      = (*MockProvider)(nil)
+	_ notification.MetadataBatchProvider = (*MockProvider)(nil)
+	_ notification.NamedProvider         = (*MockProvider)(nil)
+)
+
+// Tags that script how MockProvider answers one send. They travel with the
+// request's tags, so an integration test picks each send's outcome:
+//
+//	mock_outcome        success, error_<status> (e.g. error_503, error_422),
+//	                    transport (no response), or hang (until the deadline)
+//	mock_latency_ms     the simulated API latency of this send
+//	mock_fail_attempts  fail the first N attempts with mock_outcome (default
+//	                    error_503), then succeed, to exercise retries
+//	mock_retry_after    the Retry-After, in seconds, of a retryable error,
+//	                    returned as a common.RetryAfterError
+const (
+	MockTagOutcome      = "mock_outcome"
+	MockTagLatencyMs    = "mock_latency_ms"
+	MockTagFailAttempts = "mock_fail_attempts"
+	MockTagRetryAfter   = "mock_retry_after"
+)
+
+// MockBehavior is how MockProvider answers sends whose tags script nothing.
+type MockBehavior struct {
+	Latency time.Duration
+
+	// FailureRate is the fraction (0–1) of sends that fail with
+	// FailureStatus, like an intermittently failing provider.
+	FailureRate   float64
+	FailureStatus int // default 503
+}
+
+// MockProvider sends nothing and answers each send the way its behavior and
+// the send's mock_* tags script: success, an HTTP error, a transport error,
+// or a hang, after a simulated latency. It lets integration tests drive
+// retries, failure classification, and reaper recovery end to end without a
+// provider account.
+type MockProvider struct {
+	behavior MockBehavior
+
+	mu       sync.Mutex
+	attempts map[string]int // attempts per scripted send, for mock_fail_attempts
+}
+
+// NewMockProvider creates a mock provider with the given default behavior.
+func NewMockProvider(behavior MockBehavior) *MockProvider {
+	if behavior.FailureStatus == 0 {
+		behavior.FailureStatus = http.StatusServiceUnavailable
+	}
+	return &MockProvider{behavior: behavior, attempts: make(map[string]int)}
+}
+
+// Channel returns the email channel identifier.
+func (p *MockProvider) Channel() notification.Channel {
+	return notification.ChannelEmail
+}
+
+// Name returns "mock".
+func (p *MockProvider) Name() string {
+	return "mock"
+}
+
+// Send answers msg as scripted.
+func (p *MockProvider) Send(ctx context.Context, msg *notification.Message) (string, error) {
+	id, _, err := p.SendWithMetadata(ctx, msg)
+	return id, err
+}
+
+// SendWithMetadata answers msg as scripted, with the status code of the
+// simulated response.
+func (p *MockProvider) SendWithMetadata(ctx context.Context, msg *notification.Message) (string, *notification.ProviderMetadata, error) {
+	latency, outcome := p.script(msg)
+	if err := p.wait(ctx, latency, outcome); err != nil {
+		return "", nil, err
+	}
+	metadata, err := mockResult(outcome, mockRetryAfter(msg))
+	if err != nil {
+		return "", metadata, err
+	}
+	return mockID(), metadata, nil
+}
+
+// SendBatch answers msgs as one call: after the longest scripted latency, it
+// fails as a whole with the first scripted failure, or succeeds.
+func (p *MockProvider) SendBatch(ctx context.Context, msgs []*notification.Message) ([]string, error) {
+	ids, _, err := p.SendBatchWithMetadata(ctx, msgs)
+	return ids, err
+}
+
+// SendBatchWithMetadata is SendBatch with the simulated response's metadata.
+func (p *MockProvider) SendBatchWithMetadata(ctx context.Context, msgs []*notification.Message) ([]string, *notification.ProviderMetadata, error) {
+	var (
+		latency    time.Duration
+		retryAfter time.Duration
+	)
+	outcome := "success"
+	for _, msg := range msgs {
+		msgLatency, msgOutcome := p.script(msg)
+		latency = max(latency, msgLatency)
+		retryAfter = max(retryAfter, mockRetryAfter(msg))
+		if outcome == "success" {
+			outcome = msgOutcome
+		}
+	}
+	if err := p.wait(ctx, latency, outcome); err != nil {
+		return nil, nil, err
+	}
+	metadata, err := mockResult(outcome, retryAfter)
+	if err != nil {
+		return nil, metadata, err
+	}
+	ids := make([]string, len(msgs))
+	for i := range msgs {
+		ids[i] = mockID()
+	}
+	return ids, metadata, nil
+}
+
+// script returns the latency and outcome of this attempt at msg, from its
+// tags, else the default behavior.
+func (p *MockProvider) script(msg *notification.Message) (time.Duration, string) {
+	latency := p.behavior.Latency
+	if ms, err := strconv.Atoi(msg.Tags[MockTagLatencyMs]); err == nil && ms >= 0 {
+		latency = time.Duration(ms) * time.Millisecond
+	}
+
+	outcome := msg.Tags[MockTagOutcome]
+	if failAttempts, err := strconv.Atoi(msg.Tags[MockTagFailAttempts]); err == nil {
+		if outcome == "" || outcome == "success" {
+			outcome = "error_" + strconv.Itoa(http.StatusServiceUnavailable)
+		}
+		if p.attempt(msg) > failAttempts {
+			outcome = "success"
+		}
+	}
+	if outcome == "" {
+		outcome = "success"
+		if p.behavior.FailureRate > 0 && rand.Float64() < p.behavior.FailureRate {
+			outcome = "error_" + strconv.Itoa(p.behavior.FailureStatus)
+		}
+	}
+	return latency, outcome
+}
+
+// mockRetryAfter returns the Retry-After msg's tags script, or 0.
+func mockRetryAfter(msg *notification.Message) time.Duration {
+	if seconds, err := strconv.Atoi(msg.Tags[MockTagRetryAfter]); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 0
+}
+
+// attempt counts an attempt at msg and returns its number, from 1. Attempts
+// are told apart by recipients and subject, so tests give each scripted send
+// its own recipient.
+func (p *MockProvider) attempt(msg *notification.Message) int {
+	key := strings.Join(msg.To, ",") + "\x00" + msg.Subject
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.attempts[key]++
+	return p.attempts[key]
+}
+
+// wait waits latency, or until ctx is done when outcome is hang.
+func (p *MockProvider) wait(ctx context.Context, latency time.Duration, outcome string) error {
+	if outcome == "hang" {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if latency <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// mockResult returns the response metadata and error of outcome. Errors are
+// classified as Resend's are: 4xx about the message are permanent, the rest
+// retryable, and wait retryAfter when it is set.
+func mockResult(outcome string, retryAfter time.Duration) (*notification.ProviderMetadata, error) {
+	switch {
+	case outcome == "success":
+		return &notification.ProviderMetadata{StatusCode: http.StatusOK, Attempts: 1}, nil
+	case outcome == "transport":
+		return nil, errors.New("mock: executing request: connection reset by peer")
+	case strings.HasPrefix(outcome, "error_"):
+		status, err := strconv.Atoi(strings.TrimPrefix(outcome, "error_"))
+		if err != nil || status < 400 || status > 599 {
+			break
+		}
+		metadata := &notification.ProviderMetadata{StatusCode: status, ErrorCode: "mock_error", Attempts: 1}
+		err = common.NewHTTPStatusError(status, fmt.Errorf("mock: scripted %d %s", status, http.StatusText(status)))
+		if isPermanentStatus(status) {
+			return metadata, common.NewPermanentError(err)
+		}
+		if retryAfter > 0 {
+			return metadata, common.NewRetryAfterError(err, retryAfter)
+		}
+		return metadata, err
+	}
+	return nil, common.NewPermanentError(fmt.Errorf("mock: unknown %s %q", MockTagOutcome, outcome))
+}
+
+// mockID returns a random message ID that cannot be mistaken for a real one.
+func mockID() string {
+	b := make([]byte, 8)
+	_, _ = crand.Read(b)
+	return "mock-" + hex.EncodeToString(b)
+}
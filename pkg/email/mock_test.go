@@ -0,0 +1,84 @@
+package email_test
+
+import (
+	"context"
+	"maps"
+	"strconv"
+	"testing"
+
+	"github.com/badrkarrachai/notifly/pkg/email"
+	"github.com/badrkarrachai/notifly/pkg/notification"
+	"github.com/badrkarrachai/notifly/pkg/notification/providertest"
+)
+
+func TestMockProvider(t *testing.T) {
+	providertest.Run(t, func(t *testing.T) (notification.Provider, *providertest.Fake) {
+		fake := providertest.NewResendAPI(t)
+		return &scriptedMock{mock: email.NewMockProvider(email.MockBehavior{}), fake: fake}, fake
+	})
+}
+
+var (
+	_ notification.MetadataProvider = (*scriptedMock)(nil)
+	_ notification.BatchProvider    = (*scriptedMock)(nil)
+)
+
+// scriptedMock drives a MockProvider from the fake, which stands in for the
+// API the mock pretends to call: each send takes the fake's next answer as its
+// mock_* tags, and a success returns the IDs the fake issued.
+type scriptedMock struct {
+	mock *email.MockProvider
+	fake *providertest.Fake
+}
+
+func (s *scriptedMock) Channel() notification.Channel {
+	return s.mock.Channel()
+}
+
+func (s *scriptedMock) Send(ctx context.Context, msg *notification.Message) (string, error) {
+	id, _, err := s.SendWithMetadata(ctx, msg)
+	return id, err
+}
+
+func (s *scriptedMock) SendWithMetadata(ctx context.Context, msg *notification.Message) (string, *notification.ProviderMetadata, error) {
+	resp, ids, err := s.fake.Next(1)
+	_, metadata, err := s.mock.SendWithMetadata(ctx, scripted(msg, resp, err))
+	if err != nil {
+		return "", metadata, err
+	}
+	return ids[0], metadata, nil
+}
+
+func (s *scriptedMock) SendBatch(ctx context.Context, msgs []*notification.Message) ([]string, error) {
+	resp, ids, err := s.fake.Next(len(msgs))
+	batch := make([]*notification.Message, len(msgs))
+	for i, msg := range msgs {
+		batch[i] = scripted(msg, resp, err)
+	}
+	if _, err := s.mock.SendBatch(ctx, batch); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// scripted returns a copy of msg with the mock_* tags for the fake's answer:
+// closed (err), hang, an error status with its Retry-After, or success.
+func scripted(msg *notification.Message, resp providertest.Response, err error) *notification.Message {
+	c := *msg
+	c.Tags = maps.Clone(msg.Tags)
+	if c.Tags == nil {
+		c.Tags = make(map[string]string)
+	}
+	switch {
+	case err != nil:
+		c.Tags[email.MockTagOutcome] = "transport"
+	case resp.Hang:
+		c.Tags[email.MockTagOutcome] = "hang"
+	case resp.Status != 0:
+		c.Tags[email.MockTagOutcome] = "error_" + strconv.Itoa(resp.Status)
+		c.Tags[email.MockTagRetryAfter] = resp.RetryAfter
+	default:
+		c.Tags[email.MockTagOutcome] = "success"
+	}
+	return &c
+}
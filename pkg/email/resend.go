@@ -0,0 +1,432 @@
+// Package email implements notification.Provider for the email channel.
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+	"github.com/badrkarrachai/notifly/pkg/notification"
+)
+
+var (
+	_ notification.MetadataProvider      = (*ResendProvider)(nil)
+	_ notification.MetadataBatchProvider = (*ResendProvider)(nil)
+	_ notification.NamedProvider         = (*ResendProvider)(nil)
+)
+
+// ResendProvider sends emails using the Resend API.
+type ResendProvider struct {
+	mu          sync.RWMutex
+	apiKey      string
+	fromAddress string
+	fromName    string
+	baseURL     string
+	httpClient  *http.Client
+}
+
+// NewResendProvider creates a new Resend email provider.
+func NewResendProvider(apiKey, fromAddress, fromName string) *ResendProvider {
+	return &ResendProvider{
+		apiKey:      apiKey,
+		fromAddress: fromAddress,
+		fromName:    fromName,
+		baseURL:     resendAPIURL,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SetBaseURL points the provider at another Resend-compatible API, such as a
+// providertest fake, instead of https://api.resend.com. Call it before the
+// provider is used.
+func (p *ResendProvider) SetBaseURL(baseURL string) {
+	p.baseURL = strings.TrimRight(baseURL, "/")
+}
+
+// SetAPIKey rotates the Resend API key. Sends already in flight keep the old key.
+func (p *ResendProvider) SetAPIKey(apiKey string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.apiKey = apiKey
+}
+
+func (p *ResendProvider) key() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.apiKey
+}
+
+// Channel returns the email channel identifier.
+func (p *ResendProvider) Channel() notification.Channel {
+	return notification.ChannelEmail
+}
+
+// Name returns "resend".
+func (p *ResendProvider) Name() string {
+	return "resend"
+}
+
+// Resend API base URL and endpoints.
+const (
+	resendAPIURL     = "https://api.resend.com"
+	resendEmailsPath = "/emails"
+	resendBatchPath  = "/emails/batch"
+)
+
+// MaxBatchSize is the most emails Resend accepts in one batch call.
+const MaxBatchSize = 100
+
+// Send delivers an email via the Resend API and returns the message ID.
+// Transport errors, 429s, and 5xx responses are retried up to maxAttempts
+// times with jittered exponential backoff, waiting for Retry-After when given.
+// A Retry-After too long to wait in the call, or left on the last failure,
+// is returned as a common.RetryAfterError for the task retry to honor.
+func (p *ResendProvider) Send(ctx context.Context, msg *notification.Message) (string, error) {
+	id, _, err := p.SendWithMetadata(ctx, msg)
+	return id, err
+}
+
+// SendWithMetadata is Send, also returning the status, error name, and
+// rate-limit headers of Resend's last response.
+func (p *ResendProvider) SendWithMetadata(ctx context.Context, msg *notification.Message) (string, *notification.ProviderMetadata, error) {
+	jsonData, err := json.Marshal(p.payload(msg))
+	if err != nil {
+		return "", nil, fmt.Errorf("marshaling email payload: %w", err)
+	}
+
+	respBody, metadata, err := p.postWithRetry(ctx, p.baseURL+resendEmailsPath, jsonData)
+	if err != nil {
+		return "", metadata, err
+	}
+
+	var successResp struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(respBody, &successResp); err != nil {
+		return "", metadata, fmt.Errorf("parsing resend response: %w", err)
+	}
+
+	return successResp.ID, metadata, nil
+}
+
+// SendBatch delivers up to MaxBatchSize emails with one call to Resend's batch
+// endpoint and returns their message IDs in order. Resend validates the whole
+// batch, so one invalid message rejects all of them (a permanent error).
+func (p *ResendProvider) SendBatch(ctx context.Context, msgs []*notification.Message) ([]string, error) {
+	ids, _, err := p.SendBatchWithMetadata(ctx, msgs)
+	return ids, err
+}
+
+// SendBatchWithMetadata is SendBatch, also returning the metadata of
+// Resend's last response.
+func (p *ResendProvider) SendBatchWithMetadata(ctx context.Context, msgs []*notification.Message) ([]string, *notification.ProviderMetadata, error) {
+	if len(msgs) > MaxBatchSize {
+		return nil, nil, fmt.Errorf("resend batch of %d exceeds the limit of %d", len(msgs), MaxBatchSize)
+	}
+
+	payloads := make([]map[string]any, len(msgs))
+	for i, msg := range msgs {
+		payloads[i] = p.payload(msg)
+	}
+	jsonData, err := json.Marshal(payloads)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshaling email batch payload: %w", err)
+	}
+
+	respBody, metadata, err := p.postWithRetry(ctx, p.baseURL+resendBatchPath, jsonData)
+	if err != nil {
+		return nil, metadata, err
+	}
+
+	var successResp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &successResp); err != nil {
+		return nil, metadata, fmt.Errorf("parsing resend batch response: %w", err)
+	}
+	if len(successResp.Data) != len(msgs) {
+		return nil, metadata, fmt.Errorf("resend batch returned %d ids for %d emails", len(successResp.Data), len(msgs))
+	}
+
+	ids := make([]string, len(msgs))
+	for i, d := range successResp.Data {
+		ids[i] = d.ID
+	}
+	return ids, metadata, nil
+}
+
+// payload builds the Resend request body for one message, sent from msg.From
+// or, when it is empty, the provider's default address.
+func (p *ResendProvider) payload(msg *notification.Message) map[string]any {
+	from := msg.From
+	if from == "" {
+		from = p.fromAddress
+		if p.fromName != "" {
+			from = fmt.Sprintf("%s <%s>", p.fromName, p.fromAddress)
+		}
+	}
+
+	payload := map[string]any{
+		"from":    from,
+		"to":      msg.To,
+		"subject": msg.Subject,
+		"html":    msg.HTML,
+	}
+
+	// Include plain-text version if available
+	if msg.Text != "" {
+		payload["text"] = msg.Text
+	}
+
+	if len(msg.CC) > 0 {
+		payload["cc"] = msg.CC
+	}
+	if len(msg.BCC) > 0 {
+		payload["bcc"] = msg.BCC
+	}
+	if msg.ReplyTo != "" {
+		payload["reply_to"] = msg.ReplyTo
+	}
+	if len(msg.Headers) > 0 {
+		payload["headers"] = msg.Headers
+	}
+	if len(msg.Tags) > 0 {
+		payload["tags"] = resendTags(msg.Tags)
+	}
+	if len(msg.Attachments) > 0 {
+		payload["attachments"] = resendAttachments(msg.Attachments)
+	}
+	return payload
+}
+
+// postWithRetry posts body to url, retrying transient failures, and returns
+// the successful response body. The metadata, returned on failure too, is
+// that of the last response, with the number of attempts made.
+func (p *ResendProvider) postWithRetry(ctx context.Context, url string, body []byte) ([]byte, *notification.ProviderMetadata, error) {
+	var lastErr error
+	metadata := &notification.ProviderMetadata{}
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		respBody, retryAfter, respMetadata, err := p.post(ctx, url, body)
+		if respMetadata != nil {
+			metadata = respMetadata
+		}
+		metadata.Attempts = attempt + 1
+		if err == nil {
+			return respBody, metadata, nil
+		}
+		lastErr = err
+
+		var retryErr *retryableError
+		if !errors.As(err, &retryErr) {
+			break
+		}
+		if retryAfter > maxRetryWait || (retryAfter > 0 && attempt == maxAttempts-1) {
+			// Resend said when to come back: leave it to the task retry
+			return nil, metadata, common.NewRetryAfterError(retryErr.err, retryAfter)
+		}
+		if attempt == maxAttempts-1 {
+			break
+		}
+
+		wait := backoff(attempt)
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return nil, metadata, fmt.Errorf("%w (retry abandoned: %v)", lastErr, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+
+	var retryErr *retryableError
+	if errors.As(lastErr, &retryErr) {
+		return nil, metadata, retryErr.err
+	}
+	return nil, metadata, lastErr
+}
+
+// In-call retry policy. The task-level retry is tens of seconds away, so
+// transient failures (transport errors, 429, 5xx) get a few quick retries here
+// first, within the task's deadline.
+const (
+	maxAttempts  = 3
+	baseBackoff  = 500 * time.Millisecond
+	maxRetryWait = 5 * time.Second
+)
+
+// retryableError marks a failure Send may retry within the same call.
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// post makes one request to the Resend API and returns the response body and
+// its metadata (nil when no response arrived). Retryable failures are
+// returned as *retryableError along with any Retry-After the response asked for.
+func (p *ResendProvider) post(ctx context.Context, url string, body []byte) ([]byte, time.Duration, *notification.ProviderMetadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.key())
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		err = fmt.Errorf("executing request: %w", err)
+		if ctx.Err() != nil {
+			return nil, 0, nil, err // the task deadline passed; retrying cannot help
+		}
+		return nil, 0, nil, &retryableError{err: err}
+	}
+	defer resp.Body.Close()
+
+	metadata := &notification.ProviderMetadata{
+		StatusCode: resp.StatusCode,
+		RateLimit:  rateLimitHeaders(resp.Header),
+	}
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20)) // 1 MB max
+	if err != nil {
+		return nil, 0, metadata, &retryableError{err: fmt.Errorf("reading response: %w", err)}
+	}
+
+	if resp.StatusCode >= 400 {
+		var errResp struct {
+			Message    string `json:"message"`
+			Name       string `json:"name"`
+			StatusCode int    `json:"statusCode"`
+		}
+		_ = json.Unmarshal(respBody, &errResp)
+		metadata.ErrorCode = errResp.Name
+
+		msg := errResp.Message
+		if msg == "" {
+			msg = fmt.Sprintf("resend API error: status %d", resp.StatusCode)
+		}
+		var err error = common.NewHTTPStatusError(resp.StatusCode, fmt.Errorf("resend: %s", msg))
+		switch {
+		case isPermanentStatus(resp.StatusCode):
+			return nil, 0, metadata, common.NewPermanentError(err)
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			return nil, parseRetryAfter(resp.Header.Get("Retry-After")), metadata, &retryableError{err: err}
+		}
+		return nil, 0, metadata, err
+	}
+
+	return respBody, 0, metadata, nil
+}
+
+// rateLimitHeaderNames are the response headers Resend reports its rate
+// limit in.
+var rateLimitHeaderNames = []string{"Ratelimit-Limit", "Ratelimit-Remaining", "Ratelimit-Reset", "Retry-After"}
+
+// rateLimitHeaders returns the rate-limit headers present in h, keyed by
+// their lower-case names, or nil if there are none.
+func rateLimitHeaders(h http.Header) map[string]string {
+	var out map[string]string
+	for _, name := range rateLimitHeaderNames {
+		if v := h.Get(name); v != "" {
+			if out == nil {
+				out = make(map[string]string)
+			}
+			out[strings.ToLower(name)] = v
+		}
+	}
+	return out
+}
+
+// backoff returns the wait before retry attempt+1: exponential from
+// baseBackoff with full jitter, capped at maxRetryWait.
+func backoff(attempt int) time.Duration {
+	ceiling := min(baseBackoff<<attempt, maxRetryWait)
+	return time.Duration(rand.Int64N(int64(ceiling))) + time.Millisecond
+}
+
+// parseRetryAfter reads a Retry-After header in either delay-seconds or
+// HTTP-date form. It returns 0 when the header is absent or unparseable.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// isPermanentStatus reports whether a Resend error status means the same request
+// will keep failing: a 4xx about the message itself (400 malformed, 422
+// invalid recipient or payload). Auth errors (401/403) are treated as transient
+// so sends recover once the API key is fixed; timeouts (408) and rate limits
+// (429) are transient, as are all 5xx.
+func isPermanentStatus(status int) bool {
+	if status < 400 || status >= 500 {
+		return false
+	}
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return true
+}
+
+// resendTag is the name/value pair format Resend expects for tags.
+type resendTag struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// resendTags converts a tag map into Resend's array form, sorted for stable payloads.
+func resendTags(tags map[string]string) []resendTag {
+	out := make([]resendTag, 0, len(tags))
+	for name, value := range tags {
+		out = append(out, resendTag{Name: name, Value: value})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// resendAttachment is the attachment format Resend expects: the content
+// base64-encoded.
+type resendAttachment struct {
+	Filename    string `json:"filename"`
+	Content     string `json:"content"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// resendAttachments converts attachments into Resend's form.
+func resendAttachments(attachments []notification.Attachment) []resendAttachment {
+	out := make([]resendAttachment, len(attachments))
+	for i, a := range attachments {
+		out[i] = resendAttachment{
+			Filename:    a.Filename,
+			Content:     base64.StdEncoding.EncodeToString(a.Content),
+			ContentType: a.ContentType,
+		}
+	}
+	return out
+}
@@ -0,0 +1,213 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+	"github.com/badrkarrachai/notifly/pkg/domain"
+)
+
+var _ domain.Provider = (*ResendDomains)(nil)
+
+// resendDomainsURL is the Resend domains API endpoint.
+const resendDomainsURL = "https://api.resend.com/domains"
+
+// ResendDomains manages sending domains with the Resend domains API. It needs
+// a full-access API key; a sending-only key is refused.
+type ResendDomains struct {
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewResendDomains creates a Resend domain manager.
+func NewResendDomains(apiKey string) *ResendDomains {
+	return &ResendDomains{
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name returns "resend".
+func (p *ResendDomains) Name() string {
+	return "resend"
+}
+
+// resendDomain is a domain as the Resend API returns it.
+type resendDomain struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Region    string `json:"region"`
+	CreatedAt string `json:"created_at"`
+	Records   []struct {
+		Record   string `json:"record"`
+		Name     string `json:"name"`
+		Type     string `json:"type"`
+		Value    string `json:"value"`
+		Priority int    `json:"priority"`
+		Status   string `json:"status"`
+	} `json:"records"`
+}
+
+// Create registers name with Resend, in region when set.
+func (p *ResendDomains) Create(ctx context.Context, name, region string) (*domain.Domain, error) {
+	payload := map[string]string{"name": name}
+	if region != "" {
+		payload["region"] = region
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling domain payload: %w", err)
+	}
+
+	var d resendDomain
+	if err := p.do(ctx, http.MethodPost, resendDomainsURL, body, name, &d); err != nil {
+		return nil, err
+	}
+	return p.convert(d), nil
+}
+
+// Get returns the domain with its records.
+func (p *ResendDomains) Get(ctx context.Context, id string) (*domain.Domain, error) {
+	var d resendDomain
+	if err := p.do(ctx, http.MethodGet, resendDomainsURL+"/"+url.PathEscape(id), nil, id, &d); err != nil {
+		return nil, err
+	}
+	return p.convert(d), nil
+}
+
+// List returns every domain on the Resend account.
+func (p *ResendDomains) List(ctx context.Context) ([]domain.Domain, error) {
+	var resp struct {
+		Data []resendDomain `json:"data"`
+	}
+	if err := p.do(ctx, http.MethodGet, resendDomainsURL, nil, "", &resp); err != nil {
+		return nil, err
+	}
+	domains := make([]domain.Domain, len(resp.Data))
+	for i, d := range resp.Data {
+		domains[i] = *p.convert(d)
+	}
+	return domains, nil
+}
+
+// Verify starts Resend's asynchronous check of the domain's records and
+// returns the domain as it stands.
+func (p *ResendDomains) Verify(ctx context.Context, id string) (*domain.Domain, error) {
+	if err := p.do(ctx, http.MethodPost, resendDomainsURL+"/"+url.PathEscape(id)+"/verify", nil, id, nil); err != nil {
+		return nil, err
+	}
+	return p.Get(ctx, id)
+}
+
+// Delete removes the domain from the Resend account.
+func (p *ResendDomains) Delete(ctx context.Context, id string) error {
+	return p.do(ctx, http.MethodDelete, resendDomainsURL+"/"+url.PathEscape(id), nil, id, nil)
+}
+
+// convert maps a Resend domain to a domain.Domain. Resend gives record names
+// relative to the domain; they are returned fully qualified.
+func (p *ResendDomains) convert(d resendDomain) *domain.Domain {
+	out := &domain.Domain{
+		ID:        d.ID,
+		Name:      d.Name,
+		Provider:  p.Name(),
+		Status:    domain.Status(d.Status),
+		Region:    d.Region,
+		CreatedAt: parseResendTime(d.CreatedAt),
+	}
+	for _, r := range d.Records {
+		name := r.Name
+		if name == "" || name == "@" {
+			name = d.Name
+		} else if name != d.Name && !strings.HasSuffix(name, "."+d.Name) {
+			name += "." + d.Name
+		}
+		out.Records = append(out.Records, domain.Record{
+			Purpose:  r.Record,
+			Type:     r.Type,
+			Name:     name,
+			Value:    r.Value,
+			Priority: r.Priority,
+			Status:   domain.Status(r.Status),
+		})
+	}
+	return out
+}
+
+// parseResendTime parses a Resend timestamp, which has come in both RFC 3339
+// and Postgres form, or returns nil.
+func parseResendTime(s string) *time.Time {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999-07"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// do makes one request to the Resend domains API and decodes a successful
+// response into out, when not nil. id names the domain in a not-found error.
+func (p *ResendDomains) do(ctx context.Context, method, endpoint string, body []byte, id string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return common.NewProviderError(p.Name(), fmt.Sprintf("executing request: %v", err))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20)) // 1 MB max
+	if err != nil {
+		return common.NewProviderError(p.Name(), fmt.Sprintf("reading response: %v", err))
+	}
+
+	if resp.StatusCode >= 400 {
+		var errResp struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(respBody, &errResp)
+		msg := errResp.Message
+		if msg == "" {
+			msg = fmt.Sprintf("status %d", resp.StatusCode)
+		}
+		return domainAPIError(p.Name(), resp.StatusCode, id, msg)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("parsing resend domain response: %w", err)
+	}
+	return nil
+}
+
+// domainAPIError maps a failed domains API response to the common errors the
+// admin API turns into status codes: 404 to not found, 409 to conflict, other
+// 4xx except auth and rate limits to validation, and the rest (including a
+// key without domain access) to a provider error.
+func domainAPIError(provider string, status int, id, msg string) error {
+	switch {
+	case status == http.StatusNotFound && id != "":
+		return common.NewNotFoundError("domain", id)
+	case status == http.StatusConflict:
+		return common.NewConflictError(provider+": "+msg, id)
+	case isPermanentStatus(status):
+		return common.NewValidationError(provider + ": " + msg)
+	}
+	return common.NewProviderError(provider, fmt.Sprintf("%s (status %d)", msg, status))
+}
@@ -0,0 +1,18 @@
+package email_test
+
+import (
+	"testing"
+
+	"github.com/badrkarrachai/notifly/pkg/email"
+	"github.com/badrkarrachai/notifly/pkg/notification"
+	"github.com/badrkarrachai/notifly/pkg/notification/providertest"
+)
+
+func TestResendProvider(t *testing.T) {
+	providertest.Run(t, func(t *testing.T) (notification.Provider, *providertest.Fake) {
+		fake := providertest.NewResendAPI(t)
+		p := email.NewResendProvider("re_test", "noreply@example.com", "Example")
+		p.SetBaseURL(fake.URL)
+		return p, fake
+	})
+}
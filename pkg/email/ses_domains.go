@@ -0,0 +1,319 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+	"github.com/badrkarrachai/notifly/pkg/domain"
+)
+
+var _ domain.Provider = (*SESDomains)(nil)
+
+// sesMailFromSubdomain is the custom MAIL FROM subdomain SES domains are
+// given, so their SPF record lives on it rather than the domain itself.
+const sesMailFromSubdomain = "send"
+
+// SESDomains manages sending domains as Amazon SES v2 email identities in one
+// AWS region. Requests are signed with AWS Signature Version 4.
+type SESDomains struct {
+	region       string
+	accessKeyID  string
+	secretKey    string
+	sessionToken string
+	endpoint     string
+	httpClient   *http.Client
+}
+
+// NewSESDomains creates an SES domain manager for region. sessionToken is
+// only needed with temporary credentials.
+func NewSESDomains(region, accessKeyID, secretKey, sessionToken string) *SESDomains {
+	return &SESDomains{
+		region:       region,
+		accessKeyID:  accessKeyID,
+		secretKey:    secretKey,
+		sessionToken: sessionToken,
+		endpoint:     "https://email." + region + ".amazonaws.com",
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name returns "ses".
+func (p *SESDomains) Name() string {
+	return "ses"
+}
+
+// sesIdentity is an email identity as GetEmailIdentity and
+// CreateEmailIdentity return it.
+type sesIdentity struct {
+	VerificationStatus string `json:"VerificationStatus"`
+	DkimAttributes     struct {
+		Status string   `json:"Status"`
+		Tokens []string `json:"Tokens"`
+	} `json:"DkimAttributes"`
+	MailFromAttributes struct {
+		MailFromDomain       string `json:"MailFromDomain"`
+		MailFromDomainStatus string `json:"MailFromDomainStatus"`
+	} `json:"MailFromAttributes"`
+}
+
+// Create registers name as a domain identity with Easy DKIM and a custom
+// MAIL FROM subdomain. SES identities are regional, so region must be empty
+// or this manager's region.
+func (p *SESDomains) Create(ctx context.Context, name, region string) (*domain.Domain, error) {
+	if region != "" && region != p.region {
+		return nil, common.NewFieldError("region", fmt.Sprintf("region must be %s, the region domains.ses is configured for, got %q", p.region, region))
+	}
+
+	body, _ := json.Marshal(map[string]string{"EmailIdentity": name})
+	if err := p.do(ctx, http.MethodPost, "/v2/email/identities", nil, body, name, nil); err != nil {
+		return nil, err
+	}
+
+	mailFrom, _ := json.Marshal(map[string]string{
+		"MailFromDomain":      sesMailFromSubdomain + "." + name,
+		"BehaviorOnMxFailure": "USE_DEFAULT_VALUE",
+	})
+	if err := p.do(ctx, http.MethodPut, "/v2/email/identities/"+url.PathEscape(name)+"/mail-from", nil, mailFrom, name, nil); err != nil {
+		return nil, fmt.Errorf("setting MAIL FROM domain: %w", err)
+	}
+	return p.Get(ctx, name)
+}
+
+// Get returns the domain identity with its DKIM and MAIL FROM records.
+func (p *SESDomains) Get(ctx context.Context, id string) (*domain.Domain, error) {
+	var identity sesIdentity
+	if err := p.do(ctx, http.MethodGet, "/v2/email/identities/"+url.PathEscape(id), nil, nil, id, &identity); err != nil {
+		return nil, err
+	}
+	return p.convert(id, identity), nil
+}
+
+// List returns every domain identity in the region; email address identities
+// are left out.
+func (p *SESDomains) List(ctx context.Context) ([]domain.Domain, error) {
+	var domains []domain.Domain
+	query := url.Values{"PageSize": {"1000"}}
+	for {
+		var resp struct {
+			EmailIdentities []struct {
+				IdentityType       string `json:"IdentityType"`
+				IdentityName       string `json:"IdentityName"`
+				VerificationStatus string `json:"VerificationStatus"`
+			} `json:"EmailIdentities"`
+			NextToken string `json:"NextToken"`
+		}
+		if err := p.do(ctx, http.MethodGet, "/v2/email/identities", query, nil, "", &resp); err != nil {
+			return nil, err
+		}
+		for _, identity := range resp.EmailIdentities {
+			if identity.IdentityType != "DOMAIN" {
+				continue
+			}
+			domains = append(domains, domain.Domain{
+				ID:       identity.IdentityName,
+				Name:     identity.IdentityName,
+				Provider: p.Name(),
+				Status:   sesStatus(identity.VerificationStatus),
+				Region:   p.region,
+			})
+		}
+		if resp.NextToken == "" {
+			return domains, nil
+		}
+		query.Set("NextToken", resp.NextToken)
+	}
+}
+
+// Verify returns the domain's status. SES has no call to recheck on demand:
+// it checks pending records on its own, for up to 72 hours.
+func (p *SESDomains) Verify(ctx context.Context, id string) (*domain.Domain, error) {
+	return p.Get(ctx, id)
+}
+
+// Delete removes the domain identity.
+func (p *SESDomains) Delete(ctx context.Context, id string) error {
+	return p.do(ctx, http.MethodDelete, "/v2/email/identities/"+url.PathEscape(id), nil, nil, id, nil)
+}
+
+// convert maps an SES identity to a domain.Domain, with the three Easy DKIM
+// CNAMEs and, once set, the MAIL FROM subdomain's MX and SPF records.
+func (p *SESDomains) convert(name string, identity sesIdentity) *domain.Domain {
+	d := &domain.Domain{
+		ID:       name,
+		Name:     name,
+		Provider: p.Name(),
+		Status:   sesStatus(identity.VerificationStatus),
+		Region:   p.region,
+	}
+	dkimStatus := sesStatus(identity.DkimAttributes.Status)
+	for _, token := range identity.DkimAttributes.Tokens {
+		d.Records = append(d.Records, domain.Record{
+			Purpose: "DKIM",
+			Type:    "CNAME",
+			Name:    token + "._domainkey." + name,
+			Value:   token + ".dkim.amazonses.com",
+			Status:  dkimStatus,
+		})
+	}
+	if mailFrom := identity.MailFromAttributes.MailFromDomain; mailFrom != "" {
+		mailFromStatus := sesStatus(identity.MailFromAttributes.MailFromDomainStatus)
+		d.Records = append(d.Records,
+			domain.Record{
+				Purpose:  "SPF",
+				Type:     "MX",
+				Name:     mailFrom,
+				Value:    "feedback-smtp." + p.region + ".amazonses.com",
+				Priority: 10,
+				Status:   mailFromStatus,
+			},
+			domain.Record{
+				Purpose: "SPF",
+				Type:    "TXT",
+				Name:    mailFrom,
+				Value:   "v=spf1 include:amazonses.com ~all",
+				Status:  mailFromStatus,
+			},
+		)
+	}
+	return d
+}
+
+// sesStatus maps an SES verification status (PENDING, SUCCESS, FAILED,
+// TEMPORARY_FAILURE, NOT_STARTED) to a domain.Status.
+func sesStatus(s string) domain.Status {
+	if s == "SUCCESS" {
+		return domain.StatusVerified
+	}
+	if s == "" {
+		return domain.StatusNotStarted
+	}
+	return domain.Status(strings.ToLower(s))
+}
+
+// do makes one signed request to the SES v2 API and decodes a successful
+// response into out, when not nil. id names the domain in a not-found error.
+func (p *SESDomains) do(ctx context.Context, method, path string, query url.Values, body []byte, id string, out any) error {
+	endpoint := p.endpoint + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	p.sign(req, body, time.Now())
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return common.NewProviderError(p.Name(), fmt.Sprintf("executing request: %v", err))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20)) // 1 MB max
+	if err != nil {
+		return common.NewProviderError(p.Name(), fmt.Sprintf("reading response: %v", err))
+	}
+
+	if resp.StatusCode >= 400 {
+		var errResp struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(respBody, &errResp)
+		// e.g. "AlreadyExistsException:http://internal.amazon.com/..."
+		errType, _, _ := strings.Cut(resp.Header.Get("X-Amzn-ErrorType"), ":")
+		msg := errResp.Message
+		if msg == "" {
+			msg = errType
+		}
+		if msg == "" {
+			msg = fmt.Sprintf("status %d", resp.StatusCode)
+		}
+		status := resp.StatusCode
+		if errType == "AlreadyExistsException" {
+			status = http.StatusConflict // SES answers 400
+		}
+		return domainAPIError(p.Name(), status, id, msg)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("parsing ses response: %w", err)
+	}
+	return nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req.
+func (p *SESDomains) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if p.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", p.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	// url.Values.Encode sorts by key, as the canonical query string must be,
+	// but escapes spaces as "+" where SigV4 wants "%20"
+	canonicalQuery := strings.ReplaceAll(req.URL.Query().Encode(), "+", "%20")
+
+	canonicalRequest := strings.Join([]string{req.Method, path, canonicalQuery, canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+	scope := date + "/" + p.region + "/ses/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+p.secretKey), date)
+	key = hmacSHA256(key, p.region)
+	key = hmacSHA256(key, "ses")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", p.accessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
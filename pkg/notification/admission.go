@@ -0,0 +1,61 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+)
+
+// admission holds the checks a send must pass before its log is created:
+// bounce suppression and the per-recipient rate limit. The service runs them
+// on every request, and follow-ups on every reminder.
+type admission struct {
+	store       NotificationStore
+	rateLimiter RecipientRateLimiter // nil disables per-recipient limits
+
+	suppressBounced     atomic.Bool
+	rateLimitFailClosed atomic.Bool
+}
+
+// check returns nil when a send of notifType on channel may go to every
+// recipient, counting it against their rate limits. A suppressed recipient is
+// a validation error and an exhausted limit a rate limit error; when the rate
+// limiter fails closed, its failure is an unavailable error.
+func (a *admission) check(ctx context.Context, recipients []string, channel Channel, notifType NotificationType) error {
+	// Skip recipients whose mail has bounced before — resending hurts sender reputation
+	if a.suppressBounced.Load() {
+		for _, to := range recipients {
+			bounced, err := a.store.HasBounced(ctx, to)
+			if err != nil {
+				slog.Error("bounce suppression check failed, proceeding", "recipient", to, "error", err)
+			} else if bounced {
+				return common.NewValidationError(fmt.Sprintf("recipient suppressed after a bounce: %s", to))
+			}
+		}
+	}
+
+	// Check per-recipient rate limit
+	if a.rateLimiter != nil {
+		for _, to := range recipients {
+			result, err := a.rateLimiter.Allow(ctx, to, channel, notifType)
+			if err != nil {
+				if a.rateLimitFailClosed.Load() {
+					slog.Error("rate limit check failed, rejecting send", "recipient", to, "error", err)
+					return common.NewUnavailableError("rate limiter unavailable, try again later")
+				}
+				// Fail open — don't block the request when Redis is down
+				slog.Error("rate limit check failed, proceeding without limit", "recipient", to, "error", err)
+			} else if !result.Allowed {
+				return common.NewRateLimitError(
+					common.ReasonRecipientRateLimited,
+					fmt.Sprintf("rate limit exceeded for recipient: %s", to),
+					result.Limit, result.Remaining, result.Reset,
+				)
+			}
+		}
+	}
+	return nil
+}
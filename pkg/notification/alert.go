@@ -0,0 +1,332 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcome is a send result counted toward the alerting rates.
+type Outcome string
+
+const (
+	OutcomeSent    Outcome = "sent"    // the provider accepted the message
+	OutcomeFailed  Outcome = "failed"  // the provider send failed (each attempt counts)
+	OutcomeBounced Outcome = "bounced" // a webhook reported a bounce
+)
+
+// OutcomeCount totals the outcomes of one channel and type over a window.
+type OutcomeCount struct {
+	Channel Channel
+	Type    NotificationType
+	Sent    int64
+	Failed  int64
+	Bounced int64
+}
+
+// OutcomeStore defines the contract for the rolling send outcome counts
+// shared by the workers and servers that record them and the alerter that
+// reads them. Implementations live in internal/infra/metrics/.
+type OutcomeStore interface {
+	// RecordOutcome counts one outcome for channel and type.
+	RecordOutcome(ctx context.Context, channel Channel, notifType NotificationType, outcome Outcome) error
+
+	// OutcomeCounts totals the outcomes recorded since the given time, per
+	// channel and type.
+	OutcomeCounts(ctx context.Context, since time.Time) ([]*OutcomeCount, error)
+}
+
+// recordOutcome counts outcome for notifLog. A failure is logged: alerting
+// must not fail the send or webhook it counts.
+func recordOutcome(ctx context.Context, outcomes OutcomeStore, notifLog *NotificationLog, outcome Outcome) {
+	if outcomes == nil {
+		return
+	}
+	err := outcomes.RecordOutcome(context.WithoutCancel(ctx), Channel(notifLog.Channel), NotificationType(notifLog.Type), outcome)
+	if err != nil {
+		slog.Warn("failed to record outcome", "outcome", outcome, "log_id", notifLog.ID, "error", err)
+	}
+}
+
+// AlertMetric names a rate an alert rule watches.
+type AlertMetric string
+
+const (
+	AlertFailureRate AlertMetric = "failure_rate" // failed / (sent + failed)
+	AlertBounceRate  AlertMetric = "bounce_rate"  // bounced / sent
+)
+
+// AlertRule sets thresholds for the sends matching Key: a channel (all its
+// types), a notification type (on every channel), or channel:type. A zero
+// threshold leaves that rate unwatched.
+type AlertRule struct {
+	Key         string
+	FailureRate float64
+	BounceRate  float64
+
+	// MinVolume is how many sends the window must hold before a rate is
+	// judged, so a single failure among a handful does not page anyone.
+	MinVolume int64
+}
+
+// matches reports whether the rule's key covers channel and type.
+func (r AlertRule) matches(channel Channel, notifType NotificationType) bool {
+	if c, t, ok := strings.Cut(r.Key, ":"); ok {
+		return Channel(c) == channel && NotificationType(t) == notifType
+	}
+	return Channel(r.Key) == channel || NotificationType(r.Key) == notifType
+}
+
+// AlertConfig holds configuration for the alerter.
+type AlertConfig struct {
+	// Interval is how often the rates are checked.
+	Interval time.Duration
+
+	// Window is how far back the rates look.
+	Window time.Duration
+
+	// Cooldown is how long an alert stays quiet after firing, across replicas.
+	Cooldown time.Duration
+
+	Rules []AlertRule
+}
+
+// withDefaults fills zero or negative fields with sensible defaults.
+func (c AlertConfig) withDefaults() AlertConfig {
+	if c.Interval <= 0 {
+		c.Interval = time.Minute
+	}
+	if c.Window <= 0 {
+		c.Window = 15 * time.Minute
+	}
+	if c.Cooldown <= 0 {
+		c.Cooldown = 30 * time.Minute
+	}
+	rules := make([]AlertRule, len(c.Rules))
+	for i, rule := range c.Rules {
+		if rule.MinVolume <= 0 {
+			rule.MinVolume = 20
+		}
+		rules[i] = rule
+	}
+	c.Rules = rules
+	return c
+}
+
+// Alert is a crossed threshold, as sent to every alert action.
+type Alert struct {
+	Rule      string      `json:"rule"`
+	Metric    AlertMetric `json:"metric"`
+	Rate      float64     `json:"rate"`
+	Threshold float64     `json:"threshold"`
+	Count     int64       `json:"count"`  // failed or bounced sends
+	Volume    int64       `json:"volume"` // sends the rate is over
+	WindowSec int         `json:"window_sec"`
+	FiredAt   time.Time   `json:"fired_at"`
+}
+
+// Key identifies the alert for cooldowns and deduplication.
+func (a *Alert) Key() string {
+	return string(a.Metric) + ":" + a.Rule
+}
+
+// Summary describes the alert in one line.
+func (a *Alert) Summary() string {
+	metric := "failure rate"
+	if a.Metric == AlertBounceRate {
+		metric = "bounce rate"
+	}
+	return fmt.Sprintf("notifly: %s %s is %.1f%% (%d of %d) over the last %s, threshold %.1f%%",
+		a.Rule, metric, a.Rate*100, a.Count, a.Volume, time.Duration(a.WindowSec)*time.Second, a.Threshold*100)
+}
+
+// AlertAction delivers alerts somewhere a human will see them.
+// Implementations live in internal/infra/alert/.
+type AlertAction interface {
+	// Name identifies the action in logs.
+	Name() string
+
+	// Fire delivers alert.
+	Fire(ctx context.Context, alert *Alert) error
+}
+
+// AlertCooldown defines the contract for the cooldowns shared by alerter
+// replicas.
+type AlertCooldown interface {
+	// TryStart starts a cooldown of ttl for key. It returns false, without
+	// error, when one is already running.
+	TryStart(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+// Alerter periodically checks the rolling failure and bounce rates against
+// the alert rules and fires every alert action when one is crossed. Each
+// alert then stays quiet for the cooldown, which the replicas share, so an
+// incident fires once rather than once per check per replica.
+type Alerter struct {
+	outcomes OutcomeStore
+	cooldown AlertCooldown
+	actions  []AlertAction
+
+	mu      sync.RWMutex
+	config  AlertConfig
+	updated chan struct{} // signals Run to pick up a new interval
+}
+
+// NewAlerter creates a new alerter.
+func NewAlerter(outcomes OutcomeStore, cooldown AlertCooldown, actions []AlertAction, cfg AlertConfig) *Alerter {
+	return &Alerter{
+		outcomes: outcomes,
+		cooldown: cooldown,
+		actions:  actions,
+		config:   cfg.withDefaults(),
+		updated:  make(chan struct{}, 1),
+	}
+}
+
+// UpdateConfig replaces the alerter settings while it is running. A changed
+// interval takes effect immediately; the rest apply from the next check.
+func (a *Alerter) UpdateConfig(cfg AlertConfig) {
+	a.mu.Lock()
+	a.config = cfg.withDefaults()
+	a.mu.Unlock()
+
+	select {
+	case a.updated <- struct{}{}:
+	default: // an update is already pending
+	}
+}
+
+func (a *Alerter) currentConfig() AlertConfig {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.config
+}
+
+// Run starts the alerter loop. It blocks until the context is cancelled.
+// Should be called in a goroutine.
+func (a *Alerter) Run(ctx context.Context) {
+	cfg := a.currentConfig()
+	slog.Info("alerter started",
+		"interval", cfg.Interval,
+		"window", cfg.Window,
+		"cooldown", cfg.Cooldown,
+		"rules", len(cfg.Rules),
+		"actions", len(a.actions),
+	)
+
+	interval := cfg.Interval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("alerter stopped")
+			return
+		case <-a.updated:
+			if next := a.currentConfig().Interval; next != interval {
+				interval = next
+				ticker.Reset(interval)
+				slog.Info("alerter interval updated", "interval", interval)
+			}
+		case <-ticker.C:
+			if _, err := a.Check(ctx); err != nil {
+				slog.Error("alerter: check failed", "error", err)
+			}
+		}
+	}
+}
+
+// Check evaluates every rule once and fires the alerts that are not cooling
+// down. It returns the alerts fired.
+func (a *Alerter) Check(ctx context.Context) ([]*Alert, error) {
+	cfg := a.currentConfig()
+	now := time.Now().UTC()
+
+	counts, err := a.outcomes.OutcomeCounts(ctx, now.Add(-cfg.Window))
+	if err != nil {
+		return nil, fmt.Errorf("reading outcome counts: %w", err)
+	}
+
+	var fired []*Alert
+	for _, alert := range evaluateAlerts(cfg, counts, now) {
+		started, err := a.cooldown.TryStart(ctx, alert.Key(), cfg.Cooldown)
+		if err != nil {
+			slog.Error("alerter: failed to start cooldown", "alert", alert.Key(), "error", err)
+			continue
+		}
+		if !started {
+			continue // fired recently, here or on another replica
+		}
+		a.fire(ctx, alert)
+		fired = append(fired, alert)
+	}
+	return fired, nil
+}
+
+// fire sends alert through every action. Failures are logged: one broken
+// action must not keep the others from alerting.
+func (a *Alerter) fire(ctx context.Context, alert *Alert) {
+	slog.Warn("alert fired",
+		"rule", alert.Rule,
+		"metric", alert.Metric,
+		"rate", alert.Rate,
+		"threshold", alert.Threshold,
+		"volume", alert.Volume,
+	)
+	for _, action := range a.actions {
+		if err := action.Fire(ctx, alert); err != nil {
+			slog.Error("alerter: action failed", "action", action.Name(), "alert", alert.Key(), "error", err)
+		}
+	}
+}
+
+// evaluateAlerts returns an alert for every rule threshold counts cross,
+// ordered by rule key and metric.
+func evaluateAlerts(cfg AlertConfig, counts []*OutcomeCount, now time.Time) []*Alert {
+	var alerts []*Alert
+	for _, rule := range cfg.Rules {
+		var total OutcomeCount
+		for _, count := range counts {
+			if rule.matches(count.Channel, count.Type) {
+				total.Sent += count.Sent
+				total.Failed += count.Failed
+				total.Bounced += count.Bounced
+			}
+		}
+
+		newAlert := func(metric AlertMetric, threshold float64, n, volume int64) {
+			if threshold <= 0 || volume < rule.MinVolume {
+				return
+			}
+			rate := float64(n) / float64(volume)
+			if rate < threshold {
+				return
+			}
+			alerts = append(alerts, &Alert{
+				Rule:      rule.Key,
+				Metric:    metric,
+				Rate:      rate,
+				Threshold: threshold,
+				Count:     n,
+				Volume:    volume,
+				WindowSec: int(cfg.Window / time.Second),
+				FiredAt:   now,
+			})
+		}
+		newAlert(AlertFailureRate, rule.FailureRate, total.Failed, total.Sent+total.Failed)
+		newAlert(AlertBounceRate, rule.BounceRate, total.Bounced, total.Sent)
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].Rule != alerts[j].Rule {
+			return alerts[i].Rule < alerts[j].Rule
+		}
+		return alerts[i].Metric < alerts[j].Metric
+	})
+	return alerts
+}
@@ -0,0 +1,139 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+)
+
+// ArchiveStore is an optional NotificationStore extension that archives logs.
+// An archived log is left out of List unless asked for and of ListFailed, but
+// is still returned by ID, so audits can read it, until it is purged.
+type ArchiveStore interface {
+	// ListArchivable returns the IDs of up to limit unarchived logs matching
+	// filter that are no longer queued or processing, oldest first.
+	ListArchivable(ctx context.Context, filter ArchiveFilter, limit int) ([]string, error)
+
+	// ArchiveLogs sets archived_at to at on the given logs not archived yet.
+	ArchiveLogs(ctx context.Context, ids []string, at time.Time) error
+
+	// UnarchiveLog clears archived_at on a log.
+	UnarchiveLog(ctx context.Context, id string) error
+
+	// PurgeArchived deletes up to limit logs archived before before, oldest
+	// archive first, and returns how many it deleted.
+	PurgeArchived(ctx context.Context, before time.Time, limit int) (int, error)
+}
+
+// Values of ListFilter.Archived.
+const (
+	ArchivedInclude = "include" // archived logs are listed with the others
+	ArchivedOnly    = "only"    // only archived logs are listed
+)
+
+// archivePage is how many logs ArchiveLogs and PurgeArchived handle per store
+// round trip.
+const archivePage = 500
+
+// archives returns the store's archive support, or nil when it cannot archive.
+func (s *Service) archives() ArchiveStore {
+	archives, _ := s.store.(ArchiveStore)
+	return archives
+}
+
+// Archive archives a log, e.g. one holding sensitive data, so it no longer
+// shows in default listings. A log still queued or processing cannot be
+// archived. Archiving an archived log changes nothing.
+func (s *Service) Archive(ctx context.Context, id string) (*NotificationLog, error) {
+	notifLog, err := s.GetNotification(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if notifLog.ArchivedAt != nil {
+		return notifLog, nil
+	}
+	if notifLog.Status == StatusQueued || notifLog.Status == StatusProcessing {
+		return nil, common.NewConflictError(fmt.Sprintf("notification %s is still %s", id, notifLog.Status), id)
+	}
+
+	if err := s.archives().ArchiveLogs(ctx, []string{id}, time.Now()); err != nil {
+		return nil, fmt.Errorf("archiving notification: %w", err)
+	}
+	slog.Info("notification archived", "id", id)
+	return s.GetNotification(ctx, id)
+}
+
+// Unarchive returns an archived log to the default listings.
+func (s *Service) Unarchive(ctx context.Context, id string) (*NotificationLog, error) {
+	notifLog, err := s.GetNotification(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if notifLog.ArchivedAt == nil {
+		return notifLog, nil
+	}
+
+	if err := s.archives().UnarchiveLog(ctx, id); err != nil {
+		return nil, fmt.Errorf("unarchiving notification: %w", err)
+	}
+	slog.Info("notification unarchived", "id", id)
+	return s.GetNotification(ctx, id)
+}
+
+// ArchiveBulk archives the settled logs matching req, oldest first, up to
+// req.Limit (default 1000). Each page archived leaves the listing, so the next
+// one returns the following logs.
+func (s *Service) ArchiveBulk(ctx context.Context, req *ArchiveRequest) (*ArchiveResponse, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 1000
+	}
+
+	now := time.Now()
+	resp := &ArchiveResponse{}
+	for resp.Archived < limit {
+		ids, err := s.archives().ListArchivable(ctx, req.ArchiveFilter, min(archivePage, limit-resp.Archived))
+		if err != nil {
+			return nil, fmt.Errorf("listing archivable notifications: %w", err)
+		}
+		if len(ids) == 0 {
+			break
+		}
+		if err := s.archives().ArchiveLogs(ctx, ids, now); err != nil {
+			return nil, fmt.Errorf("archiving notifications: %w", err)
+		}
+		resp.Archived += len(ids)
+	}
+
+	slog.Info("notifications archived", "count", resp.Archived, "created_before", req.CreatedBefore)
+	return resp, nil
+}
+
+// Purge deletes logs archived before req.ArchivedBefore for good, up to
+// req.Limit (default 1000). Logs pointing at a purged one (device children,
+// fallbacks, escalation steps, follow-ups) keep their own row with the
+// reference cleared.
+func (s *Service) Purge(ctx context.Context, req *PurgeRequest) (*PurgeResponse, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 1000
+	}
+
+	resp := &PurgeResponse{}
+	for resp.Purged < limit {
+		purged, err := s.archives().PurgeArchived(ctx, *req.ArchivedBefore, min(archivePage, limit-resp.Purged))
+		if err != nil {
+			return nil, fmt.Errorf("purging notifications: %w", err)
+		}
+		if purged == 0 {
+			break
+		}
+		resp.Purged += purged
+	}
+
+	slog.Info("archived notifications purged", "count", resp.Purged, "archived_before", req.ArchivedBefore)
+	return resp, nil
+}
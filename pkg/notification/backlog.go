@@ -0,0 +1,103 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// BacklogSink receives the send queues' backlog for an external metrics
+// system, e.g. a Prometheus Pushgateway an autoscaler reads.
+// Implementations live in internal/infra/metrics/.
+type BacklogSink interface {
+	PushBacklog(ctx context.Context, state *QueueState) error
+}
+
+// BacklogPusher pushes the send queues' backlog to a BacklogSink at a fixed
+// interval. It runs on the servers rather than the workers so that backlog
+// keeps being reported while the workers are scaled to zero. Every replica
+// pushes the same cluster-wide snapshot, so the sink keeps the latest.
+type BacklogPusher struct {
+	queue    QueueControl
+	sink     BacklogSink
+	interval time.Duration
+}
+
+// NewBacklogPusher creates a pusher reading queue every interval.
+func NewBacklogPusher(queue QueueControl, sink BacklogSink, interval time.Duration) *BacklogPusher {
+	return &BacklogPusher{queue: queue, sink: sink, interval: interval}
+}
+
+// Run pushes the backlog once and then every interval until ctx is cancelled.
+// Should be called in a goroutine.
+func (p *BacklogPusher) Run(ctx context.Context) {
+	slog.Info("backlog pusher started", "interval", p.interval)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Push(ctx); err != nil {
+			slog.Warn("backlog pusher: push failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			slog.Info("backlog pusher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Push reads the queues' state and pushes it to the sink.
+func (p *BacklogPusher) Push(ctx context.Context) error {
+	state, err := p.queue.QueueState(ctx)
+	if err != nil {
+		return fmt.Errorf("reading queue state: %w", err)
+	}
+	return p.sink.PushBacklog(ctx, state)
+}
+
+// WritePrometheusBacklog writes each send queue's backlog as gauges in the
+// Prometheus text exposition format, labelled by queue:
+// notifly_queue_pending_tasks, notifly_queue_active_tasks,
+// notifly_queue_oldest_pending_seconds, and notifly_queue_paused. Scaling the
+// workers on sum(notifly_queue_pending_tasks) or
+// max(notifly_queue_oldest_pending_seconds) tracks the backlog across queues.
+func WritePrometheusBacklog(w io.Writer, state *QueueState) error {
+	gauges := []struct {
+		name, help string
+		value      func(q *QueueState) string
+	}{
+		{"notifly_queue_pending_tasks", "Tasks waiting to be processed.", func(q *QueueState) string {
+			return strconv.Itoa(q.Pending)
+		}},
+		{"notifly_queue_active_tasks", "Tasks being processed.", func(q *QueueState) string {
+			return strconv.Itoa(q.Active)
+		}},
+		{"notifly_queue_oldest_pending_seconds", "Age of the oldest pending task.", func(q *QueueState) string {
+			return strconv.FormatFloat(q.OldestPendingSec, 'g', -1, 64)
+		}},
+		{"notifly_queue_paused", "Whether the queue is paused (1) or not (0).", func(q *QueueState) string {
+			if q.Paused {
+				return "1"
+			}
+			return "0"
+		}},
+	}
+	for _, g := range gauges {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name); err != nil {
+			return err
+		}
+		for i := range state.Queues {
+			queue := &state.Queues[i]
+			if _, err := fmt.Fprintf(w, "%s{queue=%q} %s\n", g.name, queue.Queue, g.value(queue)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+)
+
+// BounceType classifies a bounce by whether sending again can succeed.
+type BounceType string
+
+const (
+	// BounceHard is a permanent failure, e.g. an unknown mailbox or a
+	// landline number. The recipient is suppressed.
+	BounceHard BounceType = "hard"
+
+	// BounceSoft is a temporary failure, e.g. a full mailbox or an
+	// unreachable handset. The notification is sent again later.
+	BounceSoft BounceType = "soft"
+)
+
+// emailBounceType classifies an SES bounce type, which Resend passes on too:
+// Permanent is hard, Transient and Undetermined are soft, and a missing type
+// is treated as hard.
+func emailBounceType(t string) BounceType {
+	switch t {
+	case "Transient", "Undetermined":
+		return BounceSoft
+	}
+	return BounceHard
+}
+
+// SoftBounceRetry is how soft-bounced notifications are sent again: up to
+// MaxRetries times, the first after Delay and each next one after twice the
+// previous wait. A MaxRetries of 0 leaves soft bounces as they are.
+type SoftBounceRetry struct {
+	MaxRetries int
+	Delay      time.Duration
+}
+
+// delay returns the wait before the retry-th resend, counting from 1.
+func (r SoftBounceRetry) delay(retry int) time.Duration {
+	return r.Delay << (retry - 1)
+}
+
+// BounceRetryEnqueuer is optionally implemented by an Enqueuer that can
+// schedule the resend of a soft-bounced notification. Without it, soft
+// bounces are recorded but not retried.
+type BounceRetryEnqueuer interface {
+	// EnqueueBounceRetry schedules BounceRetrier.Process for the retry-th
+	// resend of logID after delay. Scheduling the same retry twice is a no-op.
+	EnqueueBounceRetry(logID string, retry int, delay time.Duration) error
+}
+
+// SetSoftBounceRetry replaces the soft bounce retry policy. Retries already
+// scheduled keep their delay.
+func (s *Service) SetSoftBounceRetry(policy SoftBounceRetry) {
+	s.softBounces.Store(&policy)
+}
+
+// scheduleBounceRetry schedules the next resend of a soft-bounced log, unless
+// it has used up its retries, and reports whether it did. A failure is
+// logged: the bounce is recorded either way.
+func (s *Service) scheduleBounceRetry(notifLog *NotificationLog) bool {
+	enqueuer, ok := s.enqueuer.(BounceRetryEnqueuer)
+	if !ok {
+		return false
+	}
+	policy := *s.softBounces.Load()
+	retry := notifLog.BounceRetries + 1
+	if retry > policy.MaxRetries {
+		slog.Info("soft bounce not retried: retries used up", "log_id", notifLog.ID, "bounce_retries", notifLog.BounceRetries)
+		return false
+	}
+
+	delay := policy.delay(retry)
+	if err := enqueuer.EnqueueBounceRetry(notifLog.ID, retry, delay); err != nil {
+		slog.Error("failed to schedule soft bounce retry", "log_id", notifLog.ID, "retry", retry, "error", err)
+		return false
+	}
+	slog.Info("soft bounce retry scheduled", "log_id", notifLog.ID, "retry", retry, "delay", delay.String())
+	return true
+}
+
+// BounceRetrier runs the delayed resends of soft-bounced notifications.
+type BounceRetrier struct {
+	store    NotificationStore
+	enqueuer Enqueuer
+}
+
+// NewBounceRetrier creates a new soft bounce retrier.
+func NewBounceRetrier(store NotificationStore, enqueuer Enqueuer) *BounceRetrier {
+	return &BounceRetrier{store: store, enqueuer: enqueuer}
+}
+
+// Process sends logID again for its retry-th time if it is still
+// soft-bounced: the log is reset to queued and handed to the worker. A log
+// that was delivered, hard-bounced, or already retried since is left alone.
+// One that could not be enqueued stays queued for the reaper.
+func (r *BounceRetrier) Process(ctx context.Context, logID string, retry int) error {
+	notifLog, err := r.store.GetByID(ctx, logID)
+	if err != nil {
+		return fmt.Errorf("fetching notification log %s: %w", logID, err)
+	}
+	if notifLog == nil {
+		return common.NewPermanentError(fmt.Errorf("notification log not found: %s", logID))
+	}
+	if notifLog.Status != StatusBounced || notifLog.BounceType != BounceSoft || notifLog.BounceRetries >= retry {
+		slog.Info("soft bounce retry not needed", "log_id", logID, "status", notifLog.Status, "bounce_type", notifLog.BounceType)
+		return nil
+	}
+
+	if err := r.store.RecordBounceRetry(ctx, logID, retry); err != nil {
+		return fmt.Errorf("requeuing soft-bounced notification: %w", err)
+	}
+	if err := r.enqueuer.EnqueueSendNotification(logID); err != nil {
+		slog.Error("failed to enqueue soft bounce retry, leaving it to the reaper", "log_id", logID, "error", err)
+		return nil
+	}
+
+	slog.Info("soft-bounced notification requeued", "log_id", logID, "retry", retry)
+	return nil
+}
@@ -0,0 +1,222 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventKey is the template data key a booking or appointment send sets its
+// event in, e.g. {"Event": {"UID": "booking-42", "Start": "2024-06-01T09:00:00Z",
+// "End": "2024-06-01T09:30:00Z", "Summary": "Dental check-up"}}. An email
+// carrying one gets the event attached as an iCalendar invite the recipient's
+// calendar can add; templates can show its fields too, e.g. {{.Event.Summary}}.
+const EventKey = "Event"
+
+// Calendar methods an invite is sent with: a new or changed event, or its
+// cancellation.
+const (
+	CalendarRequest = "REQUEST"
+	CalendarCancel  = "CANCEL"
+)
+
+// calendarProdID identifies notifly as the producer of its invites.
+const calendarProdID = "-//Notifly//Notifly//EN"
+
+// CalendarEvent is the event of a booking or appointment, from the send's
+// template data. Calendars match an update or cancellation to the event by
+// UID, and take the one with the highest Sequence.
+type CalendarEvent struct {
+	UID           string    `json:"UID"`
+	Start         time.Time `json:"Start"`
+	End           time.Time `json:"End"`
+	Summary       string    `json:"Summary"`
+	Description   string    `json:"Description,omitempty"`
+	Location      string    `json:"Location,omitempty"`
+	URL           string    `json:"URL,omitempty"`
+	Organizer     string    `json:"Organizer,omitempty"`     // email address; default the sender's
+	OrganizerName string    `json:"OrganizerName,omitempty"` // default the sender's display name
+	Method        string    `json:"Method,omitempty"`        // REQUEST (default) or CANCEL
+	Sequence      int       `json:"Sequence,omitempty"`
+}
+
+// calendarEvent returns the event in data, or nil when it has none. An event
+// that is missing fields or malformed is an error.
+func calendarEvent(data map[string]any) (*CalendarEvent, error) {
+	raw, ok := data[EventKey]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("encoding event: %w", err)
+	}
+	var event CalendarEvent
+	if err := json.Unmarshal(encoded, &event); err != nil {
+		return nil, fmt.Errorf("event must be an object with RFC 3339 Start and End times: %w", err)
+	}
+
+	event.UID = strings.TrimSpace(event.UID)
+	event.Summary = strings.TrimSpace(event.Summary)
+	event.Organizer = strings.TrimSpace(event.Organizer)
+	event.Method = strings.ToUpper(strings.TrimSpace(event.Method))
+	switch {
+	case event.UID == "":
+		return nil, fmt.Errorf("event UID is required")
+	case event.Summary == "":
+		return nil, fmt.Errorf("event Summary is required")
+	case event.Start.IsZero() || event.End.IsZero():
+		return nil, fmt.Errorf("event Start and End are required")
+	case !event.End.After(event.Start):
+		return nil, fmt.Errorf("event End must be after Start")
+	case event.Sequence < 0:
+		return nil, fmt.Errorf("event Sequence cannot be negative")
+	}
+	switch event.Method {
+	case "":
+		event.Method = CalendarRequest
+	case CalendarRequest, CalendarCancel:
+	default:
+		return nil, fmt.Errorf("event Method must be REQUEST or CANCEL, got %q", event.Method)
+	}
+	if event.URL != "" {
+		u, err := url.Parse(event.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("event URL must be an absolute http or https URL")
+		}
+	}
+	if event.Organizer != "" {
+		if err := ValidateRecipient(ChannelEmail, event.Organizer); err != nil {
+			return nil, fmt.Errorf("event Organizer: %w", err)
+		}
+	}
+	return &event, nil
+}
+
+// calendarInvite returns the attachments for the event in data, if any: its
+// invite to attendees, organized by organizer (a From value) unless the event
+// names its own organizer.
+func calendarInvite(data map[string]any, organizer string, attendees []string, now time.Time) ([]Attachment, error) {
+	event, err := calendarEvent(data)
+	if err != nil || event == nil {
+		return nil, err
+	}
+	invite, err := event.invite(organizer, attendees, now)
+	if err != nil {
+		return nil, err
+	}
+	return []Attachment{invite}, nil
+}
+
+// invite returns the event as an iCalendar attachment for attendees, from
+// organizer (a From header value) unless the event names its own. METHOD and
+// the attachment's content type agree, so mail clients offer to accept the
+// invite or remove the cancelled event.
+func (e *CalendarEvent) invite(organizer string, attendees []string, now time.Time) (Attachment, error) {
+	org := &mail.Address{Address: e.Organizer, Name: e.OrganizerName}
+	if org.Address == "" {
+		parsed, err := mail.ParseAddress(organizer)
+		if err != nil {
+			return Attachment{}, fmt.Errorf("event Organizer is required when the sender address is unknown")
+		}
+		org.Address = parsed.Address
+		if org.Name == "" {
+			org.Name = parsed.Name
+		}
+	}
+
+	var b icsBuilder
+	b.line("BEGIN", "VCALENDAR")
+	b.line("PRODID", calendarProdID)
+	b.line("VERSION", "2.0")
+	b.line("CALSCALE", "GREGORIAN")
+	b.line("METHOD", e.Method)
+	b.line("BEGIN", "VEVENT")
+	b.line("UID", icsText(e.UID))
+	b.line("SEQUENCE", strconv.Itoa(e.Sequence))
+	b.line("DTSTAMP", icsTime(now))
+	b.line("DTSTART", icsTime(e.Start))
+	b.line("DTEND", icsTime(e.End))
+	b.line("SUMMARY", icsText(e.Summary))
+	if e.Description != "" {
+		b.line("DESCRIPTION", icsText(e.Description))
+	}
+	if e.Location != "" {
+		b.line("LOCATION", icsText(e.Location))
+	}
+	if e.URL != "" {
+		b.line("URL", e.URL)
+	}
+	b.line("ORGANIZER"+icsCN(org.Name), "mailto:"+org.Address)
+	for _, attendee := range attendees {
+		b.line("ATTENDEE;ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE", "mailto:"+attendee)
+	}
+	if e.Method == CalendarCancel {
+		b.line("STATUS", "CANCELLED")
+	} else {
+		b.line("STATUS", "CONFIRMED")
+	}
+	b.line("END", "VEVENT")
+	b.line("END", "VCALENDAR")
+
+	return Attachment{
+		Filename:    "invite.ics",
+		ContentType: "text/calendar; charset=UTF-8; method=" + e.Method,
+		Content:     []byte(b.String()),
+	}, nil
+}
+
+// icsBuilder writes iCalendar content lines: CRLF-terminated and folded at
+// 75 octets, as RFC 5545 requires.
+type icsBuilder struct {
+	strings.Builder
+}
+
+// line writes the content line name:value. Continuation lines start with a
+// space, which counts toward their 75 octets.
+func (b *icsBuilder) line(name, value string) {
+	line := name + ":" + value
+	for limit := 75; len(line) > limit; limit = 74 {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut-- // never split a UTF-8 sequence
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+// isRuneStart reports whether c begins a UTF-8 sequence.
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
+
+// icsTime formats t as an iCalendar UTC date-time.
+func icsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// icsTextEscaper escapes the characters iCalendar TEXT values reserve.
+var icsTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// icsText escapes s as an iCalendar TEXT value.
+func icsText(s string) string {
+	return icsTextEscaper.Replace(s)
+}
+
+// icsCN returns the CN parameter for a display name, quoted, or "" without one.
+// Quotes cannot be escaped in parameter values, so they are dropped.
+func icsCN(name string) string {
+	name = strings.NewReplacer(`"`, "", "\r", "", "\n", "").Replace(strings.TrimSpace(name))
+	if name == "" {
+		return ""
+	}
+	return `;CN="` + name + `"`
+}
@@ -0,0 +1,852 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+)
+
+// CampaignStatus is the state of a campaign's fan-out.
+type CampaignStatus string
+
+const (
+	CampaignScheduled CampaignStatus = "scheduled" // waiting for scheduled_at
+	CampaignRunning   CampaignStatus = "running"
+	CampaignPaused    CampaignStatus = "paused"
+	CampaignCompleted CampaignStatus = "completed" // every audience member dispatched
+	CampaignCancelled CampaignStatus = "cancelled"
+	CampaignFailed    CampaignStatus = "failed" // the fan-out could not be started
+)
+
+// finished reports whether a campaign in status will dispatch nothing more.
+func (s CampaignStatus) finished() bool {
+	return s == CampaignCompleted || s == CampaignCancelled || s == CampaignFailed
+}
+
+// Campaign sends one notification type and template data to an audience. The
+// worker fans the audience out in batches of CampaignConfig.BatchSize, one
+// batch every BatchInterval, creating one log per recipient tagged with the
+// campaign's ID.
+type Campaign struct {
+	ID      string           `json:"id"`
+	Name    string           `json:"name"`
+	Channel Channel          `json:"channel"`
+	Type    NotificationType `json:"type"`
+	Data    map[string]any   `json:"data,omitempty"`
+
+	// Audience is not returned by the API: it can be large and is personal
+	// data. It is cleared once the campaign finishes.
+	Audience Recipients `json:"-"`
+
+	// Throttle caps the send rate; nil sends at the configured batch pace.
+	Throttle *CampaignThrottle `json:"throttle,omitempty"`
+
+	// SendAtLocal, when set, is the wall-clock time each audience member is
+	// sent to in their own timezone: Timezones, keyed by lowercased member,
+	// or Timezone. The audience is ordered by when members are due. Timezones
+	// is not returned by the API, like the audience.
+	SendAtLocal string            `json:"send_at_local,omitempty"`
+	Timezone    string            `json:"timezone,omitempty"`
+	Timezones   map[string]string `json:"-"`
+
+	// Content is the message rendered when the campaign was created, when
+	// rendering at enqueue is on; every recipient's log carries it.
+	Content *RenderedContent `json:"content,omitempty"`
+
+	Status      CampaignStatus `json:"status"`
+	Total       int            `json:"total"`
+	Dispatched  int            `json:"dispatched"` // audience members fanned out so far
+	Suppressed  int            `json:"suppressed"` // skipped by bounce suppression
+	Error       string         `json:"error,omitempty"`
+	ScheduledAt *time.Time     `json:"scheduled_at,omitempty"`
+	StartedAt   *time.Time     `json:"started_at,omitempty"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+
+	// Progress counts the campaign's logs by outcome. Set by Campaigner.Get.
+	Progress *CampaignProgress `json:"progress,omitempty"`
+}
+
+// elapsed returns how long ago the campaign started, or zero before it has.
+func (c *Campaign) elapsed() time.Duration {
+	if c.StartedAt == nil {
+		return 0
+	}
+	return time.Since(*c.StartedAt)
+}
+
+// CampaignThrottle caps a campaign's send rate at MaxPerMinute. With a
+// warm-up, the rate starts at WarmupStartPerMinute and rises linearly to
+// MaxPerMinute over WarmupMinutes from the campaign's start, so a large
+// campaign does not hit provider limits or hurt a new domain's reputation.
+type CampaignThrottle struct {
+	MaxPerMinute         int `json:"max_per_minute" binding:"required,min=1"`
+	WarmupStartPerMinute int `json:"warmup_start_per_minute,omitempty" binding:"omitempty,min=1"`
+	WarmupMinutes        int `json:"warmup_minutes,omitempty" binding:"omitempty,min=1"`
+}
+
+// validate rejects incomplete or inverted warm-ups.
+func (t *CampaignThrottle) validate() error {
+	if t.MaxPerMinute < 1 {
+		return common.NewValidationError("throttle.max_per_minute must be at least 1")
+	}
+	if (t.WarmupStartPerMinute > 0) != (t.WarmupMinutes > 0) {
+		return common.NewValidationError("throttle.warmup_start_per_minute and throttle.warmup_minutes must be set together")
+	}
+	if t.WarmupStartPerMinute > t.MaxPerMinute {
+		return common.NewValidationError("throttle.warmup_start_per_minute must not exceed throttle.max_per_minute")
+	}
+	return nil
+}
+
+// PerMinute returns the allowed send rate elapsed after the campaign started.
+func (t *CampaignThrottle) PerMinute(elapsed time.Duration) float64 {
+	warmup := time.Duration(t.WarmupMinutes) * time.Minute
+	if t.WarmupStartPerMinute == 0 || elapsed >= warmup {
+		return float64(t.MaxPerMinute)
+	}
+	progress := max(float64(elapsed)/float64(warmup), 0)
+	start := float64(t.WarmupStartPerMinute)
+	return start + (float64(t.MaxPerMinute)-start)*progress
+}
+
+// pace sizes the next batch for perMinute: enough sends to span about
+// interval (at least one, at most maxBatch), and the delay that holds the
+// rate after sending them.
+func pace(perMinute float64, maxBatch int, interval time.Duration) (int, time.Duration) {
+	size := int(math.Ceil(perMinute * interval.Minutes()))
+	size = min(max(size, 1), maxBatch)
+	delay := time.Duration(float64(size) / perMinute * float64(time.Minute))
+	return size, delay
+}
+
+// localLayouts are the forms send_at_local takes: a wall-clock time with no
+// offset, which each audience member's timezone supplies.
+var localLayouts = []string{"2006-01-02T15:04", "2006-01-02T15:04:05"}
+
+// localDueSlack is how early a member of a send_at_local campaign may be sent
+// to, so a dispatch that runs slightly before its time still sends.
+const localDueSlack = time.Minute
+
+// localSchedule tells when each audience member of a send_at_local campaign
+// is due.
+type localSchedule struct {
+	wall      time.Time // the wall-clock time, carried in UTC
+	timezone  string
+	timezones map[string]string
+	locations map[string]*time.Location
+}
+
+// newLocalSchedule parses sendAtLocal, read in timezones[member] or else
+// timezone (default UTC). Timezones are loaded as members need them.
+func newLocalSchedule(sendAtLocal, timezone string, timezones map[string]string) (*localSchedule, error) {
+	var wall time.Time
+	var err error
+	for _, layout := range localLayouts {
+		if wall, err = time.Parse(layout, sendAtLocal); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, common.NewValidationError(fmt.Sprintf("send_at_local must be a local time like 2024-06-01T09:00, got %q", sendAtLocal))
+	}
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	return &localSchedule{wall: wall, timezone: timezone, timezones: timezones, locations: make(map[string]*time.Location)}, nil
+}
+
+// due returns when to is due: the wall-clock time in their timezone. A time
+// skipped by a daylight saving change is read as if the clock had not moved.
+func (s *localSchedule) due(to string) (time.Time, error) {
+	timezone := s.timezones[strings.ToLower(to)]
+	if timezone == "" {
+		timezone = s.timezone
+	}
+	loc, ok := s.locations[timezone]
+	if !ok {
+		var err error
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return time.Time{}, common.NewValidationError(fmt.Sprintf("unknown timezone: %s", timezone))
+		}
+		s.locations[timezone] = loc
+	}
+	w := s.wall
+	return time.Date(w.Year(), w.Month(), w.Day(), w.Hour(), w.Minute(), w.Second(), 0, loc).UTC(), nil
+}
+
+// order sorts audience by when members are due, keeping the given order
+// among members due at once, and returns when the first is due.
+func (s *localSchedule) order(audience Recipients) (time.Time, error) {
+	dues := make(map[string]time.Time, len(audience))
+	for _, to := range audience {
+		due, err := s.due(to)
+		if err != nil {
+			return time.Time{}, err
+		}
+		dues[to] = due
+	}
+	sort.SliceStable(audience, func(i, j int) bool {
+		return dues[audience[i]].Before(dues[audience[j]])
+	})
+	return dues[audience[0]], nil
+}
+
+// localSchedule returns the campaign's local send schedule, or nil when it
+// is not sent at a local time.
+func (c *Campaign) localSchedule() (*localSchedule, error) {
+	if c.SendAtLocal == "" {
+		return nil, nil
+	}
+	return newLocalSchedule(c.SendAtLocal, c.Timezone, c.Timezones)
+}
+
+// CampaignProgress summarizes the logs a campaign has created.
+type CampaignProgress struct {
+	Pending  int                        `json:"pending"` // not fanned out yet
+	Queued   int                        `json:"queued"`  // queued or processing
+	Sent     int                        `json:"sent"`    // sent, delivered, opened, or clicked
+	Failed   int                        `json:"failed"`  // failed, abandoned, bounced, or complained
+	ByStatus map[NotificationStatus]int `json:"by_status"`
+
+	// PerMinute is the send rate the throttle currently allows, while a
+	// throttled campaign is running.
+	PerMinute float64 `json:"per_minute,omitempty"`
+}
+
+// CreateCampaignRequest is the API request payload for creating a campaign.
+type CreateCampaignRequest struct {
+	Name     string           `json:"name" binding:"required,max=200"`
+	Channel  Channel          `json:"channel" binding:"required,oneof=email sms push"`
+	Type     NotificationType `json:"type" binding:"required"`
+	Data     map[string]any   `json:"data"`
+	Audience Recipients       `json:"audience" binding:"required,min=1,dive,required"`
+
+	// ScheduledAt delays the first batch; empty or past starts right away.
+	ScheduledAt *time.Time `json:"scheduled_at"`
+
+	// SendAtLocal sends to each audience member at this local time, e.g.
+	// "2024-06-01T09:00", in their timezone: Timezones, keyed by audience
+	// member, or Timezone (default UTC). Members whose time has passed are
+	// sent to right away. It cannot be combined with ScheduledAt.
+	SendAtLocal string            `json:"send_at_local" binding:"omitempty,max=19"`
+	Timezone    string            `json:"timezone" binding:"omitempty,max=64"`
+	Timezones   map[string]string `json:"timezones" binding:"omitempty,dive,max=64"`
+
+	Throttle *CampaignThrottle `json:"throttle"`
+}
+
+// CampaignStore defines the contract for campaign persistence.
+// Implementations live in internal/infra/store/.
+type CampaignStore interface {
+	// CreateCampaign inserts campaign, filling in its ID and timestamps.
+	CreateCampaign(ctx context.Context, campaign *Campaign) error
+
+	// GetCampaign retrieves a campaign with its audience. Returns nil, nil if
+	// none exists.
+	GetCampaign(ctx context.Context, id string) (*Campaign, error)
+
+	// ListCampaigns returns up to limit campaigns without their audience,
+	// newest first.
+	ListCampaigns(ctx context.Context, limit int) ([]*Campaign, error)
+
+	// TransitionCampaign stores campaign's status, error, and start and
+	// completion times, but only if its stored status is one of from. It
+	// reports whether it did. A finished campaign's audience is cleared.
+	TransitionCampaign(ctx context.Context, campaign *Campaign, from ...CampaignStatus) (bool, error)
+
+	// RecordCampaignBatch stores the fan-out position and suppressed count
+	// after a batch.
+	RecordCampaignBatch(ctx context.Context, id string, dispatched, suppressed int) error
+
+	// CountCampaignLogs returns the number of the campaign's logs in each status.
+	CountCampaignLogs(ctx context.Context, id string) (map[NotificationStatus]int, error)
+}
+
+// CampaignEnqueuer enqueues campaign fan-out batches and the sends they
+// create, apart from other notifications so a campaign cannot hold them up.
+type CampaignEnqueuer interface {
+	Enqueuer
+
+	// EnqueueCampaignDispatch enqueues the batch starting at audience index
+	// cursor, to run after delay. Enqueuing the same batch twice is a no-op.
+	EnqueueCampaignDispatch(campaignID string, cursor int, delay time.Duration) error
+
+	// EnqueueCampaignSend enqueues the send of a campaign log.
+	EnqueueCampaignSend(logID string) error
+}
+
+// CampaignConfig holds configuration for campaign fan-out.
+type CampaignConfig struct {
+	// BatchSize is how many audience members one dispatch task fans out.
+	BatchSize int
+
+	// BatchInterval is the pause between batches, throttling the campaign
+	// to BatchSize sends per interval. A campaign with its own throttle sizes
+	// batches to span about this long instead.
+	BatchInterval time.Duration
+
+	// MaxAudience caps the audience of one campaign.
+	MaxAudience int
+
+	// MaxDataSize caps the JSON-encoded size of a campaign's template data
+	// (default DefaultMaxDataSize).
+	MaxDataSize int
+
+	// SuppressBounced skips audience members that bounced or complained
+	// before. It can be changed later with SetSuppressBounced.
+	SuppressBounced bool
+
+	// RenderAtEnqueue renders the template once when a campaign is created
+	// and sends that content to the whole audience. It can be changed later
+	// with SetRenderAtEnqueue.
+	RenderAtEnqueue bool
+
+	// ProgressInterval is how often Watch checks a campaign's progress.
+	ProgressInterval time.Duration
+}
+
+// withDefaults fills zero or negative fields with sensible defaults.
+func (c CampaignConfig) withDefaults() CampaignConfig {
+	if c.BatchSize <= 0 {
+		c.BatchSize = 100
+	}
+	if c.BatchInterval < 0 {
+		c.BatchInterval = 0
+	}
+	if c.MaxAudience <= 0 {
+		c.MaxAudience = 10000
+	}
+	if c.MaxDataSize <= 0 {
+		c.MaxDataSize = DefaultMaxDataSize
+	}
+	if c.ProgressInterval <= 0 {
+		c.ProgressInterval = 2 * time.Second
+	}
+	return c
+}
+
+// maxCampaignList is how many campaigns List returns.
+const maxCampaignList = 100
+
+// Campaigner creates campaigns from the API and fans them out from the
+// worker. Campaign sends skip the per-recipient rate limit, which is meant
+// for transactional traffic; BatchSize and BatchInterval, or the campaign's
+// own throttle, pace them instead. Bounce suppression applies.
+type Campaigner struct {
+	store    CampaignStore
+	logs     NotificationStore
+	enqueuer CampaignEnqueuer
+	renderer TemplateRenderer
+	variants *Variants
+	config   CampaignConfig
+
+	suppressBounced atomic.Bool
+	renderAtEnqueue atomic.Bool
+}
+
+// NewCampaigner creates a new Campaigner. renderer may be nil to disable
+// rendering at enqueue.
+func NewCampaigner(store CampaignStore, logs NotificationStore, enqueuer CampaignEnqueuer, renderer TemplateRenderer, cfg CampaignConfig) *Campaigner {
+	c := &Campaigner{
+		store:    store,
+		logs:     logs,
+		enqueuer: enqueuer,
+		renderer: renderer,
+		config:   cfg.withDefaults(),
+	}
+	c.suppressBounced.Store(cfg.SuppressBounced)
+	c.renderAtEnqueue.Store(cfg.RenderAtEnqueue)
+	return c
+}
+
+// SetSuppressBounced toggles bounce suppression for batches dispatched from
+// now on. Safe for concurrent use.
+func (c *Campaigner) SetSuppressBounced(enabled bool) {
+	c.suppressBounced.Store(enabled)
+}
+
+// SetRenderAtEnqueue chooses whether campaigns created from now on are
+// rendered at creation. Safe for concurrent use.
+func (c *Campaigner) SetRenderAtEnqueue(enabled bool) {
+	c.renderAtEnqueue.Store(enabled)
+}
+
+// SetVariants enables A/B tests of templates for campaigns rendered by the
+// worker: each audience member's log gets the variant they fall in. A
+// campaign rendered at creation sends the type's own templates to everyone.
+// Call it before campaigns are dispatched.
+func (c *Campaigner) SetVariants(variants *Variants) {
+	c.variants = variants
+}
+
+// Create validates and stores a campaign and enqueues its first batch.
+func (c *Campaigner) Create(ctx context.Context, req *CreateCampaignRequest) (*Campaign, error) {
+	if !IsValidType(req.Type) {
+		return nil, common.NewValidationError(fmt.Sprintf("unsupported notification type: %s", req.Type))
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, common.NewValidationError("name is required")
+	}
+
+	audience := req.Audience.Normalize()
+	if len(audience) == 0 {
+		return nil, common.NewValidationError("at least one audience member is required")
+	}
+	if len(audience) > c.config.MaxAudience {
+		return nil, common.NewValidationError(fmt.Sprintf("audience too large: %d (max %d)", len(audience), c.config.MaxAudience))
+	}
+	for _, to := range audience {
+		if err := ValidateRecipient(req.Channel, to); err != nil {
+			return nil, common.NewValidationError(err.Error())
+		}
+	}
+	if key, err := ValidateData(req.Data, c.config.MaxDataSize); err != nil {
+		return nil, common.NewFieldError(dataField(key), err.Error())
+	}
+	if req.Throttle != nil {
+		if err := req.Throttle.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	// A local send time starts the campaign when its first member is due
+	scheduledAt := req.ScheduledAt
+	sendAtLocal, timezone := strings.TrimSpace(req.SendAtLocal), strings.TrimSpace(req.Timezone)
+	var timezones map[string]string
+	switch {
+	case sendAtLocal == "" && (timezone != "" || len(req.Timezones) > 0):
+		return nil, common.NewValidationError("timezone and timezones require send_at_local")
+	case sendAtLocal != "" && req.ScheduledAt != nil:
+		return nil, common.NewValidationError("send_at_local and scheduled_at cannot be combined")
+	case sendAtLocal != "":
+		timezones = make(map[string]string, len(req.Timezones))
+		for to, tz := range req.Timezones {
+			timezones[strings.ToLower(strings.TrimSpace(to))] = strings.TrimSpace(tz)
+		}
+		schedule, err := newLocalSchedule(sendAtLocal, timezone, timezones)
+		if err != nil {
+			return nil, err
+		}
+		first, err := schedule.order(audience)
+		if err != nil {
+			return nil, err
+		}
+		scheduledAt = &first
+	}
+
+	var content *RenderedContent
+	if c.renderer != nil && c.renderAtEnqueue.Load() {
+		var err error
+		if content, err = renderContent(c.renderer, TemplateVariant{}, req.Channel, req.Type, req.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().UTC()
+	campaign := &Campaign{
+		Name:     name,
+		Channel:  req.Channel,
+		Type:     req.Type,
+		Data:     req.Data,
+		Audience: audience,
+		Throttle: req.Throttle,
+		Content:  content,
+		Total:    len(audience),
+		Status:   CampaignRunning,
+
+		SendAtLocal: sendAtLocal,
+		Timezone:    timezone,
+		Timezones:   timezones,
+	}
+	var delay time.Duration
+	if scheduledAt != nil && scheduledAt.After(now) {
+		scheduledAt := scheduledAt.UTC()
+		campaign.ScheduledAt = &scheduledAt
+		campaign.Status = CampaignScheduled
+		delay = scheduledAt.Sub(now)
+	} else {
+		campaign.StartedAt = &now
+	}
+
+	if err := c.store.CreateCampaign(ctx, campaign); err != nil {
+		return nil, fmt.Errorf("creating campaign: %w", err)
+	}
+
+	if err := c.enqueuer.EnqueueCampaignDispatch(campaign.ID, 0, delay); err != nil {
+		err = fmt.Errorf("enqueuing campaign: %w", err)
+		c.fail(ctx, campaign, err)
+		return nil, err
+	}
+
+	slog.Info("campaign created",
+		"campaign_id", campaign.ID,
+		"type", campaign.Type,
+		"total", campaign.Total,
+		"status", campaign.Status,
+	)
+	return campaign, nil
+}
+
+// Get returns a campaign with its progress.
+func (c *Campaigner) Get(ctx context.Context, id string) (*Campaign, error) {
+	campaign, err := c.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := c.store.CountCampaignLogs(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("counting campaign logs: %w", err)
+	}
+	campaign.Progress = newCampaignProgress(campaign, counts)
+	return campaign, nil
+}
+
+// Watch calls emit with the campaign and its progress (as Get returns them)
+// right away and then whenever its status or counts change, checking every
+// ProgressInterval, until the campaign is finished with no logs left queued,
+// ctx is done, or emit fails. It returns nil once the campaign is settled.
+func (c *Campaigner) Watch(ctx context.Context, id string, emit func(*Campaign) error) error {
+	ticker := time.NewTicker(c.config.ProgressInterval)
+	defer ticker.Stop()
+
+	var last *Campaign
+	for {
+		campaign, err := c.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if last == nil || progressChanged(last, campaign) {
+			if err := emit(campaign); err != nil {
+				return err
+			}
+			last = campaign
+		}
+		if campaign.Status.finished() && campaign.Progress.Queued == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// progressChanged reports whether b's status or counts differ from a's.
+func progressChanged(a, b *Campaign) bool {
+	if a.Status != b.Status || a.Dispatched != b.Dispatched || a.Suppressed != b.Suppressed {
+		return true
+	}
+	pa, pb := a.Progress, b.Progress
+	return pa.Pending != pb.Pending || pa.Queued != pb.Queued || pa.Sent != pb.Sent ||
+		pa.Failed != pb.Failed || !maps.Equal(pa.ByStatus, pb.ByStatus)
+}
+
+// List returns the most recent campaigns, newest first, without progress.
+func (c *Campaigner) List(ctx context.Context) ([]*Campaign, error) {
+	campaigns, err := c.store.ListCampaigns(ctx, maxCampaignList)
+	if err != nil {
+		return nil, fmt.Errorf("listing campaigns: %w", err)
+	}
+	return campaigns, nil
+}
+
+// Pause stops a scheduled or running campaign after the batch in flight.
+func (c *Campaigner) Pause(ctx context.Context, id string) (*Campaign, error) {
+	return c.transition(ctx, id, CampaignPaused, CampaignScheduled, CampaignRunning)
+}
+
+// Cancel stops a campaign for good. Sends already enqueued are not recalled.
+func (c *Campaigner) Cancel(ctx context.Context, id string) (*Campaign, error) {
+	return c.transition(ctx, id, CampaignCancelled, CampaignScheduled, CampaignRunning, CampaignPaused)
+}
+
+// Resume continues a paused campaign from where it stopped.
+func (c *Campaigner) Resume(ctx context.Context, id string) (*Campaign, error) {
+	campaign, err := c.transition(ctx, id, CampaignRunning, CampaignPaused)
+	if err != nil {
+		return nil, err
+	}
+
+	// A campaign paused before its scheduled time still waits for it, and
+	// one sent at a local time for its next member
+	var delay time.Duration
+	if campaign.ScheduledAt != nil {
+		delay = max(time.Until(*campaign.ScheduledAt), 0)
+	}
+	schedule, err := campaign.localSchedule()
+	if err != nil {
+		return nil, fmt.Errorf("campaign %s: %w", campaign.ID, err)
+	}
+	if schedule != nil && campaign.Dispatched < len(campaign.Audience) {
+		due, err := schedule.due(campaign.Audience[campaign.Dispatched])
+		if err != nil {
+			return nil, fmt.Errorf("campaign %s: %w", campaign.ID, err)
+		}
+		delay = max(delay, time.Until(due))
+	}
+	if err := c.enqueuer.EnqueueCampaignDispatch(campaign.ID, campaign.Dispatched, delay); err != nil {
+		return nil, fmt.Errorf("enqueuing campaign: %w", err)
+	}
+	return campaign, nil
+}
+
+// transition moves a campaign to status if it is currently in one of from,
+// and returns it with its progress.
+func (c *Campaigner) transition(ctx context.Context, id string, status CampaignStatus, from ...CampaignStatus) (*Campaign, error) {
+	campaign, err := c.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := campaign.Status
+	campaign.Status = status
+	if status == CampaignRunning && campaign.StartedAt == nil && campaign.ScheduledAt == nil {
+		now := time.Now().UTC()
+		campaign.StartedAt = &now
+	}
+	if status.finished() {
+		now := time.Now().UTC()
+		campaign.CompletedAt = &now
+	}
+
+	ok, err := c.store.TransitionCampaign(ctx, campaign, from...)
+	if err != nil {
+		return nil, fmt.Errorf("updating campaign: %w", err)
+	}
+	if !ok {
+		return nil, common.NewConflictError(fmt.Sprintf("campaign %s is %s and cannot become %s", id, previous, status), id)
+	}
+
+	slog.Info("campaign status changed", "campaign_id", id, "from", previous, "to", status)
+	return c.Get(ctx, id)
+}
+
+// Dispatch fans out the campaign's next batch and enqueues the one after it.
+// It runs as a worker task and is safe to retry: each recipient's log has the
+// idempotency key "campaign:<id>:<recipient>", so a batch that is run again
+// skips the logs it already created. A campaign that is no longer running is
+// left alone, which is how pausing and cancelling stop the fan-out.
+func (c *Campaigner) Dispatch(ctx context.Context, campaignID string) error {
+	campaign, err := c.store.GetCampaign(ctx, campaignID)
+	if err != nil {
+		return fmt.Errorf("fetching campaign %s: %w", campaignID, err)
+	}
+	if campaign == nil {
+		return common.NewPermanentError(fmt.Errorf("campaign not found: %s", campaignID))
+	}
+
+	switch campaign.Status {
+	case CampaignScheduled:
+		now := time.Now().UTC()
+		campaign.Status = CampaignRunning
+		campaign.StartedAt = &now
+		ok, err := c.store.TransitionCampaign(ctx, campaign, CampaignScheduled)
+		if err != nil {
+			return fmt.Errorf("starting campaign %s: %w", campaignID, err)
+		}
+		if !ok {
+			return nil // paused or cancelled meanwhile
+		}
+		slog.Info("campaign started", "campaign_id", campaignID, "total", campaign.Total)
+	case CampaignRunning:
+	default:
+		slog.Info("campaign not running, stopping fan-out", "campaign_id", campaignID, "status", campaign.Status)
+		return nil
+	}
+
+	size, delay := c.config.BatchSize, c.config.BatchInterval
+	if campaign.Throttle != nil {
+		size, delay = pace(campaign.Throttle.PerMinute(campaign.elapsed()), c.config.BatchSize, c.config.BatchInterval)
+	}
+
+	start := min(campaign.Dispatched, len(campaign.Audience))
+	end := min(start+size, len(campaign.Audience))
+	if end, delay, err = c.dueBatch(campaign, start, end, delay); err != nil {
+		return err
+	}
+	suppressed := 0
+	for _, to := range campaign.Audience[start:end] {
+		created, err := c.dispatchOne(ctx, campaign, to)
+		if err != nil {
+			return err
+		}
+		if !created {
+			suppressed++
+		}
+	}
+
+	if err := c.store.RecordCampaignBatch(ctx, campaignID, end, campaign.Suppressed+suppressed); err != nil {
+		return fmt.Errorf("recording campaign batch: %w", err)
+	}
+	slog.Info("campaign batch dispatched",
+		"campaign_id", campaignID,
+		"dispatched", end,
+		"total", campaign.Total,
+		"suppressed", suppressed,
+		"next_in", delay,
+	)
+
+	if end < len(campaign.Audience) {
+		if err := c.enqueuer.EnqueueCampaignDispatch(campaignID, end, delay); err != nil {
+			return fmt.Errorf("enqueuing next campaign batch: %w", err)
+		}
+		return nil
+	}
+
+	now := time.Now().UTC()
+	campaign.Status = CampaignCompleted
+	campaign.CompletedAt = &now
+	if _, err := c.store.TransitionCampaign(ctx, campaign, CampaignRunning); err != nil {
+		return fmt.Errorf("completing campaign: %w", err)
+	}
+	slog.Info("campaign completed", "campaign_id", campaignID, "total", campaign.Total)
+	return nil
+}
+
+// dueBatch cuts the batch from start to end short at the first member of a
+// send_at_local campaign who is not due yet, and stretches delay to when the
+// member after the batch is due. Other campaigns' batches are returned as is.
+func (c *Campaigner) dueBatch(campaign *Campaign, start, end int, delay time.Duration) (int, time.Duration, error) {
+	schedule, err := campaign.localSchedule()
+	if err != nil {
+		return 0, 0, common.NewPermanentError(fmt.Errorf("campaign %s: %w", campaign.ID, err))
+	}
+	if schedule == nil {
+		return end, delay, nil
+	}
+
+	now := time.Now()
+	for i := start; i < end; i++ {
+		due, err := schedule.due(campaign.Audience[i])
+		if err != nil {
+			return 0, 0, common.NewPermanentError(fmt.Errorf("campaign %s: %w", campaign.ID, err))
+		}
+		if due.After(now.Add(localDueSlack)) {
+			if i == start {
+				// Run early, e.g. by clock skew: retry when due rather than
+				// re-enqueue under this batch's task ID, which is still taken
+				return 0, 0, common.NewEarlyError(fmt.Errorf("campaign %s batch at %d is not due until %s", campaign.ID, start, due.Format(time.RFC3339)), due.Sub(now))
+			}
+			end = i
+			break
+		}
+	}
+	if end < len(campaign.Audience) {
+		due, err := schedule.due(campaign.Audience[end])
+		if err != nil {
+			return 0, 0, common.NewPermanentError(fmt.Errorf("campaign %s: %w", campaign.ID, err))
+		}
+		delay = max(delay, due.Sub(now))
+	}
+	return end, delay, nil
+}
+
+// dispatchOne creates and enqueues the log for one audience member. It
+// reports false when the recipient was suppressed. A log that exists from an
+// earlier attempt is left as is; the reaper recovers it if it was never enqueued.
+func (c *Campaigner) dispatchOne(ctx context.Context, campaign *Campaign, to string) (bool, error) {
+	key := fmt.Sprintf("campaign:%s:%s", campaign.ID, strings.ToLower(to))
+	existing, err := c.logs.GetByIdempotencyKey(ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("checking campaign log for %s: %w", to, err)
+	}
+	if existing != nil {
+		return true, nil
+	}
+
+	if c.suppressBounced.Load() {
+		bounced, err := c.logs.HasBounced(ctx, to)
+		if err != nil {
+			slog.Error("bounce suppression check failed, proceeding", "campaign_id", campaign.ID, "recipient", to, "error", err)
+		} else if bounced {
+			return false, nil
+		}
+	}
+
+	req := SendRequest{Channel: campaign.Channel, Type: campaign.Type, To: Recipients{to}, Data: campaign.Data}
+	notifLog := &NotificationLog{
+		IdempotencyKey: key,
+		PayloadHash:    req.PayloadHash(),
+		CampaignID:     campaign.ID,
+		Channel:        string(campaign.Channel),
+		Type:           string(campaign.Type),
+		Recipient:      to,
+		TemplateData:   campaign.Data,
+		Content:        campaign.Content,
+		Status:         StatusQueued,
+	}
+	if campaign.Content == nil {
+		notifLog.Variant = c.variants.pick(campaign.Type, to).Name
+	}
+	if err := c.logs.Create(ctx, notifLog); err != nil {
+		return false, fmt.Errorf("creating campaign log for %s: %w", to, err)
+	}
+
+	if err := c.enqueuer.EnqueueCampaignSend(notifLog.ID); err != nil {
+		slog.Error("campaign send enqueue failed", "campaign_id", campaign.ID, "log_id", notifLog.ID, "error", err)
+		_ = c.logs.UpdateStatus(ctx, notifLog.ID, StatusFailed, "", "failed to enqueue: "+err.Error())
+	}
+	return true, nil
+}
+
+// get fetches a campaign or returns a NotFoundError.
+func (c *Campaigner) get(ctx context.Context, id string) (*Campaign, error) {
+	campaign, err := c.store.GetCampaign(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("fetching campaign: %w", err)
+	}
+	if campaign == nil {
+		return nil, common.NewNotFoundError("campaign", id)
+	}
+	return campaign, nil
+}
+
+// fail marks a campaign that could not be started as failed.
+func (c *Campaigner) fail(ctx context.Context, campaign *Campaign, cause error) {
+	now := time.Now().UTC()
+	campaign.Status = CampaignFailed
+	campaign.Error = cause.Error()
+	campaign.CompletedAt = &now
+	if _, err := c.store.TransitionCampaign(context.WithoutCancel(ctx), campaign, CampaignScheduled, CampaignRunning); err != nil {
+		slog.Error("failed to mark campaign failed", "campaign_id", campaign.ID, "error", err)
+	}
+}
+
+// newCampaignProgress summarizes counts, the campaign's logs by status.
+func newCampaignProgress(campaign *Campaign, counts map[NotificationStatus]int) *CampaignProgress {
+	progress := &CampaignProgress{
+		Pending:  max(campaign.Total-campaign.Dispatched, 0),
+		ByStatus: counts,
+	}
+	if campaign.Status.finished() {
+		progress.Pending = 0
+	}
+	if campaign.Throttle != nil && campaign.Status == CampaignRunning {
+		progress.PerMinute = math.Round(campaign.Throttle.PerMinute(campaign.elapsed()))
+	}
+	for status, n := range counts {
+		switch status {
+		case StatusQueued, StatusProcessing:
+			progress.Queued += n
+		case StatusSent, StatusDelivered, StatusOpened, StatusClicked:
+			progress.Sent += n
+		case StatusFailed, StatusAbandoned, StatusBounced, StatusComplained:
+			progress.Failed += n
+		}
+	}
+	return progress
+}
@@ -0,0 +1,110 @@
+package notification
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// NamedProvider is optionally implemented by a Provider to name the service
+// it sends through, e.g. "resend". Prices are looked up by this name.
+type NamedProvider interface {
+	Name() string
+}
+
+// ProviderName returns p's name, or "" when it does not implement NamedProvider.
+func ProviderName(p Provider) string {
+	if named, ok := p.(NamedProvider); ok {
+		return named.Name()
+	}
+	return ""
+}
+
+// PriceDefault is the Prices entry that applies to a channel's providers
+// without a price of their own.
+const PriceDefault = "default"
+
+// Prices is the estimated cost of sending one message, by channel and then
+// by provider name (or PriceDefault). All prices are in one currency of the
+// operator's choosing.
+type Prices map[Channel]map[string]float64
+
+// lookup returns the price of one message through provider on channel.
+func (p Prices) lookup(channel Channel, provider string) (float64, bool) {
+	byProvider := p[channel]
+	if price, ok := byProvider[provider]; ok && provider != "" {
+		return price, true
+	}
+	price, ok := byProvider[PriceDefault]
+	return price, ok
+}
+
+// CostTotal is the estimated cost of the messages one API key sent of one
+// channel and type on one UTC day. Logs accepted without an API key (e.g.
+// campaigns and schedules) have an empty APIKeyID.
+type CostTotal struct {
+	Day      string           `json:"day"` // YYYY-MM-DD
+	APIKeyID string           `json:"api_key_id,omitempty"`
+	Channel  Channel          `json:"channel"`
+	Type     NotificationType `json:"type"`
+	Sends    int64            `json:"sends"`
+	Cost     float64          `json:"cost"`
+}
+
+// CostStore defines the contract for the daily cost totals the workers record
+// and the stats endpoint reads. Implementations live in internal/infra/metrics/.
+type CostStore interface {
+	// RecordCost adds one priced send to today's total for the API key,
+	// channel, and type.
+	RecordCost(ctx context.Context, apiKeyID string, channel Channel, notifType NotificationType, cost float64) error
+
+	// CostTotals returns the daily totals from since's day through today.
+	CostTotals(ctx context.Context, since time.Time) ([]*CostTotal, error)
+}
+
+// costStatsDays is how many days of cost totals Stats reports, today included.
+const costStatsDays = 30
+
+// SetPrices replaces the prices sends are costed at; without any, sends are
+// not costed. Safe for concurrent use; sends already under way keep the old
+// prices.
+func (w *Worker) SetPrices(prices Prices) {
+	w.prices.Store(&prices)
+}
+
+// SetCosts enables the daily cost totals: each costed send is added to costs.
+// Call it before processing starts.
+func (w *Worker) SetCosts(costs CostStore) {
+	w.costs = costs
+}
+
+// estimateCost returns the estimated cost of sending notifLog through
+// provider, or nil when no price applies.
+func (w *Worker) estimateCost(notifLog *NotificationLog, provider Provider) *float64 {
+	prices := w.prices.Load()
+	if prices == nil {
+		return nil
+	}
+	price, ok := prices.lookup(Channel(notifLog.Channel), ProviderName(provider))
+	if !ok {
+		return nil
+	}
+	return &price
+}
+
+// recordCost adds a costed send to the daily totals. A failure is logged: the
+// cost is on the log either way.
+func (w *Worker) recordCost(ctx context.Context, notifLog *NotificationLog, cost *float64) {
+	if w.costs == nil || cost == nil {
+		return
+	}
+	err := w.costs.RecordCost(context.WithoutCancel(ctx), notifLog.APIKeyID, Channel(notifLog.Channel), NotificationType(notifLog.Type), *cost)
+	if err != nil {
+		slog.Warn("failed to record cost", "log_id", notifLog.ID, "error", err)
+	}
+}
+
+// SetCosts enables cost reporting in Stats from the daily totals in costs.
+func (s *Service) SetCosts(costs CostStore) {
+	s.costs = costs
+}
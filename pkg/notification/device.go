@@ -0,0 +1,136 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+)
+
+// Platform identifies the push service a device token belongs to.
+type Platform string
+
+const (
+	PlatformFCM  Platform = "fcm"
+	PlatformAPNs Platform = "apns"
+)
+
+// IsValidPlatform checks whether a platform is supported.
+func IsValidPlatform(p Platform) bool {
+	return p == PlatformFCM || p == PlatformAPNs
+}
+
+// Device is a push device token registered for a user. A token belongs to at
+// most one user: registering it again moves it to the new user.
+type Device struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Token      string    `json:"token"`
+	Platform   Platform  `json:"platform"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// RegisterDeviceRequest is the API request payload for registering a device
+// token. Apps should register on every launch so LastSeenAt stays current.
+type RegisterDeviceRequest struct {
+	UserID   string   `json:"user_id" binding:"required,max=200"`
+	Token    string   `json:"token" binding:"required,max=4096"`
+	Platform Platform `json:"platform" binding:"required"`
+}
+
+// DeviceStore defines the contract for device token persistence.
+// Implementations live in internal/infra/store/.
+type DeviceStore interface {
+	// UpsertDevice stores device keyed by its token, replacing the user,
+	// platform, and last-seen time of an existing row, and fills in its ID
+	// and CreatedAt.
+	UpsertDevice(ctx context.Context, device *Device) error
+
+	// ListDevices returns a user's devices, most recently seen first.
+	ListDevices(ctx context.Context, userID string) ([]*Device, error)
+
+	// DeleteDevice removes a token. Returns false if it was not registered.
+	DeleteDevice(ctx context.Context, token string) (bool, error)
+
+	// DeleteDeviceTokens removes every given token and returns how many were
+	// registered.
+	DeleteDeviceTokens(ctx context.Context, tokens []string) (int, error)
+}
+
+// Devices manages the push device token registry.
+type Devices struct {
+	store DeviceStore
+}
+
+// NewDevices creates a new device registry.
+func NewDevices(store DeviceStore) *Devices {
+	return &Devices{store: store}
+}
+
+// Register adds a device token, or refreshes it if already registered.
+func (d *Devices) Register(ctx context.Context, req *RegisterDeviceRequest) (*Device, error) {
+	device := &Device{
+		UserID:     strings.TrimSpace(req.UserID),
+		Token:      strings.TrimSpace(req.Token),
+		Platform:   Platform(strings.ToLower(string(req.Platform))),
+		LastSeenAt: time.Now().UTC(),
+	}
+	if device.UserID == "" {
+		return nil, common.NewValidationError("user_id is required")
+	}
+	if device.Token == "" {
+		return nil, common.NewValidationError("token is required")
+	}
+	if !IsValidPlatform(device.Platform) {
+		return nil, common.NewValidationError(fmt.Sprintf("unsupported platform: %s (expected fcm or apns)", req.Platform))
+	}
+
+	if err := d.store.UpsertDevice(ctx, device); err != nil {
+		return nil, fmt.Errorf("registering device: %w", err)
+	}
+
+	slog.Info("device registered", "device_id", device.ID, "user_id", device.UserID, "platform", device.Platform)
+	return device, nil
+}
+
+// List returns a user's devices, most recently seen first.
+func (d *Devices) List(ctx context.Context, userID string) ([]*Device, error) {
+	devices, err := d.store.ListDevices(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing devices: %w", err)
+	}
+	return devices, nil
+}
+
+// Unregister removes a device token, e.g. when the user signs out.
+func (d *Devices) Unregister(ctx context.Context, token string) error {
+	deleted, err := d.store.DeleteDevice(ctx, token)
+	if err != nil {
+		return fmt.Errorf("unregistering device: %w", err)
+	}
+	if !deleted {
+		return common.NewNotFoundError("device", token)
+	}
+
+	slog.Info("device unregistered")
+	return nil
+}
+
+// RemoveInvalid drops tokens a push service reported as invalid, so later
+// sends stop failing on devices that are gone.
+func (d *Devices) RemoveInvalid(ctx context.Context, tokens []string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	removed, err := d.store.DeleteDeviceTokens(ctx, tokens)
+	if err != nil {
+		return fmt.Errorf("removing invalid device tokens: %w", err)
+	}
+
+	slog.Info("invalid device tokens removed", "reported", len(tokens), "removed", removed)
+	return nil
+}
@@ -0,0 +1,167 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+)
+
+// DigestEnqueuer is optionally implemented by an Enqueuer that can hold the
+// sends of digested types back and combine those of one group into one send
+// digest task (Worker.ProcessDigest) once the group has been quiet for a
+// grace period. Without it, digested types are sent one by one.
+type DigestEnqueuer interface {
+	EnqueueDigestEvent(group, logID string) error
+}
+
+// DigestGroup names the digest group of a log: its channel, type, and
+// recipient. Events of one group are sent together.
+func DigestGroup(notifLog *NotificationLog) string {
+	return notifLog.Channel + ":" + notifLog.Type + ":" + notifLog.Recipient
+}
+
+// Template data keys added for a digest of more than one event: the number
+// of events, and each event's own data, oldest first. The rest of the data is
+// the newest event's, so a template unaware of digests renders that one.
+const (
+	DigestCountKey = "DigestCount"
+	DigestItemsKey = "Digest"
+)
+
+// digests returns the DigestEnqueuer when notifType is digested and the
+// Enqueuer implements it, or nil.
+func (s *Service) digests(notifType NotificationType) DigestEnqueuer {
+	digests, ok := s.enqueuer.(DigestEnqueuer)
+	if !ok || !slices.Contains(s.config.DigestTypes, notifType) {
+		return nil
+	}
+	return digests
+}
+
+// ProcessDigest handles a send digest task: the events of one digest group,
+// oldest first. The sendable ones go out as one message rendered from the
+// newest, with every event's data under DigestItemsKey; each log records the
+// same provider message ID, so delivery webhooks update them all. The message
+// is costed and counted once, on the newest log. A single event is sent like
+// any other notification.
+func (w *Worker) ProcessDigest(ctx context.Context, logIDs []string) (err error) {
+	start := time.Now()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = w.recovered(ctx, r, logIDs...)
+		}
+	}()
+
+	var logs []*NotificationLog
+	for _, logID := range logIDs {
+		notifLog, err := w.store.GetByID(ctx, logID)
+		if err != nil {
+			return fmt.Errorf("fetching notification log %s: %w", logID, err)
+		}
+		if notifLog == nil {
+			slog.Error("notification log not found", "log_id", logID)
+			continue
+		}
+		if !isSendable(notifLog) {
+			continue // sent by an earlier attempt, or failed for good
+		}
+		logs = append(logs, notifLog)
+	}
+	switch len(logs) {
+	case 0:
+		return nil
+	case 1:
+		return w.ProcessTask(ctx, logs[0].ID)
+	}
+
+	for _, notifLog := range logs {
+		if err := w.store.UpdateStatus(ctx, notifLog.ID, StatusProcessing, "", ""); err != nil {
+			slog.Error("failed to update status to processing", "log_id", notifLog.ID, "error", err)
+		}
+	}
+
+	// Render the newest event with every event's data; content stored at
+	// enqueue covers one event only, so it is rendered again
+	newest := *logs[len(logs)-1]
+	newest.Content = nil
+	newest.TemplateData = digestData(logs)
+
+	provider, msg, err := w.prepare(ctx, &newest)
+	if err != nil {
+		// prepare recorded the failure on the newest log; the others share it
+		for _, notifLog := range logs[:len(logs)-1] {
+			w.markFailed(ctx, notifLog.ID, "digest could not be prepared: "+err.Error(), "", false, nil)
+		}
+		return err
+	}
+
+	if err := w.held(ctx, provider, logs...); err != nil {
+		return err
+	}
+
+	providerID, metadata, err := sendMessage(ctx, provider, msg)
+	if err != nil {
+		timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+		permanent := !timedOut && common.IsPermanent(err)
+		errMsg := fmt.Sprintf("provider error: %s", err.Error())
+		for _, notifLog := range logs {
+			w.markFailed(ctx, notifLog.ID, errMsg, failureCode(err, timedOut), !permanent, metadata)
+		}
+		recordOutcome(ctx, w.outcomes, &newest, OutcomeFailed)
+		slog.Error("notification digest failed",
+			"group", DigestGroup(&newest),
+			"count", len(logs),
+			"error", err,
+			"retryable", !permanent,
+		)
+		if timedOut {
+			return fmt.Errorf("%w: %v", ErrTaskTimeout, err)
+		}
+		return w.providerError(provider, err, permanent)
+	}
+
+	cost := w.estimateCost(&newest, provider)
+	for _, notifLog := range logs {
+		var logCost *float64
+		if notifLog.ID == newest.ID {
+			logCost = cost
+		}
+		if err := w.store.RecordSent(context.WithoutCancel(ctx), notifLog.ID, providerID, logCost, metadata); err != nil {
+			slog.Error("failed to update status to sent", "log_id", notifLog.ID, "error", err)
+		}
+		observeLatency(ctx, w.latency, LatencyCreatedToSent, notifLog, time.Since(notifLog.CreatedAt))
+	}
+	w.recordCost(ctx, &newest, cost)
+	recordOutcome(ctx, w.outcomes, &newest, OutcomeSent)
+
+	slog.Info("notification digest sent",
+		"group", DigestGroup(&newest),
+		"count", len(logs),
+		"provider_id", providerID,
+		"duration", time.Since(start),
+	)
+	return nil
+}
+
+// digestData returns the template data of a digest of logs, oldest first:
+// the newest log's data, plus the count and every log's data.
+func digestData(logs []*NotificationLog) map[string]any {
+	newest := logs[len(logs)-1].TemplateData
+	data := make(map[string]any, len(newest)+2)
+	for k, v := range newest {
+		data[k] = v
+	}
+	items := make([]map[string]any, len(logs))
+	for i, notifLog := range logs {
+		items[i] = notifLog.TemplateData
+	}
+	data[DigestCountKey] = len(logs)
+	data[DigestItemsKey] = items
+	return data
+}
@@ -0,0 +1,70 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// ErrQueueUnavailable is wrapped by Enqueuer errors when the queue could not
+// be reached at all (e.g. Redis is down), as opposed to refusing the task.
+var ErrQueueUnavailable = errors.New("queue unavailable")
+
+// DirectSender sends a notification log in process, bypassing the queue.
+// *Worker implements it.
+type DirectSender interface {
+	ProcessTask(ctx context.Context, logID string) error
+}
+
+// SetDirectSender enables direct sends: a log of one of the critical types
+// whose send task cannot be enqueued because the queue is unavailable is sent
+// through sender during the request instead, each send bounded by timeout.
+// Call it before the service handles requests.
+func (s *Service) SetDirectSender(sender DirectSender, timeout time.Duration) {
+	s.direct = sender
+	s.directTimeout = timeout
+}
+
+// sendsDirect reports whether logs of notifType are sent directly when the
+// queue is unavailable.
+func (s *Service) sendsDirect(notifType NotificationType) bool {
+	return s.direct != nil && slices.Contains(s.config.CriticalTypes, notifType)
+}
+
+// sendDirect sends notifLog through the DirectSender after enqueueErr left it
+// without a task, recording on the log that it bypassed the queue. A failed
+// send is left failed and retryable on the log, for retry-failed once the
+// queue is back.
+func (s *Service) sendDirect(ctx context.Context, notifLog *NotificationLog, enqueueErr error) (*SendResponse, error) {
+	slog.Warn("queue unavailable, sending notification directly",
+		"id", notifLog.ID,
+		"type", notifLog.Type,
+		"error", enqueueErr,
+	)
+	if err := s.store.RecordDirectSend(ctx, notifLog.ID); err != nil {
+		slog.Error("failed to record direct send", "id", notifLog.ID, "error", err)
+	}
+
+	// The client hanging up must not cut the provider call short
+	sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.directTimeout)
+	defer cancel()
+	if err := s.direct.ProcessTask(sendCtx, notifLog.ID); err != nil {
+		return nil, fmt.Errorf("sending notification directly: %w", err)
+	}
+
+	status := StatusSent
+	if sent, err := s.store.GetByID(ctx, notifLog.ID); err == nil && sent != nil {
+		status = sent.Status
+	}
+	slog.Info("notification sent directly", "id", notifLog.ID, "type", notifLog.Type, "status", status)
+
+	return &SendResponse{
+		ID:             notifLog.ID,
+		IdempotencyKey: notifLog.IdempotencyKey,
+		Channel:        notifLog.Channel,
+		Status:         string(status),
+	}, nil
+}
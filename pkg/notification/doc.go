@@ -0,0 +1,21 @@
+// Package notification is Notifly's delivery pipeline: request validation and
+// enqueueing (Service), rendering and sending (Worker), stale task recovery
+// (Reaper), recurring notifications (Scheduler), and the Gin HTTP handler that
+// exposes them.
+//
+// The package depends only on the interfaces declared in provider.go, store.go,
+// and ratelimit.go, so another Go service can embed the pipeline in-process by
+// supplying its own implementations or the ones shipped with Notifly:
+//
+//	renderer, err := template.NewDefaultEngine() // or template.NewEngine(dir) to override
+//	if err != nil { ... }
+//	provider := email.NewResendProvider(apiKey, "noreply@example.com", "Example")
+//
+//	worker := notification.NewWorker(myStore, renderer, nil, provider)
+//	service := notification.NewService(myStore, myEnqueuer, myLimiter, nil, nil, notification.ServiceConfig{})
+//
+// Service.Enqueue persists a pending log and hands its ID to the Enqueuer;
+// the Enqueuer is expected to eventually call Worker.ProcessTask with that ID,
+// whether through a queue or directly. Errors are the typed errors from
+// github.com/badrkarrachai/notifly/pkg/common, which callers can inspect with errors.As.
+package notification
@@ -0,0 +1,189 @@
+package notification
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+)
+
+// ErasureStatus is the state of a recipient data erasure job.
+type ErasureStatus string
+
+const (
+	ErasurePending   ErasureStatus = "pending"
+	ErasureRunning   ErasureStatus = "running"
+	ErasureCompleted ErasureStatus = "completed"
+	ErasureFailed    ErasureStatus = "failed"
+)
+
+// ErasedRecipient replaces the recipient of anonymized logs.
+const ErasedRecipient = "[erased]"
+
+// ErasureJob tracks one erasure request (GDPR right to erasure). The job keeps
+// only a hash of the recipient, so it can be matched to a request without
+// storing the address being erased.
+type ErasureJob struct {
+	ID            string        `json:"id"`
+	RecipientHash string        `json:"recipient_hash"`
+	Status        ErasureStatus `json:"status"`
+	LogsErased    int           `json:"logs_erased"`
+	Error         string        `json:"error,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	CompletedAt   *time.Time    `json:"completed_at,omitempty"`
+}
+
+// ErasureStore defines the contract for erasure jobs and the anonymization
+// they perform. Implementations live in internal/infra/store/.
+type ErasureStore interface {
+	// CreateErasureJob inserts job, filling in its ID and CreatedAt.
+	CreateErasureJob(ctx context.Context, job *ErasureJob) error
+
+	// GetErasureJob retrieves a job by ID. Returns nil, nil if none exists.
+	GetErasureJob(ctx context.Context, id string) (*ErasureJob, error)
+
+	// UpdateErasureJob stores job's status, count, error, and completion time.
+	UpdateErasureJob(ctx context.Context, job *ErasureJob) error
+
+	// EraseRecipientLogs anonymizes up to limit logs addressed to recipient —
+	// as recipient, or in recipients, cc, or bcc — and returns how many it
+	// changed. Anonymized logs no longer match, so callers repeat until 0.
+	EraseRecipientLogs(ctx context.Context, recipient string, limit int) (int, error)
+
+	// EraseRecipientWebhookEvents deletes stored webhook events addressed to
+	// recipient and returns how many it deleted.
+	EraseRecipientWebhookEvents(ctx context.Context, recipient string) (int, error)
+}
+
+// ErasureEnqueuer enqueues erasure jobs for the worker.
+type ErasureEnqueuer interface {
+	EnqueueErasure(jobID, recipient string) error
+}
+
+// erasePageSize is how many logs one EraseRecipientLogs call anonymizes.
+const erasePageSize = 500
+
+// Eraser erases a recipient's personal data from the notification logs. The
+// API creates a job and enqueues it; a worker anonymizes the logs in pages, so
+// recipients with many logs don't hold an HTTP request open.
+type Eraser struct {
+	store    ErasureStore
+	enqueuer ErasureEnqueuer
+}
+
+// NewEraser creates a new Eraser.
+func NewEraser(store ErasureStore, enqueuer ErasureEnqueuer) *Eraser {
+	return &Eraser{store: store, enqueuer: enqueuer}
+}
+
+// Request creates a pending erasure job for recipient and enqueues it.
+func (e *Eraser) Request(ctx context.Context, recipient string) (*ErasureJob, error) {
+	recipient = strings.TrimSpace(recipient)
+	if recipient == "" {
+		return nil, common.NewValidationError("recipient is required")
+	}
+
+	job := &ErasureJob{RecipientHash: hashRecipient(recipient), Status: ErasurePending}
+	if err := e.store.CreateErasureJob(ctx, job); err != nil {
+		return nil, fmt.Errorf("creating erasure job: %w", err)
+	}
+
+	if err := e.enqueuer.EnqueueErasure(job.ID, recipient); err != nil {
+		e.finish(ctx, job, fmt.Errorf("enqueuing erasure job: %w", err))
+		return nil, fmt.Errorf("enqueuing erasure job: %w", err)
+	}
+
+	slog.Info("erasure requested", "job_id", job.ID)
+	return job, nil
+}
+
+// Job returns an erasure job by ID.
+func (e *Eraser) Job(ctx context.Context, id string) (*ErasureJob, error) {
+	job, err := e.store.GetErasureJob(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("fetching erasure job: %w", err)
+	}
+	if job == nil {
+		return nil, common.NewNotFoundError("erasure job", id)
+	}
+	return job, nil
+}
+
+// Process runs an erasure job: it anonymizes the recipient's logs page by page
+// and records the outcome on the job. Erasing is idempotent, so a retried task
+// picks up where the last attempt stopped.
+func (e *Eraser) Process(ctx context.Context, jobID, recipient string) error {
+	job, err := e.store.GetErasureJob(ctx, jobID)
+	if err != nil {
+		return fmt.Errorf("fetching erasure job %s: %w", jobID, err)
+	}
+	if job == nil {
+		return common.NewPermanentError(fmt.Errorf("erasure job not found: %s", jobID))
+	}
+	if job.Status == ErasureCompleted {
+		return nil
+	}
+
+	job.Status = ErasureRunning
+	job.Error = ""
+	if err := e.store.UpdateErasureJob(ctx, job); err != nil {
+		slog.Error("failed to mark erasure job running", "job_id", jobID, "error", err)
+	}
+
+	for {
+		erased, err := e.store.EraseRecipientLogs(ctx, recipient, erasePageSize)
+		if err != nil {
+			// Keep the job running: the task is retried and resumes from here
+			job.Error = err.Error()
+			if updateErr := e.store.UpdateErasureJob(context.WithoutCancel(ctx), job); updateErr != nil {
+				slog.Error("failed to record erasure error", "job_id", jobID, "error", updateErr)
+			}
+			return fmt.Errorf("erasing logs: %w", err)
+		}
+		if erased == 0 {
+			break
+		}
+		job.LogsErased += erased
+	}
+
+	// Raw webhook payloads carry the address too
+	events, err := e.store.EraseRecipientWebhookEvents(ctx, recipient)
+	if err != nil {
+		job.Error = err.Error()
+		if updateErr := e.store.UpdateErasureJob(context.WithoutCancel(ctx), job); updateErr != nil {
+			slog.Error("failed to record erasure error", "job_id", jobID, "error", updateErr)
+		}
+		return fmt.Errorf("erasing webhook events: %w", err)
+	}
+
+	e.finish(ctx, job, nil)
+	slog.Info("erasure completed", "job_id", jobID, "logs_erased", job.LogsErased, "webhook_events_erased", events)
+	return nil
+}
+
+// finish marks job completed, or failed with err.
+func (e *Eraser) finish(ctx context.Context, job *ErasureJob, err error) {
+	now := time.Now().UTC()
+	job.CompletedAt = &now
+	job.Status = ErasureCompleted
+	job.Error = ""
+	if err != nil {
+		job.Status = ErasureFailed
+		job.Error = err.Error()
+	}
+	if updateErr := e.store.UpdateErasureJob(context.WithoutCancel(ctx), job); updateErr != nil {
+		slog.Error("failed to update erasure job", "job_id", job.ID, "error", updateErr)
+	}
+}
+
+// hashRecipient fingerprints a recipient for the job record. Addresses are
+// compared case-insensitively, like recipient deduplication.
+func hashRecipient(recipient string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(recipient)))
+	return hex.EncodeToString(sum[:])
+}
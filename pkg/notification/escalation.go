@@ -0,0 +1,575 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+)
+
+// EscalationWebhook is the escalation step channel that calls a URL instead
+// of sending a notification — e.g. an on-call service that places a phone call.
+const EscalationWebhook Channel = "webhook"
+
+const (
+	maxEscalationSteps = 10
+	maxEscalationDelay = 7 * 24 * time.Hour
+)
+
+// EscalationStep is one step of an escalation chain. DelaySec counts from the
+// previous step, or from the original send for the first step.
+type EscalationStep struct {
+	Channel  Channel `json:"channel"` // email, sms, push, or webhook
+	DelaySec int     `json:"delay_sec"`
+	To       string  `json:"to,omitempty"`  // fixed address; otherwise the send's escalate_to entry for Channel
+	URL      string  `json:"url,omitempty"` // webhook steps only
+}
+
+// delay returns the time to wait before running the step.
+func (s EscalationStep) delay() time.Duration {
+	return time.Duration(s.DelaySec) * time.Second
+}
+
+// EscalationPolicy is the escalation chain run for every notification of
+// Type — e.g. security alerts: email, then SMS after 5 minutes, then a call
+// webhook after 10 more — until one of them is delivered or the notification
+// is acknowledged. A type has at most one policy.
+type EscalationPolicy struct {
+	ID        string           `json:"id"`
+	Name      string           `json:"name"`
+	Type      NotificationType `json:"type"`
+	Steps     []EscalationStep `json:"steps"`
+	Enabled   bool             `json:"enabled"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// CreateEscalationPolicyRequest is the API request payload for creating an
+// escalation policy.
+type CreateEscalationPolicyRequest struct {
+	Name    string           `json:"name" binding:"required,max=200"`
+	Type    NotificationType `json:"type" binding:"required"`
+	Steps   []EscalationStep `json:"steps" binding:"required"`
+	Enabled *bool            `json:"enabled"` // defaults to true
+}
+
+// UpdateEscalationPolicyRequest is the API request payload for changing an
+// escalation policy. Omitted fields keep their current value. Notifications
+// already escalating keep the steps they started with.
+type UpdateEscalationPolicyRequest struct {
+	Name    *string           `json:"name" binding:"omitempty,max=200"`
+	Type    *NotificationType `json:"type"`
+	Steps   []EscalationStep  `json:"steps"`
+	Enabled *bool             `json:"enabled"`
+}
+
+// Escalation is the escalation a log was accepted with: the policy's steps as
+// they were then, and the send's escalate_to addresses.
+type Escalation struct {
+	PolicyID string             `json:"policy_id"`
+	Steps    []EscalationStep   `json:"steps"`
+	To       map[Channel]string `json:"to,omitempty"`
+}
+
+// address returns where a step sends: its fixed address, or the send's
+// escalate_to entry for its channel.
+func (e *Escalation) address(step EscalationStep) string {
+	if step.To != "" {
+		return step.To
+	}
+	return e.To[step.Channel]
+}
+
+// EscalationWebhookPayload is the JSON body a webhook step POSTs. The request
+// carries an Idempotency-Key header of "escalation:<notification id>:<step>",
+// since a retried step can call the URL again.
+type EscalationWebhookPayload struct {
+	Event          string             `json:"event"` // always "notification.escalated"
+	NotificationID string             `json:"notification_id"`
+	PolicyID       string             `json:"policy_id"`
+	Step           int                `json:"step"` // zero-based index in the policy's steps
+	Type           string             `json:"type"`
+	Channel        string             `json:"channel"`
+	Recipient      string             `json:"recipient"`
+	UserID         string             `json:"user_id,omitempty"`
+	Status         NotificationStatus `json:"status"`
+	CreatedAt      time.Time          `json:"created_at"`
+}
+
+// EscalationPolicyStore defines the contract for escalation policy persistence.
+// Implementations live in internal/infra/store/.
+type EscalationPolicyStore interface {
+	// CreatePolicy inserts policy, filling in its ID and timestamps.
+	CreatePolicy(ctx context.Context, policy *EscalationPolicy) error
+
+	// GetPolicy retrieves a policy by ID. Returns nil, nil if none exists.
+	GetPolicy(ctx context.Context, id string) (*EscalationPolicy, error)
+
+	// GetPolicyByType retrieves the policy for a notification type. Returns
+	// nil, nil if none exists.
+	GetPolicyByType(ctx context.Context, notifType NotificationType) (*EscalationPolicy, error)
+
+	// ListPolicies returns every policy, oldest first.
+	ListPolicies(ctx context.Context) ([]*EscalationPolicy, error)
+
+	// UpdatePolicy stores policy's definition.
+	UpdatePolicy(ctx context.Context, policy *EscalationPolicy) error
+
+	// DeletePolicy removes a policy. Returns false if none existed.
+	DeletePolicy(ctx context.Context, id string) (bool, error)
+}
+
+// EscalationEnqueuer is optionally implemented by an Enqueuer that can
+// schedule escalation steps. Without it, escalation policies cannot run.
+type EscalationEnqueuer interface {
+	// EnqueueEscalationStep schedules Escalations.Step for logID and step
+	// after delay. Scheduling the same step twice is a no-op.
+	EnqueueEscalationStep(logID string, step int, delay time.Duration) error
+}
+
+// Escalations manages escalation policies and runs their steps: the server
+// starts an escalation when it accepts a notification of a covered type, and
+// the worker runs each step when its delay has passed.
+type Escalations struct {
+	policies EscalationPolicyStore
+	store    NotificationStore
+	enqueuer Enqueuer
+	client   *http.Client
+}
+
+// NewEscalations creates a new escalation manager.
+func NewEscalations(policies EscalationPolicyStore, store NotificationStore, enqueuer Enqueuer) *Escalations {
+	return &Escalations{
+		policies: policies,
+		store:    store,
+		enqueuer: enqueuer,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// CreatePolicy validates and stores a new escalation policy.
+func (e *Escalations) CreatePolicy(ctx context.Context, req *CreateEscalationPolicyRequest) (*EscalationPolicy, error) {
+	policy := &EscalationPolicy{
+		Name:    strings.TrimSpace(req.Name),
+		Type:    req.Type,
+		Steps:   req.Steps,
+		Enabled: req.Enabled == nil || *req.Enabled,
+	}
+	if err := e.prepare(ctx, policy); err != nil {
+		return nil, err
+	}
+
+	if err := e.policies.CreatePolicy(ctx, policy); err != nil {
+		return nil, fmt.Errorf("creating escalation policy: %w", err)
+	}
+
+	slog.Info("escalation policy created", "policy_id", policy.ID, "type", policy.Type, "steps", len(policy.Steps))
+	return policy, nil
+}
+
+// GetPolicy returns an escalation policy by ID.
+func (e *Escalations) GetPolicy(ctx context.Context, id string) (*EscalationPolicy, error) {
+	policy, err := e.policies.GetPolicy(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("fetching escalation policy: %w", err)
+	}
+	if policy == nil {
+		return nil, common.NewNotFoundError("escalation policy", id)
+	}
+	return policy, nil
+}
+
+// ListPolicies returns every escalation policy, oldest first.
+func (e *Escalations) ListPolicies(ctx context.Context) ([]*EscalationPolicy, error) {
+	policies, err := e.policies.ListPolicies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing escalation policies: %w", err)
+	}
+	return policies, nil
+}
+
+// UpdatePolicy applies the given changes to an escalation policy.
+func (e *Escalations) UpdatePolicy(ctx context.Context, id string, req *UpdateEscalationPolicyRequest) (*EscalationPolicy, error) {
+	policy, err := e.GetPolicy(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		policy.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Type != nil {
+		policy.Type = *req.Type
+	}
+	if req.Steps != nil {
+		policy.Steps = req.Steps
+	}
+	if req.Enabled != nil {
+		policy.Enabled = *req.Enabled
+	}
+	if err := e.prepare(ctx, policy); err != nil {
+		return nil, err
+	}
+
+	if err := e.policies.UpdatePolicy(ctx, policy); err != nil {
+		return nil, fmt.Errorf("updating escalation policy: %w", err)
+	}
+
+	slog.Info("escalation policy updated", "policy_id", policy.ID, "type", policy.Type, "enabled", policy.Enabled)
+	return policy, nil
+}
+
+// DeletePolicy removes an escalation policy. Notifications already escalating
+// run their remaining steps.
+func (e *Escalations) DeletePolicy(ctx context.Context, id string) error {
+	deleted, err := e.policies.DeletePolicy(ctx, id)
+	if err != nil {
+		return fmt.Errorf("deleting escalation policy: %w", err)
+	}
+	if !deleted {
+		return common.NewNotFoundError("escalation policy", id)
+	}
+
+	slog.Info("escalation policy deleted", "policy_id", id)
+	return nil
+}
+
+// prepare validates policy and normalizes its steps. Another policy for the
+// same type is a conflict.
+func (e *Escalations) prepare(ctx context.Context, policy *EscalationPolicy) error {
+	if policy.Name == "" {
+		return common.NewValidationError("name is required")
+	}
+	if !IsValidType(policy.Type) {
+		return common.NewValidationError(fmt.Sprintf("unsupported notification type: %s", policy.Type))
+	}
+	if len(policy.Steps) == 0 || len(policy.Steps) > maxEscalationSteps {
+		return common.NewValidationError(fmt.Sprintf("an escalation policy needs 1 to %d steps, got %d", maxEscalationSteps, len(policy.Steps)))
+	}
+	for i := range policy.Steps {
+		if err := validateEscalationStep(&policy.Steps[i]); err != nil {
+			return common.NewValidationError(fmt.Sprintf("steps[%d]: %v", i, err))
+		}
+	}
+
+	existing, err := e.policies.GetPolicyByType(ctx, policy.Type)
+	if err != nil {
+		return fmt.Errorf("checking for an escalation policy on %s: %w", policy.Type, err)
+	}
+	if existing != nil && existing.ID != policy.ID {
+		return common.NewConflictError(
+			fmt.Sprintf("escalation policy %q already covers type %s", existing.Name, policy.Type),
+			existing.ID,
+		)
+	}
+	return nil
+}
+
+// validateEscalationStep normalizes a step and checks it can run.
+func validateEscalationStep(step *EscalationStep) error {
+	step.Channel = Channel(strings.ToLower(strings.TrimSpace(string(step.Channel))))
+	step.To = strings.TrimSpace(step.To)
+	step.URL = strings.TrimSpace(step.URL)
+
+	if step.DelaySec < 1 || step.delay() > maxEscalationDelay {
+		return fmt.Errorf("delay_sec must be between 1 and %d, got %d", int(maxEscalationDelay.Seconds()), step.DelaySec)
+	}
+	switch step.Channel {
+	case ChannelEmail, ChannelSMS, ChannelPush:
+		if step.URL != "" {
+			return fmt.Errorf("url is only used by webhook steps")
+		}
+		if step.To != "" {
+			return ValidateRecipient(step.Channel, step.To)
+		}
+	case EscalationWebhook:
+		if step.To != "" {
+			return fmt.Errorf("to is not used by webhook steps")
+		}
+		u, err := url.Parse(step.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be an absolute http or https URL, got %q", step.URL)
+		}
+	default:
+		return fmt.Errorf("channel must be email, sms, push, or webhook, got %q", step.Channel)
+	}
+	return nil
+}
+
+// escalationFor returns the escalation a request starts, or nil when no
+// enabled policy covers its type. Every step must have an address, the
+// request must have a single recipient or a user_id, and escalate_to
+// without a policy is rejected rather than ignored.
+func (e *Escalations) escalationFor(ctx context.Context, req *SendRequest, recipients Recipients) (*Escalation, error) {
+	policy, err := e.policies.GetPolicyByType(ctx, req.Type)
+	if err != nil {
+		return nil, fmt.Errorf("fetching escalation policy for %s: %w", req.Type, err)
+	}
+	if policy == nil || !policy.Enabled {
+		if len(req.EscalateTo) > 0 {
+			return nil, common.NewValidationError(fmt.Sprintf("escalate_to is set but no escalation policy covers type %s", req.Type))
+		}
+		return nil, nil
+	}
+	if _, ok := e.enqueuer.(EscalationEnqueuer); !ok {
+		return nil, fmt.Errorf("escalation policy %s cannot run: the queue cannot schedule escalation steps", policy.ID)
+	}
+	if req.UserID == "" && len(recipients) > 1 {
+		return nil, common.NewValidationError(fmt.Sprintf("type %s is escalated by policy %q: send it to a single recipient or a user_id", req.Type, policy.Name))
+	}
+
+	escalation := &Escalation{PolicyID: policy.ID, Steps: policy.Steps}
+	if len(req.EscalateTo) > 0 {
+		escalation.To = make(map[Channel]string, len(req.EscalateTo))
+		for channel, to := range req.EscalateTo {
+			if channel != ChannelEmail && channel != ChannelSMS && channel != ChannelPush {
+				return nil, common.NewValidationError(fmt.Sprintf("escalate_to keys must be email, sms, or push, got %q", channel))
+			}
+			escalation.To[channel] = strings.TrimSpace(to)
+		}
+	}
+	for _, step := range escalation.Steps {
+		if step.Channel == EscalationWebhook {
+			continue
+		}
+		to := escalation.address(step)
+		if to == "" {
+			return nil, common.NewValidationError(fmt.Sprintf("type %s is escalated by policy %q: escalate_to.%s is required", req.Type, policy.Name, step.Channel))
+		}
+		if err := ValidateRecipient(step.Channel, to); err != nil {
+			return nil, common.NewValidationError(fmt.Sprintf("escalate_to.%s: %v", step.Channel, err))
+		}
+	}
+	return escalation, nil
+}
+
+// start schedules the first step of a new log's escalation. A failure is
+// logged without failing the request: the notification itself was accepted.
+func (e *Escalations) start(logID string, escalation *Escalation) {
+	if err := e.schedule(logID, 0, escalation.Steps[0]); err != nil {
+		slog.Error("failed to schedule escalation", "log_id", logID, "error", err)
+	}
+}
+
+// schedule enqueues step of the escalation of logID after the step's delay.
+func (e *Escalations) schedule(logID string, index int, step EscalationStep) error {
+	enqueuer, ok := e.enqueuer.(EscalationEnqueuer)
+	if !ok {
+		return fmt.Errorf("the queue cannot schedule escalation steps")
+	}
+	return enqueuer.EnqueueEscalationStep(logID, index, step.delay())
+}
+
+// Acknowledge stops the escalation of a notification: steps not yet run are
+// skipped. Acknowledging a step's log or a device's log acknowledges the
+// notification it escalates or belongs to; acknowledging twice keeps the
+// first time.
+func (e *Escalations) Acknowledge(ctx context.Context, id string) (*NotificationLog, error) {
+	notifLog, err := e.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if notifLog.EscalationOf != "" {
+		if notifLog, err = e.get(ctx, notifLog.EscalationOf); err != nil {
+			return nil, err
+		}
+	} else if notifLog.ParentID != "" {
+		if notifLog, err = e.get(ctx, notifLog.ParentID); err != nil {
+			return nil, err
+		}
+	}
+	if notifLog.Escalation == nil {
+		return nil, common.NewValidationError(fmt.Sprintf("notification %s has no escalation to acknowledge", notifLog.ID))
+	}
+	if notifLog.AcknowledgedAt != nil {
+		return notifLog, nil
+	}
+
+	now := time.Now().UTC()
+	if err := e.store.Acknowledge(ctx, notifLog.ID, now); err != nil {
+		return nil, fmt.Errorf("acknowledging notification: %w", err)
+	}
+	notifLog.AcknowledgedAt = &now
+
+	slog.Info("escalation acknowledged", "log_id", notifLog.ID, "policy_id", notifLog.Escalation.PolicyID)
+	return notifLog, nil
+}
+
+// get returns a notification log by ID.
+func (e *Escalations) get(ctx context.Context, id string) (*NotificationLog, error) {
+	notifLog, err := e.store.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("fetching notification: %w", err)
+	}
+	if notifLog == nil {
+		return nil, common.NewNotFoundError("notification", id)
+	}
+	return notifLog, nil
+}
+
+// Step runs one step of a log's escalation, then schedules the next. Nothing
+// is run once the notification was acknowledged, or once it or any earlier
+// step's notification was delivered. A step's log has the idempotency key
+// "escalation:<log id>:<step>", so a retried step cannot send twice; a
+// webhook step can be called again, with the same Idempotency-Key header.
+func (e *Escalations) Step(ctx context.Context, logID string, index int) error {
+	original, err := e.store.GetByID(ctx, logID)
+	if err != nil {
+		return fmt.Errorf("fetching notification log %s: %w", logID, err)
+	}
+	if original == nil {
+		return common.NewPermanentError(fmt.Errorf("notification log not found: %s", logID))
+	}
+	escalation := original.Escalation
+	if escalation == nil || index >= len(escalation.Steps) {
+		return nil // erased since
+	}
+	if original.AcknowledgedAt != nil {
+		slog.Info("escalation stopped: acknowledged", "log_id", logID, "step", index)
+		return nil
+	}
+	delivered, err := e.delivered(ctx, original)
+	if err != nil {
+		return err
+	}
+	if delivered {
+		slog.Info("escalation stopped: delivered", "log_id", logID, "step", index)
+		return nil
+	}
+
+	step := escalation.Steps[index]
+	if step.Channel == EscalationWebhook {
+		err = e.callWebhook(ctx, original, index, step.URL)
+	} else {
+		err = e.send(ctx, original, index, step.Channel, escalation.address(step))
+	}
+	if err != nil {
+		return err
+	}
+
+	if next := index + 1; next < len(escalation.Steps) {
+		if err := e.schedule(logID, next, escalation.Steps[next]); err != nil {
+			return fmt.Errorf("scheduling escalation step %d: %w", next, err)
+		}
+	}
+	return nil
+}
+
+// delivered reports whether the notification, or any step's notification
+// sent so far, was delivered.
+func (e *Escalations) delivered(ctx context.Context, original *NotificationLog) (bool, error) {
+	reached, err := hasReachedAny(ctx, e.store, original, StatusDelivered)
+	if err != nil || reached {
+		return reached, err
+	}
+	steps, err := listAll(ctx, e.store, ListFilter{EscalationOf: original.ID})
+	if err != nil {
+		return false, fmt.Errorf("listing escalation steps of %s: %w", original.ID, err)
+	}
+	for _, step := range steps {
+		if hasReached(step.Status, StatusDelivered) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// send creates and enqueues a step's notification. One that could not be
+// enqueued stays queued for the reaper.
+func (e *Escalations) send(ctx context.Context, original *NotificationLog, index int, channel Channel, to string) error {
+	key := fmt.Sprintf("escalation:%s:%d", original.ID, index)
+	existing, err := e.store.GetByIdempotencyKey(ctx, key)
+	if err != nil {
+		return fmt.Errorf("checking for an earlier escalation step: %w", err)
+	}
+	if existing != nil {
+		return nil
+	}
+
+	if channel == ChannelEmail {
+		bounced, err := e.store.HasBounced(ctx, to)
+		if err != nil {
+			slog.Error("bounce suppression check failed, proceeding", "recipient", to, "error", err)
+		} else if bounced {
+			slog.Info("escalation step suppressed after a bounce", "log_id", original.ID, "step", index)
+			return nil
+		}
+	}
+
+	notifLog := &NotificationLog{
+		IdempotencyKey: key,
+		EscalationOf:   original.ID,
+		APIKeyID:       original.APIKeyID,
+		Channel:        string(channel),
+		Type:           original.Type,
+		Recipient:      to,
+		Headers:        original.Headers,
+		Tags:           original.Tags,
+		TemplateData:   original.TemplateData,
+		Status:         StatusQueued,
+	}
+	if err := e.store.Create(ctx, notifLog); err != nil {
+		return fmt.Errorf("creating escalation notification: %w", err)
+	}
+	if err := e.enqueuer.EnqueueSendNotification(notifLog.ID); err != nil {
+		slog.Error("failed to enqueue escalation notification, leaving it to the reaper", "log_id", notifLog.ID, "error", err)
+		return nil
+	}
+
+	slog.Info("escalation notification enqueued",
+		"log_id", notifLog.ID,
+		"escalation_of", original.ID,
+		"step", index,
+		"channel", channel,
+	)
+	return nil
+}
+
+// callWebhook POSTs an EscalationWebhookPayload to a webhook step's URL. Any
+// response other than 2xx is an error, so the step is retried.
+func (e *Escalations) callWebhook(ctx context.Context, original *NotificationLog, index int, webhookURL string) error {
+	body, err := json.Marshal(EscalationWebhookPayload{
+		Event:          "notification.escalated",
+		NotificationID: original.ID,
+		PolicyID:       original.Escalation.PolicyID,
+		Step:           index,
+		Type:           original.Type,
+		Channel:        original.Channel,
+		Recipient:      original.Recipient,
+		UserID:         original.UserID,
+		Status:         original.Status,
+		CreatedAt:      original.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("marshaling escalation webhook: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return common.NewPermanentError(fmt.Errorf("creating escalation webhook request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(idempotencyKeyHeader, fmt.Sprintf("escalation:%s:%d", original.ID, index))
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling escalation webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("escalation webhook returned %s", resp.Status)
+	}
+
+	slog.Info("escalation webhook called", "log_id", original.ID, "step", index, "status", resp.StatusCode)
+	return nil
+}
@@ -0,0 +1,174 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+)
+
+// FallbackRule sends a notification again on another channel when it has not
+// reached Until within After — e.g. "push first; if not delivered within 10
+// minutes, send email".
+type FallbackRule struct {
+	Channel Channel
+	After   time.Duration
+	Until   NotificationStatus // StatusSent, StatusDelivered, or StatusOpened
+}
+
+// FallbackRules holds fallback rules keyed by "channel:type", type, or
+// channel. The most specific key that matches a notification wins.
+type FallbackRules map[string]FallbackRule
+
+// lookup returns the rule for notifications of notifType sent on channel.
+func (r FallbackRules) lookup(channel Channel, notifType NotificationType) (FallbackRule, bool) {
+	for _, key := range []string{string(channel) + ":" + string(notifType), string(notifType), string(channel)} {
+		if rule, ok := r[key]; ok {
+			if rule.Until == "" {
+				rule.Until = StatusDelivered
+			}
+			return rule, true
+		}
+	}
+	return FallbackRule{}, false
+}
+
+// Fallback is the fallback a log was accepted with: where to send it, and the
+// status the log must reach to make that unnecessary.
+type Fallback struct {
+	Channel Channel            `json:"channel"`
+	To      string             `json:"to"`
+	Until   NotificationStatus `json:"until"`
+}
+
+// FallbackEnqueuer is optionally implemented by an Enqueuer that can schedule
+// the check behind a fallback. Without it, fallback_to is rejected.
+type FallbackEnqueuer interface {
+	// EnqueueFallback schedules Fallbacker.Process for logID after delay.
+	// Scheduling the same log twice is a no-op.
+	EnqueueFallback(logID string, delay time.Duration) error
+}
+
+// deliveryProgress ranks statuses by how far a notification got, so reaching
+// a later one (opened) implies the earlier ones (sent, delivered).
+var deliveryProgress = map[NotificationStatus]int{
+	StatusSent:       1,
+	StatusDelivered:  2,
+	StatusComplained: 2,
+	StatusOpened:     3,
+	StatusClicked:    4,
+}
+
+// hasReached reports whether a log in status got at least as far as until.
+func hasReached(status, until NotificationStatus) bool {
+	return deliveryProgress[status] >= deliveryProgress[until]
+}
+
+// Fallbacker runs the delayed fallback checks scheduled at enqueue.
+type Fallbacker struct {
+	store    NotificationStore
+	enqueuer Enqueuer
+}
+
+// NewFallbacker creates a new fallback runner.
+func NewFallbacker(store NotificationStore, enqueuer Enqueuer) *Fallbacker {
+	return &Fallbacker{store: store, enqueuer: enqueuer}
+}
+
+// Process checks a log whose fallback delay has passed. If the log (or, for a
+// push to a user, any of its devices) has reached the fallback's status,
+// nothing is sent; otherwise a new log is sent on the fallback channel. The
+// fallback log's idempotency key is "fallback:<log id>", so a retried check
+// cannot send twice. One that could not be enqueued stays queued for the reaper.
+func (f *Fallbacker) Process(ctx context.Context, logID string) error {
+	original, err := f.store.GetByID(ctx, logID)
+	if err != nil {
+		return fmt.Errorf("fetching notification log %s: %w", logID, err)
+	}
+	if original == nil {
+		return common.NewPermanentError(fmt.Errorf("notification log not found: %s", logID))
+	}
+	fallback := original.Fallback
+	if fallback == nil {
+		return nil // erased since
+	}
+
+	reached, err := hasReachedAny(ctx, f.store, original, fallback.Until)
+	if err != nil {
+		return err
+	}
+	if reached {
+		slog.Info("fallback not needed", "log_id", logID, "status", original.Status, "until", fallback.Until)
+		return nil
+	}
+
+	key := "fallback:" + original.ID
+	existing, err := f.store.GetByIdempotencyKey(ctx, key)
+	if err != nil {
+		return fmt.Errorf("checking for an earlier fallback: %w", err)
+	}
+	if existing != nil {
+		return nil
+	}
+
+	if fallback.Channel == ChannelEmail {
+		bounced, err := f.store.HasBounced(ctx, fallback.To)
+		if err != nil {
+			slog.Error("bounce suppression check failed, proceeding", "recipient", fallback.To, "error", err)
+		} else if bounced {
+			slog.Info("fallback suppressed after a bounce", "log_id", logID)
+			return nil
+		}
+	}
+
+	notifLog := &NotificationLog{
+		IdempotencyKey: key,
+		FallbackOf:     original.ID,
+		APIKeyID:       original.APIKeyID,
+		Channel:        string(fallback.Channel),
+		Type:           original.Type,
+		Recipient:      fallback.To,
+		Headers:        original.Headers,
+		Tags:           original.Tags,
+		TemplateData:   original.TemplateData,
+		Status:         StatusQueued,
+	}
+	if err := f.store.Create(ctx, notifLog); err != nil {
+		return fmt.Errorf("creating fallback notification: %w", err)
+	}
+	if err := f.enqueuer.EnqueueSendNotification(notifLog.ID); err != nil {
+		slog.Error("failed to enqueue fallback notification, leaving it to the reaper", "log_id", notifLog.ID, "error", err)
+		return nil
+	}
+
+	slog.Info("fallback notification enqueued",
+		"log_id", notifLog.ID,
+		"fallback_of", original.ID,
+		"channel", fallback.Channel,
+		"original_status", original.Status,
+	)
+	return nil
+}
+
+// hasReachedAny reports whether original got to until: itself or, for the
+// parent of a push to a user's devices, through any of its children.
+func hasReachedAny(ctx context.Context, store NotificationStore, original *NotificationLog, until NotificationStatus) (bool, error) {
+	if hasReached(original.Status, until) {
+		return true, nil
+	}
+	if !original.IsParent() {
+		return false, nil
+	}
+	children, err := listChildren(ctx, store, original.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, child := range children {
+		if hasReached(child.Status, until) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
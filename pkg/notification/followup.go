@@ -0,0 +1,436 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+)
+
+const (
+	maxFollowUpSteps = 5
+	maxFollowUpDelay = 30 * 24 * time.Hour
+)
+
+// FollowUpStep is one reminder of a follow-up sequence: the notification of
+// Type sent to the same recipient when neither the original nor an earlier
+// reminder has reached Until. DelaySec counts from the previous step, or from
+// the original send for the first step.
+type FollowUpStep struct {
+	Type     NotificationType   `json:"type"` // the reminder's template
+	DelaySec int                `json:"delay_sec"`
+	Until    NotificationStatus `json:"until,omitempty"` // delivered, opened (default), or clicked
+}
+
+// delay returns the time to wait before running the step.
+func (s FollowUpStep) delay() time.Duration {
+	return time.Duration(s.DelaySec) * time.Second
+}
+
+// FollowUpSequence is the reminders sent after every notification of Type
+// that goes unanswered — e.g. confirm_signup not opened within 48 hours sends
+// a reminder template. A type has at most one sequence.
+type FollowUpSequence struct {
+	ID        string           `json:"id"`
+	Name      string           `json:"name"`
+	Type      NotificationType `json:"type"`
+	Steps     []FollowUpStep   `json:"steps"`
+	Enabled   bool             `json:"enabled"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// CreateFollowUpSequenceRequest is the API request payload for creating a
+// follow-up sequence.
+type CreateFollowUpSequenceRequest struct {
+	Name    string           `json:"name" binding:"required,max=200"`
+	Type    NotificationType `json:"type" binding:"required"`
+	Steps   []FollowUpStep   `json:"steps" binding:"required"`
+	Enabled *bool            `json:"enabled"` // defaults to true
+}
+
+// UpdateFollowUpSequenceRequest is the API request payload for changing a
+// follow-up sequence. Omitted fields keep their current value. Notifications
+// already being followed up run the sequence's steps as they are when each
+// step is due.
+type UpdateFollowUpSequenceRequest struct {
+	Name    *string           `json:"name" binding:"omitempty,max=200"`
+	Type    *NotificationType `json:"type"`
+	Steps   []FollowUpStep    `json:"steps"`
+	Enabled *bool             `json:"enabled"`
+}
+
+// FollowUpStore defines the contract for follow-up sequence persistence.
+// Implementations live in internal/infra/store/.
+type FollowUpStore interface {
+	// CreateSequence inserts sequence, filling in its ID and timestamps.
+	CreateSequence(ctx context.Context, sequence *FollowUpSequence) error
+
+	// GetSequence retrieves a sequence by ID. Returns nil, nil if none exists.
+	GetSequence(ctx context.Context, id string) (*FollowUpSequence, error)
+
+	// GetSequenceByType retrieves the sequence for a notification type.
+	// Returns nil, nil if none exists.
+	GetSequenceByType(ctx context.Context, notifType NotificationType) (*FollowUpSequence, error)
+
+	// ListSequences returns every sequence, oldest first.
+	ListSequences(ctx context.Context) ([]*FollowUpSequence, error)
+
+	// UpdateSequence stores sequence's definition.
+	UpdateSequence(ctx context.Context, sequence *FollowUpSequence) error
+
+	// DeleteSequence removes a sequence. Returns false if none existed.
+	DeleteSequence(ctx context.Context, id string) (bool, error)
+}
+
+// FollowUpEnqueuer is optionally implemented by an Enqueuer that can schedule
+// follow-up checks. Without it, follow-up sequences cannot run.
+type FollowUpEnqueuer interface {
+	// EnqueueFollowUp schedules FollowUps.Check for step of sequenceID on
+	// logID after delay. Scheduling the same step twice is a no-op.
+	EnqueueFollowUp(logID, sequenceID string, step int, delay time.Duration) error
+}
+
+// FollowUps manages follow-up sequences and runs their checks: the server
+// starts a sequence when it accepts a notification of a covered type, and the
+// worker checks each step when its delay has passed.
+type FollowUps struct {
+	sequences FollowUpStore
+	store     NotificationStore
+	enqueuer  Enqueuer
+
+	// admission checks each reminder as the service checks a request
+	admission admission
+}
+
+// NewFollowUps creates a new follow-up manager.
+func NewFollowUps(sequences FollowUpStore, store NotificationStore, enqueuer Enqueuer) *FollowUps {
+	return &FollowUps{sequences: sequences, store: store, enqueuer: enqueuer, admission: admission{store: store}}
+}
+
+// SetRateLimiter counts reminders against the per-recipient rate limits of
+// rateLimiter; nil disables them. Call it before checks run.
+func (f *FollowUps) SetRateLimiter(rateLimiter RecipientRateLimiter) {
+	f.admission.rateLimiter = rateLimiter
+}
+
+// SetSuppressBounced toggles bounce suppression for reminders. Safe for
+// concurrent use.
+func (f *FollowUps) SetSuppressBounced(enabled bool) {
+	f.admission.suppressBounced.Store(enabled)
+}
+
+// SetRateLimitFailClosed chooses what happens to a reminder when the rate
+// limiter errors: the check is retried later (true) or the reminder goes out
+// unlimited (false). Safe for concurrent use.
+func (f *FollowUps) SetRateLimitFailClosed(enabled bool) {
+	f.admission.rateLimitFailClosed.Store(enabled)
+}
+
+// CreateSequence validates and stores a new follow-up sequence.
+func (f *FollowUps) CreateSequence(ctx context.Context, req *CreateFollowUpSequenceRequest) (*FollowUpSequence, error) {
+	sequence := &FollowUpSequence{
+		Name:    strings.TrimSpace(req.Name),
+		Type:    req.Type,
+		Steps:   req.Steps,
+		Enabled: req.Enabled == nil || *req.Enabled,
+	}
+	if err := f.prepare(ctx, sequence); err != nil {
+		return nil, err
+	}
+
+	if err := f.sequences.CreateSequence(ctx, sequence); err != nil {
+		return nil, fmt.Errorf("creating follow-up sequence: %w", err)
+	}
+
+	slog.Info("follow-up sequence created", "sequence_id", sequence.ID, "type", sequence.Type, "steps", len(sequence.Steps))
+	return sequence, nil
+}
+
+// GetSequence returns a follow-up sequence by ID.
+func (f *FollowUps) GetSequence(ctx context.Context, id string) (*FollowUpSequence, error) {
+	sequence, err := f.sequences.GetSequence(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("fetching follow-up sequence: %w", err)
+	}
+	if sequence == nil {
+		return nil, common.NewNotFoundError("follow-up sequence", id)
+	}
+	return sequence, nil
+}
+
+// ListSequences returns every follow-up sequence, oldest first.
+func (f *FollowUps) ListSequences(ctx context.Context) ([]*FollowUpSequence, error) {
+	sequences, err := f.sequences.ListSequences(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing follow-up sequences: %w", err)
+	}
+	return sequences, nil
+}
+
+// UpdateSequence applies the given changes to a follow-up sequence.
+func (f *FollowUps) UpdateSequence(ctx context.Context, id string, req *UpdateFollowUpSequenceRequest) (*FollowUpSequence, error) {
+	sequence, err := f.GetSequence(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		sequence.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Type != nil {
+		sequence.Type = *req.Type
+	}
+	if req.Steps != nil {
+		sequence.Steps = req.Steps
+	}
+	if req.Enabled != nil {
+		sequence.Enabled = *req.Enabled
+	}
+	if err := f.prepare(ctx, sequence); err != nil {
+		return nil, err
+	}
+
+	if err := f.sequences.UpdateSequence(ctx, sequence); err != nil {
+		return nil, fmt.Errorf("updating follow-up sequence: %w", err)
+	}
+
+	slog.Info("follow-up sequence updated", "sequence_id", sequence.ID, "type", sequence.Type, "enabled", sequence.Enabled)
+	return sequence, nil
+}
+
+// DeleteSequence removes a follow-up sequence. Notifications being followed
+// up get no further reminders.
+func (f *FollowUps) DeleteSequence(ctx context.Context, id string) error {
+	deleted, err := f.sequences.DeleteSequence(ctx, id)
+	if err != nil {
+		return fmt.Errorf("deleting follow-up sequence: %w", err)
+	}
+	if !deleted {
+		return common.NewNotFoundError("follow-up sequence", id)
+	}
+
+	slog.Info("follow-up sequence deleted", "sequence_id", id)
+	return nil
+}
+
+// prepare validates sequence and normalizes its steps. Another sequence for
+// the same type is a conflict.
+func (f *FollowUps) prepare(ctx context.Context, sequence *FollowUpSequence) error {
+	if sequence.Name == "" {
+		return common.NewValidationError("name is required")
+	}
+	if !IsValidType(sequence.Type) {
+		return common.NewValidationError(fmt.Sprintf("unsupported notification type: %s", sequence.Type))
+	}
+	if len(sequence.Steps) == 0 || len(sequence.Steps) > maxFollowUpSteps {
+		return common.NewValidationError(fmt.Sprintf("a follow-up sequence needs 1 to %d steps, got %d", maxFollowUpSteps, len(sequence.Steps)))
+	}
+	for i := range sequence.Steps {
+		if err := validateFollowUpStep(&sequence.Steps[i]); err != nil {
+			return common.NewValidationError(fmt.Sprintf("steps[%d]: %v", i, err))
+		}
+	}
+
+	existing, err := f.sequences.GetSequenceByType(ctx, sequence.Type)
+	if err != nil {
+		return fmt.Errorf("checking for a follow-up sequence on %s: %w", sequence.Type, err)
+	}
+	if existing != nil && existing.ID != sequence.ID {
+		return common.NewConflictError(
+			fmt.Sprintf("follow-up sequence %q already covers type %s", existing.Name, sequence.Type),
+			existing.ID,
+		)
+	}
+	return nil
+}
+
+// validateFollowUpStep normalizes a step and checks it can run.
+func validateFollowUpStep(step *FollowUpStep) error {
+	step.Type = NotificationType(strings.TrimSpace(string(step.Type)))
+	step.Until = NotificationStatus(strings.ToLower(strings.TrimSpace(string(step.Until))))
+
+	if !IsValidType(step.Type) {
+		return fmt.Errorf("unsupported notification type: %s", step.Type)
+	}
+	if step.DelaySec < 60 || step.delay() > maxFollowUpDelay {
+		return fmt.Errorf("delay_sec must be between 60 and %d, got %d", int(maxFollowUpDelay.Seconds()), step.DelaySec)
+	}
+	switch step.Until {
+	case "":
+		step.Until = StatusOpened
+	case StatusDelivered, StatusOpened, StatusClicked:
+	default:
+		return fmt.Errorf("until must be delivered, opened, or clicked, got %q", step.Until)
+	}
+	return nil
+}
+
+// sequenceFor returns the enabled sequence that follows up notifications of
+// notifType, or nil when there is none.
+func (f *FollowUps) sequenceFor(ctx context.Context, notifType NotificationType) (*FollowUpSequence, error) {
+	sequence, err := f.sequences.GetSequenceByType(ctx, notifType)
+	if err != nil {
+		return nil, fmt.Errorf("fetching follow-up sequence for %s: %w", notifType, err)
+	}
+	if sequence == nil || !sequence.Enabled {
+		return nil, nil
+	}
+	return sequence, nil
+}
+
+// start schedules the first step of sequence for a new log. A failure is
+// logged without failing the request: the notification itself was accepted.
+func (f *FollowUps) start(logID string, sequence *FollowUpSequence) {
+	if err := f.schedule(logID, sequence.ID, 0, sequence.Steps[0]); err != nil {
+		slog.Error("failed to schedule follow-up", "log_id", logID, "sequence_id", sequence.ID, "error", err)
+	}
+}
+
+// schedule enqueues the check of step of sequenceID on logID after the step's
+// delay.
+func (f *FollowUps) schedule(logID, sequenceID string, index int, step FollowUpStep) error {
+	enqueuer, ok := f.enqueuer.(FollowUpEnqueuer)
+	if !ok {
+		return fmt.Errorf("the queue cannot schedule follow-ups")
+	}
+	return enqueuer.EnqueueFollowUp(logID, sequenceID, index, step.delay())
+}
+
+// Check runs one step of a log's follow-up sequence, then schedules the next.
+// The sequence is read as it is now: once it is deleted, disabled, or moved
+// to another type, or has fewer steps, nothing more is sent. No reminder goes
+// out once the original or any earlier reminder reached the step's Until, or
+// once the original failed, bounced, or drew a complaint. A reminder passes
+// the same bounce suppression and rate limits as a request; one refused by
+// them ends the sequence. A reminder's log has the idempotency key
+// "followup:<log id>:<step>", so a retried check cannot send twice.
+func (f *FollowUps) Check(ctx context.Context, logID, sequenceID string, index int) error {
+	original, err := f.store.GetByID(ctx, logID)
+	if err != nil {
+		return fmt.Errorf("fetching notification log %s: %w", logID, err)
+	}
+	if original == nil {
+		return common.NewPermanentError(fmt.Errorf("notification log not found: %s", logID))
+	}
+	sequence, err := f.sequences.GetSequence(ctx, sequenceID)
+	if err != nil {
+		return fmt.Errorf("fetching follow-up sequence %s: %w", sequenceID, err)
+	}
+	if sequence == nil || !sequence.Enabled || string(sequence.Type) != original.Type || index >= len(sequence.Steps) {
+		slog.Info("follow-up stopped: sequence changed", "log_id", logID, "sequence_id", sequenceID, "step", index)
+		return nil
+	}
+	switch original.Status {
+	case StatusFailed, StatusBounced, StatusComplained:
+		slog.Info("follow-up stopped", "log_id", logID, "step", index, "status", original.Status)
+		return nil
+	}
+
+	step := sequence.Steps[index]
+	reached, err := f.reached(ctx, original, step.Until)
+	if err != nil {
+		return err
+	}
+	if reached {
+		slog.Info("follow-up not needed", "log_id", logID, "step", index, "until", step.Until)
+		return nil
+	}
+
+	sent, err := f.send(ctx, original, index, step)
+	if err != nil {
+		return err
+	}
+	if !sent {
+		return nil
+	}
+
+	if next := index + 1; next < len(sequence.Steps) {
+		if err := f.schedule(logID, sequenceID, next, sequence.Steps[next]); err != nil {
+			return fmt.Errorf("scheduling follow-up step %d: %w", next, err)
+		}
+	}
+	return nil
+}
+
+// reached reports whether the notification, or any reminder sent for it so
+// far, got to until.
+func (f *FollowUps) reached(ctx context.Context, original *NotificationLog, until NotificationStatus) (bool, error) {
+	if hasReached(original.Status, until) {
+		return true, nil
+	}
+	reminders, err := listAll(ctx, f.store, ListFilter{FollowUpOf: original.ID})
+	if err != nil {
+		return false, fmt.Errorf("listing follow-ups of %s: %w", original.ID, err)
+	}
+	for _, reminder := range reminders {
+		if hasReached(reminder.Status, until) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// send creates and enqueues a step's reminder to the original's recipients,
+// and reports whether it was sent: false when admission refused it. One that
+// could not be enqueued stays queued for the reaper. A rate limiter that
+// fails closed is an error, so the check is retried.
+func (f *FollowUps) send(ctx context.Context, original *NotificationLog, index int, step FollowUpStep) (bool, error) {
+	key := fmt.Sprintf("followup:%s:%d", original.ID, index)
+	existing, err := f.store.GetByIdempotencyKey(ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("checking for an earlier follow-up: %w", err)
+	}
+	if existing != nil {
+		return true, nil
+	}
+
+	recipients := original.Recipients
+	if len(recipients) == 0 {
+		recipients = []string{original.Recipient}
+	}
+	if err := f.admission.check(ctx, recipients, Channel(original.Channel), step.Type); err != nil {
+		var unavailable *common.UnavailableError
+		if errors.As(err, &unavailable) {
+			return false, err
+		}
+		slog.Info("follow-up stopped: reminder refused", "log_id", original.ID, "step", index, "reason", err)
+		return false, nil
+	}
+
+	notifLog := &NotificationLog{
+		IdempotencyKey: key,
+		FollowUpOf:     original.ID,
+		APIKeyID:       original.APIKeyID,
+		Channel:        original.Channel,
+		Type:           string(step.Type),
+		Recipient:      original.Recipient,
+		Recipients:     original.Recipients,
+		ReplyTo:        original.ReplyTo,
+		Sender:         original.Sender,
+		Headers:        original.Headers,
+		Tags:           original.Tags,
+		TemplateData:   original.TemplateData,
+		Status:         StatusQueued,
+	}
+	if err := f.store.Create(ctx, notifLog); err != nil {
+		return false, fmt.Errorf("creating follow-up notification: %w", err)
+	}
+	if err := f.enqueuer.EnqueueSendNotification(notifLog.ID); err != nil {
+		slog.Error("failed to enqueue follow-up notification, leaving it to the reaper", "log_id", notifLog.ID, "error", err)
+		return true, nil
+	}
+
+	slog.Info("follow-up notification enqueued",
+		"log_id", notifLog.ID,
+		"follow_up_of", original.ID,
+		"step", index,
+		"type", step.Type,
+		"original_status", original.Status,
+	)
+	return true, nil
+}
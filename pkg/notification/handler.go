@@ -0,0 +1,1110 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Handler handles HTTP requests for the notification domain.
+type Handler struct {
+	service     *Service
+	reaper      *Reaper
+	queue       QueueControl
+	eraser      *Eraser
+	campaigner  *Campaigner
+	scheduler   *Scheduler
+	devices     *Devices
+	escalations *Escalations
+	followUps   *FollowUps
+	webhooks    *WebhookRegistry
+}
+
+// NewHandler creates a new notification handler.
+// reaper may be nil, in which case the reaper admin routes are not registered;
+// the rate limit admin routes likewise need a service rate limiter that
+// implements RateLimitInspector. queue, eraser, campaigner, scheduler, devices,
+// escalations, followUps, and webhooks may be nil to leave out the queue
+// pause/resume, erasure, campaign, schedule, device, escalation, follow-up,
+// and provider webhook routes.
+func NewHandler(service *Service, reaper *Reaper, queue QueueControl, eraser *Eraser, campaigner *Campaigner, scheduler *Scheduler, devices *Devices, escalations *Escalations, followUps *FollowUps, webhooks *WebhookRegistry) *Handler {
+	return &Handler{
+		service:     service,
+		reaper:      reaper,
+		queue:       queue,
+		eraser:      eraser,
+		campaigner:  campaigner,
+		scheduler:   scheduler,
+		devices:     devices,
+		escalations: escalations,
+		followUps:   followUps,
+		webhooks:    webhooks,
+	}
+}
+
+// idempotencyKeyHeader is the standard HTTP idempotency header. On POST /send
+// it takes precedence over the body's idempotency_key and is echoed back.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// Send handles POST /api/v1/send
+// Enqueues a notification for async processing and returns 202 Accepted.
+func (h *Handler) Send(c *gin.Context) {
+	var req SendRequest
+	if err := bindSendRequest(c, &req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			common.Error(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+		common.HandleError(c, err)
+		return
+	}
+	if key := c.GetHeader(idempotencyKeyHeader); key != "" {
+		req.IdempotencyKey = key
+	}
+	if req.IdempotencyKey != "" {
+		c.Header(idempotencyKeyHeader, req.IdempotencyKey)
+	}
+
+	resp, err := h.service.Enqueue(c.Request.Context(), &req)
+	if err != nil {
+		slog.Error("enqueue notification failed",
+			"error", err,
+			"channel", req.Channel,
+			"type", req.Type,
+			"to", req.To,
+		)
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusAccepted, resp)
+}
+
+// bindSendRequest decodes a send request strictly: unknown fields are
+// rejected rather than ignored, so a misspelled field (e.g. "idempotencyKey")
+// is not silently dropped. Decoding and binding failures are returned as
+// ValidationErrors naming the field at fault.
+func bindSendRequest(c *gin.Context, req *SendRequest) error {
+	dec := json.NewDecoder(c.Request.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(req); err != nil {
+		var tooLarge *http.MaxBytesError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &tooLarge):
+			return err
+		case errors.As(err, &typeErr) && typeErr.Field != "":
+			return common.NewFieldError(typeErr.Field, fmt.Sprintf("%s must be %s", typeErr.Field, jsonKind(typeErr.Type)))
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+			return common.NewFieldError(field, fmt.Sprintf("unknown field: %q", field))
+		}
+		return common.NewValidationError("invalid request body: " + err.Error())
+	}
+	if dec.More() {
+		return common.NewValidationError("invalid request body: unexpected data after the JSON object")
+	}
+
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		var fieldErrs validator.ValidationErrors
+		if errors.As(err, &fieldErrs) && len(fieldErrs) > 0 {
+			return bindingFieldError(req, fieldErrs[0])
+		}
+		return common.NewValidationError("invalid request body: " + err.Error())
+	}
+	return nil
+}
+
+// bindingFieldError describes a failed binding tag on a field of v in terms of
+// its JSON name, keeping any index (e.g. "cc[1]" or "escalate_to[sms]").
+func bindingFieldError(v any, fe validator.FieldError) *common.ValidationError {
+	name := fe.StructNamespace()
+	name = name[strings.Index(name, ".")+1:]
+	goName, index, _ := strings.Cut(name, "[")
+	field := goName
+	if sf, ok := reflect.TypeOf(v).Elem().FieldByName(goName); ok {
+		field, _, _ = strings.Cut(sf.Tag.Get("json"), ",")
+	}
+	if index != "" {
+		field += "[" + index
+	}
+
+	var msg string
+	switch fe.Tag() {
+	case "required", "required_without":
+		msg = "is required"
+	case "oneof":
+		msg = "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "max":
+		msg = "must have at most " + fe.Param() + " " + sizeUnit(fe.Kind())
+	case "email":
+		msg = "must be an email address"
+	default:
+		msg = fmt.Sprintf("failed the %q check", fe.Tag())
+	}
+	return common.NewFieldError(field, field+" "+msg)
+}
+
+// sizeUnit names what a max tag counts for a value of kind.
+func sizeUnit(kind reflect.Kind) string {
+	if kind == reflect.String {
+		return "characters"
+	}
+	return "entries"
+}
+
+// jsonKind names the JSON value a Go type decodes from.
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	case reflect.Bool:
+		return "a boolean"
+	}
+	return "a number"
+}
+
+// GetNotification handles GET /api/v1/notifications/:id
+// The response's ETag changes whenever the log is updated; a request whose
+// If-None-Match still names it gets a 304, so status polls stay cheap.
+func (h *Handler) GetNotification(c *gin.Context) {
+	id := c.Param("id")
+
+	notifLog, err := h.service.GetNotification(c.Request.Context(), id)
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	if common.NotModified(c, logETag(notifLog)) {
+		return
+	}
+	common.Success(c, http.StatusOK, notifLog)
+}
+
+// logETag returns the ETag of a log's representation: every write to a log
+// sets its updated_at, so the update time identifies the version. It is weak
+// since equal versions are not guaranteed byte-identical JSON.
+func logETag(notifLog *NotificationLog) string {
+	return `W/"` + strconv.FormatInt(notifLog.UpdatedAt.UnixNano(), 36) + `"`
+}
+
+// PreviewNotification handles GET /api/v1/notifications/:id/preview
+// Returns the log's subject, HTML, and text: as stored at enqueue, or rendered
+// now from its template data.
+func (h *Handler) PreviewNotification(c *gin.Context) {
+	preview, err := h.service.PreviewNotification(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, preview)
+}
+
+// ListNotifications handles GET /api/v1/notifications
+func (h *Handler) ListNotifications(c *gin.Context) {
+	var filter ListFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		common.Error(c, http.StatusBadRequest, "invalid query parameters: "+err.Error())
+		return
+	}
+
+	resp, err := h.service.ListNotifications(c.Request.Context(), filter)
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, resp)
+}
+
+// NotificationStatuses handles POST /api/v1/notifications/status
+// Returns the current status of up to MaxStatusQuery notifications at once.
+func (h *Handler) NotificationStatuses(c *gin.Context) {
+	var query StatusQuery
+	if err := c.ShouldBindJSON(&query); err != nil {
+		common.Error(c, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	resp, err := h.service.QueryStatuses(c.Request.Context(), &query)
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, resp)
+}
+
+// Stats handles GET /api/v1/notifications/stats
+func (h *Handler) Stats(c *gin.Context) {
+	resp, err := h.service.Stats(c.Request.Context())
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, resp)
+}
+
+// Usage handles GET /api/v1/usage
+// Reports each API key's sends for the current billing period to date and,
+// with history, the periods before it.
+func (h *Handler) Usage(c *gin.Context) {
+	var query UsageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		common.Error(c, http.StatusBadRequest, "invalid query parameters: "+err.Error())
+		return
+	}
+
+	resp, err := h.service.Usage(c.Request.Context(), query)
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, resp)
+}
+
+// Metrics handles GET /metrics
+// Serves the delivery latency histograms and the send queues' backlog in the
+// Prometheus text format.
+func (h *Handler) Metrics(c *gin.Context) {
+	histograms, err := h.service.LatencyHistograms(c.Request.Context())
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := WritePrometheusLatency(&buf, histograms); err != nil {
+		common.HandleError(c, err)
+		return
+	}
+	if h.queue != nil {
+		state, err := h.queue.QueueState(c.Request.Context())
+		if err != nil {
+			common.HandleError(c, err)
+			return
+		}
+		if err := WritePrometheusBacklog(&buf, state); err != nil {
+			common.HandleError(c, err)
+			return
+		}
+	}
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", buf.Bytes())
+}
+
+// ReaperStats handles GET /api/v1/admin/reaper
+// Returns sweep totals across all replicas and the most recent sweep.
+func (h *Handler) ReaperStats(c *gin.Context) {
+	stats, err := h.reaper.Stats(c.Request.Context())
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, stats)
+}
+
+// ReaperSweep handles POST /api/v1/admin/reaper/sweep
+// Runs a sweep immediately instead of waiting for the next tick. The response
+// has skipped=true when another replica was sweeping at the time.
+func (h *Handler) ReaperSweep(c *gin.Context) {
+	result, err := h.reaper.Sweep(c.Request.Context(), SweepTriggerManual)
+	if err != nil {
+		slog.Error("manual reaper sweep failed", "error", err)
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, result)
+}
+
+// RetryFailed handles POST /api/v1/admin/notifications/retry-failed
+// Requeues failed logs matching the optional filters in the body, e.g. after a
+// provider outage. An empty body retries the oldest failed logs up to the default limit.
+func (h *Handler) RetryFailed(c *gin.Context) {
+	var req RetryFailedRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		common.Error(c, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	resp, err := h.service.RetryFailed(c.Request.Context(), &req)
+	if err != nil {
+		slog.Error("bulk retry of failed notifications failed", "error", err)
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, resp)
+}
+
+// ArchiveNotification handles POST /api/v1/notifications/:id/archive
+// Archives one settled notification; it stays readable by ID.
+func (h *Handler) ArchiveNotification(c *gin.Context) {
+	notifLog, err := h.service.Archive(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, notifLog)
+}
+
+// UnarchiveNotification handles POST /api/v1/notifications/:id/unarchive
+func (h *Handler) UnarchiveNotification(c *gin.Context) {
+	notifLog, err := h.service.Unarchive(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, notifLog)
+}
+
+// ArchiveNotifications handles POST /api/v1/admin/notifications/archive
+// Archives the settled notifications created before a cutoff.
+func (h *Handler) ArchiveNotifications(c *gin.Context) {
+	var req ArchiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Error(c, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	resp, err := h.service.ArchiveBulk(c.Request.Context(), &req)
+	if err != nil {
+		slog.Error("bulk archive of notifications failed", "error", err)
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, resp)
+}
+
+// PurgeNotifications handles POST /api/v1/admin/notifications/purge
+// Deletes the notifications archived before a cutoff for good.
+func (h *Handler) PurgeNotifications(c *gin.Context) {
+	var req PurgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Error(c, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	resp, err := h.service.Purge(c.Request.Context(), &req)
+	if err != nil {
+		slog.Error("purge of archived notifications failed", "error", err)
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, resp)
+}
+
+// EraseRecipient handles DELETE /api/v1/recipients/:recipient/data
+// Starts erasing the recipient's personal data and returns 202 with the job to
+// poll. The recipient's rate limit windows are cleared right away.
+func (h *Handler) EraseRecipient(c *gin.Context) {
+	recipient := c.Param("recipient")
+	job, err := h.eraser.Request(c.Request.Context(), recipient)
+	if err != nil {
+		slog.Error("erasure request failed", "error", err)
+		common.HandleError(c, err)
+		return
+	}
+
+	if h.service.rateLimitInspector() != nil {
+		if _, err := h.service.ResetRateLimit(c.Request.Context(), recipient); err != nil {
+			slog.Error("failed to clear rate limit windows for erasure", "job_id", job.ID, "error", err)
+		}
+	}
+
+	common.Success(c, http.StatusAccepted, job)
+}
+
+// ErasureJob handles GET /api/v1/erasures/:id
+func (h *Handler) ErasureJob(c *gin.Context) {
+	job, err := h.eraser.Job(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, job)
+}
+
+// CreateCampaign handles POST /api/v1/campaigns
+// Stores the campaign and enqueues its fan-out; returns 202 Accepted.
+func (h *Handler) CreateCampaign(c *gin.Context) {
+	var req CreateCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			common.Error(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+		common.Error(c, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	campaign, err := h.campaigner.Create(c.Request.Context(), &req)
+	if err != nil {
+		slog.Error("create campaign failed", "error", err, "type", req.Type)
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusAccepted, campaign)
+}
+
+// ListCampaigns handles GET /api/v1/campaigns
+func (h *Handler) ListCampaigns(c *gin.Context) {
+	campaigns, err := h.campaigner.List(c.Request.Context())
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, gin.H{"campaigns": campaigns})
+}
+
+// GetCampaign handles GET /api/v1/campaigns/:id
+// Returns the campaign with its progress.
+func (h *Handler) GetCampaign(c *gin.Context) {
+	campaign, err := h.campaigner.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, campaign)
+}
+
+// CampaignEvents handles GET /api/v1/campaigns/:id/events
+// Streams the campaign's progress as server-sent events: a "progress" event
+// carrying the campaign as GetCampaign returns it, at once and whenever it
+// changes, then a "done" event once the campaign is finished and nothing is
+// left queued. Errors before the first event are answered as JSON.
+func (h *Handler) CampaignEvents(c *gin.Context) {
+	// Streams outlive the server's write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		slog.Warn("campaign events: could not lift write deadline", "error", err)
+	}
+
+	started := false
+	err := h.campaigner.Watch(c.Request.Context(), c.Param("id"), func(campaign *Campaign) error {
+		if !started {
+			c.Header("Cache-Control", "no-cache")
+			c.Header("X-Accel-Buffering", "no") // keep proxies from holding events back
+			started = true
+		}
+		c.SSEvent("progress", campaign)
+		c.Writer.Flush()
+		return c.Request.Context().Err()
+	})
+	switch {
+	case !started && err != nil:
+		common.HandleError(c, err)
+	case err == nil:
+		c.SSEvent("done", gin.H{"id": c.Param("id")})
+		c.Writer.Flush()
+	case c.Request.Context().Err() == nil:
+		slog.Error("campaign events failed", "campaign_id", c.Param("id"), "error", err)
+		c.SSEvent("error", gin.H{"message": "progress unavailable"})
+		c.Writer.Flush()
+	}
+}
+
+// PauseCampaign handles POST /api/v1/campaigns/:id/pause
+func (h *Handler) PauseCampaign(c *gin.Context) {
+	h.campaignAction(c, h.campaigner.Pause)
+}
+
+// ResumeCampaign handles POST /api/v1/campaigns/:id/resume
+func (h *Handler) ResumeCampaign(c *gin.Context) {
+	h.campaignAction(c, h.campaigner.Resume)
+}
+
+// CancelCampaign handles POST /api/v1/campaigns/:id/cancel
+func (h *Handler) CancelCampaign(c *gin.Context) {
+	h.campaignAction(c, h.campaigner.Cancel)
+}
+
+func (h *Handler) campaignAction(c *gin.Context, action func(ctx context.Context, id string) (*Campaign, error)) {
+	campaign, err := action(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, campaign)
+}
+
+// CreateSchedule handles POST /api/v1/schedules
+func (h *Handler) CreateSchedule(c *gin.Context) {
+	var req CreateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Error(c, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	schedule, err := h.scheduler.Create(c.Request.Context(), &req)
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusCreated, schedule)
+}
+
+// ListSchedules handles GET /api/v1/schedules
+func (h *Handler) ListSchedules(c *gin.Context) {
+	schedules, err := h.scheduler.List(c.Request.Context())
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, gin.H{"schedules": schedules})
+}
+
+// GetSchedule handles GET /api/v1/schedules/:id
+func (h *Handler) GetSchedule(c *gin.Context) {
+	schedule, err := h.scheduler.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, schedule)
+}
+
+// UpdateSchedule handles PATCH /api/v1/schedules/:id
+func (h *Handler) UpdateSchedule(c *gin.Context) {
+	var req UpdateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Error(c, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	schedule, err := h.scheduler.Update(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, schedule)
+}
+
+// DeleteSchedule handles DELETE /api/v1/schedules/:id
+func (h *Handler) DeleteSchedule(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.scheduler.Delete(c.Request.Context(), id); err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, gin.H{"id": id, "status": "deleted"})
+}
+
+// RegisterDevice handles POST /api/v1/devices
+// Registers a push device token, or refreshes its owner and last-seen time.
+func (h *Handler) RegisterDevice(c *gin.Context) {
+	var req RegisterDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Error(c, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	device, err := h.devices.Register(c.Request.Context(), &req)
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, device)
+}
+
+// ListDevices handles GET /api/v1/users/:user_id/devices
+func (h *Handler) ListDevices(c *gin.Context) {
+	devices, err := h.devices.List(c.Request.Context(), c.Param("user_id"))
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, gin.H{"devices": devices})
+}
+
+// UnregisterDevice handles DELETE /api/v1/devices/:token
+func (h *Handler) UnregisterDevice(c *gin.Context) {
+	if err := h.devices.Unregister(c.Request.Context(), c.Param("token")); err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, gin.H{"status": "unregistered"})
+}
+
+// CreateEscalationPolicy handles POST /api/v1/escalation-policies
+func (h *Handler) CreateEscalationPolicy(c *gin.Context) {
+	var req CreateEscalationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Error(c, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	policy, err := h.escalations.CreatePolicy(c.Request.Context(), &req)
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusCreated, policy)
+}
+
+// ListEscalationPolicies handles GET /api/v1/escalation-policies
+func (h *Handler) ListEscalationPolicies(c *gin.Context) {
+	policies, err := h.escalations.ListPolicies(c.Request.Context())
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, gin.H{"policies": policies})
+}
+
+// GetEscalationPolicy handles GET /api/v1/escalation-policies/:id
+func (h *Handler) GetEscalationPolicy(c *gin.Context) {
+	policy, err := h.escalations.GetPolicy(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, policy)
+}
+
+// UpdateEscalationPolicy handles PATCH /api/v1/escalation-policies/:id
+func (h *Handler) UpdateEscalationPolicy(c *gin.Context) {
+	var req UpdateEscalationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Error(c, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	policy, err := h.escalations.UpdatePolicy(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, policy)
+}
+
+// DeleteEscalationPolicy handles DELETE /api/v1/escalation-policies/:id
+func (h *Handler) DeleteEscalationPolicy(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.escalations.DeletePolicy(c.Request.Context(), id); err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, gin.H{"id": id, "status": "deleted"})
+}
+
+// AcknowledgeNotification handles POST /api/v1/notifications/:id/acknowledge
+// Stops the notification's escalation; returns the escalating notification.
+func (h *Handler) AcknowledgeNotification(c *gin.Context) {
+	notifLog, err := h.escalations.Acknowledge(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, notifLog)
+}
+
+// CreateFollowUpSequence handles POST /api/v1/follow-up-sequences
+func (h *Handler) CreateFollowUpSequence(c *gin.Context) {
+	var req CreateFollowUpSequenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Error(c, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	sequence, err := h.followUps.CreateSequence(c.Request.Context(), &req)
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusCreated, sequence)
+}
+
+// ListFollowUpSequences handles GET /api/v1/follow-up-sequences
+func (h *Handler) ListFollowUpSequences(c *gin.Context) {
+	sequences, err := h.followUps.ListSequences(c.Request.Context())
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, gin.H{"sequences": sequences})
+}
+
+// GetFollowUpSequence handles GET /api/v1/follow-up-sequences/:id
+func (h *Handler) GetFollowUpSequence(c *gin.Context) {
+	sequence, err := h.followUps.GetSequence(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, sequence)
+}
+
+// UpdateFollowUpSequence handles PATCH /api/v1/follow-up-sequences/:id
+func (h *Handler) UpdateFollowUpSequence(c *gin.Context) {
+	var req UpdateFollowUpSequenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Error(c, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	sequence, err := h.followUps.UpdateSequence(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, sequence)
+}
+
+// DeleteFollowUpSequence handles DELETE /api/v1/follow-up-sequences/:id
+func (h *Handler) DeleteFollowUpSequence(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.followUps.DeleteSequence(c.Request.Context(), id); err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, gin.H{"id": id, "status": "deleted"})
+}
+
+// QueueState handles GET /api/v1/admin/queue
+// Reports whether the notifications queue is paused and its task counts.
+func (h *Handler) QueueState(c *gin.Context) {
+	state, err := h.queue.QueueState(c.Request.Context())
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, state)
+}
+
+// PauseQueue handles POST /api/v1/admin/queue/pause
+// Workers stop picking up notification tasks until the queue is resumed;
+// sends are still accepted and wait in the queue.
+func (h *Handler) PauseQueue(c *gin.Context) {
+	if err := h.queue.PauseQueue(c.Request.Context()); err != nil {
+		slog.Error("pausing queue failed", "error", err)
+		common.HandleError(c, err)
+		return
+	}
+	slog.Warn("notifications queue paused via admin API")
+
+	h.QueueState(c)
+}
+
+// ResumeQueue handles POST /api/v1/admin/queue/resume
+func (h *Handler) ResumeQueue(c *gin.Context) {
+	if err := h.queue.ResumeQueue(c.Request.Context()); err != nil {
+		slog.Error("resuming queue failed", "error", err)
+		common.HandleError(c, err)
+		return
+	}
+	slog.Warn("notifications queue resumed via admin API")
+
+	h.QueueState(c)
+}
+
+// RateLimitStatus handles GET /api/v1/admin/ratelimit/:recipient
+// Shows the recipient's usage of every rate limit window that applies to them.
+func (h *Handler) RateLimitStatus(c *gin.Context) {
+	status, err := h.service.RateLimitStatus(c.Request.Context(), c.Param("recipient"))
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, status)
+}
+
+// ResetRateLimit handles DELETE /api/v1/admin/ratelimit/:recipient
+// Clears the recipient's windows, e.g. to unblock a user who hit the hourly cap.
+func (h *Handler) ResetRateLimit(c *gin.Context) {
+	recipient := c.Param("recipient")
+	cleared, err := h.service.ResetRateLimit(c.Request.Context(), recipient)
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, gin.H{"recipient": recipient, "windows_cleared": cleared})
+}
+
+// Webhook handles POST /api/v1/webhooks/:provider
+// Parses the request with the provider's registered adapter, stores the raw
+// event, and applies the status it reports.
+func (h *Handler) Webhook(c *gin.Context) {
+	h.webhook(c, c.Param("provider"))
+}
+
+// signedWebhook serves a signed provider's route, which has no :provider param.
+func (h *Handler) signedWebhook(provider string) gin.HandlerFunc {
+	return func(c *gin.Context) { h.webhook(c, provider) }
+}
+
+func (h *Handler) webhook(c *gin.Context, provider string) {
+	adapter, ok := h.webhooks.Adapter(provider)
+	if !ok {
+		common.HandleError(c, common.NewNotFoundError("webhook provider", provider))
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBytes+1))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			common.Error(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+		common.Error(c, http.StatusBadRequest, "reading webhook payload: "+err.Error())
+		return
+	}
+	if len(body) > maxWebhookBytes {
+		common.Error(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", maxWebhookBytes))
+		return
+	}
+
+	parsed, err := adapter.ParseEvent(c.Request.Context(), &WebhookRequest{
+		URL:    requestURL(c.Request),
+		Header: c.Request.Header,
+		Body:   body,
+	})
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	event, err := h.service.ReceiveWebhook(c.Request.Context(), provider, parsed, body)
+	if err != nil {
+		slog.Error("webhook processing failed",
+			"provider", provider,
+			"event_type", event.EventType,
+			"provider_id", event.ProviderID,
+			"error", err,
+		)
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, gin.H{"status": event.Result})
+}
+
+// requestURL rebuilds the full URL the client called, honoring
+// X-Forwarded-Proto from a TLS-terminating proxy.
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// ListWebhookEvents handles GET /api/v1/admin/webhooks/events
+// Filters by provider, event_type, and result; newest first.
+func (h *Handler) ListWebhookEvents(c *gin.Context) {
+	var filter WebhookEventFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		common.Error(c, http.StatusBadRequest, "invalid query parameters: "+err.Error())
+		return
+	}
+
+	events, err := h.service.ListWebhookEvents(c.Request.Context(), filter)
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, events)
+}
+
+// GetWebhookEvent handles GET /api/v1/admin/webhooks/events/:id
+func (h *Handler) GetWebhookEvent(c *gin.Context) {
+	event, err := h.service.WebhookEvent(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, event)
+}
+
+// ReplayWebhookEvent handles POST /api/v1/admin/webhooks/events/:id/replay
+// Applies a stored event again and returns it with the new result.
+func (h *Handler) ReplayWebhookEvent(c *gin.Context) {
+	event, err := h.service.ReplayWebhookEvent(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		slog.Error("webhook replay failed", "webhook_event_id", c.Param("id"), "error", err)
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, event)
+}
+
+// TrackClick handles GET /t/click/:token
+// Records a click on a tracked link and redirects to the original URL.
+// Public: recipients follow these links from their mail client.
+func (h *Handler) TrackClick(c *gin.Context) {
+	target, err := h.service.RecordClick(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, target)
+}
+
+// RegisterPublicRoutes registers unauthenticated notification routes (tracking
+// links and signed provider webhooks).
+func (h *Handler) RegisterPublicRoutes(r gin.IRouter) {
+	r.GET("/t/click/:token", h.TrackClick)
+	if h.webhooks == nil {
+		return
+	}
+	// Under /api/v1 like the other provider webhooks, but registered here so
+	// the API key check does not apply: the adapter checks the signature.
+	for _, provider := range h.webhooks.Providers() {
+		if h.webhooks.Signed(provider) {
+			r.POST("/api/v1/webhooks/"+provider, h.signedWebhook(provider))
+		}
+	}
+}
+
+// RegisterStreamRoutes registers the routes that stream responses to the
+// given router group, which must not buffer them (no request timeout).
+func (h *Handler) RegisterStreamRoutes(rg *gin.RouterGroup) {
+	if h.campaigner != nil {
+		rg.GET("/campaigns/:id/events", h.CampaignEvents)
+	}
+}
+
+// RegisterRoutes registers notification routes to the given router group.
+func (h *Handler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.POST("/send", h.Send)
+	rg.GET("/notifications", h.ListNotifications)
+	rg.GET("/notifications/stats", h.Stats)
+	rg.POST("/notifications/status", h.NotificationStatuses)
+	rg.GET("/notifications/:id", h.GetNotification)
+	rg.GET("/notifications/:id/preview", h.PreviewNotification)
+	if h.webhooks != nil {
+		rg.POST("/webhooks/:provider", h.Webhook)
+	}
+	rg.POST("/admin/notifications/retry-failed", h.RetryFailed)
+
+	if h.reaper != nil {
+		rg.GET("/admin/reaper", h.ReaperStats)
+		rg.POST("/admin/reaper/sweep", h.ReaperSweep)
+	}
+	if h.eraser != nil {
+		rg.DELETE("/recipients/:recipient/data", h.EraseRecipient)
+		rg.GET("/erasures/:id", h.ErasureJob)
+	}
+	if h.campaigner != nil {
+		rg.POST("/campaigns", h.CreateCampaign)
+		rg.GET("/campaigns", h.ListCampaigns)
+		rg.GET("/campaigns/:id", h.GetCampaign)
+		rg.POST("/campaigns/:id/pause", h.PauseCampaign)
+		rg.POST("/campaigns/:id/resume", h.ResumeCampaign)
+		rg.POST("/campaigns/:id/cancel", h.CancelCampaign)
+	}
+	if h.scheduler != nil {
+		rg.POST("/schedules", h.CreateSchedule)
+		rg.GET("/schedules", h.ListSchedules)
+		rg.GET("/schedules/:id", h.GetSchedule)
+		rg.PATCH("/schedules/:id", h.UpdateSchedule)
+		rg.DELETE("/schedules/:id", h.DeleteSchedule)
+	}
+	if h.devices != nil {
+		rg.POST("/devices", h.RegisterDevice)
+		rg.DELETE("/devices/:token", h.UnregisterDevice)
+		rg.GET("/users/:user_id/devices", h.ListDevices)
+	}
+	if h.escalations != nil {
+		rg.POST("/escalation-policies", h.CreateEscalationPolicy)
+		rg.GET("/escalation-policies", h.ListEscalationPolicies)
+		rg.GET("/escalation-policies/:id", h.GetEscalationPolicy)
+		rg.PATCH("/escalation-policies/:id", h.UpdateEscalationPolicy)
+		rg.DELETE("/escalation-policies/:id", h.DeleteEscalationPolicy)
+		rg.POST("/notifications/:id/acknowledge", h.AcknowledgeNotification)
+	}
+	if h.followUps != nil {
+		rg.POST("/follow-up-sequences", h.CreateFollowUpSequence)
+		rg.GET("/follow-up-sequences", h.ListFollowUpSequences)
+		rg.GET("/follow-up-sequences/:id", h.GetFollowUpSequence)
+		rg.PATCH("/follow-up-sequences/:id", h.UpdateFollowUpSequence)
+		rg.DELETE("/follow-up-sequences/:id", h.DeleteFollowUpSequence)
+	}
+	if h.queue != nil {
+		rg.GET("/admin/queue", h.QueueState)
+		rg.POST("/admin/queue/pause", h.PauseQueue)
+		rg.POST("/admin/queue/resume", h.ResumeQueue)
+	}
+	if h.service.webhookEvents() != nil {
+		rg.GET("/admin/webhooks/events", h.ListWebhookEvents)
+		rg.GET("/admin/webhooks/events/:id", h.GetWebhookEvent)
+		rg.POST("/admin/webhooks/events/:id/replay", h.ReplayWebhookEvent)
+	}
+	if h.service.archives() != nil {
+		rg.POST("/notifications/:id/archive", h.ArchiveNotification)
+		rg.POST("/notifications/:id/unarchive", h.UnarchiveNotification)
+		rg.POST("/admin/notifications/archive", h.ArchiveNotifications)
+		rg.POST("/admin/notifications/purge", h.PurgeNotifications)
+	}
+	if h.service.usageStore() != nil {
+		rg.GET("/usage", h.Usage)
+	}
+	if h.service.rateLimitInspector() != nil {
+		rg.GET("/admin/ratelimit/:recipient", h.RateLimitStatus)
+		rg.DELETE("/admin/ratelimit/:recipient", h.ResetRateLimit)
+	}
+}
@@ -0,0 +1,85 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+)
+
+// providerHolds remembers the providers that asked not to be sent to for a
+// while (a 429's Retry-After) and until when, so the worker's other sends
+// through them wait as well instead of being refused one by one. Holds are
+// per worker process. Safe for concurrent use; the zero value holds nothing.
+type providerHolds struct {
+	mu    sync.Mutex
+	until map[Provider]time.Time
+}
+
+// hold keeps sends away from p for d, unless it is held longer already.
+func (h *providerHolds) hold(p Provider, d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	until := time.Now().Add(d)
+	if until.Before(h.until[p]) {
+		return
+	}
+	if h.until == nil {
+		h.until = make(map[Provider]time.Time)
+	}
+	h.until[p] = until
+}
+
+// remaining returns how much longer p is held, or 0.
+func (h *providerHolds) remaining(p Provider) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	until, ok := h.until[p]
+	if !ok {
+		return 0
+	}
+	if d := time.Until(until); d > 0 {
+		return d
+	}
+	delete(h.until, p)
+	return 0
+}
+
+// held checks whether provider is held before logs are sent through it. If
+// it is, the logs are marked failed and retryable without calling the
+// provider, and the returned error asks for their task to be retried once
+// the hold is over; otherwise it returns nil.
+func (w *Worker) held(ctx context.Context, provider Provider, logs ...*NotificationLog) error {
+	wait := w.holds.remaining(provider)
+	if wait == 0 {
+		return nil
+	}
+	errMsg := fmt.Sprintf("provider rate limited: retrying in %s", wait.Round(time.Second))
+	for _, notifLog := range logs {
+		w.markFailed(ctx, notifLog.ID, errMsg, FailureProvider4xx, true, nil)
+	}
+	slog.Warn("provider held, send deferred",
+		"channel", provider.Channel(),
+		"count", len(logs),
+		"retry_in", wait,
+	)
+	return common.NewRetryAfterError(common.NewProviderError(string(provider.Channel()), errMsg), wait)
+}
+
+// providerError is the task error for a failed send through provider: a
+// ProviderError, permanent when the failure is, and carrying any wait the
+// provider asked for before a retry, which also holds the provider.
+func (w *Worker) providerError(provider Provider, err error, permanent bool) error {
+	providerErr := common.NewProviderError(string(provider.Channel()), err.Error())
+	if permanent {
+		return common.NewPermanentError(providerErr)
+	}
+	if wait, ok := common.RetryAfter(err); ok {
+		w.holds.hold(provider, wait)
+		return common.NewRetryAfterError(providerErr, wait)
+	}
+	return providerErr
+}
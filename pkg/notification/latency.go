@@ -0,0 +1,169 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// LatencyStage names a span of a notification's delivery that is timed.
+type LatencyStage string
+
+const (
+	LatencyCreatedToSent   LatencyStage = "created_to_sent"   // accepted → provider accepted (queueing, rendering, sending)
+	LatencySentToDelivered LatencyStage = "sent_to_delivered" // provider accepted → delivery reported
+	LatencySentToOpened    LatencyStage = "sent_to_opened"    // provider accepted → open reported
+)
+
+// LatencyBuckets are the upper bounds, in seconds, of the latency histogram
+// buckets. Slower observations only count toward the histogram's total.
+var LatencyBuckets = []float64{0.5, 1, 2, 5, 10, 30, 60, 120, 300, 900, 3600, 21600, 86400}
+
+// LatencyHistogram holds the latencies observed for one stage of one channel
+// and notification type.
+type LatencyHistogram struct {
+	Stage   LatencyStage
+	Channel Channel
+	Type    NotificationType
+	Buckets []int64 // observations per LatencyBuckets bound, not cumulative
+	Count   int64   // all observations, including those over the last bound
+	Sum     float64 // seconds
+}
+
+// Quantile estimates the q-quantile (0 < q < 1) in seconds by interpolating
+// linearly within the bucket it falls in, as Prometheus' histogram_quantile
+// does. It returns the last bound when the quantile lies beyond it, and 0
+// for an empty histogram.
+func (h *LatencyHistogram) Quantile(q float64) float64 {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := q * float64(h.Count)
+	var seen int64
+	lower := 0.0
+	for i, bound := range LatencyBuckets {
+		n := int64(0)
+		if i < len(h.Buckets) {
+			n = h.Buckets[i]
+		}
+		if n > 0 && float64(seen+n) >= rank {
+			return lower + (bound-lower)*(rank-float64(seen))/float64(n)
+		}
+		seen += n
+		lower = bound
+	}
+	return LatencyBuckets[len(LatencyBuckets)-1]
+}
+
+// LatencySummary reports one histogram in the stats endpoint, in seconds.
+type LatencySummary struct {
+	Stage   LatencyStage     `json:"stage"`
+	Channel Channel          `json:"channel"`
+	Type    NotificationType `json:"type"`
+	Count   int64            `json:"count"`
+	Mean    float64          `json:"mean"`
+	P50     float64          `json:"p50"`
+	P95     float64          `json:"p95"`
+	P99     float64          `json:"p99"`
+}
+
+// LatencyRecorder defines the contract for the delivery latency histograms
+// shared by the worker and server replicas that observe them and the API that
+// reports them. Implementations live in internal/infra/metrics/.
+type LatencyRecorder interface {
+	// ObserveLatency adds d to the histogram of stage for channel and type.
+	ObserveLatency(ctx context.Context, stage LatencyStage, channel Channel, notifType NotificationType, d time.Duration) error
+
+	// LatencyHistograms returns every histogram observed so far.
+	LatencyHistograms(ctx context.Context) ([]*LatencyHistogram, error)
+}
+
+// observeLatency records d for stage. A failure is logged: latency is
+// monitoring and must not fail the send or webhook it was measured for.
+func observeLatency(ctx context.Context, recorder LatencyRecorder, stage LatencyStage, notifLog *NotificationLog, d time.Duration) {
+	if recorder == nil || d < 0 {
+		return
+	}
+	err := recorder.ObserveLatency(context.WithoutCancel(ctx), stage, Channel(notifLog.Channel), NotificationType(notifLog.Type), d)
+	if err != nil {
+		slog.Warn("failed to record latency", "stage", stage, "log_id", notifLog.ID, "error", err)
+	}
+}
+
+// summarizeLatency turns the non-empty histograms into summaries, ordered by
+// stage, channel, and type.
+func summarizeLatency(histograms []*LatencyHistogram) []LatencySummary {
+	summaries := make([]LatencySummary, 0, len(histograms))
+	for _, h := range sortHistograms(histograms) {
+		if h.Count == 0 {
+			continue
+		}
+		summaries = append(summaries, LatencySummary{
+			Stage:   h.Stage,
+			Channel: h.Channel,
+			Type:    h.Type,
+			Count:   h.Count,
+			Mean:    roundSeconds(h.Sum / float64(h.Count)),
+			P50:     roundSeconds(h.Quantile(0.50)),
+			P95:     roundSeconds(h.Quantile(0.95)),
+			P99:     roundSeconds(h.Quantile(0.99)),
+		})
+	}
+	return summaries
+}
+
+// WritePrometheusLatency writes histograms as the
+// notifly_delivery_latency_seconds histogram in the Prometheus text
+// exposition format, labelled by stage, channel, and type.
+func WritePrometheusLatency(w io.Writer, histograms []*LatencyHistogram) error {
+	const name = "notifly_delivery_latency_seconds"
+	if _, err := fmt.Fprintf(w, "# HELP %s End-to-end notification delivery latency by stage.\n# TYPE %s histogram\n", name, name); err != nil {
+		return err
+	}
+	for _, h := range sortHistograms(histograms) {
+		labels := fmt.Sprintf("stage=%q,channel=%q,type=%q", h.Stage, h.Channel, h.Type)
+		var cumulative int64
+		for i, bound := range LatencyBuckets {
+			if i < len(h.Buckets) {
+				cumulative += h.Buckets[i]
+			}
+			if _, err := fmt.Fprintf(w, "%s_bucket{%s,le=%q} %d\n", name, labels, strconv.FormatFloat(bound, 'g', -1, 64), cumulative); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n%s_sum{%s} %s\n%s_count{%s} %d\n",
+			name, labels, h.Count,
+			name, labels, strconv.FormatFloat(h.Sum, 'g', -1, 64),
+			name, labels, h.Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortHistograms returns a copy of histograms ordered by stage, channel, and type.
+func sortHistograms(histograms []*LatencyHistogram) []*LatencyHistogram {
+	sorted := make([]*LatencyHistogram, len(histograms))
+	copy(sorted, histograms)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Stage != b.Stage {
+			return a.Stage < b.Stage
+		}
+		if a.Channel != b.Channel {
+			return a.Channel < b.Channel
+		}
+		return a.Type < b.Type
+	})
+	return sorted
+}
+
+// roundSeconds rounds to milliseconds.
+func roundSeconds(s float64) float64 {
+	return math.Round(s*1000) / 1000
+}
@@ -0,0 +1,316 @@
+package notification
+
+import "time"
+
+// NotificationStatus represents the delivery status of a notification.
+type NotificationStatus string
+
+const (
+	StatusQueued     NotificationStatus = "queued"
+	StatusProcessing NotificationStatus = "processing"
+	StatusSent       NotificationStatus = "sent"
+	StatusFailed     NotificationStatus = "failed"
+	StatusDelivered  NotificationStatus = "delivered"
+	StatusBounced    NotificationStatus = "bounced"
+	StatusOpened     NotificationStatus = "opened"
+	StatusClicked    NotificationStatus = "clicked"
+
+	// StatusComplained marks a notification the recipient reported as spam.
+	StatusComplained NotificationStatus = "complained"
+
+	// StatusAbandoned marks a log the reaper gave up on after too many recoveries.
+	StatusAbandoned NotificationStatus = "abandoned"
+)
+
+// Statuses returns every notification status in lifecycle order.
+func Statuses() []NotificationStatus {
+	return []NotificationStatus{
+		StatusQueued, StatusProcessing, StatusSent, StatusFailed, StatusAbandoned,
+		StatusDelivered, StatusBounced, StatusComplained, StatusOpened, StatusClicked,
+	}
+}
+
+// FailureCode classifies why a notification failed, alongside the free-text
+// error message.
+type FailureCode string
+
+const (
+	FailureRenderError      FailureCode = "render_error"      // the template could not be rendered
+	FailureInvalidRecipient FailureCode = "invalid_recipient" // the provider rejected the address or device token
+	FailureProvider4xx      FailureCode = "provider_4xx"      // the provider rejected the request
+	FailureProvider5xx      FailureCode = "provider_5xx"      // the provider failed or could not be reached
+	FailureTimeout          FailureCode = "timeout"           // the task's deadline passed during the send
+	FailureSuppressed       FailureCode = "suppressed"        // dropped by suppression before sending
+	FailureExpired          FailureCode = "expired"           // dropped because it was no longer worth sending
+)
+
+// FailureCodes returns every failure code.
+func FailureCodes() []FailureCode {
+	return []FailureCode{
+		FailureRenderError, FailureInvalidRecipient, FailureProvider4xx, FailureProvider5xx,
+		FailureTimeout, FailureSuppressed, FailureExpired,
+	}
+}
+
+// NotificationLog represents a persisted notification record.
+type NotificationLog struct {
+	ID               string             `json:"id"`
+	IdempotencyKey   string             `json:"idempotency_key,omitempty"`
+	PayloadHash      string             `json:"-"`
+	CampaignID       string             `json:"campaign_id,omitempty"`
+	UserID           string             `json:"user_id,omitempty"`
+	ParentID         string             `json:"parent_id,omitempty"`
+	FallbackOf       string             `json:"fallback_of,omitempty"`
+	EscalationOf     string             `json:"escalation_of,omitempty"`
+	FollowUpOf       string             `json:"follow_up_of,omitempty"`
+	Channel          string             `json:"channel"`
+	Type             string             `json:"type"`
+	Variant          string             `json:"variant,omitempty"`
+	Recipient        string             `json:"recipient"`
+	Recipients       []string           `json:"recipients,omitempty"`
+	CC               []string           `json:"cc,omitempty"`
+	BCC              []string           `json:"bcc,omitempty"`
+	ReplyTo          string             `json:"reply_to,omitempty"`
+	Sender           string             `json:"sender,omitempty"`
+	Headers          map[string]string  `json:"headers,omitempty"`
+	Tags             map[string]string  `json:"tags,omitempty"`
+	TemplateData     map[string]any     `json:"template_data,omitempty"`
+	Content          *RenderedContent   `json:"content,omitempty"`
+	ProviderID       string             `json:"provider_id,omitempty"`
+	ProviderMetadata *ProviderMetadata  `json:"provider_metadata,omitempty"`
+	Status           NotificationStatus `json:"status"`
+	ErrorMessage     string             `json:"error_message,omitempty"`
+	FailureCode      FailureCode        `json:"failure_code,omitempty"`
+	Retryable        *bool              `json:"retryable,omitempty"`
+	RecoveryAttempts int                `json:"recovery_attempts"`
+	BounceType       BounceType         `json:"bounce_type,omitempty"`
+	BounceRetries    int                `json:"bounce_retries"`
+	APIKeyID         string             `json:"api_key_id,omitempty"`
+	Cost             *float64           `json:"cost,omitempty"`
+	DirectSend       bool               `json:"direct_send,omitempty"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+	SentAt           *time.Time         `json:"sent_at,omitempty"`
+	DeliveredAt      *time.Time         `json:"delivered_at,omitempty"`
+	OpenedAt         *time.Time         `json:"opened_at,omitempty"`
+	ClickedAt        *time.Time         `json:"clicked_at,omitempty"`
+	BouncedAt        *time.Time         `json:"bounced_at,omitempty"`
+	ComplainedAt     *time.Time         `json:"complained_at,omitempty"`
+	AcknowledgedAt   *time.Time         `json:"acknowledged_at,omitempty"`
+	ArchivedAt       *time.Time         `json:"archived_at,omitempty"`
+
+	// Fallback is where the notification is sent again if it does not get
+	// far enough in time; the fallback log has FallbackOf set to this ID.
+	Fallback *Fallback `json:"fallback,omitempty"`
+
+	// Escalation is the escalation chain run for the notification until it is
+	// delivered or acknowledged; each step's log has EscalationOf set to this ID.
+	Escalation *Escalation `json:"escalation,omitempty"`
+}
+
+// IsParent reports whether the log is the parent of a push to a user's
+// devices. A parent is never sent itself: each device gets a child log, and
+// the parent's status follows its children's.
+func (l *NotificationLog) IsParent() bool {
+	return l.UserID != "" && l.ParentID == ""
+}
+
+// RenderedContent is a message rendered when it was enqueued. A log that
+// carries it is sent exactly as stored, whatever its template says by then.
+type RenderedContent struct {
+	Subject string       `json:"subject,omitempty"`
+	HTML    string       `json:"html,omitempty"`
+	Text    string       `json:"text,omitempty"`
+	Push    *PushContent `json:"push,omitempty"`
+}
+
+// PushContent is a push notification's payload. FCM and APNs, when set,
+// override the title and body on that platform and carry fields only it
+// understands (e.g. APNs "sound" or FCM "priority").
+type PushContent struct {
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Data  map[string]string `json:"data,omitempty"` // FCM allows only string values
+	FCM   *PushOverride     `json:"fcm,omitempty"`
+	APNs  *PushOverride     `json:"apns,omitempty"`
+}
+
+// PushOverride replaces parts of a push payload on one platform.
+type PushOverride struct {
+	Title  string         `json:"title,omitempty"`
+	Body   string         `json:"body,omitempty"`
+	Fields map[string]any `json:"fields,omitempty"`
+}
+
+// Preview sources: where a MessagePreview's content came from.
+const (
+	PreviewStored   = "stored"   // rendered at enqueue and stored on the log
+	PreviewRendered = "rendered" // rendered now from the log's template data
+)
+
+// MessagePreview is the message a log sent, or will send, without the
+// click-tracking link rewrites applied at send time.
+type MessagePreview struct {
+	ID      string   `json:"id"`
+	Channel string   `json:"channel"`
+	Type    string   `json:"type"`
+	To      []string `json:"to"`
+	Subject string   `json:"subject,omitempty"`
+	HTML    string   `json:"html,omitempty"`
+	Text    string   `json:"text,omitempty"`
+	Source  string   `json:"source"`
+
+	Push *PushContent `json:"push,omitempty"`
+}
+
+// ListFilter defines pagination and filtering options for listing notification logs.
+type ListFilter struct {
+	Page         int    `form:"page"`
+	PageSize     int    `form:"page_size"`
+	Status       string `form:"status"`
+	Recipient    string `form:"recipient"`
+	Channel      string `form:"channel"`
+	CampaignID   string `form:"campaign_id"`
+	ParentID     string `form:"parent_id"`
+	EscalationOf string `form:"escalation_of"`
+	FollowUpOf   string `form:"follow_up_of"`
+	FailureCode  string `form:"failure_code" binding:"omitempty,oneof=render_error invalid_recipient provider_4xx provider_5xx timeout suppressed expired"`
+	// Archived lists archived logs too (include) or only them (only); by
+	// default they are left out.
+	Archived string `form:"archived" binding:"omitempty,oneof=include only"`
+	// Count is how the total is computed (default exact).
+	Count string `form:"count" binding:"omitempty,oneof=exact planned estimated none"`
+	// Primary reads from the primary database even when the store has a
+	// read replica, for callers that must see their own writes.
+	Primary bool `form:"-"`
+}
+
+// Values of ListFilter.Count. Exact counts every matching row, which gets
+// slow on a large table; planned takes the query planner's estimate;
+// estimated counts exactly up to the server's max rows and estimates past
+// them; none skips the count.
+const (
+	CountExact     = "exact"
+	CountPlanned   = "planned"
+	CountEstimated = "estimated"
+	CountNone      = "none"
+)
+
+// FailedFilter selects failed logs for a bulk retry. Empty fields match everything.
+type FailedFilter struct {
+	Type          string     `json:"type"`
+	Channel       string     `json:"channel" binding:"omitempty,oneof=email sms push"`
+	CreatedAfter  *time.Time `json:"created_after"`
+	CreatedBefore *time.Time `json:"created_before"`
+	// ErrorContains matches error_message case-insensitively.
+	ErrorContains string `json:"error_contains"`
+}
+
+// RetryFailedRequest is the payload for POST /api/v1/admin/notifications/retry-failed.
+// Limit caps how many logs one call requeues (default 1000).
+type RetryFailedRequest struct {
+	FailedFilter
+	Limit int `json:"limit" binding:"omitempty,min=1,max=10000"`
+}
+
+// RetryFailedResponse reports a bulk retry. Failures counts logs reset to
+// queued whose task could not be enqueued; the reaper picks them up once stale.
+type RetryFailedResponse struct {
+	Requeued int `json:"requeued"`
+	Enqueued int `json:"enqueued"`
+	Failures int `json:"failures"`
+}
+
+// ArchiveFilter selects logs for a bulk archive. Empty fields match
+// everything; Status, when set, must be one a log is settled in.
+type ArchiveFilter struct {
+	CreatedBefore *time.Time `json:"created_before" binding:"required"`
+	Type          string     `json:"type"`
+	Channel       string     `json:"channel" binding:"omitempty,oneof=email sms push"`
+	Status        string     `json:"status" binding:"omitempty,oneof=sent failed delivered bounced opened clicked complained abandoned"`
+	Recipient     string     `json:"recipient"`
+}
+
+// ArchiveRequest is the payload for POST /api/v1/admin/notifications/archive.
+// Limit caps how many logs one call archives (default 1000).
+type ArchiveRequest struct {
+	ArchiveFilter
+	Limit int `json:"limit" binding:"omitempty,min=1,max=10000"`
+}
+
+// ArchiveResponse reports a bulk archive.
+type ArchiveResponse struct {
+	Archived int `json:"archived"`
+}
+
+// PurgeRequest is the payload for POST /api/v1/admin/notifications/purge.
+// Limit caps how many logs one call deletes (default 1000).
+type PurgeRequest struct {
+	ArchivedBefore *time.Time `json:"archived_before" binding:"required"`
+	Limit          int        `json:"limit" binding:"omitempty,min=1,max=10000"`
+}
+
+// PurgeResponse reports a purge.
+type PurgeResponse struct {
+	Purged int `json:"purged"`
+}
+
+// ListResponse wraps a paginated list of notification logs. Total is null
+// when the request asked for no count; TotalEstimated marks a total that is
+// an estimate.
+type ListResponse struct {
+	Notifications  []*NotificationLog `json:"notifications"`
+	Total          *int               `json:"total"`
+	TotalEstimated bool               `json:"total_estimated,omitempty"`
+	Page           int                `json:"page"`
+	PageSize       int                `json:"page_size"`
+}
+
+// MaxStatusQuery caps how many notifications one status query can name.
+const MaxStatusQuery = 500
+
+// StatusQuery is the payload for POST /api/v1/notifications/status. IDs and
+// IdempotencyKeys together name at most MaxStatusQuery notifications.
+type StatusQuery struct {
+	IDs             []string `json:"ids" binding:"omitempty,dive,uuid"`
+	IdempotencyKeys []string `json:"idempotency_keys" binding:"omitempty,dive,required,max=255"`
+}
+
+// StatusResult is one notification's current status.
+type StatusResult struct {
+	ID             string             `json:"id"`
+	IdempotencyKey string             `json:"idempotency_key,omitempty"`
+	Channel        string             `json:"channel"`
+	Status         NotificationStatus `json:"status"`
+	ErrorMessage   string             `json:"error_message,omitempty"`
+	FailureCode    FailureCode        `json:"failure_code,omitempty"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+}
+
+// StatusQueryResponse holds the statuses of the queried notifications, in
+// the order they were asked for (IDs first), once each. NotFound lists the
+// IDs and idempotency keys that match no notification.
+type StatusQueryResponse struct {
+	Notifications []StatusResult `json:"notifications"`
+	NotFound      []string       `json:"not_found"`
+}
+
+// StatsResponse summarizes notification logs by status.
+type StatsResponse struct {
+	Total    int                        `json:"total"`
+	ByStatus map[NotificationStatus]int `json:"by_status"`
+	// Abandoned is the number of logs the reaper stopped recovering; each needs
+	// a human to look at why it never completed.
+	Abandoned int `json:"abandoned"`
+	// ByFailureCode counts failed logs by why they failed.
+	ByFailureCode map[FailureCode]int `json:"by_failure_code"`
+	// Latency summarizes delivery latency per stage, channel, and type; it is
+	// left out when latency tracking is off.
+	Latency []LatencySummary `json:"latency,omitempty"`
+	// Variants reports the open and click rates of each template variant
+	// under A/B test; it is left out when no test runs.
+	Variants []VariantStats `json:"variants,omitempty"`
+	// Costs totals the estimated cost of sends per day, API key, channel, and
+	// type over the last 30 days; it is left out when costs are not tracked.
+	Costs []*CostTotal `json:"costs,omitempty"`
+}
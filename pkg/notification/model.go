@@ -0,0 +1,356 @@
+package notification
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Channel represents a notification delivery channel.
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	ChannelSMS   Channel = "sms"  // future
+	ChannelPush  Channel = "push" // future
+)
+
+// NotificationType enumerates all supported notification template types.
+type NotificationType string
+
+const (
+	TypeConfirmSignup    NotificationType = "confirm_signup"
+	TypeInviteUser       NotificationType = "invite_user"
+	TypeMagicLink        NotificationType = "magic_link"
+	TypeChangeEmail      NotificationType = "change_email"
+	TypeResetPassword    NotificationType = "reset_password"
+	TypeReauthentication NotificationType = "reauthentication"
+	TypePasswordChanged  NotificationType = "password_changed"
+	TypeEmailChanged     NotificationType = "email_changed"
+	TypePhoneChanged     NotificationType = "phone_changed"
+	TypeIdentityLinked   NotificationType = "identity_linked"
+	TypeIdentityUnlinked NotificationType = "identity_unlinked"
+	TypeDailySummary     NotificationType = "daily_summary" // operational report to admins
+)
+
+// validTypes is the set of all recognized notification types.
+var validTypes = map[NotificationType]bool{
+	TypeConfirmSignup:    true,
+	TypeInviteUser:       true,
+	TypeMagicLink:        true,
+	TypeChangeEmail:      true,
+	TypeResetPassword:    true,
+	TypeReauthentication: true,
+	TypePasswordChanged:  true,
+	TypeEmailChanged:     true,
+	TypePhoneChanged:     true,
+	TypeIdentityLinked:   true,
+	TypeIdentityUnlinked: true,
+	TypeDailySummary:     true,
+}
+
+// IsValidType checks whether a notification type is recognized.
+func IsValidType(t NotificationType) bool {
+	return validTypes[t]
+}
+
+// Types returns every recognized notification type, sorted by name.
+func Types() []NotificationType {
+	types := make([]NotificationType, 0, len(validTypes))
+	for t := range validTypes {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// Recipients is a list of recipient addresses. In JSON it accepts either a
+// single string ("a@example.com") or an array of strings.
+type Recipients []string
+
+// UnmarshalJSON accepts both the single-string and array forms.
+func (r *Recipients) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*r = Recipients{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("to must be a string or an array of strings")
+	}
+	*r = many
+	return nil
+}
+
+// Normalize trims whitespace and removes empty and duplicate addresses, keeping order.
+func (r Recipients) Normalize() Recipients {
+	seen := make(map[string]bool, len(r))
+	out := make(Recipients, 0, len(r))
+	for _, to := range r {
+		to = strings.TrimSpace(to)
+		key := strings.ToLower(to)
+		if to == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, to)
+	}
+	return out
+}
+
+// SendRequest is the API request payload for sending a notification.
+type SendRequest struct {
+	Channel        Channel          `json:"channel" binding:"required,oneof=email sms push"`
+	Type           NotificationType `json:"type" binding:"required"`
+	To             Recipients       `json:"to" binding:"required_without=UserID,omitempty,dive,required"`
+	Data           map[string]any   `json:"data"`
+	IdempotencyKey string           `json:"idempotency_key"`
+
+	// UserID sends a push notification to every device registered for the
+	// user instead of to addresses in To, which must then be empty.
+	UserID string `json:"user_id" binding:"omitempty,max=200"`
+
+	// FallbackTo is the address used on the fallback channel when a fallback
+	// rule matches the request and the notification does not get far enough.
+	FallbackTo string `json:"fallback_to" binding:"omitempty,max=320"`
+
+	// EscalateTo holds the addresses, keyed by channel, that the steps of an
+	// escalation policy for the request's type send to.
+	EscalateTo map[Channel]string `json:"escalate_to" binding:"omitempty,max=3,dive,max=320"`
+
+	// Email-only addressing. Ignored by channels that have no equivalent.
+	CC      []string `json:"cc" binding:"omitempty,max=50,dive,email"`
+	BCC     []string `json:"bcc" binding:"omitempty,max=50,dive,email"`
+	ReplyTo string   `json:"reply_to" binding:"omitempty,email"`
+
+	// Sender names the configured sender identity the email is sent from,
+	// instead of its type's default sender. Ignored by other channels.
+	Sender string `json:"sender" binding:"omitempty,max=64"`
+
+	// Headers are extra email headers (e.g. X-Entity-Ref-ID); Tags are provider
+	// metadata for analytics. Both are stored on the log for traceability.
+	Headers map[string]string `json:"headers" binding:"omitempty,max=20"`
+	Tags    map[string]string `json:"tags" binding:"omitempty,max=10"`
+}
+
+// PayloadHash fingerprints everything about a request that determines what is
+// sent — channel, type, recipients or user, data, fallback and escalation
+// addresses, addressing, sender, headers, and tags — but not the idempotency key itself. Two
+// requests with the same key and hash are the same request; the same key with
+// a different hash is a conflict. Fields added after hashes were first stored
+// are left out when empty, so those hashes still match.
+func (r *SendRequest) PayloadHash() string {
+	// encoding/json sorts map keys, so equal payloads always encode equally
+	canonical, _ := json.Marshal(struct {
+		Channel    Channel            `json:"channel"`
+		Type       NotificationType   `json:"type"`
+		To         Recipients         `json:"to"`
+		UserID     string             `json:"user_id,omitempty"`
+		FallbackTo string             `json:"fallback_to,omitempty"`
+		EscalateTo map[Channel]string `json:"escalate_to,omitempty"`
+		Data       map[string]any     `json:"data"`
+		CC         []string           `json:"cc"`
+		BCC        []string           `json:"bcc"`
+		ReplyTo    string             `json:"reply_to"`
+		Sender     string             `json:"sender,omitempty"`
+		Headers    map[string]string  `json:"headers"`
+		Tags       map[string]string  `json:"tags"`
+	}{r.Channel, r.Type, r.To.Normalize(), r.UserID, r.FallbackTo, r.EscalateTo, r.Data, r.CC, r.BCC, r.ReplyTo, r.Sender, r.Headers, r.Tags})
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
+
+// SendResponse is the API response payload after a notification is enqueued.
+// When a multi-recipient request fans out into one log per recipient, ID is
+// empty and Notifications holds the per-recipient results. A push to a user_id
+// has the parent log's ID and one result per device.
+type SendResponse struct {
+	ID             string            `json:"id,omitempty"`
+	IdempotencyKey string            `json:"idempotency_key,omitempty"`
+	Channel        string            `json:"channel"`
+	Status         string            `json:"status"`
+	Notifications  []RecipientResult `json:"notifications,omitempty"`
+}
+
+// RecipientResult is the outcome for one recipient of a fanned-out request.
+type RecipientResult struct {
+	ID             string `json:"id,omitempty"`
+	To             string `json:"to"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	Status         string `json:"status"`
+	Error          string `json:"error,omitempty"`
+}
+
+// Message is the internal rendered message ready for delivery.
+type Message struct {
+	// From is the email sender as a From header value; empty means the
+	// provider's default address.
+	From string
+
+	To      []string
+	CC      []string
+	BCC     []string
+	ReplyTo string
+	Headers map[string]string
+	Tags    map[string]string
+	Subject string
+	HTML    string
+	Text    string
+
+	// Attachments are files attached to an email, such as a calendar invite.
+	Attachments []Attachment
+
+	// Push is the push payload; set only on the push channel.
+	Push *PushContent
+}
+
+// Attachment is a file attached to an email.
+type Attachment struct {
+	Filename    string
+	ContentType string // e.g. "text/calendar; charset=UTF-8; method=REQUEST"
+	Content     []byte
+}
+
+// reservedHeaders are set by the service or provider and cannot be overridden per request.
+var reservedHeaders = map[string]bool{
+	"from": true, "to": true, "cc": true, "bcc": true, "reply-to": true, "subject": true,
+	"date": true, "message-id": true, "content-type": true, "content-transfer-encoding": true,
+	"mime-version": true, "sender": true, "return-path": true,
+}
+
+var (
+	headerNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]*$`)
+	tagRe        = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+)
+
+// ValidateHeaders checks custom header names are well-formed, not reserved,
+// and that values contain no line breaks (header injection).
+func ValidateHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if !headerNameRe.MatchString(name) {
+			return fmt.Errorf("invalid header name: %q", name)
+		}
+		if reservedHeaders[strings.ToLower(name)] {
+			return fmt.Errorf("header %q cannot be set per request", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("header %q contains a line break", name)
+		}
+	}
+	return nil
+}
+
+// ValidateTags checks tag names and values use only letters, digits, underscores,
+// and dashes (the common denominator across providers, including Resend).
+func ValidateTags(tags map[string]string) error {
+	for name, value := range tags {
+		if !tagRe.MatchString(name) {
+			return fmt.Errorf("invalid tag name: %q", name)
+		}
+		if !tagRe.MatchString(value) {
+			return fmt.Errorf("invalid value for tag %q", name)
+		}
+	}
+	return nil
+}
+
+// idempotencyKeyRe matches an idempotency key: 1–255 printable ASCII
+// characters without spaces, so keys survive headers, URLs, and logs intact.
+var idempotencyKeyRe = regexp.MustCompile(`^[!-~]{1,255}$`)
+
+// ValidateIdempotencyKey checks a caller-supplied idempotency key's format.
+func ValidateIdempotencyKey(key string) error {
+	if !idempotencyKeyRe.MatchString(key) {
+		return fmt.Errorf("invalid idempotency key: must be 1-255 printable ASCII characters without spaces")
+	}
+	return nil
+}
+
+// MaxDataValueSize caps the JSON-encoded size of each top-level value in a
+// request's template data.
+const MaxDataValueSize = 16 << 10
+
+// DefaultMaxDataSize is the default cap on the JSON-encoded size of a
+// request's whole template data.
+const DefaultMaxDataSize = 64 << 10
+
+// ValidateData checks that each template data value encodes to at most
+// MaxDataValueSize bytes and the whole data to at most maxSize (0: no total
+// cap). It returns the key of the first value that is too large, in key
+// order, with the error, or "" when only the total is.
+func ValidateData(data map[string]any, maxSize int) (string, error) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		encoded, err := json.Marshal(data[key])
+		if err != nil {
+			return key, fmt.Errorf("invalid data value %q: %w", key, err)
+		}
+		if len(encoded) > MaxDataValueSize {
+			return key, fmt.Errorf("data value %q is %d bytes (max %d)", key, len(encoded), MaxDataValueSize)
+		}
+	}
+	if maxSize > 0 && len(data) > 0 {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return "", fmt.Errorf("invalid data: %w", err)
+		}
+		if len(encoded) > maxSize {
+			return "", fmt.Errorf("data is %d bytes (max %d)", len(encoded), maxSize)
+		}
+	}
+	return "", nil
+}
+
+// dataField names the request field a ValidateData error is about: the value
+// at key, or the whole data when key is "".
+func dataField(key string) string {
+	if key == "" {
+		return "data"
+	}
+	return "data." + key
+}
+
+// e164Re matches an E.164 phone number: "+", country code, up to 15 digits total.
+var e164Re = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// ValidateRecipient checks that an address is well-formed for the channel:
+// a bare email address for email, an E.164 number for SMS. Push recipients
+// are opaque device tokens and are only checked for presence.
+func ValidateRecipient(channel Channel, to string) error {
+	switch channel {
+	case ChannelEmail:
+		addr, err := mail.ParseAddress(to)
+		if err != nil || addr.Address != to || addr.Name != "" {
+			return fmt.Errorf("invalid email address: %q", to)
+		}
+		if _, domain, _ := strings.Cut(to, "@"); !strings.Contains(domain, ".") {
+			return fmt.Errorf("invalid email address: %q", to)
+		}
+	case ChannelSMS:
+		if !e164Re.MatchString(to) {
+			return fmt.Errorf("invalid phone number %q: must be E.164 (e.g. +14155550100)", to)
+		}
+	default:
+		if strings.TrimSpace(to) == "" {
+			return fmt.Errorf("recipient is required")
+		}
+	}
+	return nil
+}
+
+// EmailDomain returns the lower-cased domain part of an email address.
+func EmailDomain(addr string) string {
+	_, domain, _ := strings.Cut(addr, "@")
+	return strings.ToLower(domain)
+}
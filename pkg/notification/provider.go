@@ -0,0 +1,141 @@
+package notification
+
+import (
+	"context"
+	"time"
+)
+
+// Provider defines the contract for a notification delivery channel.
+// Implementations live in pkg/email/ (Resend) and future channel packages (e.g., Twilio for SMS).
+type Provider interface {
+	// Send delivers a rendered message and returns the provider's message ID.
+	Send(ctx context.Context, msg *Message) (string, error)
+
+	// Channel returns which delivery channel this provider handles.
+	Channel() Channel
+}
+
+// BatchProvider is implemented by providers that can deliver many messages in
+// one API call. The worker uses it for batch tasks when available.
+type BatchProvider interface {
+	Provider
+
+	// SendBatch delivers msgs and returns their provider message IDs in the
+	// same order. The batch succeeds or fails as a whole.
+	SendBatch(ctx context.Context, msgs []*Message) ([]string, error)
+}
+
+// ProviderMetadata is what a provider's API answered to a send. It is stored
+// on the log so a rejection can be understood without the provider's dashboard.
+type ProviderMetadata struct {
+	StatusCode int               `json:"status_code,omitempty"`
+	ErrorCode  string            `json:"error_code,omitempty"` // the provider's own error name, e.g. "validation_error"
+	RateLimit  map[string]string `json:"rate_limit,omitempty"` // the response's rate-limit headers
+	Region     string            `json:"region,omitempty"`     // where the provider reports one
+	Attempts   int               `json:"attempts,omitempty"`   // API calls made, counting in-call retries
+}
+
+// MetadataProvider is optionally implemented by a Provider that reports the
+// provider's response to a send. The worker stores it on the log, whether the
+// send succeeded or failed.
+type MetadataProvider interface {
+	Provider
+
+	// SendWithMetadata is Send, also returning the response metadata (nil if
+	// no response was received).
+	SendWithMetadata(ctx context.Context, msg *Message) (string, *ProviderMetadata, error)
+}
+
+// MetadataBatchProvider is the batch counterpart of MetadataProvider. Every
+// log of a batch gets the metadata of the batch call.
+type MetadataBatchProvider interface {
+	BatchProvider
+
+	// SendBatchWithMetadata is SendBatch, also returning the response metadata.
+	SendBatchWithMetadata(ctx context.Context, msgs []*Message) ([]string, *ProviderMetadata, error)
+}
+
+// LinkTracker defines the contract for click tracking.
+// Implementations live in internal/infra/tracking/.
+type LinkTracker interface {
+	// Rewrite replaces links in rendered HTML with tracked redirects for the given log.
+	Rewrite(logID, html string) string
+
+	// Resolve decodes a tracking token into the log ID and original target URL.
+	Resolve(token string) (logID, target string, err error)
+}
+
+// MXChecker defines the contract for verifying that an email domain can receive mail.
+// Implementations live in internal/infra/validation/.
+type MXChecker interface {
+	// CanReceiveMail reports whether the domain publishes MX (or fallback A/AAAA) records.
+	// An error means the lookup itself failed and the answer is unknown.
+	CanReceiveMail(ctx context.Context, domain string) (bool, error)
+}
+
+// SweepLock defines the contract for a distributed lock that lets only one
+// replica run a periodic job (the reaper's sweep, the scheduler's tick) at a time.
+// Implementations live in internal/infra/lock/.
+type SweepLock interface {
+	// TryAcquire takes the lock for at most ttl without blocking. When acquired
+	// is true, release must be called once the work is done.
+	TryAcquire(ctx context.Context, ttl time.Duration) (release func(), acquired bool, err error)
+}
+
+// TemplateRenderer defines the contract for rendering notification templates.
+// Implementations live in pkg/template/.
+type TemplateRenderer interface {
+	// Render produces a subject line, HTML body, and plain-text body for the given notification type.
+	Render(notifType NotificationType, data map[string]any) (subject, html, text string, err error)
+}
+
+// SMSRenderer is optionally implemented by a TemplateRenderer with dedicated
+// SMS bodies. Without it, SMS notifications carry the plain-text body.
+type SMSRenderer interface {
+	// RenderSMS produces the text of an SMS for the given notification type.
+	RenderSMS(notifType NotificationType, data map[string]any) (string, error)
+}
+
+// PushRenderer is optionally implemented by a TemplateRenderer with dedicated
+// push payloads. Without it, push notifications carry the subject as title
+// and the plain-text body as body.
+type PushRenderer interface {
+	// RenderPush produces the push payload for the given notification type.
+	RenderPush(notifType NotificationType, data map[string]any) (*PushContent, error)
+}
+
+// renderMessage renders a notification for channel. SMS gets only a text
+// body and push a payload, from the renderer's SMS or push templates when it
+// has them; email gets the subject, HTML, and text.
+func renderMessage(renderer TemplateRenderer, channel Channel, notifType NotificationType, data map[string]any) (*RenderedContent, error) {
+	switch channel {
+	case ChannelSMS:
+		if sms, ok := renderer.(SMSRenderer); ok {
+			text, err := sms.RenderSMS(notifType, data)
+			if err != nil {
+				return nil, err
+			}
+			return &RenderedContent{Text: text}, nil
+		}
+	case ChannelPush:
+		if push, ok := renderer.(PushRenderer); ok {
+			payload, err := push.RenderPush(notifType, data)
+			if err != nil {
+				return nil, err
+			}
+			return &RenderedContent{Subject: payload.Title, Text: payload.Body, Push: payload}, nil
+		}
+	}
+
+	subject, html, text, err := renderer.Render(notifType, data)
+	if err != nil {
+		return nil, err
+	}
+	switch channel {
+	case ChannelSMS:
+		return &RenderedContent{Text: text}, nil
+	case ChannelPush:
+		return &RenderedContent{Subject: subject, Text: text, Push: &PushContent{Title: subject, Body: text}}, nil
+	}
+	return &RenderedContent{Subject: subject, HTML: html, Text: text}, nil
+}
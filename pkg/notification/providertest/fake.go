@@ -0,0 +1,238 @@
+package providertest
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// Response is one scripted answer of a Fake. The zero Response is a success.
+type Response struct {
+	// Status is the HTTP error status to answer with; 0 answers with success.
+	Status int
+
+	// RetryAfter is sent as the Retry-After header, e.g. "120".
+	RetryAfter string
+
+	// Hang sends no answer until the request is canceled.
+	Hang bool
+}
+
+// Request is a request a Fake received.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// Fake is an httptest server speaking a provider's HTTP API. It answers each
+// send with the next scripted Response, then with success, and records what
+// it received and the message IDs it issued; requests to other endpoints get
+// a 404. Point the provider under test at URL, or, for a provider that
+// answers in process, have it ask Next for each answer.
+type Fake struct {
+	URL string
+
+	api    fakeAPI
+	server *httptest.Server
+
+	mu        sync.Mutex
+	script    []Response
+	requests  []Request
+	issuedIDs []string
+	closed    bool
+}
+
+// ErrClosed is what Next returns once the fake is closed, as a send over
+// HTTP then fails to connect.
+var ErrClosed = errors.New("providertest: fake API closed")
+
+// fakeAPI is what tells the provider APIs apart: where messages are posted,
+// and how success and errors are answered.
+type fakeAPI struct {
+	// paths maps each send endpoint to how many messages a request body holds
+	paths map[string]func(body []byte) int
+
+	// success writes the answer for IDs, one per message
+	success func(w http.ResponseWriter, ids []string)
+
+	// failure writes an error answer with status
+	failure func(w http.ResponseWriter, status int)
+}
+
+// NewResendAPI starts a fake of Resend's emails API: POST /emails answers
+// {"id": ...}, POST /emails/batch {"data": [{"id": ...}, ...]}, and errors
+// {"statusCode", "name", "message"}.
+func NewResendAPI(t *testing.T) *Fake {
+	return newFake(t, fakeAPI{
+		paths: map[string]func([]byte) int{
+			"/emails": func([]byte) int { return 1 },
+			"/emails/batch": func(body []byte) int {
+				var msgs []json.RawMessage
+				_ = json.Unmarshal(body, &msgs)
+				return len(msgs)
+			},
+		},
+		success: func(w http.ResponseWriter, ids []string) {
+			if len(ids) == 1 {
+				writeJSON(w, http.StatusOK, map[string]string{"id": ids[0]})
+				return
+			}
+			data := make([]map[string]string, len(ids))
+			for i, id := range ids {
+				data[i] = map[string]string{"id": id}
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"data": data})
+		},
+		failure: func(w http.ResponseWriter, status int) {
+			writeJSON(w, status, map[string]any{
+				"statusCode": status,
+				"name":       resendErrorName(status),
+				"message":    "providertest: scripted " + http.StatusText(status),
+			})
+		},
+	})
+}
+
+func newFake(t *testing.T, api fakeAPI) *Fake {
+	f := &Fake{api: api}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	f.URL = f.server.URL
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// Script queues responses for the next requests, after any queued before.
+func (f *Fake) Script(responses ...Response) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.script = append(f.script, responses...)
+}
+
+// Requests returns the requests received so far.
+func (f *Fake) Requests() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Request(nil), f.requests...)
+}
+
+// IssuedIDs returns the message IDs the fake has answered with, in order.
+func (f *Fake) IssuedIDs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.issuedIDs...)
+}
+
+// Close shuts the server down, so further sends fail to connect.
+func (f *Fake) Close() {
+	f.mu.Lock()
+	f.closed = true
+	f.mu.Unlock()
+	f.server.CloseClientConnections()
+	f.server.Close()
+}
+
+// Next answers a send of n messages by a provider that answers in process
+// rather than over HTTP, such as a mock: it returns the next scripted
+// Response and, for a success, the n IDs issued. The send is recorded as a
+// request without a path or body. After Close it returns ErrClosed.
+func (f *Fake) Next(n int) (Response, []string, error) {
+	f.mu.Lock()
+	closed := f.closed
+	f.mu.Unlock()
+	if closed {
+		return Response{}, nil, ErrClosed
+	}
+	resp, ids := f.answer(Request{Method: http.MethodPost}, n)
+	return resp, ids, nil
+}
+
+// answer records req and takes the next scripted Response for it, issuing
+// n IDs (at least one) when it is a success.
+func (f *Fake) answer(req Request, n int) (Response, []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, req)
+	var resp Response
+	if len(f.script) > 0 {
+		resp, f.script = f.script[0], f.script[1:]
+	}
+	if resp.Hang || resp.Status != 0 {
+		return resp, nil
+	}
+	ids := make([]string, max(n, 1))
+	for i := range ids {
+		ids[i] = randomID()
+	}
+	f.issuedIDs = append(f.issuedIDs, ids...)
+	return resp, ids
+}
+
+func (f *Fake) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	req := Request{Method: r.Method, Path: r.URL.Path, Header: r.Header.Clone(), Body: body}
+
+	count, ok := f.api.paths[r.URL.Path]
+	if r.Method != http.MethodPost || !ok {
+		f.mu.Lock()
+		f.requests = append(f.requests, req)
+		f.mu.Unlock()
+		f.api.failure(w, http.StatusNotFound)
+		return
+	}
+	resp, ids := f.answer(req, count(body))
+
+	switch {
+	case resp.Hang:
+		<-r.Context().Done()
+		return
+	case resp.Status != 0:
+		if resp.RetryAfter != "" {
+			w.Header().Set("Retry-After", resp.RetryAfter)
+		}
+		f.api.failure(w, resp.Status)
+		return
+	}
+	f.api.success(w, ids)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// resendErrorName returns the error name Resend answers status with.
+func resendErrorName(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_request"
+	case http.StatusUnauthorized:
+		return "missing_api_key"
+	case http.StatusForbidden:
+		return "invalid_api_key"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusUnprocessableEntity:
+		return "validation_error"
+	case http.StatusTooManyRequests:
+		return "rate_limit_exceeded"
+	}
+	return "application_error"
+}
+
+// randomID returns a random version 4 UUID, the form Resend's IDs take.
+func randomID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,330 @@
+// Package providertest is a conformance suite for notification.Provider
+// implementations that call an HTTP API, with an httptest fake of the Resend
+// API to run it against. Every provider must answer the worker the same way —
+// a message ID on success, errors classified so the right ones are retried,
+// and the task's context honored — or sends get retried, dropped, or stuck
+// differently per provider. A provider runs it from its own test, pointed at
+// a fake:
+//
+//	func TestResendProvider(t *testing.T) {
+//		providertest.Run(t, func(t *testing.T) (notification.Provider, *providertest.Fake) {
+//			fake := providertest.NewResendAPI(t)
+//			p := email.NewResendProvider("re_test", "noreply@example.com", "Example")
+//			p.SetBaseURL(fake.URL)
+//			return p, fake
+//		})
+//	}
+//
+// A provider that answers in process, such as a mock, takes each answer from
+// the fake's Next instead.
+package providertest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+	"github.com/badrkarrachai/notifly/pkg/notification"
+)
+
+// Factory returns the provider under test, sending to the returned fake. It
+// is called once per check.
+type Factory func(t *testing.T) (notification.Provider, *Fake)
+
+// callLimit bounds how long any send may take in the checks, in-call retries
+// included; a provider that takes longer ignores its context or retries too long.
+const callLimit = 10 * time.Second
+
+// failures is how many times a persistent failure is scripted: more than any
+// provider's in-call retries.
+const failures = 10
+
+// Run runs every check against providers from newProvider, each as a subtest.
+func Run(t *testing.T, newProvider Factory) {
+	checks := []struct {
+		name  string
+		check func(t *testing.T, p notification.Provider, fake *Fake)
+	}{
+		{"ReturnsID", testReturnsID},
+		{"Batch", testBatch},
+		{"PermanentRejection", testPermanentRejection},
+		{"AuthErrorRetryable", testAuthErrorRetryable},
+		{"ServerErrorRetryable", testServerErrorRetryable},
+		{"TransientErrorRetriedInCall", testTransientErrorRetriedInCall},
+		{"RetryAfter", testRetryAfter},
+		{"TransportError", testTransportError},
+		{"ContextCanceled", testContextCanceled},
+		{"Timeout", testTimeout},
+	}
+	for _, c := range checks {
+		t.Run(c.name, func(t *testing.T) {
+			p, fake := newProvider(t)
+			c.check(t, p, fake)
+		})
+	}
+}
+
+// testReturnsID checks that a successful send returns the ID the API issued,
+// after one request.
+func testReturnsID(t *testing.T, p notification.Provider, fake *Fake) {
+	id, err := send(t, context.Background(), p)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	issued := fake.IssuedIDs()
+	if id == "" || !slices.Contains(issued, id) {
+		t.Errorf("Send returned ID %q, want the one the API issued (%v)", id, issued)
+	}
+	if n := len(fake.Requests()); n != 1 {
+		t.Errorf("Send made %d requests, want 1", n)
+	}
+
+	if m, ok := p.(notification.MetadataProvider); ok {
+		_, metadata, err := m.SendWithMetadata(context.Background(), message())
+		if err != nil {
+			t.Fatalf("SendWithMetadata: %v", err)
+		}
+		if metadata != nil && metadata.StatusCode >= 400 {
+			t.Errorf("metadata of a success has status %d", metadata.StatusCode)
+		}
+	}
+}
+
+// testBatch checks that a BatchProvider returns one distinct issued ID per
+// message, in one request.
+func testBatch(t *testing.T, p notification.Provider, fake *Fake) {
+	batcher, ok := p.(notification.BatchProvider)
+	if !ok {
+		t.Skip("not a BatchProvider")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), callLimit)
+	defer cancel()
+
+	msgs := []*notification.Message{message(), message(), message()}
+	ids, err := batcher.SendBatch(ctx, msgs)
+	if err != nil {
+		t.Fatalf("SendBatch: %v", err)
+	}
+	if len(ids) != len(msgs) {
+		t.Fatalf("SendBatch returned %d IDs for %d messages", len(ids), len(msgs))
+	}
+	if !slices.Equal(ids, fake.IssuedIDs()) {
+		t.Errorf("SendBatch returned %v, want the issued IDs in order %v", ids, fake.IssuedIDs())
+	}
+	if n := len(fake.Requests()); n != 1 {
+		t.Errorf("SendBatch made %d requests, want 1", n)
+	}
+}
+
+// testPermanentRejection checks that a rejection of the message itself is
+// permanent, carries its status, and is not retried.
+func testPermanentRejection(t *testing.T, p notification.Provider, fake *Fake) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnprocessableEntity} {
+		before := len(fake.Requests())
+		fake.Script(Response{Status: status})
+
+		_, err := send(t, context.Background(), p)
+		if err == nil {
+			t.Fatalf("Send answered %d succeeded", status)
+		}
+		if !common.IsPermanent(err) {
+			t.Errorf("Send answered %d: error %v is not permanent", status, err)
+		}
+		checkStatus(t, err, status)
+		if n := len(fake.Requests()) - before; n != 1 {
+			t.Errorf("Send answered %d made %d requests, want 1", status, n)
+		}
+		checkMetadataStatus(t, p, fake, status)
+	}
+}
+
+// testAuthErrorRetryable checks that auth errors are not permanent, so sends
+// recover once the key is fixed.
+func testAuthErrorRetryable(t *testing.T, p notification.Provider, fake *Fake) {
+	for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+		fake.Script(Response{Status: status})
+		_, err := send(t, context.Background(), p)
+		if err == nil {
+			t.Fatalf("Send answered %d succeeded", status)
+		}
+		if common.IsPermanent(err) {
+			t.Errorf("Send answered %d: error %v is permanent, want retryable", status, err)
+		}
+		checkStatus(t, err, status)
+	}
+}
+
+// testServerErrorRetryable checks that a persistent 5xx ends in a retryable
+// error carrying its status.
+func testServerErrorRetryable(t *testing.T, p notification.Provider, fake *Fake) {
+	fake.Script(repeat(Response{Status: http.StatusServiceUnavailable})...)
+	_, err := send(t, context.Background(), p)
+	if err == nil {
+		t.Fatal("Send answered 503 succeeded")
+	}
+	if common.IsPermanent(err) {
+		t.Errorf("error %v is permanent, want retryable", err)
+	}
+	checkStatus(t, err, http.StatusServiceUnavailable)
+}
+
+// testTransientErrorRetriedInCall checks that a single 5xx is either retried
+// in the call or returned as retryable, and that no more sends go out than
+// requests were answered.
+func testTransientErrorRetriedInCall(t *testing.T, p notification.Provider, fake *Fake) {
+	fake.Script(Response{Status: http.StatusBadGateway})
+	id, err := send(t, context.Background(), p)
+	switch {
+	case err == nil:
+		if !slices.Contains(fake.IssuedIDs(), id) {
+			t.Errorf("Send returned ID %q after a retry, want the issued one", id)
+		}
+	case common.IsPermanent(err):
+		t.Errorf("error %v after a 502 is permanent, want retryable", err)
+	}
+}
+
+// testRetryAfter checks that a Retry-After longer than a provider waits in
+// the call reaches the task retry as a common.RetryAfterError.
+func testRetryAfter(t *testing.T, p notification.Provider, fake *Fake) {
+	fake.Script(repeat(Response{Status: http.StatusTooManyRequests, RetryAfter: "120"})...)
+	_, err := send(t, context.Background(), p)
+	if err == nil {
+		t.Fatal("Send answered 429 succeeded")
+	}
+	if common.IsPermanent(err) {
+		t.Errorf("error %v after a 429 is permanent, want retryable", err)
+	}
+	after, ok := common.RetryAfter(err)
+	if !ok || after != 120*time.Second {
+		t.Errorf("Send answered 429 with Retry-After 120: retry after %v (%v), want 2m0s", after, ok)
+	}
+}
+
+// testTransportError checks that an unreachable API is a retryable failure.
+func testTransportError(t *testing.T, p notification.Provider, fake *Fake) {
+	fake.Close()
+	_, err := send(t, context.Background(), p)
+	if err == nil {
+		t.Fatal("Send to a closed server succeeded")
+	}
+	if common.IsPermanent(err) {
+		t.Errorf("transport error %v is permanent, want retryable", err)
+	}
+}
+
+// testContextCanceled checks that a send stops when its context is canceled,
+// before or during the request, without a permanent error.
+func testContextCanceled(t *testing.T, p notification.Provider, fake *Fake) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := send(t, ctx, p); err == nil {
+		t.Error("Send with a canceled context succeeded")
+	}
+
+	fake.Script(Response{Hang: true})
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	_, err := send(t, ctx, p)
+	if err == nil {
+		t.Fatal("Send canceled during the request succeeded")
+	}
+	if common.IsPermanent(err) {
+		t.Errorf("cancellation error %v is permanent, want retryable", err)
+	}
+}
+
+// testTimeout checks that a send gives up at its context's deadline when the
+// API does not answer.
+func testTimeout(t *testing.T, p notification.Provider, fake *Fake) {
+	fake.Script(repeat(Response{Hang: true})...)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := send(t, ctx, p)
+	if err == nil {
+		t.Fatal("Send to an API that never answers succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Send returned %v after its 200ms deadline", elapsed)
+	}
+	if common.IsPermanent(err) {
+		t.Errorf("timeout error %v is permanent, want retryable", err)
+	}
+	if ctx.Err() == nil {
+		t.Error("Send failed before its deadline, want it to wait for the answer")
+	}
+}
+
+// send sends one message within callLimit, failing the test if it runs over.
+func send(t *testing.T, ctx context.Context, p notification.Provider) (string, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(ctx, callLimit)
+	defer cancel()
+
+	start := time.Now()
+	id, err := p.Send(ctx, message())
+	if elapsed := time.Since(start); elapsed >= callLimit {
+		t.Fatalf("Send took %v", elapsed)
+	}
+	return id, err
+}
+
+// checkStatus checks that err carries status as a common.HTTPStatusError,
+// which failure codes are derived from.
+func checkStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var statusErr *common.HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		t.Errorf("error %v carries no HTTP status, want %d", err, status)
+		return
+	}
+	if statusErr.StatusCode != status {
+		t.Errorf("error carries status %d, want %d", statusErr.StatusCode, status)
+	}
+}
+
+// checkMetadataStatus checks that a MetadataProvider reports the status of a
+// failed send.
+func checkMetadataStatus(t *testing.T, p notification.Provider, fake *Fake, status int) {
+	t.Helper()
+	m, ok := p.(notification.MetadataProvider)
+	if !ok {
+		return
+	}
+	fake.Script(Response{Status: status})
+	ctx, cancel := context.WithTimeout(context.Background(), callLimit)
+	defer cancel()
+	_, metadata, err := m.SendWithMetadata(ctx, message())
+	if err == nil {
+		t.Fatalf("SendWithMetadata answered %d succeeded", status)
+	}
+	if metadata == nil || metadata.StatusCode != status {
+		t.Errorf("metadata of a %d failure = %+v, want its status", status, metadata)
+	}
+}
+
+// message returns an email to send.
+func message() *notification.Message {
+	return &notification.Message{
+		To:      []string{"providertest@example.com"},
+		Subject: "providertest",
+		HTML:    "<p>providertest</p>",
+		Text:    "providertest",
+		Tags:    map[string]string{"suite": "providertest"},
+	}
+}
+
+// repeat returns failures copies of resp.
+func repeat(resp Response) []Response {
+	responses := make([]Response, failures)
+	for i := range responses {
+		responses[i] = resp
+	}
+	return responses
+}
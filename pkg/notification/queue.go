@@ -0,0 +1,30 @@
+package notification
+
+import "context"
+
+// QueueControl pauses and resumes consumption of the send queues.
+// While paused, workers stop picking up tasks — tasks already running finish —
+// and new sends keep being enqueued, so nothing is lost while operators deal
+// with an incident such as a broken template.
+// Implementations live in internal/infra/queue/.
+type QueueControl interface {
+	PauseQueue(ctx context.Context) error
+	ResumeQueue(ctx context.Context) error
+	QueueState(ctx context.Context) (*QueueState, error)
+}
+
+// QueueState is a snapshot of the send queues, returned by
+// GET /api/v1/admin/queue: their names, whether any is paused, their total
+// task counts, and the age of the oldest pending task of any of them, with
+// each queue's own state in Queues.
+type QueueState struct {
+	Queue            string       `json:"queue"`
+	Paused           bool         `json:"paused"`
+	Pending          int          `json:"pending"`
+	Active           int          `json:"active"`
+	Scheduled        int          `json:"scheduled"`
+	Retry            int          `json:"retry"`
+	Archived         int          `json:"archived"`
+	OldestPendingSec float64      `json:"oldest_pending_sec"`
+	Queues           []QueueState `json:"queues,omitempty"`
+}
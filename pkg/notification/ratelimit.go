@@ -0,0 +1,52 @@
+package notification
+
+import (
+	"context"
+	"time"
+)
+
+// RecipientRateLimiter defines the contract for per-recipient rate limiting.
+// Implementations live in internal/infra/ratelimit/.
+type RecipientRateLimiter interface {
+	// Allow checks whether a notification of the given channel and type can be
+	// sent to the recipient, counting it if so. Limits may differ by channel and
+	// type. The result reports the decision and the window state for callers
+	// that surface it to clients.
+	Allow(ctx context.Context, recipient string, channel Channel, notifType NotificationType) (RateLimitResult, error)
+}
+
+// RateLimitResult is the outcome of a rate limit check.
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int           // sends allowed per window; 0 when the send is exempt
+	Remaining int           // sends left in the current window
+	Reset     time.Duration // until the oldest counted send leaves the window
+}
+
+// RateLimitInspector is implemented by recipient rate limiters that can report
+// and clear a recipient's windows. It backs the rate limit admin API, which
+// support uses to unblock a recipient who hit their cap.
+type RateLimitInspector interface {
+	// Usage reports every window that applies to the recipient under the
+	// current limits.
+	Usage(ctx context.Context, recipient string) ([]RateLimitWindow, error)
+	// Reset clears the recipient's windows and returns how many held sends.
+	Reset(ctx context.Context, recipient string) (int, error)
+}
+
+// RateLimitWindow is a recipient's usage of one limit. Rule is "default" for
+// recipient_rate_limit.max_per_hour, otherwise the channel, type, or
+// channel:type rule that owns the window.
+type RateLimitWindow struct {
+	Rule       string `json:"rule"`
+	Limit      int    `json:"limit"`
+	Used       int    `json:"used"`
+	Remaining  int    `json:"remaining"`
+	ResetInSec int    `json:"reset_in_sec"` // until the oldest counted send leaves the window
+}
+
+// RateLimitStatus is the response for GET /api/v1/admin/ratelimit/:recipient.
+type RateLimitStatus struct {
+	Recipient string            `json:"recipient"`
+	Windows   []RateLimitWindow `json:"windows"`
+}
@@ -0,0 +1,331 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ReaperConfig holds configuration for the stale task reaper.
+type ReaperConfig struct {
+	// Interval is how often the reaper scans for stale tasks.
+	Interval time.Duration
+
+	// StaleThreshold is how long a task can stay in queued/processing
+	// before the reaper considers it stale and re-enqueues it.
+	StaleThreshold time.Duration
+
+	// BatchSize is the maximum number of stale tasks to recover per cycle.
+	BatchSize int
+
+	// MaxRecoveryAttempts is how many times a log may be re-enqueued before
+	// the reaper marks it abandoned instead of retrying it forever.
+	MaxRecoveryAttempts int
+}
+
+// Reaper periodically scans the notification store for stuck tasks
+// and re-enqueues them. This ensures no notification is ever permanently
+// lost, even if Redis data is wiped or a worker crashes without recovery.
+//
+// This implements the "database reconciliation" pattern:
+// the database (Supabase) is the source of truth, and the reaper
+// reconciles it with the queue (Redis) on a timer.
+//
+// With several worker replicas, a SweepLock ensures only one of them sweeps at
+// a time so the same stale log is not re-enqueued twice.
+type Reaper struct {
+	store    NotificationStore
+	enqueuer Enqueuer
+	lock     SweepLock
+	stats    ReaperStatsStore
+	queue    QueueControl
+
+	mu      sync.RWMutex
+	config  ReaperConfig
+	local   ReaperStats   // totals for sweeps run by this process
+	updated chan struct{} // signals Run to pick up a new interval
+}
+
+// Reasons a sweep is skipped.
+const (
+	SkipReasonLocked      = "locked"
+	SkipReasonQueuePaused = "queue_paused"
+)
+
+// Sweep triggers, recorded on each SweepResult.
+const (
+	SweepTriggerTimer  = "timer"
+	SweepTriggerManual = "manual"
+)
+
+// SweepResult describes one reaper cycle.
+type SweepResult struct {
+	Trigger    string    `json:"trigger"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMS int64     `json:"duration_ms"`
+	// Skipped is set when another replica held the sweep lock or the queue
+	// was paused; SkipReason says which.
+	Skipped    bool   `json:"skipped"`
+	SkipReason string `json:"skip_reason,omitempty"`
+	StaleFound int    `json:"stale_found"`
+	Recovered  int    `json:"recovered"`
+	Abandoned  int    `json:"abandoned"`
+	Failures   int    `json:"failures"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ReaperStats aggregates completed (non-skipped) sweeps.
+type ReaperStats struct {
+	Sweeps     int64        `json:"sweeps"`
+	StaleFound int64        `json:"stale_found"`
+	Recovered  int64        `json:"recovered"`
+	Abandoned  int64        `json:"abandoned"`
+	Failures   int64        `json:"failures"`
+	LastSweep  *SweepResult `json:"last_sweep,omitempty"`
+}
+
+func (s *ReaperStats) add(result *SweepResult) {
+	s.Sweeps++
+	s.StaleFound += int64(result.StaleFound)
+	s.Recovered += int64(result.Recovered)
+	s.Abandoned += int64(result.Abandoned)
+	s.Failures += int64(result.Failures)
+	last := *result
+	s.LastSweep = &last
+}
+
+// NewReaper creates a new stale task reaper.
+// lock may be nil when only one reaper runs (e.g. a single worker replica);
+// stats may be nil to keep sweep totals in process only. queue may be nil;
+// otherwise sweeps are skipped while it is paused, because paused logs sit in
+// queued on purpose and must not be recovered and eventually abandoned.
+func NewReaper(store NotificationStore, enqueuer Enqueuer, lock SweepLock, stats ReaperStatsStore, queue QueueControl, cfg ReaperConfig) *Reaper {
+	return &Reaper{
+		store:    store,
+		enqueuer: enqueuer,
+		lock:     lock,
+		stats:    stats,
+		queue:    queue,
+		config:   cfg.withDefaults(),
+		updated:  make(chan struct{}, 1),
+	}
+}
+
+// withDefaults fills zero or negative fields with sensible defaults.
+func (c ReaperConfig) withDefaults() ReaperConfig {
+	if c.Interval <= 0 {
+		c.Interval = 5 * time.Minute
+	}
+	if c.StaleThreshold <= 0 {
+		c.StaleThreshold = 10 * time.Minute
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 50
+	}
+	if c.MaxRecoveryAttempts <= 0 {
+		c.MaxRecoveryAttempts = 3
+	}
+	return c
+}
+
+// UpdateConfig replaces the reaper settings while it is running. A changed
+// interval takes effect immediately; the threshold and batch size apply from
+// the next sweep.
+func (r *Reaper) UpdateConfig(cfg ReaperConfig) {
+	r.mu.Lock()
+	r.config = cfg.withDefaults()
+	r.mu.Unlock()
+
+	select {
+	case r.updated <- struct{}{}:
+	default: // an update is already pending
+	}
+}
+
+func (r *Reaper) currentConfig() ReaperConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config
+}
+
+// Run starts the reaper loop. It blocks until the context is cancelled.
+// Should be called in a goroutine.
+func (r *Reaper) Run(ctx context.Context) {
+	cfg := r.currentConfig()
+	slog.Info("reaper started",
+		"interval", cfg.Interval,
+		"stale_threshold", cfg.StaleThreshold,
+		"batch_size", cfg.BatchSize,
+		"max_recovery_attempts", cfg.MaxRecoveryAttempts,
+	)
+
+	interval := cfg.Interval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("reaper stopped")
+			return
+		case <-r.updated:
+			if next := r.currentConfig().Interval; next != interval {
+				interval = next
+				ticker.Reset(interval)
+				slog.Info("reaper interval updated", "interval", interval)
+			}
+		case <-ticker.C:
+			r.sweep(ctx)
+		}
+	}
+}
+
+// sweep runs one timer-driven cycle. Errors are logged; the next tick retries.
+func (r *Reaper) sweep(ctx context.Context) {
+	if _, err := r.Sweep(ctx, SweepTriggerTimer); err != nil {
+		slog.Error("reaper: sweep failed", "error", err)
+	}
+}
+
+// Sweep performs one reaper cycle immediately: find stale tasks, re-enqueue
+// them (or abandon repeat offenders), and record the outcome. When another
+// replica holds the sweep lock, the returned result has Skipped set and
+// nothing is recorded.
+func (r *Reaper) Sweep(ctx context.Context, trigger string) (*SweepResult, error) {
+	cfg := r.currentConfig()
+	result := &SweepResult{Trigger: trigger, StartedAt: time.Now().UTC()}
+
+	if r.queue != nil {
+		state, err := r.queue.QueueState(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("reading queue state: %w", err)
+		}
+		if state.Paused {
+			slog.Debug("reaper: queue is paused, skipping")
+			result.Skipped = true
+			result.SkipReason = SkipReasonQueuePaused
+			return result, nil
+		}
+	}
+
+	if r.lock != nil {
+		// The stale threshold comfortably bounds a sweep; if the holder dies the
+		// lock frees itself before the logs it was recovering go stale again.
+		release, acquired, err := r.lock.TryAcquire(ctx, cfg.StaleThreshold)
+		if err != nil {
+			return nil, fmt.Errorf("acquiring sweep lock: %w", err)
+		}
+		if !acquired {
+			slog.Debug("reaper: another replica is sweeping, skipping")
+			result.Skipped = true
+			result.SkipReason = SkipReasonLocked
+			return result, nil
+		}
+		defer release()
+	}
+	olderThan := time.Now().Add(-cfg.StaleThreshold)
+
+	staleLogs, err := r.store.ListStale(ctx, olderThan, cfg.BatchSize)
+	if err != nil {
+		result.Error = err.Error()
+		r.record(ctx, result)
+		return nil, fmt.Errorf("listing stale tasks: %w", err)
+	}
+	result.StaleFound = len(staleLogs)
+
+	if len(staleLogs) > 0 {
+		slog.Warn("reaper: found stale tasks", "count", len(staleLogs), "trigger", trigger)
+	}
+
+	for _, notifLog := range staleLogs {
+		// A log that keeps going stale is poison (or its dependencies are gone) — stop the loop
+		if notifLog.RecoveryAttempts >= cfg.MaxRecoveryAttempts {
+			errMsg := fmt.Sprintf("abandoned after %d reaper recoveries", notifLog.RecoveryAttempts)
+			if err := r.store.UpdateStatus(ctx, notifLog.ID, StatusAbandoned, "", errMsg); err != nil {
+				slog.Error("reaper: failed to abandon task",
+					"log_id", notifLog.ID,
+					"error", err,
+				)
+				result.Failures++
+				continue
+			}
+			result.Abandoned++
+			slog.Warn("reaper: abandoned stale task",
+				"log_id", notifLog.ID,
+				"recovery_attempts", notifLog.RecoveryAttempts,
+			)
+			continue
+		}
+
+		// Reset status to queued before re-enqueuing so the worker
+		// picks it up cleanly.
+		if err := r.store.RecordRecovery(ctx, notifLog.ID, notifLog.RecoveryAttempts+1); err != nil {
+			slog.Error("reaper: failed to reset status",
+				"log_id", notifLog.ID,
+				"error", err,
+			)
+			result.Failures++
+			continue
+		}
+
+		if err := r.enqueuer.EnqueueSendNotification(notifLog.ID); err != nil {
+			slog.Error("reaper: failed to re-enqueue task",
+				"log_id", notifLog.ID,
+				"error", err,
+			)
+			result.Failures++
+			continue
+		}
+
+		result.Recovered++
+		slog.Info("reaper: recovered stale task",
+			"log_id", notifLog.ID,
+			"original_status", notifLog.Status,
+			"attempt", notifLog.RecoveryAttempts+1,
+			"age", time.Since(notifLog.UpdatedAt).Round(time.Second),
+		)
+	}
+
+	result.DurationMS = time.Since(result.StartedAt).Milliseconds()
+	r.record(ctx, result)
+
+	if result.StaleFound > 0 {
+		slog.Info("reaper: sweep complete",
+			"trigger", trigger,
+			"total_stale", result.StaleFound,
+			"recovered", result.Recovered,
+			"abandoned", result.Abandoned,
+			"failures", result.Failures,
+		)
+	}
+	return result, nil
+}
+
+// record adds a completed sweep to the running totals. Totals are kept in
+// process and, when a ReaperStatsStore is configured, shared across replicas.
+func (r *Reaper) record(ctx context.Context, result *SweepResult) {
+	r.mu.Lock()
+	r.local.add(result)
+	r.mu.Unlock()
+
+	if r.stats == nil {
+		return
+	}
+	if err := r.stats.RecordSweep(ctx, result); err != nil {
+		slog.Error("reaper: failed to record sweep stats", "error", err)
+	}
+}
+
+// Stats returns the sweep totals and the most recent sweep — across all
+// replicas when a ReaperStatsStore is configured, otherwise for this process.
+func (r *Reaper) Stats(ctx context.Context) (*ReaperStats, error) {
+	if r.stats != nil {
+		return r.stats.ReaperStats(ctx)
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	stats := r.local
+	return &stats, nil
+}
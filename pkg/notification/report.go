@@ -0,0 +1,307 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DailyReportConfig holds configuration for the daily summary report.
+type DailyReportConfig struct {
+	// Recipients are the admin addresses the report is emailed to.
+	Recipients []string
+
+	// Hour is the UTC hour the report is sent at; it covers the 24 hours
+	// before.
+	Hour int
+
+	// Quotas are daily send quotas per channel (e.g. the provider plan's),
+	// reported against the channel's sends. Channels without one are left out.
+	Quotas map[Channel]int64
+}
+
+// reportTopFailures is how many failure codes the report lists.
+const reportTopFailures = 5
+
+// reportCheckInterval is how often the reporter checks whether the report is due.
+const reportCheckInterval = time.Minute
+
+// DailyReport is one period's operational summary.
+type DailyReport struct {
+	Since time.Time
+	Until time.Time
+
+	Total   int   // logs accepted in the period
+	Sent    int64 // provider send attempts that succeeded
+	Failed  int64 // provider send attempts that failed
+	Bounced int64
+
+	Types    []TypeVolume
+	Failures []FailureCount // most frequent first
+	Reaper   *ReaperStats   // all-time totals; nil without a reaper
+	Quotas   []QuotaUsage
+}
+
+// TypeVolume is one notification type's share of a report.
+type TypeVolume struct {
+	Type     NotificationType
+	Accepted int
+	Sent     int64
+	Failed   int64
+	Bounced  int64
+}
+
+// FailureCount is how many failed logs a report period has with one code.
+type FailureCount struct {
+	Code  FailureCode
+	Count int
+}
+
+// QuotaUsage is one channel's sends against its daily quota.
+type QuotaUsage struct {
+	Channel Channel
+	Used    int64
+	Limit   int64
+}
+
+// DailyReporter emails the daily summary report to admin addresses through
+// the normal Enqueue path, as a daily_summary email.
+//
+// Every replica runs one: each sends with the idempotency key
+// "daily-summary:<date>", so the report goes out once a day however many
+// replicas try. A report missed while no reporter was running is sent when
+// one starts later that day.
+type DailyReporter struct {
+	store    NotificationStore
+	outcomes OutcomeStore
+	reaper   *Reaper
+	sender   ScheduleSender
+	config   DailyReportConfig
+
+	lastSent string // date of the last report this process sent
+}
+
+// NewDailyReporter creates a daily reporter. outcomes and reaper may be nil
+// to leave the send rates and the reaper totals out of the report.
+func NewDailyReporter(store NotificationStore, outcomes OutcomeStore, reaper *Reaper, sender ScheduleSender, cfg DailyReportConfig) *DailyReporter {
+	return &DailyReporter{
+		store:    store,
+		outcomes: outcomes,
+		reaper:   reaper,
+		sender:   sender,
+		config:   cfg,
+	}
+}
+
+// Run starts the reporter loop. It blocks until the context is cancelled.
+// Should be called in a goroutine.
+func (r *DailyReporter) Run(ctx context.Context) {
+	slog.Info("daily reporter started", "hour_utc", r.config.Hour, "recipients", len(r.config.Recipients))
+
+	ticker := time.NewTicker(reportCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("daily reporter stopped")
+			return
+		case <-ticker.C:
+			now := time.Now().UTC()
+			date := now.Format(time.DateOnly)
+			if now.Hour() < r.config.Hour || date == r.lastSent {
+				continue
+			}
+			if err := r.Send(ctx, now); err != nil {
+				slog.Error("daily reporter: send failed", "error", err)
+				continue
+			}
+			r.lastSent = date
+		}
+	}
+}
+
+// Send builds the report for the 24 hours before until and enqueues it.
+func (r *DailyReporter) Send(ctx context.Context, until time.Time) error {
+	report, err := r.Build(ctx, until)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.sender.Enqueue(ctx, &SendRequest{
+		Channel:        ChannelEmail,
+		Type:           TypeDailySummary,
+		To:             Recipients(r.config.Recipients),
+		Data:           report.templateData(),
+		IdempotencyKey: "daily-summary:" + until.UTC().Format(time.DateOnly),
+	})
+	if err != nil {
+		return fmt.Errorf("enqueuing daily summary: %w", err)
+	}
+
+	slog.Info("daily summary enqueued", "id", resp.ID, "total", report.Total, "failed", report.Failed)
+	return nil
+}
+
+// Build gathers the report for the 24 hours before until.
+func (r *DailyReporter) Build(ctx context.Context, until time.Time) (*DailyReport, error) {
+	until = until.UTC()
+	report := &DailyReport{Since: until.Add(-24 * time.Hour), Until: until}
+
+	accepted, err := r.store.CountCreatedByType(ctx, report.Since, report.Until)
+	if err != nil {
+		return nil, fmt.Errorf("counting notifications: %w", err)
+	}
+	byType := make(map[NotificationType]*TypeVolume)
+	for notifType, n := range accepted {
+		if n == 0 {
+			continue
+		}
+		byType[notifType] = &TypeVolume{Type: notifType, Accepted: n}
+		report.Total += n
+	}
+
+	sentByChannel := make(map[Channel]int64)
+	if r.outcomes != nil {
+		counts, err := r.outcomes.OutcomeCounts(ctx, report.Since)
+		if err != nil {
+			return nil, fmt.Errorf("reading outcome counts: %w", err)
+		}
+		for _, count := range counts {
+			v, ok := byType[count.Type]
+			if !ok {
+				v = &TypeVolume{Type: count.Type}
+				byType[count.Type] = v
+			}
+			v.Sent += count.Sent
+			v.Failed += count.Failed
+			v.Bounced += count.Bounced
+			report.Sent += count.Sent
+			report.Failed += count.Failed
+			report.Bounced += count.Bounced
+			sentByChannel[count.Channel] += count.Sent
+		}
+	}
+	for _, v := range byType {
+		report.Types = append(report.Types, *v)
+	}
+	sort.Slice(report.Types, func(i, j int) bool {
+		if report.Types[i].Accepted != report.Types[j].Accepted {
+			return report.Types[i].Accepted > report.Types[j].Accepted
+		}
+		return report.Types[i].Type < report.Types[j].Type
+	})
+
+	failures, err := r.store.CountFailuresByCode(ctx, report.Since, report.Until)
+	if err != nil {
+		return nil, fmt.Errorf("counting failures: %w", err)
+	}
+	for code, n := range failures {
+		if n > 0 {
+			report.Failures = append(report.Failures, FailureCount{Code: code, Count: n})
+		}
+	}
+	sort.Slice(report.Failures, func(i, j int) bool {
+		if report.Failures[i].Count != report.Failures[j].Count {
+			return report.Failures[i].Count > report.Failures[j].Count
+		}
+		return report.Failures[i].Code < report.Failures[j].Code
+	})
+	if len(report.Failures) > reportTopFailures {
+		report.Failures = report.Failures[:reportTopFailures]
+	}
+
+	if r.reaper != nil {
+		stats, err := r.reaper.Stats(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("reading reaper stats: %w", err)
+		}
+		report.Reaper = stats
+	}
+
+	for channel, limit := range r.config.Quotas {
+		report.Quotas = append(report.Quotas, QuotaUsage{Channel: channel, Used: sentByChannel[channel], Limit: limit})
+	}
+	sort.Slice(report.Quotas, func(i, j int) bool { return report.Quotas[i].Channel < report.Quotas[j].Channel })
+
+	return report, nil
+}
+
+// templateData turns the report into the daily_summary template's data. Every
+// value is preformatted: template data is stored as JSON, where numbers lose
+// their type.
+func (r *DailyReport) templateData() map[string]any {
+	const layout = "January 2, 2006 3:04 PM"
+
+	types := make([]any, len(r.Types))
+	for i, v := range r.Types {
+		types[i] = map[string]any{
+			"Type":        string(v.Type),
+			"Accepted":    formatCount(int64(v.Accepted)),
+			"SuccessRate": formatRate(v.Sent, v.Sent+v.Failed),
+			"BounceRate":  formatRate(v.Bounced, v.Sent),
+		}
+	}
+	failures := make([]any, len(r.Failures))
+	for i, f := range r.Failures {
+		failures[i] = map[string]any{"Code": string(f.Code), "Count": formatCount(int64(f.Count))}
+	}
+	quotas := make([]any, len(r.Quotas))
+	for i, q := range r.Quotas {
+		quotas[i] = map[string]any{
+			"Channel": string(q.Channel),
+			"Used":    formatCount(q.Used),
+			"Limit":   formatCount(q.Limit),
+			"Percent": formatRate(q.Used, q.Limit),
+		}
+	}
+
+	var reaper map[string]any
+	if r.Reaper != nil {
+		lastSweep := "never"
+		if r.Reaper.LastSweep != nil {
+			lastSweep = r.Reaper.LastSweep.StartedAt.UTC().Format(layout) + " UTC"
+		}
+		reaper = map[string]any{
+			"Recovered": formatCount(r.Reaper.Recovered),
+			"Abandoned": formatCount(r.Reaper.Abandoned),
+			"LastSweep": lastSweep,
+		}
+	}
+
+	return map[string]any{
+		"Subject":  "Notifly Daily Summary — " + r.Until.Format(time.DateOnly),
+		"Period":   r.Since.Format(layout) + " – " + r.Until.Format(layout) + " UTC",
+		"Total":    formatCount(int64(r.Total)),
+		"Sent":     formatCount(r.Sent),
+		"Failed":   formatCount(r.Failed),
+		"Bounced":  formatCount(r.Bounced),
+		"Types":    types,
+		"Failures": failures,
+		"Reaper":   reaper,
+		"Quotas":   quotas,
+	}
+}
+
+// formatCount formats n with thousands separators.
+func formatCount(n int64) string {
+	if n < 0 {
+		return "-" + formatCount(-n)
+	}
+	s := strconv.FormatInt(n, 10)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// formatRate formats n/of as a percentage, or "–" when of is zero.
+func formatRate(n, of int64) string {
+	if of == 0 {
+		return "–"
+	}
+	return fmt.Sprintf("%.1f%%", float64(n)/float64(of)*100)
+}
@@ -0,0 +1,117 @@
+package notification
+
+import (
+	"net/mail"
+	"sync"
+)
+
+// Sender is an email sender identity: the address and display name a message
+// is sent from.
+type Sender struct {
+	Address string
+	Name    string
+}
+
+// String formats the sender as a From header value, e.g. "Acme <hello@acme.com>".
+func (s Sender) String() string {
+	if s.Name == "" {
+		return s.Address
+	}
+	return (&mail.Address{Name: s.Name, Address: s.Address}).String()
+}
+
+// Senders holds the named sender identities email can be sent from, and the
+// one each notification type uses unless a request names another. A
+// notification with no sender goes out from the provider's default address.
+// A nil *Senders has no identities.
+type Senders struct {
+	mu         sync.RWMutex
+	identities map[string]Sender
+	types      map[NotificationType]string
+
+	// defaultAddress and names rename the sender of a type; see SetFromNames.
+	defaultAddress string
+	names          map[NotificationType]string
+}
+
+// NewSenders creates the sender identities, by name, and the default sender
+// name of each type.
+func NewSenders(identities map[string]Sender, types map[NotificationType]string) *Senders {
+	return &Senders{identities: identities, types: types}
+}
+
+// Set replaces the identities and type defaults, e.g. after a config reload.
+// Logs already created keep the sender name they were given.
+func (s *Senders) Set(identities map[string]Sender, types map[NotificationType]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.identities = identities
+	s.types = types
+}
+
+// SetFromNames sets the display name each notification type's email is sent
+// under, replacing its sender identity's name, or, without an identity, the
+// provider's default name on defaultAddress. Safe for concurrent use.
+func (s *Senders) SetFromNames(defaultAddress string, names map[NotificationType]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaultAddress = defaultAddress
+	s.names = names
+}
+
+// has reports whether name is a configured identity.
+func (s *Senders) has(name string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.identities[name]
+	return ok
+}
+
+// resolve returns the sender name for a notification of notifType: requested
+// when set, otherwise the type's default, or "" for the provider's default.
+func (s *Senders) resolve(notifType NotificationType, requested string) string {
+	if requested != "" || s == nil {
+		return requested
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.types[notifType]
+}
+
+// from returns the From value of the named identity for a notification of
+// notifType, under the type's display name when it has one. It returns ""
+// when the name is no longer configured, or is empty and the type keeps the
+// default name, so the provider's default sender is used.
+func (s *Senders) from(notifType NotificationType, name string) string {
+	if s == nil {
+		return ""
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	identity, ok := s.identities[name]
+	if !ok {
+		if name != "" || s.names[notifType] == "" || s.defaultAddress == "" {
+			return ""
+		}
+		identity = Sender{Address: s.defaultAddress}
+	}
+	if fromName := s.names[notifType]; fromName != "" {
+		identity.Name = fromName
+	}
+	return identity.String()
+}
+
+// fromOrDefault returns the From value of a notification of notifType sent as
+// the named identity, like from, but the default address where from leaves
+// the provider's default. It returns "" when neither is known.
+func (s *Senders) fromOrDefault(notifType NotificationType, name string) string {
+	if from := s.from(notifType, name); from != "" || s == nil {
+		return from
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.defaultAddress
+}
@@ -0,0 +1,1249 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+)
+
+// Enqueuer defines the contract for enqueuing notification tasks.
+// This allows the service to be decoupled from the specific queue implementation.
+type Enqueuer interface {
+	EnqueueSendNotification(logID string) error
+}
+
+// BatchEnqueuer is optionally implemented by an Enqueuer that can hand several
+// logs to the worker as one task (Worker.ProcessBatch), so a fanned-out request
+// reaches the provider in a few batch calls instead of one call per recipient.
+type BatchEnqueuer interface {
+	EnqueueSendBatch(logIDs []string) error
+}
+
+// CriticalEnqueuer is optionally implemented by an Enqueuer with a queue
+// ahead of the others. Accepted notifications of ServiceConfig.CriticalTypes
+// go through it, so they never wait behind bulk traffic.
+type CriticalEnqueuer interface {
+	EnqueueCriticalSend(logID string) error
+	EnqueueCriticalBatch(logIDs []string) error
+}
+
+// ServiceConfig holds tunables for the notification service.
+type ServiceConfig struct {
+	// MaxRecipients caps how many addresses a single request may target.
+	MaxRecipients int
+
+	// MaxDataSize caps the JSON-encoded size of a request's template data
+	// (default DefaultMaxDataSize).
+	MaxDataSize int
+
+	// FanOut controls multi-recipient requests: when true each recipient gets
+	// its own log and task (per-recipient status tracking); when false one log
+	// is created and the provider is called once with every recipient.
+	FanOut bool
+
+	// BatchSize groups the logs of a fanned-out request into batch tasks of up
+	// to this many when the Enqueuer implements BatchEnqueuer. 0 or 1 disables
+	// batching: every log gets its own task.
+	BatchSize int
+
+	// SuppressBounced rejects recipients that already have a bounced
+	// notification. It can be changed later with SetSuppressBounced.
+	SuppressBounced bool
+
+	// RateLimitFailClosed rejects sends when the rate limiter cannot be
+	// consulted (e.g. Redis is down) instead of letting them through
+	// unlimited. It can be changed later with SetRateLimitFailClosed.
+	RateLimitFailClosed bool
+
+	// RenderAtEnqueue renders the template when a request is accepted and
+	// stores the result on its logs, instead of rendering in the worker. It
+	// can be changed later with SetRenderAtEnqueue.
+	RenderAtEnqueue bool
+
+	// Fallbacks are the cross-channel fallback rules applied to requests
+	// with a fallback_to address. They can be changed later with SetFallbacks.
+	Fallbacks FallbackRules
+
+	// SoftBounces is how soft-bounced notifications are retried when the
+	// Enqueuer implements BounceRetryEnqueuer. It can be changed later with
+	// SetSoftBounceRetry.
+	SoftBounces SoftBounceRetry
+
+	// CriticalTypes are the notification types enqueued through the
+	// CriticalEnqueuer, when the Enqueuer implements it.
+	CriticalTypes []NotificationType
+
+	// DigestTypes are the notification types whose sends to one recipient
+	// are combined into digests, when the Enqueuer implements DigestEnqueuer.
+	DigestTypes []NotificationType
+}
+
+// Service orchestrates notification business logic.
+// In the async flow: validate → check idempotency → check rate limit → create log → enqueue.
+type Service struct {
+	store       NotificationStore
+	enqueuer    Enqueuer
+	admission   admission
+	tracker     LinkTracker
+	mxChecker   MXChecker
+	renderer    TemplateRenderer
+	devices     *Devices
+	escalations *Escalations
+	followUps   *FollowUps
+	variants    *Variants
+	senders     *Senders
+	latency     LatencyRecorder
+	outcomes    OutcomeStore
+	costs       CostStore
+	config      ServiceConfig
+
+	// direct sends critical types when the queue is down; nil disables it
+	direct        DirectSender
+	directTimeout time.Duration
+
+	renderAtEnqueue atomic.Bool
+	fallbacks       atomic.Pointer[FallbackRules]
+	softBounces     atomic.Pointer[SoftBounceRetry]
+	usage           atomic.Pointer[UsageConfig]
+}
+
+// NewService creates a new notification service.
+// rateLimiter, tracker, mxChecker, and renderer may be nil to disable
+// per-recipient limits, click tracking, MX checks, and rendering at enqueue
+// respectively.
+func NewService(store NotificationStore, enqueuer Enqueuer, rateLimiter RecipientRateLimiter, tracker LinkTracker, mxChecker MXChecker, renderer TemplateRenderer, cfg ServiceConfig) *Service {
+	// Sensible defaults
+	if cfg.MaxRecipients <= 0 {
+		cfg.MaxRecipients = 50
+	}
+	if cfg.MaxDataSize <= 0 {
+		cfg.MaxDataSize = DefaultMaxDataSize
+	}
+
+	s := &Service{
+		store:     store,
+		enqueuer:  enqueuer,
+		admission: admission{store: store, rateLimiter: rateLimiter},
+		tracker:   tracker,
+		mxChecker: mxChecker,
+		renderer:  renderer,
+		config:    cfg,
+	}
+	s.admission.suppressBounced.Store(cfg.SuppressBounced)
+	s.admission.rateLimitFailClosed.Store(cfg.RateLimitFailClosed)
+	s.renderAtEnqueue.Store(cfg.RenderAtEnqueue)
+	s.SetFallbacks(cfg.Fallbacks)
+	s.SetSoftBounceRetry(cfg.SoftBounces)
+	return s
+}
+
+// SetSuppressBounced toggles bounce suppression. Safe for concurrent use.
+func (s *Service) SetSuppressBounced(enabled bool) {
+	s.admission.suppressBounced.Store(enabled)
+}
+
+// SetRateLimitFailClosed chooses what happens when the rate limiter errors:
+// reject the send (true) or allow it unlimited (false). Safe for concurrent use.
+func (s *Service) SetRateLimitFailClosed(enabled bool) {
+	s.admission.rateLimitFailClosed.Store(enabled)
+}
+
+// SetRenderAtEnqueue chooses where templates are rendered: when a request is
+// accepted (true) or when the worker sends it (false). Safe for concurrent use.
+func (s *Service) SetRenderAtEnqueue(enabled bool) {
+	s.renderAtEnqueue.Store(enabled)
+}
+
+// SetFallbacks replaces the cross-channel fallback rules. Fallbacks already
+// scheduled keep the rule they were accepted with.
+func (s *Service) SetFallbacks(rules FallbackRules) {
+	s.fallbacks.Store(&rules)
+}
+
+// fallbackRule returns the fallback rule for a request, if any.
+func (s *Service) fallbackRule(req *SendRequest) (FallbackRule, bool) {
+	return (*s.fallbacks.Load()).lookup(req.Channel, req.Type)
+}
+
+// SetDevices enables push to a user_id: the request fans out to the devices
+// registered for the user. Call it before the service handles requests.
+func (s *Service) SetDevices(devices *Devices) {
+	s.devices = devices
+}
+
+// SetEscalations enables escalation policies: a request whose type has an
+// enabled policy starts its escalation chain when accepted.
+func (s *Service) SetEscalations(escalations *Escalations) {
+	s.escalations = escalations
+}
+
+// SetFollowUps enables follow-up sequences: a log whose type has an enabled
+// sequence is followed up with reminders until it is opened. Sends to a
+// user_id are not followed up. Call it before the service handles requests.
+func (s *Service) SetFollowUps(followUps *FollowUps) {
+	s.followUps = followUps
+}
+
+// SetVariants enables A/B tests of templates: each log of a type under test
+// gets a variant, and Stats reports the variants' open and click rates.
+// Call it before the service handles requests.
+func (s *Service) SetVariants(variants *Variants) {
+	s.variants = variants
+}
+
+// SetSenders enables sender identities: an email log is sent from the
+// identity its request names, or its type's default. Call it before the
+// service handles requests.
+func (s *Service) SetSenders(senders *Senders) {
+	s.senders = senders
+}
+
+// SetLatency enables delivery latency tracking: delivered and opened webhook
+// events observe their time since the send in latency, and Stats reports the
+// percentiles.
+func (s *Service) SetLatency(latency LatencyRecorder) {
+	s.latency = latency
+}
+
+// SetOutcomes enables bounce counting for alerting: bounced webhook events
+// count in outcomes.
+func (s *Service) SetOutcomes(outcomes OutcomeStore) {
+	s.outcomes = outcomes
+}
+
+// rateLimitInspector returns the rate limiter's admin view, or nil when there is
+// no rate limiter or it cannot be inspected.
+func (s *Service) rateLimitInspector() RateLimitInspector {
+	inspector, _ := s.admission.rateLimiter.(RateLimitInspector)
+	return inspector
+}
+
+// RateLimitStatus reports the recipient's current rate limit windows.
+func (s *Service) RateLimitStatus(ctx context.Context, recipient string) (*RateLimitStatus, error) {
+	windows, err := s.rateLimitInspector().Usage(ctx, recipient)
+	if err != nil {
+		return nil, fmt.Errorf("reading rate limit usage: %w", err)
+	}
+	return &RateLimitStatus{Recipient: recipient, Windows: windows}, nil
+}
+
+// ResetRateLimit clears the recipient's rate limit windows so they can be sent
+// to again immediately. Returns how many windows held sends.
+func (s *Service) ResetRateLimit(ctx context.Context, recipient string) (int, error) {
+	cleared, err := s.rateLimitInspector().Reset(ctx, recipient)
+	if err != nil {
+		return 0, fmt.Errorf("resetting rate limit: %w", err)
+	}
+	slog.Info("recipient rate limit reset", "recipient", recipient, "windows_cleared", cleared)
+	return cleared, nil
+}
+
+// Enqueue validates a notification request, checks idempotency and rate limits,
+// creates a log record, and enqueues the task for async processing.
+// Multi-recipient requests either fan out into one log per recipient or are
+// sent as a single message, depending on ServiceConfig.FanOut. A push to a
+// user_id always fans out, one child log per device.
+func (s *Service) Enqueue(ctx context.Context, req *SendRequest) (*SendResponse, error) {
+	// Validate notification type
+	if !IsValidType(req.Type) {
+		return nil, common.NewFieldError("type", fmt.Sprintf("unsupported notification type: %s", req.Type))
+	}
+
+	if req.IdempotencyKey != "" {
+		if err := ValidateIdempotencyKey(req.IdempotencyKey); err != nil {
+			return nil, common.NewFieldError("idempotency_key", err.Error())
+		}
+	}
+	if key, err := ValidateData(req.Data, s.config.MaxDataSize); err != nil {
+		return nil, common.NewFieldError(dataField(key), err.Error())
+	}
+	if req.Channel == ChannelEmail {
+		if _, err := calendarEvent(req.Data); err != nil {
+			return nil, common.NewFieldError(dataField(EventKey), err.Error())
+		}
+	}
+	if err := ValidateHeaders(req.Headers); err != nil {
+		return nil, common.NewFieldError("headers", err.Error())
+	}
+	if err := ValidateTags(req.Tags); err != nil {
+		return nil, common.NewFieldError("tags", err.Error())
+	}
+	if req.Sender != "" && !s.senders.has(req.Sender) {
+		return nil, common.NewFieldError("sender", fmt.Sprintf("unknown sender: %s", req.Sender))
+	}
+
+	recipients := req.To.Normalize()
+	if req.UserID != "" {
+		if err := validateUserTarget(req); err != nil {
+			return nil, err
+		}
+		tokens, err := s.userTokens(ctx, req.UserID)
+		if err != nil {
+			return nil, err
+		}
+		recipients = tokens
+	}
+	if len(recipients) == 0 {
+		return nil, common.NewFieldError("to", "at least one recipient is required")
+	}
+	if len(recipients) > s.config.MaxRecipients {
+		return nil, common.NewFieldError("to", fmt.Sprintf("too many recipients: %d (max %d)", len(recipients), s.config.MaxRecipients))
+	}
+	if err := s.validateRecipients(ctx, req.Channel, recipients); err != nil {
+		return nil, err
+	}
+	if req.FallbackTo != "" {
+		if err := s.validateFallback(req, recipients); err != nil {
+			return nil, err
+		}
+	}
+	escalation, err := s.escalationFor(ctx, req, recipients)
+	if err != nil {
+		return nil, err
+	}
+	followUp := s.followUpFor(ctx, req)
+
+	// Render once per template variant for every log of the request, so
+	// logs with the same variant all say the same thing
+	var content variantContent
+	if s.renderer != nil && s.renderAtEnqueue.Load() {
+		if content, err = s.renderVariants(req); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.UserID != "" {
+		return s.enqueueUser(ctx, req, recipients, content, escalation)
+	}
+	if len(recipients) > 1 && s.config.FanOut {
+		return s.enqueueFanOut(ctx, req, recipients, content, followUp)
+	}
+
+	return s.enqueueOne(ctx, req, recipients, req.IdempotencyKey, true, content, escalation, followUp)
+}
+
+// validateUserTarget checks a request addressed to a user_id: only push can
+// be sent to a user's devices, and addresses cannot be given as well.
+func validateUserTarget(req *SendRequest) error {
+	if req.Channel != ChannelPush {
+		return common.NewValidationError(fmt.Sprintf("user_id is only supported on the push channel, not %s", req.Channel))
+	}
+	if len(req.To.Normalize()) > 0 {
+		return common.NewValidationError("set either to or user_id, not both")
+	}
+	return nil
+}
+
+// validateFallback checks a request's fallback_to: a fallback rule must apply
+// to the request, the address must suit the rule's channel, and the request
+// must have a single recipient or a user_id, for whom the address stands in.
+func (s *Service) validateFallback(req *SendRequest, recipients Recipients) error {
+	if _, ok := s.enqueuer.(FallbackEnqueuer); !ok {
+		return common.NewValidationError("fallback_to is not supported: the queue cannot schedule fallbacks")
+	}
+	rule, ok := s.fallbackRule(req)
+	if !ok {
+		return common.NewValidationError(fmt.Sprintf("fallback_to is set but no fallback rule applies to %s:%s", req.Channel, req.Type))
+	}
+	if req.UserID == "" && len(recipients) > 1 {
+		return common.NewValidationError("fallback_to needs a single recipient or a user_id")
+	}
+	if err := ValidateRecipient(rule.Channel, req.FallbackTo); err != nil {
+		return common.NewFieldError("fallback_to", "fallback_to: "+err.Error())
+	}
+	return nil
+}
+
+// scheduleFallback schedules the fallback check for a new log. A failure is
+// logged without failing the request: the notification itself was accepted.
+func (s *Service) scheduleFallback(logID string, after time.Duration) {
+	enqueuer, ok := s.enqueuer.(FallbackEnqueuer)
+	if !ok {
+		return
+	}
+	if err := enqueuer.EnqueueFallback(logID, after); err != nil {
+		slog.Error("failed to schedule fallback", "log_id", logID, "error", err)
+	}
+}
+
+// escalationFor returns the escalation a request starts, or nil when none
+// applies.
+func (s *Service) escalationFor(ctx context.Context, req *SendRequest, recipients Recipients) (*Escalation, error) {
+	if s.escalations == nil {
+		if len(req.EscalateTo) > 0 {
+			return nil, common.NewValidationError("escalate_to is not supported: escalation policies are not enabled")
+		}
+		return nil, nil
+	}
+	return s.escalations.escalationFor(ctx, req, recipients)
+}
+
+// followUpFor returns the follow-up sequence a request's logs start, or nil
+// when none applies. A failed lookup is logged without failing the request:
+// the notification matters more than its reminders.
+func (s *Service) followUpFor(ctx context.Context, req *SendRequest) *FollowUpSequence {
+	if s.followUps == nil || req.UserID != "" {
+		return nil
+	}
+	sequence, err := s.followUps.sequenceFor(ctx, req.Type)
+	if err != nil {
+		slog.Error("follow-up sequence lookup failed, sending without follow-ups", "type", req.Type, "error", err)
+		return nil
+	}
+	return sequence
+}
+
+// userTokens returns the tokens of the user's most recently seen devices, at
+// most MaxRecipients of them.
+func (s *Service) userTokens(ctx context.Context, userID string) (Recipients, error) {
+	if s.devices == nil {
+		return nil, common.NewValidationError("user_id is not supported: no device registry is configured")
+	}
+	devices, err := s.devices.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(devices) == 0 {
+		return nil, common.NewValidationError(fmt.Sprintf("user has no registered devices: %s", userID))
+	}
+
+	devices = devices[:min(len(devices), s.config.MaxRecipients)]
+	tokens := make(Recipients, len(devices))
+	for i, device := range devices {
+		tokens[i] = device.Token
+	}
+	return tokens, nil
+}
+
+// variantContent holds a request's content rendered at enqueue, by template
+// variant name ("" for none). It is nil when rendering is left to the worker.
+type variantContent map[string]*RenderedContent
+
+// renderVariants renders a request once for each variant its type is tested
+// with, or once when it is not under test.
+func (s *Service) renderVariants(req *SendRequest) (variantContent, error) {
+	variants := s.variants.all(req.Type)
+	if len(variants) == 0 {
+		variants = []TemplateVariant{{}}
+	}
+	content := make(variantContent, len(variants))
+	for _, variant := range variants {
+		rendered, err := renderContent(s.renderer, variant, req.Channel, req.Type, req.Data)
+		if err != nil {
+			return nil, err
+		}
+		content[variant.Name] = rendered
+	}
+	return content, nil
+}
+
+// renderContent renders a notification to store on its logs. A template that
+// fails to render is the request's fault, so the error is a ValidationError.
+func renderContent(renderer TemplateRenderer, variant TemplateVariant, channel Channel, notifType NotificationType, data map[string]any) (*RenderedContent, error) {
+	content, err := renderVariant(renderer, variant, channel, notifType, data)
+	if err != nil {
+		return nil, common.NewValidationError(fmt.Sprintf("rendering template %s: %s", notifType, err))
+	}
+	return content, nil
+}
+
+// validateRecipients rejects malformed addresses and, when an MXChecker is
+// configured, email domains that cannot receive mail. MX lookup failures fail
+// open — a DNS hiccup must not block notifications.
+func (s *Service) validateRecipients(ctx context.Context, channel Channel, recipients Recipients) error {
+	for _, to := range recipients {
+		if err := ValidateRecipient(channel, to); err != nil {
+			return common.NewFieldError("to", err.Error())
+		}
+	}
+
+	if channel != ChannelEmail || s.mxChecker == nil {
+		return nil
+	}
+
+	checked := make(map[string]bool)
+	for _, to := range recipients {
+		domain := EmailDomain(to)
+		if checked[domain] {
+			continue
+		}
+		checked[domain] = true
+
+		ok, err := s.mxChecker.CanReceiveMail(ctx, domain)
+		if err != nil {
+			slog.Warn("mx check failed, accepting recipient", "domain", domain, "error", err)
+			continue
+		}
+		if !ok {
+			return common.NewFieldError("to", fmt.Sprintf("email domain cannot receive mail: %s", domain))
+		}
+	}
+	return nil
+}
+
+// enqueueFanOut creates one log and task per recipient. A recipient rejected by
+// validation (e.g. rate limited) or whose log could not be created or enqueued is
+// reported in the response without failing the others, so the caller learns which
+// recipients were already accepted. Derived idempotency keys ("<key>:<recipient>")
+// make retries safe. CC and BCC are attached to the first accepted recipient only,
+// otherwise every copy address would receive one email per recipient. With
+// batching, logs are created first and enqueued in groups of BatchSize.
+func (s *Service) enqueueFanOut(ctx context.Context, req *SendRequest, recipients Recipients, content variantContent, followUp *FollowUpSequence) (*SendResponse, error) {
+	resp := &SendResponse{
+		IdempotencyKey: req.IdempotencyKey,
+		Channel:        string(req.Channel),
+		Status:         string(StatusQueued),
+		Notifications:  make([]RecipientResult, 0, len(recipients)),
+	}
+
+	// Digested logs are enqueued one by one, each into its recipient's group
+	batcher, batching := s.enqueuer.(BatchEnqueuer)
+	batching = batching && s.config.BatchSize > 1 && s.digests(req.Type) == nil
+
+	// pending holds logs created but not yet enqueued when batching:
+	// their IDs and their index in resp.Notifications
+	var pendingIDs []string
+	var pendingIdx []int
+
+	accepted := 0
+	var lastErr error
+	for _, to := range recipients {
+		key := ""
+		if req.IdempotencyKey != "" {
+			key = req.IdempotencyKey + ":" + to
+		}
+
+		var result *SendResponse
+		var err error
+		if batching {
+			var notifLog *NotificationLog
+			notifLog, result, err = s.createLog(ctx, req, Recipients{to}, key, accepted == 0, content, nil, followUp)
+			if notifLog != nil {
+				pendingIDs = append(pendingIDs, notifLog.ID)
+				pendingIdx = append(pendingIdx, len(resp.Notifications))
+				result = &SendResponse{ID: notifLog.ID, IdempotencyKey: notifLog.IdempotencyKey, Status: string(StatusQueued)}
+			}
+		} else {
+			result, err = s.enqueueOne(ctx, req, Recipients{to}, key, accepted == 0, content, nil, followUp)
+		}
+		if err != nil {
+			var validation *common.ValidationError
+			var rateLimited *common.RateLimitError
+			if errors.As(err, &validation) || errors.As(err, &rateLimited) {
+				resp.Notifications = append(resp.Notifications, RecipientResult{
+					To:     to,
+					Status: "rejected",
+					Error:  err.Error(),
+				})
+				continue
+			}
+			var conflict *common.ConflictError
+			if errors.As(err, &conflict) {
+				lastErr = err
+				resp.Notifications = append(resp.Notifications, RecipientResult{
+					ID:             conflict.ExistingID,
+					To:             to,
+					IdempotencyKey: key,
+					Status:         "conflict",
+					Error:          conflict.Error(),
+				})
+				continue
+			}
+
+			slog.Error("fan-out enqueue failed for recipient", "to", to, "error", err)
+			lastErr = err
+			resp.Notifications = append(resp.Notifications, RecipientResult{
+				To:     to,
+				Status: string(StatusFailed),
+				Error:  "failed to enqueue notification",
+			})
+			continue
+		}
+
+		accepted++
+		resp.Notifications = append(resp.Notifications, RecipientResult{
+			ID:             result.ID,
+			To:             to,
+			IdempotencyKey: result.IdempotencyKey,
+			Status:         result.Status,
+		})
+	}
+
+	// Enqueue the created logs in batches; a failed batch fails only its recipients
+	for start := 0; start < len(pendingIDs); start += s.config.BatchSize {
+		end := min(start+s.config.BatchSize, len(pendingIDs))
+		ids := pendingIDs[start:end]
+		if err := s.enqueueSendBatch(batcher, req.Type, ids); err != nil {
+			slog.Error("fan-out batch enqueue failed", "count", len(ids), "error", err)
+			lastErr = fmt.Errorf("enqueuing notification batch: %w", err)
+			for i, id := range ids {
+				_ = s.store.UpdateStatus(ctx, id, StatusFailed, "", "failed to enqueue: "+err.Error())
+				result := &resp.Notifications[pendingIdx[start+i]]
+				result.Status = string(StatusFailed)
+				result.Error = "failed to enqueue notification"
+			}
+			accepted -= len(ids)
+			continue
+		}
+		slog.Info("notification batch enqueued", "channel", req.Channel, "type", req.Type, "count", len(ids))
+	}
+
+	if accepted == 0 {
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, common.NewValidationError("all recipients were rejected: " + resp.Notifications[0].Error)
+	}
+
+	return resp, nil
+}
+
+// enqueueUser sends a push to a user's devices: it creates a parent log for
+// the user, then one child log and task per device token. The parent is never
+// sent; the worker sets its status from its children's as they finish.
+// Idempotency, suppression, and rate limits apply to the user, on the parent.
+func (s *Service) enqueueUser(ctx context.Context, req *SendRequest, tokens Recipients, content variantContent, escalation *Escalation) (*SendResponse, error) {
+	parent, existing, err := s.createLog(ctx, req, Recipients{req.UserID}, req.IdempotencyKey, false, content, escalation, nil)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	resp := &SendResponse{
+		ID:             parent.ID,
+		IdempotencyKey: parent.IdempotencyKey,
+		Channel:        string(req.Channel),
+		Status:         string(StatusQueued),
+		Notifications:  make([]RecipientResult, 0, len(tokens)),
+	}
+
+	var childIDs []string
+	for _, token := range tokens {
+		child := &NotificationLog{
+			UserID:       parent.UserID,
+			ParentID:     parent.ID,
+			APIKeyID:     parent.APIKeyID,
+			Channel:      parent.Channel,
+			Type:         parent.Type,
+			Variant:      parent.Variant,
+			Recipient:    token,
+			Headers:      parent.Headers,
+			Tags:         parent.Tags,
+			TemplateData: parent.TemplateData,
+			Content:      parent.Content,
+			Status:       StatusQueued,
+		}
+		if err := s.store.Create(ctx, child); err != nil {
+			slog.Error("device fan-out failed for token", "parent_id", parent.ID, "error", err)
+			resp.Notifications = append(resp.Notifications, RecipientResult{
+				To:     token,
+				Status: string(StatusFailed),
+				Error:  "failed to create notification",
+			})
+			continue
+		}
+		childIDs = append(childIDs, child.ID)
+		resp.Notifications = append(resp.Notifications, RecipientResult{
+			ID:     child.ID,
+			To:     token,
+			Status: string(StatusQueued),
+		})
+	}
+
+	// Enqueue the children, in batches when the enqueuer supports them
+	var enqueueErr error
+	notEnqueued := make(map[string]bool)
+	if batcher, ok := s.enqueuer.(BatchEnqueuer); ok && s.config.BatchSize > 1 && len(childIDs) > 1 {
+		for start := 0; start < len(childIDs); start += s.config.BatchSize {
+			ids := childIDs[start:min(start+s.config.BatchSize, len(childIDs))]
+			if err := s.enqueueSendBatch(batcher, req.Type, ids); err != nil {
+				enqueueErr = err
+				for _, id := range ids {
+					notEnqueued[id] = true
+				}
+			}
+		}
+	} else {
+		for _, id := range childIDs {
+			if err := s.enqueueSend(req.Type, id); err != nil {
+				enqueueErr = err
+				notEnqueued[id] = true
+			}
+		}
+	}
+
+	queued := 0
+	for i := range resp.Notifications {
+		result := &resp.Notifications[i]
+		if notEnqueued[result.ID] {
+			_ = s.store.UpdateStatus(ctx, result.ID, StatusFailed, "", "failed to enqueue: "+enqueueErr.Error())
+			result.Status = string(StatusFailed)
+			result.Error = "failed to enqueue notification"
+		}
+		if result.Status == string(StatusQueued) {
+			queued++
+		}
+	}
+	if queued == 0 {
+		_ = s.store.UpdateStatus(ctx, parent.ID, StatusFailed, "", "no device could be enqueued")
+		if enqueueErr != nil {
+			return nil, fmt.Errorf("enqueuing device notifications: %w", enqueueErr)
+		}
+		return nil, fmt.Errorf("creating device notifications for %s failed", parent.ID)
+	}
+
+	slog.Info("notification enqueued for user devices",
+		"id", parent.ID,
+		"type", req.Type,
+		"user_id", req.UserID,
+		"devices", queued,
+	)
+	return resp, nil
+}
+
+// enqueueOne creates a single log addressed to the given recipients and enqueues it.
+// withCopies controls whether the request's CC and BCC addresses go on this log;
+// content, when not nil, holds what is stored on it as rendered at enqueue.
+func (s *Service) enqueueOne(ctx context.Context, req *SendRequest, recipients Recipients, idempotencyKey string, withCopies bool, content variantContent, escalation *Escalation, followUp *FollowUpSequence) (*SendResponse, error) {
+	notifLog, existing, err := s.createLog(ctx, req, recipients, idempotencyKey, withCopies, content, escalation, followUp)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	// Enqueue the task for async processing; a digested type waits for the
+	// recipient's other events to be sent with them
+	if digests := s.digests(req.Type); digests != nil {
+		err = digests.EnqueueDigestEvent(DigestGroup(notifLog), notifLog.ID)
+	} else {
+		err = s.enqueueSend(req.Type, notifLog.ID)
+	}
+	if err != nil {
+		if errors.Is(err, ErrQueueUnavailable) && s.sendsDirect(req.Type) && s.digests(req.Type) == nil {
+			return s.sendDirect(ctx, notifLog, err)
+		}
+		// Update log status to failed since we couldn't enqueue
+		_ = s.store.UpdateStatus(ctx, notifLog.ID, StatusFailed, "", "failed to enqueue: "+err.Error())
+		return nil, fmt.Errorf("enqueuing notification: %w", err)
+	}
+
+	slog.Info("notification enqueued",
+		"id", notifLog.ID,
+		"channel", req.Channel,
+		"type", req.Type,
+		"to", notifLog.Recipient,
+		"recipients", len(recipients),
+	)
+
+	return &SendResponse{
+		ID:             notifLog.ID,
+		IdempotencyKey: notifLog.IdempotencyKey,
+		Channel:        string(req.Channel),
+		Status:         string(StatusQueued),
+	}, nil
+}
+
+// critical returns the CriticalEnqueuer when notifType is one of the
+// critical types and the Enqueuer implements it, or nil.
+func (s *Service) critical(notifType NotificationType) CriticalEnqueuer {
+	critical, ok := s.enqueuer.(CriticalEnqueuer)
+	if !ok || !slices.Contains(s.config.CriticalTypes, notifType) {
+		return nil
+	}
+	return critical
+}
+
+// enqueueSend enqueues a send task for a newly accepted log of notifType.
+func (s *Service) enqueueSend(notifType NotificationType, logID string) error {
+	if critical := s.critical(notifType); critical != nil {
+		return critical.EnqueueCriticalSend(logID)
+	}
+	return s.enqueuer.EnqueueSendNotification(logID)
+}
+
+// enqueueSendBatch enqueues a batch task for newly accepted logs of notifType.
+func (s *Service) enqueueSendBatch(batcher BatchEnqueuer, notifType NotificationType, logIDs []string) error {
+	if critical := s.critical(notifType); critical != nil {
+		return critical.EnqueueCriticalBatch(logIDs)
+	}
+	return batcher.EnqueueSendBatch(logIDs)
+}
+
+// createLog runs the per-log checks (idempotency, bounce suppression, rate
+// limit) and persists a queued log without enqueuing it. When the idempotency
+// key already exists it returns the existing result instead of a new log.
+func (s *Service) createLog(ctx context.Context, req *SendRequest, recipients Recipients, idempotencyKey string, withCopies bool, content variantContent, escalation *Escalation, followUp *FollowUpSequence) (*NotificationLog, *SendResponse, error) {
+	payloadHash := req.PayloadHash()
+
+	// Check idempotency — if a request with the same key already exists, return the existing result
+	if idempotencyKey != "" {
+		existing, err := s.store.GetByIdempotencyKey(ctx, idempotencyKey)
+		if err != nil {
+			slog.Error("idempotency check failed", "key", idempotencyKey, "error", err)
+			// Don't fail the request — proceed without idempotency protection
+		}
+		if existing != nil {
+			// Same key, different request: refuse rather than pretend it was sent.
+			// Logs created before payload hashes were stored have none and match anything.
+			if existing.PayloadHash != "" && existing.PayloadHash != payloadHash {
+				return nil, nil, common.NewConflictError(
+					fmt.Sprintf("idempotency key %q was already used for a different request (notification %s)", idempotencyKey, existing.ID),
+					existing.ID,
+				)
+			}
+			slog.Info("idempotent request — returning existing result",
+				"idempotency_key", idempotencyKey,
+				"existing_id", existing.ID,
+				"existing_status", existing.Status,
+			)
+			return nil, &SendResponse{
+				ID:             existing.ID,
+				IdempotencyKey: existing.IdempotencyKey,
+				Channel:        existing.Channel,
+				Status:         string(existing.Status),
+			}, nil
+		}
+	}
+
+	if err := s.admission.check(ctx, recipients, req.Channel, req.Type); err != nil {
+		return nil, nil, err
+	}
+
+	// Create the notification log, with the template variant its first
+	// recipient gets when the type is under A/B test
+	variant := s.variants.pick(req.Type, recipients[0])
+	notifLog := &NotificationLog{
+		IdempotencyKey: idempotencyKey,
+		PayloadHash:    payloadHash,
+		UserID:         req.UserID,
+		APIKeyID:       common.APIKeyID(ctx),
+		Channel:        string(req.Channel),
+		Type:           string(req.Type),
+		Variant:        variant.Name,
+		Recipient:      recipients[0],
+		ReplyTo:        req.ReplyTo,
+		Headers:        req.Headers,
+		Tags:           req.Tags,
+		TemplateData:   req.Data,
+		Content:        content[variant.Name],
+		Status:         StatusQueued,
+		Escalation:     escalation,
+	}
+	if len(recipients) > 1 {
+		notifLog.Recipients = recipients
+	}
+	if withCopies {
+		notifLog.CC = req.CC
+		notifLog.BCC = req.BCC
+	}
+	if req.Channel == ChannelEmail {
+		notifLog.Sender = s.senders.resolve(req.Type, req.Sender)
+	}
+	var fallbackAfter time.Duration
+	if req.FallbackTo != "" {
+		if rule, ok := s.fallbackRule(req); ok {
+			notifLog.Fallback = &Fallback{Channel: rule.Channel, To: req.FallbackTo, Until: rule.Until}
+			fallbackAfter = rule.After
+		}
+	}
+
+	if err := s.store.Create(ctx, notifLog); err != nil {
+		return nil, nil, fmt.Errorf("creating notification log: %w", err)
+	}
+	if notifLog.Fallback != nil {
+		s.scheduleFallback(notifLog.ID, fallbackAfter)
+	}
+	if escalation != nil {
+		s.escalations.start(notifLog.ID, escalation)
+	}
+	if followUp != nil {
+		s.followUps.start(notifLog.ID, followUp)
+	}
+	return notifLog, nil, nil
+}
+
+// GetNotification retrieves a notification log by ID.
+func (s *Service) GetNotification(ctx context.Context, id string) (*NotificationLog, error) {
+	notifLog, err := s.store.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("fetching notification: %w", err)
+	}
+	if notifLog == nil {
+		return nil, common.NewNotFoundError("notification", id)
+	}
+	return notifLog, nil
+}
+
+// retryFailedPage is how many failed logs RetryFailed requeues per store round trip.
+const retryFailedPage = 100
+
+// PreviewNotification returns a log's message: the content stored at enqueue
+// when there is one, otherwise the template rendered now from the log's
+// template data. A re-render reflects the current templates, so it can
+// differ from what was sent if they changed since.
+func (s *Service) PreviewNotification(ctx context.Context, id string) (*MessagePreview, error) {
+	notifLog, err := s.GetNotification(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	to := notifLog.Recipients
+	if len(to) == 0 {
+		to = []string{notifLog.Recipient}
+	}
+	preview := &MessagePreview{
+		ID:      notifLog.ID,
+		Channel: notifLog.Channel,
+		Type:    notifLog.Type,
+		To:      to,
+	}
+
+	if content := notifLog.Content; content != nil {
+		preview.Subject, preview.HTML, preview.Text, preview.Push = content.Subject, content.HTML, content.Text, content.Push
+		preview.Source = PreviewStored
+		return preview, nil
+	}
+
+	// Erasure clears the template data: a re-render would show an empty message
+	if notifLog.Recipient == ErasedRecipient {
+		return nil, common.NewConflictError(fmt.Sprintf("notification %s was erased; its content is gone", id), id)
+	}
+	if s.renderer == nil {
+		return nil, common.NewUnavailableError("template rendering is not available")
+	}
+
+	notifType := NotificationType(notifLog.Type)
+	content, err := renderVariant(s.renderer, s.variants.lookup(notifType, notifLog.Variant), Channel(notifLog.Channel), notifType, notifLog.TemplateData)
+	if err != nil {
+		return nil, fmt.Errorf("rendering template %s: %w", notifLog.Type, err)
+	}
+	preview.Subject, preview.HTML, preview.Text, preview.Push = content.Subject, content.HTML, content.Text, content.Push
+	preview.Source = PreviewRendered
+	return preview, nil
+}
+
+// RetryFailed resets failed logs matching req to queued and enqueues them
+// again, oldest first, up to req.Limit (default 1000). Logs are requeued a page
+// at a time; each page leaves the failed status, so the next listing returns
+// the following ones. Enqueues use batch tasks of BatchSize per channel when
+// the enqueuer supports them.
+func (s *Service) RetryFailed(ctx context.Context, req *RetryFailedRequest) (*RetryFailedResponse, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 1000
+	}
+
+	resp := &RetryFailedResponse{}
+	for resp.Requeued < limit {
+		logs, err := s.store.ListFailed(ctx, req.FailedFilter, min(retryFailedPage, limit-resp.Requeued))
+		if err != nil {
+			return nil, fmt.Errorf("listing failed notifications: %w", err)
+		}
+		if len(logs) == 0 {
+			break
+		}
+
+		ids := make([]string, len(logs))
+		for i, notifLog := range logs {
+			ids[i] = notifLog.ID
+		}
+		if err := s.store.Requeue(ctx, ids); err != nil {
+			return nil, fmt.Errorf("requeuing failed notifications: %w", err)
+		}
+		resp.Requeued += len(logs)
+
+		enqueued := s.enqueueRequeued(logs)
+		resp.Enqueued += enqueued
+		resp.Failures += len(logs) - enqueued
+	}
+
+	slog.Info("failed notifications requeued",
+		"requeued", resp.Requeued,
+		"enqueued", resp.Enqueued,
+		"failures", resp.Failures,
+	)
+	return resp, nil
+}
+
+// enqueueRequeued enqueues tasks for requeued logs and returns how many were
+// enqueued. Batch tasks never mix channels, since a batch is sent through one provider.
+func (s *Service) enqueueRequeued(logs []*NotificationLog) int {
+	batcher, batching := s.enqueuer.(BatchEnqueuer)
+	if !batching || s.config.BatchSize <= 1 {
+		enqueued := 0
+		for _, notifLog := range logs {
+			if err := s.enqueuer.EnqueueSendNotification(notifLog.ID); err != nil {
+				slog.Error("failed to enqueue requeued notification", "log_id", notifLog.ID, "error", err)
+				continue
+			}
+			enqueued++
+		}
+		return enqueued
+	}
+
+	byChannel := make(map[string][]string)
+	for _, notifLog := range logs {
+		byChannel[notifLog.Channel] = append(byChannel[notifLog.Channel], notifLog.ID)
+	}
+
+	enqueued := 0
+	for channel, ids := range byChannel {
+		for batch := range slices.Chunk(ids, s.config.BatchSize) {
+			if err := batcher.EnqueueSendBatch(batch); err != nil {
+				slog.Error("failed to enqueue requeued batch", "channel", channel, "count", len(batch), "error", err)
+				continue
+			}
+			enqueued += len(batch)
+		}
+	}
+	return enqueued
+}
+
+// ListNotifications retrieves notification logs with pagination and filtering.
+func (s *Service) ListNotifications(ctx context.Context, filter ListFilter) (*ListResponse, error) {
+	logs, total, err := s.store.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("listing notifications: %w", err)
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 20
+	}
+
+	resp := &ListResponse{
+		Notifications: logs,
+		Page:          filter.Page,
+		PageSize:      filter.PageSize,
+	}
+	if filter.Count != CountNone {
+		resp.Total = &total
+		resp.TotalEstimated = filter.Count == CountPlanned || filter.Count == CountEstimated
+	}
+	return resp, nil
+}
+
+// QueryStatuses returns the current status of each notification named by ID
+// or idempotency key, so callers of a batch send can poll it in one request.
+func (s *Service) QueryStatuses(ctx context.Context, query *StatusQuery) (*StatusQueryResponse, error) {
+	ids := distinct(query.IDs, strings.TrimSpace)
+	keys := distinct(query.IdempotencyKeys, strings.TrimSpace)
+	if len(ids)+len(keys) == 0 {
+		return nil, common.NewValidationError("ids or idempotency_keys is required")
+	}
+	if len(ids)+len(keys) > MaxStatusQuery {
+		return nil, common.NewValidationError(fmt.Sprintf("too many notifications: %d (max %d)", len(ids)+len(keys), MaxStatusQuery))
+	}
+
+	logs, err := s.store.GetStatuses(ctx, ids, keys)
+	if err != nil {
+		return nil, fmt.Errorf("querying notification statuses: %w", err)
+	}
+	byID := make(map[string]*NotificationLog, len(logs))
+	byKey := make(map[string]*NotificationLog, len(logs))
+	for _, l := range logs {
+		byID[l.ID] = l
+		if l.IdempotencyKey != "" {
+			byKey[l.IdempotencyKey] = l
+		}
+	}
+
+	resp := &StatusQueryResponse{
+		Notifications: make([]StatusResult, 0, len(logs)),
+		NotFound:      []string{},
+	}
+	seen := make(map[string]bool, len(logs))
+	add := func(requested string, l *NotificationLog) {
+		switch {
+		case l == nil:
+			resp.NotFound = append(resp.NotFound, requested)
+		case !seen[l.ID]:
+			seen[l.ID] = true
+			resp.Notifications = append(resp.Notifications, StatusResult{
+				ID:             l.ID,
+				IdempotencyKey: l.IdempotencyKey,
+				Channel:        l.Channel,
+				Status:         l.Status,
+				ErrorMessage:   l.ErrorMessage,
+				FailureCode:    l.FailureCode,
+				UpdatedAt:      l.UpdatedAt,
+			})
+		}
+	}
+	for _, id := range ids {
+		add(id, byID[id])
+	}
+	for _, key := range keys {
+		add(key, byKey[key])
+	}
+	return resp, nil
+}
+
+// distinct returns values normalized by norm, without empty or repeated ones.
+func distinct(values []string, norm func(string) string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		v = norm(v)
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	return out
+}
+
+// Stats counts notification logs by status, including those the reaper
+// abandoned, and failed logs by failure code, and reports the open and click
+// rates of the template variants under A/B test and the daily cost totals of
+// the last costStatsDays days.
+func (s *Service) Stats(ctx context.Context) (*StatsResponse, error) {
+	counts, err := s.store.CountByStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("counting notifications: %w", err)
+	}
+
+	byCode, err := s.store.CountByFailureCode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("counting failures: %w", err)
+	}
+
+	resp := &StatsResponse{ByStatus: counts, Abandoned: counts[StatusAbandoned], ByFailureCode: byCode}
+	for _, n := range counts {
+		resp.Total += n
+	}
+
+	for _, notifType := range s.variants.types() {
+		for _, variant := range s.variants.all(notifType) {
+			stats, err := s.store.CountVariant(ctx, notifType, variant.Name)
+			if err != nil {
+				return nil, fmt.Errorf("counting %s variant %s: %w", notifType, variant.Name, err)
+			}
+			stats.Type, stats.Variant = notifType, variant.Name
+			stats.setRates()
+			resp.Variants = append(resp.Variants, *stats)
+		}
+	}
+
+	if s.latency != nil {
+		// Latency is monitoring: without it the counts are still worth reporting
+		histograms, err := s.latency.LatencyHistograms(ctx)
+		if err != nil {
+			slog.Warn("failed to read latency", "error", err)
+		} else {
+			resp.Latency = summarizeLatency(histograms)
+		}
+	}
+
+	if s.costs != nil {
+		since := time.Now().UTC().AddDate(0, 0, 1-costStatsDays)
+		costs, err := s.costs.CostTotals(ctx, since)
+		if err != nil {
+			slog.Warn("failed to read costs", "error", err)
+		} else {
+			resp.Costs = costs
+		}
+	}
+	return resp, nil
+}
+
+// LatencyHistograms returns the delivery latency histograms, or none when
+// latency tracking is off.
+func (s *Service) LatencyHistograms(ctx context.Context) ([]*LatencyHistogram, error) {
+	if s.latency == nil {
+		return nil, nil
+	}
+	return s.latency.LatencyHistograms(ctx)
+}
+
+// HandleWebhookEvent processes a delivery status update from a provider webhook.
+// bounceType classifies a bounce: a hard bounce is final and suppresses the
+// recipient, a soft one is retried. An unclassified bounce counts as hard.
+func (s *Service) HandleWebhookEvent(ctx context.Context, providerID string, status NotificationStatus, bounceType BounceType) error {
+	if providerID == "" {
+		return common.NewValidationError("provider_id is required")
+	}
+	if status != StatusBounced {
+		bounceType = ""
+	} else if bounceType == "" {
+		bounceType = BounceHard
+	}
+
+	logs, err := s.store.UpdateWebhookStatus(ctx, providerID, status, bounceType)
+	if err != nil {
+		return fmt.Errorf("updating webhook status: %w", err)
+	}
+	s.observeWebhookLatency(ctx, status, logs)
+	if status == StatusBounced {
+		for _, notifLog := range logs {
+			if bounceType == BounceSoft && s.scheduleBounceRetry(notifLog) {
+				continue
+			}
+			recordOutcome(ctx, s.outcomes, notifLog, OutcomeBounced)
+		}
+	}
+
+	slog.Info("webhook status updated",
+		"provider_id", providerID,
+		"status", status,
+		"bounce_type", bounceType,
+	)
+
+	return nil
+}
+
+// observeWebhookLatency records the time from the send to the delivery or
+// open the webhook reported.
+func (s *Service) observeWebhookLatency(ctx context.Context, status NotificationStatus, logs []*NotificationLog) {
+	for _, notifLog := range logs {
+		if notifLog.SentAt == nil {
+			continue
+		}
+		switch status {
+		case StatusDelivered:
+			if notifLog.DeliveredAt != nil {
+				observeLatency(ctx, s.latency, LatencySentToDelivered, notifLog, notifLog.DeliveredAt.Sub(*notifLog.SentAt))
+			}
+		case StatusOpened:
+			if notifLog.OpenedAt != nil {
+				observeLatency(ctx, s.latency, LatencySentToOpened, notifLog, notifLog.OpenedAt.Sub(*notifLog.SentAt))
+			}
+		}
+	}
+}
+
+// RecordClick resolves a click-tracking token, marks the notification as clicked,
+// and returns the original URL to redirect to. A failure to record the click is
+// logged but does not prevent the redirect.
+func (s *Service) RecordClick(ctx context.Context, token string) (string, error) {
+	if s.tracker == nil {
+		return "", common.NewNotFoundError("tracking link", token)
+	}
+
+	logID, target, err := s.tracker.Resolve(token)
+	if err != nil {
+		return "", common.NewValidationError(err.Error())
+	}
+
+	if err := s.store.UpdateStatus(ctx, logID, StatusClicked, "", ""); err != nil {
+		slog.Error("failed to record click", "log_id", logID, "error", err)
+	} else {
+		slog.Info("notification link clicked", "log_id", logID)
+	}
+
+	return target, nil
+}
@@ -0,0 +1,111 @@
+package notification
+
+import (
+	"context"
+	"time"
+)
+
+// NotificationStore defines the contract for persisting notification records.
+// Implementations live in internal/infra/store/ (e.g., Supabase); a new one
+// can check itself against the package storetest.
+type NotificationStore interface {
+	// Create inserts a new notification log record.
+	Create(ctx context.Context, log *NotificationLog) error
+
+	// GetByID retrieves a notification log by its ID.
+	GetByID(ctx context.Context, id string) (*NotificationLog, error)
+
+	// GetByIdempotencyKey retrieves a notification log by its idempotency key.
+	// Returns nil, nil if no record is found.
+	GetByIdempotencyKey(ctx context.Context, key string) (*NotificationLog, error)
+
+	// UpdateStatus updates the status of a notification log. A move to
+	// processing is ignored unless the log is queued, processing, or failed.
+	UpdateStatus(ctx context.Context, id string, status NotificationStatus, providerID string, errMsg string) error
+
+	// RecordSent marks a log sent with the provider's message ID, its
+	// estimated cost (nil when no price applies), and the provider's response
+	// metadata (nil when it reported none). It is ignored unless the log is
+	// queued or processing.
+	RecordSent(ctx context.Context, id string, providerID string, cost *float64, metadata *ProviderMetadata) error
+
+	// RecordFailure marks a log failed with errMsg and code (empty when the
+	// failure has no code), records whether the failure is retryable
+	// (transient) or permanent, and stores the provider's response metadata
+	// (nil when there was no provider response). It is ignored once the log
+	// is sent, so a late failure never overwrites a delivery.
+	RecordFailure(ctx context.Context, id string, errMsg string, code FailureCode, retryable bool, metadata *ProviderMetadata) error
+
+	// UpdateWebhookStatus updates the status of a notification based on provider ID (for webhook events)
+	// and returns the updated logs. bounceType is stored with a bounce and is
+	// empty otherwise.
+	UpdateWebhookStatus(ctx context.Context, providerID string, status NotificationStatus, bounceType BounceType) ([]*NotificationLog, error)
+
+	// Acknowledge records that a notification was acknowledged, unless it
+	// already was.
+	Acknowledge(ctx context.Context, id string, at time.Time) error
+
+	// GetStatuses retrieves the logs with any of the given IDs or idempotency
+	// keys. Only the fields of a StatusResult need to be filled in.
+	GetStatuses(ctx context.Context, ids, keys []string) ([]*NotificationLog, error)
+
+	// List retrieves notification logs with pagination and filtering, and
+	// the total matching count as filter.Count asks (-1 for CountNone).
+	List(ctx context.Context, filter ListFilter) ([]*NotificationLog, int, error)
+
+	// HasBounced reports whether any notification to recipient has
+	// hard-bounced or drawn a spam complaint. Used for bounce suppression.
+	HasBounced(ctx context.Context, recipient string) (bool, error)
+
+	// RecordRecovery resets a stale log to queued and stores its new recovery attempt count.
+	RecordRecovery(ctx context.Context, id string, attempts int) error
+
+	// RecordDirectSend records that a log is being sent directly, bypassing
+	// the queue.
+	RecordDirectSend(ctx context.Context, id string) error
+
+	// RecordBounceRetry resets a soft-bounced log to queued for another send
+	// and stores its new bounce retry count.
+	RecordBounceRetry(ctx context.Context, id string, retries int) error
+
+	// ListFailed retrieves up to limit failed logs matching filter, oldest first.
+	ListFailed(ctx context.Context, filter FailedFilter, limit int) ([]*NotificationLog, error)
+
+	// Requeue resets the given logs to queued and clears their error, ahead of
+	// enqueuing them again.
+	Requeue(ctx context.Context, ids []string) error
+
+	// CountByStatus returns the number of logs in each status.
+	CountByStatus(ctx context.Context) (map[NotificationStatus]int, error)
+
+	// CountByFailureCode returns the number of failed logs with each failure code.
+	CountByFailureCode(ctx context.Context) (map[FailureCode]int, error)
+
+	// CountVariant returns how many logs of notifType with the given
+	// template variant were sent, and of those how many were opened or
+	// clicked, and clicked.
+	CountVariant(ctx context.Context, notifType NotificationType, variant string) (*VariantStats, error)
+
+	// CountCreatedByType returns the number of logs of each type created in
+	// [since, until).
+	CountCreatedByType(ctx context.Context, since, until time.Time) (map[NotificationType]int, error)
+
+	// CountFailuresByCode returns the number of logs created in [since,
+	// until) that are failed, per failure code.
+	CountFailuresByCode(ctx context.Context, since, until time.Time) (map[FailureCode]int, error)
+
+	// ListStale retrieves notification logs stuck in queued/processing for longer
+	// than the given threshold. Used by the reaper for reconciliation.
+	ListStale(ctx context.Context, olderThan time.Time, limit int) ([]*NotificationLog, error)
+}
+
+// ReaperStatsStore defines the contract for sharing reaper sweep totals between
+// the worker replicas that sweep and the API that reports them.
+// Implementations live in internal/infra/metrics/.
+type ReaperStatsStore interface {
+	// RecordSweep adds a completed sweep to the totals and stores it as the latest.
+	RecordSweep(ctx context.Context, result *SweepResult) error
+
+	// ReaperStats returns the totals and the latest sweep.
+	ReaperStats(ctx context.Context) (*ReaperStats, error)
+}
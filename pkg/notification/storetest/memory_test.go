@@ -0,0 +1,395 @@
+package storetest_test
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+	"github.com/badrkarrachai/notifly/pkg/notification/storetest"
+)
+
+// TestMemoryStore runs the suite against memoryStore, so it is compiled and
+// exercised without a database.
+func TestMemoryStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) notification.NotificationStore {
+		return newMemoryStore()
+	})
+}
+
+var _ notification.NotificationStore = (*memoryStore)(nil)
+
+// memoryStore is a NotificationStore in a map, following the Supabase store's
+// rules: the same unique idempotency keys, filters, orders, and status guards.
+type memoryStore struct {
+	mu     sync.Mutex
+	logs   map[string]*notification.NotificationLog
+	nextID int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{logs: make(map[string]*notification.NotificationLog)}
+}
+
+var errNotFound = errors.New("notification log not found")
+
+// Statuses a log can still move to processing or failed from, and to sent from.
+var (
+	sendable = []notification.NotificationStatus{notification.StatusQueued, notification.StatusProcessing, notification.StatusFailed}
+	unsent   = []notification.NotificationStatus{notification.StatusQueued, notification.StatusProcessing}
+)
+
+// clone copies a log so callers cannot change the stored one.
+func clone(log *notification.NotificationLog) *notification.NotificationLog {
+	c := *log
+	return &c
+}
+
+// update applies fn to log id, when its status is one of from (any if none).
+func (m *memoryStore) update(id string, fn func(log *notification.NotificationLog, now time.Time), from ...notification.NotificationStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	log, ok := m.logs[id]
+	if !ok || (len(from) > 0 && !slices.Contains(from, log.Status)) {
+		return
+	}
+	now := time.Now().UTC()
+	fn(log, now)
+	log.UpdatedAt = now
+}
+
+// filter returns copies of the logs match accepts.
+func (m *memoryStore) filter(match func(log *notification.NotificationLog) bool) []*notification.NotificationLog {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var logs []*notification.NotificationLog
+	for _, log := range m.logs {
+		if match(log) {
+			logs = append(logs, clone(log))
+		}
+	}
+	return logs
+}
+
+func (m *memoryStore) Create(_ context.Context, log *notification.NotificationLog) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if log.IdempotencyKey != "" {
+		for _, existing := range m.logs {
+			if existing.IdempotencyKey == log.IdempotencyKey {
+				return fmt.Errorf("idempotency key %q already used", log.IdempotencyKey)
+			}
+		}
+	}
+	m.nextID++
+	now := time.Now().UTC()
+	log.ID = fmt.Sprintf("00000000-0000-4000-8000-%012d", m.nextID)
+	log.CreatedAt, log.UpdatedAt = now, now
+	m.logs[log.ID] = clone(log)
+	return nil
+}
+
+func (m *memoryStore) GetByID(_ context.Context, id string) (*notification.NotificationLog, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	log, ok := m.logs[id]
+	if !ok {
+		return nil, errNotFound
+	}
+	return clone(log), nil
+}
+
+func (m *memoryStore) GetByIdempotencyKey(_ context.Context, key string) (*notification.NotificationLog, error) {
+	logs := m.filter(func(log *notification.NotificationLog) bool { return log.IdempotencyKey == key })
+	if len(logs) == 0 {
+		return nil, nil
+	}
+	return logs[0], nil
+}
+
+func (m *memoryStore) UpdateStatus(_ context.Context, id string, status notification.NotificationStatus, providerID string, errMsg string) error {
+	var from []notification.NotificationStatus
+	if status == notification.StatusProcessing {
+		from = sendable
+	}
+	m.update(id, func(log *notification.NotificationLog, now time.Time) {
+		log.Status = status
+		if providerID != "" {
+			log.ProviderID = providerID
+		}
+		if errMsg != "" {
+			log.ErrorMessage = errMsg
+		}
+		switch status {
+		case notification.StatusSent:
+			log.SentAt = &now
+		case notification.StatusClicked:
+			log.ClickedAt = &now
+		}
+	}, from...)
+	return nil
+}
+
+func (m *memoryStore) RecordSent(_ context.Context, id string, providerID string, cost *float64, metadata *notification.ProviderMetadata) error {
+	m.update(id, func(log *notification.NotificationLog, now time.Time) {
+		log.Status = notification.StatusSent
+		log.SentAt = &now
+		if providerID != "" {
+			log.ProviderID = providerID
+		}
+		if cost != nil {
+			log.Cost = cost
+		}
+		if metadata != nil {
+			log.ProviderMetadata = metadata
+		}
+	}, unsent...)
+	return nil
+}
+
+func (m *memoryStore) RecordFailure(_ context.Context, id string, errMsg string, code notification.FailureCode, retryable bool, metadata *notification.ProviderMetadata) error {
+	m.update(id, func(log *notification.NotificationLog, _ time.Time) {
+		log.Status = notification.StatusFailed
+		log.ErrorMessage = errMsg
+		log.FailureCode = code
+		log.Retryable = &retryable
+		if metadata != nil {
+			log.ProviderMetadata = metadata
+		}
+	}, sendable...)
+	return nil
+}
+
+func (m *memoryStore) UpdateWebhookStatus(_ context.Context, providerID string, status notification.NotificationStatus, bounceType notification.BounceType) ([]*notification.NotificationLog, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var updated []*notification.NotificationLog
+	now := time.Now().UTC()
+	for _, log := range m.logs {
+		if providerID == "" || log.ProviderID != providerID {
+			continue
+		}
+		log.Status = status
+		log.UpdatedAt = now
+		switch status {
+		case notification.StatusDelivered:
+			log.DeliveredAt = &now
+		case notification.StatusBounced:
+			log.BouncedAt = &now
+			if bounceType != "" {
+				log.BounceType = bounceType
+			}
+		case notification.StatusComplained:
+			log.ComplainedAt = &now
+		case notification.StatusOpened:
+			log.OpenedAt = &now
+		case notification.StatusClicked:
+			log.ClickedAt = &now
+		}
+		updated = append(updated, clone(log))
+	}
+	return updated, nil
+}
+
+func (m *memoryStore) Acknowledge(_ context.Context, id string, at time.Time) error {
+	m.update(id, func(log *notification.NotificationLog, _ time.Time) {
+		if log.AcknowledgedAt == nil {
+			at := at.UTC()
+			log.AcknowledgedAt = &at
+		}
+	})
+	return nil
+}
+
+func (m *memoryStore) GetStatuses(_ context.Context, ids, keys []string) ([]*notification.NotificationLog, error) {
+	return m.filter(func(log *notification.NotificationLog) bool {
+		return slices.Contains(ids, log.ID) || (log.IdempotencyKey != "" && slices.Contains(keys, log.IdempotencyKey))
+	}), nil
+}
+
+func (m *memoryStore) List(_ context.Context, filter notification.ListFilter) ([]*notification.NotificationLog, int, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 20
+	}
+
+	logs := m.filter(func(log *notification.NotificationLog) bool {
+		for _, f := range []struct{ want, got string }{
+			{filter.Status, string(log.Status)},
+			{filter.Recipient, log.Recipient},
+			{filter.Channel, log.Channel},
+			{filter.CampaignID, log.CampaignID},
+			{filter.ParentID, log.ParentID},
+			{filter.EscalationOf, log.EscalationOf},
+			{filter.FollowUpOf, log.FollowUpOf},
+			{filter.FailureCode, string(log.FailureCode)},
+		} {
+			if f.want != "" && f.want != f.got {
+				return false
+			}
+		}
+		switch filter.Archived {
+		case notification.ArchivedInclude:
+			return true
+		case notification.ArchivedOnly:
+			return log.ArchivedAt != nil
+		default:
+			return log.ArchivedAt == nil
+		}
+	})
+	slices.SortFunc(logs, func(a, b *notification.NotificationLog) int { return b.CreatedAt.Compare(a.CreatedAt) })
+
+	total := len(logs)
+	if filter.Count == notification.CountNone {
+		total = -1
+	}
+	offset := min((filter.Page-1)*filter.PageSize, len(logs))
+	return logs[offset:min(offset+filter.PageSize, len(logs))], total, nil
+}
+
+func (m *memoryStore) HasBounced(_ context.Context, recipient string) (bool, error) {
+	logs := m.filter(func(log *notification.NotificationLog) bool {
+		return log.Recipient == recipient && (log.Status == notification.StatusComplained ||
+			(log.Status == notification.StatusBounced && log.BounceType != notification.BounceSoft))
+	})
+	return len(logs) > 0, nil
+}
+
+func (m *memoryStore) RecordRecovery(_ context.Context, id string, attempts int) error {
+	m.update(id, func(log *notification.NotificationLog, _ time.Time) {
+		log.Status = notification.StatusQueued
+		log.RecoveryAttempts = attempts
+	})
+	return nil
+}
+
+func (m *memoryStore) RecordDirectSend(_ context.Context, id string) error {
+	m.update(id, func(log *notification.NotificationLog, _ time.Time) { log.DirectSend = true })
+	return nil
+}
+
+func (m *memoryStore) RecordBounceRetry(_ context.Context, id string, retries int) error {
+	m.update(id, func(log *notification.NotificationLog, _ time.Time) {
+		log.Status = notification.StatusQueued
+		log.BounceRetries = retries
+		log.BounceType = ""
+		log.BouncedAt = nil
+		log.ProviderID = ""
+	})
+	return nil
+}
+
+func (m *memoryStore) ListFailed(_ context.Context, filter notification.FailedFilter, limit int) ([]*notification.NotificationLog, error) {
+	logs := m.filter(func(log *notification.NotificationLog) bool {
+		return log.Status == notification.StatusFailed && log.ArchivedAt == nil &&
+			(filter.Type == "" || log.Type == filter.Type) &&
+			(filter.Channel == "" || log.Channel == filter.Channel) &&
+			(filter.CreatedAfter == nil || !log.CreatedAt.Before(*filter.CreatedAfter)) &&
+			(filter.CreatedBefore == nil || log.CreatedAt.Before(*filter.CreatedBefore)) &&
+			strings.Contains(strings.ToLower(log.ErrorMessage), strings.ToLower(filter.ErrorContains))
+	})
+	slices.SortFunc(logs, func(a, b *notification.NotificationLog) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return logs[:min(limit, len(logs))], nil
+}
+
+func (m *memoryStore) Requeue(_ context.Context, ids []string) error {
+	for _, id := range ids {
+		m.update(id, func(log *notification.NotificationLog, _ time.Time) {
+			log.Status = notification.StatusQueued
+			log.ErrorMessage = ""
+			log.FailureCode = ""
+			log.Retryable = nil
+		})
+	}
+	return nil
+}
+
+func (m *memoryStore) CountByStatus(_ context.Context) (map[notification.NotificationStatus]int, error) {
+	counts := make(map[notification.NotificationStatus]int)
+	for _, log := range m.filter(func(*notification.NotificationLog) bool { return true }) {
+		counts[log.Status]++
+	}
+	return counts, nil
+}
+
+func (m *memoryStore) CountByFailureCode(_ context.Context) (map[notification.FailureCode]int, error) {
+	counts := make(map[notification.FailureCode]int)
+	for _, log := range m.filter(func(log *notification.NotificationLog) bool {
+		return log.Status == notification.StatusFailed && log.FailureCode != ""
+	}) {
+		counts[log.FailureCode]++
+	}
+	return counts, nil
+}
+
+func (m *memoryStore) CountVariant(_ context.Context, notifType notification.NotificationType, variant string) (*notification.VariantStats, error) {
+	stats := &notification.VariantStats{Type: notifType, Variant: variant}
+	for _, log := range m.filter(func(log *notification.NotificationLog) bool {
+		return log.Type == string(notifType) && log.Variant == variant
+	}) {
+		if log.SentAt != nil {
+			stats.Sent++
+		}
+		if log.OpenedAt != nil || log.ClickedAt != nil {
+			stats.Opened++
+		}
+		if log.ClickedAt != nil {
+			stats.Clicked++
+		}
+	}
+	return stats, nil
+}
+
+// created reports whether log was created in [since, until).
+func created(log *notification.NotificationLog, since, until time.Time) bool {
+	return !log.CreatedAt.Before(since) && log.CreatedAt.Before(until)
+}
+
+func (m *memoryStore) CountCreatedByType(_ context.Context, since, until time.Time) (map[notification.NotificationType]int, error) {
+	counts := make(map[notification.NotificationType]int)
+	for _, notifType := range notification.Types() {
+		counts[notifType] = 0
+	}
+	for _, log := range m.filter(func(log *notification.NotificationLog) bool { return created(log, since, until) }) {
+		if _, ok := counts[notification.NotificationType(log.Type)]; ok {
+			counts[notification.NotificationType(log.Type)]++
+		}
+	}
+	return counts, nil
+}
+
+func (m *memoryStore) CountFailuresByCode(_ context.Context, since, until time.Time) (map[notification.FailureCode]int, error) {
+	counts := make(map[notification.FailureCode]int)
+	for _, code := range notification.FailureCodes() {
+		counts[code] = 0
+	}
+	for _, log := range m.filter(func(log *notification.NotificationLog) bool {
+		return log.Status == notification.StatusFailed && created(log, since, until)
+	}) {
+		if _, ok := counts[log.FailureCode]; ok {
+			counts[log.FailureCode]++
+		}
+	}
+	return counts, nil
+}
+
+func (m *memoryStore) ListStale(_ context.Context, olderThan time.Time, limit int) ([]*notification.NotificationLog, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	logs := m.filter(func(log *notification.NotificationLog) bool {
+		return slices.Contains(unsent, log.Status) && log.UpdatedAt.Before(olderThan) && !log.IsParent()
+	})
+	slices.SortFunc(logs, func(a, b *notification.NotificationLog) int {
+		return cmp.Or(a.UpdatedAt.Compare(b.UpdatedAt), strings.Compare(a.ID, b.ID))
+	})
+	return logs[:min(limit, len(logs))], nil
+}
@@ -0,0 +1,721 @@
+// Package storetest is a conformance suite for notification.NotificationStore
+// implementations, so a new store backend behaves as the service, worker, and
+// reaper expect of the Supabase one. A backend runs it from its own test:
+//
+//	func TestNotificationStore(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) notification.NotificationStore {
+//			return newStoreOnTestDatabase(t)
+//		})
+//	}
+//
+// The store must be backed by a database used only for tests. It need not be
+// empty: every check writes logs with unique recipients, keys, and provider
+// IDs, and reads back only those.
+package storetest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+)
+
+// Factory returns the store under test. It is called once per check.
+type Factory func(t *testing.T) notification.NotificationStore
+
+// staleLimit bounds ListStale in the stale checks; it must exceed the stale
+// logs other runs may have left in the test database.
+const staleLimit = 1000
+
+// Run runs every check against stores from newStore, each as a subtest.
+func Run(t *testing.T, newStore Factory) {
+	checks := []struct {
+		name  string
+		check func(t *testing.T, s notification.NotificationStore)
+	}{
+		{"Create", testCreate},
+		{"GetByIDUnknown", testGetByIDUnknown},
+		{"IdempotencyKeyUnique", testIdempotencyKeyUnique},
+		{"GetStatuses", testGetStatuses},
+		{"RecordSent", testRecordSent},
+		{"RecordFailure", testRecordFailure},
+		{"IllegalTransitions", testIllegalTransitions},
+		{"WebhookStatus", testWebhookStatus},
+		{"HasBounced", testHasBounced},
+		{"Acknowledge", testAcknowledge},
+		{"RequeueAndRecovery", testRequeueAndRecovery},
+		{"BounceRetry", testBounceRetry},
+		{"ListStaleBoundaries", testListStaleBoundaries},
+		{"ListPagination", testListPagination},
+		{"ListFilters", testListFilters},
+	}
+	for _, c := range checks {
+		t.Run(c.name, func(t *testing.T) {
+			c.check(t, newStore(t))
+		})
+	}
+}
+
+// testCreate checks that Create fills in the ID and timestamps, and that the
+// log reads back as written.
+func testCreate(t *testing.T, s notification.NotificationStore) {
+	log := newLog()
+	log.Tags = map[string]string{"suite": "storetest"}
+	log.TemplateData = map[string]any{"MagicLinkURL": "https://example.com/magic"}
+	create(t, s, log)
+
+	if log.ID == "" {
+		t.Fatal("Create did not set the ID")
+	}
+	if log.CreatedAt.IsZero() || log.UpdatedAt.IsZero() {
+		t.Errorf("Create did not set CreatedAt and UpdatedAt: %v, %v", log.CreatedAt, log.UpdatedAt)
+	}
+
+	got := get(t, s, log.ID)
+	if got.Recipient != log.Recipient || got.Channel != log.Channel || got.Type != log.Type {
+		t.Errorf("read back recipient %q, channel %q, type %q; want %q, %q, %q",
+			got.Recipient, got.Channel, got.Type, log.Recipient, log.Channel, log.Type)
+	}
+	if got.Status != notification.StatusQueued {
+		t.Errorf("status = %q, want %q", got.Status, notification.StatusQueued)
+	}
+	if got.Tags["suite"] != "storetest" {
+		t.Errorf("tags = %v, want suite=storetest", got.Tags)
+	}
+	if got.TemplateData["MagicLinkURL"] != "https://example.com/magic" {
+		t.Errorf("template data = %v, want MagicLinkURL", got.TemplateData)
+	}
+
+	other := newLog()
+	create(t, s, other)
+	if other.ID == log.ID {
+		t.Errorf("two logs got the same ID %q", log.ID)
+	}
+}
+
+// testGetByIDUnknown checks that an unknown ID is an error, not an empty log.
+func testGetByIDUnknown(t *testing.T, s notification.NotificationStore) {
+	got, err := s.GetByID(context.Background(), randomUUID())
+	if err == nil {
+		t.Fatalf("GetByID(unknown) = %+v, want an error", got)
+	}
+}
+
+// testIdempotencyKeyUnique checks that a second log with an idempotency key
+// is rejected and the key keeps finding the first.
+func testIdempotencyKeyUnique(t *testing.T, s notification.NotificationStore) {
+	ctx := context.Background()
+	key := "storetest-" + randomHex(8)
+
+	if got, err := s.GetByIdempotencyKey(ctx, key); err != nil || got != nil {
+		t.Fatalf("GetByIdempotencyKey(unused) = %v, %v; want nil, nil", got, err)
+	}
+
+	first := newLog()
+	first.IdempotencyKey = key
+	create(t, s, first)
+
+	second := newLog()
+	second.IdempotencyKey = key
+	if err := s.Create(ctx, second); err == nil {
+		t.Fatalf("Create with a used idempotency key succeeded (ID %q), want an error", second.ID)
+	}
+
+	got, err := s.GetByIdempotencyKey(ctx, key)
+	if err != nil {
+		t.Fatalf("GetByIdempotencyKey: %v", err)
+	}
+	if got == nil || got.ID != first.ID {
+		t.Fatalf("GetByIdempotencyKey = %v, want log %s", got, first.ID)
+	}
+
+	// Logs without a key do not collide with each other
+	create(t, s, newLog())
+	create(t, s, newLog())
+}
+
+// testGetStatuses checks lookups by ID and by idempotency key in one call.
+func testGetStatuses(t *testing.T, s notification.NotificationStore) {
+	ctx := context.Background()
+	byID := newLog()
+	create(t, s, byID)
+	byKey := newLog()
+	byKey.IdempotencyKey = "storetest-" + randomHex(8)
+	create(t, s, byKey)
+	create(t, s, newLog()) // not asked for
+
+	logs, err := s.GetStatuses(ctx, []string{byID.ID, randomUUID()}, []string{byKey.IdempotencyKey})
+	if err != nil {
+		t.Fatalf("GetStatuses: %v", err)
+	}
+	if want := []string{byID.ID, byKey.ID}; !sameIDs(logs, want) {
+		t.Errorf("GetStatuses returned %v, want %v", ids(logs), want)
+	}
+	for _, log := range logs {
+		if log.Status != notification.StatusQueued {
+			t.Errorf("log %s status = %q, want %q", log.ID, log.Status, notification.StatusQueued)
+		}
+	}
+
+	if logs, err := s.GetStatuses(ctx, nil, nil); err != nil || len(logs) != 0 {
+		t.Errorf("GetStatuses(nothing) = %v, %v; want no logs", ids(logs), err)
+	}
+}
+
+// testRecordSent checks that a sent log gets its provider ID, sent time,
+// cost, and metadata, and a later UpdatedAt.
+func testRecordSent(t *testing.T, s notification.NotificationStore) {
+	ctx := context.Background()
+	log := newLog()
+	create(t, s, log)
+	processing(t, s, log.ID)
+
+	providerID := "storetest-" + randomHex(8)
+	cost := 0.0004
+	metadata := &notification.ProviderMetadata{StatusCode: 200, Attempts: 2}
+	if err := s.RecordSent(ctx, log.ID, providerID, &cost, metadata); err != nil {
+		t.Fatalf("RecordSent: %v", err)
+	}
+
+	got := get(t, s, log.ID)
+	if got.Status != notification.StatusSent {
+		t.Errorf("status = %q, want %q", got.Status, notification.StatusSent)
+	}
+	if got.ProviderID != providerID {
+		t.Errorf("provider ID = %q, want %q", got.ProviderID, providerID)
+	}
+	if got.SentAt == nil {
+		t.Error("SentAt not set")
+	}
+	if got.Cost == nil || *got.Cost != cost {
+		t.Errorf("cost = %v, want %v", got.Cost, cost)
+	}
+	if got.ProviderMetadata == nil || got.ProviderMetadata.StatusCode != 200 || got.ProviderMetadata.Attempts != 2 {
+		t.Errorf("provider metadata = %+v, want status 200 after 2 attempts", got.ProviderMetadata)
+	}
+	if got.UpdatedAt.Before(log.UpdatedAt) {
+		t.Errorf("UpdatedAt went back from %v to %v", log.UpdatedAt, got.UpdatedAt)
+	}
+}
+
+// testRecordFailure checks the failure fields, and that a permanent failure
+// is recorded as not retryable.
+func testRecordFailure(t *testing.T, s notification.NotificationStore) {
+	ctx := context.Background()
+	log := newLog()
+	create(t, s, log)
+	processing(t, s, log.ID)
+
+	if err := s.RecordFailure(ctx, log.ID, "provider returned 422", notification.FailureProvider4xx, false,
+		&notification.ProviderMetadata{StatusCode: 422, ErrorCode: "validation_error"}); err != nil {
+		t.Fatalf("RecordFailure: %v", err)
+	}
+
+	got := get(t, s, log.ID)
+	if got.Status != notification.StatusFailed {
+		t.Errorf("status = %q, want %q", got.Status, notification.StatusFailed)
+	}
+	if got.ErrorMessage != "provider returned 422" {
+		t.Errorf("error message = %q", got.ErrorMessage)
+	}
+	if got.FailureCode != notification.FailureProvider4xx {
+		t.Errorf("failure code = %q, want %q", got.FailureCode, notification.FailureProvider4xx)
+	}
+	if got.Retryable == nil || *got.Retryable {
+		t.Errorf("retryable = %v, want false", got.Retryable)
+	}
+	if got.ProviderMetadata == nil || got.ProviderMetadata.ErrorCode != "validation_error" {
+		t.Errorf("provider metadata = %+v, want error code validation_error", got.ProviderMetadata)
+	}
+
+	// A failure without a code clears the previous one
+	if err := s.RecordFailure(ctx, log.ID, "retried and failed", "", true, nil); err != nil {
+		t.Fatalf("RecordFailure: %v", err)
+	}
+	got = get(t, s, log.ID)
+	if got.FailureCode != "" {
+		t.Errorf("failure code = %q after a failure without one, want none", got.FailureCode)
+	}
+	if got.Retryable == nil || !*got.Retryable {
+		t.Errorf("retryable = %v, want true", got.Retryable)
+	}
+}
+
+// testIllegalTransitions checks that writes which would move a log back from
+// a settled status are ignored or rejected: a sent log is never failed or
+// picked up again, and a permanently failed one is never recorded sent.
+func testIllegalTransitions(t *testing.T, s notification.NotificationStore) {
+	ctx := context.Background()
+
+	delivered := sent(t, s)
+	if _, err := s.UpdateWebhookStatus(ctx, delivered.ProviderID, notification.StatusDelivered, ""); err != nil {
+		t.Fatalf("UpdateWebhookStatus: %v", err)
+	}
+	failed := newLog()
+	create(t, s, failed)
+	processing(t, s, failed.ID)
+	if err := s.RecordFailure(ctx, failed.ID, "provider returned 422", notification.FailureProvider4xx, false, nil); err != nil {
+		t.Fatalf("RecordFailure: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		log    *notification.NotificationLog
+		write  func(id string) error
+		status notification.NotificationStatus
+	}{
+		{"sent to failed", sent(t, s), func(id string) error {
+			return s.RecordFailure(ctx, id, "late failure", notification.FailureTimeout, false, nil)
+		}, notification.StatusSent},
+		{"sent to processing", sent(t, s), func(id string) error {
+			return s.UpdateStatus(ctx, id, notification.StatusProcessing, "", "")
+		}, notification.StatusSent},
+		{"sent to sent", sent(t, s), func(id string) error {
+			return s.RecordSent(ctx, id, "storetest-"+randomHex(8), nil, nil)
+		}, notification.StatusSent},
+		{"delivered to failed", delivered, func(id string) error {
+			return s.RecordFailure(ctx, id, "late failure", notification.FailureTimeout, true, nil)
+		}, notification.StatusDelivered},
+		{"failed to sent", failed, func(id string) error {
+			return s.RecordSent(ctx, id, "storetest-"+randomHex(8), nil, nil)
+		}, notification.StatusFailed},
+	}
+	for _, c := range cases {
+		before := get(t, s, c.log.ID)
+		_ = c.write(c.log.ID) // rejecting the write is as good as ignoring it
+
+		got := get(t, s, c.log.ID)
+		if got.Status != c.status {
+			t.Errorf("%s: status = %q, want it left %q", c.name, got.Status, c.status)
+		}
+		if got.ProviderID != before.ProviderID || got.ErrorMessage != before.ErrorMessage || got.FailureCode != before.FailureCode {
+			t.Errorf("%s: provider ID %q, error %q, code %q changed from %q, %q, %q", c.name,
+				got.ProviderID, got.ErrorMessage, got.FailureCode, before.ProviderID, before.ErrorMessage, before.FailureCode)
+		}
+	}
+}
+
+// testWebhookStatus checks that webhook updates find every log with the
+// provider ID and set the status and its timestamp.
+func testWebhookStatus(t *testing.T, s notification.NotificationStore) {
+	ctx := context.Background()
+	log := sent(t, s)
+
+	steps := []struct {
+		status notification.NotificationStatus
+		at     func(*notification.NotificationLog) *time.Time
+	}{
+		{notification.StatusDelivered, func(l *notification.NotificationLog) *time.Time { return l.DeliveredAt }},
+		{notification.StatusOpened, func(l *notification.NotificationLog) *time.Time { return l.OpenedAt }},
+		{notification.StatusClicked, func(l *notification.NotificationLog) *time.Time { return l.ClickedAt }},
+		{notification.StatusComplained, func(l *notification.NotificationLog) *time.Time { return l.ComplainedAt }},
+	}
+	for _, step := range steps {
+		updated, err := s.UpdateWebhookStatus(ctx, log.ProviderID, step.status, "")
+		if err != nil {
+			t.Fatalf("UpdateWebhookStatus(%s): %v", step.status, err)
+		}
+		if len(updated) != 1 || updated[0].ID != log.ID || updated[0].Status != step.status {
+			t.Fatalf("UpdateWebhookStatus(%s) returned %v, want log %s in that status", step.status, ids(updated), log.ID)
+		}
+		got := get(t, s, log.ID)
+		if got.Status != step.status {
+			t.Errorf("status = %q, want %q", got.Status, step.status)
+		}
+		if step.at(got) == nil {
+			t.Errorf("no timestamp set for %s", step.status)
+		}
+	}
+
+	updated, err := s.UpdateWebhookStatus(ctx, "storetest-unknown-"+randomHex(8), notification.StatusDelivered, "")
+	if err != nil || len(updated) != 0 {
+		t.Errorf("UpdateWebhookStatus(unknown provider ID) = %v, %v; want no logs", ids(updated), err)
+	}
+}
+
+// testHasBounced checks that only hard bounces and complaints suppress a
+// recipient.
+func testHasBounced(t *testing.T, s notification.NotificationStore) {
+	ctx := context.Background()
+	log := sent(t, s)
+
+	bounced := func() bool {
+		t.Helper()
+		ok, err := s.HasBounced(ctx, log.Recipient)
+		if err != nil {
+			t.Fatalf("HasBounced: %v", err)
+		}
+		return ok
+	}
+
+	if bounced() {
+		t.Fatal("HasBounced = true before any bounce")
+	}
+	if _, err := s.UpdateWebhookStatus(ctx, log.ProviderID, notification.StatusBounced, notification.BounceSoft); err != nil {
+		t.Fatalf("UpdateWebhookStatus: %v", err)
+	}
+	if got := get(t, s, log.ID); got.BounceType != notification.BounceSoft || got.BouncedAt == nil {
+		t.Errorf("bounce type %q, bounced at %v; want a soft bounce", got.BounceType, got.BouncedAt)
+	}
+	if bounced() {
+		t.Error("HasBounced = true after a soft bounce")
+	}
+	if _, err := s.UpdateWebhookStatus(ctx, log.ProviderID, notification.StatusBounced, notification.BounceHard); err != nil {
+		t.Fatalf("UpdateWebhookStatus: %v", err)
+	}
+	if !bounced() {
+		t.Error("HasBounced = false after a hard bounce")
+	}
+
+	complained := sent(t, s)
+	if _, err := s.UpdateWebhookStatus(ctx, complained.ProviderID, notification.StatusComplained, ""); err != nil {
+		t.Fatalf("UpdateWebhookStatus: %v", err)
+	}
+	if ok, err := s.HasBounced(ctx, complained.Recipient); err != nil || !ok {
+		t.Errorf("HasBounced after a complaint = %v, %v; want true", ok, err)
+	}
+}
+
+// testAcknowledge checks that the first acknowledgement is kept.
+func testAcknowledge(t *testing.T, s notification.NotificationStore) {
+	ctx := context.Background()
+	log := newLog()
+	create(t, s, log)
+
+	first := time.Now().UTC().Add(-time.Minute).Truncate(time.Millisecond)
+	if err := s.Acknowledge(ctx, log.ID, first); err != nil {
+		t.Fatalf("Acknowledge: %v", err)
+	}
+	if err := s.Acknowledge(ctx, log.ID, first.Add(30*time.Second)); err != nil {
+		t.Fatalf("Acknowledge again: %v", err)
+	}
+
+	got := get(t, s, log.ID)
+	if got.AcknowledgedAt == nil || !got.AcknowledgedAt.Equal(first) {
+		t.Errorf("acknowledged at %v, want the first acknowledgement %v", got.AcknowledgedAt, first)
+	}
+}
+
+// testRequeueAndRecovery checks the two ways a log goes back to queued.
+func testRequeueAndRecovery(t *testing.T, s notification.NotificationStore) {
+	ctx := context.Background()
+	failed := newLog()
+	create(t, s, failed)
+	if err := s.RecordFailure(ctx, failed.ID, "provider returned 503", notification.FailureProvider5xx, true, nil); err != nil {
+		t.Fatalf("RecordFailure: %v", err)
+	}
+
+	if err := s.Requeue(ctx, []string{failed.ID}); err != nil {
+		t.Fatalf("Requeue: %v", err)
+	}
+	got := get(t, s, failed.ID)
+	if got.Status != notification.StatusQueued {
+		t.Errorf("status after Requeue = %q, want %q", got.Status, notification.StatusQueued)
+	}
+	if got.ErrorMessage != "" || got.FailureCode != "" || got.Retryable != nil {
+		t.Errorf("Requeue kept error %q, code %q, retryable %v; want them cleared", got.ErrorMessage, got.FailureCode, got.Retryable)
+	}
+
+	stuck := newLog()
+	create(t, s, stuck)
+	processing(t, s, stuck.ID)
+	if err := s.RecordRecovery(ctx, stuck.ID, 2); err != nil {
+		t.Fatalf("RecordRecovery: %v", err)
+	}
+	got = get(t, s, stuck.ID)
+	if got.Status != notification.StatusQueued || got.RecoveryAttempts != 2 {
+		t.Errorf("after RecordRecovery status %q, attempts %d; want queued, 2", got.Status, got.RecoveryAttempts)
+	}
+
+	if err := s.RecordDirectSend(ctx, stuck.ID); err != nil {
+		t.Fatalf("RecordDirectSend: %v", err)
+	}
+	if got := get(t, s, stuck.ID); !got.DirectSend {
+		t.Error("DirectSend not set after RecordDirectSend")
+	}
+}
+
+// testBounceRetry checks that a soft-bounced log is reset for another send
+// without its bounce and provider ID.
+func testBounceRetry(t *testing.T, s notification.NotificationStore) {
+	ctx := context.Background()
+	log := sent(t, s)
+	if _, err := s.UpdateWebhookStatus(ctx, log.ProviderID, notification.StatusBounced, notification.BounceSoft); err != nil {
+		t.Fatalf("UpdateWebhookStatus: %v", err)
+	}
+
+	if err := s.RecordBounceRetry(ctx, log.ID, 1); err != nil {
+		t.Fatalf("RecordBounceRetry: %v", err)
+	}
+	got := get(t, s, log.ID)
+	if got.Status != notification.StatusQueued || got.BounceRetries != 1 {
+		t.Errorf("status %q, bounce retries %d; want queued, 1", got.Status, got.BounceRetries)
+	}
+	if got.BounceType != "" || got.BouncedAt != nil || got.ProviderID != "" {
+		t.Errorf("kept bounce type %q, bounced at %v, provider ID %q; want them cleared", got.BounceType, got.BouncedAt, got.ProviderID)
+	}
+
+	// The old provider ID no longer reaches the log
+	updated, err := s.UpdateWebhookStatus(ctx, log.ProviderID, notification.StatusDelivered, "")
+	if err != nil || len(updated) != 0 {
+		t.Errorf("UpdateWebhookStatus(old provider ID) = %v, %v; want no logs", ids(updated), err)
+	}
+}
+
+// testListStaleBoundaries checks that ListStale returns only queued and
+// processing logs last updated before the threshold, oldest first, never a
+// fan-out parent, and at most limit.
+func testListStaleBoundaries(t *testing.T, s notification.NotificationStore) {
+	ctx := context.Background()
+
+	queued := newLog()
+	create(t, s, queued)
+	inFlight := newLog()
+	create(t, s, inFlight)
+	processing(t, s, inFlight.ID)
+	settled := sent(t, s)
+
+	userID := "storetest-user-" + randomHex(4)
+	parent := newLog()
+	parent.Channel = string(notification.ChannelPush)
+	parent.Recipient = userID
+	parent.UserID = userID
+	create(t, s, parent)
+	child := newLog()
+	child.Channel = string(notification.ChannelPush)
+	child.UserID = userID
+	child.ParentID = parent.ID
+	create(t, s, child)
+
+	// Nothing just written is stale an hour ago
+	stale, err := s.ListStale(ctx, time.Now().Add(-time.Hour), staleLimit)
+	if err != nil {
+		t.Fatalf("ListStale: %v", err)
+	}
+	for _, id := range []string{queued.ID, inFlight.ID, child.ID} {
+		if slices.Contains(ids(stale), id) {
+			t.Errorf("ListStale(an hour ago) returned log %s, updated just now", id)
+		}
+	}
+
+	// Everything is stale an hour from now, but settled logs and parents
+	stale, err = s.ListStale(ctx, time.Now().Add(time.Hour), staleLimit)
+	if err != nil {
+		t.Fatalf("ListStale: %v", err)
+	}
+	got := ids(stale)
+	for _, id := range []string{queued.ID, inFlight.ID, child.ID} {
+		if !slices.Contains(got, id) {
+			t.Errorf("ListStale(an hour from now) left out unsettled log %s", id)
+		}
+	}
+	if slices.Contains(got, settled.ID) {
+		t.Errorf("ListStale returned sent log %s", settled.ID)
+	}
+	if slices.Contains(got, parent.ID) {
+		t.Errorf("ListStale returned fan-out parent %s", parent.ID)
+	}
+	for i := 1; i < len(stale); i++ {
+		if stale[i].UpdatedAt.Before(stale[i-1].UpdatedAt) {
+			t.Errorf("ListStale is not oldest first: %v before %v", stale[i-1].UpdatedAt, stale[i].UpdatedAt)
+			break
+		}
+	}
+
+	limited, err := s.ListStale(ctx, time.Now().Add(time.Hour), 1)
+	if err != nil {
+		t.Fatalf("ListStale: %v", err)
+	}
+	if len(limited) != 1 {
+		t.Errorf("ListStale(limit 1) returned %d logs", len(limited))
+	}
+}
+
+// testListPagination checks that pages of one filter are newest first, do not
+// overlap, cover every match, and report the total as filter.Count asks.
+func testListPagination(t *testing.T, s notification.NotificationStore) {
+	ctx := context.Background()
+	recipient := "storetest+" + randomHex(8) + "@example.com"
+	var created []string
+	for range 5 {
+		log := newLog()
+		log.Recipient = recipient
+		create(t, s, log)
+		created = append(created, log.ID)
+		time.Sleep(5 * time.Millisecond) // distinct created_at, for the order
+	}
+
+	var listed []*notification.NotificationLog
+	for page, want := range []int{2, 2, 1, 0} {
+		logs, total, err := s.List(ctx, notification.ListFilter{Recipient: recipient, Page: page + 1, PageSize: 2})
+		if err != nil {
+			t.Fatalf("List page %d: %v", page+1, err)
+		}
+		if len(logs) != want {
+			t.Errorf("page %d has %d logs, want %d", page+1, len(logs), want)
+		}
+		if total != len(created) {
+			t.Errorf("page %d total = %d, want %d", page+1, total, len(created))
+		}
+		listed = append(listed, logs...)
+	}
+	if !sameIDs(listed, created) {
+		t.Errorf("pages listed %v, want each of %v once", ids(listed), created)
+	}
+	for i := 1; i < len(listed); i++ {
+		if listed[i].CreatedAt.After(listed[i-1].CreatedAt) {
+			t.Errorf("List is not newest first: %v before %v", listed[i-1].CreatedAt, listed[i].CreatedAt)
+			break
+		}
+	}
+
+	logs, total, err := s.List(ctx, notification.ListFilter{Recipient: recipient, Count: notification.CountNone})
+	if err != nil {
+		t.Fatalf("List without a count: %v", err)
+	}
+	if total != -1 || len(logs) != len(created) {
+		t.Errorf("List without a count returned %d logs, total %d; want %d, -1", len(logs), total, len(created))
+	}
+
+	// Out-of-range page sizes fall back on the default
+	logs, _, err = s.List(ctx, notification.ListFilter{Recipient: recipient, PageSize: 1000})
+	if err != nil {
+		t.Fatalf("List with page size 1000: %v", err)
+	}
+	if len(logs) != len(created) {
+		t.Errorf("List with page size 1000 returned %d logs, want %d", len(logs), len(created))
+	}
+}
+
+// testListFilters checks the status, channel, failure code, and parent filters.
+func testListFilters(t *testing.T, s notification.NotificationStore) {
+	ctx := context.Background()
+	recipient := "storetest+" + randomHex(8) + "@example.com"
+
+	queued := newLog()
+	queued.Recipient = recipient
+	create(t, s, queued)
+
+	failed := newLog()
+	failed.Recipient = recipient
+	failed.Channel = string(notification.ChannelSMS)
+	create(t, s, failed)
+	if err := s.RecordFailure(ctx, failed.ID, "timed out", notification.FailureTimeout, true, nil); err != nil {
+		t.Fatalf("RecordFailure: %v", err)
+	}
+
+	child := newLog()
+	child.Recipient = recipient
+	child.Channel = string(notification.ChannelPush)
+	child.UserID = "storetest-user-" + randomHex(4)
+	child.ParentID = queued.ID
+	create(t, s, child)
+
+	cases := []struct {
+		name   string
+		filter notification.ListFilter
+		want   []string
+	}{
+		{"status", notification.ListFilter{Status: string(notification.StatusFailed)}, []string{failed.ID}},
+		{"channel", notification.ListFilter{Channel: string(notification.ChannelEmail)}, []string{queued.ID}},
+		{"failure code", notification.ListFilter{FailureCode: string(notification.FailureTimeout)}, []string{failed.ID}},
+		{"parent", notification.ListFilter{ParentID: queued.ID}, []string{child.ID}},
+		{"none", notification.ListFilter{}, []string{queued.ID, failed.ID, child.ID}},
+	}
+	for _, c := range cases {
+		c.filter.Recipient = recipient
+		logs, total, err := s.List(ctx, c.filter)
+		if err != nil {
+			t.Fatalf("List by %s: %v", c.name, err)
+		}
+		if !sameIDs(logs, c.want) || total != len(c.want) {
+			t.Errorf("List by %s = %v (total %d), want %v", c.name, ids(logs), total, c.want)
+		}
+	}
+}
+
+// newLog returns a queued email log to a recipient of its own.
+func newLog() *notification.NotificationLog {
+	return &notification.NotificationLog{
+		Channel:   string(notification.ChannelEmail),
+		Type:      string(notification.TypeMagicLink),
+		Recipient: "storetest+" + randomHex(8) + "@example.com",
+		Status:    notification.StatusQueued,
+	}
+}
+
+// create stores log, failing the test on error.
+func create(t *testing.T, s notification.NotificationStore, log *notification.NotificationLog) {
+	t.Helper()
+	if err := s.Create(context.Background(), log); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+}
+
+// get reads log id, failing the test on error.
+func get(t *testing.T, s notification.NotificationStore, id string) *notification.NotificationLog {
+	t.Helper()
+	log, err := s.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetByID(%s): %v", id, err)
+	}
+	return log
+}
+
+// processing moves log id to processing, as the worker does when it picks it up.
+func processing(t *testing.T, s notification.NotificationStore, id string) {
+	t.Helper()
+	if err := s.UpdateStatus(context.Background(), id, notification.StatusProcessing, "", ""); err != nil {
+		t.Fatalf("UpdateStatus(processing): %v", err)
+	}
+}
+
+// sent creates a log and records it sent with a provider ID of its own.
+func sent(t *testing.T, s notification.NotificationStore) *notification.NotificationLog {
+	t.Helper()
+	log := newLog()
+	create(t, s, log)
+	log.ProviderID = "storetest-" + randomHex(8)
+	if err := s.RecordSent(context.Background(), log.ID, log.ProviderID, nil, nil); err != nil {
+		t.Fatalf("RecordSent: %v", err)
+	}
+	return log
+}
+
+// ids returns the IDs of logs in order.
+func ids(logs []*notification.NotificationLog) []string {
+	out := make([]string, len(logs))
+	for i, log := range logs {
+		out[i] = log.ID
+	}
+	return out
+}
+
+// sameIDs reports whether logs are exactly the logs of want, in any order.
+func sameIDs(logs []*notification.NotificationLog, want []string) bool {
+	got := ids(logs)
+	slices.Sort(got)
+	want = slices.Clone(want)
+	slices.Sort(want)
+	return slices.Equal(got, want)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// randomUUID returns a random version 4 UUID, an ID no log has.
+func randomUUID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,279 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+
+	"github.com/hibiken/asynq"
+)
+
+// TaskTypeSendNotification is the asynq task type for sending notifications.
+const TaskTypeSendNotification = "notification:send"
+
+// SendNotificationPayload is the serialized payload for a send notification task.
+type SendNotificationPayload struct {
+	LogID string `json:"log_id"`
+}
+
+// NewSendNotificationTask creates a new asynq task for sending a notification.
+func NewSendNotificationTask(logID string) (*asynq.Task, error) {
+	payload, err := json.Marshal(SendNotificationPayload{LogID: logID})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling task payload: %w", err)
+	}
+	return asynq.NewTask(TaskTypeSendNotification, payload), nil
+}
+
+// TaskTypeSendBatch is the asynq task type for sending several logs in one
+// provider call.
+const TaskTypeSendBatch = "notification:send_batch"
+
+// SendBatchPayload is the serialized payload for a send batch task.
+type SendBatchPayload struct {
+	LogIDs []string `json:"log_ids"`
+}
+
+// NewSendBatchTask creates a new asynq task for sending a batch of notifications.
+func NewSendBatchTask(logIDs []string) (*asynq.Task, error) {
+	payload, err := json.Marshal(SendBatchPayload{LogIDs: logIDs})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling batch task payload: %w", err)
+	}
+	return asynq.NewTask(TaskTypeSendBatch, payload), nil
+}
+
+// ParseSendBatchPayload deserializes the batch task payload.
+func ParseSendBatchPayload(data []byte) (*SendBatchPayload, error) {
+	var p SendBatchPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("unmarshaling batch task payload: %w", err)
+	}
+	return &p, nil
+}
+
+// TaskError adapts a ProcessTask error for asynq: permanent failures are
+// wrapped with asynq.SkipRetry so they are archived instead of retried.
+func TaskError(err error) error {
+	if err != nil && common.IsPermanent(err) {
+		return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+	}
+	return err
+}
+
+// ParseSendNotificationPayload deserializes the task payload.
+func ParseSendNotificationPayload(data []byte) (*SendNotificationPayload, error) {
+	var p SendNotificationPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("unmarshaling task payload: %w", err)
+	}
+	return &p, nil
+}
+
+// TaskTypeEraseRecipient is the asynq task type for erasing a recipient's data.
+const TaskTypeEraseRecipient = "recipient:erase"
+
+// EraseRecipientPayload is the serialized payload for an erasure task.
+type EraseRecipientPayload struct {
+	JobID     string `json:"job_id"`
+	Recipient string `json:"recipient"`
+}
+
+// NewEraseRecipientTask creates a new asynq task for an erasure job.
+func NewEraseRecipientTask(jobID, recipient string) (*asynq.Task, error) {
+	payload, err := json.Marshal(EraseRecipientPayload{JobID: jobID, Recipient: recipient})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling erasure task payload: %w", err)
+	}
+	return asynq.NewTask(TaskTypeEraseRecipient, payload), nil
+}
+
+// ParseEraseRecipientPayload deserializes the erasure task payload.
+func ParseEraseRecipientPayload(data []byte) (*EraseRecipientPayload, error) {
+	var p EraseRecipientPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("unmarshaling erasure task payload: %w", err)
+	}
+	return &p, nil
+}
+
+// TaskTypeDispatchCampaign is the asynq task type for fanning out one batch
+// of a campaign.
+const TaskTypeDispatchCampaign = "campaign:dispatch"
+
+// DispatchCampaignPayload is the serialized payload for a campaign dispatch
+// task. The batch position is read from the campaign, not the payload.
+type DispatchCampaignPayload struct {
+	CampaignID string `json:"campaign_id"`
+}
+
+// NewDispatchCampaignTask creates a new asynq task for a campaign batch.
+func NewDispatchCampaignTask(campaignID string) (*asynq.Task, error) {
+	payload, err := json.Marshal(DispatchCampaignPayload{CampaignID: campaignID})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling campaign task payload: %w", err)
+	}
+	return asynq.NewTask(TaskTypeDispatchCampaign, payload), nil
+}
+
+// ParseDispatchCampaignPayload deserializes the campaign dispatch task payload.
+func ParseDispatchCampaignPayload(data []byte) (*DispatchCampaignPayload, error) {
+	var p DispatchCampaignPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("unmarshaling campaign task payload: %w", err)
+	}
+	return &p, nil
+}
+
+// TaskTypeCheckFallback is the asynq task type for a delayed cross-channel
+// fallback check.
+const TaskTypeCheckFallback = "notification:fallback"
+
+// CheckFallbackPayload is the serialized payload for a fallback check task.
+type CheckFallbackPayload struct {
+	LogID string `json:"log_id"`
+}
+
+// NewCheckFallbackTask creates a new asynq task for a fallback check.
+func NewCheckFallbackTask(logID string) (*asynq.Task, error) {
+	payload, err := json.Marshal(CheckFallbackPayload{LogID: logID})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling fallback task payload: %w", err)
+	}
+	return asynq.NewTask(TaskTypeCheckFallback, payload), nil
+}
+
+// ParseCheckFallbackPayload deserializes the fallback check task payload.
+func ParseCheckFallbackPayload(data []byte) (*CheckFallbackPayload, error) {
+	var p CheckFallbackPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("unmarshaling fallback task payload: %w", err)
+	}
+	return &p, nil
+}
+
+// TaskTypeEscalate is the asynq task type for a delayed escalation step.
+const TaskTypeEscalate = "notification:escalate"
+
+// EscalatePayload is the serialized payload for an escalation step task.
+type EscalatePayload struct {
+	LogID string `json:"log_id"`
+	Step  int    `json:"step"`
+}
+
+// NewEscalateTask creates a new asynq task for an escalation step.
+func NewEscalateTask(logID string, step int) (*asynq.Task, error) {
+	payload, err := json.Marshal(EscalatePayload{LogID: logID, Step: step})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling escalation task payload: %w", err)
+	}
+	return asynq.NewTask(TaskTypeEscalate, payload), nil
+}
+
+// ParseEscalatePayload deserializes the escalation step task payload.
+func ParseEscalatePayload(data []byte) (*EscalatePayload, error) {
+	var p EscalatePayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("unmarshaling escalation task payload: %w", err)
+	}
+	return &p, nil
+}
+
+// TaskTypeFollowUp is the asynq task type for a delayed follow-up check.
+const TaskTypeFollowUp = "notification:follow_up"
+
+// FollowUpPayload is the serialized payload for a follow-up check task.
+type FollowUpPayload struct {
+	LogID      string `json:"log_id"`
+	SequenceID string `json:"sequence_id"`
+	Step       int    `json:"step"`
+}
+
+// NewFollowUpTask creates a new asynq task for a follow-up check.
+func NewFollowUpTask(logID, sequenceID string, step int) (*asynq.Task, error) {
+	payload, err := json.Marshal(FollowUpPayload{LogID: logID, SequenceID: sequenceID, Step: step})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling follow-up task payload: %w", err)
+	}
+	return asynq.NewTask(TaskTypeFollowUp, payload), nil
+}
+
+// ParseFollowUpPayload deserializes the follow-up check task payload.
+func ParseFollowUpPayload(data []byte) (*FollowUpPayload, error) {
+	var p FollowUpPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("unmarshaling follow-up task payload: %w", err)
+	}
+	return &p, nil
+}
+
+// TaskTypeRetryBounce is the asynq task type for a delayed resend of a
+// soft-bounced notification.
+const TaskTypeRetryBounce = "notification:retry_bounce"
+
+// RetryBouncePayload is the serialized payload for a bounce retry task.
+type RetryBouncePayload struct {
+	LogID string `json:"log_id"`
+	Retry int    `json:"retry"`
+}
+
+// NewRetryBounceTask creates a new asynq task for a bounce retry.
+func NewRetryBounceTask(logID string, retry int) (*asynq.Task, error) {
+	payload, err := json.Marshal(RetryBouncePayload{LogID: logID, Retry: retry})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling bounce retry task payload: %w", err)
+	}
+	return asynq.NewTask(TaskTypeRetryBounce, payload), nil
+}
+
+// ParseRetryBouncePayload deserializes the bounce retry task payload.
+func ParseRetryBouncePayload(data []byte) (*RetryBouncePayload, error) {
+	var p RetryBouncePayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("unmarshaling bounce retry task payload: %w", err)
+	}
+	return &p, nil
+}
+
+// TaskTypeDigestEvent is the asynq task type for one event of a digested
+// notification type. Such tasks are grouped by DigestGroup and never run on
+// their own: asynq combines each group into a send digest task.
+const TaskTypeDigestEvent = "notification:digest_event"
+
+// NewDigestEventTask creates a new asynq task for one digested event.
+func NewDigestEventTask(logID string) (*asynq.Task, error) {
+	payload, err := json.Marshal(SendNotificationPayload{LogID: logID})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling digest event task payload: %w", err)
+	}
+	return asynq.NewTask(TaskTypeDigestEvent, payload), nil
+}
+
+// TaskTypeSendDigest is the asynq task type for sending the events of one
+// recipient's digest group as one message.
+const TaskTypeSendDigest = "notification:send_digest"
+
+// SendDigestPayload is the serialized payload for a send digest task.
+type SendDigestPayload struct {
+	LogIDs []string `json:"log_ids"`
+}
+
+// NewSendDigestTask creates a new asynq task for sending a digest of logIDs,
+// oldest first. opts apply to the task, e.g. its retries.
+func NewSendDigestTask(logIDs []string, opts ...asynq.Option) (*asynq.Task, error) {
+	payload, err := json.Marshal(SendDigestPayload{LogIDs: logIDs})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling digest task payload: %w", err)
+	}
+	return asynq.NewTask(TaskTypeSendDigest, payload, opts...), nil
+}
+
+// ParseSendDigestPayload deserializes the send digest task payload.
+func ParseSendDigestPayload(data []byte) (*SendDigestPayload, error) {
+	var p SendDigestPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("unmarshaling digest task payload: %w", err)
+	}
+	return &p, nil
+}
@@ -0,0 +1,190 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+)
+
+// UsageStore is an optional NotificationStore extension that counts an API
+// key's logs for usage reports. Without it, GET /api/v1/usage is not served.
+type UsageStore interface {
+	// CountUsage counts the logs apiKeyID sent that were accepted from from
+	// up to to: all of them, and those sent, delivered, and bounced.
+	CountUsage(ctx context.Context, apiKeyID string, from, to time.Time) (*UsageCounts, error)
+}
+
+// UsageCounts are the logs of one API key accepted over a period, by how far
+// they got.
+type UsageCounts struct {
+	Accepted  int `json:"accepted"`
+	Sent      int `json:"sent"`
+	Delivered int `json:"delivered"`
+	Bounced   int `json:"bounced"`
+}
+
+// UsageConfig configures usage reports.
+type UsageConfig struct {
+	// APIKeyIDs are the IDs of the configured API keys, the ones reported.
+	APIKeyIDs []string
+
+	// BillingDay is the day of the month (1–28) billing periods start on, at
+	// 00:00 UTC. 0 means 1.
+	BillingDay int
+
+	// Quotas caps the sends of an API key per billing period, by key ID;
+	// QuotaDefault applies to keys without their own. Keys with neither have
+	// no quota.
+	Quotas map[string]int
+}
+
+// QuotaDefault is the UsageConfig.Quotas entry for keys without their own.
+const QuotaDefault = "default"
+
+// UsageQuery is the query of GET /api/v1/usage.
+type UsageQuery struct {
+	// APIKeyID reports only that key; empty reports every configured key.
+	APIKeyID string `form:"api_key_id"`
+	// History is how many billing periods before the current one to report,
+	// up to 12.
+	History int `form:"history" binding:"omitempty,min=0,max=12"`
+}
+
+// UsagePeriod is an API key's usage over one billing period. Cost is left out
+// when no send in the period was costed, and Quota and QuotaUsed (sent over
+// quota) when the key has no quota.
+type UsagePeriod struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	UsageCounts
+	Cost      *float64 `json:"cost,omitempty"`
+	Quota     int      `json:"quota,omitempty"`
+	QuotaUsed float64  `json:"quota_used,omitempty"`
+}
+
+// KeyUsage is one API key's usage: the current billing period to date, and
+// the periods before it, most recent first.
+type KeyUsage struct {
+	APIKeyID string        `json:"api_key_id"`
+	Current  UsagePeriod   `json:"current"`
+	History  []UsagePeriod `json:"history,omitempty"`
+}
+
+// UsageResponse is the body of GET /api/v1/usage.
+type UsageResponse struct {
+	Keys []KeyUsage `json:"keys"`
+}
+
+// SetUsage replaces the usage report configuration. Safe for concurrent use.
+func (s *Service) SetUsage(cfg UsageConfig) {
+	s.usage.Store(&cfg)
+}
+
+// usageStore returns the store's usage counts, or nil when it cannot count them.
+func (s *Service) usageStore() UsageStore {
+	usage, _ := s.store.(UsageStore)
+	return usage
+}
+
+// Usage reports each configured API key's usage, or only query.APIKeyID's,
+// for the current billing period to date and query.History periods before it.
+func (s *Service) Usage(ctx context.Context, query UsageQuery) (*UsageResponse, error) {
+	store := s.usageStore()
+	if store == nil {
+		return nil, common.NewNotFoundError("usage", "")
+	}
+	cfg := s.usage.Load()
+	if cfg == nil {
+		cfg = &UsageConfig{}
+	}
+
+	keys := cfg.APIKeyIDs
+	if query.APIKeyID != "" {
+		if !slices.Contains(cfg.APIKeyIDs, query.APIKeyID) {
+			return nil, common.NewNotFoundError("api key", query.APIKeyID)
+		}
+		keys = []string{query.APIKeyID}
+	}
+
+	now := time.Now().UTC()
+	start := billingPeriodStart(now, cfg.BillingDay)
+	oldest := start.AddDate(0, -query.History, 0)
+
+	var costs []*CostTotal
+	if s.costs != nil {
+		var err error
+		if costs, err = s.costs.CostTotals(ctx, oldest); err != nil {
+			return nil, fmt.Errorf("reading costs: %w", err)
+		}
+	}
+
+	resp := &UsageResponse{Keys: make([]KeyUsage, 0, len(keys))}
+	for _, key := range keys {
+		usage := KeyUsage{APIKeyID: key}
+		for i := 0; i <= query.History; i++ {
+			from := start.AddDate(0, -i, 0)
+			to := from.AddDate(0, 1, 0)
+			if i == 0 {
+				to = now
+			}
+			period, err := s.usagePeriod(ctx, store, cfg, costs, key, from, to)
+			if err != nil {
+				return nil, err
+			}
+			if i == 0 {
+				usage.Current = *period
+			} else {
+				usage.History = append(usage.History, *period)
+			}
+		}
+		resp.Keys = append(resp.Keys, usage)
+	}
+	return resp, nil
+}
+
+// usagePeriod counts key's usage from from up to to, adding up its costs.
+func (s *Service) usagePeriod(ctx context.Context, store UsageStore, cfg *UsageConfig, costs []*CostTotal, key string, from, to time.Time) (*UsagePeriod, error) {
+	counts, err := store.CountUsage(ctx, key, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("counting usage of %s: %w", key, err)
+	}
+	period := &UsagePeriod{Start: from, End: to, UsageCounts: *counts}
+
+	first, last := from.Format(time.DateOnly), to.Add(-time.Nanosecond).Format(time.DateOnly)
+	for _, total := range costs {
+		if total.APIKeyID != key || total.Day < first || total.Day > last {
+			continue
+		}
+		if period.Cost == nil {
+			period.Cost = new(float64)
+		}
+		*period.Cost += total.Cost
+	}
+
+	quota, ok := cfg.Quotas[key]
+	if !ok {
+		quota = cfg.Quotas[QuotaDefault]
+	}
+	if quota > 0 {
+		period.Quota = quota
+		period.QuotaUsed = float64(period.Sent) / float64(quota)
+	}
+	return period, nil
+}
+
+// billingPeriodStart returns the start of the billing period t falls in:
+// 00:00 UTC on the latest billingDay of the month at or before t.
+func billingPeriodStart(t time.Time, billingDay int) time.Time {
+	if billingDay < 1 {
+		billingDay = 1
+	}
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), billingDay, 0, 0, 0, 0, time.UTC)
+	if start.After(t) {
+		start = start.AddDate(0, -1, 0)
+	}
+	return start
+}
@@ -0,0 +1,168 @@
+package notification
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// TemplateVariant is one arm of an A/B test of a notification type's
+// templates. It renders the type's "<template>.<Name>" templates where the
+// renderer has them (see VariantRenderer) and the type's own elsewhere, so a
+// variant can differ in its body, its subject, or both.
+type TemplateVariant struct {
+	Name    string // lowercase letters, digits, and underscores
+	Percent int    // share of recipients, 0–100
+	Subject string // replaces the type's subject; empty keeps it
+}
+
+// variantNameRe matches the names a variant can have: they become part of
+// template file names.
+var variantNameRe = regexp.MustCompile(`^[a-z0-9_]{1,64}$`)
+
+// IsValidVariantName reports whether name can name a template variant.
+func IsValidVariantName(name string) bool {
+	return variantNameRe.MatchString(name)
+}
+
+// Variants picks the template variant of each notification type under A/B
+// test. A recipient falls in a bucket from 0 to 99 picked by hashing the type
+// with the recipient, so they get the same variant on every send; variants
+// take consecutive ranges of buckets in order, and buckets past the last one
+// get no variant. A nil *Variants runs no tests.
+type Variants struct {
+	mu    sync.RWMutex
+	tests map[NotificationType][]TemplateVariant
+}
+
+// NewVariants creates the A/B tests, keyed by notification type. The percents
+// of a type's variants should add up to at most 100.
+func NewVariants(tests map[NotificationType][]TemplateVariant) *Variants {
+	return &Variants{tests: tests}
+}
+
+// SetTests replaces the A/B tests, e.g. after a config reload. Logs already
+// created keep the variant they were given.
+func (v *Variants) SetTests(tests map[NotificationType][]TemplateVariant) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.tests = tests
+}
+
+// pick returns the variant a notification of notifType to recipient gets, or
+// the zero variant when none.
+func (v *Variants) pick(notifType NotificationType, recipient string) TemplateVariant {
+	if v == nil {
+		return TemplateVariant{}
+	}
+	v.mu.RLock()
+	variants := v.tests[notifType]
+	v.mu.RUnlock()
+	if len(variants) == 0 {
+		return TemplateVariant{}
+	}
+
+	b := bucket("template:"+string(notifType), recipient)
+	upper := 0
+	for _, variant := range variants {
+		upper += variant.Percent
+		if b < upper {
+			return variant
+		}
+	}
+	return TemplateVariant{}
+}
+
+// lookup returns the named variant of notifType. A variant whose test has
+// ended is returned with only its name, so its logs still render its templates.
+func (v *Variants) lookup(notifType NotificationType, name string) TemplateVariant {
+	if v != nil && name != "" {
+		v.mu.RLock()
+		defer v.mu.RUnlock()
+		for _, variant := range v.tests[notifType] {
+			if variant.Name == name {
+				return variant
+			}
+		}
+	}
+	return TemplateVariant{Name: name}
+}
+
+// all returns every variant under test for notifType, or nil.
+func (v *Variants) all(notifType NotificationType) []TemplateVariant {
+	if v == nil {
+		return nil
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.tests[notifType]
+}
+
+// types returns the notification types under test, sorted.
+func (v *Variants) types() []NotificationType {
+	if v == nil {
+		return nil
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	types := make([]NotificationType, 0, len(v.tests))
+	for t, variants := range v.tests {
+		if len(variants) > 0 {
+			types = append(types, t)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// VariantRenderer is optionally implemented by a TemplateRenderer with
+// template variants. Without it, every variant renders the type's own
+// templates and can differ only in subject.
+type VariantRenderer interface {
+	// Variant returns a renderer that renders each type's templates of the
+	// named variant where there are some, and the type's own elsewhere.
+	Variant(name string) TemplateRenderer
+}
+
+// renderVariant renders a notification for channel with variant's templates
+// and subject; the zero variant renders the type's own. A subject in data
+// still wins over the variant's.
+func renderVariant(renderer TemplateRenderer, variant TemplateVariant, channel Channel, notifType NotificationType, data map[string]any) (*RenderedContent, error) {
+	if variant.Name != "" {
+		if vr, ok := renderer.(VariantRenderer); ok {
+			renderer = vr.Variant(variant.Name)
+		}
+	}
+	if subject, _ := data["Subject"].(string); variant.Subject != "" && subject == "" {
+		withSubject := make(map[string]any, len(data)+1)
+		for k, v := range data {
+			withSubject[k] = v
+		}
+		withSubject["Subject"] = variant.Subject
+		data = withSubject
+	}
+	return renderMessage(renderer, channel, notifType, data)
+}
+
+// VariantStats reports how one template variant performs: of its sent
+// notifications, how many were opened and clicked. A click counts as an
+// open too, since clicking implies the message was opened.
+type VariantStats struct {
+	Type      NotificationType `json:"type"`
+	Variant   string           `json:"variant"`
+	Sent      int              `json:"sent"`
+	Opened    int              `json:"opened"`
+	Clicked   int              `json:"clicked"`
+	OpenRate  float64          `json:"open_rate"`
+	ClickRate float64          `json:"click_rate"`
+}
+
+// setRates fills in the open and click rates from the counts.
+func (s *VariantStats) setRates() {
+	if s.Sent == 0 {
+		return
+	}
+	s.OpenRate = math.Round(float64(s.Opened)/float64(s.Sent)*10000) / 10000
+	s.ClickRate = math.Round(float64(s.Clicked)/float64(s.Sent)*10000) / 10000
+}
@@ -0,0 +1,272 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+)
+
+// Built-in webhook providers, each the path segment of its route.
+const (
+	WebhookProviderResend = "resend"
+	WebhookProviderSES    = "ses" // SES notifications delivered through SNS
+	WebhookProviderTwilio = "twilio"
+)
+
+// WebhookResult is the outcome of processing a stored webhook event.
+type WebhookResult string
+
+const (
+	WebhookReceived  WebhookResult = "received"  // stored, not processed yet
+	WebhookProcessed WebhookResult = "processed" // mapped to a status and applied
+	WebhookIgnored   WebhookResult = "ignored"   // event type we do not track
+	WebhookFailed    WebhookResult = "failed"    // applying the status failed
+)
+
+// WebhookEvent is an inbound provider webhook exactly as received, with the
+// outcome of processing it. Events are stored before the status is applied,
+// so ignored and failed ones can be inspected and replayed.
+type WebhookEvent struct {
+	ID          string             `json:"id"`
+	Provider    string             `json:"provider"`
+	EventID     string             `json:"event_id,omitempty"`
+	EventType   string             `json:"event_type"`
+	ProviderID  string             `json:"provider_id,omitempty"`
+	Status      NotificationStatus `json:"status,omitempty"`
+	BounceType  BounceType         `json:"bounce_type,omitempty"`
+	Payload     json.RawMessage    `json:"payload"`
+	Result      WebhookResult      `json:"result"`
+	Error       string             `json:"error,omitempty"`
+	ReceivedAt  time.Time          `json:"received_at"`
+	ProcessedAt *time.Time         `json:"processed_at,omitempty"`
+}
+
+// WebhookEventFilter selects stored webhook events. Empty fields match everything.
+type WebhookEventFilter struct {
+	Provider  string `form:"provider"`
+	EventType string `form:"event_type"`
+	Result    string `form:"result" binding:"omitempty,oneof=received processed ignored failed"`
+	Limit     int    `form:"limit"`
+}
+
+// WebhookEventStore is an optional NotificationStore extension that keeps raw
+// webhook events. Without it, webhooks are processed but not stored.
+type WebhookEventStore interface {
+	// CreateWebhookEvent inserts event, filling in its ID and ReceivedAt.
+	CreateWebhookEvent(ctx context.Context, event *WebhookEvent) error
+
+	// UpdateWebhookEvent stores event's result, error, and processing time.
+	UpdateWebhookEvent(ctx context.Context, event *WebhookEvent) error
+
+	// GetWebhookEvent retrieves an event by ID. Returns nil, nil if none exists.
+	GetWebhookEvent(ctx context.Context, id string) (*WebhookEvent, error)
+
+	// ListWebhookEvents returns matching events, newest first.
+	ListWebhookEvents(ctx context.Context, filter WebhookEventFilter) ([]*WebhookEvent, error)
+}
+
+const (
+	defaultWebhookEventLimit = 50
+	maxWebhookEventLimit     = 500
+)
+
+// maxWebhookBytes caps an inbound webhook body. SNS, the largest, delivers
+// messages of up to 256 KiB plus its envelope.
+const maxWebhookBytes = 300 << 10
+
+// WebhookRequest is an inbound webhook as an adapter sees it.
+type WebhookRequest struct {
+	URL    string // full public URL the provider called, for signature checks
+	Header http.Header
+	Body   []byte
+}
+
+// ParsedWebhook is what an adapter extracts from a webhook request.
+type ParsedWebhook struct {
+	EventID    string             // the provider's ID for this event, if it has one
+	EventType  string             // the provider's event type, e.g. email.delivered
+	ProviderID string             // the provider's message ID, matched against logs
+	Status     NotificationStatus // empty when the event type is not tracked
+	BounceType BounceType         // with StatusBounced; empty counts as hard
+	Payload    json.RawMessage    // the event to store; nil stores the request body
+}
+
+// WebhookAdapter turns one provider's webhooks into status updates. Adding a
+// provider is an adapter plus a WebhookRegistry registration.
+type WebhookAdapter interface {
+	// ParseEvent parses a webhook request. Adapters registered as signed must
+	// also authenticate it, returning a *common.UnauthorizedError on failure.
+	// Malformed requests return a *common.ValidationError.
+	ParseEvent(ctx context.Context, req *WebhookRequest) (*ParsedWebhook, error)
+}
+
+// WebhookRegistry maps provider path segments to adapters.
+type WebhookRegistry struct {
+	adapters map[string]WebhookAdapter
+	signed   map[string]bool
+}
+
+// NewWebhookRegistry creates an empty registry.
+func NewWebhookRegistry() *WebhookRegistry {
+	return &WebhookRegistry{
+		adapters: make(map[string]WebhookAdapter),
+		signed:   make(map[string]bool),
+	}
+}
+
+// Register serves adapter at POST /api/v1/webhooks/<provider>, behind API key
+// authentication.
+func (r *WebhookRegistry) Register(provider string, adapter WebhookAdapter) {
+	r.adapters[provider] = adapter
+	delete(r.signed, provider)
+}
+
+// RegisterSigned serves adapter at POST /api/v1/webhooks/<provider> without
+// API key authentication, for providers that cannot send one. The adapter
+// must verify the provider's signature in ParseEvent.
+func (r *WebhookRegistry) RegisterSigned(provider string, adapter WebhookAdapter) {
+	r.adapters[provider] = adapter
+	r.signed[provider] = true
+}
+
+// Adapter returns the adapter registered for provider.
+func (r *WebhookRegistry) Adapter(provider string) (WebhookAdapter, bool) {
+	adapter, ok := r.adapters[provider]
+	return adapter, ok
+}
+
+// Signed reports whether provider's adapter authenticates requests itself.
+func (r *WebhookRegistry) Signed(provider string) bool {
+	return r.signed[provider]
+}
+
+// Providers returns the registered providers in sorted order.
+func (r *WebhookRegistry) Providers() []string {
+	return slices.Sorted(maps.Keys(r.adapters))
+}
+
+// webhookEvents returns the store's webhook event storage, or nil when the
+// store does not keep raw events.
+func (s *Service) webhookEvents() WebhookEventStore {
+	events, _ := s.store.(WebhookEventStore)
+	return events
+}
+
+// ReceiveWebhook stores a parsed provider webhook and then applies it. A
+// failure to store the event is logged and does not stop the status update.
+func (s *Service) ReceiveWebhook(ctx context.Context, provider string, parsed *ParsedWebhook, body []byte) (*WebhookEvent, error) {
+	event := &WebhookEvent{
+		Provider:   provider,
+		EventID:    parsed.EventID,
+		EventType:  parsed.EventType,
+		ProviderID: parsed.ProviderID,
+		Status:     parsed.Status,
+		BounceType: parsed.BounceType,
+		Payload:    parsed.Payload,
+		Result:     WebhookReceived,
+	}
+	if event.Payload == nil {
+		event.Payload = body
+	}
+
+	events := s.webhookEvents()
+	stored := false
+	if events != nil {
+		if err := events.CreateWebhookEvent(ctx, event); err != nil {
+			slog.Error("failed to store webhook event",
+				"provider", provider,
+				"event_type", event.EventType,
+				"error", err,
+			)
+		} else {
+			stored = true
+		}
+	}
+
+	err := s.applyWebhook(ctx, event)
+	if stored {
+		if updateErr := events.UpdateWebhookEvent(context.WithoutCancel(ctx), event); updateErr != nil {
+			slog.Error("failed to record webhook result", "webhook_event_id", event.ID, "error", updateErr)
+		}
+	}
+	return event, err
+}
+
+// WebhookEvent returns a stored webhook event by ID.
+func (s *Service) WebhookEvent(ctx context.Context, id string) (*WebhookEvent, error) {
+	event, err := s.webhookEvents().GetWebhookEvent(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("fetching webhook event: %w", err)
+	}
+	if event == nil {
+		return nil, common.NewNotFoundError("webhook event", id)
+	}
+	return event, nil
+}
+
+// ListWebhookEvents returns stored webhook events, newest first.
+func (s *Service) ListWebhookEvents(ctx context.Context, filter WebhookEventFilter) ([]*WebhookEvent, error) {
+	if filter.Limit < 1 {
+		filter.Limit = defaultWebhookEventLimit
+	}
+	if filter.Limit > maxWebhookEventLimit {
+		filter.Limit = maxWebhookEventLimit
+	}
+
+	events, err := s.webhookEvents().ListWebhookEvents(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhook events: %w", err)
+	}
+	return events, nil
+}
+
+// ReplayWebhookEvent applies a stored webhook event again, e.g. one that failed
+// while the database was unavailable, and records the new result.
+func (s *Service) ReplayWebhookEvent(ctx context.Context, id string) (*WebhookEvent, error) {
+	event, err := s.WebhookEvent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	applyErr := s.applyWebhook(ctx, event)
+	if err := s.webhookEvents().UpdateWebhookEvent(context.WithoutCancel(ctx), event); err != nil {
+		return nil, fmt.Errorf("recording webhook result: %w", err)
+	}
+	if applyErr != nil {
+		return nil, applyErr
+	}
+
+	slog.Info("webhook event replayed", "webhook_event_id", id, "result", event.Result)
+	return event, nil
+}
+
+// applyWebhook applies event's status to the log it is about, setting event's
+// result, error, and processing time.
+func (s *Service) applyWebhook(ctx context.Context, event *WebhookEvent) error {
+	now := time.Now().UTC()
+	event.ProcessedAt = &now
+	event.Error = ""
+
+	if event.Status == "" {
+		// Acknowledge but ignore unhandled event types
+		slog.Info("ignoring webhook event", "provider", event.Provider, "type", event.EventType)
+		event.Result = WebhookIgnored
+		return nil
+	}
+
+	if err := s.HandleWebhookEvent(ctx, event.ProviderID, event.Status, event.BounceType); err != nil {
+		event.Result = WebhookFailed
+		event.Error = err.Error()
+		return err
+	}
+
+	event.Result = WebhookProcessed
+	return nil
+}
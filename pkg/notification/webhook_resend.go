@@ -0,0 +1,132 @@
+package notification
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+)
+
+// Headers of the Svix signature Resend signs its webhooks with.
+const (
+	svixIDHeader        = "svix-id"
+	svixTimestampHeader = "svix-timestamp"
+	svixSignatureHeader = "svix-signature"
+)
+
+// svixTolerance is how far a signed timestamp may be from now before the
+// request is refused as a replay.
+const svixTolerance = 5 * time.Minute
+
+// resendStatuses maps the Resend event types we track to notification statuses.
+var resendStatuses = map[string]NotificationStatus{
+	"email.delivered":  StatusDelivered,
+	"email.bounced":    StatusBounced,
+	"email.complained": StatusComplained,
+	"email.opened":     StatusOpened,
+	"email.clicked":    StatusClicked,
+}
+
+// ResendWebhookAdapter parses Resend webhooks. Without a signing secret it
+// does not authenticate requests, so register it with WebhookRegistry.Register
+// behind the API key; with one it checks their Svix signature and can be
+// registered with RegisterSigned.
+type ResendWebhookAdapter struct {
+	key []byte // the decoded signing secret; nil when unsigned
+}
+
+var _ WebhookAdapter = (*ResendWebhookAdapter)(nil)
+
+// NewResendWebhookAdapter creates an adapter checking signatures made with
+// the endpoint's signing secret (whsec_…), or none when signingSecret is empty.
+func NewResendWebhookAdapter(signingSecret string) (*ResendWebhookAdapter, error) {
+	if signingSecret == "" {
+		return &ResendWebhookAdapter{}, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(signingSecret, "whsec_"))
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("invalid Resend signing secret: must be whsec_ followed by base64")
+	}
+	return &ResendWebhookAdapter{key: key}, nil
+}
+
+// Signed reports whether the adapter checks signatures.
+func (a *ResendWebhookAdapter) Signed() bool {
+	return a.key != nil
+}
+
+// ParseEvent implements WebhookAdapter.
+func (a *ResendWebhookAdapter) ParseEvent(ctx context.Context, req *WebhookRequest) (*ParsedWebhook, error) {
+	if a.Signed() {
+		if err := a.verify(req, time.Now()); err != nil {
+			slog.Warn("rejected Resend webhook", "svix_id", req.Header.Get(svixIDHeader), "reason", err)
+			return nil, common.NewUnauthorizedError("Resend signature verification failed")
+		}
+	}
+
+	var payload struct {
+		Type string `json:"type"`
+		Data struct {
+			EmailID string `json:"email_id"`
+			Bounce  struct {
+				Type string `json:"type"` // Permanent, Transient, or Undetermined
+			} `json:"bounce"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(req.Body, &payload); err != nil {
+		return nil, common.NewValidationError("invalid webhook payload: " + err.Error())
+	}
+
+	parsed := &ParsedWebhook{
+		EventID:    req.Header.Get(svixIDHeader), // Resend delivers webhooks through Svix
+		EventType:  payload.Type,
+		ProviderID: payload.Data.EmailID,
+		Status:     resendStatuses[payload.Type],
+	}
+	if parsed.Status == StatusBounced {
+		parsed.BounceType = emailBounceType(payload.Data.Bounce.Type)
+	}
+	return parsed, nil
+}
+
+// verify checks the request's Svix signature: base64 HMAC-SHA256, keyed by
+// the signing secret, of "<svix-id>.<svix-timestamp>.<body>". The signature
+// header lists space-separated "v1,<signature>" entries, one per active
+// secret while secrets rotate; any match is accepted.
+func (a *ResendWebhookAdapter) verify(req *WebhookRequest, now time.Time) error {
+	id := req.Header.Get(svixIDHeader)
+	timestamp := req.Header.Get(svixTimestampHeader)
+	signatures := req.Header.Get(svixSignatureHeader)
+	if id == "" || timestamp == "" || signatures == "" {
+		return fmt.Errorf("missing svix headers")
+	}
+
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid svix-timestamp %q", timestamp)
+	}
+	if skew := now.Sub(time.Unix(sec, 0)).Abs(); skew > svixTolerance {
+		return fmt.Errorf("timestamp %s off", skew.Round(time.Second))
+	}
+
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(id + "." + timestamp + "."))
+	mac.Write(req.Body)
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	for _, entry := range strings.Fields(signatures) {
+		version, signature, ok := strings.Cut(entry, ",")
+		if ok && version == "v1" && hmac.Equal([]byte(signature), []byte(expected)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no matching signature")
+}
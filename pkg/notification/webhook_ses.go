@@ -0,0 +1,314 @@
+package notification
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+)
+
+// SNS message types.
+const (
+	snsNotification             = "Notification"
+	snsSubscriptionConfirmation = "SubscriptionConfirmation"
+)
+
+// snsHost matches the SNS endpoints that sign messages and serve their
+// certificates, in every partition (amazonaws.com and amazonaws.com.cn).
+var snsHost = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// snsMessage is the JSON envelope SNS posts to HTTP(S) subscribers.
+type snsMessage struct {
+	Type             string `json:"Type"`
+	MessageID        string `json:"MessageId"`
+	Token            string `json:"Token"`
+	TopicArn         string `json:"TopicArn"`
+	Subject          string `json:"Subject"`
+	Message          string `json:"Message"`
+	Timestamp        string `json:"Timestamp"`
+	SignatureVersion string `json:"SignatureVersion"`
+	Signature        string `json:"Signature"`
+	SigningCertURL   string `json:"SigningCertURL"`
+	SubscribeURL     string `json:"SubscribeURL"`
+}
+
+// sesNotification is the part of an SES notification we act on. Identity
+// notifications set notificationType; configuration set event publishing
+// sets eventType instead.
+type sesNotification struct {
+	NotificationType string `json:"notificationType"`
+	EventType        string `json:"eventType"`
+	Mail             struct {
+		MessageID string `json:"messageId"`
+	} `json:"mail"`
+	Bounce struct {
+		BounceType string `json:"bounceType"`
+	} `json:"bounce"`
+}
+
+// sesStatuses maps the SES notification types we track to notification
+// statuses.
+var sesStatuses = map[string]NotificationStatus{
+	"Bounce":    StatusBounced,
+	"Delivery":  StatusDelivered,
+	"Complaint": StatusComplained,
+	"Open":      StatusOpened,
+	"Click":     StatusClicked,
+}
+
+// SESWebhookAdapter parses SES notifications delivered by an SNS HTTPS
+// subscription. It checks SNS message signatures and confirms subscriptions;
+// signing certificates are fetched only from SNS hosts and cached by URL.
+// Register it with WebhookRegistry.RegisterSigned.
+type SESWebhookAdapter struct {
+	client    *http.Client
+	topicARNs []string
+
+	mu    sync.Mutex
+	certs map[string]*x509.Certificate
+}
+
+var _ WebhookAdapter = (*SESWebhookAdapter)(nil)
+
+// NewSESWebhookAdapter creates an adapter that accepts messages from the given
+// topics, or from any topic when topicARNs is empty.
+func NewSESWebhookAdapter(topicARNs []string) *SESWebhookAdapter {
+	return &SESWebhookAdapter{
+		client:    &http.Client{Timeout: 10 * time.Second},
+		topicARNs: topicARNs,
+		certs:     make(map[string]*x509.Certificate),
+	}
+}
+
+// ParseEvent implements WebhookAdapter. A subscription confirmation is
+// confirmed on the spot and reported as an untracked event; a notification is
+// stored as the SES message it carries.
+func (v *SESWebhookAdapter) ParseEvent(ctx context.Context, req *WebhookRequest) (*ParsedWebhook, error) {
+	// SNS posts JSON with a text/plain content type
+	var msg snsMessage
+	if err := json.Unmarshal(req.Body, &msg); err != nil {
+		return nil, common.NewValidationError("invalid SNS message: " + err.Error())
+	}
+	if err := v.verify(ctx, &msg); err != nil {
+		slog.Warn("rejected SNS message", "topic_arn", msg.TopicArn, "type", msg.Type, "error", err)
+		return nil, common.NewUnauthorizedError("SNS message verification failed")
+	}
+
+	switch msg.Type {
+	case snsSubscriptionConfirmation:
+		if err := v.confirmSubscription(ctx, &msg); err != nil {
+			return nil, err
+		}
+		slog.Info("SNS subscription confirmed", "topic_arn", msg.TopicArn)
+		return &ParsedWebhook{EventID: msg.MessageID, EventType: msg.Type}, nil
+
+	case snsNotification:
+		var notification sesNotification
+		if err := json.Unmarshal([]byte(msg.Message), &notification); err != nil {
+			return nil, common.NewValidationError("invalid SES notification: " + err.Error())
+		}
+		parsed := &ParsedWebhook{
+			EventID:    msg.MessageID,
+			EventType:  notification.NotificationType,
+			ProviderID: notification.Mail.MessageID,
+			Payload:    json.RawMessage(msg.Message),
+		}
+		if parsed.EventType == "" {
+			parsed.EventType = notification.EventType
+		}
+		parsed.Status = sesStatuses[parsed.EventType]
+		if parsed.Status == StatusBounced {
+			parsed.BounceType = emailBounceType(notification.Bounce.BounceType)
+		}
+		return parsed, nil
+
+	default:
+		return &ParsedWebhook{EventID: msg.MessageID, EventType: msg.Type}, nil
+	}
+}
+
+// verify checks that msg comes from an allowed topic and carries a valid
+// signature from an SNS signing certificate.
+func (v *SESWebhookAdapter) verify(ctx context.Context, msg *snsMessage) error {
+	if len(v.topicARNs) > 0 && !slices.Contains(v.topicARNs, msg.TopicArn) {
+		return fmt.Errorf("topic %q is not allowed", msg.TopicArn)
+	}
+
+	var hash crypto.Hash
+	switch msg.SignatureVersion {
+	case "1":
+		hash = crypto.SHA1
+	case "2":
+		hash = crypto.SHA256
+	default:
+		return fmt.Errorf("unsupported signature version %q", msg.SignatureVersion)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(msg.Signature)
+	if err != nil {
+		return fmt.Errorf("decoding signature: %w", err)
+	}
+
+	cert, err := v.certificate(ctx, msg.SigningCertURL)
+	if err != nil {
+		return err
+	}
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("signing certificate does not hold an RSA key")
+	}
+
+	signed := []byte(snsStringToSign(msg))
+	var digest []byte
+	if hash == crypto.SHA1 {
+		sum := sha1.Sum(signed)
+		digest = sum[:]
+	} else {
+		sum := sha256.Sum256(signed)
+		digest = sum[:]
+	}
+	if err := rsa.VerifyPKCS1v15(pub, hash, digest, signature); err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	return nil
+}
+
+// confirmSubscription completes the SNS subscription handshake by visiting the
+// message's SubscribeURL.
+func (v *SESWebhookAdapter) confirmSubscription(ctx context.Context, msg *snsMessage) error {
+	if err := checkSNSURL(msg.SubscribeURL); err != nil {
+		return fmt.Errorf("subscribe url: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, msg.SubscribeURL, nil)
+	if err != nil {
+		return fmt.Errorf("building subscribe request: %w", err)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("confirming subscription: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("confirming subscription: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// certificate returns the signing certificate at certURL, fetching it once.
+func (v *SESWebhookAdapter) certificate(ctx context.Context, certURL string) (*x509.Certificate, error) {
+	if err := checkSNSURL(certURL); err != nil {
+		return nil, fmt.Errorf("signing cert url: %w", err)
+	}
+	if !strings.HasSuffix(certURL, ".pem") {
+		return nil, errors.New("signing cert url must point to a .pem file")
+	}
+
+	v.mu.Lock()
+	cert, ok := v.certs[certURL]
+	v.mu.Unlock()
+	if ok {
+		return cert, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, certURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building cert request: %w", err)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching signing cert: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching signing cert: status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, fmt.Errorf("reading signing cert: %w", err)
+	}
+
+	block, _ := pem.Decode(body)
+	if block == nil {
+		return nil, errors.New("signing cert is not PEM encoded")
+	}
+	cert, err = x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing signing cert: %w", err)
+	}
+
+	v.mu.Lock()
+	v.certs[certURL] = cert
+	v.mu.Unlock()
+	return cert, nil
+}
+
+// checkSNSURL rejects anything but an https URL on an SNS host, so a forged
+// message cannot point the verifier at a certificate or URL of its choosing.
+func checkSNSURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" || !snsHost.MatchString(u.Hostname()) {
+		return fmt.Errorf("%q is not an SNS https url", raw)
+	}
+	return nil
+}
+
+// snsStringToSign builds the canonical string SNS signs: selected fields as
+// "Name\nvalue\n" pairs in a fixed order that depends on the message type.
+func snsStringToSign(msg *snsMessage) string {
+	type field struct{ name, value string }
+	var fields []field
+	if msg.Type == snsNotification {
+		fields = []field{{"Message", msg.Message}, {"MessageId", msg.MessageID}}
+		if msg.Subject != "" {
+			fields = append(fields, field{"Subject", msg.Subject})
+		}
+		fields = append(fields,
+			field{"Timestamp", msg.Timestamp},
+			field{"TopicArn", msg.TopicArn},
+			field{"Type", msg.Type},
+		)
+	} else {
+		fields = []field{
+			{"Message", msg.Message},
+			{"MessageId", msg.MessageID},
+			{"SubscribeURL", msg.SubscribeURL},
+			{"Timestamp", msg.Timestamp},
+			{"Token", msg.Token},
+			{"TopicArn", msg.TopicArn},
+			{"Type", msg.Type},
+		}
+	}
+
+	var b strings.Builder
+	for _, f := range fields {
+		b.WriteString(f.name)
+		b.WriteByte('\n')
+		b.WriteString(f.value)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
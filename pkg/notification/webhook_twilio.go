@@ -0,0 +1,134 @@
+package notification
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+)
+
+// twilioSignatureHeader carries Twilio's request signature.
+const twilioSignatureHeader = "X-Twilio-Signature"
+
+// twilioStatuses maps the Twilio message statuses we track to notification
+// statuses. queued, accepted, and sending say nothing the log doesn't already.
+var twilioStatuses = map[string]NotificationStatus{
+	"sent":        StatusSent,
+	"delivered":   StatusDelivered,
+	"undelivered": StatusBounced, // the carrier could not deliver it
+	"failed":      StatusFailed,
+}
+
+// TwilioWebhookAdapter parses Twilio message status callbacks and checks
+// their X-Twilio-Signature. Register it with WebhookRegistry.RegisterSigned.
+type TwilioWebhookAdapter struct {
+	authToken string
+	baseURL   string
+}
+
+var _ WebhookAdapter = (*TwilioWebhookAdapter)(nil)
+
+// NewTwilioWebhookAdapter creates an adapter for the account's auth token.
+// baseURL is the public URL Twilio calls (scheme and host, e.g.
+// https://notify.example.com); when empty the request's URL is used as is.
+func NewTwilioWebhookAdapter(authToken, baseURL string) *TwilioWebhookAdapter {
+	return &TwilioWebhookAdapter{authToken: authToken, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// ParseEvent implements WebhookAdapter. The callback's form params are stored
+// as a JSON object.
+func (a *TwilioWebhookAdapter) ParseEvent(ctx context.Context, req *WebhookRequest) (*ParsedWebhook, error) {
+	params, err := url.ParseQuery(string(req.Body))
+	if err != nil {
+		return nil, common.NewValidationError("invalid Twilio callback: " + err.Error())
+	}
+
+	signature := req.Header.Get(twilioSignatureHeader)
+	expected := twilioSignature(a.authToken, a.signedURL(req.URL), params)
+	if signature == "" || !hmac.Equal([]byte(signature), []byte(expected)) {
+		slog.Warn("rejected Twilio callback", "message_sid", params.Get("MessageSid"))
+		return nil, common.NewUnauthorizedError("Twilio signature verification failed")
+	}
+
+	fields := make(map[string]string, len(params))
+	for name := range params {
+		fields[name] = params.Get(name)
+	}
+	payload, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	status := params.Get("MessageStatus")
+	parsed := &ParsedWebhook{
+		EventType:  status,
+		ProviderID: params.Get("MessageSid"),
+		Status:     twilioStatuses[status],
+		Payload:    payload,
+	}
+	if parsed.Status == StatusBounced {
+		parsed.BounceType = twilioBounceType(params.Get("ErrorCode"))
+	}
+	return parsed, nil
+}
+
+// twilioHardBounceCodes are the Twilio error codes for numbers that will
+// never take the message: blocked, unknown, and landline or unreachable
+// carrier. Other codes, e.g. 30003 (unreachable handset), may clear up.
+var twilioHardBounceCodes = map[string]bool{
+	"30004": true,
+	"30005": true,
+	"30006": true,
+}
+
+// twilioBounceType classifies an undelivered message by its error code. One
+// without a code is treated as hard.
+func twilioBounceType(errorCode string) BounceType {
+	if errorCode != "" && !twilioHardBounceCodes[errorCode] {
+		return BounceSoft
+	}
+	return BounceHard
+}
+
+// signedURL returns the URL Twilio signed: requestURL, on baseURL if one is set.
+func (a *TwilioWebhookAdapter) signedURL(requestURL string) string {
+	if a.baseURL == "" {
+		return requestURL
+	}
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return requestURL
+	}
+	return a.baseURL + u.RequestURI()
+}
+
+// twilioSignature computes Twilio's signature: base64 HMAC-SHA1, keyed by the
+// auth token, of the URL followed by every POST param name and value, sorted
+// by name.
+func twilioSignature(authToken, requestURL string, params url.Values) string {
+	var b strings.Builder
+	b.WriteString(requestURL)
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		for _, value := range params[name] {
+			b.WriteString(name)
+			b.WriteString(value)
+		}
+	}
+
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(b.String()))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
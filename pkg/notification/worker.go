@@ -0,0 +1,637 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+)
+
+// ErrTaskTimeout is returned by ProcessTask when ctx's deadline (the queue's
+// per-task timeout) expires during the send. The log is marked failed with a
+// "timed out" message; the task is still retried, since a slow provider is
+// usually a transient problem.
+var ErrTaskTimeout = errors.New("task timed out")
+
+// Worker processes notification tasks from the queue.
+// It picks up a task, fetches the log from the store, renders the template,
+// sends via the appropriate provider, and updates the log status.
+type Worker struct {
+	store    NotificationStore
+	renderer TemplateRenderer
+	tracker  LinkTracker
+	devices  *Devices
+	latency  LatencyRecorder
+	outcomes OutcomeStore
+	flags    *Flags
+	variants *Variants
+	senders  *Senders
+	costs    CostStore
+	prices   atomic.Pointer[Prices]
+
+	// holds keeps sends away from providers that asked for a pause
+	holds providerHolds
+
+	mu        sync.RWMutex
+	providers map[Channel]Provider
+	canaries  map[Channel]Provider
+}
+
+// NewWorker creates a new notification worker.
+// tracker may be nil, in which case links are sent untouched.
+func NewWorker(store NotificationStore, renderer TemplateRenderer, tracker LinkTracker, providers ...Provider) *Worker {
+	pm := make(map[Channel]Provider, len(providers))
+	for _, p := range providers {
+		pm[p.Channel()] = p
+	}
+	return &Worker{
+		store:     store,
+		renderer:  renderer,
+		tracker:   tracker,
+		providers: pm,
+		canaries:  make(map[Channel]Provider),
+	}
+}
+
+// SetProvider installs p for its channel, replacing the current provider.
+// Tasks already sending keep the provider they started with.
+func (w *Worker) SetProvider(p Provider) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.providers[p.Channel()] = p
+}
+
+// SetCanary installs p as the canary provider for channel, or removes the
+// canary when p is nil. Notifications FlagProviderCanary is on for go
+// through the canary instead of the channel's provider.
+func (w *Worker) SetCanary(channel Channel, p Provider) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if p == nil {
+		delete(w.canaries, channel)
+		return
+	}
+	w.canaries[channel] = p
+}
+
+// SetFlags sets the feature flags that gate click tracking and canary
+// providers; without them every flag has its default. Call it before
+// processing starts.
+func (w *Worker) SetFlags(flags *Flags) {
+	w.flags = flags
+}
+
+// SetVariants sets the A/B tests whose variant subjects logs are rendered
+// with; a log's variant templates are rendered either way. Call it before
+// processing starts.
+func (w *Worker) SetVariants(variants *Variants) {
+	w.variants = variants
+}
+
+// SetSenders sets the sender identities email is sent from: a log's sender,
+// or its type's default when it has none. Without them, or for a sender no
+// longer configured, email goes out from the provider's default address.
+// Call it before processing starts.
+func (w *Worker) SetSenders(senders *Senders) {
+	w.senders = senders
+}
+
+// SetDevices enables invalid-token cleanup: push tokens a provider reports as
+// invalid are removed from devices. Call it before processing starts.
+func (w *Worker) SetDevices(devices *Devices) {
+	w.devices = devices
+}
+
+// SetLatency enables created→sent latency tracking: each sent notification's
+// time since it was accepted is observed in latency. Call it before
+// processing starts.
+func (w *Worker) SetLatency(latency LatencyRecorder) {
+	w.latency = latency
+}
+
+// SetOutcomes enables outcome counting for alerting: every provider send
+// counts as sent or failed in outcomes. Call it before processing starts.
+func (w *Worker) SetOutcomes(outcomes OutcomeStore) {
+	w.outcomes = outcomes
+}
+
+// provider returns the provider for notifLog: its channel's canary when
+// FlagProviderCanary is on for it, else the channel's provider.
+func (w *Worker) provider(notifLog *NotificationLog) (Provider, bool) {
+	channel := Channel(notifLog.Channel)
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if canary, ok := w.canaries[channel]; ok && w.flags.Enabled(FlagProviderCanary, NotificationType(notifLog.Type), notifLog.Recipient) {
+		return canary, true
+	}
+	p, ok := w.providers[channel]
+	return p, ok
+}
+
+// ProcessTask handles a send notification task from the queue.
+// A panic while rendering or sending marks the log failed and is returned as a
+// permanent error rather than leaving the log in processing for the reaper.
+func (w *Worker) ProcessTask(ctx context.Context, logID string) (err error) {
+	start := time.Now()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = w.recovered(ctx, r, logID)
+		}
+	}()
+
+	// Fetch the notification log
+	notifLog, err := w.store.GetByID(ctx, logID)
+	if err != nil {
+		return fmt.Errorf("fetching notification log %s: %w", logID, err)
+	}
+
+	if notifLog == nil {
+		slog.Error("notification log not found", "log_id", logID)
+		return common.NewPermanentError(fmt.Errorf("notification log not found: %s", logID))
+	}
+
+	// A user fan-out parent is only here if it was requeued: settle, never send
+	if notifLog.IsParent() {
+		return w.settleParent(ctx, notifLog.ID)
+	}
+	defer w.settleParents(ctx, notifLog)
+
+	// A duplicate task for a log settled meanwhile (sent by the original
+	// task, or failed for good) must not send it again
+	if !isSendable(notifLog) {
+		slog.Info("notification already settled, skipping", "log_id", logID, "status", notifLog.Status)
+		return nil
+	}
+
+	// Update status to processing
+	if err := w.store.UpdateStatus(ctx, logID, StatusProcessing, "", ""); err != nil {
+		slog.Error("failed to update status to processing", "log_id", logID, "error", err)
+	}
+
+	provider, msg, err := w.prepare(ctx, notifLog)
+	if err != nil {
+		return err
+	}
+
+	return w.send(ctx, notifLog, provider, msg, start)
+}
+
+// ProcessBatch handles a batch task: the logs of one fanned-out request,
+// rendered individually and sent with a single provider call when the channel
+// provider implements BatchProvider. Logs already sent by an earlier attempt
+// are skipped, so a retried batch does not send twice. If the provider rejects
+// the batch outright, each message is sent on its own so one bad address
+// cannot fail the rest.
+func (w *Worker) ProcessBatch(ctx context.Context, logIDs []string) (err error) {
+	start := time.Now()
+
+	// settled holds the logs this attempt is done with, so a panic fails
+	// only the rest: a log the provider already accepted stays sent
+	settled := make(map[string]bool, len(logIDs))
+	defer func() {
+		if r := recover(); r != nil {
+			var unsettled []string
+			for _, logID := range logIDs {
+				if !settled[logID] {
+					unsettled = append(unsettled, logID)
+				}
+			}
+			err = w.recovered(ctx, r, unsettled...)
+		}
+	}()
+
+	var (
+		logs     []*NotificationLog
+		msgs     []*Message
+		provider Provider
+		splitErr error // first transient failure of a log sent outside the batch
+	)
+	defer func() { w.settleParents(ctx, logs...) }()
+	for _, logID := range logIDs {
+		notifLog, err := w.store.GetByID(ctx, logID)
+		if err != nil {
+			return fmt.Errorf("fetching notification log %s: %w", logID, err)
+		}
+		if notifLog == nil {
+			slog.Error("notification log not found", "log_id", logID)
+			settled[logID] = true
+			continue
+		}
+		if !isSendable(notifLog) {
+			settled[logID] = true
+			continue // settled by an earlier attempt of this batch
+		}
+
+		if err := w.store.UpdateStatus(ctx, logID, StatusProcessing, "", ""); err != nil {
+			slog.Error("failed to update status to processing", "log_id", logID, "error", err)
+		}
+
+		p, msg, err := w.prepare(ctx, notifLog)
+		if err != nil {
+			settled[logID] = true
+			continue // prepare recorded the failure on the log
+		}
+		if (provider != nil && p != provider) || len(msg.Attachments) > 0 {
+			// Rolled out to another provider than the batch's (a canary), or
+			// with attachments, which batch APIs do not take: send it on its own
+			if err := w.send(ctx, notifLog, p, msg, start); err != nil && !common.IsPermanent(err) && splitErr == nil {
+				splitErr = err
+			}
+			settled[logID] = true
+			continue
+		}
+		provider = p
+		logs = append(logs, notifLog)
+		msgs = append(msgs, msg)
+	}
+	if len(msgs) == 0 {
+		return splitErr
+	}
+
+	if err := w.held(ctx, provider, logs...); err != nil {
+		return err
+	}
+
+	batcher, ok := provider.(BatchProvider)
+	if ok && len(msgs) > 1 {
+		providerIDs, metadata, err := sendBatch(ctx, batcher, msgs)
+		if err == nil {
+			// The provider accepted every message: none is failed from here on
+			for _, notifLog := range logs {
+				settled[notifLog.ID] = true
+			}
+			for i, notifLog := range logs {
+				cost := w.estimateCost(notifLog, provider)
+				if err := w.store.RecordSent(context.WithoutCancel(ctx), notifLog.ID, providerIDs[i], cost, metadata); err != nil {
+					slog.Error("failed to update status to sent", "log_id", notifLog.ID, "error", err)
+				}
+				w.recordCost(ctx, notifLog, cost)
+				observeLatency(ctx, w.latency, LatencyCreatedToSent, notifLog, time.Since(notifLog.CreatedAt))
+				recordOutcome(ctx, w.outcomes, notifLog, OutcomeSent)
+			}
+			slog.Info("notification batch sent",
+				"channel", provider.Channel(),
+				"count", len(msgs),
+				"duration", time.Since(start),
+			)
+			return splitErr
+		}
+		if !common.IsPermanent(err) {
+			errMsg := fmt.Sprintf("provider error: %s", err.Error())
+			for _, notifLog := range logs {
+				w.markFailed(ctx, notifLog.ID, errMsg, failureCode(err, false), true, metadata)
+				settled[notifLog.ID] = true
+				recordOutcome(ctx, w.outcomes, notifLog, OutcomeFailed)
+			}
+			slog.Error("notification batch failed", "count", len(msgs), "error", err)
+			return w.providerError(provider, err, false)
+		}
+		slog.Warn("notification batch rejected, sending individually", "count", len(msgs), "error", err)
+	}
+
+	// Individual sends: the provider cannot batch, or it rejected the batch.
+	// A transient failure retries the whole task; sent logs are skipped then.
+	retryErr := splitErr
+	for i, notifLog := range logs {
+		if err := w.send(ctx, notifLog, provider, msgs[i], start); err != nil && !common.IsPermanent(err) && retryErr == nil {
+			retryErr = err
+		}
+		settled[notifLog.ID] = true
+	}
+	return retryErr
+}
+
+// settleParents settles the fan-out parents of logs once each, after their
+// sends. Failures are logged: the next child to finish settles again.
+func (w *Worker) settleParents(ctx context.Context, logs ...*NotificationLog) {
+	settled := make(map[string]bool)
+	for _, notifLog := range logs {
+		if notifLog.ParentID == "" || settled[notifLog.ParentID] {
+			continue
+		}
+		settled[notifLog.ParentID] = true
+		if err := w.settleParent(ctx, notifLog.ParentID); err != nil {
+			slog.Error("failed to settle parent notification", "parent_id", notifLog.ParentID, "error", err)
+		}
+	}
+}
+
+// settleParent sets a user fan-out parent's status from its children's.
+func (w *Worker) settleParent(ctx context.Context, parentID string) error {
+	ctx = context.WithoutCancel(ctx)
+
+	parent, err := w.store.GetByID(ctx, parentID)
+	if err != nil {
+		return fmt.Errorf("fetching parent notification %s: %w", parentID, err)
+	}
+	if parent == nil {
+		return common.NewPermanentError(fmt.Errorf("parent notification not found: %s", parentID))
+	}
+
+	children, err := listChildren(ctx, w.store, parentID)
+	if err != nil {
+		return err
+	}
+
+	status, errMsg := aggregateStatus(children)
+	if status == parent.Status {
+		return nil
+	}
+	if err := w.store.UpdateStatus(ctx, parentID, status, "", errMsg); err != nil {
+		return fmt.Errorf("updating parent notification %s: %w", parentID, err)
+	}
+
+	slog.Info("parent notification settled", "log_id", parentID, "status", status, "devices", len(children))
+	return nil
+}
+
+// listChildren returns every child log of a user fan-out parent.
+func listChildren(ctx context.Context, store NotificationStore, parentID string) ([]*NotificationLog, error) {
+	children, err := listAll(ctx, store, ListFilter{ParentID: parentID})
+	if err != nil {
+		return nil, fmt.Errorf("listing children of %s: %w", parentID, err)
+	}
+	return children, nil
+}
+
+// listAll returns every log matching filter, archived or not, fetching it
+// from the primary page by page until a short one, without counting.
+func listAll(ctx context.Context, store NotificationStore, filter ListFilter) ([]*NotificationLog, error) {
+	filter.PageSize = 100
+	filter.Archived = ArchivedInclude
+	filter.Count = CountNone
+	filter.Primary = true
+	var all []*NotificationLog
+	for filter.Page = 1; ; filter.Page++ {
+		logs, _, err := store.List(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, logs...)
+		if len(logs) < filter.PageSize {
+			return all, nil
+		}
+	}
+}
+
+// aggregateStatus is the status of a fan-out parent: sent if any device
+// succeeded, failed once every device failed, processing until then. Failed
+// children count as finished even if retryable, so a parent can go from
+// failed to sent when a retry succeeds.
+func aggregateStatus(children []*NotificationLog) (NotificationStatus, string) {
+	failed := 0
+	for _, child := range children {
+		switch child.Status {
+		case StatusSent, StatusDelivered, StatusOpened, StatusClicked:
+			return StatusSent, ""
+		case StatusFailed, StatusBounced, StatusComplained, StatusAbandoned:
+			failed++
+		}
+	}
+	if len(children) > 0 && failed == len(children) {
+		return StatusFailed, fmt.Sprintf("all %d devices failed", failed)
+	}
+	return StatusProcessing, ""
+}
+
+// isSendable reports whether a task may (re)send a log: it has not been
+// sent yet, and any earlier failure was retryable.
+func isSendable(notifLog *NotificationLog) bool {
+	switch notifLog.Status {
+	case StatusQueued, StatusProcessing:
+		return true
+	case StatusFailed:
+		return notifLog.Retryable == nil || *notifLog.Retryable
+	}
+	return false
+}
+
+// recovered handles a panic in a task: it marks the task's logs failed and
+// returns the permanent error the task should report.
+func (w *Worker) recovered(ctx context.Context, r any, logIDs ...string) error {
+	errMsg := fmt.Sprintf("panic: %v", r)
+	slog.Error("notification task panicked",
+		"log_ids", logIDs,
+		"panic", r,
+		"stack", string(debug.Stack()),
+	)
+	for _, logID := range logIDs {
+		w.markFailed(ctx, logID, errMsg, "", false, nil)
+	}
+	return common.NewPermanentError(errors.New(errMsg))
+}
+
+// prepare resolves the provider for a log and renders its message. Failures
+// are recorded on the log and returned as permanent errors.
+func (w *Worker) prepare(ctx context.Context, notifLog *NotificationLog) (Provider, *Message, error) {
+	logID := notifLog.ID
+	channel := Channel(notifLog.Channel)
+	notifType := NotificationType(notifLog.Type)
+
+	// Validate notification type
+	if !IsValidType(notifType) {
+		errMsg := fmt.Sprintf("unsupported notification type: %s", notifType)
+		w.markFailed(ctx, logID, errMsg, FailureRenderError, false, nil)
+		return nil, nil, common.NewValidationError(errMsg)
+	}
+
+	// Resolve the channel provider
+	provider, ok := w.provider(notifLog)
+	if !ok {
+		errMsg := fmt.Sprintf("unsupported channel: %s", channel)
+		w.markFailed(ctx, logID, errMsg, "", false, nil)
+		return nil, nil, common.NewValidationError(errMsg)
+	}
+
+	// Render the template, unless it was rendered at enqueue: then the
+	// stored content is sent as is, whatever the template says now
+	content := notifLog.Content
+	if content == nil {
+		var err error
+		content, err = renderVariant(w.renderer, w.variants.lookup(notifType, notifLog.Variant), channel, notifType, notifLog.TemplateData)
+		if err != nil {
+			errMsg := fmt.Sprintf("rendering template: %s", err.Error())
+			w.markFailed(ctx, logID, errMsg, FailureRenderError, false, nil)
+			return nil, nil, common.NewPermanentError(fmt.Errorf("rendering template %s: %w", notifType, err))
+		}
+	}
+
+	// Build the message
+	// Route links through the click-tracking endpoint
+	html := content.HTML
+	if w.tracker != nil && html != "" && w.flags.Enabled(FlagClickTracking, notifType, notifLog.Recipient) {
+		html = w.tracker.Rewrite(logID, html)
+	}
+
+	to := notifLog.Recipients
+	if len(to) == 0 {
+		to = []string{notifLog.Recipient}
+	}
+
+	// Send from the log's sender identity, or its type's default, with the
+	// invite of a booking or appointment event in the data
+	var from string
+	var attachments []Attachment
+	if channel == ChannelEmail {
+		sender := w.senders.resolve(notifType, notifLog.Sender)
+		from = w.senders.from(notifType, sender)
+		if sender != "" && from == "" {
+			slog.Warn("sender is no longer configured, using the default address", "log_id", logID, "sender", sender)
+		}
+
+		var err error
+		attachments, err = calendarInvite(notifLog.TemplateData, w.senders.fromOrDefault(notifType, sender), to, time.Now())
+		if err != nil {
+			errMsg := fmt.Sprintf("building calendar invite: %s", err.Error())
+			w.markFailed(ctx, logID, errMsg, FailureRenderError, false, nil)
+			return nil, nil, common.NewPermanentError(fmt.Errorf("building calendar invite: %w", err))
+		}
+	}
+
+	return provider, &Message{
+		From:        from,
+		To:          to,
+		CC:          notifLog.CC,
+		BCC:         notifLog.BCC,
+		ReplyTo:     notifLog.ReplyTo,
+		Headers:     notifLog.Headers,
+		Tags:        notifLog.Tags,
+		Subject:     content.Subject,
+		HTML:        html,
+		Text:        content.Text,
+		Attachments: attachments,
+		Push:        content.Push,
+	}, nil
+}
+
+// send delivers one prepared message and records the outcome on its log.
+func (w *Worker) send(ctx context.Context, notifLog *NotificationLog, provider Provider, msg *Message, start time.Time) error {
+	logID := notifLog.ID
+	channel := provider.Channel()
+
+	if err := w.held(ctx, provider, notifLog); err != nil {
+		return err
+	}
+
+	providerID, metadata, err := sendMessage(ctx, provider, msg)
+	if err != nil {
+		timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+		permanent := !timedOut && common.IsPermanent(err)
+		errMsg := fmt.Sprintf("provider error: %s", err.Error())
+		if timedOut {
+			errMsg = fmt.Sprintf("timed out: %s", err.Error())
+		}
+		w.markFailed(ctx, logID, errMsg, failureCode(err, timedOut), !permanent, metadata)
+		recordOutcome(ctx, w.outcomes, notifLog, OutcomeFailed)
+		w.removeInvalidTokens(ctx, msg, err)
+
+		slog.Error("notification delivery failed",
+			"log_id", logID,
+			"channel", channel,
+			"type", notifLog.Type,
+			"to", notifLog.Recipient,
+			"error", err,
+			"duration", time.Since(start),
+			"timed_out", timedOut,
+			"retryable", !permanent,
+		)
+		if timedOut {
+			return fmt.Errorf("%w: %v", ErrTaskTimeout, err)
+		}
+		return w.providerError(provider, err, permanent)
+	}
+
+	// Update log with success — even if the deadline passed right after the send
+	cost := w.estimateCost(notifLog, provider)
+	if err := w.store.RecordSent(context.WithoutCancel(ctx), logID, providerID, cost, metadata); err != nil {
+		slog.Error("failed to update status to sent", "log_id", logID, "error", err)
+	}
+	w.recordCost(ctx, notifLog, cost)
+	observeLatency(ctx, w.latency, LatencyCreatedToSent, notifLog, time.Since(notifLog.CreatedAt))
+	recordOutcome(ctx, w.outcomes, notifLog, OutcomeSent)
+
+	slog.Info("notification sent",
+		"log_id", logID,
+		"channel", channel,
+		"type", notifLog.Type,
+		"to", notifLog.Recipient,
+		"provider_id", providerID,
+		"duration", time.Since(start),
+	)
+
+	return nil
+}
+
+// removeInvalidTokens drops the device tokens err reports as invalid from the
+// registry. A provider that does not name the tokens means the message's own.
+func (w *Worker) removeInvalidTokens(ctx context.Context, msg *Message, err error) {
+	var tokenErr *common.InvalidTokenError
+	if w.devices == nil || !errors.As(err, &tokenErr) {
+		return
+	}
+	tokens := tokenErr.Tokens
+	if len(tokens) == 0 {
+		tokens = msg.To
+	}
+	if err := w.devices.RemoveInvalid(context.WithoutCancel(ctx), tokens); err != nil {
+		slog.Error("failed to remove invalid device tokens", "error", err)
+	}
+}
+
+// sendMessage sends msg, with the provider's response metadata when the
+// provider reports it.
+func sendMessage(ctx context.Context, provider Provider, msg *Message) (string, *ProviderMetadata, error) {
+	if p, ok := provider.(MetadataProvider); ok {
+		return p.SendWithMetadata(ctx, msg)
+	}
+	providerID, err := provider.Send(ctx, msg)
+	return providerID, nil, err
+}
+
+// sendBatch sends msgs in one call, with the provider's response metadata
+// when the provider reports it.
+func sendBatch(ctx context.Context, batcher BatchProvider, msgs []*Message) ([]string, *ProviderMetadata, error) {
+	if p, ok := batcher.(MetadataBatchProvider); ok {
+		return p.SendBatchWithMetadata(ctx, msgs)
+	}
+	providerIDs, err := batcher.SendBatch(ctx, msgs)
+	return providerIDs, nil, err
+}
+
+// markFailed records a failure, its code, whether it will be retried, and
+// the provider's response metadata. It ignores ctx's deadline so a timed-out
+// task can still record why it failed.
+func (w *Worker) markFailed(ctx context.Context, logID, errMsg string, code FailureCode, retryable bool, metadata *ProviderMetadata) {
+	if err := w.store.RecordFailure(context.WithoutCancel(ctx), logID, errMsg, code, retryable, metadata); err != nil {
+		slog.Error("failed to update status to failed", "log_id", logID, "error", err)
+	}
+}
+
+// failureCode classifies a provider send error. Errors without an HTTP
+// status fall back on whether they are permanent: a permanent one was a
+// rejection, anything else (a transport error) a provider-side failure.
+func failureCode(err error, timedOut bool) FailureCode {
+	var tokenErr *common.InvalidTokenError
+	var statusErr *common.HTTPStatusError
+	switch {
+	case timedOut:
+		return FailureTimeout
+	case errors.As(err, &tokenErr):
+		return FailureInvalidRecipient
+	case errors.As(err, &statusErr):
+		if statusErr.StatusCode >= 500 {
+			return FailureProvider5xx
+		}
+		return FailureProvider4xx
+	case common.IsPermanent(err):
+		return FailureProvider4xx
+	}
+	return FailureProvider5xx
+}
@@ -0,0 +1,66 @@
+package settings
+
+import (
+	"net/http"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler handles HTTP requests for runtime settings.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new settings handler.
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// List handles GET /api/v1/admin/settings
+func (h *Handler) List(c *gin.Context) {
+	views, err := h.service.List(c.Request.Context())
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, gin.H{"settings": views})
+}
+
+// Update handles PUT /api/v1/admin/settings/:key
+// Running processes pick up the change on their next settings poll.
+func (h *Handler) Update(c *gin.Context) {
+	var req UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Error(c, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	view, err := h.service.Set(c.Request.Context(), Key(c.Param("key")), req.Value)
+	if err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, view)
+}
+
+// Reset handles DELETE /api/v1/admin/settings/:key
+func (h *Handler) Reset(c *gin.Context) {
+	key := Key(c.Param("key"))
+	if err := h.service.Reset(c.Request.Context(), key); err != nil {
+		common.HandleError(c, err)
+		return
+	}
+
+	common.Success(c, http.StatusOK, gin.H{"key": key, "status": "reset"})
+}
+
+// RegisterRoutes registers settings routes to the given router group.
+func (h *Handler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.GET("/admin/settings", h.List)
+	rg.PUT("/admin/settings/:key", h.Update)
+	rg.DELETE("/admin/settings/:key", h.Reset)
+}
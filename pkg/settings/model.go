@@ -0,0 +1,232 @@
+// Package settings stores operational knobs in the database so they can be
+// changed at runtime through the admin API. Stored values override the
+// matching config.yaml / env setting and are picked up by every server and
+// worker process without a redeploy.
+package settings
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+)
+
+// Key names a runtime setting. Keys mirror the config paths they override.
+type Key string
+
+const (
+	KeyRecipientMaxPerHour     Key = "recipient_rate_limit.max_per_hour"
+	KeyReaperIntervalSec       Key = "reaper.interval_sec"
+	KeyReaperStaleThresholdSec Key = "reaper.stale_threshold_sec"
+	KeyReaperBatchSize         Key = "reaper.batch_size"
+	KeySuppressionBounced      Key = "suppression.bounced"
+	KeyEmailProvider           Key = "email.provider"
+
+	// Feature flag rollouts; see notification.FeatureFlag.
+	KeyFlagClickTracking  Key = "flags.click_tracking"
+	KeyFlagProviderCanary Key = "flags.provider_canary"
+)
+
+// Kind is the JSON type a setting's value must have.
+type Kind string
+
+const (
+	KindInt    Kind = "int"
+	KindBool   Kind = "bool"
+	KindString Kind = "string"
+
+	// KindRollout is a feature flag rollout: {"percent": 10, "types": {"magic_link": 100}}.
+	KindRollout Kind = "rollout"
+)
+
+// spec describes the accepted values for one key.
+type spec struct {
+	Kind        Kind
+	Min         int      // KindInt only
+	OneOf       []string // KindString only
+	Description string
+}
+
+// specs is the set of keys that may be stored. Anything else is rejected.
+var specs = map[Key]spec{
+	KeyRecipientMaxPerHour:     {Kind: KindInt, Min: 1, Description: "Max notifications per recipient per hour"},
+	KeyReaperIntervalSec:       {Kind: KindInt, Min: 1, Description: "Seconds between reaper sweeps"},
+	KeyReaperStaleThresholdSec: {Kind: KindInt, Min: 60, Description: "Seconds before a queued/processing log is stale"},
+	KeyReaperBatchSize:         {Kind: KindInt, Min: 1, Description: "Max stale logs recovered per sweep"},
+	KeySuppressionBounced:      {Kind: KindBool, Description: "Reject sends to recipients with a bounced notification"},
+	KeyEmailProvider:           {Kind: KindString, OneOf: []string{"resend"}, Description: "Email delivery provider"},
+	KeyFlagClickTracking:       {Kind: KindRollout, Description: "Percent of notifications with click tracking, overall and per type"},
+	KeyFlagProviderCanary:      {Kind: KindRollout, Description: "Percent of notifications sent through the canary provider, overall and per type"},
+}
+
+// IsKnown reports whether k can be stored.
+func IsKnown(k Key) bool {
+	_, ok := specs[k]
+	return ok
+}
+
+// Keys returns every known key, sorted.
+func Keys() []Key {
+	keys := make([]Key, 0, len(specs))
+	for k := range specs {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// Normalize checks raw (as decoded from JSON) against the key's spec and
+// returns the canonical value: int, bool, or string.
+func Normalize(k Key, raw any) (any, error) {
+	sp, ok := specs[k]
+	if !ok {
+		return nil, fmt.Errorf("unknown setting: %s", k)
+	}
+
+	switch sp.Kind {
+	case KindInt:
+		var n int
+		switch v := raw.(type) {
+		case int:
+			n = v
+		case float64:
+			if v != math.Trunc(v) {
+				return nil, fmt.Errorf("%s must be an integer", k)
+			}
+			n = int(v)
+		default:
+			return nil, fmt.Errorf("%s must be an integer", k)
+		}
+		if n < sp.Min {
+			return nil, fmt.Errorf("%s must be at least %d, got %d", k, sp.Min, n)
+		}
+		return n, nil
+	case KindBool:
+		b, ok := raw.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s must be true or false", k)
+		}
+		return b, nil
+	case KindRollout:
+		return normalizeRollout(k, raw)
+	default:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string", k)
+		}
+		for _, allowed := range sp.OneOf {
+			if s == allowed {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("%s must be one of %v, got %q", k, sp.OneOf, s)
+	}
+}
+
+// Rollout is the value of a feature flag setting: the percent of
+// notifications the flag is on for, and per-type percents that take precedence.
+type Rollout struct {
+	Percent int            `json:"percent"`
+	Types   map[string]int `json:"types,omitempty"`
+}
+
+// normalizeRollout checks a decoded rollout object: percents are integers
+// from 0 to 100 and types are known notification types.
+func normalizeRollout(k Key, raw any) (Rollout, error) {
+	obj, ok := raw.(map[string]any)
+	if !ok {
+		return Rollout{}, fmt.Errorf("%s must be an object with percent and optional types", k)
+	}
+	percent := func(name string, v any) (int, error) {
+		f, ok := v.(float64)
+		if !ok || f != math.Trunc(f) || f < 0 || f > 100 {
+			return 0, fmt.Errorf("%s: %s must be an integer from 0 to 100", k, name)
+		}
+		return int(f), nil
+	}
+
+	var rollout Rollout
+	for field, v := range obj {
+		switch field {
+		case "percent":
+			p, err := percent("percent", v)
+			if err != nil {
+				return Rollout{}, err
+			}
+			rollout.Percent = p
+		case "types":
+			types, ok := v.(map[string]any)
+			if !ok {
+				return Rollout{}, fmt.Errorf("%s: types must be an object of notification type to percent", k)
+			}
+			rollout.Types = make(map[string]int, len(types))
+			for t, tv := range types {
+				if !notification.IsValidType(notification.NotificationType(t)) {
+					return Rollout{}, fmt.Errorf("%s: unknown notification type %q", k, t)
+				}
+				p, err := percent("types."+t, tv)
+				if err != nil {
+					return Rollout{}, err
+				}
+				rollout.Types[t] = p
+			}
+		default:
+			return Rollout{}, fmt.Errorf("%s: unknown field %q", k, field)
+		}
+	}
+	if _, ok := obj["percent"]; !ok {
+		return Rollout{}, fmt.Errorf("%s: percent is required", k)
+	}
+	return rollout, nil
+}
+
+// Setting is one stored override.
+type Setting struct {
+	Key       Key       `json:"key"`
+	Value     any       `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Values holds the normalized overrides currently in effect, keyed by setting.
+type Values map[Key]any
+
+// Int returns the override for k if one is set.
+func (v Values) Int(k Key) (int, bool) {
+	n, ok := v[k].(int)
+	return n, ok
+}
+
+// Bool returns the override for k if one is set.
+func (v Values) Bool(k Key) (bool, bool) {
+	b, ok := v[k].(bool)
+	return b, ok
+}
+
+// String returns the override for k if one is set.
+func (v Values) String(k Key) (string, bool) {
+	s, ok := v[k].(string)
+	return s, ok
+}
+
+// Rollout returns the override for k if one is set.
+func (v Values) Rollout(k Key) (Rollout, bool) {
+	r, ok := v[k].(Rollout)
+	return r, ok
+}
+
+// UpdateRequest is the payload for PUT /api/v1/admin/settings/:key.
+type UpdateRequest struct {
+	Value any `json:"value" binding:"required"`
+}
+
+// SettingView describes one key in the admin listing. Value is nil when the
+// config file / env value is in effect.
+type SettingView struct {
+	Key         Key        `json:"key"`
+	Kind        Kind       `json:"kind"`
+	Description string     `json:"description"`
+	Value       any        `json:"value"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
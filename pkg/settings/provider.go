@@ -0,0 +1,16 @@
+package settings
+
+import "context"
+
+// Store defines the contract for persisting runtime settings.
+// Implementations live in internal/infra/store/ (e.g., Supabase).
+type Store interface {
+	// List returns every stored setting. Values are as decoded from JSON.
+	List(ctx context.Context) ([]Setting, error)
+
+	// Put inserts or replaces the value for key.
+	Put(ctx context.Context, key Key, value any) error
+
+	// Delete removes the override for key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key Key) error
+}
@@ -0,0 +1,98 @@
+package settings
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/badrkarrachai/notifly/pkg/common"
+)
+
+// Service validates and persists runtime settings.
+type Service struct {
+	store Store
+}
+
+// NewService creates a new settings service.
+func NewService(store Store) *Service {
+	return &Service{store: store}
+}
+
+// List returns every known key with its stored override, if any.
+func (s *Service) List(ctx context.Context) ([]SettingView, error) {
+	stored, err := s.store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing settings: %w", err)
+	}
+	byKey := make(map[Key]Setting, len(stored))
+	for _, st := range stored {
+		byKey[st.Key] = st
+	}
+
+	views := make([]SettingView, 0, len(specs))
+	for _, k := range Keys() {
+		sp := specs[k]
+		view := SettingView{Key: k, Kind: sp.Kind, Description: sp.Description}
+		if st, ok := byKey[k]; ok {
+			view.Value = st.Value
+			updatedAt := st.UpdatedAt
+			view.UpdatedAt = &updatedAt
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// Set validates and stores an override for key.
+func (s *Service) Set(ctx context.Context, key Key, raw any) (*SettingView, error) {
+	if !IsKnown(key) {
+		return nil, common.NewNotFoundError("setting", string(key))
+	}
+	value, err := Normalize(key, raw)
+	if err != nil {
+		return nil, common.NewValidationError(err.Error())
+	}
+
+	if err := s.store.Put(ctx, key, value); err != nil {
+		return nil, fmt.Errorf("storing setting %s: %w", key, err)
+	}
+
+	slog.Info("runtime setting updated", "key", key, "value", value)
+
+	sp := specs[key]
+	return &SettingView{Key: key, Kind: sp.Kind, Description: sp.Description, Value: value}, nil
+}
+
+// Reset removes the override for key so the config file / env value applies again.
+func (s *Service) Reset(ctx context.Context, key Key) error {
+	if !IsKnown(key) {
+		return common.NewNotFoundError("setting", string(key))
+	}
+	if err := s.store.Delete(ctx, key); err != nil {
+		return fmt.Errorf("deleting setting %s: %w", key, err)
+	}
+
+	slog.Info("runtime setting reset", "key", key)
+	return nil
+}
+
+// Values loads the stored overrides in normalized form. Rows with unknown keys
+// or values that no longer validate are logged and skipped, so one bad row
+// cannot block the others.
+func (s *Service) Values(ctx context.Context) (Values, error) {
+	stored, err := s.store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing settings: %w", err)
+	}
+
+	values := make(Values, len(stored))
+	for _, st := range stored {
+		value, err := Normalize(st.Key, st.Value)
+		if err != nil {
+			slog.Warn("ignoring invalid runtime setting", "key", st.Key, "error", err)
+			continue
+		}
+		values[st.Key] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,602 @@
+// Package template renders notification emails from an HTML layout, shared
+// partials, and one content page per notification type; SMS bodies from
+// optional sms/*.txt templates; and push payloads from optional push/*.json
+// templates; subjects are localized by optional subjects/<locale>.json files.
+// Engine implements notification.TemplateRenderer, SMSRenderer,
+// PushRenderer, and VariantRenderer; Validate checks a templates directory offline.
+// The default templates are embedded in the binary; a directory can override them.
+package template
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+	texttemplate "text/template"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+)
+
+var (
+	_ notification.TemplateRenderer = (*Engine)(nil)
+	_ notification.SMSRenderer      = (*Engine)(nil)
+	_ notification.PushRenderer     = (*Engine)(nil)
+	_ notification.VariantRenderer  = (*Engine)(nil)
+)
+
+//go:embed templates
+var embedded embed.FS
+
+// Embedded returns the templates shipped with Notifly, rooted at the templates
+// directory. It is what NewDefaultEngine loads.
+func Embedded() fs.FS {
+	sub, err := fs.Sub(embedded, "templates")
+	if err != nil {
+		panic(err) // the directory is embedded at build time; this cannot fail
+	}
+	return sub
+}
+
+// templateMeta holds the subject, template name, and sample data for each notification type.
+// Subject is the English subject; other locales' are in subjects/<locale>.json.
+// SampleData lists every variable the template expects; it is used by Validate.
+type templateMeta struct {
+	Subject      string
+	TemplateName string
+	SampleData   map[string]any
+}
+
+// registry maps notification types to their metadata.
+var registry = map[notification.NotificationType]templateMeta{
+	notification.TypeConfirmSignup: {
+		Subject: "Confirm Your Email Address", TemplateName: "confirm_signup",
+		SampleData: map[string]any{"ConfirmationURL": "https://example.com/confirm?token=abc123"},
+	},
+	notification.TypeInviteUser: {
+		Subject: "You've Been Invited", TemplateName: "invite_user",
+		SampleData: map[string]any{"InviterName": "Jane Doe", "AppName": "Example", "InviteURL": "https://example.com/invite?token=abc123"},
+	},
+	notification.TypeMagicLink: {
+		Subject: "Your Sign-In Link", TemplateName: "magic_link",
+		SampleData: map[string]any{"MagicLinkURL": "https://example.com/magic?token=abc123"},
+	},
+	notification.TypeChangeEmail: {
+		Subject: "Confirm Your New Email Address", TemplateName: "change_email",
+		SampleData: map[string]any{"NewEmail": "new@example.com", "ConfirmationURL": "https://example.com/confirm?token=abc123"},
+	},
+	notification.TypeResetPassword: {
+		Subject: "Reset Your Password", TemplateName: "reset_password",
+		SampleData: map[string]any{"ResetURL": "https://example.com/reset?token=abc123"},
+	},
+	notification.TypeReauthentication: {
+		Subject: "Confirm Your Identity", TemplateName: "reauthentication",
+		SampleData: map[string]any{"ConfirmationURL": "https://example.com/confirm?token=abc123"},
+	},
+	notification.TypePasswordChanged: {
+		Subject: "Your Password Has Been Changed", TemplateName: "password_changed",
+		SampleData: map[string]any{"ChangedAt": "January 2, 2026 at 3:04 PM UTC"},
+	},
+	notification.TypeEmailChanged: {
+		Subject: "Your Email Address Has Been Changed", TemplateName: "email_changed",
+		SampleData: map[string]any{"OldEmail": "old@example.com", "NewEmail": "new@example.com", "ChangedAt": "January 2, 2026 at 3:04 PM UTC"},
+	},
+	notification.TypePhoneChanged: {
+		Subject: "Your Phone Number Has Been Changed", TemplateName: "phone_changed",
+		SampleData: map[string]any{"NewPhone": "+1 555 0100", "ChangedAt": "January 2, 2026 at 3:04 PM UTC"},
+	},
+	notification.TypeIdentityLinked: {
+		Subject: "A New Identity Has Been Linked", TemplateName: "identity_linked",
+		SampleData: map[string]any{"Provider": "Google", "LinkedAt": "January 2, 2026 at 3:04 PM UTC"},
+	},
+	notification.TypeIdentityUnlinked: {
+		Subject: "An Identity Has Been Unlinked", TemplateName: "identity_unlinked",
+		SampleData: map[string]any{"Provider": "Google", "UnlinkedAt": "January 2, 2026 at 3:04 PM UTC"},
+	},
+	notification.TypeDailySummary: {
+		Subject: "Notifly Daily Summary", TemplateName: "daily_summary",
+		SampleData: map[string]any{
+			"Period":   "January 1, 2026 8:00 AM – January 2, 2026 8:00 AM UTC",
+			"Total":    "1,204",
+			"Sent":     "1,180",
+			"Failed":   "12",
+			"Bounced":  "5",
+			"Types":    []any{map[string]any{"Type": "magic_link", "Accepted": "904", "SuccessRate": "99.1%", "BounceRate": "0.4%"}},
+			"Failures": []any{map[string]any{"Code": "provider_5xx", "Count": "9"}},
+			"Reaper":   map[string]any{"Recovered": "3", "Abandoned": "0", "LastSweep": "January 2, 2026 7:55 AM UTC"},
+			"Quotas":   []any{map[string]any{"Channel": "email", "Used": "1,180", "Limit": "50,000", "Percent": "2.4%"}},
+		},
+	},
+}
+
+// Types returns the notification types that have a template, sorted.
+func Types() []notification.NotificationType {
+	types := make([]notification.NotificationType, 0, len(registry))
+	for notifType := range registry {
+		types = append(types, notifType)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// SampleData returns a copy of the sample data for notifType's template, or
+// nil when the type has none. It renders every variable the template expects.
+func SampleData(notifType notification.NotificationType) map[string]any {
+	meta, ok := registry[notifType]
+	if !ok {
+		return nil
+	}
+	return maps.Clone(meta.SampleData)
+}
+
+// layoutTemplate is the entry point every HTML page is executed through.
+// Pages define the "title", "heading", and "content" blocks it composes.
+const layoutTemplate = "base"
+
+// smsDir holds the SMS bodies, one <name>.txt per notification type.
+const smsDir = "sms"
+
+// Engine renders notification templates using Go's html/template package.
+// Each HTML page is composed with the shared layout and partials at startup.
+// Plain-text bodies come from optional .txt templates rendered with text/template,
+// SMS bodies from optional sms/*.txt templates, and push payloads from optional
+// push/*.json templates.
+type Engine struct {
+	pages         map[string]*template.Template
+	textTemplates *texttemplate.Template
+	smsTemplates  *texttemplate.Template
+	pushTemplates map[string]*pushTemplate
+	strict        bool
+
+	// subjectsByLocale holds the localized subjects by locale, then template
+	// name; defaultLocale is the locale used when data names none. See
+	// SetDefaultLocale.
+	subjectsByLocale map[string]map[string]string
+	defaultLocale    atomic.Pointer[string]
+
+	// SMS bodies longer than smsMaxSegments (0 = no limit) are logged, or
+	// truncated when smsTruncate is set. See SetSMSLimits.
+	smsMaxSegments atomic.Int64
+	smsTruncate    atomic.Bool
+
+	// sanitized holds the types whose template data has HTML stripped from
+	// its string values before rendering. See SetSanitizedTypes.
+	sanitized atomic.Pointer[map[notification.NotificationType]bool]
+
+	// subjects holds the subjects that replace the registry's, by type. See
+	// SetSubjects.
+	subjects atomic.Pointer[map[notification.NotificationType]string]
+}
+
+// NewDefaultEngine creates a template engine from the templates embedded in
+// the binary, so embedders need no files on disk.
+func NewDefaultEngine() (*Engine, error) {
+	return NewEngineFS(Embedded())
+}
+
+// NewEngine creates a new template engine by loading all templates from the given directory.
+// Use it to override the embedded templates, e.g. with /app/templates in Docker.
+func NewEngine(templatesDir string) (*Engine, error) {
+	return NewEngineFS(os.DirFS(templatesDir))
+}
+
+// NewEngineFS creates a new template engine from fsys, rooted at the templates directory.
+// The layout lives in layouts/, reusable blocks in partials/, and one content page per
+// notification type at the top level. *.txt files are optional plain-text counterparts,
+// sms/*.txt optional SMS bodies, push/*.json optional push payloads, and
+// subjects/<locale>.json optional localized subjects.
+func NewEngineFS(fsys fs.FS) (*Engine, error) {
+	return loadEngine(fsys, false)
+}
+
+// loadEngine parses the templates in fsys. In strict mode, referencing a
+// variable missing from the data map is an execution error instead of "<no value>".
+// Text templates always treat a missing key as an error so "<no value>" never
+// reaches a recipient; outside strict mode Render falls back to the stripped HTML.
+func loadEngine(fsys fs.FS, strict bool) (*Engine, error) {
+	base := template.New(layoutTemplate).Funcs(funcMap)
+	if strict {
+		base = base.Option("missingkey=error")
+	}
+	base, err := base.ParseFS(fsys, "layouts/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("parsing layouts: %w", err)
+	}
+
+	partials, err := fs.Glob(fsys, "partials/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("listing partials: %w", err)
+	}
+	if len(partials) > 0 {
+		if _, err := base.ParseFS(fsys, partials...); err != nil {
+			return nil, fmt.Errorf("parsing partials: %w", err)
+		}
+	}
+
+	pageFiles, err := fs.Glob(fsys, "*.html")
+	if err != nil {
+		return nil, fmt.Errorf("listing templates: %w", err)
+	}
+	if len(pageFiles) == 0 {
+		return nil, fmt.Errorf("no templates found")
+	}
+
+	// Each page gets its own clone of the layout so their block definitions don't collide
+	pages := make(map[string]*template.Template, len(pageFiles))
+	for _, file := range pageFiles {
+		page, err := template.Must(base.Clone()).ParseFS(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("parsing template %s: %w", file, err)
+		}
+		pages[strings.TrimSuffix(path.Base(file), ".html")] = page
+	}
+
+	engine := &Engine{pages: pages, strict: strict}
+
+	textFiles, err := fs.Glob(fsys, "*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("listing text templates: %w", err)
+	}
+	if len(textFiles) > 0 {
+		textTmpl := texttemplate.New("").Funcs(texttemplate.FuncMap(funcMap)).Option("missingkey=error")
+		textTmpl, err = textTmpl.ParseFS(fsys, textFiles...)
+		if err != nil {
+			return nil, fmt.Errorf("parsing text templates: %w", err)
+		}
+		engine.textTemplates = textTmpl
+	}
+
+	smsFiles, err := fs.Glob(fsys, smsDir+"/*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("listing sms templates: %w", err)
+	}
+	if len(smsFiles) > 0 {
+		smsTmpl := texttemplate.New("").Funcs(texttemplate.FuncMap(funcMap)).Option("missingkey=error")
+		smsTmpl, err = smsTmpl.ParseFS(fsys, smsFiles...)
+		if err != nil {
+			return nil, fmt.Errorf("parsing sms templates: %w", err)
+		}
+		engine.smsTemplates = smsTmpl
+	}
+
+	engine.pushTemplates, err = loadPushTemplates(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	engine.subjectsByLocale, err = loadSubjects(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return engine, nil
+}
+
+// SetSMSLimits sets the segment limit for SMS bodies (0 disables it) and
+// whether a longer body is truncated instead of only logged. Safe for
+// concurrent use.
+func (e *Engine) SetSMSLimits(maxSegments int, truncate bool) {
+	e.smsMaxSegments.Store(int64(maxSegments))
+	e.smsTruncate.Store(truncate)
+}
+
+// SetSanitizedTypes sets the notification types whose template data is
+// sanitized before rendering: HTML tags are stripped from every string value,
+// and script and style content dropped, so user-generated content passed as
+// data cannot carry markup or links into any channel's message. Safe for
+// concurrent use.
+func (e *Engine) SetSanitizedTypes(types []notification.NotificationType) {
+	set := make(map[notification.NotificationType]bool, len(types))
+	for _, t := range types {
+		set[t] = true
+	}
+	e.sanitized.Store(&set)
+}
+
+// SetSubjects sets the subjects that replace the built-in ones, by
+// notification type, so a subject line can change without a release. A
+// subject in the template data, or a localized one, still wins. Safe for
+// concurrent use.
+func (e *Engine) SetSubjects(subjects map[notification.NotificationType]string) {
+	e.subjects.Store(&subjects)
+}
+
+// SetDefaultLocale sets the locale subjects are rendered in when the template
+// data names none, or one without a translation. Safe for concurrent use.
+func (e *Engine) SetDefaultLocale(locale string) {
+	e.defaultLocale.Store(&locale)
+}
+
+// subject returns the subject of notifType in the locale data names, or else
+// the default locale: its translation from a subjects file, or for English,
+// its configured subject or meta's. A type with no translation in either
+// locale gets its English subject.
+func (e *Engine) subject(notifType notification.NotificationType, meta templateMeta, data map[string]any) string {
+	locales := make([]string, 0, 2)
+	if locale, _ := data[LocaleKey].(string); locale != "" {
+		locales = append(locales, locale)
+	}
+	if locale := e.defaultLocale.Load(); locale != nil && *locale != "" {
+		locales = append(locales, *locale)
+	}
+	for _, locale := range locales {
+		if subject, ok := e.localizedSubject(meta.TemplateName, locale); ok {
+			return subject
+		}
+		if isEnglish(locale) {
+			break
+		}
+	}
+	if subjects := e.subjects.Load(); subjects != nil && (*subjects)[notifType] != "" {
+		return (*subjects)[notifType]
+	}
+	return meta.Subject
+}
+
+// templateData returns the data to render notifType with: sanitized when the
+// type is set to be, otherwise data itself.
+func (e *Engine) templateData(notifType notification.NotificationType, data map[string]any) map[string]any {
+	if set := e.sanitized.Load(); set != nil && (*set)[notifType] {
+		return sanitizeData(data)
+	}
+	return data
+}
+
+// Variant returns a renderer for the named A/B test variant. Each type
+// renders from its <name>.<variant>.html page (with <name>.<variant>.txt),
+// sms/<name>.<variant>.txt, and push/<name>.<variant>.json where they exist,
+// and from its own templates otherwise.
+func (e *Engine) Variant(name string) notification.TemplateRenderer {
+	return &variantEngine{engine: e, variant: name}
+}
+
+// variantEngine renders one variant's templates; see Engine.Variant.
+type variantEngine struct {
+	engine  *Engine
+	variant string
+}
+
+func (v *variantEngine) Render(notifType notification.NotificationType, data map[string]any) (subject, html, text string, err error) {
+	return v.engine.render(notifType, v.variant, data)
+}
+
+func (v *variantEngine) RenderSMS(notifType notification.NotificationType, data map[string]any) (string, error) {
+	return v.engine.renderSMS(notifType, v.variant, data)
+}
+
+func (v *variantEngine) RenderPush(notifType notification.NotificationType, data map[string]any) (*notification.PushContent, error) {
+	return v.engine.renderPush(notifType, v.variant, data)
+}
+
+// variantTemplate returns the name of variant's template of name when exists
+// reports one, and name otherwise.
+func variantTemplate(name, variant string, exists func(string) bool) string {
+	if variant != "" && exists(name+"."+variant) {
+		return name + "." + variant
+	}
+	return name
+}
+
+// Render produces a subject line, HTML body, and plain-text fallback for the given notification type.
+func (e *Engine) Render(notifType notification.NotificationType, data map[string]any) (subject, html, text string, err error) {
+	return e.render(notifType, "", data)
+}
+
+// render is Render with variant's page and plain-text templates, when it has
+// a page. The plain text never falls back to the type's own .txt, which
+// would not match the variant's HTML.
+func (e *Engine) render(notifType notification.NotificationType, variant string, data map[string]any) (subject, html, text string, err error) {
+	meta, ok := registry[notifType]
+	if !ok {
+		return "", "", "", fmt.Errorf("no template registered for type: %s", notifType)
+	}
+	data = e.templateData(notifType, data)
+
+	// Allow subject override via data
+	subject = e.subject(notifType, meta, data)
+	if customSubject, ok := data["Subject"].(string); ok && customSubject != "" {
+		subject = customSubject
+	}
+
+	name := variantTemplate(meta.TemplateName, variant, func(name string) bool {
+		_, ok := e.pages[name]
+		return ok
+	})
+	page, ok := e.pages[name]
+	if !ok {
+		return "", "", "", fmt.Errorf("template file missing for type %s: %s.html", notifType, name)
+	}
+
+	// Render the page through the shared layout
+	var buf bytes.Buffer
+	if err := page.ExecuteTemplate(&buf, layoutTemplate, data); err != nil {
+		return "", "", "", fmt.Errorf("executing template %s: %w", name, err)
+	}
+	html = buf.String()
+
+	// Prefer a dedicated plain-text template; fall back to stripping the HTML
+	text, err = e.renderText(name, data)
+	if err != nil {
+		if e.strict {
+			return "", "", "", err
+		}
+		slog.Warn("text template failed, using stripped html", "template", name, "error", err)
+		text = ""
+	}
+	if text == "" {
+		text = stripHTML(html)
+	}
+
+	return subject, html, text, nil
+}
+
+// renderText executes the .txt template for the given name, if one was loaded.
+// Returns an empty string when no plain-text template exists.
+func (e *Engine) renderText(name string, data map[string]any) (string, error) {
+	if e.textTemplates == nil || e.textTemplates.Lookup(name+".txt") == nil {
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	if err := e.textTemplates.ExecuteTemplate(&buf, name+".txt", data); err != nil {
+		return "", fmt.Errorf("executing text template %s: %w", name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// RenderSMS produces the SMS body for the given notification type: its
+// sms/<name>.txt template when there is one, otherwise the plain-text body
+// Render produces. A body over the segment limit is truncated with an
+// ellipsis when truncation is on, and logged either way.
+func (e *Engine) RenderSMS(notifType notification.NotificationType, data map[string]any) (string, error) {
+	return e.renderSMS(notifType, "", data)
+}
+
+// renderSMS is RenderSMS with variant's templates where it has them.
+func (e *Engine) renderSMS(notifType notification.NotificationType, variant string, data map[string]any) (string, error) {
+	meta, ok := registry[notifType]
+	if !ok {
+		return "", fmt.Errorf("no template registered for type: %s", notifType)
+	}
+	data = e.templateData(notifType, data)
+
+	name := variantTemplate(meta.TemplateName, variant, func(name string) bool {
+		return e.smsTemplates != nil && e.smsTemplates.Lookup(name+".txt") != nil
+	})
+	body, err := e.renderSMSText(name, data)
+	if err != nil {
+		if e.strict {
+			return "", err
+		}
+		slog.Warn("sms template failed, using plain-text body", "template", name, "error", err)
+		body = ""
+	}
+	if body == "" {
+		if _, _, body, err = e.render(notifType, variant, data); err != nil {
+			return "", err
+		}
+	}
+
+	maxSegments := int(e.smsMaxSegments.Load())
+	if maxSegments <= 0 {
+		return body, nil
+	}
+	info := CountSMS(body)
+	if info.Segments <= maxSegments {
+		return body, nil
+	}
+	truncate := e.smsTruncate.Load()
+	slog.Warn("sms body exceeds segment limit",
+		"template", name,
+		"encoding", info.Encoding,
+		"segments", info.Segments,
+		"max_segments", maxSegments,
+		"truncated", truncate,
+	)
+	if truncate {
+		body = truncateSMS(body, maxSegments)
+	}
+	return body, nil
+}
+
+// renderSMSText executes the sms/<name>.txt template, if one was loaded.
+// Returns an empty string when no SMS template exists.
+func (e *Engine) renderSMSText(name string, data map[string]any) (string, error) {
+	if e.smsTemplates == nil || e.smsTemplates.Lookup(name+".txt") == nil {
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	if err := e.smsTemplates.ExecuteTemplate(&buf, name+".txt", data); err != nil {
+		return "", fmt.Errorf("executing sms template %s: %w", name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// RenderPush produces the push payload for the given notification type from
+// its push/<name>.json template. Without one, the payload is the subject as
+// title and the plain-text body as body.
+func (e *Engine) RenderPush(notifType notification.NotificationType, data map[string]any) (*notification.PushContent, error) {
+	return e.renderPush(notifType, "", data)
+}
+
+// renderPush is RenderPush with variant's templates where it has them.
+func (e *Engine) renderPush(notifType notification.NotificationType, variant string, data map[string]any) (*notification.PushContent, error) {
+	meta, ok := registry[notifType]
+	if !ok {
+		return nil, fmt.Errorf("no template registered for type: %s", notifType)
+	}
+	data = e.templateData(notifType, data)
+
+	name := variantTemplate(meta.TemplateName, variant, func(name string) bool {
+		_, ok := e.pushTemplates[name]
+		return ok
+	})
+	if tmpl, ok := e.pushTemplates[name]; ok {
+		content, err := tmpl.execute(data)
+		if err == nil {
+			return content, nil
+		}
+		if e.strict {
+			return nil, fmt.Errorf("executing push template %s: %w", name, err)
+		}
+		slog.Warn("push template failed, using subject and plain-text body", "template", name, "error", err)
+	}
+
+	subject, _, text, err := e.render(notifType, variant, data)
+	if err != nil {
+		return nil, err
+	}
+	return &notification.PushContent{Title: subject, Body: text}, nil
+}
+
+// stripHTML removes HTML tags and collapses whitespace to produce a plain-text version.
+func stripHTML(s string) string {
+	// Remove HTML tags
+	re := regexp.MustCompile(`<[^>]*>`)
+	text := re.ReplaceAllString(s, "")
+
+	// Decode common HTML entities
+	text = strings.ReplaceAll(text, "&amp;", "&")
+	text = strings.ReplaceAll(text, "&lt;", "<")
+	text = strings.ReplaceAll(text, "&gt;", ">")
+	text = strings.ReplaceAll(text, "&quot;", `"`)
+	text = strings.ReplaceAll(text, "&#39;", "'")
+	text = strings.ReplaceAll(text, "&nbsp;", " ")
+
+	// Collapse whitespace
+	wsRe := regexp.MustCompile(`\s+`)
+	text = wsRe.ReplaceAllString(text, " ")
+
+	return strings.TrimSpace(text)
+}
+
+// funcMap holds helpers available to layouts, partials, and pages.
+var funcMap = template.FuncMap{
+	"dict": dict,
+}
+
+// dict builds a map from alternating key/value arguments so partials can take
+// more than one parameter, e.g. {{template "button" (dict "URL" .URL "Label" "Go")}}.
+func dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict: odd number of arguments")
+	}
+	m := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key at position %d is not a string", i)
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
@@ -0,0 +1,86 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// subjectsDir holds the localized subjects, one <locale>.json per locale
+// mapping template names to subjects, e.g. subjects/es.json.
+const subjectsDir = "subjects"
+
+// LocaleKey is the template data key a request sets its recipient's locale
+// in, e.g. {"Locale": "pt-BR"}. Without it the engine's default locale is used.
+const LocaleKey = "Locale"
+
+// localeRe matches a BCP 47 language tag in the form subject files are
+// named: a language, then optional subtags, lowercase.
+var localeRe = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// IsValidLocale reports whether locale is a language tag such as "fr" or
+// "pt-BR", case-insensitively.
+func IsValidLocale(locale string) bool {
+	return localeRe.MatchString(normalizeLocale(locale))
+}
+
+// normalizeLocale lowercases locale and separates its subtags with hyphens,
+// so "pt_BR" and "pt-br" name the same locale.
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// isEnglish reports whether locale is English, the language of the
+// registry's subjects.
+func isEnglish(locale string) bool {
+	language, _, _ := strings.Cut(normalizeLocale(locale), "-")
+	return language == "en"
+}
+
+// loadSubjects parses every subjects/*.json in fsys, keyed by locale and then
+// by template name.
+func loadSubjects(fsys fs.FS) (map[string]map[string]string, error) {
+	files, err := fs.Glob(fsys, subjectsDir+"/*.json")
+	if err != nil {
+		return nil, fmt.Errorf("listing subjects: %w", err)
+	}
+
+	locales := make(map[string]map[string]string, len(files))
+	for _, file := range files {
+		locale := strings.TrimSuffix(path.Base(file), ".json")
+		if !localeRe.MatchString(locale) {
+			return nil, fmt.Errorf("subjects file %s: name must be a lowercase language tag, e.g. es.json or pt-br.json", file)
+		}
+		src, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("reading subjects %s: %w", file, err)
+		}
+		var subjects map[string]string
+		if err := json.Unmarshal(src, &subjects); err != nil {
+			return nil, fmt.Errorf("parsing subjects %s: %w", file, err)
+		}
+		locales[locale] = subjects
+	}
+	return locales, nil
+}
+
+// localizedSubject returns the subject of the template name in locale, from
+// its subjects file, falling back from a regional locale ("pt-br") to its
+// language ("pt"). It reports false when neither has one.
+func (e *Engine) localizedSubject(name, locale string) (string, bool) {
+	locale = normalizeLocale(locale)
+	for locale != "" {
+		if subject := e.subjectsByLocale[locale][name]; subject != "" {
+			return subject, true
+		}
+		i := strings.LastIndex(locale, "-")
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	return "", false
+}
@@ -0,0 +1,131 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+)
+
+// pushDir holds the push payloads, one <name>.json per notification type.
+const pushDir = "push"
+
+// pushTemplate is a parsed push/<name>.json: the JSON document with every
+// string value compiled as a text/template, so data is never spliced into
+// JSON syntax and needs no escaping.
+type pushTemplate struct {
+	root any
+}
+
+// loadPushTemplates parses every push/*.json in fsys, keyed by template name.
+func loadPushTemplates(fsys fs.FS) (map[string]*pushTemplate, error) {
+	files, err := fs.Glob(fsys, pushDir+"/*.json")
+	if err != nil {
+		return nil, fmt.Errorf("listing push templates: %w", err)
+	}
+
+	templates := make(map[string]*pushTemplate, len(files))
+	for _, file := range files {
+		src, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("reading push template %s: %w", file, err)
+		}
+		var doc any
+		if err := json.Unmarshal(src, &doc); err != nil {
+			return nil, fmt.Errorf("parsing push template %s: %w", file, err)
+		}
+		root, err := compilePush(file, doc)
+		if err != nil {
+			return nil, fmt.Errorf("parsing push template %s: %w", file, err)
+		}
+		templates[strings.TrimSuffix(path.Base(file), ".json")] = &pushTemplate{root: root}
+	}
+	return templates, nil
+}
+
+// compilePush replaces the string values in v with compiled templates.
+func compilePush(name string, v any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return texttemplate.New(name).Funcs(texttemplate.FuncMap(funcMap)).Option("missingkey=error").Parse(v)
+	case map[string]any:
+		for key, value := range v {
+			compiled, err := compilePush(name, value)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = compiled
+		}
+	case []any:
+		for i, value := range v {
+			compiled, err := compilePush(name, value)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = compiled
+		}
+	}
+	return v, nil
+}
+
+// execute renders the template into a push payload. Unknown keys and
+// non-string data values are errors, so a typo cannot silently drop a field.
+func (t *pushTemplate) execute(data map[string]any) (*notification.PushContent, error) {
+	rendered, err := executePush(t.root, data)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := json.Marshal(rendered)
+	if err != nil {
+		return nil, err
+	}
+
+	var content notification.PushContent
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&content); err != nil {
+		return nil, fmt.Errorf("decoding payload: %w", err)
+	}
+	if content.Title == "" && content.Body == "" {
+		return nil, fmt.Errorf("payload has neither a title nor a body")
+	}
+	return &content, nil
+}
+
+// executePush returns a copy of v with every compiled template executed.
+func executePush(v any, data map[string]any) (any, error) {
+	switch v := v.(type) {
+	case *texttemplate.Template:
+		var buf bytes.Buffer
+		if err := v.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		return strings.TrimSpace(buf.String()), nil
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, value := range v {
+			rendered, err := executePush(value, data)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = rendered
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, value := range v {
+			rendered, err := executePush(value, data)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = rendered
+		}
+		return out, nil
+	}
+	return v, nil
+}
@@ -0,0 +1,80 @@
+package template
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// droppedElements lose their content along with their tags: it is code or
+// embedded markup, never text meant for the recipient.
+var droppedElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Iframe:   true,
+	atom.Object:   true,
+	atom.Noscript: true,
+	atom.Template: true,
+}
+
+// sanitizeData returns a copy of data with HTML stripped from every string
+// value, nested maps and lists included. Other values are kept as is.
+func sanitizeData(data map[string]any) map[string]any {
+	if data == nil {
+		return nil
+	}
+	out := make(map[string]any, len(data))
+	for key, value := range data {
+		out[key] = sanitizeValue(value)
+	}
+	return out
+}
+
+func sanitizeValue(value any) any {
+	switch v := value.(type) {
+	case string:
+		return stripTags(v)
+	case map[string]any:
+		return sanitizeData(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = sanitizeValue(item)
+		}
+		return out
+	}
+	return value
+}
+
+// stripTags removes every HTML tag and comment from s, like a strict
+// sanitizer policy, along with the content of script-like elements. The text
+// between tags is kept verbatim: entities are left for the template's own
+// escaping, so "&amp;" is not turned back into markup.
+func stripTags(s string) string {
+	if !strings.Contains(s, "<") {
+		return s
+	}
+
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(s))
+	dropped := 0
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return b.String() // io.EOF: reading from a string cannot fail otherwise
+		case html.TextToken:
+			if dropped == 0 {
+				b.Write(z.Raw())
+			}
+		case html.StartTagToken:
+			if name, _ := z.TagName(); droppedElements[atom.Lookup(name)] {
+				dropped++
+			}
+		case html.EndTagToken:
+			if name, _ := z.TagName(); droppedElements[atom.Lookup(name)] && dropped > 0 {
+				dropped--
+			}
+		}
+	}
+}
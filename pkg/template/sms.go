@@ -0,0 +1,110 @@
+package template
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// SMS encodings. A body made only of GSM 03.38 characters is sent as GSM-7;
+// any other character switches the whole message to UCS-2.
+const (
+	EncodingGSM7 = "GSM-7"
+	EncodingUCS2 = "UCS-2"
+)
+
+// Segment capacities: a single SMS, and each part of a concatenated one
+// (the rest of the part holds the concatenation header).
+const (
+	gsm7Single  = 160 // septets
+	gsm7Part    = 153
+	ucs2Single  = 70 // UTF-16 code units
+	ucs2Part    = 67
+	gsm7Elision = "..."
+	ucs2Elision = "…"
+)
+
+// gsm7Basic is the GSM 03.38 basic character set (without the escape code);
+// gsm7Extension holds the characters sent as an escape plus one septet.
+const (
+	gsm7Basic     = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+	gsm7Extension = "\f^{}\\[~]|€"
+)
+
+// gsm7Septets maps every GSM-7 character to the septets it takes.
+var gsm7Septets = func() map[rune]int {
+	m := make(map[rune]int)
+	for _, r := range gsm7Basic {
+		m[r] = 1
+	}
+	for _, r := range gsm7Extension {
+		m[r] = 2
+	}
+	return m
+}()
+
+// SMSSegments describes how an SMS body is encoded and split for delivery.
+type SMSSegments struct {
+	Encoding string `json:"encoding"`
+	Units    int    `json:"units"` // GSM-7 septets or UCS-2 code units
+	Segments int    `json:"segments"`
+}
+
+// CountSMS reports the encoding and number of segments body is sent as.
+func CountSMS(body string) SMSSegments {
+	septets := 0
+	for _, r := range body {
+		n, ok := gsm7Septets[r]
+		if !ok {
+			units := len(utf16.Encode([]rune(body)))
+			return SMSSegments{Encoding: EncodingUCS2, Units: units, Segments: segments(units, ucs2Single, ucs2Part)}
+		}
+		septets += n
+	}
+	return SMSSegments{Encoding: EncodingGSM7, Units: septets, Segments: segments(septets, gsm7Single, gsm7Part)}
+}
+
+// segments returns how many parts units take: one if they fit a single
+// message, otherwise as many concatenated parts as needed.
+func segments(units, single, part int) int {
+	switch {
+	case units == 0:
+		return 0
+	case units <= single:
+		return 1
+	default:
+		return (units + part - 1) / part
+	}
+}
+
+// truncateSMS shortens body to fit maxSegments, ending it with an ellipsis
+// in the body's own encoding so the cut does not switch it to UCS-2.
+func truncateSMS(body string, maxSegments int) string {
+	info := CountSMS(body)
+	if info.Segments <= maxSegments {
+		return body
+	}
+
+	single, part, elision := gsm7Single, gsm7Part, gsm7Elision
+	width := func(r rune) int { return gsm7Septets[r] }
+	if info.Encoding == EncodingUCS2 {
+		single, part, elision = ucs2Single, ucs2Part, ucs2Elision
+		width = utf16.RuneLen
+	}
+	budget := part * maxSegments
+	if maxSegments == 1 {
+		budget = single
+	}
+	budget -= CountSMS(elision).Units
+
+	var b strings.Builder
+	used := 0
+	for _, r := range body {
+		if used+width(r) > budget {
+			break
+		}
+		used += width(r)
+		b.WriteRune(r)
+	}
+	return strings.TrimRightFunc(b.String(), unicode.IsSpace) + elision
+}
@@ -0,0 +1,212 @@
+package template
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/badrkarrachai/notifly/pkg/notification"
+)
+
+// Issue describes a single problem found while validating templates.
+type Issue struct {
+	Type     notification.NotificationType `json:"type,omitempty"`
+	Template string                        `json:"template"`
+	Message  string                        `json:"message"`
+}
+
+func (i Issue) String() string {
+	if i.Type == "" {
+		return fmt.Sprintf("%s: %s", i.Template, i.Message)
+	}
+	return fmt.Sprintf("%s (%s): %s", i.Template, i.Type, i.Message)
+}
+
+// Validate loads the templates in strict mode and executes every registered
+// notification type with its sample data, including its SMS body and push
+// payload, and every A/B test variant page (<name>.<variant>.html) the same
+// way. It reports
+// registered types without a template file, template files without a registry
+// entry, variables referenced by a template but missing from the sample data,
+// unbalanced HTML tags, and localized subjects that are empty or name no
+// registered template.
+//
+// The returned error is non-nil only if the directory could not be loaded at all.
+func Validate(templatesDir string) ([]Issue, error) {
+	return ValidateFS(os.DirFS(templatesDir))
+}
+
+// ValidateFS is Validate for templates in fsys, e.g. Embedded().
+func ValidateFS(fsys fs.FS) ([]Issue, error) {
+	engine, err := loadEngine(fsys, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []Issue
+
+	// Check every notification type the domain accepts has a registry entry
+	for _, t := range notification.Types() {
+		if _, ok := registry[t]; !ok {
+			issues = append(issues, Issue{Type: t, Template: string(t), Message: "no template registered for type"})
+		}
+	}
+
+	registered := make(map[string]notification.NotificationType, len(registry))
+	for notifType, meta := range registry {
+		registered[meta.TemplateName] = notifType
+
+		if _, ok := engine.pages[meta.TemplateName]; !ok {
+			issues = append(issues, Issue{Type: notifType, Template: meta.TemplateName + ".html", Message: "template file missing"})
+			continue
+		}
+
+		_, html, _, err := engine.Render(notifType, meta.SampleData)
+		if err != nil {
+			issues = append(issues, Issue{Type: notifType, Template: meta.TemplateName, Message: err.Error()})
+			continue
+		}
+
+		if err := checkHTML(html); err != nil {
+			issues = append(issues, Issue{Type: notifType, Template: meta.TemplateName + ".html", Message: err.Error()})
+		}
+
+		if _, err := engine.renderSMSText(meta.TemplateName, meta.SampleData); err != nil {
+			issues = append(issues, Issue{Type: notifType, Template: smsDir + "/" + meta.TemplateName + ".txt", Message: err.Error()})
+		}
+		if tmpl, ok := engine.pushTemplates[meta.TemplateName]; ok {
+			if _, err := tmpl.execute(meta.SampleData); err != nil {
+				issues = append(issues, Issue{Type: notifType, Template: pushDir + "/" + meta.TemplateName + ".json", Message: err.Error()})
+			}
+		}
+	}
+
+	// A variant's templates are checked like the type's own
+	isRegistered := func(name string) bool {
+		base, _, _ := strings.Cut(name, ".")
+		_, ok := registered[base]
+		return ok
+	}
+	for name := range engine.pages {
+		base, variant, ok := strings.Cut(name, ".")
+		notifType, registeredBase := registered[base]
+		if !ok || !registeredBase {
+			continue
+		}
+		if !notification.IsValidVariantName(variant) {
+			issues = append(issues, Issue{Type: notifType, Template: name + ".html", Message: fmt.Sprintf("invalid variant name %q: use lowercase letters, digits, and underscores", variant)})
+			continue
+		}
+		meta := registry[notifType]
+		renderer := &variantEngine{engine: engine, variant: variant}
+		_, html, _, err := renderer.Render(notifType, meta.SampleData)
+		if err != nil {
+			issues = append(issues, Issue{Type: notifType, Template: name, Message: err.Error()})
+			continue
+		}
+		if err := checkHTML(html); err != nil {
+			issues = append(issues, Issue{Type: notifType, Template: name + ".html", Message: err.Error()})
+		}
+		if _, err := renderer.RenderSMS(notifType, meta.SampleData); err != nil {
+			issues = append(issues, Issue{Type: notifType, Template: smsDir + "/" + name + ".txt", Message: err.Error()})
+		}
+		if _, err := renderer.RenderPush(notifType, meta.SampleData); err != nil {
+			issues = append(issues, Issue{Type: notifType, Template: pushDir + "/" + name + ".json", Message: err.Error()})
+		}
+	}
+
+	// Template files that no notification type points to are dead weight (rules §6.2)
+	for name := range engine.pages {
+		if !isRegistered(name) {
+			issues = append(issues, Issue{Template: name + ".html", Message: "template file is not registered in engine.go"})
+		}
+	}
+	if engine.textTemplates != nil {
+		for _, t := range engine.textTemplates.Templates() {
+			name := strings.TrimSuffix(t.Name(), filepath.Ext(t.Name()))
+			if t.Name() != "" && !isRegistered(name) {
+				issues = append(issues, Issue{Template: t.Name(), Message: "text template has no matching registered type"})
+			}
+		}
+	}
+	if engine.smsTemplates != nil {
+		for _, t := range engine.smsTemplates.Templates() {
+			name := strings.TrimSuffix(t.Name(), filepath.Ext(t.Name()))
+			if t.Name() != "" && !isRegistered(name) {
+				issues = append(issues, Issue{Template: smsDir + "/" + t.Name(), Message: "sms template has no matching registered type"})
+			}
+		}
+	}
+	for name := range engine.pushTemplates {
+		if !isRegistered(name) {
+			issues = append(issues, Issue{Template: pushDir + "/" + name + ".json", Message: "push template has no matching registered type"})
+		}
+	}
+
+	for locale, subjects := range engine.subjectsByLocale {
+		file := subjectsDir + "/" + locale + ".json"
+		for name, subject := range subjects {
+			notifType, ok := registered[name]
+			switch {
+			case !ok:
+				issues = append(issues, Issue{Template: file, Message: fmt.Sprintf("subject for %q has no matching registered type", name)})
+			case strings.TrimSpace(subject) == "":
+				issues = append(issues, Issue{Type: notifType, Template: file, Message: fmt.Sprintf("subject for %q is empty", name)})
+			}
+		}
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].Template != issues[j].Template {
+			return issues[i].Template < issues[j].Template
+		}
+		return issues[i].Message < issues[j].Message
+	})
+
+	return issues, nil
+}
+
+// voidElements never have a closing tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true,
+	"img": true, "input": true, "link": true, "meta": true, "source": true, "wbr": true,
+}
+
+var (
+	commentRe = regexp.MustCompile(`(?s)<!--.*?-->`)
+	tagRe     = regexp.MustCompile(`<(/?)([a-zA-Z][a-zA-Z0-9]*)\b[^>]*?(/?)>`)
+)
+
+// checkHTML verifies that every non-void element in the rendered output is
+// closed in the right order. It is a structural sanity check, not a full parser.
+func checkHTML(s string) error {
+	s = commentRe.ReplaceAllString(s, "")
+
+	var stack []string
+	for _, m := range tagRe.FindAllStringSubmatch(s, -1) {
+		closing, name, selfClosing := m[1] == "/", strings.ToLower(m[2]), m[3] == "/"
+		if voidElements[name] || selfClosing {
+			continue
+		}
+		if !closing {
+			stack = append(stack, name)
+			continue
+		}
+		if len(stack) == 0 {
+			return fmt.Errorf("unexpected closing tag </%s>", name)
+		}
+		if top := stack[len(stack)-1]; top != name {
+			return fmt.Errorf("mismatched closing tag </%s>, expected </%s>", name, top)
+		}
+		stack = stack[:len(stack)-1]
+	}
+
+	if len(stack) > 0 {
+		return fmt.Errorf("unclosed tag <%s>", stack[len(stack)-1])
+	}
+	return nil
+}